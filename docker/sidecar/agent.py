@@ -0,0 +1,36 @@
+"""Entry point of the static agent build (Dockerfile target ``agent``).
+
+The build is a single executable, so the subcommands that normally run as
+their own scripts are dispatched here: ``install`` (see executor.install)
+and the search worker (see executor.search) start without importing the
+server. Everything else (the server, ``selftest``, ``bench``) is main.py.
+"""
+
+import json
+import os
+import runpy
+import sys
+from pathlib import Path
+
+if __name__ == "__main__":
+    if sys.argv[1:2] == ["install"]:
+        from executor import install
+
+        args = install.parse_args(sys.argv[2:])
+        try:
+            report = install.install(Path(args.dest), install.bundled_files(), profiles_only=args.profiles_only)
+        except install.InstallError as e:
+            print(f"install: {e}", file=sys.stderr, flush=True)
+            sys.exit(2)
+        print(json.dumps(report, indent=2), flush=True)
+        sys.exit(0 if report["ok"] else 1)
+    if sys.argv[1:2] == ["search-worker"]:
+        from executor import search
+
+        search.worker()
+        sys.exit(0)
+
+    # nsenter is installed next to the agent, and the image it runs in may not have one
+    if getattr(sys, "frozen", False):
+        os.environ["PATH"] = f"{Path(sys.executable).parent}{os.pathsep}{os.environ.get('PATH', '')}"
+    runpy.run_module("main", run_name="__main__")
@@ -0,0 +1,6 @@
+"""Helper modules for the KubeCodeRun HTTP sidecar.
+
+main.py owns the FastAPI app and the nsenter plumbing. The modules in this
+package hold the pure logic behind individual endpoints so it can be unit
+tested without a running pod.
+"""
@@ -0,0 +1,227 @@
+"""Synthetic benchmark of an execution pod (``python main.py bench``).
+
+Runs synthetic workloads through the same path as /execute and reports
+throughput, latency, CPU and memory, so operators can size pods and
+check their limits before production:
+
+- ``small``: many trivial executions, run concurrently (spawn and
+  interpreter startup dominate)
+- ``cpu``: a busy loop for ``cpu_seconds``; the loop count and CPU
+  throttling show what the pod's CPU limit allows
+- ``output``: a flood of ``output_bytes`` on stdout, to check output
+  capture and MAX_OUTPUT_SIZE
+
+    kubectl exec <pod> -c sidecar -- python main.py bench --workload small,cpu --iterations 100
+
+Memory is the cgroup's, sampled while each workload runs; user code runs
+in the sidecar's cgroup, so it's what the pod's memory limit applies to.
+"""
+
+import argparse
+import asyncio
+import os
+import time
+from collections.abc import Awaitable, Callable
+
+from . import runtime, selftest
+
+WORKLOADS = ("small", "cpu", "output")
+DEFAULT_ITERATIONS = {"small": 50, "cpu": 3, "output": 3}
+SAMPLE_INTERVAL = 0.05
+
+# Code of each workload per language; SECONDS and LINES (of 1 KiB) are replaced before running
+WORKLOAD_CODE: dict[str, dict[str, str]] = {
+    "py": {
+        "small": "print(1)",
+        "cpu": "import time\nend = time.time() + SECONDS\nn = 0\nwhile time.time() < end:\n    n += 1\nprint(n)",
+        "output": "import sys\nline = 'x' * 1023 + '\\n'\nfor _ in range(LINES):\n    sys.stdout.write(line)",
+    },
+    "js": {
+        "small": "console.log(1)",
+        "cpu": "const end = Date.now() + SECONDS * 1000; let n = 0; while (Date.now() < end) n++; console.log(n);",
+        "output": "const line = 'x'.repeat(1023) + '\\n'; for (let i = 0; i < LINES; i++) process.stdout.write(line);",
+    },
+    "php": {
+        "small": "<?php echo 1;",
+        "cpu": "<?php $end = microtime(true) + SECONDS; $n = 0; while (microtime(true) < $end) $n++; echo $n;",
+        "output": "<?php $line = str_repeat('x', 1023) . \"\\n\"; for ($i = 0; $i < LINES; $i++) echo $line;",
+    },
+    "r": {
+        "small": "cat(1)",
+        "cpu": "end <- Sys.time() + SECONDS; n <- 0; while (Sys.time() < end) n <- n + 1; cat(sprintf('%.0f', n))",
+        "output": "line <- paste0(strrep('x', 1023), '\\n'); for (i in seq_len(LINES)) cat(line)",
+    },
+}
+WORKLOAD_CODE["ts"] = WORKLOAD_CODE["js"]
+
+# Result of an execution: exit_code, stdout, stderr and execution_time_ms, as /execute returns them
+Execute = Callable[[str, int], Awaitable[dict]]
+
+
+def parse_args(argv: list[str]) -> argparse.Namespace:
+    parser = argparse.ArgumentParser(prog="main.py bench", description="Synthetic benchmark of this pod")
+    parser.add_argument("--workload", default=",".join(WORKLOADS), help=f"Comma-separated: {', '.join(WORKLOADS)}")
+    parser.add_argument("--iterations", type=int, help="Executions per workload (default: 50 small, 3 cpu/output)")
+    parser.add_argument("--concurrency", type=int, default=4, help="Executions running at once (default: 4)")
+    parser.add_argument("--cpu-seconds", type=float, default=2.0, help="Busy loop length of the cpu workload")
+    parser.add_argument("--output-bytes", type=int, default=1048576, help="Stdout written by the output workload")
+    parser.add_argument("--timeout", type=int, default=60, help="Timeout of each execution (default: 60)")
+    args = parser.parse_args(argv)
+    args.workloads = [w.strip() for w in args.workload.split(",") if w.strip()]
+    unknown = [w for w in args.workloads if w not in WORKLOADS]
+    if unknown:
+        parser.error(f"Unknown workloads: {', '.join(unknown)}")
+    return args
+
+
+def workload_code(language: str, workload: str, cpu_seconds: float = 2.0, output_bytes: int = 1048576) -> str:
+    code = runtime.LANGUAGE_ALIASES.get(language, language)
+    if code not in WORKLOAD_CODE:
+        raise ValueError(f"No synthetic workloads for {language!r} (available: {', '.join(sorted(WORKLOAD_CODE))})")
+    return (
+        WORKLOAD_CODE[code][workload]
+        .replace("SECONDS", str(cpu_seconds))
+        .replace("LINES", str(max(1, output_bytes // 1024)))
+    )
+
+
+def percentiles(values: list[float]) -> dict[str, float]:
+    """p50, p95, p99, max and mean (nearest rank)."""
+    if not values:
+        return {}
+    ordered = sorted(values)
+
+    def rank(p: float) -> float:
+        return ordered[min(len(ordered) - 1, max(0, round(p / 100 * len(ordered)) - 1))]
+
+    return {
+        "p50": rank(50),
+        "p95": rank(95),
+        "p99": rank(99),
+        "max": ordered[-1],
+        "mean": round(sum(ordered) / len(ordered), 1),
+    }
+
+
+def _read_int(path: str) -> int | None:
+    try:
+        with open(path) as f:
+            return int(f.read().split()[0])
+    except (OSError, ValueError, IndexError):
+        return None
+
+
+def read_cpu_stat(root: str | None = None) -> dict[str, int]:
+    """CPU usage and throttling counters of the cgroup (cgroup v2 cpu.stat)."""
+    stats: dict[str, int] = {}
+    try:
+        with open(os.path.join(root or selftest.CGROUP_ROOT, "cpu.stat")) as f:
+            for line in f:
+                name, _, value = line.partition(" ")
+                if name in ("usage_usec", "nr_throttled", "throttled_usec"):
+                    stats[name] = int(value)
+    except (OSError, ValueError):
+        pass
+    return stats
+
+
+class MemorySampler:
+    """Highest cgroup memory usage seen while it runs."""
+
+    def __init__(self, root: str | None = None):
+        self.path = os.path.join(root or selftest.CGROUP_ROOT, "memory.current")
+        self.peak: int | None = None
+        self._task: asyncio.Task | None = None
+
+    def sample(self) -> None:
+        value = _read_int(self.path)
+        if value is not None:
+            self.peak = max(self.peak or 0, value)
+
+    async def _loop(self) -> None:
+        while True:
+            self.sample()
+            await asyncio.sleep(SAMPLE_INTERVAL)
+
+    async def __aenter__(self) -> "MemorySampler":
+        self.sample()
+        self._task = asyncio.create_task(self._loop())
+        return self
+
+    async def __aexit__(self, *exc) -> None:
+        self._task.cancel()
+        self.sample()
+
+
+async def run_workload(
+    execute: Execute,
+    name: str,
+    code: str,
+    iterations: int,
+    concurrency: int,
+    timeout: int,
+    cgroup_root: str | None = None,
+) -> dict:
+    """Run one workload; latencies are measured around each execution, as a client would see them."""
+    semaphore = asyncio.Semaphore(concurrency)
+    latencies: list[float] = []
+    exec_times: list[float] = []
+    failures: list[str] = []
+    stdout_bytes = 0
+    loop_counts: list[int] = []
+
+    async def one() -> None:
+        nonlocal stdout_bytes
+        async with semaphore:
+            start = time.perf_counter()
+            result = await execute(code, timeout)
+            latencies.append(round((time.perf_counter() - start) * 1000, 1))
+        exec_times.append(result.get("execution_time_ms", 0))
+        stdout_bytes += len(result.get("stdout", "").encode())
+        if result.get("exit_code") != 0:
+            stderr = result.get("stderr", "").strip()
+            failures.append(f"exit {result.get('exit_code')}: {stderr.splitlines()[0][:200] if stderr else ''}")
+        elif name == "cpu" and result.get("stdout", "").strip().isdigit():
+            loop_counts.append(int(result["stdout"].strip()))
+
+    cpu_before = read_cpu_stat(cgroup_root)
+    start = time.perf_counter()
+    async with MemorySampler(cgroup_root) as memory:
+        await asyncio.gather(*(one() for _ in range(iterations)))
+    wall = time.perf_counter() - start
+    cpu_after = read_cpu_stat(cgroup_root)
+
+    report = {
+        "workload": name,
+        "iterations": iterations,
+        "concurrency": concurrency,
+        "failed": len(failures),
+        "errors": sorted(set(failures))[:5],
+        "wall_seconds": round(wall, 3),
+        "throughput_per_second": round(iterations / wall, 2) if wall else None,
+        "latency_ms": percentiles(latencies),
+        "execution_time_ms": percentiles(exec_times),
+        "memory_peak_bytes": memory.peak,
+        "stdout_bytes": stdout_bytes,
+        "cpu": {name: cpu_after[name] - cpu_before[name] for name in cpu_after if name in cpu_before},
+    }
+    if loop_counts:
+        report["cpu_loops_per_execution"] = percentiles([float(n) for n in loop_counts])
+    return report
+
+
+async def run_bench(execute: Execute, language: str, args: argparse.Namespace, cgroup_root: str | None = None) -> dict:
+    """Run the selected workloads one after another; ``ok`` is false if an execution failed."""
+    results = []
+    for workload in args.workloads:
+        code = workload_code(language, workload, args.cpu_seconds, args.output_bytes)
+        iterations = args.iterations or DEFAULT_ITERATIONS[workload]
+        results.append(
+            await run_workload(execute, workload, code, iterations, args.concurrency, args.timeout, cgroup_root)
+        )
+    return {
+        "ok": all(r["failed"] == 0 for r in results),
+        "language": language,
+        "limits": selftest.read_cgroup_limits(cgroup_root),
+        "workloads": results,
+    }
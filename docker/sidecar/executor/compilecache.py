@@ -0,0 +1,212 @@
+"""Compiler caches shared by every pod on the node.
+
+With COMPILE_CACHE_DIR set, the API mounts a node directory read-write at
+that path in both containers, and executions reuse what earlier ones
+compiled, in any session on the node:
+
+- C, C++ and Fortran compile through ccache (when the image has it) into
+  <dir>/ccache. Only compilations are cached, so the code is compiled
+  with -c and linked in a second step.
+- Go builds with GOCACHE=<dir>/go-build.
+
+Hits and misses are reported per execution: ccache writes each result to
+a stats log (CCACHE_STATSLOG), and go writes its action graph
+(-debug-actiongraph), where packages it compiled have the command that
+compiled them and cached ones have none. Both files go to <dir>/.stats,
+which the sidecar sees too.
+
+The directory is kept under COMPILE_CACHE_MAX_MB by a periodic sweep that
+deletes the least recently used files (both tools refresh the mtime of
+entries they use) down to 90% of the quota. Pods on a node sweep the same
+directory; a file deleted under a running build is a miss, not an error.
+"""
+
+import json
+import os
+import stat
+import time
+import uuid
+
+CCACHE_LANGUAGES = ("c", "cpp", "fortran", "f90")
+GO_LANGUAGES = ("go",)
+STATS_DIR = ".stats"
+LOW_WATER = 0.9  # A sweep over quota deletes down to this fraction of it
+STALE_STATS_SECONDS = 3600  # Stats files left by executions that were killed
+
+# ccache stats log lines counted as hits and misses
+CCACHE_HITS = ("direct_cache_hit", "preprocessed_cache_hit")
+CCACHE_MISSES = ("cache_miss",)
+
+
+def cache_tool(language: str) -> str | None:
+    """ccache or go for languages with a compiler cache, None for the rest."""
+    if language in CCACHE_LANGUAGES:
+        return "ccache"
+    if language in GO_LANGUAGES:
+        return "go"
+    return None
+
+
+def read_ccache_stats(path: str) -> tuple[int, int] | None:
+    """Hits and misses in a ccache stats log, None if it wasn't written."""
+    try:
+        with open(path) as f:
+            lines = [line.strip() for line in f]
+    except OSError:
+        return None
+    hits = sum(1 for line in lines if line in CCACHE_HITS)
+    misses = sum(1 for line in lines if line in CCACHE_MISSES)
+    return hits, misses
+
+
+def read_go_actiongraph(path: str) -> tuple[int, int] | None:
+    """Packages go took from its cache and packages it compiled, None if the graph is missing or unreadable."""
+    try:
+        with open(path) as f:
+            actions = json.load(f)
+    except (OSError, ValueError):
+        return None
+    if not isinstance(actions, list):
+        return None
+    builds = [a for a in actions if isinstance(a, dict) and a.get("Mode") == "build" and a.get("Package")]
+    misses = sum(1 for a in builds if a.get("Cmd"))
+    return len(builds) - misses, misses
+
+
+class CompileCacheRun:
+    """The cache of one execution: which tool, and where it reports hits and misses."""
+
+    def __init__(self, tool: str, stats_path: str):
+        self.tool = tool
+        self.stats_path = stats_path
+
+
+class CompileCache:
+    """The node's compiler cache directory, its quota and this pod's hit counts."""
+
+    def __init__(self, root: str, max_bytes: int):
+        self.root = root
+        self.max_bytes = max_bytes
+        self.hits = 0
+        self.misses = 0
+        self.evictions = 0
+        self.used_bytes: int | None = None  # As of the last sweep
+        self.last_sweep: float | None = None
+
+    @property
+    def stats_dir(self) -> str:
+        return os.path.join(self.root, STATS_DIR)
+
+    def prepare(self, language: str, env: dict[str, str]) -> CompileCacheRun | None:
+        """Point the language's compiler at the cache in ``env``.
+
+        Returns None for languages without a cache, and when the directory is unusable.
+        """
+        tool = cache_tool(language)
+        if tool is None:
+            return None
+        try:
+            os.makedirs(self.stats_dir, exist_ok=True)
+        except OSError as e:
+            print(f"[CACHE] {self.root} is unusable, compiling without the cache: {e}", flush=True)
+            return None
+
+        stats_path = os.path.join(self.stats_dir, uuid.uuid4().hex)
+        if tool == "ccache":
+            env["CCACHE_DIR"] = os.path.join(self.root, "ccache")
+            env["CCACHE_MAXSIZE"] = f"{self.max_bytes // (1024 * 1024)}M"
+            env["CCACHE_STATSLOG"] = stats_path
+        else:
+            env["GOCACHE"] = os.path.join(self.root, "go-build")
+            env["GOFLAGS"] = " ".join(filter(None, [env.get("GOFLAGS"), f"-debug-actiongraph={stats_path}"]))
+        return CompileCacheRun(tool, stats_path)
+
+    def finish(self, run: CompileCacheRun) -> dict:
+        """The execution's hits and misses (None when the tool reported nothing), added to this pod's totals."""
+        read = read_ccache_stats if run.tool == "ccache" else read_go_actiongraph
+        counts = read(run.stats_path)
+        try:
+            os.unlink(run.stats_path)
+        except OSError:
+            pass
+        if counts is None:
+            return {"tool": run.tool, "hits": None, "misses": None}
+        hits, misses = counts
+        self.hits += hits
+        self.misses += misses
+        return {"tool": run.tool, "hits": hits, "misses": misses}
+
+    def sweep(self, now: float | None = None) -> int:
+        """Delete the least recently used files while the cache is over quota; returns how many were deleted."""
+        now = time.time() if now is None else now
+        files = []
+        total = 0
+        for dirpath, _, names in os.walk(self.root):
+            stats = os.path.basename(dirpath) == STATS_DIR
+            for name in names:
+                path = os.path.join(dirpath, name)
+                try:
+                    st = os.lstat(path)
+                except OSError:
+                    continue
+                if stat.S_ISDIR(st.st_mode):
+                    continue
+                if stats and now - st.st_mtime > STALE_STATS_SECONDS:
+                    self._unlink(path)
+                    continue
+                files.append((st.st_mtime, st.st_size, path))
+                total += st.st_size
+
+        evicted = 0
+        if total > self.max_bytes:
+            target = self.max_bytes * LOW_WATER
+            for _, size, path in sorted(files):
+                if total <= target:
+                    break
+                if self._unlink(path):
+                    evicted += 1
+                total -= size
+
+        self.evictions += evicted
+        self.used_bytes = total
+        self.last_sweep = now
+        return evicted
+
+    @staticmethod
+    def _unlink(path: str) -> bool:
+        """Delete a file; False if it couldn't be (another pod's sweep may have deleted it first)."""
+        try:
+            os.unlink(path)
+            return True
+        except OSError:
+            return False
+
+    def describe(self) -> dict:
+        lookups = self.hits + self.misses
+        return {
+            "dir": self.root,
+            "max_bytes": self.max_bytes,
+            "used_bytes": self.used_bytes,
+            "hits": self.hits,
+            "misses": self.misses,
+            "hit_rate": round(self.hits / lookups, 4) if lookups else None,
+            "evictions": self.evictions,
+            "last_sweep": self.last_sweep,
+        }
+
+
+def load(root: str, max_mb: str) -> CompileCache | None:
+    """The cache for COMPILE_CACHE_DIR and COMPILE_CACHE_MAX_MB; None when the directory isn't set.
+
+    Raises:
+        ValueError: For a quota that isn't a positive number of MB
+    """
+    if not root:
+        return None
+    try:
+        max_bytes = int(max_mb or "2048") * 1024 * 1024
+    except ValueError:
+        raise ValueError(f"COMPILE_CACHE_MAX_MB must be a number of MB, got {max_mb!r}") from None
+    if max_bytes <= 0:
+        raise ValueError(f"COMPILE_CACHE_MAX_MB must be positive, got {max_mb!r}")
+    return CompileCache(root, max_bytes)
@@ -0,0 +1,150 @@
+"""Outbound connection accounting for executions.
+
+Operators who allow networking still want to know how much of it a job
+uses, and to stop one that opens far more connections than it should.
+While an execution runs its process tree is sampled: the socket inodes
+among each process's open fds are matched against the pod's TCP tables
+(/proc/net/tcp and tcp6), and every connection to a remote address is
+counted once. If more than the allowed number are open at the same time
+the execution is killed and a CONNECTION_LIMIT_EXCEEDED error returned.
+
+Sampling can miss connections that open and close between two samples,
+so the count is a lower bound; the limit holds for anything that stays
+open longer than the interval.
+"""
+
+import os
+import re
+
+ERROR_CODE = "CONNECTION_LIMIT_EXCEEDED"
+
+# Seconds between samples of the process tree
+SAMPLE_INTERVAL = 0.1
+
+TCP_TABLES = ("/proc/net/tcp", "/proc/net/tcp6")
+
+# States of a connection that is being opened or is open (not LISTEN or closing)
+_CONNECTED_STATES = {"01", "02", "03"}  # ESTABLISHED, SYN_SENT, SYN_RECV
+
+_SOCKET_LINK = re.compile(r"^socket:\[(\d+)\]$")
+
+
+def parse_tcp_table(text: str) -> dict[int, tuple[str, int]]:
+    """Connections in a /proc/net/tcp{,6} table by socket inode: (remote address, remote port).
+
+    Listening and closing sockets are left out.
+    """
+    connections = {}
+    for line in text.splitlines()[1:]:
+        fields = line.split()
+        if len(fields) < 10 or fields[3] not in _CONNECTED_STATES:
+            continue
+        address, _, port = fields[2].partition(":")
+        inode = int(fields[9])
+        if inode:
+            connections[inode] = (address, int(port, 16))
+    return connections
+
+
+def _is_loopback(address: str) -> bool:
+    # Hex, in the kernel's byte order: 127.x.x.x ends in 7F, ::1 and ::ffff:127.x.x.x too
+    if len(address) == 8:
+        return address.endswith("7F")
+    return address == "00000000000000000000000001000000" or (
+        address.startswith("0000000000000000FFFF0000") and address.endswith("7F")
+    )
+
+
+def read_connections(tables: tuple[str, ...] = TCP_TABLES) -> dict[int, tuple[str, int]]:
+    """Open connections to other hosts in the pod's network namespace, by socket inode."""
+    connections = {}
+    for table in tables:
+        try:
+            with open(table) as f:
+                connections.update(parse_tcp_table(f.read()))
+        except OSError:
+            continue
+    return {inode: peer for inode, peer in connections.items() if not _is_loopback(peer[0])}
+
+
+def process_tree(root_pid: int, proc: str = "/proc") -> list[int]:
+    """``root_pid`` and all of its descendants."""
+    children: dict[int, list[int]] = {}
+    try:
+        entries = os.listdir(proc)
+    except OSError:
+        return [root_pid]
+    for entry in entries:
+        if not entry.isdigit():
+            continue
+        try:
+            with open(f"{proc}/{entry}/stat") as f:
+                # The command name is in parentheses and may contain spaces
+                ppid = int(f.read().rsplit(")", 1)[1].split()[1])
+        except (OSError, IndexError, ValueError):
+            continue
+        children.setdefault(ppid, []).append(int(entry))
+
+    tree, pending = [], [root_pid]
+    while pending:
+        pid = pending.pop()
+        tree.append(pid)
+        pending.extend(children.get(pid, []))
+    return tree
+
+
+def socket_inodes(pids: list[int], proc: str = "/proc") -> set[int]:
+    """Inodes of the sockets the processes have open."""
+    inodes = set()
+    for pid in pids:
+        fd_dir = f"{proc}/{pid}/fd"
+        try:
+            fds = os.listdir(fd_dir)
+        except OSError:
+            continue
+        for fd in fds:
+            try:
+                match = _SOCKET_LINK.match(os.readlink(f"{fd_dir}/{fd}"))
+            except OSError:
+                continue
+            if match:
+                inodes.add(int(match.group(1)))
+    return inodes
+
+
+class ConnectionMonitor:
+    """Counts the outbound connections of one execution's process tree."""
+
+    def __init__(self, root_pid: int, limit: int | None = None):
+        self.root_pid = root_pid
+        self.limit = limit
+        self.seen: set[int] = set()
+        self.peak = 0
+        self.exceeded = False
+
+    @property
+    def count(self) -> int:
+        """Distinct connections seen so far."""
+        return len(self.seen)
+
+    def record(self, open_connections: set[int]) -> bool:
+        """Add a sample of the tree's open connections. Returns False once the limit is exceeded."""
+        self.seen |= open_connections
+        self.peak = max(self.peak, len(open_connections))
+        if self.limit is not None and len(open_connections) > self.limit:
+            self.exceeded = True
+        return not self.exceeded
+
+    def sample(self) -> bool:
+        """Sample the process tree now. Returns False once the limit is exceeded."""
+        inodes = socket_inodes(process_tree(self.root_pid))
+        if not inodes:
+            return self.record(set())
+        return self.record(inodes & read_connections().keys())
+
+    def error(self) -> dict:
+        """Structured error for an execution stopped for opening too many connections."""
+        return {
+            "code": ERROR_CODE,
+            "message": f"Execution opened {self.peak} connections at once; at most {self.limit} are allowed",
+        }
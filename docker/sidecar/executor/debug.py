@@ -0,0 +1,165 @@
+"""Support bundle for debugging a pod.
+
+GET /debug/bundle returns one tar.gz holding what an operator would
+otherwise collect by hand with kubectl: the sidecar's recent log lines,
+a summary of recent executions, its configuration and the main
+container's environment (with secret-looking values redacted), platform
+information and the pod's resource usage from cgroup v2. Executed code
+isn't included, but the logs are what ``kubectl logs`` would show,
+output previews included.
+"""
+
+import io
+import json
+import os
+import platform
+import re
+import shutil
+import sys
+import tarfile
+import time
+from collections import deque
+from datetime import UTC, datetime
+
+# Log lines and executions kept for the bundle
+MAX_LOG_LINES = 2000
+MAX_HISTORY = 50
+
+CGROUP_ROOT = "/sys/fs/cgroup"
+CGROUP_FILES = (
+    "memory.current",
+    "memory.max",
+    "memory.peak",
+    "memory.events",
+    "memory.stat",
+    "cpu.max",
+    "cpu.stat",
+    "pids.current",
+    "pids.max",
+    "io.stat",
+)
+
+REDACTED = "[REDACTED]"
+_SECRET_NAME = re.compile(r"KEY|TOKEN|SECRET|PASSW|CREDENTIAL|AUTH|PRIVATE|COOKIE", re.IGNORECASE)
+
+STARTED_AT = time.time()
+
+
+class LogBuffer:
+    """The most recent lines written to stdout and stderr."""
+
+    def __init__(self, maxlen: int = MAX_LOG_LINES):
+        self.lines: deque[str] = deque(maxlen=maxlen)
+        self._partial = ""
+
+    def write(self, text: str) -> None:
+        lines = (self._partial + text).split("\n")
+        self._partial = lines.pop()
+        stamp = datetime.now(UTC).strftime("%Y-%m-%dT%H:%M:%S.%fZ")
+        self.lines.extend(f"{stamp} {line}" for line in lines)
+
+    def text(self) -> str:
+        return "\n".join([*self.lines, self._partial] if self._partial else self.lines) + "\n"
+
+    def tee(self, stream):
+        """A stream writing to ``stream`` and to this buffer."""
+        return _Tee(stream, self)
+
+
+class _Tee:
+    def __init__(self, stream, buffer: LogBuffer):
+        self._stream = stream
+        self._buffer = buffer
+
+    def write(self, text: str) -> int:
+        self._buffer.write(text)
+        return self._stream.write(text)
+
+    def __getattr__(self, name):
+        return getattr(self._stream, name)
+
+
+class ExecutionHistory:
+    """Summaries of the most recent executions (no code or output)."""
+
+    def __init__(self, maxlen: int = MAX_HISTORY):
+        self.entries: deque[dict] = deque(maxlen=maxlen)
+
+    def record(self, started_at: float, response, code_bytes: int) -> None:
+        error = response.error or {}
+        self.entries.append(
+            {
+                "started_at": datetime.fromtimestamp(started_at, UTC).isoformat(),
+                "execution_time_ms": response.execution_time_ms,
+                "exit_code": response.exit_code,
+                "interrupted": response.interrupted,
+                "error": error.get("code"),
+                "connections": response.connections,
+                "dns_denied": len(response.dns_denied or []),
+                "code_bytes": code_bytes,
+                "stdout_bytes": len(response.stdout),
+                "stderr_bytes": len(response.stderr),
+                "provenance": response.provenance,
+            }
+        )
+
+
+def redact_env(env: dict[str, str]) -> dict[str, str]:
+    """Environment with the values of secret-looking names masked."""
+    return {name: REDACTED if _SECRET_NAME.search(name) else value for name, value in sorted(env.items())}
+
+
+def platform_info(**extra) -> dict:
+    """Versions, kernel and CPUs of the pod, plus sidecar details passed in ``extra``."""
+    try:
+        cpus = sorted(os.sched_getaffinity(0))
+    except (AttributeError, OSError):
+        cpus = None
+    return {
+        "python": sys.version,
+        "platform": platform.platform(),
+        "kernel": " ".join(os.uname()),
+        "machine": platform.machine(),
+        "hostname": platform.node(),
+        "cpu_count": os.cpu_count(),
+        "cpus": cpus,
+        "uptime_seconds": round(time.time() - STARTED_AT),
+        **extra,
+    }
+
+
+def resource_stats(working_dir: str, cgroup_root: str = CGROUP_ROOT) -> dict:
+    """cgroup v2 usage and limits of the sidecar (where code runs), load and disk usage."""
+    cgroup = {}
+    for name in CGROUP_FILES:
+        try:
+            with open(f"{cgroup_root}/{name}") as f:
+                cgroup[name] = f.read().strip()
+        except OSError:
+            continue
+    stats: dict = {"cgroup": cgroup}
+    try:
+        stats["loadavg"] = os.getloadavg()
+    except OSError:
+        pass
+    try:
+        usage = shutil.disk_usage(working_dir)
+        stats["working_dir"] = {"path": working_dir, "total": usage.total, "used": usage.used, "free": usage.free}
+    except OSError:
+        pass
+    return stats
+
+
+def build_bundle(files: dict[str, str | dict | list]) -> bytes:
+    """tar.gz with one member per entry; dicts and lists are written as JSON."""
+    buffer = io.BytesIO()
+    prefix = f"support-bundle-{datetime.now(UTC).strftime('%Y%m%dT%H%M%SZ')}"
+    with tarfile.open(fileobj=buffer, mode="w:gz") as tar:
+        for name, content in files.items():
+            data = (content if isinstance(content, str) else json.dumps(content, indent=2, default=str)).encode()
+            info = tarfile.TarInfo(f"{prefix}/{name}")
+            info.size = len(data)
+            info.mtime = int(time.time())
+            info.mode = 0o644
+            tar.addfile(info, io.BytesIO(data))
+    return buffer.getvalue()
@@ -0,0 +1,36 @@
+"""Default profiles shipped inside the agent (executor/profiles).
+
+They are package data rather than files next to the image, so the static
+agent build (see executor.install) carries them in its one executable:
+
+- ``seccomp.json``: a seccomp profile for ``seccompProfile: Localhost``,
+  denying syscalls user code has no use for (kernel modules, kexec, bpf,
+  perf, keyrings, clock and swap changes) on top of whatever the runtime
+  already filters
+- ``policy.json``: the execution limits that apply when MAX_EXECUTION_TIME
+  and MAX_OUTPUT_SIZE aren't set
+"""
+
+import json
+from importlib import resources
+
+PROFILES = ("seccomp.json", "policy.json")
+
+
+def read(name: str) -> str:
+    """Text of an embedded profile.
+
+    Raises:
+        ValueError: If ``name`` isn't one of PROFILES
+    """
+    if name not in PROFILES:
+        raise ValueError(f"No embedded profile {name!r} (available: {', '.join(PROFILES)})")
+    return resources.files(__package__).joinpath("profiles", name).read_text(encoding="utf-8")
+
+
+def seccomp_profile() -> dict:
+    return json.loads(read("seccomp.json"))
+
+
+def policy() -> dict:
+    return json.loads(read("policy.json"))
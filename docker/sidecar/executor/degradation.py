@@ -0,0 +1,139 @@
+"""Slow-spawn and environment-degradation alarms.
+
+The sidecar periodically times a few probes: spawning a trivial process
+in the main container, starting the language's interpreter there, and a
+small write/fsync/read in the working directory. The first samples of
+each probe, taken while the pod is fresh, become its baseline. When the
+median of the latest samples exceeds the baseline by DEGRADED_FACTOR (and
+by at least the probe's floor, so jitter on fast probes doesn't count),
+or the probe fails outright, /ready reports the pod degraded with these
+diagnostics and the API drains it from its pool.
+"""
+
+import os
+import statistics
+import time
+import uuid
+from collections import deque
+
+# Samples averaged into a probe's baseline, and compared against it
+BASELINE_SAMPLES = 5
+RECENT_SAMPLES = 3
+DEFAULT_FACTOR = 3.0
+PROBE_TIMEOUT = 10
+DISK_PROBE_BYTES = 64 * 1024
+
+SPAWN_PROBE = ["true"]
+# Interpreter startup per language; compiled languages have nothing cheap to start
+STARTUP_PROBES = {
+    "py": ["python", "-c", "pass"],
+    "python": ["python", "-c", "pass"],
+    "js": ["node", "-e", "0"],
+    "javascript": ["node", "-e", "0"],
+    "ts": ["node", "-e", "0"],
+    "typescript": ["node", "-e", "0"],
+    "php": ["php", "-r", ""],
+    "r": ["Rscript", "-e", "0"],
+}
+# Milliseconds a probe must slow down by before the factor counts
+FLOORS_MS = {"spawn": 100.0, "interpreter": 500.0, "disk": 100.0}
+
+
+class ProbeBaseline:
+    """Baseline and recent samples of one probe."""
+
+    def __init__(self, floor_ms: float, factor: float = DEFAULT_FACTOR):
+        self.floor_ms = floor_ms
+        self.factor = factor
+        self._baseline_samples: list[float] = []
+        self.baseline_ms: float | None = None
+        self.recent: deque[float] = deque(maxlen=RECENT_SAMPLES)
+        self.error: str | None = None
+
+    def record(self, ms: float) -> None:
+        self.error = None
+        if self.baseline_ms is None:
+            self._baseline_samples.append(ms)
+            if len(self._baseline_samples) >= BASELINE_SAMPLES:
+                self.baseline_ms = statistics.median(self._baseline_samples)
+            return
+        self.recent.append(ms)
+
+    def record_failure(self, error: str) -> None:
+        self.error = error
+
+    @property
+    def recent_ms(self) -> float | None:
+        return statistics.median(self.recent) if len(self.recent) == RECENT_SAMPLES else None
+
+    @property
+    def degraded(self) -> bool:
+        if self.error:
+            return True
+        recent = self.recent_ms
+        if self.baseline_ms is None or recent is None:
+            return False
+        return recent > self.baseline_ms * self.factor and recent - self.baseline_ms > self.floor_ms
+
+    def diagnostics(self) -> dict:
+        recent = self.recent_ms
+        return {
+            "degraded": self.degraded,
+            "baseline_ms": round(self.baseline_ms, 1) if self.baseline_ms is not None else None,
+            "recent_ms": round(recent, 1) if recent is not None else None,
+            "last_ms": round(self.recent[-1], 1) if self.recent else None,
+            "threshold_ms": round(max(self.baseline_ms * self.factor, self.baseline_ms + self.floor_ms), 1)
+            if self.baseline_ms is not None
+            else None,
+            "error": self.error,
+        }
+
+
+class HealthMonitor:
+    """Baselines of the pod's probes and whether any has degraded."""
+
+    def __init__(self, factor: float = DEFAULT_FACTOR, probes: tuple[str, ...] = tuple(FLOORS_MS)):
+        self.checks = {name: ProbeBaseline(FLOORS_MS[name], factor) for name in probes}
+        self.last_probe: float | None = None
+
+    def record(self, name: str, ms: float) -> None:
+        self.checks[name].record(ms)
+
+    def record_failure(self, name: str, error: str) -> None:
+        self.checks[name].record_failure(error)
+
+    @property
+    def degraded(self) -> list[str]:
+        return [name for name, check in self.checks.items() if check.degraded]
+
+    def report(self) -> dict:
+        degraded = self.degraded
+        return {
+            "status": "degraded" if degraded else "ready",
+            "degraded": degraded,
+            "checks": {name: check.diagnostics() for name, check in self.checks.items()},
+            "last_probe_age_seconds": round(time.time() - self.last_probe) if self.last_probe else None,
+        }
+
+
+def disk_probe(directory: str, size: int = DISK_PROBE_BYTES) -> float:
+    """Milliseconds to write, fsync, read back and delete a small file in ``directory``."""
+    path = os.path.join(directory, f".health-probe-{uuid.uuid4().hex}")
+    data = os.urandom(size)
+    start = time.perf_counter()
+    try:
+        fd = os.open(path, os.O_WRONLY | os.O_CREAT | os.O_EXCL, 0o600)
+        try:
+            os.write(fd, data)
+            os.fsync(fd)
+        finally:
+            os.close(fd)
+        with open(path, "rb") as f:
+            if f.read() != data:
+                raise OSError("Read back different data than written")
+    finally:
+        try:
+            os.unlink(path)
+        except FileNotFoundError:
+            pass
+    return (time.perf_counter() - start) * 1000
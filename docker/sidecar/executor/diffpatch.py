@@ -0,0 +1,218 @@
+"""Apply unified diffs to files in the working directory.
+
+A diff may touch several files, create them (``--- /dev/null``) or delete
+them (``+++ /dev/null``). Every hunk is applied in memory first; if any
+hunk doesn't apply, no file is changed and the failed hunks are returned
+as rejects, with the lines the hunk expected and the lines found where it
+should have applied. Like ``patch``, a hunk whose context has moved (lines
+were added or removed above it) is found at its new position; there's no
+fuzz, so all of a hunk's context and removed lines must match, ignoring
+line endings.
+"""
+
+import re
+from dataclasses import dataclass, field
+from pathlib import Path
+
+DEV_NULL = "/dev/null"
+# How far around the expected position a moved hunk is looked for in each direction
+MAX_OFFSET = 1000
+
+_HUNK_HEADER = re.compile(r"^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@")
+
+
+class PatchError(Exception):
+    """A diff that can't be parsed or touches paths outside the root."""
+
+
+@dataclass
+class Hunk:
+    header: str
+    old_start: int
+    # (kind, text, no_newline) with kind " ", "-" or "+"
+    lines: list[tuple[str, str, bool]] = field(default_factory=list)
+
+    @property
+    def old(self) -> list[str]:
+        return [text for kind, text, _ in self.lines if kind != "+"]
+
+
+@dataclass
+class FileDiff:
+    old_path: str
+    new_path: str
+    hunks: list[Hunk] = field(default_factory=list)
+
+    @property
+    def path(self) -> str:
+        return self.old_path if self.new_path == DEV_NULL else self.new_path
+
+
+def _strip_path(raw: str, strip: int) -> str:
+    path = raw.split("\t")[0].strip()
+    if path == DEV_NULL:
+        return path
+    parts = path.split("/")
+    if len(parts) <= strip:
+        raise PatchError(f"Cannot strip {strip} components from {path!r}")
+    return "/".join(parts[strip:])
+
+
+def parse(diff: str, strip: int = 1) -> list[FileDiff]:
+    """Files and hunks of a unified diff; ``strip`` leading path components are dropped, as ``patch -p``.
+
+    Raises:
+        PatchError: If the diff has no files or a hunk is malformed or truncated
+    """
+    files: list[FileDiff] = []
+    lines = diff.splitlines()
+    i = 0
+    while i < len(lines):
+        if not (lines[i].startswith("--- ") and i + 1 < len(lines) and lines[i + 1].startswith("+++ ")):
+            i += 1
+            continue
+        current = FileDiff(_strip_path(lines[i][4:], strip), _strip_path(lines[i + 1][4:], strip))
+        if current.old_path == DEV_NULL and current.new_path == DEV_NULL:
+            raise PatchError("Both sides of a file diff are /dev/null")
+        files.append(current)
+        i += 2
+        while i < len(lines) and lines[i].startswith("@@"):
+            header = _HUNK_HEADER.match(lines[i])
+            if not header:
+                raise PatchError(f"Malformed hunk header: {lines[i]!r}")
+            old_remaining = int(header.group(2) or 1)
+            new_remaining = int(header.group(4) or 1)
+            hunk = Hunk(header=lines[i], old_start=int(header.group(1)))
+            i += 1
+            while old_remaining > 0 or new_remaining > 0:
+                if i >= len(lines):
+                    raise PatchError(f"Truncated hunk in {current.path}: {hunk.header}")
+                line = lines[i]
+                # Some tools strip the single space of an empty context line
+                kind, text = (line[0], line[1:]) if line else (" ", "")
+                if kind not in " -+":
+                    raise PatchError(f"Unexpected line in hunk in {current.path}: {line!r}")
+                hunk.lines.append((kind, text, False))
+                old_remaining -= kind != "+"
+                new_remaining -= kind != "-"
+                i += 1
+                if i < len(lines) and lines[i].startswith("\\"):
+                    hunk.lines[-1] = (kind, text, True)
+                    i += 1
+            current.hunks.append(hunk)
+    if not files:
+        raise PatchError("No file diffs found")
+    return files
+
+
+def _line_ending(lines: list[str]) -> str:
+    for line in lines:
+        if line.endswith("\r\n"):
+            return "\r\n"
+        if line.endswith("\n"):
+            return "\n"
+    return "\n"
+
+
+def _find(content: list[str], expected: list[str], position: int, start: int) -> int | None:
+    """Index nearest ``position`` (not before ``start``) where ``expected`` matches, ignoring line endings."""
+    def matches_at(index: int) -> bool:
+        if index < start or index + len(expected) > len(content):
+            return False
+        return all(content[index + k].rstrip("\r\n") == expected[k] for k in range(len(expected)))
+
+    for distance in range(MAX_OFFSET + 1):
+        for index in (position - distance, position + distance) if distance else (position,):
+            if matches_at(index):
+                return index
+    return None
+
+
+def apply_hunks(content: list[str], file_diff: FileDiff) -> tuple[list[str], list[dict]]:
+    """``content`` (lines with endings) with the file's hunks applied, and rejects for hunks that didn't apply."""
+    ending = _line_ending(content)
+    result: list[str] = []
+    rejects: list[dict] = []
+    consumed = 0
+    delta = 0
+    for hunk in file_diff.hunks:
+        expected = hunk.old
+        # A hunk replacing nothing ("-0,0") inserts after line old_start, otherwise it starts at it
+        position = max(0, hunk.old_start - (1 if expected else 0)) + delta
+        index = _find(content, expected, position, consumed)
+        if index is None:
+            found = content[max(0, position):max(0, position) + max(len(expected), 1)]
+            rejects.append({
+                "path": file_diff.path,
+                "hunk": hunk.header,
+                "line": hunk.old_start,
+                "reason": "Context does not match",
+                "expected": expected,
+                "found": [line.rstrip("\r\n") for line in found],
+            })
+            continue
+        result.extend(content[consumed:index])
+        original = iter(content[index:index + len(expected)])
+        for kind, text, no_newline in hunk.lines:
+            if kind == " ":
+                result.append(next(original))
+            elif kind == "-":
+                next(original)
+            else:
+                result.append(text + ("" if no_newline else ending))
+        consumed = index + len(expected)
+        delta = index - (hunk.old_start - (1 if expected else 0))
+    result.extend(content[consumed:])
+    return result, rejects
+
+
+def plan(root: Path, diff: str, strip: int = 1) -> tuple[dict[Path, bytes | None], list[dict], list[dict]]:
+    """Work out a diff's effect without writing anything.
+
+    Returns the new contents of every touched file (None for deletions),
+    a summary of each file (path, status and hunk count) and the rejects.
+
+    Raises:
+        PatchError: If the diff is malformed or a path is outside ``root``
+    """
+    root = root.resolve()
+    contents: dict[Path, bytes | None] = {}
+    summary: list[dict] = []
+    rejects: list[dict] = []
+
+    for file_diff in parse(diff, strip):
+        path = (root / file_diff.path).resolve()
+        if not path.is_relative_to(root) or path == root:
+            raise PatchError(f"Path outside the working directory: {file_diff.path}")
+        if path.is_dir():
+            raise PatchError(f"Path is a directory: {file_diff.path}")
+
+        if path in contents:
+            existing = contents[path]
+        else:
+            existing = path.read_bytes() if path.is_file() else None
+
+        if file_diff.old_path == DEV_NULL and existing is not None:
+            rejects.append({"path": file_diff.path, "reason": "File to create already exists"})
+            continue
+        if file_diff.old_path != DEV_NULL and existing is None:
+            rejects.append({"path": file_diff.path, "reason": "File not found"})
+            continue
+
+        lines = (existing or b"").decode("utf-8", errors="surrogateescape").splitlines(keepends=True)
+        updated, file_rejects = apply_hunks(lines, file_diff)
+        rejects.extend(file_rejects)
+        if file_rejects:
+            continue
+
+        if file_diff.new_path == DEV_NULL:
+            if updated:
+                rejects.append({"path": file_diff.path, "reason": "File to delete has lines the diff doesn't remove"})
+                continue
+            contents[path] = None
+            status = "deleted"
+        else:
+            contents[path] = "".join(updated).encode("utf-8", errors="surrogateescape")
+            status = "created" if existing is None else "modified"
+        summary.append({"path": file_diff.path, "status": status, "hunks": len(file_diff.hunks)})
+    return contents, summary, rejects
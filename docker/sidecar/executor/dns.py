@@ -0,0 +1,238 @@
+"""DNS allowlists for executions.
+
+With a DNS policy the pod's resolv.conf points at 127.0.0.1 and ::1,
+where the sidecar runs a small resolver. Each lookup is attributed to the execution
+that made it (the querying UDP socket is found among the process tree's
+open fds) and answered NXDOMAIN unless the name is allowed, both by the
+operator's allowlist and the execution's own. Allowed lookups are
+forwarded to the cluster's DNS server.
+
+This only controls name resolution: code that connects to IP addresses
+directly isn't stopped, which takes a NetworkPolicy or egress proxy.
+"""
+
+import asyncio
+import struct
+from dataclasses import dataclass, field
+
+from executor import connections
+
+ERROR_CODE = "DNS_POLICY_UNAVAILABLE"
+
+# ::1 is skipped where the pod has no IPv6 loopback
+LISTEN_ADDRESSES = ("127.0.0.1", "::1")
+PORT = 53
+# Seconds to wait for the upstream server
+UPSTREAM_TIMEOUT = 5.0
+# Denied names kept per execution
+MAX_DENIED = 100
+
+UDP_TABLES = ("/proc/net/udp", "/proc/net/udp6")
+
+_HEADER = struct.Struct(">HHHHHH")
+_NXDOMAIN = 3
+
+
+@dataclass
+class Query:
+    id: int
+    flags: int
+    name: str
+    question: bytes  # Wire format, echoed in the response
+
+
+def parse_query(data: bytes) -> Query | None:
+    """First question of a DNS query, or None if it isn't one."""
+    if len(data) < _HEADER.size:
+        return None
+    query_id, flags, questions = _HEADER.unpack_from(data)[:3]
+    if flags & 0x8000 or questions < 1:
+        return None
+    labels, pos = [], _HEADER.size
+    while pos < len(data):
+        length = data[pos]
+        pos += 1
+        if length == 0:
+            break
+        # Queries don't compress their question; anything else is malformed
+        if length > 63 or pos + length > len(data):
+            return None
+        labels.append(data[pos : pos + length].decode("ascii", errors="replace"))
+        pos += length
+    else:
+        return None
+    if pos + 4 > len(data):
+        return None
+    return Query(id=query_id, flags=flags, name=".".join(labels).lower(), question=data[_HEADER.size : pos + 4])
+
+
+def nxdomain(query: Query) -> bytes:
+    """NXDOMAIN answer to a query."""
+    # QR and RA set; opcode and RD copied from the query
+    flags = 0x8000 | (query.flags & 0x7900) | 0x0080 | _NXDOMAIN
+    return _HEADER.pack(query.id, flags, 1, 0, 0, 0) + query.question
+
+
+def matches(name: str, allowlist: list[str]) -> bool:
+    """Whether a name is allowed: ``example.com`` allows only itself, ``*.example.com`` its subdomains."""
+    name = name.lower().rstrip(".")
+    for pattern in allowlist:
+        pattern = pattern.lower().rstrip(".")
+        if pattern.startswith("*."):
+            if name.endswith(pattern[1:]):
+                return True
+        elif name == pattern:
+            return True
+    return False
+
+
+def udp_socket_inode(port: int, tables: tuple[str, ...] = UDP_TABLES) -> int | None:
+    """Inode of the UDP socket bound to a local port."""
+    wanted = f"{port:04X}"
+    for table in tables:
+        try:
+            with open(table) as f:
+                lines = f.read().splitlines()[1:]
+        except OSError:
+            continue
+        for line in lines:
+            fields = line.split()
+            if len(fields) >= 10 and fields[1].rpartition(":")[2] == wanted:
+                return int(fields[9])
+    return None
+
+
+@dataclass
+class _Execution:
+    allowlist: list[str] | None
+    denied: list[str] = field(default_factory=list)
+
+
+class PolicyRegistry:
+    """Running executions and what each may resolve.
+
+    ``default`` is the operator's allowlist, applying to every lookup in the
+    pod; empty allows everything.
+    """
+
+    def __init__(self, default: list[str] | None = None):
+        self.default = default or []
+        self._executions: dict[int, _Execution] = {}
+
+    def register(self, pid: int, allowlist: list[str] | None) -> None:
+        self._executions[pid] = _Execution(allowlist)
+
+    def finish(self, pid: int) -> list[str]:
+        """Stop tracking an execution, returning the names it was denied."""
+        execution = self._executions.pop(pid, None)
+        return execution.denied if execution else []
+
+    def owner(self, port: int) -> int | None:
+        """Execution (root pid) whose process tree sent a query from ``port``.
+
+        Reads /proc; call it off the event loop.
+        """
+        if not self._executions:
+            return None
+        inode = udp_socket_inode(port)
+        if inode is None:
+            return None
+        for pid in list(self._executions):
+            if inode in connections.socket_inodes(connections.process_tree(pid)):
+                return pid
+        return None
+
+    def allows(self, name: str, owner: int | None) -> bool:
+        """Check a lookup, recording it against the execution if it's denied."""
+        execution = self._executions.get(owner) if owner is not None else None
+        allowlists = [self.default] if self.default else []
+        if execution and execution.allowlist is not None:
+            allowlists.append(execution.allowlist)
+        allowed = all(matches(name, allowlist) for allowlist in allowlists)
+        if not allowed and execution and name not in execution.denied and len(execution.denied) < MAX_DENIED:
+            execution.denied.append(name)
+        return allowed
+
+
+class _Reply(asyncio.DatagramProtocol):
+    def __init__(self, answer: asyncio.Future):
+        self.answer = answer
+
+    def datagram_received(self, data: bytes, addr) -> None:
+        if not self.answer.done():
+            self.answer.set_result(data)
+
+
+async def forward(data: bytes, upstream: str, timeout: float = UPSTREAM_TIMEOUT) -> bytes | None:
+    """Send a query to the upstream server and return its answer, or None if there's none in time."""
+    loop = asyncio.get_running_loop()
+    answer = loop.create_future()
+    transport, _ = await loop.create_datagram_endpoint(lambda: _Reply(answer), remote_addr=(upstream, PORT))
+    try:
+        transport.sendto(data)
+        return await asyncio.wait_for(answer, timeout)
+    except TimeoutError:
+        return None
+    finally:
+        transport.close()
+
+
+class Resolver(asyncio.DatagramProtocol):
+    """UDP resolver enforcing a PolicyRegistry, forwarding allowed lookups upstream.
+
+    Answers too large for UDP come back truncated; the TCP retry isn't served.
+    """
+
+    def __init__(self, upstream: str, policies: PolicyRegistry):
+        self.upstream = upstream
+        self.policies = policies
+        self.transport = None
+        self._tasks: set[asyncio.Task] = set()
+
+    def connection_made(self, transport) -> None:
+        self.transport = transport
+
+    def datagram_received(self, data: bytes, addr) -> None:
+        task = asyncio.get_running_loop().create_task(self.handle(data, addr))
+        self._tasks.add(task)
+        task.add_done_callback(self._tasks.discard)
+
+    async def handle(self, data: bytes, addr) -> None:
+        query = parse_query(data)
+        if query is None:
+            return
+        owner = await asyncio.to_thread(self.policies.owner, addr[1])
+        if not self.policies.allows(query.name, owner):
+            print(f"[DNS] Denied lookup of {query.name!r} (execution pid={owner})", flush=True)
+            self.transport.sendto(nxdomain(query), addr)
+            return
+        try:
+            answer = await forward(data, self.upstream)
+        except OSError as e:
+            print(f"[DNS] Upstream {self.upstream} failed: {e}", flush=True)
+            return
+        if answer:
+            self.transport.sendto(answer, addr)
+
+
+async def start_resolver(
+    upstream: str, policies: PolicyRegistry, addresses: tuple[str, ...] = LISTEN_ADDRESSES, port: int = PORT
+) -> list[asyncio.DatagramTransport]:
+    """Listen on port 53 of the loopback addresses for the pod's lookups.
+
+    Raises the first address's error when none of them can be bound.
+    """
+    loop = asyncio.get_running_loop()
+    transports: list[asyncio.DatagramTransport] = []
+    errors: list[OSError] = []
+    for address in addresses:
+        try:
+            transport, _ = await loop.create_datagram_endpoint(
+                lambda: Resolver(upstream, policies), local_addr=(address, port)
+            )
+            transports.append(transport)
+        except OSError as e:
+            errors.append(e)
+    if not transports:
+        raise errors[0]
+    return transports
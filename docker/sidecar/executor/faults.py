@@ -0,0 +1,122 @@
+"""Fault injection for resilience testing (FAULT_INJECTION).
+
+Off unless FAULT_INJECTION is set, e.g.
+``spawn_failure=0.1,delay=0.2,truncate=0.05,drop=0.05,delay_ms=3000``.
+Each fault is a probability from 0 to 1, rolled independently per request:
+
+- ``spawn_failure``: /execute fails with SPAWN_FAILED without running the code
+- ``delay``: the response is held back for ``delay_ms`` (default 2000)
+- ``truncate``: the connection is closed halfway through the first body
+  chunk, after the status and headers were sent
+- ``drop``: the connection is closed right after the status and headers
+
+``seed`` makes the rolls reproducible. Truncated and dropped requests have
+been handled in full, as when a connection is lost on the way back, so
+they also test that retries are safe. /health and /ready are never
+faulted, so the pod isn't restarted or drained by its own probes.
+"""
+
+import asyncio
+import random
+from collections import Counter
+
+FAULTS = ("spawn_failure", "delay", "truncate", "drop")
+OPTIONS = ("delay_ms", "seed")
+DEFAULT_DELAY_MS = 2000
+EXEMPT_PATHS = ("/health", "/ready")
+SPAWN_FAILED = "SPAWN_FAILED"
+
+
+class FaultSpecError(ValueError):
+    """An invalid FAULT_INJECTION value."""
+
+
+class InjectedFault(Exception):
+    """Raised mid-response to make the server close the connection."""
+
+
+class FaultInjector:
+    """Rolls the configured faults, counting the ones injected."""
+
+    def __init__(self, rates: dict[str, float], delay_ms: int = DEFAULT_DELAY_MS, seed: int | None = None):
+        self.rates = rates
+        self.delay_ms = delay_ms
+        self.injected: Counter[str] = Counter()
+        self._random = random.Random(seed)
+
+    @classmethod
+    def parse(cls, spec: str) -> "FaultInjector | None":
+        """The injector for a FAULT_INJECTION value, or None when it's empty."""
+        rates: dict[str, float] = {}
+        options: dict[str, int] = {}
+        for item in filter(None, (part.strip() for part in spec.split(","))):
+            name, sep, value = item.partition("=")
+            name = name.strip()
+            if not sep or name not in FAULTS + OPTIONS:
+                names = ", ".join(FAULTS + OPTIONS)
+                raise FaultSpecError(f"Invalid fault {item!r}; expected name=value with name in {names}")
+            try:
+                if name in OPTIONS:
+                    options[name] = int(value)
+                else:
+                    rates[name] = float(value)
+            except ValueError:
+                raise FaultSpecError(f"Invalid value for {name}: {value!r}")
+            if name in FAULTS and not 0 <= rates[name] <= 1:
+                raise FaultSpecError(f"{name} must be a probability from 0 to 1")
+        if not rates:
+            return None
+        return cls(rates, options.get("delay_ms", DEFAULT_DELAY_MS), options.get("seed"))
+
+    def roll(self, fault: str) -> bool:
+        """Whether to inject the fault this time."""
+        rate = self.rates.get(fault, 0)
+        if rate and self._random.random() < rate:
+            self.injected[fault] += 1
+            return True
+        return False
+
+    def report(self) -> dict:
+        return {"rates": self.rates, "delay_ms": self.delay_ms, "injected": dict(self.injected)}
+
+
+def spawn_failure_error() -> dict:
+    return {"code": SPAWN_FAILED, "message": "Injected fault: process spawn failed"}
+
+
+class FaultMiddleware:
+    """ASGI middleware injecting delays, truncated responses and dropped connections."""
+
+    def __init__(self, app, injector: FaultInjector):
+        self.app = app
+        self.injector = injector
+
+    async def __call__(self, scope, receive, send):
+        if scope["type"] != "http" or scope["path"] in EXEMPT_PATHS:
+            return await self.app(scope, receive, send)
+
+        if self.injector.roll("delay"):
+            await asyncio.sleep(self.injector.delay_ms / 1000)
+        if self.injector.roll("drop"):
+            fault = "drop"
+        elif self.injector.roll("truncate"):
+            fault = "truncate"
+        else:
+            return await self.app(scope, receive, send)
+        print(f"[FAULT] {fault} {scope['method']} {scope['path']}", flush=True)
+
+        async def faulty_send(message):
+            if message["type"] == "http.response.start":
+                await send(message)
+                if fault == "drop":
+                    raise InjectedFault(fault)
+            elif message["type"] == "http.response.body":
+                body = message.get("body", b"")
+                if body:
+                    await send({"type": "http.response.body", "body": body[: len(body) // 2], "more_body": True})
+                    raise InjectedFault(fault)
+                await send(message)
+            else:
+                await send(message)
+
+        await self.app(scope, receive, faulty_send)
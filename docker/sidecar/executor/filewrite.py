@@ -0,0 +1,92 @@
+"""Atomic file writes and in-place appends for the file API.
+
+Uploads are written to a temporary file next to the destination and
+renamed over it, so a reader (or an execution) sees either the old file
+or the complete new one, never a partial upload. Appends and offset
+writes modify the file in place: an append is a single O_APPEND write, so
+concurrent appenders don't overwrite each other, and an offset write
+replaces bytes inside the file or extends it at its end. Both can be made
+conditional on the file's current size, which lets a client that retries
+a request after a lost response detect that it already landed.
+"""
+
+import os
+import uuid
+from pathlib import Path
+
+
+class FileWriteError(Exception):
+    """A write that can't be applied, with the HTTP status to answer with."""
+
+    def __init__(self, message: str, status: int = 400):
+        super().__init__(message)
+        self.status = status
+
+
+def atomic_write(path: Path, data: bytes) -> None:
+    """Replace ``path`` with ``data`` via a temporary file and rename."""
+    path.parent.mkdir(parents=True, exist_ok=True)
+    tmp_path = path.with_name(f".{path.name}.upload-{uuid.uuid4().hex[:8]}")
+    try:
+        with open(tmp_path, "wb") as f:
+            f.write(data)
+            f.flush()
+            os.fsync(f.fileno())
+        os.replace(tmp_path, path)
+    except BaseException:
+        tmp_path.unlink(missing_ok=True)
+        raise
+
+
+def current_size(path: Path) -> int:
+    """Size of the file at ``path``, 0 if it doesn't exist yet.
+
+    Raises:
+        FileWriteError: 400 if the path is a directory
+    """
+    if path.is_dir():
+        raise FileWriteError("Path is a directory")
+    return path.stat().st_size if path.exists() else 0
+
+
+def growth(path: Path, length: int, offset: int | None = None) -> int:
+    """Bytes a write of ``length`` at ``offset`` (None appends) adds to the file."""
+    size = current_size(path)
+    if offset is None:
+        return length
+    return max(0, offset + length - size)
+
+
+def write_at(path: Path, data: bytes, offset: int | None = None, expected_size: int | None = None) -> int:
+    """Append ``data`` to the file, or write it at ``offset``; returns the new size.
+
+    A missing file is created (an offset write then needs offset 0).
+
+    Raises:
+        FileWriteError: 400 if the path is a directory or the offset is negative,
+            409 if the file's size isn't ``expected_size`` or the offset is past its end
+    """
+    size = current_size(path)
+    if expected_size is not None and size != expected_size:
+        raise FileWriteError(f"File is {size} bytes, expected {expected_size}", status=409)
+    if offset is not None:
+        if offset < 0:
+            raise FileWriteError("offset must not be negative")
+        if offset > size:
+            raise FileWriteError(f"offset {offset} is past the end of the file ({size} bytes)", status=409)
+
+    path.parent.mkdir(parents=True, exist_ok=True)
+    flags = os.O_WRONLY | os.O_CREAT | (os.O_APPEND if offset is None else 0)
+    fd = os.open(path, flags, 0o644)
+    try:
+        view = memoryview(data)
+        while view:
+            # Large writes can be partial; O_APPEND keeps each chunk at the end
+            written = os.write(fd, view) if offset is None else os.pwrite(fd, view, offset)
+            view = view[written:]
+            if offset is not None:
+                offset += written
+        os.fsync(fd)
+        return os.fstat(fd).st_size
+    finally:
+        os.close(fd)
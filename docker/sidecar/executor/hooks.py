@@ -0,0 +1,44 @@
+"""Operator-defined hooks around executions.
+
+A pre-exec hook runs before the code and a post-exec hook after it, both
+as ``sh -c`` scripts in the main container's working directory, each with
+its own time budget. They can snapshot state, scrub temp dirs or notify
+other systems. A failing pre-exec hook stops the execution; a failing
+post-exec hook leaves the result as it is. Either way the failure is
+reported as a HOOK_FAILED error naming the hook, so it can't be mistaken
+for a failure of the submitted code.
+"""
+
+ERROR_CODE = "HOOK_FAILED"
+
+# Exit status run_in_main_container reports for a command that timed out
+TIMEOUT_EXIT_CODE = 124
+
+# Hook stderr kept in the error; the end is where the reason usually is
+MAX_MESSAGE_CHARS = 2000
+
+
+def hook_env(phase: str, exit_code: int | None = None) -> dict[str, str]:
+    """Variables a hook runs with on top of the container environment.
+
+    EXEC_HOOK is ``pre`` or ``post``; post-exec hooks also get the code's
+    exit status as EXEC_EXIT_CODE.
+    """
+    env = {"EXEC_HOOK": phase}
+    if exit_code is not None:
+        env["EXEC_EXIT_CODE"] = str(exit_code)
+    return env
+
+
+def hook_failed(phase: str, exit_code: int, stderr: str, timeout: int) -> dict:
+    """Structured error for a hook that exited non-zero or ran out of time."""
+    if exit_code == TIMEOUT_EXIT_CODE:
+        message = f"timed out after {timeout} seconds"
+    else:
+        message = stderr.strip()[-MAX_MESSAGE_CHARS:] or f"exited with status {exit_code}"
+    return {"code": ERROR_CODE, "hook": phase, "exit_code": exit_code, "message": message}
+
+
+def format_message(error: dict) -> str:
+    """Human-readable stderr line for a HOOK_FAILED error."""
+    return f"{ERROR_CODE}: {error['hook']}-exec hook failed (exit code {error['exit_code']}): {error['message']}\n"
@@ -0,0 +1,106 @@
+"""Installing the static agent into a shared volume (``kubecoderun-agent install``).
+
+The Dockerfile's ``agent`` target is a ``FROM scratch`` image holding the
+agent as one static executable (PyInstaller and staticx), nsenter linked
+statically from source, and nothing else. An init container running it copies
+them into a volume the language container's pod shares, so the agent
+runs from any image without a Python of its own:
+
+    initContainers:
+      - name: install-agent
+        image: <registry>/kubecoderun-agent:<version>  # docker build --target agent
+        args: ["install", "/kubecoderun"]
+
+nsenter's file capabilities live in the ``security.capability`` xattr,
+which a plain copy drops; they're copied too when the init container may
+set them (CAP_SETFCAP), and the report says when they were lost. The
+embedded profiles (see executor.defaults) are written next to the
+executables, so nodes can be given the seccomp profile from the same
+image (``--profiles-only``).
+"""
+
+import argparse
+import os
+import shutil
+import sys
+from pathlib import Path
+
+from . import defaults
+
+CAPABILITY_XATTR = "security.capability"
+# Installed next to the agent and found on its PATH (see agent.py)
+HELPERS = ("nsenter",)
+
+
+class InstallError(Exception):
+    """The agent can't be installed."""
+
+
+def parse_args(argv: list[str]) -> argparse.Namespace:
+    parser = argparse.ArgumentParser(prog="kubecoderun-agent install", description="Copy the agent into a volume")
+    parser.add_argument("dest", help="Directory to install into, e.g. a shared emptyDir")
+    parser.add_argument("--profiles-only", action="store_true", help="Only write the embedded profiles")
+    return parser.parse_args(argv)
+
+
+def bundled_files() -> list[Path]:
+    """The running static agent and its helpers.
+
+    Raises:
+        InstallError: If this isn't the static build
+    """
+    if not getattr(sys, "frozen", False):
+        raise InstallError("install needs the static agent build (Dockerfile target agent), not main.py")
+    agent = Path(sys.executable)
+    return [agent, *(agent.parent / name for name in HELPERS)]
+
+
+def copy_executable(source: Path, dest: Path) -> str | None:
+    """Copy an executable, keeping its file capabilities where possible.
+
+    Returns None when it has none, else ``preserved`` or ``lost``. The copy
+    is renamed into place, so a pod restarting mid-copy never runs half a file.
+    """
+    partial = dest.with_name(f".{dest.name}.partial")
+    shutil.copyfile(source, partial)
+    partial.chmod(0o755)
+    capabilities = None
+    try:
+        value = os.getxattr(source, CAPABILITY_XATTR)
+    except OSError:
+        value = None
+    if value:
+        try:
+            os.setxattr(partial, CAPABILITY_XATTR, value)
+            capabilities = "preserved"
+        except OSError:
+            capabilities = "lost"
+    os.replace(partial, dest)
+    return capabilities
+
+
+def install(dest: Path, files: list[Path], profiles_only: bool = False) -> dict:
+    """Copy ``files`` and the embedded profiles into ``dest`` and report what was installed.
+
+    ``ok`` is False when an executable's capabilities couldn't be kept.
+
+    Raises:
+        InstallError: If a file is missing or ``dest`` can't be written
+    """
+    report = {"dest": str(dest), "files": [], "profiles": [], "ok": True}
+    try:
+        dest.mkdir(parents=True, exist_ok=True)
+        if not profiles_only:
+            for source in files:
+                if not source.is_file():
+                    raise InstallError(f"{source} is missing from the agent build")
+                capabilities = copy_executable(source, dest / source.name)
+                report["files"].append({"name": source.name, "capabilities": capabilities})
+                if capabilities == "lost":
+                    report["ok"] = False
+        for name in defaults.PROFILES:
+            (dest / name).write_text(defaults.read(name), encoding="utf-8")
+            report["profiles"].append(name)
+    except OSError as e:
+        raise InstallError(f"Can't install into {dest}: {e}")
+    return report
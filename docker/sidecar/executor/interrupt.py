@@ -0,0 +1,82 @@
+"""Interrupt (Ctrl-C) semantics for running executions.
+
+Executions run in their own process group, so an interrupt signals the
+whole group with SIGINT just like a terminal would: Python raises
+KeyboardInterrupt in the running code, and shell pipelines (compile && run)
+stop at whichever step is running. The pod and its workspace are left
+intact, unlike a timeout or cancel which kill the execution. A kill
+(quarantine of the session) sends SIGKILL instead, which code can't catch.
+"""
+
+import os
+import signal
+
+# Conventional exit status for a process stopped by SIGINT (128 + 2)
+INTERRUPT_EXIT_CODE = 128 + signal.SIGINT
+
+
+class InterruptRegistry:
+    """Tracks running execution process groups and which were interrupted."""
+
+    def __init__(self, killpg=os.killpg):
+        self._running: set[int] = set()
+        self._interrupted: set[int] = set()
+        self._killpg = killpg
+
+    def register(self, pgid: int) -> None:
+        """Record a started execution (its pid is its process group id)."""
+        self._running.add(pgid)
+
+    def finish(self, pgid: int) -> bool:
+        """Forget a finished execution. Returns True if it was interrupted."""
+        self._running.discard(pgid)
+        if pgid in self._interrupted:
+            self._interrupted.discard(pgid)
+            return True
+        return False
+
+    @property
+    def running(self) -> int:
+        """Number of executions currently running."""
+        return len(self._running)
+
+    def _signal_all(self, sig: int) -> list[int]:
+        signalled = []
+        for pgid in sorted(self._running):
+            try:
+                self._killpg(pgid, sig)
+            except ProcessLookupError:
+                # Exited between the check and the signal
+                continue
+            signalled.append(pgid)
+        return signalled
+
+    def interrupt_all(self) -> int:
+        """Send SIGINT to every running execution. Returns how many were signalled."""
+        signalled = self._signal_all(signal.SIGINT)
+        self._interrupted.update(signalled)
+        return len(signalled)
+
+    def kill_all(self) -> int:
+        """Send SIGKILL to every running execution. Returns how many were signalled.
+
+        Killed executions aren't reported as interrupted: they end like a
+        cancelled one, with the signal's exit status.
+        """
+        return len(self._signal_all(signal.SIGKILL))
+
+
+def interrupted_result(returncode: int | None, stderr: str, language: str) -> tuple[int, str]:
+    """Exit code and stderr to report for an interrupted execution.
+
+    A process killed by the signal reports 130 instead of a negative code,
+    with a KeyboardInterrupt (Python) or Interrupted marker appended if the
+    runtime didn't print one. Code that caught the interrupt and exited on
+    its own keeps its exit code and output.
+    """
+    if returncode is not None and returncode >= 0:
+        return returncode, stderr
+    marker = "KeyboardInterrupt" if language in ("python", "py") else "Interrupted"
+    if marker not in stderr:
+        stderr = f"{stderr.rstrip()}\n{marker}\n".lstrip("\n")
+    return INTERRUPT_EXIT_CODE, stderr
@@ -0,0 +1,130 @@
+"""Addresses the sidecar's HTTP API listens on (SIDECAR_LISTEN).
+
+Comma-separated; TCP addresses use SIDECAR_PORT:
+
+- ``[::]`` (default): every address, IPv6 and IPv4 (dual-stack), so the
+  API can reach the pod's IP in IPv4, IPv6-only and dual-stack clusters.
+  Where IPv6 is disabled in the kernel it falls back to ``0.0.0.0``.
+- ``0.0.0.0``: every IPv4 address only
+- ``loopback``: 127.0.0.1 and [::1]
+- ``[::1]``, ``127.0.0.1`` or any other address: just that one
+- ``unix:/path/to/socket``: a Unix socket, e.g. in a volume shared with
+  another container of the pod
+
+The API (and the pod's probes) reach the sidecar at the pod's IP, so
+pods it manages need a wildcard address; loopback addresses and Unix
+sockets are for sidecars fronted by something else in the pod.
+"""
+
+import errno
+import ipaddress
+import os
+import socket
+import stat
+from dataclasses import dataclass
+
+DEFAULT = "[::]"
+BACKLOG = 2048
+
+
+class ListenSpecError(ValueError):
+    """An invalid SIDECAR_LISTEN value."""
+
+
+@dataclass(frozen=True)
+class Listener:
+    """A TCP address (``host``) or Unix socket (``path``) to listen on."""
+
+    host: str | None = None
+    path: str | None = None
+
+    def describe(self, port: int) -> str:
+        if self.path:
+            return f"unix:{self.path}"
+        return f"[{self.host}]:{port}" if ":" in self.host else f"{self.host}:{port}"
+
+
+def parse(spec: str) -> list[Listener]:
+    """The listeners of a SIDECAR_LISTEN value (DEFAULT when it's empty)."""
+    listeners: list[Listener] = []
+    for item in filter(None, (part.strip() for part in (spec or DEFAULT).split(","))):
+        if item.startswith("unix:"):
+            path = item[len("unix:") :]
+            if not os.path.isabs(path):
+                raise ListenSpecError(f"Unix socket path must be absolute: {item!r}")
+            found = [Listener(path=path)]
+        elif item == "loopback":
+            found = [Listener(host="127.0.0.1"), Listener(host="::1")]
+        else:
+            host = item[1:-1] if item.startswith("[") and item.endswith("]") else item
+            try:
+                ipaddress.ip_address(host)
+            except ValueError:
+                raise ListenSpecError(f"Invalid listen address {item!r}; expected an IP, loopback or unix:/path")
+            found = [Listener(host=host)]
+        listeners.extend(listener for listener in found if listener not in listeners)
+    return listeners
+
+
+def bind_tcp(host: str, port: int) -> socket.socket:
+    """A socket bound to ``host``; ``::`` accepts IPv4 too, other IPv6 addresses only IPv6."""
+    family = socket.AF_INET6 if ":" in host else socket.AF_INET
+    sock = socket.socket(family, socket.SOCK_STREAM)
+    try:
+        sock.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+        if family == socket.AF_INET6:
+            sock.setsockopt(socket.IPPROTO_IPV6, socket.IPV6_V6ONLY, 0 if host == "::" else 1)
+        sock.bind((host, port))
+    except OSError:
+        sock.close()
+        raise
+    return sock
+
+
+def bind_unix(path: str) -> socket.socket:
+    """A socket bound to ``path``, replacing a stale socket left there by a previous run."""
+    try:
+        if stat.S_ISSOCK(os.stat(path).st_mode):
+            os.unlink(path)
+    except FileNotFoundError:
+        pass
+    os.makedirs(os.path.dirname(path), exist_ok=True)
+    sock = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+    try:
+        sock.bind(path)
+        os.chmod(path, 0o660)
+    except OSError:
+        sock.close()
+        raise
+    return sock
+
+
+def bind_all(listeners: list[Listener], port: int) -> tuple[list[socket.socket], list[str]]:
+    """Sockets for every listener, and what each listens on.
+
+    ``::`` falls back to 0.0.0.0 when the kernel has no IPv6; any other
+    address that can't be bound is an error.
+    """
+    sockets: list[socket.socket] = []
+    addresses: list[str] = []
+    try:
+        for listener in listeners:
+            if listener.path:
+                sockets.append(bind_unix(listener.path))
+                addresses.append(listener.describe(port))
+                continue
+            try:
+                sockets.append(bind_tcp(listener.host, port))
+            except OSError as e:
+                if listener.host != "::" or e.errno not in (errno.EAFNOSUPPORT, errno.EADDRNOTAVAIL):
+                    raise
+                listener = Listener(host="0.0.0.0")
+                sockets.append(bind_tcp(listener.host, port))
+            addresses.append(listener.describe(port))
+    except OSError:
+        for sock in sockets:
+            sock.close()
+        raise
+    for sock in sockets:
+        sock.listen(BACKLOG)
+    return sockets, addresses
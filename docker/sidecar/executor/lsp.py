@@ -0,0 +1,408 @@
+"""Language servers for the /lsp endpoints.
+
+A language server (pyright, gopls or typescript-language-server) is
+started in the main container on the first request for a file of its
+language, rooted at the working directory or the workspace the file is in,
+and kept running so later requests are fast. Before each request the
+file is read from disk and sent to the server (didOpen, then didChange
+when its content changed), so the server sees what executions and the
+file API wrote without clients syncing documents themselves. Servers
+idle for longer than the idle timeout are shut down.
+
+Lines and columns in requests and responses are 1-based, like the rest of
+the sidecar's API; LSP's 0-based positions are converted here.
+"""
+
+import asyncio
+import json
+import time
+from collections.abc import Awaitable, Callable
+from pathlib import Path
+from urllib.parse import unquote
+
+# Server name -> candidate commands, in order of preference
+SERVERS = {
+    "python": [["pyright-langserver", "--stdio"], ["pylsp"]],
+    "go": [["gopls"]],
+    "typescript": [["typescript-language-server", "--stdio"]],
+}
+
+# File extension -> (server name, LSP languageId)
+EXTENSIONS = {
+    ".py": ("python", "python"),
+    ".pyi": ("python", "python"),
+    ".go": ("go", "go"),
+    ".js": ("typescript", "javascript"),
+    ".mjs": ("typescript", "javascript"),
+    ".cjs": ("typescript", "javascript"),
+    ".jsx": ("typescript", "javascriptreact"),
+    ".ts": ("typescript", "typescript"),
+    ".tsx": ("typescript", "typescriptreact"),
+}
+
+REQUEST_TIMEOUT = 30.0
+# How long a diagnostics request waits for the server to publish them after a change
+DIAGNOSTICS_WAIT = 10.0
+DEFAULT_IDLE_TIMEOUT = 300
+
+SEVERITIES = {1: "error", 2: "warning", 3: "information", 4: "hint"}
+
+
+class LspError(Exception):
+    """A language server request that failed, with the HTTP status to answer with."""
+
+    def __init__(self, message: str, status: int = 502):
+        super().__init__(message)
+        self.status = status
+
+
+def encode(message: dict) -> bytes:
+    """Frame a JSON-RPC message with its Content-Length header."""
+    body = json.dumps(message, separators=(",", ":")).encode()
+    return f"Content-Length: {len(body)}\r\n\r\n".encode() + body
+
+
+async def read_message(reader: asyncio.StreamReader) -> dict | None:
+    """Next framed JSON-RPC message, or None at end of stream."""
+    length = None
+    while True:
+        line = await reader.readline()
+        if not line:
+            return None
+        line = line.strip()
+        if not line:
+            break
+        name, _, value = line.decode("ascii", errors="replace").partition(":")
+        if name.strip().lower() == "content-length":
+            length = int(value.strip())
+    if length is None:
+        raise LspError("Language server sent a message without Content-Length")
+    try:
+        return json.loads(await reader.readexactly(length))
+    except asyncio.IncompleteReadError:
+        return None
+
+
+def server_for(path: str) -> tuple[str, str]:
+    """Server name and languageId for a file.
+
+    Raises:
+        LspError: 415 for a file type without a language server
+    """
+    server = EXTENSIONS.get(Path(path).suffix.lower())
+    if not server:
+        raise LspError(f"No language server for {Path(path).suffix or path} files", status=415)
+    return server
+
+
+def _position(line: int, column: int) -> dict:
+    return {"line": max(0, line - 1), "character": max(0, column - 1)}
+
+
+def _range(lsp_range: dict) -> dict:
+    start, end = lsp_range.get("start", {}), lsp_range.get("end", {})
+    return {
+        "line": start.get("line", 0) + 1,
+        "column": start.get("character", 0) + 1,
+        "end_line": end.get("line", 0) + 1,
+        "end_column": end.get("character", 0) + 1,
+    }
+
+
+def _hover_text(contents) -> str:
+    """Text of a hover's MarkupContent, MarkedString or list of MarkedStrings."""
+    if isinstance(contents, str):
+        return contents
+    if isinstance(contents, list):
+        return "\n\n".join(filter(None, (_hover_text(item) for item in contents)))
+    if isinstance(contents, dict):
+        return contents.get("value", "")
+    return ""
+
+
+class LanguageServer:
+    """One running language server process and the documents it has open."""
+
+    def __init__(self, name: str, command: list[str], root: Path, process):
+        self.name = name
+        self.command = command
+        self.root = root
+        self.process = process
+        self.started_at = time.time()
+        self.last_used = time.time()
+        self._next_id = 0
+        self._pending: dict[int, asyncio.Future] = {}
+        self._documents: dict[str, tuple[int, str]] = {}  # uri -> (version, text)
+        self._diagnostics: dict[str, list[dict]] = {}
+        self._published: dict[str, asyncio.Event] = {}
+        self._write_lock = asyncio.Lock()
+        self._reader: asyncio.Task | None = None
+
+    @property
+    def alive(self) -> bool:
+        return self.process.returncode is None
+
+    def uri(self, path: Path) -> str:
+        return path.resolve().as_uri()
+
+    def relative(self, uri: str) -> str:
+        """Path of a result location, relative to the root when it's inside it."""
+        path = Path(unquote(uri.removeprefix("file://")))
+        return path.relative_to(self.root).as_posix() if path.is_relative_to(self.root) else str(path)
+
+    async def start(self) -> None:
+        """Start reading the server's output and run the initialize handshake."""
+        self._reader = asyncio.create_task(self._read_loop())
+        await self.request("initialize", {
+            "processId": None,
+            "rootUri": self.root.as_uri(),
+            "workspaceFolders": [{"uri": self.root.as_uri(), "name": self.root.name or "workspace"}],
+            "capabilities": {
+                "workspace": {"configuration": True, "workspaceFolders": True},
+                "textDocument": {
+                    "synchronization": {"didSave": False},
+                    "publishDiagnostics": {"relatedInformation": False},
+                    "hover": {"contentFormat": ["markdown", "plaintext"]},
+                    "definition": {"linkSupport": False},
+                },
+            },
+        })
+        await self.notify("initialized", {})
+
+    async def _send(self, message: dict) -> None:
+        async with self._write_lock:
+            self.process.stdin.write(encode({"jsonrpc": "2.0", **message}))
+            await self.process.stdin.drain()
+
+    async def request(self, method: str, params: dict, timeout: float = REQUEST_TIMEOUT):
+        """Send a request and wait for its result.
+
+        Raises:
+            LspError: 502 for an error response or a dead server, 504 on timeout
+        """
+        if not self.alive:
+            raise LspError(f"{self.name} language server has exited")
+        self._next_id += 1
+        request_id = self._next_id
+        future = asyncio.get_running_loop().create_future()
+        self._pending[request_id] = future
+        try:
+            await self._send({"id": request_id, "method": method, "params": params})
+            response = await asyncio.wait_for(future, timeout)
+        except TimeoutError:
+            raise LspError(f"{self.name} language server did not answer {method} in {timeout:g}s", status=504)
+        except (BrokenPipeError, ConnectionResetError):
+            raise LspError(f"{self.name} language server has exited")
+        finally:
+            self._pending.pop(request_id, None)
+        if "error" in response:
+            raise LspError(f"{method} failed: {response['error'].get('message', 'unknown error')}")
+        return response.get("result")
+
+    async def notify(self, method: str, params: dict) -> None:
+        await self._send({"method": method, "params": params})
+
+    async def _read_loop(self) -> None:
+        try:
+            while (message := await read_message(self.process.stdout)) is not None:
+                await self._dispatch(message)
+        except (LspError, ValueError):
+            pass
+        finally:
+            for future in self._pending.values():
+                if not future.done():
+                    future.set_result({"error": {"message": f"{self.name} language server has exited"}})
+
+    async def _dispatch(self, message: dict) -> None:
+        method = message.get("method")
+        if method is None:
+            future = self._pending.get(message.get("id"))
+            if future and not future.done():
+                future.set_result(message)
+        elif method == "textDocument/publishDiagnostics":
+            uri = message.get("params", {}).get("uri", "")
+            self._diagnostics[uri] = message["params"].get("diagnostics", [])
+            self._published.setdefault(uri, asyncio.Event()).set()
+        elif "id" in message:
+            # Requests from the server: answer configuration with defaults, accept everything else
+            items = message.get("params", {}).get("items", [])
+            result = [None] * len(items) if method == "workspace/configuration" else None
+            await self._send({"id": message["id"], "result": result})
+
+    @staticmethod
+    def _read(path: Path) -> str:
+        try:
+            return path.read_text(errors="replace")
+        except (FileNotFoundError, IsADirectoryError):
+            raise LspError("File not found", status=404)
+
+    async def sync(self, path: Path, language_id: str, text: str | None = None) -> tuple[str, bool]:
+        """Send the file's current content to the server; returns its uri and whether it changed.
+
+        Raises:
+            LspError: 404 if the file doesn't exist
+        """
+        text = self._read(path) if text is None else text
+        uri = self.uri(path)
+        self.last_used = time.time()
+        opened = self._documents.get(uri)
+        if opened is None:
+            self._documents[uri] = (1, text)
+            await self.notify("textDocument/didOpen", {
+                "textDocument": {"uri": uri, "languageId": language_id, "version": 1, "text": text},
+            })
+            return uri, True
+        version, previous = opened
+        if previous == text:
+            return uri, False
+        self._documents[uri] = (version + 1, text)
+        await self.notify("textDocument/didChange", {
+            "textDocument": {"uri": uri, "version": version + 1},
+            "contentChanges": [{"text": text}],
+        })
+        return uri, True
+
+    async def diagnostics(self, path: Path, language_id: str, wait: float = DIAGNOSTICS_WAIT) -> list[dict]:
+        """The file's diagnostics, waiting for the server to publish them if the file changed."""
+        text = self._read(path)
+        uri = self.uri(path)
+        published = self._published.setdefault(uri, asyncio.Event())
+        # Cleared before the change is sent, so only diagnostics for the new content count
+        fresh = uri not in self._documents or self._documents[uri][1] != text
+        if fresh:
+            published.clear()
+        await self.sync(path, language_id, text)
+        if fresh or uri not in self._diagnostics:
+            try:
+                await asyncio.wait_for(published.wait(), wait)
+            except TimeoutError:
+                pass
+        return [
+            {
+                **_range(d.get("range", {})),
+                "severity": SEVERITIES.get(d.get("severity", 1), "error"),
+                "message": d.get("message", ""),
+                "source": d.get("source"),
+                "code": d.get("code"),
+            }
+            for d in self._diagnostics.get(uri, [])
+        ]
+
+    async def hover(self, path: Path, language_id: str, line: int, column: int) -> dict | None:
+        uri, _ = await self.sync(path, language_id)
+        result = await self.request("textDocument/hover", {
+            "textDocument": {"uri": uri},
+            "position": _position(line, column),
+        })
+        if not result:
+            return None
+        hover = {"contents": _hover_text(result.get("contents"))}
+        if result.get("range"):
+            hover.update(_range(result["range"]))
+        return hover
+
+    async def definition(self, path: Path, language_id: str, line: int, column: int) -> list[dict]:
+        uri, _ = await self.sync(path, language_id)
+        result = await self.request("textDocument/definition", {
+            "textDocument": {"uri": uri},
+            "position": _position(line, column),
+        })
+        if not result:
+            return []
+        locations = result if isinstance(result, list) else [result]
+        return [
+            {
+                "path": self.relative(location.get("uri") or location.get("targetUri", "")),
+                **_range(location.get("range") or location.get("targetSelectionRange") or {}),
+            }
+            for location in locations
+        ]
+
+    async def stop(self) -> None:
+        """Shut the server down politely, killing it if it doesn't exit."""
+        if self.alive:
+            try:
+                await self.request("shutdown", {}, timeout=5)
+                await self.notify("exit", {})
+                await asyncio.wait_for(self.process.wait(), 5)
+            except (LspError, TimeoutError, OSError):
+                pass
+        if self.alive:
+            self.process.kill()
+            await self.process.wait()
+        if self._reader:
+            self._reader.cancel()
+
+    def status(self) -> dict:
+        return {
+            "server": self.name,
+            "command": self.command,
+            "root": str(self.root),
+            "alive": self.alive,
+            "open_documents": len(self._documents),
+            "started_at": self.started_at,
+            "last_used": self.last_used,
+        }
+
+
+class LspManager:
+    """Starts language servers on demand, one per server and root, and stops idle ones.
+
+    ``spawn(command, root)`` starts a server process with piped stdin and
+    stdout; ``available(names)`` returns which binaries can be run, so the
+    first installed candidate of a server is used.
+    """
+
+    def __init__(
+        self,
+        spawn: Callable[[list[str], Path], Awaitable[object]],
+        available: Callable[[list[str]], Awaitable[set[str]]],
+    ):
+        self._spawn = spawn
+        self._available = available
+        self._servers: dict[tuple[str, Path], LanguageServer] = {}
+        self._lock = asyncio.Lock()
+
+    async def get(self, name: str, root: Path) -> LanguageServer:
+        """The running server for a root, started if needed.
+
+        Raises:
+            LspError: 503 if none of the server's commands is installed
+        """
+        root = root.resolve()
+        async with self._lock:
+            server = self._servers.get((name, root))
+            if server and server.alive:
+                return server
+            candidates = SERVERS[name]
+            installed = await self._available([command[0] for command in candidates])
+            command = next((c for c in candidates if c[0] in installed), None)
+            if command is None:
+                names = ", ".join(c[0] for c in candidates)
+                raise LspError(f"No {name} language server installed (looked for {names})", status=503)
+            process = await self._spawn(command, root)
+            server = LanguageServer(name, command, root, process)
+            try:
+                await server.start()
+            except LspError:
+                await server.stop()
+                raise
+            self._servers[(name, root)] = server
+            return server
+
+    async def stop_idle(self, max_idle: float, now: float | None = None) -> list[LanguageServer]:
+        """Stop servers unused for longer than ``max_idle`` seconds (and dead ones); returns them."""
+        now = time.time() if now is None else now
+        stopped = [s for s in self._servers.values() if not s.alive or now - s.last_used > max_idle]
+        for server in stopped:
+            self._servers.pop((server.name, server.root), None)
+            await server.stop()
+        return stopped
+
+    async def stop_all(self) -> None:
+        servers, self._servers = list(self._servers.values()), {}
+        for server in servers:
+            await server.stop()
+
+    def status(self) -> list[dict]:
+        return [server.status() for server in self._servers.values()]
@@ -0,0 +1,150 @@
+"""FFmpeg media profile for the /media endpoint.
+
+Builds ffmpeg invocations that report machine-readable progress
+(``-progress pipe:1``) and turns that key=value stream into structured
+progress events, so long conversions don't look like a silent hang.
+"""
+
+import re
+from dataclasses import dataclass, field
+
+# Options the sidecar controls itself; letting callers override them would
+# break progress parsing or the output size limit.
+RESERVED_OPTIONS = frozenset({"-progress", "-stats", "-nostats", "-fs", "-y", "-n", "-i"})
+
+# Options that write files besides the output. -fs only limits the output
+# it precedes, so these would escape the size limit; -f is here because
+# muxers such as tee, segment and hls write extra files of their own.
+FILE_WRITING_OPTIONS = frozenset({"-f", "-vstats", "-vstats_file", "-passlogfile", "-dump_attachment", "-report"})
+
+# Output options that take no value. Every other option consumes the next
+# arg, so anything left over is a positional arg, which ffmpeg would treat
+# as another output path.
+FLAG_OPTIONS = frozenset({
+    "-an", "-vn", "-sn", "-dn", "-shortest", "-copyts", "-start_at_zero", "-copy_unknown",
+    "-bitexact", "-xerror", "-hide_banner", "-nostdin", "-autorotate", "-noautorotate",
+    "-autoscale", "-noautoscale", "-accurate_seek", "-noaccurate_seek", "-fix_sub_duration",
+    "-ignore_unknown", "-qphist", "-benchmark", "-benchmark_all", "-debug_ts", "-re",
+})
+
+_DURATION_RE = re.compile(r"Duration:\s*(\d+):(\d{2}):(\d{2}(?:\.\d+)?)")
+
+
+def build_ffmpeg_command(input_path: str, output_path: str, args: list[str], max_output_bytes: int) -> list[str]:
+    """Build the ffmpeg command for a single input/output conversion.
+
+    Caller-supplied args are placed between the input and the output so they
+    act as output options (codecs, filters, bitrates). ``-fs`` makes ffmpeg
+    itself stop writing once the output reaches the size limit, and ``-n``
+    makes it fail rather than overwrite a file that appeared at the output
+    path after the caller checked it was free.
+
+    Raises:
+        ValueError: If args contain options reserved by the sidecar, options
+            that write other files, or extra output paths
+    """
+    check_args(args)
+    return [
+        "ffmpeg",
+        "-hide_banner",
+        "-nostdin",
+        "-nostats",
+        "-n",
+        "-progress",
+        "pipe:1",
+        "-i",
+        input_path,
+        *args,
+        "-fs",
+        str(max_output_bytes),
+        output_path,
+    ]
+
+
+def check_args(args: list[str]) -> None:
+    """Reject args that would override the sidecar or write beyond the one output."""
+    disallowed = sorted({arg for arg in args if arg in RESERVED_OPTIONS or arg in FILE_WRITING_OPTIONS})
+    if disallowed:
+        raise ValueError(f"Options not allowed in args: {', '.join(disallowed)}")
+
+    index = 0
+    while index < len(args):
+        arg = args[index]
+        if not arg.startswith("-") or arg == "-":
+            raise ValueError(f"Extra output paths are not allowed in args: {arg}")
+        # Values are taken as-is, so negative numbers and "-" are fine after an option
+        index += 1 if arg in FLAG_OPTIONS else 2
+
+
+def parse_duration(line: str) -> float | None:
+    """Extract the input duration in seconds from an ffmpeg stderr line."""
+    match = _DURATION_RE.search(line)
+    if not match:
+        return None
+    hours, minutes, seconds = match.groups()
+    return int(hours) * 3600 + int(minutes) * 60 + float(seconds)
+
+
+def _to_int(value: str | None) -> int | None:
+    try:
+        return int(value) if value not in (None, "", "N/A") else None
+    except ValueError:
+        return None
+
+
+def _to_float(value: str | None) -> float | None:
+    try:
+        return float(value.rstrip("x")) if value not in (None, "", "N/A") else None
+    except ValueError:
+        return None
+
+
+@dataclass
+class ProgressParser:
+    """Accumulates ``-progress`` key=value lines into progress events.
+
+    ffmpeg emits one block of key=value pairs per update, terminated by a
+    ``progress=continue`` or ``progress=end`` line.
+    """
+
+    duration_seconds: float | None = None
+    _block: dict[str, str] = field(default_factory=dict)
+
+    def feed(self, line: str) -> dict | None:
+        """Consume one line; return a progress event when a block completes."""
+        line = line.strip()
+        if "=" not in line:
+            return None
+        key, value = line.split("=", 1)
+        key, value = key.strip(), value.strip()
+        if key != "progress":
+            self._block[key] = value
+            return None
+
+        block, self._block = self._block, {}
+        return self._event(block, done=value == "end")
+
+    def _event(self, block: dict[str, str], done: bool) -> dict:
+        # out_time_us is authoritative; older builds mislabel it as out_time_ms
+        out_time_us = _to_int(block.get("out_time_us"))
+        if out_time_us is None:
+            out_time_us = _to_int(block.get("out_time_ms"))
+        out_time_ms = out_time_us // 1000 if out_time_us is not None and out_time_us >= 0 else None
+
+        percent = None
+        if done:
+            percent = 100.0
+        elif out_time_ms is not None and self.duration_seconds:
+            percent = round(min(out_time_ms / (self.duration_seconds * 1000) * 100, 100.0), 1)
+
+        return {
+            "type": "progress",
+            "done": done,
+            "percent": percent,
+            "out_time_ms": out_time_ms,
+            "duration_ms": int(self.duration_seconds * 1000) if self.duration_seconds else None,
+            "frame": _to_int(block.get("frame")),
+            "fps": _to_float(block.get("fps")),
+            "speed": _to_float(block.get("speed")),
+            "total_size": _to_int(block.get("total_size")),
+        }
@@ -0,0 +1,72 @@
+"""The policy bundle the pod runs under (POLICY_BUNDLE).
+
+The API sends its policy bundle (see src/config/policy.py) as compact
+JSON. The sidecar reports its name, version and digest in /health and
+the support bundle, so the policy every pod enforces can be audited, and
+takes its defaults from it: the bundle's max_execution_time and
+dns_allowlist apply when MAX_EXECUTION_TIME or DNS_ALLOWLIST aren't set.
+The API sets both from the same bundle, but per pod (e.g. an elevated
+grant's longer timeout), so they win when present.
+
+The digest is sha256 of the canonical JSON (sorted keys, no whitespace),
+the same as the API reports at GET /admin/policy.
+"""
+
+import hashlib
+import json
+from dataclasses import dataclass, field
+from typing import Any
+
+
+class PolicyBundleError(ValueError):
+    """An invalid POLICY_BUNDLE value."""
+
+
+@dataclass(frozen=True)
+class Policy:
+    name: str
+    version: str
+    digest: str
+    limits: dict[str, Any] = field(default_factory=dict)
+    network: dict[str, Any] = field(default_factory=dict)
+
+    def describe(self) -> dict[str, str]:
+        return {"name": self.name, "version": self.version, "digest": self.digest}
+
+
+def load(text: str) -> Policy | None:
+    """The policy of a POLICY_BUNDLE value, or None when it's empty."""
+    if not text.strip():
+        return None
+    try:
+        bundle = json.loads(text)
+    except ValueError as e:
+        raise PolicyBundleError(f"POLICY_BUNDLE is not valid JSON: {e}")
+    if not isinstance(bundle, dict):
+        raise PolicyBundleError("POLICY_BUNDLE must be a JSON object")
+    name, version = bundle.get("name"), bundle.get("version")
+    if not isinstance(name, str) or not name or not isinstance(version, str) or not version:
+        raise PolicyBundleError("POLICY_BUNDLE needs a name and a version")
+    limits, network = bundle.get("limits") or {}, bundle.get("network") or {}
+    if not isinstance(limits, dict) or not isinstance(network, dict):
+        raise PolicyBundleError("POLICY_BUNDLE limits and network must be objects")
+
+    canonical = json.dumps(bundle, sort_keys=True, separators=(",", ":"))
+    digest = "sha256:" + hashlib.sha256(canonical.encode("utf-8")).hexdigest()
+    return Policy(name=name, version=version, digest=digest, limits=limits, network=network)
+
+
+def max_execution_time(env: str, policy: Policy | None, default: int) -> int:
+    """MAX_EXECUTION_TIME: the environment's value, else the bundle's limit, else ``default``."""
+    if env:
+        return int(env)
+    value = policy.limits.get("max_execution_time") if policy else None
+    return int(value) if value else default
+
+
+def dns_allowlist(env: str | None, policy: Policy | None) -> list[str]:
+    """DNS_ALLOWLIST: the environment's names when it's set (even empty), else the bundle's."""
+    if env is not None:
+        return [name.strip() for name in env.split(",") if name.strip()]
+    names = policy.network.get("dns_allowlist") if policy else None
+    return [str(name) for name in names or []]
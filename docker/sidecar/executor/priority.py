@@ -0,0 +1,144 @@
+"""Lower OOM, CPU and I/O priority for background executions.
+
+Executions in a pod share the sidecar's cgroup, so when the pod runs out of
+memory, CPU or disk bandwidth they compete with each other. A request can
+mark itself as background work: a higher ``oom_score_adj`` makes the
+kernel's OOM killer pick it before the interactive executions, a lower
+``cpu_weight`` or a higher ``nice`` gives it a smaller share of the CPU, an
+``io_class``/``io_priority`` puts its disk I/O behind everyone else's, and
+``cpu_affinity`` keeps it off the CPUs the interactive work uses.
+
+All of it is applied to the spawned process before it execs, and inherited
+by everything it starts. Priorities can only be lowered: raising
+``oom_score_adj`` and the nice value, and lowering the I/O priority, need
+no privileges, while the opposite would let a request escape the pod's own
+settings.
+"""
+
+import ctypes
+import os
+import platform
+
+# cgroup v2 cpu.weight of a process at nice 0
+DEFAULT_CPU_WEIGHT = 100
+
+# The kernel's sched_prio_to_weight table: CFS weight of nice -20..19
+# fmt: off
+NICE_WEIGHTS = [
+    88761, 71755, 56483, 46273, 36291, 29154, 23254, 18705, 14949, 11916,
+    9548, 7620, 6100, 4904, 3906, 3121, 2501, 1991, 1586, 1277,
+    1024, 820, 655, 526, 423, 335, 272, 215, 172, 137,
+    110, 87, 70, 56, 45, 36, 29, 23, 18, 15,
+]
+# fmt: on
+
+OOM_SCORE_ADJ_PATH = "/proc/self/oom_score_adj"
+
+# ioprio_set(2): classes, and the best-effort level of a process at nice 0
+IOPRIO_CLASSES = {"best-effort": 2, "idle": 3}
+IOPRIO_CLASS_SHIFT = 13
+IOPRIO_WHO_PROCESS = 1
+DEFAULT_IO_PRIORITY = 4
+LOWEST_IO_PRIORITY = 7
+# ioprio_set has no libc wrapper, so it's called by number
+IOPRIO_SET_SYSCALLS = {"x86_64": 251, "aarch64": 30, "arm64": 30, "i686": 289, "armv7l": 314}
+
+AFFINITY_ERROR_CODE = "INVALID_CPU_AFFINITY"
+
+
+def nice_for_weight(weight: int) -> int:
+    """Nice value (0..19) whose CFS weight is closest to a cpu.weight.
+
+    A pod can't create child cgroups, so the weight is applied the way the
+    kernel maps cgroup weights to nice values (cpu.weight.nice): 100 is
+    nice 0, and each nice step is about 1.25 times less CPU.
+    """
+    target = NICE_WEIGHTS[20] * weight / DEFAULT_CPU_WEIGHT
+    return min(range(20), key=lambda nice: abs(NICE_WEIGHTS[20 + nice] - target))
+
+
+def raise_oom_score_adj(value: int, path: str = OOM_SCORE_ADJ_PATH) -> None:
+    """Raise this process's OOM score adjustment to at least ``value``."""
+    with open(path) as f:
+        current = int(f.read().strip() or 0)
+    if value > current:
+        with open(path, "w") as f:
+            f.write(str(value))
+
+
+def set_io_priority(io_class: str, level: int) -> None:
+    """Set this process's I/O scheduling class (and best-effort level)."""
+    number = IOPRIO_SET_SYSCALLS.get(platform.machine())
+    if number is None:
+        raise OSError(f"ioprio_set isn't supported on {platform.machine()}")
+    value = IOPRIO_CLASSES[io_class] << IOPRIO_CLASS_SHIFT | (level if io_class == "best-effort" else 0)
+    libc = ctypes.CDLL(None, use_errno=True)
+    if libc.syscall(number, IOPRIO_WHO_PROCESS, 0, value) != 0:
+        raise OSError(ctypes.get_errno(), "ioprio_set failed")
+
+
+def format_cpus(cpus: list[int]) -> str:
+    """CPU list in the kernel's format: 0-3,6."""
+    ranges: list[list[int]] = []
+    for cpu in sorted(set(cpus)):
+        if ranges and cpu == ranges[-1][1] + 1:
+            ranges[-1][1] = cpu
+        else:
+            ranges.append([cpu, cpu])
+    return ",".join(str(a) if a == b else f"{a}-{b}" for a, b in ranges)
+
+
+def affinity_error(cpus: list[int], online: set[int]) -> dict | None:
+    """INVALID_CPU_AFFINITY error if the mask names CPUs this pod can't run on."""
+    unavailable = sorted(set(cpus) - online)
+    if not unavailable:
+        return None
+    return {
+        "code": AFFINITY_ERROR_CODE,
+        "message": f"CPUs {format_cpus(unavailable)} aren't available; online: {format_cpus(sorted(online))}",
+    }
+
+
+def make_preexec(
+    oom_score_adj: int | None = None,
+    cpu_weight: int | None = None,
+    nice: int | None = None,
+    io_class: str | None = None,
+    io_priority: int | None = None,
+    cpu_affinity: list[int] | None = None,
+):
+    """A preexec_fn lowering the spawned process's priority, or None if there's nothing to do.
+
+    With both ``cpu_weight`` and ``nice`` the lower priority wins. An
+    ``io_priority`` without an ``io_class`` is a best-effort level.
+    """
+    nice = max(nice or 0, nice_for_weight(cpu_weight) if cpu_weight is not None else 0)
+    if io_class is None and io_priority is not None:
+        io_class = "best-effort"
+    if not (oom_score_adj or nice or io_class or cpu_affinity):
+        return None
+
+    def apply() -> None:
+        # Best effort: an unsupported setting mustn't stop the execution
+        if oom_score_adj:
+            try:
+                raise_oom_score_adj(oom_score_adj)
+            except (OSError, ValueError):
+                pass
+        if nice:
+            try:
+                os.nice(nice)
+            except OSError:
+                pass
+        if io_class:
+            try:
+                set_io_priority(io_class, DEFAULT_IO_PRIORITY if io_priority is None else io_priority)
+            except OSError:
+                pass
+        if cpu_affinity:
+            try:
+                os.sched_setaffinity(0, cpu_affinity)
+            except OSError:
+                pass
+
+    return apply
@@ -0,0 +1,132 @@
+"""Which interpreter build ran an execution.
+
+For every execution the sidecar records the command it ran (without the
+environment, which may hold secrets) and, for each binary the language
+needs, its absolute path in the main container, the sha256 of the file
+and its version output. Post-incident analysis can then prove exactly
+which build ran the code, even after the image was replaced.
+
+Paths are resolved in the main container's filesystem through
+/proc/<pid>/root, following symlinks inside it. Hashes and versions are
+cached by path, inode, size and mtime, so only the first execution after
+a binary changes pays for them.
+"""
+
+import asyncio
+import hashlib
+import os
+import re
+from collections.abc import Awaitable, Callable
+
+from . import runtime
+
+MAX_SYMLINKS = 40
+VERSION_TIMEOUT = 10
+
+# Arguments printing each binary's version; others get --version
+VERSION_ARGS = {
+    "go": ["version"],
+    "javac": ["-version"],
+    "java": ["-version"],
+}
+
+ENV_ASSIGNMENT = re.compile(r"^[A-Za-z_][A-Za-z0-9_]*=")
+
+# (exit_code, stdout, stderr) of a command run in the main container
+RunCommand = Callable[[list[str], int], Awaitable[tuple[int, str, str]]]
+
+
+def resolve_in_root(root: str, path: str) -> str | None:
+    """The absolute path ``path`` resolves to inside ``root``, or None if it doesn't exist.
+
+    Like realpath in a chroot: absolute symlink targets are relative to root.
+    """
+    parts = [p for p in path.split("/") if p]
+    resolved: list[str] = []
+    hops = 0
+    while parts:
+        part = parts.pop(0)
+        if part == ".":
+            continue
+        if part == "..":
+            if resolved:
+                resolved.pop()
+            continue
+        host = os.path.join(root, *resolved, part)
+        if os.path.islink(host):
+            hops += 1
+            if hops > MAX_SYMLINKS:
+                return None
+            target = os.readlink(host)
+            if target.startswith("/"):
+                resolved = []
+            parts = [p for p in target.split("/") if p] + parts
+            continue
+        if not os.path.lexists(host):
+            return None
+        resolved.append(part)
+    return "/" + "/".join(resolved)
+
+
+def which(root: str, name: str, path_env: str) -> str | None:
+    """Resolved path of the executable ``name`` on ``path_env`` inside ``root``."""
+    for directory in path_env.split(":"):
+        if not directory:
+            continue
+        resolved = resolve_in_root(root, os.path.join(directory, name))
+        if resolved:
+            host = os.path.join(root, resolved.lstrip("/"))
+            if os.path.isfile(host) and os.access(host, os.X_OK):
+                return resolved
+    return None
+
+
+def strip_env(cmd: list[str]) -> list[str]:
+    """The command without its ``/usr/bin/env -i NAME=value...`` wrapper."""
+    if cmd[:2] != ["/usr/bin/env", "-i"]:
+        return list(cmd)
+    rest = cmd[2:]
+    while rest and ENV_ASSIGNMENT.match(rest[0]):
+        rest = rest[1:]
+    return rest
+
+
+def sha256_file(path: str) -> str:
+    digest = hashlib.sha256()
+    with open(path, "rb") as f:
+        for chunk in iter(lambda: f.read(1 << 20), b""):
+            digest.update(chunk)
+    return digest.hexdigest()
+
+
+class ProvenanceCache:
+    """Hashes and versions of binaries, by path and file identity."""
+
+    def __init__(self, run: RunCommand):
+        self.run = run
+        self._entries: dict[tuple, dict] = {}
+
+    async def describe(self, root: str, name: str, path: str) -> dict:
+        host = os.path.join(root, path.lstrip("/"))
+        stat = os.stat(host)
+        key = (path, stat.st_ino, stat.st_size, stat.st_mtime_ns)
+        if key not in self._entries:
+            sha256 = await asyncio.to_thread(sha256_file, host)
+            exit_code, stdout, stderr = await self.run([path, *VERSION_ARGS.get(name, ["--version"])], VERSION_TIMEOUT)
+            lines = (stdout.strip() or stderr.strip()).splitlines()
+            self._entries[key] = {
+                "sha256": sha256,
+                "version": lines[0][:200] if exit_code == 0 and lines else None,
+            }
+        return {"name": name, "path": path, **self._entries[key]}
+
+    async def record(self, root: str, path_env: str, cmd: list[str], language: str) -> dict:
+        """Provenance of an execution: its command and the language's binaries."""
+        binaries = []
+        for name in runtime.LANGUAGE_BINARIES.get(runtime.LANGUAGE_ALIASES.get(language, language), []):
+            path = which(root, name, path_env)
+            if path:
+                binaries.append(await self.describe(root, name, path))
+            else:
+                binaries.append({"name": name, "path": None, "sha256": None, "version": None})
+        return {"command": strip_env(cmd), "binaries": binaries}
@@ -0,0 +1,147 @@
+"""Document rendering toolchains for the /render endpoint.
+
+Maps a (source format, output format) pair to the commands that can produce
+it, in order of preference. The sidecar probes the main container for the
+required binaries and runs the first toolchain that is fully available, so
+images only need to ship whichever toolchain they prefer.
+"""
+
+import re
+from collections.abc import Callable
+from dataclasses import dataclass
+from pathlib import PurePosixPath
+
+SOURCE_EXTENSIONS = {
+    "latex": ".tex",
+    "markdown": ".md",
+}
+
+OUTPUT_EXTENSIONS = {
+    "pdf": ".pdf",
+    "html": ".html",
+}
+
+# Characters allowed in rendered artifact names (anything else becomes "_")
+_SAFE_NAME_RE = re.compile(r"[^A-Za-z0-9._-]")
+
+
+@dataclass(frozen=True)
+class Toolchain:
+    """A command able to convert one document format to another.
+
+    Attributes:
+        name: Human-readable identifier reported back to clients
+        requires: Binaries that must exist in the main container
+        args: Command template; {source}, {output} and {outdir} are substituted
+    """
+
+    name: str
+    requires: tuple[str, ...]
+    args: tuple[str, ...]
+
+    def command(self, source: str, output: str) -> list[str]:
+        """Build the concrete command for a source file and output path."""
+        outdir = str(PurePosixPath(output).parent)
+        return [arg.format(source=source, output=output, outdir=outdir) for arg in self.args]
+
+
+_LATEX_FLAGS = ("-interaction=nonstopmode", "-halt-on-error")
+
+TOOLCHAINS: dict[tuple[str, str], list[Toolchain]] = {
+    ("latex", "pdf"): [
+        Toolchain("latexmk", ("latexmk", "pdflatex"), ("latexmk", "-pdf", *_LATEX_FLAGS, "-outdir={outdir}", "{source}")),
+        Toolchain("pdflatex", ("pdflatex",), ("pdflatex", *_LATEX_FLAGS, "-output-directory={outdir}", "{source}")),
+        Toolchain("xelatex", ("xelatex",), ("xelatex", *_LATEX_FLAGS, "-output-directory={outdir}", "{source}")),
+        Toolchain("tectonic", ("tectonic",), ("tectonic", "--outdir", "{outdir}", "{source}")),
+    ],
+    ("markdown", "html"): [
+        Toolchain(
+            "pandoc",
+            ("pandoc",),
+            ("pandoc", "--standalone", "--from=markdown", "--to=html5", "--output={output}", "{source}"),
+        ),
+    ],
+    ("markdown", "pdf"): [
+        Toolchain(
+            f"pandoc+{engine}",
+            ("pandoc", engine),
+            ("pandoc", "--from=markdown", f"--pdf-engine={engine}", "--output={output}", "{source}"),
+        )
+        for engine in ("pdflatex", "xelatex", "weasyprint", "wkhtmltopdf")
+    ],
+}
+
+
+def supported_conversions() -> list[tuple[str, str]]:
+    """List every (source, output) pair that has at least one toolchain."""
+    return sorted(TOOLCHAINS)
+
+
+def required_binaries(source_format: str, output_format: str) -> list[str]:
+    """All binaries worth probing for a conversion, without duplicates."""
+    seen: dict[str, None] = {}
+    for toolchain in TOOLCHAINS.get((source_format, output_format), []):
+        for binary in toolchain.requires:
+            seen.setdefault(binary, None)
+    return list(seen)
+
+
+def select_toolchain(source_format: str, output_format: str, available: set[str]) -> Toolchain | None:
+    """Pick the first toolchain whose binaries are all available.
+
+    Args:
+        source_format: Source document format (e.g. "latex")
+        output_format: Desired output format (e.g. "pdf")
+        available: Binaries found in the main container
+
+    Returns:
+        The preferred usable toolchain, or None if none is installed
+    """
+    for toolchain in TOOLCHAINS.get((source_format, output_format), []):
+        if all(binary in available for binary in toolchain.requires):
+            return toolchain
+    return None
+
+
+def output_filename(source_name: str | None, output_format: str, requested: str | None = None) -> str:
+    """Derive a safe artifact filename for the rendered document.
+
+    An explicit name wins (its extension is forced to match the output
+    format); otherwise the source file's stem is reused, defaulting to
+    "document".
+    """
+    extension = OUTPUT_EXTENSIONS[output_format]
+    base = requested or (PurePosixPath(source_name).stem if source_name else "") or "document"
+    stem = _SAFE_NAME_RE.sub("_", PurePosixPath(base).name)
+    if stem.lower().endswith(extension):
+        stem = stem[: -len(extension)]
+    stem = stem.lstrip(".") or "document"
+    return f"{stem}{extension}"
+
+
+def unused_filename(name: str, exists: Callable[[str], bool]) -> str:
+    """``name``, or ``<stem>.rendered<ext>`` (then ``.rendered-2`` and so on) if a file already has it.
+
+    Rendering never replaces a file in the working directory, such as the
+    document's own source.
+    """
+    if not exists(name):
+        return name
+    path = PurePosixPath(name)
+    candidate = f"{path.stem}.rendered{path.suffix}"
+    number = 2
+    while exists(candidate):
+        candidate = f"{path.stem}.rendered-{number}{path.suffix}"
+        number += 1
+    return candidate
+
+
+def built_artifact_name(toolchain: Toolchain, source_name: str, output_format: str) -> str:
+    """Name of the file the toolchain writes into the build directory.
+
+    LaTeX engines name their output after the source file and ignore the
+    requested output path; pandoc writes exactly where it is told.
+    """
+    if toolchain.name.startswith("pandoc"):
+        return f"output{OUTPUT_EXTENSIONS[output_format]}"
+    return f"{PurePosixPath(source_name).stem}{OUTPUT_EXTENSIONS[output_format]}"
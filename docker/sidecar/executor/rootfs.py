@@ -0,0 +1,125 @@
+"""Running with read-only root filesystems (readOnlyRootFilesystem: true).
+
+Everything the sidecar and the code it runs write goes to one of two
+directories, so a pod whose containers have read-only root filesystems
+only needs both on writable volumes, mounted at the same path in the
+sidecar and the main container:
+
+- WORKING_DIR: code files, uploads, workspaces and render builds
+- SCRATCH_DIR: the sidecar's temp files (uploads spooled to disk), and
+  for executions TMPDIR (unless the image sets it), the binaries compiled
+  languages build, and HOME when the main container's environment can't
+  be read
+
+SCRATCH_DIR is /tmp unless set; ``<WORKING_DIR>/.scratch`` keeps it in the
+workspace volume (dot directories aren't listed as files). At startup
+the sidecar checks which directories each container can write to and
+logs the features that don't work in this pod.
+"""
+
+import os
+import time
+import uuid
+from collections.abc import Awaitable, Callable
+
+PROBE_TIMEOUT = 10
+# Languages that build in SCRATCH_DIR (go run in TMPDIR); Java builds in the working directory
+COMPILED_LANGUAGES = ("go", "rust", "rs", "c", "cpp", "fortran", "f90", "d", "dlang")
+
+# Features that stop working when a container can't write to a directory, by (container, directory)
+FEATURES = {
+    ("main", "working_dir"): ["executions"],
+    ("sidecar", "working_dir"): ["file uploads", "workspaces", "renders"],
+    ("sidecar", "scratch_dir"): ["uploads over 1 MiB"],
+    ("main", "scratch_dir"): ["compiled languages"],
+}
+
+# Prints "ok" or "not writable" for each directory argument, one per line
+MAIN_PROBE_SCRIPT = (
+    'for d; do f="$d/.rootfs-probe-$$"; '
+    'if (mkdir -p "$d" && : > "$f") 2>/dev/null; then rm -f "$f"; echo ok; else echo "not writable"; fi; done'
+)
+
+# (exit_code, stdout, stderr) of a command run in the main container
+RunCommand = Callable[[list[str], int], Awaitable[tuple[int, str, str]]]
+
+
+def is_read_only(path: str) -> bool | None:
+    """Whether the filesystem holding ``path`` is mounted read-only, or None if it can't be told."""
+    try:
+        return bool(os.statvfs(path).f_flag & os.ST_RDONLY)
+    except OSError:
+        return None
+
+
+def probe_writable(directory: str) -> str | None:
+    """None when a file can be created in ``directory`` (created if missing), else the error."""
+    path = os.path.join(directory, f".rootfs-probe-{uuid.uuid4().hex[:8]}")
+    try:
+        os.makedirs(directory, exist_ok=True)
+        with open(path, "w"):
+            pass
+        os.unlink(path)
+        return None
+    except OSError as e:
+        return e.strerror or str(e)
+
+
+async def probe_main(run: RunCommand, directories: list[str]) -> list[str | None]:
+    """``probe_writable`` for each directory, from the main container."""
+    exit_code, stdout, stderr = await run(["sh", "-c", MAIN_PROBE_SCRIPT, "sh", *directories], PROBE_TIMEOUT)
+    lines = stdout.splitlines()
+    if exit_code != 0 or len(lines) != len(directories):
+        error = (stderr.strip() or stdout.strip() or f"exit {exit_code}").splitlines()[0][:200]
+        return [f"Probe failed: {error}"] * len(directories)
+    return [None if line == "ok" else line[:200] for line in lines]
+
+
+async def check(
+    run: RunCommand,
+    directories: dict[str, str],
+    language: str,
+    main_root: str | None = None,
+) -> dict:
+    """Writability of ``directories`` ({name: path}) from both containers, and the features that degrade.
+
+    ``main_root`` is the main container's root as the sidecar sees it
+    (/proc/<pid>/root), for telling whether it's read-only.
+    """
+    start = time.perf_counter()
+    sidecar = {name: probe_writable(path) for name, path in directories.items()}
+    main = dict(zip(directories, await probe_main(run, list(directories.values()))))
+
+    degraded: list[str] = []
+    for (container, directory), features in FEATURES.items():
+        errors = sidecar if container == "sidecar" else main
+        if directory in errors and errors[directory] is not None:
+            if (container, directory) == ("main", "scratch_dir") and language not in COMPILED_LANGUAGES:
+                continue
+            degraded.extend(f for f in features if f not in degraded)
+
+    return {
+        "ok": not degraded,
+        "degraded": degraded,
+        "read_only_root": {"sidecar": is_read_only("/"), "main": is_read_only(main_root) if main_root else None},
+        "directories": {
+            name: {"path": path, "sidecar_error": sidecar[name], "main_error": main[name]}
+            for name, path in directories.items()
+        },
+        "ms": round((time.perf_counter() - start) * 1000, 1),
+    }
+
+
+def summary(report: dict) -> str:
+    """One log line for a check report."""
+    read_only = [c for c, ro in report["read_only_root"].items() if ro]
+    prefix = f"read-only root in {', '.join(read_only)}; " if read_only else ""
+    if report["ok"]:
+        return f"{prefix}all directories writable"
+    errors = [
+        f"{name} ({d['path']}): {container} {d[f'{container}_error']}"
+        for name, d in report["directories"].items()
+        for container in ("sidecar", "main")
+        if d[f"{container}_error"]
+    ]
+    return f"{prefix}degraded: {', '.join(report['degraded'])} ({'; '.join(errors)})"
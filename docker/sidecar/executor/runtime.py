@@ -0,0 +1,131 @@
+"""Missing-interpreter detection for executions.
+
+When the interpreter or compiler a language needs isn't on the main
+container's PATH, the spawn fails with a terse message such as
+``env: 'python3': No such file or directory`` or ``sh: 1: rustc: not
+found``. That reads like a bug in the submitted code, so the failure is
+turned into a structured RUNTIME_NOT_FOUND error naming the binary that
+was wanted, the similar binaries that are available, and a suggested
+substitute.
+"""
+
+import re
+
+ERROR_CODE = "RUNTIME_NOT_FOUND"
+
+# Exit status of a shell (or env) that couldn't find the command
+NOT_FOUND_EXIT_CODE = 127
+
+# Binaries each language's command runs (see get_language_command in main.py)
+LANGUAGE_BINARIES = {
+    "py": ["python"],
+    "js": ["node"],
+    "ts": ["node"],
+    "go": ["go"],
+    "rs": ["rustc"],
+    "java": ["javac", "java"],
+    "c": ["gcc"],
+    "cpp": ["g++"],
+    "php": ["php"],
+    "r": ["Rscript"],
+    "f90": ["gfortran"],
+    "d": ["ldc2"],
+}
+LANGUAGE_ALIASES = {
+    "python": "py",
+    "javascript": "js",
+    "typescript": "ts",
+    "rust": "rs",
+    "fortran": "f90",
+    "dlang": "d",
+}
+
+# Wrappers every command goes through
+LAUNCHERS = ["env", "sh"]
+
+# Other names that can stand in for a binary
+ALTERNATIVES = {
+    "python": ["python3"],
+    "node": ["nodejs"],
+    "gcc": ["cc", "clang"],
+    "g++": ["c++", "clang++"],
+    "gfortran": ["f95"],
+    "ldc2": ["ldmd2", "dmd", "gdc"],
+    "Rscript": ["R"],
+}
+
+# Spawn failures, by tool: GNU/busybox env, nsenter, dash/busybox sh, bash
+NOT_FOUND_PATTERNS = [
+    re.compile(r"env: (?:can't execute )?['‘`]?(?P<name>[^'’`:\s]+)['’`]?: No such file or directory"),
+    re.compile(r"nsenter: failed to execute (?P<name>\S+): No such file or directory"),
+    re.compile(r"^(?:/bin/)?sh: (?:\d+: )?(?P<name>[^\s:]+): (?:command )?not found", re.MULTILINE),
+]
+
+
+def language_binaries(language: str) -> list[str]:
+    """Binaries a language's command needs, launchers included."""
+    code = LANGUAGE_ALIASES.get(language, language)
+    return [*LAUNCHERS, *LANGUAGE_BINARIES.get(code, [])]
+
+
+def _base_name(path: str) -> str:
+    return path.rsplit("/", 1)[-1]
+
+
+def _stem(name: str) -> str:
+    """A binary name without its version suffix: python3.11 -> python."""
+    return re.sub(r"[\d.\-]+$", "", name) or name
+
+
+def find_missing_binary(exit_code: int, stderr: str, language: str) -> str | None:
+    """The binary the command couldn't start, if that's why it failed.
+
+    Only binaries the language's command itself runs count, so a script
+    that happens to exit 127 after a failed ``subprocess`` call is left
+    alone.
+    """
+    if exit_code != NOT_FOUND_EXIT_CODE or not stderr:
+        return None
+    expected = {_stem(name) for name in language_binaries(language)}
+    for pattern in NOT_FOUND_PATTERNS:
+        for match in pattern.finditer(stderr):
+            name = _base_name(match.group("name"))
+            if _stem(name) in expected:
+                return name
+    return None
+
+
+def _natural_key(name: str) -> list:
+    return [int(part) if part.isdigit() else part for part in re.split(r"(\d+)", name)]
+
+
+def find_candidates(wanted: str, path_binaries: list[str]) -> list[str]:
+    """Binaries on PATH that could replace the missing one, most likely first.
+
+    Versioned variants (python3.11 for python3) come first, newest first,
+    then known alternatives (nodejs for node).
+    """
+    stem = _stem(wanted)
+    names = set(path_binaries) - {wanted}
+    versioned = [n for n in names if _stem(n) == stem]
+    alternatives = [n for n in ALTERNATIVES.get(stem, []) if n in names and n not in versioned]
+    return sorted(versioned, key=_natural_key, reverse=True) + alternatives
+
+
+def runtime_not_found(wanted: str, available: list[str]) -> dict:
+    """Structured error for a missing runtime."""
+    error = {"code": ERROR_CODE, "wanted": wanted, "available": available}
+    if available:
+        error["suggestion"] = available[0]
+    return error
+
+
+def format_message(error: dict, original: str = "") -> str:
+    """Human-readable stderr for a RUNTIME_NOT_FOUND error, keeping the original failure."""
+    message = f"{ERROR_CODE}: '{error['wanted']}' is not installed in this image."
+    if error.get("available"):
+        message += f" Available: {', '.join(error['available'])}."
+        message += f" Use '{error['suggestion']}' instead."
+    if original.strip():
+        message += f"\n{original.strip()}"
+    return message + "\n"
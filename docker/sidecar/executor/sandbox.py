@@ -0,0 +1,82 @@
+"""Which protections an execution actually ran under.
+
+Executions are children of the sidecar: nsenter only enters the main
+container's mount namespace, so the code inherits the sidecar's cgroup,
+seccomp filter, no_new_privs flag, credentials and network namespace.
+The report reads them from the kernel (/proc/self, the cgroup and
+/sys) for every execution instead of repeating the pod's configuration,
+so clients and auditors can check what was enforced.
+
+Landlock isn't applied by the sidecar; the report says whether the
+kernel could enforce it, so a missing ruleset is visible rather than
+assumed.
+"""
+
+import os
+
+from . import selftest
+
+PROC_STATUS = "/proc/self/status"
+LSM_LIST = "/sys/kernel/security/lsm"
+NET_DIR = "/sys/class/net"
+
+SECCOMP_MODES = {"0": "disabled", "1": "strict", "2": "filter"}
+
+
+def read_status(path: str = PROC_STATUS) -> dict[str, str]:
+    """Fields of a /proc/<pid>/status file; empty if unreadable."""
+    try:
+        with open(path) as f:
+            lines = f.read().splitlines()
+    except OSError:
+        return {}
+    fields = {}
+    for line in lines:
+        name, _, value = line.partition(":")
+        fields[name] = value.strip()
+    return fields
+
+
+def landlock_status(lsm_path: str = LSM_LIST) -> str | None:
+    """not_applied if the kernel has Landlock enabled, unavailable if not, None if unreadable."""
+    try:
+        with open(lsm_path) as f:
+            modules = f.read().strip().split(",")
+    except OSError:
+        return None
+    return "not_applied" if "landlock" in modules else "unavailable"
+
+
+def network_interfaces(net_dir: str = NET_DIR) -> list[str] | None:
+    """Interfaces in the pod's network namespace other than loopback, None if unreadable."""
+    try:
+        return sorted(name for name in os.listdir(net_dir) if name != "lo")
+    except OSError:
+        return None
+
+
+def report(
+    network_isolated: bool,
+    dns_policy: bool,
+    status_path: str = PROC_STATUS,
+    cgroup_root: str | None = None,
+    lsm_path: str = LSM_LIST,
+    net_dir: str = NET_DIR,
+) -> dict:
+    """The sandbox block of an /execute response; fields that can't be read are None."""
+    status = read_status(status_path)
+    uid = status.get("Uid", "").split()
+    no_new_privs = status.get("NoNewPrivs")
+    return {
+        "cgroup": selftest.read_cgroup_limits(cgroup_root),
+        "seccomp": SECCOMP_MODES.get(status.get("Seccomp", "")),
+        "seccomp_filters": int(status["Seccomp_filters"]) if status.get("Seccomp_filters", "").isdigit() else None,
+        "no_new_privs": no_new_privs == "1" if no_new_privs is not None else None,
+        "uid": int(uid[1]) if len(uid) > 1 and uid[1].isdigit() else None,
+        "landlock": landlock_status(lsm_path),
+        "network": {
+            "mode": "isolated" if network_isolated else "open",
+            "interfaces": network_interfaces(net_dir),
+            "dns_policy": dns_policy,
+        },
+    }
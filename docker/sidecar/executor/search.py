@@ -0,0 +1,194 @@
+"""Bounded content search over the working directory.
+
+Lets a client grep the project through the sidecar instead of running a
+grep process in the main container for every query. Every dimension of a
+search is capped (files scanned, file size, line length, matches and wall
+time) so a broad pattern over a large workspace returns a truncated
+result instead of tying up the sidecar. Binary files, hidden directories
+and symlinks are skipped, so results can't describe files outside the
+searched directory.
+
+The endpoint runs searches through run(), in a child process: the wall
+time is checked between lines, but a pattern that backtracks
+catastrophically can spend hours inside one regex call, and only killing
+the process stops it.
+"""
+
+import asyncio
+import json
+import os
+import re
+import sys
+import time
+from pathlib import Path
+
+MAX_MATCHES = 200
+MAX_CONTEXT = 10
+MAX_FILES = 5000
+MAX_FILE_SIZE = 1024 * 1024
+# Longer lines are matched and reported only up to this length, bounding each regex call
+MAX_LINE_LENGTH = 2000
+DEFAULT_TIMEOUT = 5.0
+# Time a search may run past its timeout before its process is killed
+KILL_GRACE = 1.0
+
+
+class SearchError(Exception):
+    """A search request that can't be run, such as an invalid pattern."""
+
+
+def glob_to_regex(pattern: str) -> re.Pattern:
+    """Compile a glob over relative posix paths: ``*`` and ``?`` stay within a segment, ``**`` spans segments."""
+    out = []
+    i = 0
+    while i < len(pattern):
+        if pattern.startswith("**/", i):
+            out.append("(?:.*/)?")
+            i += 3
+        elif pattern.startswith("**", i):
+            out.append(".*")
+            i += 2
+        elif pattern[i] == "*":
+            out.append("[^/]*")
+            i += 1
+        elif pattern[i] == "?":
+            out.append("[^/]")
+            i += 1
+        else:
+            out.append(re.escape(pattern[i]))
+            i += 1
+    return re.compile("".join(out) + r"\Z")
+
+
+def _is_binary(data: bytes) -> bool:
+    return b"\0" in data[:8192]
+
+
+def search(
+    root: Path,
+    query: str,
+    glob: str = "**/*",
+    regex: bool = True,
+    ignore_case: bool = False,
+    context: int = 2,
+    max_matches: int = MAX_MATCHES,
+    timeout: float = DEFAULT_TIMEOUT,
+) -> dict:
+    """Lines matching ``query`` in files under ``root`` whose relative path matches ``glob``.
+
+    Returns ``{"matches": [...], "files_scanned": n, "truncated": bool}``;
+    each match has the file's relative path, 1-based line and column, the
+    line and up to ``context`` lines before and after it. ``truncated`` is
+    set when a cap or the timeout stopped the search early.
+
+    Raises:
+        SearchError: If the query or glob is empty or the query isn't a valid regex
+    """
+    if not query:
+        raise SearchError("q must not be empty")
+    if not glob:
+        raise SearchError("glob must not be empty")
+    try:
+        matcher = re.compile(query if regex else re.escape(query), re.IGNORECASE if ignore_case else 0)
+    except re.error as e:
+        raise SearchError(f"Invalid pattern: {e}")
+    path_filter = glob_to_regex(glob)
+    context = max(0, min(context, MAX_CONTEXT))
+    max_matches = max(1, min(max_matches, MAX_MATCHES))
+    deadline = time.monotonic() + timeout
+
+    matches: list[dict] = []
+    files_scanned = 0
+    truncated = False
+    for directory, dirnames, filenames in os.walk(root):
+        dirnames[:] = sorted(
+            d for d in dirnames if not d.startswith(".") and not (Path(directory) / d).is_symlink()
+        )
+        for name in sorted(filenames):
+            path = Path(directory) / name
+            relative = path.relative_to(root).as_posix()
+            if not path_filter.match(relative) or path.is_symlink() or not path.is_file():
+                continue
+            if files_scanned >= MAX_FILES or time.monotonic() > deadline:
+                return {"matches": matches, "files_scanned": files_scanned, "truncated": True}
+            files_scanned += 1
+            try:
+                if path.stat().st_size > MAX_FILE_SIZE:
+                    truncated = True
+                    continue
+                data = path.read_bytes()
+            except OSError:
+                continue
+            if _is_binary(data):
+                continue
+
+            lines = data.decode("utf-8", errors="replace").splitlines()
+            for number, line in enumerate(lines):
+                if time.monotonic() > deadline:
+                    return {"matches": matches, "files_scanned": files_scanned, "truncated": True}
+                found = matcher.search(line[:MAX_LINE_LENGTH])
+                if not found:
+                    continue
+                matches.append({
+                    "path": relative,
+                    "line": number + 1,
+                    "column": found.start() + 1,
+                    "text": line[:MAX_LINE_LENGTH],
+                    "before": [text[:MAX_LINE_LENGTH] for text in lines[max(0, number - context):number]],
+                    "after": [text[:MAX_LINE_LENGTH] for text in lines[number + 1:number + 1 + context]],
+                })
+                if len(matches) >= max_matches:
+                    return {"matches": matches, "files_scanned": files_scanned, "truncated": True}
+    return {"matches": matches, "files_scanned": files_scanned, "truncated": truncated}
+
+
+async def run(root: Path, query: str, timeout: float = DEFAULT_TIMEOUT, **options) -> dict:
+    """search() in a child process, killed if it is still running KILL_GRACE after its timeout.
+
+    Raises:
+        SearchError: As search() does, or if the search had to be killed
+    """
+    proc = await asyncio.create_subprocess_exec(
+        *worker_command(),
+        cwd=str(Path(__file__).resolve().parent.parent),
+        stdin=asyncio.subprocess.PIPE,
+        stdout=asyncio.subprocess.PIPE,
+        stderr=asyncio.subprocess.DEVNULL,
+    )
+    request = json.dumps({"root": str(root), "query": query, "timeout": timeout, **options}).encode()
+    try:
+        stdout, _ = await asyncio.wait_for(proc.communicate(request), timeout + KILL_GRACE)
+    except asyncio.TimeoutError:
+        proc.kill()
+        await proc.wait()
+        raise SearchError(f"Search timed out after {timeout:g}s; the pattern is too slow to match")
+    try:
+        result = json.loads(stdout)
+    except ValueError:
+        raise SearchError(f"Search failed (exit code {proc.returncode})")
+    if "error" in result:
+        raise SearchError(result["error"])
+    return result
+
+
+def worker_command() -> list[str]:
+    """Command that runs worker() in a new process."""
+    # The static agent build is one executable, without -m; agent.py runs the worker for it
+    if getattr(sys, "frozen", False):
+        return [sys.executable, "search-worker"]
+    return [sys.executable, "-m", "executor.search"]
+
+
+def worker() -> None:
+    """Run one search described by a JSON object on stdin, writing the result (or error) to stdout."""
+    request = json.load(sys.stdin)
+    root = Path(request.pop("root"))
+    try:
+        result = search(root, request.pop("query"), **request)
+    except SearchError as e:
+        result = {"error": str(e)}
+    json.dump(result, sys.stdout)
+
+
+if __name__ == "__main__":
+    worker()
@@ -0,0 +1,224 @@
+"""Conformance self-test of an execution pod (``python main.py selftest``).
+
+Checks what executions depend on, in the pod the sidecar runs in:
+an nsenter that can enter the main container, spawning a process in it,
+the cgroup limits user code
+inherits, reading and writing the working directory from both
+containers, the language's own runtime, and every other runtime found
+in the image. The report is JSON, and the exit status is non-zero when a
+required check fails, so CI for custom language images and cluster
+onboarding can run it as is:
+
+    kubectl exec <pod> -c sidecar -- python main.py selftest
+
+Commands run through the same nsenter path as executions, so the report
+describes the language image, not the sidecar's.
+"""
+
+import os
+import shutil
+import struct
+import time
+import uuid
+from collections.abc import Awaitable, Callable
+
+from . import runtime
+
+CHECK_TIMEOUT = 30
+CGROUP_ROOT = "/sys/fs/cgroup"
+
+# Command run for each runtime binary, and what its output must contain (None: exit 0 is enough)
+RUNTIME_PROBES: dict[str, tuple[list[str], str | None]] = {
+    "python": (["python", "-c", "print('selftest-ok')"], "selftest-ok"),
+    "node": (["node", "-e", "console.log('selftest-ok')"], "selftest-ok"),
+    "php": (["php", "-r", "echo 'selftest-ok';"], "selftest-ok"),
+    "Rscript": (["Rscript", "-e", "cat('selftest-ok')"], "selftest-ok"),
+    "go": (["go", "version"], None),
+    "rustc": (["rustc", "--version"], None),
+    "javac": (["javac", "-version"], None),
+    "java": (["java", "-version"], None),
+    "gcc": (["gcc", "--version"], None),
+    "g++": (["g++", "--version"], None),
+    "gfortran": (["gfortran", "--version"], None),
+    "ldc2": (["ldc2", "--version"], None),
+}
+
+# Capabilities nsenter needs to enter the main container as a non-root sidecar
+NSENTER_CAPABILITIES = {"cap_sys_chroot": 18, "cap_sys_ptrace": 19, "cap_sys_admin": 21}
+# Section staticx adds to its bootloader; file capabilities on one don't reach the binary it unpacks
+STATICX_MARKER = b".staticx.archive"
+
+# (exit_code, stdout, stderr) of a command run in the main container
+RunCommand = Callable[[list[str], int], Awaitable[tuple[int, str, str]]]
+ProbeBinaries = Callable[[list[str]], Awaitable[set[str]]]
+
+
+def _result(name: str, ok: bool, start: float, detail: str = "", **extra) -> dict:
+    return {"name": name, "ok": ok, "ms": round((time.perf_counter() - start) * 1000, 1), "detail": detail, **extra}
+
+
+def _first_line(*texts: str) -> str:
+    for text in texts:
+        if text.strip():
+            return text.strip().splitlines()[0][:200]
+    return ""
+
+
+def _read(path: str) -> str | None:
+    try:
+        with open(path) as f:
+            return f.read().strip()
+    except OSError:
+        return None
+
+
+def read_cgroup_limits(root: str | None = None) -> dict[str, str | None]:
+    """Memory, CPU and process limits of the sidecar's cgroup (v2, with v1 memory as fallback).
+
+    User code runs in this cgroup, so these are its limits; ``max`` means unlimited.
+    """
+    root = root or CGROUP_ROOT
+    memory = _read(os.path.join(root, "memory.max"))
+    if memory is None:
+        v1 = _read(os.path.join(root, "memory", "memory.limit_in_bytes"))
+        # cgroup v1 reports "unlimited" as a huge page-aligned number
+        memory = "max" if v1 and int(v1) >= 2**62 else v1
+    return {
+        "memory": memory,
+        "cpu": _read(os.path.join(root, "cpu.max")),
+        "pids": _read(os.path.join(root, "pids.max")),
+    }
+
+
+def check_limits(root: str | None = None) -> dict:
+    """Limits are reported; unlimited ones are warnings, not failures, as CI often runs without any."""
+    start = time.perf_counter()
+    root = root or CGROUP_ROOT
+    limits = read_cgroup_limits(root)
+    if all(value is None for value in limits.values()):
+        return _result("limits", False, start, f"No cgroup limits readable under {root}", limits=limits)
+    unlimited = [name for name, value in limits.items() if value is None or value.split()[0] == "max"]
+    detail = f"Unlimited: {', '.join(unlimited)}" if unlimited else ""
+    return _result("limits", True, start, detail, limits=limits, unlimited=unlimited)
+
+
+def file_capabilities(path: str) -> set[str] | None:
+    """Which of NSENTER_CAPABILITIES ``path``'s xattr grants; None without one."""
+    try:
+        return parse_capabilities(os.getxattr(path, "security.capability"))
+    except OSError:
+        return None
+
+
+def parse_capabilities(value: bytes) -> set[str]:
+    """Which of NSENTER_CAPABILITIES a ``security.capability`` value makes permitted and effective."""
+    if len(value) < 12:
+        return set()
+    magic, permitted = struct.unpack_from("<II", value)
+    if not magic & 1:  # VFS_CAP_FLAGS_EFFECTIVE
+        return set()
+    return {name for name, bit in NSENTER_CAPABILITIES.items() if permitted & (1 << bit)}
+
+
+def check_nsenter(path: str | None = None, uid: int | None = None) -> dict:
+    """nsenter is a real binary with the capabilities a non-root sidecar needs (see executor.install)."""
+    start = time.perf_counter()
+    path = path or shutil.which("nsenter")
+    if not path:
+        return _result("nsenter", False, start, "nsenter not found on PATH")
+    try:
+        with open(path, "rb") as f:
+            bundled = STATICX_MARKER in f.read()
+    except OSError as e:
+        return _result("nsenter", False, start, str(e), path=path)
+    if bundled:
+        return _result("nsenter", False, start, "nsenter is a staticx bundle; its capabilities can't apply", path=path)
+    uid = os.geteuid() if uid is None else uid
+    if uid == 0:
+        return _result("nsenter", True, start, "Running as root", path=path)
+    missing = sorted(set(NSENTER_CAPABILITIES) - (file_capabilities(path) or set()))
+    detail = f"Missing file capabilities: {', '.join(missing)}" if missing else ""
+    return _result("nsenter", not missing, start, detail, path=path)
+
+
+async def check_spawn(run: RunCommand) -> dict:
+    start = time.perf_counter()
+    exit_code, stdout, stderr = await run(["sh", "-c", "echo selftest-ok"], CHECK_TIMEOUT)
+    ok = exit_code == 0 and "selftest-ok" in stdout
+    return _result("spawn", ok, start, "" if ok else f"exit {exit_code}: {_first_line(stderr, stdout)}")
+
+
+async def check_workspace_io(run: RunCommand, working_dir: str) -> dict:
+    """A file written by the sidecar is read and replaced by the main container, and read back."""
+    start = time.perf_counter()
+    name = f".selftest-{uuid.uuid4().hex}"
+    path = os.path.join(working_dir, name)
+    try:
+        with open(path, "w") as f:
+            f.write("from-sidecar")
+        script = f'test "$(cat {name})" = from-sidecar && printf from-container > {name}'
+        exit_code, stdout, stderr = await run(["sh", "-c", script], CHECK_TIMEOUT)
+        if exit_code != 0:
+            return _result("workspace_io", False, start, f"exit {exit_code}: {_first_line(stderr, stdout)}")
+        with open(path) as f:
+            content = f.read()
+        if content != "from-container":
+            return _result("workspace_io", False, start, "Sidecar and main container see different files")
+        return _result("workspace_io", True, start)
+    except OSError as e:
+        return _result("workspace_io", False, start, str(e))
+    finally:
+        try:
+            os.unlink(path)
+        except OSError:
+            pass
+
+
+async def check_runtime(run: RunCommand, binary: str) -> dict:
+    start = time.perf_counter()
+    args, expected = RUNTIME_PROBES[binary]
+    exit_code, stdout, stderr = await run(args, CHECK_TIMEOUT)
+    ok = exit_code == 0 and (expected is None or expected in stdout)
+    return _result(binary, ok, start, _first_line(stdout, stderr) if ok else f"exit {exit_code}: {_first_line(stderr)}")
+
+
+async def run_selftest(
+    run: RunCommand, probe_binaries: ProbeBinaries, working_dir: str, language: str, version: str = ""
+) -> dict:
+    """Run every check; ``ok`` is false if a required check failed.
+
+    Runtimes other than the language's are informational: their failures
+    are reported but don't fail the self-test.
+    """
+    checks = [check_nsenter(), await check_spawn(run), check_limits(), await check_workspace_io(run, working_dir)]
+
+    language_binaries = [b for b in runtime.language_binaries(language) if b not in runtime.LAUNCHERS]
+    present = await probe_binaries(sorted(set(RUNTIME_PROBES) | set(language_binaries)))
+    start = time.perf_counter()
+    missing = [b for b in language_binaries if b not in present]
+    checks.append(
+        _result(
+            "language_runtime",
+            not missing,
+            start,
+            f"Missing: {', '.join(missing)}" if missing else "",
+            required=language_binaries,
+        )
+    )
+
+    runtimes = []
+    for binary in sorted(present):
+        if binary in RUNTIME_PROBES:
+            result = await check_runtime(run, binary)
+            result["required"] = binary in language_binaries
+            runtimes.append(result)
+
+    failed = [c["name"] for c in checks if not c["ok"]] + [r["name"] for r in runtimes if r["required"] and not r["ok"]]
+    return {
+        "ok": not failed,
+        "failed": failed,
+        "version": version,
+        "language": language,
+        "checks": checks,
+        "runtimes": runtimes,
+    }
@@ -0,0 +1,106 @@
+"""Storage drivers for the working directory.
+
+The working directory (/mnt/data, and the named workspaces in it) is a
+volume the API picks per deployment with WORKSPACE_STORAGE_DRIVER and
+tells the sidecar about in WORKSPACE_STORAGE:
+
+- ``local``: an emptyDir on the node's disk.
+- ``tmpfs``: an emptyDir in memory, for diskless nodes. Files count
+  against the pod's memory limit.
+- ``object``: a FUSE mount of object storage (a CSI driver such as
+  csi-s3 or mountpoint-s3), for datasets larger than any node's disk.
+
+Code sees a filesystem either way; the file API writes through the
+driver, because object storage has no atomic rename (it's a copy and a
+delete, and some mounts refuse it) and no in-place writes (objects are
+uploaded whole). On ``object`` uploads are written directly, so a reader
+can see a file that is still being written, and appends and offset writes
+rewrite the whole file, so concurrent appenders can lose each other's
+writes.
+"""
+
+import os
+from pathlib import Path
+
+from . import filewrite
+
+DRIVERS = ("local", "tmpfs", "object")
+
+
+class StorageDriver:
+    """Working directory on a regular filesystem (local disk)."""
+
+    name = "local"
+    atomic_rename = True
+    in_place_writes = True
+    memory_backed = False
+
+    def write_file(self, path: Path, data: bytes) -> None:
+        """Replace the file at ``path`` with ``data``."""
+        filewrite.atomic_write(path, data)
+
+    def write_at(self, path: Path, data: bytes, offset: int | None = None, expected_size: int | None = None) -> int:
+        """Append ``data`` or write it at ``offset`` (see filewrite.write_at); returns the new size."""
+        return filewrite.write_at(path, data, offset=offset, expected_size=expected_size)
+
+    def describe(self) -> dict:
+        return {
+            "driver": self.name,
+            "atomic_rename": self.atomic_rename,
+            "in_place_writes": self.in_place_writes,
+            "memory_backed": self.memory_backed,
+        }
+
+
+class TmpfsDriver(StorageDriver):
+    """Working directory in memory; same semantics as local disk."""
+
+    name = "tmpfs"
+    memory_backed = True
+
+
+class ObjectStorageDriver(StorageDriver):
+    """Working directory on FUSE-mounted object storage: files are written whole."""
+
+    name = "object"
+    atomic_rename = False
+    in_place_writes = False
+
+    def write_file(self, path: Path, data: bytes) -> None:
+        path.parent.mkdir(parents=True, exist_ok=True)
+        with open(path, "wb") as f:
+            f.write(data)
+            f.flush()
+            os.fsync(f.fileno())
+
+    def write_at(self, path: Path, data: bytes, offset: int | None = None, expected_size: int | None = None) -> int:
+        size = filewrite.current_size(path)
+        if expected_size is not None and size != expected_size:
+            raise filewrite.FileWriteError(f"File is {size} bytes, expected {expected_size}", status=409)
+        if offset is not None:
+            if offset < 0:
+                raise filewrite.FileWriteError("offset must not be negative")
+            if offset > size:
+                message = f"offset {offset} is past the end of the file ({size} bytes)"
+                raise filewrite.FileWriteError(message, status=409)
+
+        current = path.read_bytes() if size else b""
+        if offset is None:
+            updated = current + data
+        else:
+            updated = current[:offset] + data + current[offset + len(data) :]
+        self.write_file(path, updated)
+        return len(updated)
+
+
+def load(name: str) -> StorageDriver:
+    """The driver named by WORKSPACE_STORAGE; empty means local.
+
+    Raises:
+        ValueError: For an unknown driver
+    """
+    drivers = {"local": StorageDriver, "tmpfs": TmpfsDriver, "object": ObjectStorageDriver}
+    driver = drivers.get(name.strip().lower() or "local")
+    if driver is None:
+        raise ValueError(f"Unknown WORKSPACE_STORAGE {name!r}, expected one of: {', '.join(DRIVERS)}")
+    return driver()
@@ -0,0 +1,210 @@
+"""Strict validation mode for the HTTP API (STRICT_MODE).
+
+The API sets STRICT_MODE from SIDECAR_STRICT_MODE. With it on, requests
+are held to their schema instead of being made to fit:
+
+- Bodies are validated strictly: no type coercion (``"30"`` isn't a
+  timeout, ``1`` isn't true), ranges and enum values as declared, and
+  unknown fields are rejected rather than dropped. So are query
+  parameters the route doesn't declare.
+- Paths (the file API, uploads, renders, media and LSP) are normalized
+  before use: NFC, empty and ``.`` segments dropped and ``..`` resolved.
+  Paths with control characters or backslashes, over-long paths or names,
+  and paths that climb out of their base are refused.
+- Refused requests (400, 403, 405, 413, 422 and unknown routes) are logged
+  as ``[SECURITY]`` JSON lines with the client, method, route and reason;
+  validation errors are logged by location and type, never by value. A
+  client with BURST_THRESHOLD of them in a minute is logged once more as
+  an ``anomaly_burst``.
+
+Off, requests are parsed as before: values pydantic can coerce are
+accepted and unknown fields are ignored.
+"""
+
+import json
+import time
+import unicodedata
+from collections import Counter, defaultdict, deque
+from urllib.parse import parse_qs
+
+MAX_PATH_LENGTH = 4096  # PATH_MAX, in bytes
+MAX_NAME_LENGTH = 255  # NAME_MAX, in bytes
+ANOMALY_WINDOW_SECONDS = 60
+BURST_THRESHOLD = 20
+ANOMALOUS_STATUSES = {
+    400: "bad_request",
+    403: "access_denied",
+    405: "method_not_allowed",
+    413: "too_large",
+    422: "validation_failed",
+}
+MAX_LOGGED_DETAIL = 300
+
+
+class PathRejected(ValueError):
+    """A path strict mode refuses to use."""
+
+
+def model_config(enabled: bool) -> dict:
+    """pydantic model_config for request bodies: strict types and no unknown fields when enabled."""
+    return {"extra": "forbid", "strict": True} if enabled else {}
+
+
+def normalize_path(path: str) -> str:
+    """The normalized form of a request path, absolute or relative to its base.
+
+    Raises:
+        PathRejected: For control characters, backslashes, over-long paths or
+            names, and ``..`` above the start of the path
+    """
+    if any(ord(c) < 32 or ord(c) == 127 for c in path):
+        raise PathRejected("Path contains control characters")
+    if "\\" in path:
+        raise PathRejected("Path contains a backslash")
+    path = unicodedata.normalize("NFC", path)
+    if len(path.encode()) > MAX_PATH_LENGTH:
+        raise PathRejected(f"Path is longer than {MAX_PATH_LENGTH} bytes")
+
+    parts: list[str] = []
+    for part in path.split("/"):
+        if part in ("", "."):
+            continue
+        if part == "..":
+            if not parts:
+                raise PathRejected("Path leaves its base directory")
+            parts.pop()
+            continue
+        if len(part.encode()) > MAX_NAME_LENGTH:
+            raise PathRejected(f"Path has a name longer than {MAX_NAME_LENGTH} bytes")
+        parts.append(part)
+    prefix = "/" if path.startswith("/") else ""
+    return prefix + "/".join(parts)
+
+
+def summarize_detail(detail) -> str | list | None:
+    """What to log of an error response's detail: validation errors by location and type only."""
+    if isinstance(detail, list):
+        return [
+            {"loc": item.get("loc"), "type": item.get("type")} if isinstance(item, dict) else str(item)[:80]
+            for item in detail[:10]
+        ]
+    if isinstance(detail, str):
+        return detail[:MAX_LOGGED_DETAIL]
+    return None
+
+
+class AnomalyMonitor:
+    """Logs refused requests and counts them, per reason and per client, for GET /health."""
+
+    def __init__(self, threshold: int = BURST_THRESHOLD, window: float = ANOMALY_WINDOW_SECONDS):
+        self.threshold = threshold
+        self.window = window
+        self.counts: Counter[str] = Counter()
+        self._recent: dict[str, deque[float]] = defaultdict(deque)
+        self._bursting: set[str] = set()
+
+    def record(self, reason: str, client: str, method: str, path: str, detail=None, now: float | None = None) -> None:
+        now = time.time() if now is None else now
+        self.counts[reason] += 1
+        log_event("anomalous_request", reason=reason, client=client, method=method, path=path, detail=detail)
+
+        recent = self._recent[client]
+        recent.append(now)
+        while recent and now - recent[0] > self.window:
+            recent.popleft()
+        if len(recent) >= self.threshold and client not in self._bursting:
+            self._bursting.add(client)
+            self.counts["anomaly_burst"] += 1
+            log_event("anomaly_burst", client=client, requests=len(recent), window_seconds=self.window)
+        elif len(recent) < self.threshold:
+            self._bursting.discard(client)
+
+    def report(self) -> dict:
+        return {"anomalies": dict(self.counts), "bursting_clients": sorted(self._bursting)}
+
+
+def log_event(event: str, **fields) -> None:
+    print("[SECURITY] " + json.dumps({"event": event, **fields}, default=str), flush=True)
+
+
+def _client(scope) -> str:
+    client = scope.get("client")
+    return client[0] if client else "unknown"
+
+
+def undeclared_query_params(route, scope) -> list[str]:
+    """Query parameters of the request that ``route`` doesn't declare."""
+    query = parse_qs(scope.get("query_string", b"").decode("latin-1"), keep_blank_values=True)
+    dependant = getattr(route, "dependant", None)
+    if not query or dependant is None:
+        return []
+    from fastapi.dependencies.utils import get_flat_dependant
+
+    declared = {param.alias for param in get_flat_dependant(dependant).query_params}
+    return sorted(set(query) - declared)
+
+
+def match_route(app, scope):
+    """The route of the app serving the request, or None (unknown path, or a method it doesn't allow)."""
+    from starlette.routing import Match
+
+    for route in app.router.routes:
+        match, _ = route.matches(scope)
+        if match == Match.FULL:
+            return route
+    return None
+
+
+class StrictMiddleware:
+    """ASGI middleware refusing undeclared query parameters and logging refused requests."""
+
+    def __init__(self, app, monitor: AnomalyMonitor):
+        self.app = app
+        self.monitor = monitor
+
+    async def __call__(self, scope, receive, send):
+        if scope["type"] != "http":
+            return await self.app(scope, receive, send)
+
+        client, method, path = _client(scope), scope["method"], scope["path"]
+        route = match_route(scope["app"], scope) if "app" in scope else None
+        unknown = undeclared_query_params(route, scope) if route else []
+        if unknown:
+            detail = f"Unknown query parameters: {', '.join(unknown)}"
+            self.monitor.record("unknown_query_params", client, method, path, detail)
+            body = json.dumps({"detail": detail}).encode()
+            await send(
+                {
+                    "type": "http.response.start",
+                    "status": 422,
+                    "headers": [(b"content-type", b"application/json"), (b"content-length", str(len(body)).encode())],
+                }
+            )
+            await send({"type": "http.response.body", "body": body})
+            return
+
+        status = None
+        body = b""
+
+        async def watching_send(message):
+            nonlocal status, body
+            if message["type"] == "http.response.start":
+                status = message["status"]
+            elif message["type"] == "http.response.body" and not message.get("more_body"):
+                if status in ANOMALOUS_STATUSES:
+                    body = (body + message.get("body", b""))[:4096]
+                    self.monitor.record(ANOMALOUS_STATUSES[status], client, method, path, _detail(body))
+                elif status == 404 and route is None:
+                    self.monitor.record("unknown_route", client, method, path)
+            elif message["type"] == "http.response.body" and status in ANOMALOUS_STATUSES:
+                body = (body + message.get("body", b""))[:4096]
+            await send(message)
+
+        await self.app(scope, receive, watching_send)
+
+
+def _detail(body: bytes):
+    try:
+        return summarize_detail(json.loads(body).get("detail"))
+    except (ValueError, AttributeError):
+        return None
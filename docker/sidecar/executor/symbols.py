@@ -0,0 +1,215 @@
+"""Code outlines (functions, classes and their line ranges) for /files/{path}/symbols.
+
+Source files are parsed with tree-sitter grammars from
+tree-sitter-language-pack; nothing is executed. Each language maps the
+syntax node types that define something to a symbol kind, and symbols
+nest the way the definitions do (a class's methods are its children).
+Python files are outlined with the standard library's ast module when
+tree-sitter isn't installed, so that case keeps working in a slim image.
+"""
+
+import ast
+from dataclasses import dataclass, field
+from pathlib import PurePosixPath
+
+# Files larger than this aren't parsed
+MAX_SOURCE_SIZE = 2 * 1024 * 1024
+
+# File extension -> tree-sitter-language-pack grammar name
+LANGUAGES = {
+    ".py": "python",
+    ".js": "javascript",
+    ".mjs": "javascript",
+    ".cjs": "javascript",
+    ".jsx": "javascript",
+    ".ts": "typescript",
+    ".tsx": "tsx",
+    ".go": "go",
+    ".rs": "rust",
+    ".java": "java",
+    ".c": "c",
+    ".h": "c",
+    ".cc": "cpp",
+    ".cpp": "cpp",
+    ".hpp": "cpp",
+    ".php": "php",
+    ".rb": "ruby",
+}
+
+_CLASS_LIKE = {"class", "interface", "struct", "enum", "trait", "impl", "module", "namespace"}
+
+_JS_NODES = {
+    "function_declaration": "function",
+    "generator_function_declaration": "function",
+    "class_declaration": "class",
+    "method_definition": "method",
+}
+_TS_NODES = {
+    **_JS_NODES,
+    "interface_declaration": "interface",
+    "enum_declaration": "enum",
+    "type_alias_declaration": "type",
+    "abstract_class_declaration": "class",
+}
+_C_NODES = {"function_definition": "function", "struct_specifier": "struct", "enum_specifier": "enum"}
+
+# Grammar name -> definition node type -> symbol kind
+DEFINITIONS: dict[str, dict[str, str]] = {
+    "python": {"function_definition": "function", "class_definition": "class"},
+    "javascript": _JS_NODES,
+    "typescript": _TS_NODES,
+    "tsx": _TS_NODES,
+    "go": {"function_declaration": "function", "method_declaration": "method", "type_spec": "type"},
+    "rust": {
+        "function_item": "function",
+        "struct_item": "struct",
+        "enum_item": "enum",
+        "trait_item": "trait",
+        "impl_item": "impl",
+        "mod_item": "module",
+    },
+    "java": {
+        "class_declaration": "class",
+        "interface_declaration": "interface",
+        "enum_declaration": "enum",
+        "record_declaration": "class",
+        "method_declaration": "method",
+        "constructor_declaration": "method",
+    },
+    "c": _C_NODES,
+    "cpp": {**_C_NODES, "class_specifier": "class", "namespace_definition": "namespace"},
+    "php": {
+        "function_definition": "function",
+        "class_declaration": "class",
+        "interface_declaration": "interface",
+        "trait_declaration": "trait",
+        "method_declaration": "method",
+    },
+    "ruby": {"method": "method", "singleton_method": "method", "class": "class", "module": "module"},
+}
+
+# Fields holding a definition's name, tried in order; C declarators nest the name further down
+_NAME_FIELDS = ("name", "declarator", "type")
+_NAME_NODES = {"identifier", "field_identifier", "type_identifier", "property_identifier", "constant", "name"}
+
+
+class SymbolsError(Exception):
+    """A file that can't be outlined, with the HTTP status to answer with."""
+
+    def __init__(self, message: str, status: int = 400):
+        super().__init__(message)
+        self.status = status
+
+
+@dataclass
+class Symbol:
+    name: str
+    kind: str
+    start_line: int
+    end_line: int
+    children: list["Symbol"] = field(default_factory=list)
+
+    def to_dict(self) -> dict:
+        return {
+            "name": self.name,
+            "kind": self.kind,
+            "start_line": self.start_line,
+            "end_line": self.end_line,
+            "children": [child.to_dict() for child in self.children],
+        }
+
+
+def language_for(path: str) -> str:
+    """Grammar name for a file.
+
+    Raises:
+        SymbolsError: 415 for an unsupported file type
+    """
+    language = LANGUAGES.get(PurePosixPath(path).suffix.lower())
+    if not language:
+        raise SymbolsError(f"Unsupported file type: {PurePosixPath(path).suffix or path}", status=415)
+    return language
+
+
+def _node_name(node, source: bytes) -> str | None:
+    for _ in range(8):
+        for name_field in _NAME_FIELDS:
+            child = node.child_by_field_name(name_field)
+            if child is not None:
+                break
+        else:
+            return None
+        if child.type in _NAME_NODES or child.child_count == 0:
+            return source[child.start_byte:child.end_byte].decode("utf-8", errors="replace")
+        node = child
+    return None
+
+
+def outline_tree(root, source: bytes, definitions: dict[str, str]) -> list[Symbol]:
+    """Symbols of a tree-sitter tree, nested by containment."""
+    def walk(node, inside_class: bool) -> list[Symbol]:
+        found: list[Symbol] = []
+        for child in node.children:
+            kind = definitions.get(child.type)
+            # Bodiless specifiers (struct foo x;) only refer to a definition
+            if kind and (kind not in ("struct", "enum") or child.child_by_field_name("body") is not None):
+                name = _node_name(child, source)
+                if name:
+                    if kind == "function" and inside_class:
+                        kind = "method"
+                    symbol = Symbol(name, kind, child.start_point[0] + 1, child.end_point[0] + 1)
+                    symbol.children = walk(child, kind in _CLASS_LIKE)
+                    found.append(symbol)
+                    continue
+            found.extend(walk(child, inside_class))
+        return found
+
+    return walk(root, False)
+
+
+def outline_python_ast(source: bytes) -> list[Symbol]:
+    """Symbols of Python source, using the ast module."""
+    try:
+        tree = ast.parse(source)
+    except SyntaxError as e:
+        raise SymbolsError(f"Cannot parse Python source: {e.msg} (line {e.lineno})", status=422)
+
+    def walk(body: list[ast.stmt], inside_class: bool) -> list[Symbol]:
+        found = []
+        for node in body:
+            if isinstance(node, ast.ClassDef):
+                symbol = Symbol(node.name, "class", node.lineno, node.end_lineno or node.lineno)
+                symbol.children = walk(node.body, True)
+                found.append(symbol)
+            elif isinstance(node, ast.FunctionDef | ast.AsyncFunctionDef):
+                kind = "method" if inside_class else "function"
+                symbol = Symbol(node.name, kind, node.lineno, node.end_lineno or node.lineno)
+                symbol.children = walk(node.body, False)
+                found.append(symbol)
+            else:
+                # Definitions under if/try/with blocks still belong to this scope
+                for attr in ("body", "orelse", "finalbody", "handlers"):
+                    found.extend(walk(getattr(node, attr, []) or [], inside_class))
+        return found
+
+    return walk(tree.body, False)
+
+
+def outline(path: str, source: bytes) -> tuple[str, list[Symbol], str]:
+    """Language, symbols and parser used for a source file.
+
+    Raises:
+        SymbolsError: 415 for an unsupported type, 413 for an oversized file,
+            503 when the language needs tree-sitter and it isn't installed
+    """
+    language = language_for(path)
+    if len(source) > MAX_SOURCE_SIZE:
+        raise SymbolsError(f"File is larger than {MAX_SOURCE_SIZE} bytes", status=413)
+    try:
+        from tree_sitter_language_pack import get_parser
+    except ImportError:
+        if language == "python":
+            return language, outline_python_ast(source), "ast"
+        raise SymbolsError("tree-sitter is not installed in this sidecar", status=503)
+    tree = get_parser(language).parse(source)
+    return language, outline_tree(tree.root_node, source, DEFINITIONS[language]), "tree-sitter"
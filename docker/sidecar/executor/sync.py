@@ -0,0 +1,190 @@
+"""rsync-style incremental sync of the working directory.
+
+Syncing a large workspace file by file re-sends everything. Instead:
+
+1. A manifest (path, size, mtime and optionally sha256 for every file)
+   tells the other side which files changed.
+2. For each changed file, the side holding the old copy sends a signature:
+   a weak rolling checksum and a strong hash per fixed-size block.
+3. The side holding the new copy answers with a delta: "copy" operations
+   for runs of blocks the other side already has, and literal "data"
+   operations (base64) for everything else.
+4. The delta is applied to the old copy to rebuild the new one, and the
+   result is checked against the new copy's sha256.
+
+Both directions are supported: a client can fetch a file's signature and
+send a delta back (upload), or send the signature of its own copy and
+receive a delta (download).
+"""
+
+import base64
+import hashlib
+import os
+from pathlib import Path
+
+DEFAULT_BLOCK_SIZE = 8192
+MIN_BLOCK_SIZE = 512
+MAX_BLOCK_SIZE = 1024 * 1024
+
+_MOD = 1 << 16
+
+
+class DeltaError(ValueError):
+    """A delta doesn't fit the file it's applied to."""
+
+
+class DeltaTooLarge(DeltaError):
+    """A delta would rebuild a file larger than allowed."""
+
+
+def weak_checksum(block: bytes) -> int:
+    """rsync's rolling checksum of a block (a + b * 2^16)."""
+    a = sum(block) % _MOD
+    b = sum((len(block) - i) * byte for i, byte in enumerate(block)) % _MOD
+    return a | (b << 16)
+
+
+def strong_hash(block: bytes) -> str:
+    return hashlib.blake2b(block, digest_size=16).hexdigest()
+
+
+def file_sha256(data: bytes) -> str:
+    return hashlib.sha256(data).hexdigest()
+
+
+def signature(data: bytes, block_size: int = DEFAULT_BLOCK_SIZE) -> list[dict]:
+    """Checksums of each block of a file; the last block may be short."""
+    return [
+        {"weak": weak_checksum(data[i : i + block_size]), "strong": strong_hash(data[i : i + block_size])}
+        for i in range(0, len(data), block_size)
+    ]
+
+
+def _match(index: dict[int, list[tuple[str, int]]], weak: int, block: bytes) -> int | None:
+    candidates = index.get(weak)
+    if not candidates:
+        return None
+    strong = strong_hash(block)
+    return next((number for digest, number in candidates if digest == strong), None)
+
+
+def compute_delta(data: bytes, blocks: list[dict], block_size: int = DEFAULT_BLOCK_SIZE) -> list[dict]:
+    """Operations that rebuild ``data`` from a file with the given block signature.
+
+    Returns:
+        List of ``{"copy": first_block, "count": n}`` and ``{"data": base64}`` operations
+    """
+    index: dict[int, list[tuple[str, int]]] = {}
+    for number, block in enumerate(blocks):
+        index.setdefault(block["weak"], []).append((block["strong"], number))
+
+    ops: list[dict] = []
+    literal = bytearray()
+
+    def flush_literal():
+        if literal:
+            ops.append({"data": base64.b64encode(bytes(literal)).decode("ascii")})
+            literal.clear()
+
+    def add_copy(number: int):
+        flush_literal()
+        last = ops[-1] if ops else None
+        if last and "copy" in last and last["copy"] + last["count"] == number:
+            last["count"] += 1
+        else:
+            ops.append({"copy": number, "count": 1})
+
+    size = len(data)
+    pos = 0
+    rolling = bool(index) and size >= block_size
+    if rolling:
+        window = data[:block_size]
+        a = sum(window) % _MOD
+        b = sum((block_size - i) * byte for i, byte in enumerate(window)) % _MOD
+    while rolling and pos + block_size <= size:
+        number = _match(index, a | (b << 16), data[pos : pos + block_size])
+        if number is not None:
+            add_copy(number)
+            pos += block_size
+            if pos + block_size <= size:
+                window = data[pos : pos + block_size]
+                a = sum(window) % _MOD
+                b = sum((block_size - i) * byte for i, byte in enumerate(window)) % _MOD
+            continue
+        # Slide the window one byte
+        out = data[pos]
+        literal.append(out)
+        if pos + block_size < size:
+            a = (a - out + data[pos + block_size]) % _MOD
+            b = (b - block_size * out + a) % _MOD
+        pos += 1
+
+    tail = data[pos:]
+    if tail:
+        # The old file's last block may be short and still match
+        number = _match(index, weak_checksum(tail), tail) if index else None
+        if number is not None:
+            add_copy(number)
+        else:
+            literal.extend(tail)
+    flush_literal()
+    return ops
+
+
+def apply_delta(
+    base: bytes, ops: list[dict], block_size: int = DEFAULT_BLOCK_SIZE, max_size: int | None = None
+) -> bytes:
+    """Rebuild a file from its old copy and a delta.
+
+    Copies can repeat blocks any number of times, so a small delta can
+    describe a huge file; with ``max_size`` the delta is refused as soon as
+    its operations add up to more, before anything is joined.
+
+    Raises:
+        DeltaTooLarge: If the rebuilt file would be larger than ``max_size``
+        DeltaError: If an operation is malformed or refers to blocks the old copy doesn't have
+    """
+    block_count = (len(base) + block_size - 1) // block_size
+    parts = []
+    size = 0
+    for op in ops:
+        if "copy" in op:
+            first, count = op.get("copy"), op.get("count", 1)
+            if not isinstance(first, int) or not isinstance(count, int) or first < 0 or count < 1:
+                raise DeltaError(f"Invalid copy operation: {op}")
+            if first + count > block_count:
+                raise DeltaError(f"Copy of blocks {first}-{first + count - 1} beyond the file's {block_count} blocks")
+            # At most the whole old copy; repeats are what add up
+            part = base[first * block_size : (first + count) * block_size]
+        elif "data" in op:
+            try:
+                part = base64.b64decode(op["data"], validate=True)
+            except (ValueError, TypeError):
+                raise DeltaError("Invalid base64 in data operation")
+        else:
+            raise DeltaError(f"Unknown operation: {op}")
+        size += len(part)
+        if max_size is not None and size > max_size:
+            raise DeltaTooLarge(f"Delta rebuilds a file over the {max_size} byte limit")
+        parts.append(part)
+    return b"".join(parts)
+
+
+def manifest(root: Path, checksums: bool = False) -> list[dict]:
+    """Every regular file under root with its size and mtime (and sha256 if asked), sorted by path.
+
+    Symlinks are skipped so the manifest can't describe files outside root.
+    """
+    entries = []
+    for directory, dirnames, filenames in os.walk(root):
+        dirnames[:] = sorted(d for d in dirnames if not (Path(directory) / d).is_symlink())
+        for name in filenames:
+            path = Path(directory) / name
+            if path.is_symlink() or not path.is_file():
+                continue
+            stat = path.stat()
+            entry = {"path": path.relative_to(root).as_posix(), "size": stat.st_size, "mtime": stat.st_mtime}
+            if checksums:
+                entry["sha256"] = file_sha256(path.read_bytes())
+            entries.append(entry)
+    return sorted(entries, key=lambda e: e["path"])
@@ -0,0 +1,56 @@
+"""Session context templating for execution requests.
+
+Request env values (and, when asked, the code itself) may reference
+variables the platform already knows, e.g. ``${SESSION_ID}`` or
+``${WORKSPACE}``. Only known names are substituted; anything else is left
+untouched so shell and template-literal syntax keeps working, and ``$${NAME}``
+produces a literal ``${NAME}``.
+"""
+
+import re
+
+ENV_NAME_RE = re.compile(r"^[A-Za-z_][A-Za-z0-9_]*$")
+
+_TEMPLATE_RE = re.compile(r"\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)\}")
+
+
+def expand(text: str, variables: dict[str, str]) -> str:
+    """Substitute ``${NAME}`` references to known variables in text."""
+
+    def replace(match: re.Match) -> str:
+        escaped, name = match.groups()
+        if escaped:
+            return f"${{{name}}}"
+        return variables.get(name, match.group(0))
+
+    return _TEMPLATE_RE.sub(replace, text)
+
+
+def resolve_env(env: dict[str, str], context: dict[str, str]) -> dict[str, str]:
+    """Expand request env values against the session context.
+
+    Values are expanded in a single pass against the context only, so env
+    entries can't reference each other and expansion can't recurse.
+
+    Raises:
+        ValueError: If an env var name is not a valid identifier
+    """
+    invalid = sorted(name for name in env if not ENV_NAME_RE.match(name))
+    if invalid:
+        raise ValueError(f"Invalid environment variable names: {', '.join(invalid)}")
+    return {name: expand(value, context) for name, value in env.items()}
+
+
+def redact_command(cmd: list[str], names) -> list[str]:
+    """Mask ``NAME=value`` arguments for the given env names before logging."""
+    names = set(names)
+    return [f"{arg.split('=', 1)[0]}=***" if "=" in arg and arg.split("=", 1)[0] in names else arg for arg in cmd]
+
+
+def build_variables(context: dict[str, str], env: dict[str, str]) -> dict[str, str]:
+    """Variables available to code templates: resolved env overlaid by context.
+
+    Context wins so platform-provided values like SESSION_ID can't be spoofed
+    through request env.
+    """
+    return {**env, **context}
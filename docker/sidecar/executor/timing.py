@@ -0,0 +1,60 @@
+"""Where the time of an execution went.
+
+Reported with every /execute response so a client can tell an overloaded
+pod (slow to start the process) from slow user code (slow to produce
+output or finish). The API adds the time it spent waiting for a pod.
+"""
+
+import asyncio
+import time
+
+READ_SIZE = 64 * 1024
+
+
+class ExecutionTimer:
+    """Marks the phases of one execution, all relative to when the sidecar received it."""
+
+    def __init__(self):
+        self.started = time.perf_counter()
+        self.spawned: float | None = None
+        self.first_output: float | None = None
+
+    def mark_spawned(self) -> None:
+        self.spawned = time.perf_counter()
+
+    def mark_output(self) -> None:
+        if self.first_output is None:
+            self.first_output = time.perf_counter()
+
+    def timings(self) -> dict[str, int | None]:
+        """Milliseconds per phase; a phase that wasn't reached is None.
+
+        spawn_ms runs from receiving the request (pre-exec hook included) to
+        the process running, first_output_ms from then to its first byte of
+        stdout or stderr, and total_ms to now.
+        """
+        now = time.perf_counter()
+
+        def ms(start: float | None, end: float | None) -> int | None:
+            return None if start is None or end is None else int((end - start) * 1000)
+
+        return {
+            "spawn_ms": ms(self.started, self.spawned),
+            "first_output_ms": ms(self.spawned, self.first_output),
+            "total_ms": ms(self.started, now),
+        }
+
+
+async def communicate(proc: asyncio.subprocess.Process, timer: ExecutionTimer) -> tuple[bytes, bytes]:
+    """proc.communicate() without stdin, marking the first byte of output."""
+
+    async def drain(stream: asyncio.StreamReader) -> bytes:
+        chunks = []
+        while chunk := await stream.read(READ_SIZE):
+            timer.mark_output()
+            chunks.append(chunk)
+        return b"".join(chunks)
+
+    stdout, stderr = await asyncio.gather(drain(proc.stdout), drain(proc.stderr))
+    await proc.wait()
+    return stdout, stderr
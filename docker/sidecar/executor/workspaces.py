@@ -0,0 +1,244 @@
+"""Named workspaces inside the working directory.
+
+A pod can hold several workspaces, each a directory /mnt/data/<id> with
+its own policy:
+
+- quota_bytes: uploads that would take the workspace past it are refused,
+  and an execution that leaves it over quota is reported (the files it
+  wrote stay, but nothing more can be uploaded until some are deleted).
+- retention_seconds: the workspace is deleted once it has been idle
+  (no execution or file access) this long.
+- read_only: uploads and deletes are refused, and executions run in a
+  scratch copy of the workspace that is thrown away afterwards.
+
+Policies are kept next to the workspaces, in /mnt/data/.workspaces, so
+they survive a sidecar restart. The file API refuses writes that reach a
+workspace, or the policies, through the working directory (see owner()).
+Executions only get the workspace as their directory: they share a user
+and filesystem with every other workspace, so read-only and the quota
+are advisory for code that goes looking in ../, and workspaces are not
+isolated from each other.
+"""
+
+import json
+import os
+import re
+import shutil
+import stat
+import time
+import uuid
+from collections.abc import Callable
+from dataclasses import asdict, dataclass, field
+from pathlib import Path
+
+WORKSPACE_ID_PATTERN = r"^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$"
+METADATA_DIR = ".workspaces"
+QUOTA_EXCEEDED = "WORKSPACE_QUOTA_EXCEEDED"
+
+_WORKSPACE_ID = re.compile(WORKSPACE_ID_PATTERN)
+
+
+class WorkspaceError(Exception):
+    """A workspace request that can't be served, with the HTTP status and error code to answer with."""
+
+    def __init__(self, message: str, status: int = 400, code: str = "INVALID_WORKSPACE"):
+        super().__init__(message)
+        self.status = status
+        self.code = code
+
+
+@dataclass
+class WorkspacePolicy:
+    quota_bytes: int | None = None
+    retention_seconds: int | None = None
+    read_only: bool = False
+
+
+@dataclass
+class Workspace:
+    id: str
+    policy: WorkspacePolicy = field(default_factory=WorkspacePolicy)
+    created_at: float = field(default_factory=time.time)
+    last_used: float = field(default_factory=time.time)
+
+    def to_dict(self) -> dict:
+        return asdict(self)
+
+
+def directory_size(path: Path) -> int:
+    """Bytes of the regular files under ``path``; symlinks aren't followed."""
+    total = 0
+    for root, _, files in os.walk(path):
+        for name in files:
+            try:
+                st = os.lstat(os.path.join(root, name))
+            except OSError:
+                continue
+            if stat.S_ISREG(st.st_mode):
+                total += st.st_size
+    return total
+
+
+class WorkspaceRegistry:
+    """Workspaces under a working directory and their policies."""
+
+    def __init__(self, root: str, write_file: Callable[[Path, bytes], None] | None = None):
+        self.root = Path(root)
+        self.metadata_dir = self.root / METADATA_DIR
+        # Policies are written through the working directory's storage driver when given
+        self._write_file = write_file
+        self._workspaces: dict[str, Workspace] = {}
+
+    def load(self) -> None:
+        """Read the policies of workspaces left by an earlier sidecar process."""
+        if not self.metadata_dir.is_dir():
+            return
+        for meta in self.metadata_dir.glob("*.json"):
+            try:
+                data = json.loads(meta.read_text())
+                workspace = Workspace(
+                    id=data["id"],
+                    policy=WorkspacePolicy(**data.get("policy", {})),
+                    created_at=data.get("created_at", time.time()),
+                    last_used=data.get("last_used", time.time()),
+                )
+            except (OSError, ValueError, KeyError, TypeError):
+                continue
+            if _WORKSPACE_ID.match(workspace.id) and (self.root / workspace.id).is_dir():
+                self._workspaces[workspace.id] = workspace
+
+    def _save(self, workspace: Workspace) -> None:
+        self.metadata_dir.mkdir(parents=True, exist_ok=True)
+        if self._write_file:
+            self._write_file(self.metadata_dir / f"{workspace.id}.json", json.dumps(workspace.to_dict()).encode())
+            return
+        tmp = self.metadata_dir / f".{workspace.id}.{uuid.uuid4().hex[:8]}"
+        tmp.write_text(json.dumps(workspace.to_dict()))
+        os.replace(tmp, self.metadata_dir / f"{workspace.id}.json")
+
+    @staticmethod
+    def validate_id(workspace_id: str) -> None:
+        if not _WORKSPACE_ID.match(workspace_id):
+            raise WorkspaceError(f"Invalid workspace id: {workspace_id!r}")
+
+    def path(self, workspace_id: str) -> Path:
+        return self.root / workspace_id
+
+    def owner(self, path: Path) -> str | None:
+        """Workspace id (or METADATA_DIR) that ``path`` resolves into, if any.
+
+        Symlinks are followed, so a link in the working directory that points
+        into a workspace belongs to the workspace.
+        """
+        try:
+            parts = path.resolve().relative_to(self.root.resolve()).parts
+        except (OSError, ValueError):
+            return None
+        if parts and (parts[0] == METADATA_DIR or parts[0] in self._workspaces):
+            return parts[0]
+        return None
+
+    def put(self, workspace_id: str, policy: WorkspacePolicy) -> tuple[Workspace, bool]:
+        """Create a workspace, or replace the policy of an existing one; returns it and whether it's new."""
+        self.validate_id(workspace_id)
+        workspace = self._workspaces.get(workspace_id)
+        created = workspace is None
+        if created:
+            self.path(workspace_id).mkdir(parents=True, exist_ok=True)
+            workspace = Workspace(id=workspace_id, policy=policy)
+            self._workspaces[workspace_id] = workspace
+        else:
+            workspace.policy = policy
+        self._save(workspace)
+        return workspace, created
+
+    def get(self, workspace_id: str) -> Workspace:
+        """The workspace, which must exist.
+
+        Raises:
+            WorkspaceError: 400 for an invalid id, 404 for an unknown workspace
+        """
+        self.validate_id(workspace_id)
+        workspace = self._workspaces.get(workspace_id)
+        if workspace is None:
+            raise WorkspaceError(f"Workspace not found: {workspace_id}", status=404, code="WORKSPACE_NOT_FOUND")
+        return workspace
+
+    def all(self) -> list[Workspace]:
+        return sorted(self._workspaces.values(), key=lambda w: w.id)
+
+    def delete(self, workspace_id: str) -> None:
+        self.get(workspace_id)
+        shutil.rmtree(self.path(workspace_id), ignore_errors=True)
+        (self.metadata_dir / f"{workspace_id}.json").unlink(missing_ok=True)
+        del self._workspaces[workspace_id]
+
+    def touch(self, workspace_id: str) -> None:
+        """Mark the workspace used now, restarting its retention period."""
+        workspace = self._workspaces.get(workspace_id)
+        if workspace:
+            workspace.last_used = time.time()
+            self._save(workspace)
+
+    def usage(self, workspace_id: str) -> int:
+        return directory_size(self.path(workspace_id))
+
+    def check_writable(self, workspace_id: str, incoming_bytes: int = 0) -> Workspace:
+        """The workspace, if ``incoming_bytes`` more may be written to it through the file API.
+
+        Deletes (no incoming bytes) are allowed over quota, so space can be freed.
+
+        Raises:
+            WorkspaceError: 403 if read-only, 413 if the write would exceed the quota
+        """
+        workspace = self.get(workspace_id)
+        if workspace.policy.read_only:
+            raise WorkspaceError(f"Workspace is read-only: {workspace_id}", status=403, code="WORKSPACE_READ_ONLY")
+        quota = workspace.policy.quota_bytes
+        if quota is not None and incoming_bytes > 0:
+            used = self.usage(workspace_id)
+            if used + incoming_bytes > quota:
+                raise WorkspaceError(
+                    f"Workspace quota exceeded: {used + incoming_bytes} of {quota} bytes",
+                    status=413,
+                    code=QUOTA_EXCEEDED,
+                )
+        return workspace
+
+    def quota_error(self, workspace_id: str) -> dict | None:
+        """Structured error when the workspace is over its quota, else None."""
+        workspace = self._workspaces.get(workspace_id)
+        if workspace is None or workspace.policy.quota_bytes is None:
+            return None
+        used = self.usage(workspace_id)
+        if used <= workspace.policy.quota_bytes:
+            return None
+        return {
+            "code": QUOTA_EXCEEDED,
+            "workspace": workspace_id,
+            "usage_bytes": used,
+            "quota_bytes": workspace.policy.quota_bytes,
+            "message": f"Workspace {workspace_id} uses {used} of its {workspace.policy.quota_bytes} bytes",
+        }
+
+    def scratch_copy(self, workspace_id: str) -> Path:
+        """Copy of the workspace for an execution whose changes are discarded (read-only workspaces)."""
+        scratch = self.metadata_dir / f"scratch-{workspace_id}-{uuid.uuid4().hex[:8]}"
+        shutil.copytree(self.path(workspace_id), scratch, symlinks=True)
+        return scratch
+
+    def expired(self, now: float | None = None) -> list[str]:
+        """Workspaces idle for longer than their retention period."""
+        now = time.time() if now is None else now
+        return [
+            w.id
+            for w in self._workspaces.values()
+            if w.policy.retention_seconds is not None and now - w.last_used > w.policy.retention_seconds
+        ]
+
+    def sweep(self, now: float | None = None) -> list[str]:
+        """Delete expired workspaces; returns their ids."""
+        expired = self.expired(now)
+        for workspace_id in expired:
+            self.delete(workspace_id)
+        return expired
@@ -9,39 +9,192 @@ Requires: shareProcessNamespace: true in the pod spec.
 """
 
 import asyncio
+import json
 import os
 import shlex
 import shutil
+import signal
+import sys
+import tempfile
 import time
 import traceback
+import uuid
+from collections import deque
+from collections.abc import AsyncIterator
 from contextlib import asynccontextmanager
 from datetime import datetime
 from pathlib import Path
-from typing import Optional
+from typing import Literal, Optional
 
-from fastapi import FastAPI, File, HTTPException, Response, UploadFile
-from fastapi.responses import FileResponse
+from fastapi import FastAPI, File, HTTPException, Request, Response, UploadFile
+from fastapi.responses import FileResponse, StreamingResponse
 from pydantic import BaseModel, Field
 
+from executor import (
+    bench,
+    compilecache,
+    connections,
+    debug,
+    defaults,
+    degradation,
+    diffpatch,
+    dns,
+    faults,
+    filewrite,
+    hooks,
+    interrupt,
+    listen,
+    lsp,
+    media,
+    policy,
+    priority,
+    provenance,
+    render,
+    rootfs,
+    runtime,
+    sandbox,
+    search,
+    selftest,
+    storage,
+    strict,
+    symbols,
+    sync,
+    templating,
+    timing,
+    workspaces,
+)
+
 # Configuration from environment
+# Policy bundle the API runs under (see executor.policy); defaults for MAX_EXECUTION_TIME and DNS_ALLOWLIST
+POLICY = policy.load(os.getenv("POLICY_BUNDLE", ""))
+# Limits built into the agent (see executor.defaults), for when neither the environment nor the bundle sets them
+POLICY_DEFAULTS = defaults.policy()
 WORKING_DIR = os.getenv("WORKING_DIR", "/mnt/data")
+# Volume the working directory is on (see executor.storage)
+STORAGE = storage.load(os.getenv("WORKSPACE_STORAGE", ""))
+# Node-shared compiler caches (ccache, Go build cache) mounted read-write by the API; off when unset
+COMPILE_CACHE = compilecache.load(os.getenv("COMPILE_CACHE_DIR", ""), os.getenv("COMPILE_CACHE_MAX_MB", ""))
+COMPILE_CACHE_SWEEP_INTERVAL = int(os.getenv("COMPILE_CACHE_SWEEP_INTERVAL", "60"))
 LANGUAGE = os.getenv("LANGUAGE", "python")
-MAX_EXECUTION_TIME = int(os.getenv("MAX_EXECUTION_TIME", "120"))
-MAX_OUTPUT_SIZE = int(os.getenv("MAX_OUTPUT_SIZE", "1048576"))  # 1MB
+MAX_EXECUTION_TIME = policy.max_execution_time(
+    os.getenv("MAX_EXECUTION_TIME", ""), POLICY, int(POLICY_DEFAULTS["limits"]["max_execution_time"])
+)
+MAX_OUTPUT_SIZE = int(os.getenv("MAX_OUTPUT_SIZE") or POLICY_DEFAULTS["limits"]["max_output_size"])  # 1MB
 # Process name to identify main container (set via env, defaults based on language)
 MAIN_PROCESS_NAME = os.getenv("MAIN_PROCESS_NAME", "")
 # Version from build arg (set via Dockerfile ARG -> ENV)
 VERSION = os.getenv("VERSION", "0.0.0-dev")
 # Network isolation mode - when true, disables network-dependent features (e.g., Go module proxy)
 NETWORK_ISOLATED = os.getenv("NETWORK_ISOLATED", "false").lower() in ("true", "1", "yes")
+# Strict request validation, path normalization and anomaly logging (see executor.strict)
+STRICT_MODE = os.getenv("STRICT_MODE", "false").lower() in ("true", "1", "yes")
+# Temp files, compiled binaries and executions' TMPDIR; must be writable in both containers (see executor.rootfs)
+SCRATCH_DIR = os.getenv("SCRATCH_DIR", "/tmp")
+# Environment used when the main container's environment can't be read
+DEFAULT_ENV = {"PATH": "/usr/local/bin:/usr/bin:/bin", "HOME": SCRATCH_DIR, "TMPDIR": SCRATCH_DIR}
+# Upper bound for files written by the media (ffmpeg) profile
+MAX_MEDIA_OUTPUT_SIZE = int(os.getenv("MAX_MEDIA_OUTPUT_SIZE", "104857600"))  # 100MB
+# Upper bound for files rebuilt by /sync/patch
+MAX_SYNC_FILE_SIZE = int(os.getenv("MAX_SYNC_FILE_SIZE", "104857600"))  # 100MB
+
+# DNS policy: the pod's resolv.conf points at the sidecar, which forwards allowed lookups here
+DNS_UPSTREAM = os.getenv("DNS_UPSTREAM", "")
+# Operator allowlist applying to every lookup in the pod (comma-separated; empty allows all)
+DNS_ALLOWLIST = policy.dns_allowlist(os.getenv("DNS_ALLOWLIST"), POLICY)
+
+# Seconds between degradation probes (0 disables them), and the slowdown over baseline that marks the pod degraded
+HEALTH_PROBE_INTERVAL = int(os.getenv("HEALTH_PROBE_INTERVAL", "30"))
+DEGRADED_FACTOR = float(os.getenv("DEGRADED_FACTOR", str(degradation.DEFAULT_FACTOR)))
+# Seconds between sweeps deleting workspaces idle past their retention
+WORKSPACE_SWEEP_INTERVAL = int(os.getenv("WORKSPACE_SWEEP_INTERVAL", "60"))
+# Seconds a language server may sit unused before it's shut down
+LSP_IDLE_TIMEOUT = int(os.getenv("LSP_IDLE_TIMEOUT", str(lsp.DEFAULT_IDLE_TIMEOUT)))
+# Faults injected for resilience testing, e.g. "spawn_failure=0.1,drop=0.05" (see executor.faults)
+FAULT_INJECTION = os.getenv("FAULT_INJECTION", "")
+
+# Recent log lines and executions for GET /debug/bundle; logs still go to the container's output
+LOGS = debug.LogBuffer()
+sys.stdout = LOGS.tee(sys.stdout)
+sys.stderr = LOGS.tee(sys.stderr)
+HISTORY = debug.ExecutionHistory()
+
+# Running executions that POST /interrupt can signal
+INTERRUPTS = interrupt.InterruptRegistry()
+# Running executions and the names each may resolve
+DNS_POLICIES = dns.PolicyRegistry(DNS_ALLOWLIST)
+# Sockets of the DNS policy resolver, while it's listening
+DNS_RESOLVER = None
+# Baselines of the degradation probes, reported by /ready
+HEALTH = degradation.HealthMonitor(
+    DEGRADED_FACTOR,
+    probes=("spawn", "disk") + (("interpreter",) if LANGUAGE in degradation.STARTUP_PROBES else ()),
+)
+# Named workspaces under the working directory (see executor.workspaces)
+WORKSPACES = workspaces.WorkspaceRegistry(WORKING_DIR, write_file=STORAGE.write_file)
+# Running language servers for the /lsp endpoints
+LSP = lsp.LspManager(
+    spawn=lambda command, root: spawn_language_server(command, root),
+    available=lambda names: probe_binaries(names),
+)
+# Hashes and versions of the binaries executions ran (see executor.provenance)
+PROVENANCE = provenance.ProvenanceCache(
+    run=lambda args, timeout: run_in_main_container(args, WORKING_DIR, timeout),
+)
+# Fault injector, when FAULT_INJECTION is set
+FAULTS = faults.FaultInjector.parse(FAULT_INJECTION)
+# Requests refused in STRICT_MODE, per reason and client
+ANOMALIES = strict.AnomalyMonitor()
+# Which directories each container can write to, once checked at startup
+ROOTFS_REPORT: dict | None = None
+# Uploads the server spools to disk, and any other temp file of the sidecar's
+tempfile.tempdir = SCRATCH_DIR
+
+
+class RequestModel(BaseModel):
+    """Base of request bodies: strict types and no unknown fields in STRICT_MODE."""
+    model_config = strict.model_config(STRICT_MODE)
+
+
+class ExecHooks(RequestModel):
+    """Operator-defined shell scripts run around the execution."""
+    pre: str | None = None
+    post: str | None = None
+    timeout: int = Field(default=10, ge=1, le=300)  # Budget of each hook, in seconds
 
-class ExecuteRequest(BaseModel):
+
+class ExecPriority(RequestModel):
+    """Lower OOM/CPU/I/O priority for background executions (see executor.priority)."""
+    oom_score_adj: int | None = Field(default=None, ge=0, le=1000)  # Raised to at least this
+    cpu_weight: int | None = Field(default=None, ge=1, le=priority.DEFAULT_CPU_WEIGHT)
+    nice: int | None = Field(default=None, ge=0, le=19)
+    io_class: Literal["best-effort", "idle"] | None = None
+    # Best-effort level; 4 is the default, 7 the lowest
+    io_priority: int | None = Field(default=None, ge=priority.DEFAULT_IO_PRIORITY, le=priority.LOWEST_IO_PRIORITY)
+    cpu_affinity: list[int] | None = Field(default=None, min_length=1)  # Checked against the pod's CPUs
+
+
+class ExecuteRequest(RequestModel):
     """Request to execute code."""
     code: str
     timeout: int = Field(default=30, ge=1, le=MAX_EXECUTION_TIME)
     working_dir: str = Field(default=WORKING_DIR)
+    workspace: str | None = None  # Named workspace to run in, instead of working_dir
     initial_state: str | None = None  # Base64-encoded state
     capture_state: bool = False
+    env: dict[str, str] = Field(default_factory=dict)  # Extra env vars, may use ${...} templates
+    context: dict[str, str] = Field(default_factory=dict)  # Template variables from the API (e.g. SESSION_ID)
+    template_code: bool = False  # Also expand ${...} templates in the code
+    hooks: ExecHooks | None = None
+    priority: ExecPriority | None = None
+    max_connections: int | None = Field(default=None, ge=1)  # Open at once; the execution is killed above it
+    dns_allowlist: list[str] | None = None  # Names the execution may resolve, within DNS_ALLOWLIST
+
+
+class WorkspaceRequest(RequestModel):
+    """Policy of a named workspace."""
+    quota_bytes: int | None = Field(default=None, ge=0)
+    retention_seconds: int | None = Field(default=None, ge=60)  # Idle time before it's deleted
+    read_only: bool = False
 
 
 class ExecuteResponse(BaseModel):
@@ -52,6 +205,74 @@ class ExecuteResponse(BaseModel):
     execution_time_ms: int
     state: str | None = None  # Base64-encoded state
     state_errors: list | None = None
+    interrupted: bool = False  # Stopped by POST /interrupt
+    error: dict | None = None  # Structured failure, e.g. {"code": "RUNTIME_NOT_FOUND", "wanted": ...}
+    connections: int | None = None  # Outbound connections seen; None when not counted
+    dns_denied: list[str] | None = None  # Lookups refused by the DNS policy; None without one
+    timings: dict | None = None  # spawn_ms, first_output_ms and total_ms (see executor.timing)
+    provenance: dict | None = None  # Command and interpreter binaries (path, sha256, version) that ran the code
+    sandbox: dict | None = None  # Protections the code ran under (see executor.sandbox)
+    compile_cache: dict | None = None  # Compiler cache hits and misses; None without a cache
+
+
+class RenderRequest(RequestModel):
+    """Request to render a LaTeX or Markdown document.
+
+    Exactly one of source (inline document) or path (file in the working
+    directory) must be provided.
+    """
+    format: Literal["latex", "markdown"]
+    output: Literal["pdf", "html"] = "pdf"
+    source: str | None = None
+    path: str | None = None
+    filename: str | None = None  # Artifact name, derived from the source if omitted
+    timeout: int = Field(default=60, ge=1, le=MAX_EXECUTION_TIME)
+
+
+class MediaRequest(RequestModel):
+    """Request to process a media file with ffmpeg.
+
+    Progress and the final result are streamed back as NDJSON events.
+    """
+    input: str  # Path in the working directory
+    output: str  # Output path in the working directory; the extension selects the container format
+    args: list[str] = Field(default_factory=list)  # Output options, e.g. ["-c:v", "libx264", "-crf", "28"]
+    max_output_size: int = Field(default=MAX_MEDIA_OUTPUT_SIZE, ge=1, le=MAX_MEDIA_OUTPUT_SIZE)
+    timeout: int = Field(default=MAX_EXECUTION_TIME, ge=1, le=MAX_EXECUTION_TIME)
+
+
+class BlockSignature(RequestModel):
+    """Checksums of one block of a file."""
+    weak: int
+    strong: str
+
+
+class SyncSignature(RequestModel):
+    """Block signature of a file, sent by the side holding the old copy."""
+    block_size: int = Field(default=sync.DEFAULT_BLOCK_SIZE, ge=sync.MIN_BLOCK_SIZE, le=sync.MAX_BLOCK_SIZE)
+    blocks: list[BlockSignature] = Field(default_factory=list)
+
+
+class SyncDelta(RequestModel):
+    """Operations that turn the old copy of a file into the new one."""
+    block_size: int = Field(default=sync.DEFAULT_BLOCK_SIZE, ge=sync.MIN_BLOCK_SIZE, le=sync.MAX_BLOCK_SIZE)
+    ops: list[dict] = Field(default_factory=list)  # {"copy": block, "count": n} or {"data": base64}
+    sha256: str | None = None  # Of the new copy; the patch is rejected if the result differs
+
+
+class FilePatchRequest(RequestModel):
+    """Unified diff to apply to the working directory (or a workspace)."""
+    diff: str
+    strip: int = Field(default=1, ge=0)  # Leading path components to drop, as patch -p
+    dry_run: bool = False
+
+
+class LspRequest(RequestModel):
+    """A file, and for hover and definition a 1-based position in it, to ask a language server about."""
+    path: str
+    line: int = Field(default=1, ge=1)
+    column: int = Field(default=1, ge=1)
+    workspace: str | None = None
 
 
 class HealthResponse(BaseModel):
@@ -60,6 +281,10 @@ class HealthResponse(BaseModel):
     language: str
     working_dir: str
     timestamp: str
+    policy: dict | None = None  # Name, version and digest of POLICY_BUNDLE
+    storage: dict | None = None  # Driver of the working directory's volume and its semantics
+    strict: dict | None = None  # Requests refused in STRICT_MODE; None when it's off
+    compile_cache: dict | None = None  # Usage, quota and hit rate of the node's compiler cache
 
 
 class FileInfo(BaseModel):
@@ -70,8 +295,59 @@ class FileInfo(BaseModel):
     mime_type: str | None = None
 
 
-def validate_path_within_working_dir(path: str) -> Path:
-    """Validate and resolve a path, ensuring it's within the working directory.
+class RenderResponse(BaseModel):
+    """Response from document rendering."""
+    exit_code: int
+    toolchain: str
+    output: FileInfo | None = None
+    log: str
+    execution_time_ms: int
+
+
+def workspace_dir(workspace: str | None) -> Path:
+    """Directory the file API works in: the named workspace, else the working directory.
+
+    Raises:
+        HTTPException: 400 for an invalid workspace id, 404 for an unknown workspace
+    """
+    if not workspace:
+        return Path(WORKING_DIR)
+    try:
+        WORKSPACES.get(workspace)
+    except workspaces.WorkspaceError as e:
+        raise HTTPException(status_code=e.status, detail=str(e))
+    WORKSPACES.touch(workspace)
+    return WORKSPACES.path(workspace)
+
+
+def check_workspace_writable(workspace: str | None, incoming_bytes: int = 0) -> None:
+    """Refuse writes to read-only workspaces and uploads past a workspace's quota."""
+    if not workspace:
+        return
+    try:
+        WORKSPACES.check_writable(workspace, incoming_bytes)
+    except workspaces.WorkspaceError as e:
+        raise HTTPException(status_code=e.status, detail=str(e))
+
+
+def check_path_writable(path: Path, workspace: str | None, incoming_bytes: int = 0) -> None:
+    """As check_workspace_writable, and refuse writes through the working directory into a workspace.
+
+    Without this a path like ``<id>/file`` (or a symlink into a workspace)
+    would bypass its read-only flag and quota, and writing to ``.workspaces/``
+    would replace their policies.
+    """
+    if not workspace:
+        owner = WORKSPACES.owner(path)
+        if owner == workspaces.METADATA_DIR:
+            raise HTTPException(status_code=403, detail="Access denied")
+        if owner:
+            raise HTTPException(status_code=403, detail=f"Path is in workspace {owner}; use ?workspace={owner}")
+    check_workspace_writable(workspace, incoming_bytes)
+
+
+def validate_path_within_working_dir(path: str, base: Path | None = None) -> Path:
+    """Validate and resolve a path, ensuring it's within the working directory (or ``base``).
 
     Uses Path.is_relative_to() for proper path containment validation,
     which correctly handles prefix collision attacks (e.g., /mnt/data vs /mnt/data-evil).
@@ -84,11 +360,16 @@ def validate_path_within_working_dir(path: str) -> Path:
 
     Raises:
         HTTPException: 403 if path escapes working directory
-        HTTPException: 400 if path is invalid
+        HTTPException: 400 if path is invalid, or refused by STRICT_MODE
     """
+    if STRICT_MODE:
+        try:
+            path = strict.normalize_path(path)
+        except strict.PathRejected as e:
+            raise HTTPException(status_code=400, detail=str(e))
     try:
-        file_path = (Path(WORKING_DIR) / path).resolve()
-        working_path = Path(WORKING_DIR).resolve()
+        file_path = (Path(base or WORKING_DIR) / path).resolve()
+        working_path = Path(base or WORKING_DIR).resolve()
 
         # Use is_relative_to() for proper path containment check
         # This correctly handles prefix collisions like /mnt/data vs /mnt/data-evil
@@ -105,10 +386,39 @@ def validate_path_within_working_dir(path: str) -> Path:
 @asynccontextmanager
 async def lifespan(app: FastAPI):
     """Application lifespan handler."""
+    global DNS_RESOLVER
     # Startup
     os.makedirs(WORKING_DIR, exist_ok=True)
+    WORKSPACES.load()
+    if DNS_UPSTREAM:
+        try:
+            DNS_RESOLVER = await dns.start_resolver(DNS_UPSTREAM, DNS_POLICIES)
+            print(f"[DNS] Resolving for the pod via {DNS_UPSTREAM}, allowlist={DNS_ALLOWLIST or 'all'}", flush=True)
+        except OSError as e:
+            # Lookups in the pod fail until this is fixed; executions with an allowlist are refused
+            print(f"[DNS] Failed to listen on port {dns.PORT} of {', '.join(dns.LISTEN_ADDRESSES)}: {e}", flush=True)
+    probes = asyncio.create_task(probe_health_loop()) if HEALTH_PROBE_INTERVAL > 0 else None
+    sweeper = asyncio.create_task(workspace_retention_loop()) if WORKSPACE_SWEEP_INTERVAL > 0 else None
+    cache_sweeper = (
+        asyncio.create_task(compile_cache_sweep_loop()) if COMPILE_CACHE and COMPILE_CACHE_SWEEP_INTERVAL > 0 else None
+    )
+    lsp_reaper = asyncio.create_task(lsp_idle_loop()) if LSP_IDLE_TIMEOUT > 0 else None
+    rootfs_check = asyncio.create_task(check_rootfs())
     yield
-    # Shutdown - nothing to clean up
+    # Shutdown
+    if probes:
+        probes.cancel()
+    if sweeper:
+        sweeper.cancel()
+    if cache_sweeper:
+        cache_sweeper.cancel()
+    if lsp_reaper:
+        lsp_reaper.cancel()
+    rootfs_check.cancel()
+    await LSP.stop_all()
+    if DNS_RESOLVER:
+        for transport in DNS_RESOLVER:
+            transport.close()
 
 
 app = FastAPI(
@@ -117,6 +427,14 @@ app = FastAPI(
     version=VERSION,
     lifespan=lifespan,
 )
+if FAULTS:
+    print(f"[FAULT] Fault injection enabled: {FAULT_INJECTION}", flush=True)
+    app.add_middleware(faults.FaultMiddleware, injector=FAULTS)
+if STRICT_MODE:
+    print("[STRICT] Strict request validation enabled", flush=True)
+    app.add_middleware(strict.StrictMiddleware, monitor=ANOMALIES)
+if POLICY:
+    print(f"[POLICY] Running under {POLICY.name} {POLICY.version} ({POLICY.digest})", flush=True)
 
 
 def find_main_container_pid() -> int | None:
@@ -195,7 +513,8 @@ def apply_network_isolation_overrides(env: dict[str, str], language: str) -> dic
     environment to enable offline/air-gapped operation.
 
     Args:
-        env: The container environment dictionary (will be modified in place)
+        env: The container environment dictionary (will be modified in place),
+            with any request env already applied
         language: The language being executed
 
     Returns:
@@ -218,8 +537,38 @@ def apply_network_isolation_overrides(env: dict[str, str], language: str) -> dic
     return env
 
 
+def resolve_request_templates(request: ExecuteRequest) -> tuple[dict[str, str], str]:
+    """Resolve ${...} templates in the request env (and optionally the code).
+
+    WORKSPACE and LANGUAGE are known to the sidecar; everything else in the
+    context (SESSION_ID, ...) is supplied by the API.
+
+    Returns:
+        Tuple of (env overrides, code to execute)
+
+    Raises:
+        ValueError: If the request env contains invalid variable names
+    """
+    context = {**request.context, "WORKSPACE": request.working_dir, "LANGUAGE": LANGUAGE}
+    env = templating.resolve_env(request.env, context)
+    code = request.code
+    if request.template_code:
+        code = templating.expand(code, templating.build_variables(context, env))
+    return env, code
+
+
+def apply_scratch_dir(env: dict[str, str]) -> dict[str, str]:
+    """Point temp files at SCRATCH_DIR, unless the image sets its own TMPDIR.
+
+    The main container's /tmp is on its root filesystem, which may be read-only.
+    """
+    if env:
+        env.setdefault("TMPDIR", SCRATCH_DIR)
+    return env
+
+
 def get_language_command(
-    language: str, code: str, working_dir: str, container_env: dict[str, str]
+    language: str, code: str, working_dir: str, container_env: dict[str, str], compile_cache: bool = False
 ) -> tuple[list[str], Path | None]:
     """Get the command to execute code for a given language.
 
@@ -233,9 +582,12 @@ def get_language_command(
     - Shell mode: Uses 'sh -c' for multi-step (compile && run) commands
 
     Both modes use the runtime-detected environment from the container.
+
+    With compile_cache, C, C++ and Fortran are compiled through ccache, if the
+    image has it, and linked separately (ccache only caches compilations).
     """
     # Use container env, fall back to minimal defaults if not available
-    env = container_env if container_env else {"PATH": "/usr/local/bin:/usr/bin:/bin", "HOME": "/tmp"}
+    env = container_env if container_env else DEFAULT_ENV
 
     # Single wrapper using /usr/bin/env -i with runtime-detected environment
     def wrap(cmd_args: list[str]) -> list[str]:
@@ -244,6 +596,15 @@ def get_language_command(
 
     # Helper for compiled languages needing shell for compile && run
     safe_wd = shlex.quote(working_dir)
+    # Binaries of compiled languages go to SCRATCH_DIR, which stays writable with a read-only root filesystem
+    binary = shlex.quote(os.path.join(SCRATCH_DIR, "code"))
+
+    def compile_and_run(compiler: str, code_file: Path) -> list[str]:
+        if not compile_cache:
+            return ["sh", "-c", f"cd {safe_wd} && {compiler} {code_file} -o {binary} && {binary}"]
+        obj = shlex.quote(os.path.join(SCRATCH_DIR, "code.o"))
+        compile_step = f"$(command -v ccache) {compiler} -c {code_file} -o {obj}"
+        return ["sh", "-c", f"cd {safe_wd} && {compile_step} && {compiler} {obj} -o {binary} && {binary}"]
 
     if language in ("python", "py"):
         code_file = Path(working_dir) / "code.py"
@@ -264,7 +625,7 @@ def get_language_command(
     elif language in ("rust", "rs"):
         code_file = Path(working_dir) / "main.rs"
         code_file.write_text(code)
-        return wrap(["sh", "-c", f"cd {safe_wd} && rustc {code_file} -o /tmp/main && /tmp/main"]), code_file
+        return wrap(["sh", "-c", f"cd {safe_wd} && rustc {code_file} -o {binary} && {binary}"]), code_file
     elif language in ("java",):
         code_file = Path(working_dir) / "Code.java"
         code_file.write_text(code)
@@ -272,11 +633,11 @@ def get_language_command(
     elif language in ("c",):
         code_file = Path(working_dir) / "code.c"
         code_file.write_text(code)
-        return wrap(["sh", "-c", f"cd {safe_wd} && gcc {code_file} -o /tmp/code && /tmp/code"]), code_file
+        return wrap(compile_and_run("gcc", code_file)), code_file
     elif language in ("cpp",):
         code_file = Path(working_dir) / "code.cpp"
         code_file.write_text(code)
-        return wrap(["sh", "-c", f"cd {safe_wd} && g++ {code_file} -o /tmp/code && /tmp/code"]), code_file
+        return wrap(compile_and_run("g++", code_file)), code_file
     elif language in ("php",):
         code_file = Path(working_dir) / "code.php"
         code_file.write_text(code)
@@ -288,16 +649,64 @@ def get_language_command(
     elif language in ("fortran", "f90"):
         code_file = Path(working_dir) / "code.f90"
         code_file.write_text(code)
-        return wrap(["sh", "-c", f"cd {safe_wd} && gfortran {code_file} -o /tmp/code && /tmp/code"]), code_file
+        return wrap(compile_and_run("gfortran", code_file)), code_file
     elif language in ("d", "dlang"):
         code_file = Path(working_dir) / "code.d"
         code_file.write_text(code)
-        return wrap(["sh", "-c", f"cd {safe_wd} && ldc2 {code_file} -of=/tmp/code && /tmp/code"]), code_file
+        return wrap(["sh", "-c", f"cd {safe_wd} && ldc2 {code_file} -of={binary} && {binary}"]), code_file
     else:
         return [], None
 
 
-async def execute_via_nsenter(request: ExecuteRequest) -> ExecuteResponse:
+def priority_preexec(request: ExecuteRequest):
+    """preexec_fn applying the request's lower priority to the spawned process, if any."""
+    if not request.priority:
+        return None
+    return priority.make_preexec(**request.priority.model_dump())
+
+
+async def communicate_counting_connections(
+    proc: asyncio.subprocess.Process, request: ExecuteRequest, timer: timing.ExecutionTimer
+) -> tuple[bytes, bytes, connections.ConnectionMonitor | None]:
+    """proc.communicate(), counting the execution's outbound connections meanwhile.
+
+    An execution with more than ``request.max_connections`` open at once is
+    killed. Network-isolated pods have nothing to count.
+    """
+    if NETWORK_ISOLATED:
+        stdout, stderr = await timing.communicate(proc, timer)
+        return stdout, stderr, None
+
+    monitor = connections.ConnectionMonitor(proc.pid, request.max_connections)
+
+    async def watch() -> None:
+        while await asyncio.to_thread(monitor.sample):
+            await asyncio.sleep(connections.SAMPLE_INTERVAL)
+        print(f"[EXECUTE] {monitor.peak} connections open (limit {monitor.limit}), killing pid={proc.pid}", flush=True)
+        try:
+            os.killpg(proc.pid, signal.SIGKILL)
+        except ProcessLookupError:
+            pass
+
+    watcher = asyncio.create_task(watch())
+    try:
+        stdout, stderr = await timing.communicate(proc, timer)
+    finally:
+        watcher.cancel()
+    return stdout, stderr, monitor
+
+
+async def record_provenance(main_pid: int, container_env: dict[str, str], cmd: list[str]) -> dict | None:
+    """The execution's command and interpreter binaries (see executor.provenance), None if unreadable."""
+    try:
+        path_env = container_env.get("PATH", DEFAULT_ENV["PATH"])
+        return await PROVENANCE.record(f"/proc/{main_pid}/root", path_env, cmd, LANGUAGE)
+    except Exception as e:
+        print(f"[EXECUTE] Provenance failed: {type(e).__name__}: {e}", flush=True)
+        return None
+
+
+async def execute_via_nsenter(request: ExecuteRequest, timer: timing.ExecutionTimer) -> ExecuteResponse:
     """Execute code in the main container using nsenter.
 
     This requires shareProcessNamespace: true in the pod spec.
@@ -309,19 +718,24 @@ async def execute_via_nsenter(request: ExecuteRequest) -> ExecuteResponse:
         main_pid = find_main_container_pid()
         if not main_pid:
             # Fallback: try to execute directly (might work if runtime is in sidecar)
-            return await execute_via_subprocess_direct(request)
+            return await execute_via_subprocess_direct(request, timer)
+
+        env_overrides, code = resolve_request_templates(request)
 
         # Read the container's environment from /proc/<pid>/environ
         # This ensures we use the exact environment from the Dockerfile,
         # eliminating config drift between Dockerfiles and sidecar code
         container_env = get_container_env(main_pid)
 
-        # Apply network isolation overrides if enabled
+        container_env = apply_scratch_dir(container_env)
+        container_env.update(env_overrides)
+        # Network isolation overrides go last so request env can't undo them
         container_env = apply_network_isolation_overrides(container_env, LANGUAGE)
+        cache_run = COMPILE_CACHE.prepare(LANGUAGE, container_env) if COMPILE_CACHE else None
 
         # Get the command for this language (this writes code to a temp file)
         cmd, temp_file = get_language_command(
-            LANGUAGE, request.code, request.working_dir, container_env
+            LANGUAGE, code, request.working_dir, container_env, compile_cache=cache_run is not None
         )
         if not cmd:
             return ExecuteResponse(
@@ -330,6 +744,8 @@ async def execute_via_nsenter(request: ExecuteRequest) -> ExecuteResponse:
                 stderr=f"Unsupported language: {LANGUAGE}",
                 execution_time_ms=0,
             )
+    except ValueError as e:
+        return ExecuteResponse(exit_code=1, stdout="", stderr=str(e), execution_time_ms=0)
     except Exception as e:
         return ExecuteResponse(
             exit_code=1,
@@ -353,27 +769,39 @@ async def execute_via_nsenter(request: ExecuteRequest) -> ExecuteResponse:
         *wd_args,
         "--",
     ] + cmd
+    # Resolved while the code runs; hashes and versions are cached after the first execution
+    provenance_task = asyncio.create_task(record_provenance(main_pid, container_env, cmd))
+    # Inherited by the code from the sidecar, read as the execution starts
+    sandbox_report = sandbox.report(NETWORK_ISOLATED, DNS_RESOLVER is not None)
 
     # Debug logging - use flush=True to ensure output before container termination
     print(f"[EXECUTE] main_pid={main_pid}, language={LANGUAGE}", flush=True)
     print(f"[EXECUTE] container_env PATH={container_env.get('PATH', 'NOT SET')}", flush=True)
-    print(f"[EXECUTE] nsenter_cmd={nsenter_cmd}", flush=True)
+    # Request env commonly carries credentials; keep values out of pod logs
+    print(f"[EXECUTE] nsenter_cmd={templating.redact_command(nsenter_cmd, env_overrides)}", flush=True)
     if temp_file:
         print(f"[EXECUTE] code_file={temp_file}, exists={temp_file.exists()}, size={temp_file.stat().st_size if temp_file.exists() else 0}", flush=True)
 
     try:
         print(f"[EXECUTE] Creating subprocess...", flush=True)
+        # Own process group so POST /interrupt can signal the whole execution
         proc = await asyncio.create_subprocess_exec(
             *nsenter_cmd,
             stdout=asyncio.subprocess.PIPE,
             stderr=asyncio.subprocess.PIPE,
             cwd=request.working_dir,
+            start_new_session=True,
+            preexec_fn=priority_preexec(request),
         )
+        timer.mark_spawned()
+        INTERRUPTS.register(proc.pid)
+        if DNS_RESOLVER:
+            DNS_POLICIES.register(proc.pid, request.dns_allowlist)
         print(f"[EXECUTE] Subprocess created, pid={proc.pid}, waiting for completion (timeout={request.timeout}s)...", flush=True)
 
         try:
-            stdout, stderr = await asyncio.wait_for(
-                proc.communicate(),
+            stdout, stderr, monitor = await asyncio.wait_for(
+                communicate_counting_connections(proc, request, timer),
                 timeout=request.timeout,
             )
         except TimeoutError:
@@ -385,12 +813,31 @@ async def execute_via_nsenter(request: ExecuteRequest) -> ExecuteResponse:
                 stdout="",
                 stderr=f"Execution timed out after {request.timeout} seconds",
                 execution_time_ms=int((time.perf_counter() - start_time) * 1000),
+                provenance=await provenance_task,
+                sandbox=sandbox_report,
+                compile_cache=COMPILE_CACHE.finish(cache_run) if cache_run else None,
             )
+        finally:
+            interrupted = INTERRUPTS.finish(proc.pid)
+            dns_denied = DNS_POLICIES.finish(proc.pid) if DNS_RESOLVER else None
 
         execution_time_ms = int((time.perf_counter() - start_time) * 1000)
 
         stdout_str = stdout.decode("utf-8", errors="replace")[:MAX_OUTPUT_SIZE]
         stderr_str = stderr.decode("utf-8", errors="replace")[:MAX_OUTPUT_SIZE]
+        exit_code = proc.returncode or 0
+        error = None
+        if interrupted:
+            exit_code, stderr_str = interrupt.interrupted_result(proc.returncode, stderr_str, LANGUAGE)
+            print(f"[EXECUTE] Interrupted, exit_code={exit_code}", flush=True)
+        elif monitor and monitor.exceeded:
+            error = monitor.error()
+            stderr_str += f"\n{error['code']}: {error['message']}\n"
+        else:
+            error = await missing_runtime_error(exit_code, stderr_str)
+            if error:
+                print(f"[EXECUTE] Runtime not found: {error}", flush=True)
+                stderr_str = runtime.format_message(error, stderr_str)
 
         # Debug logging
         print(f"[EXECUTE] exit_code={proc.returncode}, stdout_len={len(stdout_str)}, stderr_len={len(stderr_str)}", flush=True)
@@ -400,13 +847,21 @@ async def execute_via_nsenter(request: ExecuteRequest) -> ExecuteResponse:
             print(f"[EXECUTE] stderr preview: {stderr_str[:500]!r}", flush=True)
 
         return ExecuteResponse(
-            exit_code=proc.returncode or 0,
+            exit_code=exit_code,
             stdout=stdout_str,
             stderr=stderr_str,
             execution_time_ms=execution_time_ms,
+            interrupted=interrupted,
+            error=error,
+            connections=monitor.count if monitor else None,
+            dns_denied=dns_denied,
+            provenance=await provenance_task,
+            sandbox=sandbox_report,
+            compile_cache=COMPILE_CACHE.finish(cache_run) if cache_run else None,
         )
 
     except Exception as e:
+        provenance_task.cancel()
         print(f"[EXECUTE] EXCEPTION: {type(e).__name__}: {e}", flush=True)
         print(f"[EXECUTE] Traceback: {traceback.format_exc()}", flush=True)
         return ExecuteResponse(
@@ -417,12 +872,18 @@ async def execute_via_nsenter(request: ExecuteRequest) -> ExecuteResponse:
         )
 
 
-async def execute_via_subprocess_direct(request: ExecuteRequest) -> ExecuteResponse:
+async def execute_via_subprocess_direct(request: ExecuteRequest, timer: timing.ExecutionTimer) -> ExecuteResponse:
     """Execute code directly via subprocess (fallback for when nsenter isn't available)."""
     start_time = time.perf_counter()
 
-    # No container env available in fallback mode - use empty dict for defaults
-    cmd, temp_file = get_language_command(LANGUAGE, request.code, request.working_dir, {})
+    try:
+        env_overrides, code = resolve_request_templates(request)
+    except ValueError as e:
+        return ExecuteResponse(exit_code=1, stdout="", stderr=str(e), execution_time_ms=0)
+
+    # No container env available in fallback mode - start from the defaults
+    env = apply_network_isolation_overrides({**DEFAULT_ENV, **env_overrides}, LANGUAGE) if env_overrides else {}
+    cmd, temp_file = get_language_command(LANGUAGE, code, request.working_dir, env)
     if not cmd:
         return ExecuteResponse(
             exit_code=1,
@@ -437,11 +898,29 @@ async def execute_via_subprocess_direct(request: ExecuteRequest) -> ExecuteRespo
             stdout=asyncio.subprocess.PIPE,
             stderr=asyncio.subprocess.PIPE,
             cwd=request.working_dir,
+            start_new_session=True,
+            preexec_fn=priority_preexec(request),
+        )
+    except FileNotFoundError as e:
+        wanted = Path(e.filename or cmd[0]).name
+        error = runtime.runtime_not_found(wanted, runtime.find_candidates(wanted, await list_path_binaries()))
+        return ExecuteResponse(
+            exit_code=runtime.NOT_FOUND_EXIT_CODE,
+            stdout="",
+            stderr=runtime.format_message(error, str(e)),
+            execution_time_ms=int((time.perf_counter() - start_time) * 1000),
+            error=error,
         )
 
+    timer.mark_spawned()
+    try:
+        INTERRUPTS.register(proc.pid)
+        if DNS_RESOLVER:
+            DNS_POLICIES.register(proc.pid, request.dns_allowlist)
+
         try:
-            stdout, stderr = await asyncio.wait_for(
-                proc.communicate(),
+            stdout, stderr, monitor = await asyncio.wait_for(
+                communicate_counting_connections(proc, request, timer),
                 timeout=request.timeout,
             )
         except TimeoutError:
@@ -453,14 +932,34 @@ async def execute_via_subprocess_direct(request: ExecuteRequest) -> ExecuteRespo
                 stderr=f"Execution timed out after {request.timeout} seconds",
                 execution_time_ms=int((time.perf_counter() - start_time) * 1000),
             )
+        finally:
+            interrupted = INTERRUPTS.finish(proc.pid)
+            dns_denied = DNS_POLICIES.finish(proc.pid) if DNS_RESOLVER else None
 
         execution_time_ms = int((time.perf_counter() - start_time) * 1000)
 
+        stderr_str = stderr.decode("utf-8", errors="replace")[:MAX_OUTPUT_SIZE]
+        exit_code = proc.returncode or 0
+        error = None
+        if interrupted:
+            exit_code, stderr_str = interrupt.interrupted_result(proc.returncode, stderr_str, LANGUAGE)
+        elif monitor and monitor.exceeded:
+            error = monitor.error()
+            stderr_str += f"\n{error['code']}: {error['message']}\n"
+        else:
+            error = await missing_runtime_error(exit_code, stderr_str)
+            if error:
+                stderr_str = runtime.format_message(error, stderr_str)
+
         return ExecuteResponse(
-            exit_code=proc.returncode or 0,
+            exit_code=exit_code,
             stdout=stdout.decode("utf-8", errors="replace")[:MAX_OUTPUT_SIZE],
-            stderr=stderr.decode("utf-8", errors="replace")[:MAX_OUTPUT_SIZE],
+            stderr=stderr_str,
             execution_time_ms=execution_time_ms,
+            interrupted=interrupted,
+            error=error,
+            connections=monitor.count if monitor else None,
+            dns_denied=dns_denied,
         )
 
     except Exception as e:
@@ -472,15 +971,548 @@ async def execute_via_subprocess_direct(request: ExecuteRequest) -> ExecuteRespo
         )
 
 
+def build_container_command(args: list[str], working_dir: str, env: dict[str, str] | None = None) -> list[str]:
+    """Wrap a command so it runs in the main container's mount namespace.
+
+    Uses the same environment detection and nsenter invocation as code
+    execution, falling back to a direct command when the main container
+    can't be found. ``env`` is added to the container environment.
+    """
+    main_pid = find_main_container_pid()
+    container_env = get_container_env(main_pid) if main_pid else {}
+    container_env = apply_scratch_dir(container_env)
+    env = {**(container_env if container_env else DEFAULT_ENV), **(env or {})}
+    env = apply_network_isolation_overrides(env, LANGUAGE)
+    cmd = ["/usr/bin/env", "-i"] + [f"{k}={v}" for k, v in env.items()] + args
+    if main_pid:
+        cmd = ["nsenter", "-t", str(main_pid), "-m", f"--wdns={working_dir}", "--"] + cmd
+    return cmd
+
+
+async def run_in_main_container(
+    args: list[str], working_dir: str, timeout: int, env: dict[str, str] | None = None
+) -> tuple[int, str, str]:
+    """Run an arbitrary command in the main container and wait for it.
+
+    Returns:
+        Tuple of (exit_code, stdout, stderr). Exit code 124 means the command
+        timed out, 127 means the binary could not be started.
+    """
+    cmd = build_container_command(args, working_dir, env)
+
+    try:
+        proc = await asyncio.create_subprocess_exec(
+            *cmd,
+            stdout=asyncio.subprocess.PIPE,
+            stderr=asyncio.subprocess.PIPE,
+            cwd=working_dir,
+        )
+    except FileNotFoundError as e:
+        return 127, "", f"Failed to start command: {e}"
+
+    try:
+        stdout, stderr = await asyncio.wait_for(proc.communicate(), timeout=timeout)
+    except TimeoutError:
+        proc.kill()
+        await proc.wait()
+        return 124, "", f"Command timed out after {timeout} seconds"
+
+    return (
+        proc.returncode or 0,
+        stdout.decode("utf-8", errors="replace")[:MAX_OUTPUT_SIZE],
+        stderr.decode("utf-8", errors="replace")[:MAX_OUTPUT_SIZE],
+    )
+
+
+async def probe_binaries(names: list[str]) -> set[str]:
+    """Return the subset of binaries present on the main container's PATH."""
+    if not names:
+        return set()
+    script = 'for b in "$@"; do command -v "$b" >/dev/null 2>&1 && echo "$b"; done'
+    exit_code, stdout, _ = await run_in_main_container(["sh", "-c", script, "sh", *names], WORKING_DIR, timeout=10)
+    if exit_code not in (0, 1):
+        return set()
+    return {line.strip() for line in stdout.splitlines() if line.strip()}
+
+
+async def list_path_binaries() -> list[str]:
+    """Names of the executables on the main container's PATH."""
+    script = 'IFS=:; for d in $PATH; do [ -d "$d" ] && ls -1 "$d"; done 2>/dev/null'
+    _, stdout, _ = await run_in_main_container(["sh", "-c", script], WORKING_DIR, timeout=10)
+    return sorted({line.strip() for line in stdout.splitlines() if line.strip()})
+
+
+async def missing_runtime_error(exit_code: int, stderr: str) -> dict | None:
+    """RUNTIME_NOT_FOUND error when the execution failed because its interpreter is missing."""
+    wanted = runtime.find_missing_binary(exit_code, stderr, LANGUAGE)
+    if not wanted:
+        return None
+    return runtime.runtime_not_found(wanted, runtime.find_candidates(wanted, await list_path_binaries()))
+
+
+async def run_hook(phase: str, request: ExecuteRequest, exit_code: int | None = None) -> dict | None:
+    """Run the request's pre- or post-exec hook; returns a HOOK_FAILED error if it failed."""
+    script = getattr(request.hooks, phase)
+    timeout = request.hooks.timeout
+    code, _, stderr = await run_in_main_container(
+        ["sh", "-c", script], request.working_dir, timeout, env=hooks.hook_env(phase, exit_code)
+    )
+    if code == 0:
+        return None
+    error = hooks.hook_failed(phase, code, stderr, timeout)
+    print(f"[EXECUTE] {phase}-exec hook failed: exit_code={code}", flush=True)
+    return error
+
+
+async def run_timed_probe(name: str, args: list[str]) -> None:
+    """Time a command in the main container, recording a failure if it doesn't exit 0."""
+    start = time.perf_counter()
+    exit_code, _, stderr = await run_in_main_container(args, WORKING_DIR, timeout=degradation.PROBE_TIMEOUT)
+    if exit_code == 0:
+        HEALTH.record(name, (time.perf_counter() - start) * 1000)
+    else:
+        HEALTH.record_failure(name, f"{' '.join(args)} exited {exit_code}: {stderr.strip()[:200]}")
+
+
+async def probe_health() -> None:
+    """Time process spawn, interpreter startup and disk I/O once."""
+    await run_timed_probe("spawn", degradation.SPAWN_PROBE)
+    if "interpreter" in HEALTH.checks:
+        await run_timed_probe("interpreter", degradation.STARTUP_PROBES[LANGUAGE])
+    try:
+        HEALTH.record("disk", await asyncio.to_thread(degradation.disk_probe, WORKING_DIR))
+    except OSError as e:
+        HEALTH.record_failure("disk", str(e))
+    HEALTH.last_probe = time.time()
+
+
+async def check_rootfs(wait_seconds: int = 30) -> None:
+    """Check which directories both containers can write to once the main container is up, logging what degrades."""
+    global ROOTFS_REPORT
+    main_pid = None
+    for _ in range(wait_seconds):
+        main_pid = find_main_container_pid()
+        if main_pid:
+            break
+        await asyncio.sleep(1)
+    if not main_pid:
+        print("[ROOTFS] Main container not found; skipped the writability check", flush=True)
+        return
+    try:
+        ROOTFS_REPORT = await rootfs.check(
+            run=lambda args, timeout: run_in_main_container(args, WORKING_DIR, timeout),
+            directories={"working_dir": WORKING_DIR, "scratch_dir": SCRATCH_DIR},
+            language=LANGUAGE,
+            main_root=f"/proc/{main_pid}/root",
+        )
+        print(f"[ROOTFS] {rootfs.summary(ROOTFS_REPORT)}", flush=True)
+    except Exception as e:
+        print(f"[ROOTFS] Check failed: {type(e).__name__}: {e}", flush=True)
+
+
+async def probe_health_loop() -> None:
+    """Probe every HEALTH_PROBE_INTERVAL seconds, logging when the pod becomes degraded or recovers."""
+    degraded: list[str] = []
+    while True:
+        try:
+            await probe_health()
+        except Exception as e:
+            print(f"[HEALTH] Probe failed: {type(e).__name__}: {e}", flush=True)
+        if HEALTH.degraded != degraded:
+            degraded = HEALTH.degraded
+            if degraded:
+                print(f"[HEALTH] Degraded: {', '.join(degraded)} ({HEALTH.report()['checks']})", flush=True)
+            else:
+                print("[HEALTH] Recovered", flush=True)
+        await asyncio.sleep(HEALTH_PROBE_INTERVAL)
+
+
 @app.post("/execute", response_model=ExecuteResponse)
 async def execute_code(request: ExecuteRequest) -> ExecuteResponse:
-    """Execute code and return results via nsenter."""
-    return await execute_via_nsenter(request)
+    """Execute code and return results via nsenter, recording a summary for GET /debug/bundle."""
+    started_at = time.time()
+    timer = timing.ExecutionTimer()
+    if FAULTS and FAULTS.roll("spawn_failure"):
+        error = faults.spawn_failure_error()
+        response = ExecuteResponse(
+            exit_code=1,
+            stdout="",
+            stderr=f"{error['code']}: {error['message']}\n",
+            execution_time_ms=0,
+            error=error,
+        )
+    elif request.workspace:
+        response = await execute_in_workspace(request, timer)
+    else:
+        response = await execute_between_hooks(request, timer)
+    response.timings = timer.timings()
+    HISTORY.record(started_at, response, code_bytes=len(request.code.encode()))
+    return response
+
+
+async def execute_in_workspace(request: ExecuteRequest, timer: timing.ExecutionTimer) -> ExecuteResponse:
+    """Execute in a named workspace: in a scratch copy if it's read-only, reporting it if left over quota."""
+    try:
+        workspace = WORKSPACES.get(request.workspace)
+    except workspaces.WorkspaceError as e:
+        error = {"code": e.code, "message": str(e)}
+        return ExecuteResponse(
+            exit_code=1,
+            stdout="",
+            stderr=f"{error['code']}: {error['message']}\n",
+            execution_time_ms=0,
+            error=error,
+        )
+
+    read_only = workspace.policy.read_only
+    WORKSPACES.touch(workspace.id)
+    if read_only:
+        run_dir = await asyncio.to_thread(WORKSPACES.scratch_copy, workspace.id)
+    else:
+        run_dir = WORKSPACES.path(workspace.id)
+    try:
+        response = await execute_between_hooks(request.model_copy(update={"working_dir": str(run_dir)}), timer)
+    finally:
+        if read_only:
+            await asyncio.to_thread(shutil.rmtree, run_dir, True)
+    WORKSPACES.touch(workspace.id)
+    if not read_only and not response.error:
+        response.error = await asyncio.to_thread(WORKSPACES.quota_error, workspace.id)
+    return response
+
+
+async def workspace_retention_loop() -> None:
+    """Every WORKSPACE_SWEEP_INTERVAL seconds, delete workspaces idle past their retention."""
+    while True:
+        await asyncio.sleep(WORKSPACE_SWEEP_INTERVAL)
+        try:
+            deleted = await asyncio.to_thread(WORKSPACES.sweep)
+            if deleted:
+                print(f"[WORKSPACE] Deleted expired workspaces: {', '.join(deleted)}", flush=True)
+        except Exception as e:
+            print(f"[WORKSPACE] Sweep failed: {type(e).__name__}: {e}", flush=True)
+
+
+async def compile_cache_sweep_loop() -> None:
+    """Every COMPILE_CACHE_SWEEP_INTERVAL seconds, evict the compiler cache's least recently used files over quota."""
+    while True:
+        await asyncio.sleep(COMPILE_CACHE_SWEEP_INTERVAL)
+        try:
+            evicted = await asyncio.to_thread(COMPILE_CACHE.sweep)
+            if evicted:
+                print(f"[CACHE] Evicted {evicted} files, {COMPILE_CACHE.used_bytes} bytes in use", flush=True)
+        except Exception as e:
+            print(f"[CACHE] Sweep failed: {type(e).__name__}: {e}", flush=True)
+
+
+async def spawn_language_server(command: list[str], root: Path):
+    """Start a language server in the main container, speaking LSP over stdin and stdout."""
+    return await asyncio.create_subprocess_exec(
+        *build_container_command(command, str(root)),
+        stdin=asyncio.subprocess.PIPE,
+        stdout=asyncio.subprocess.PIPE,
+        stderr=asyncio.subprocess.DEVNULL,
+        cwd=str(root),
+    )
+
+
+async def lsp_idle_loop() -> None:
+    """Shut down language servers unused for LSP_IDLE_TIMEOUT seconds."""
+    while True:
+        await asyncio.sleep(min(LSP_IDLE_TIMEOUT, 60))
+        try:
+            for server in await LSP.stop_idle(LSP_IDLE_TIMEOUT):
+                print(f"[LSP] Stopped idle {server.name} server for {server.root}", flush=True)
+        except Exception as e:
+            print(f"[LSP] Idle shutdown failed: {type(e).__name__}: {e}", flush=True)
+
+
+async def execute_between_hooks(request: ExecuteRequest, timer: timing.ExecutionTimer) -> ExecuteResponse:
+    """Execute code via nsenter, between the request's hooks."""
+    start_time = time.perf_counter()
+    if request.dns_allowlist is not None and not DNS_RESOLVER:
+        error = {"code": dns.ERROR_CODE, "message": "This pod doesn't enforce DNS policies"}
+        return ExecuteResponse(
+            exit_code=1,
+            stdout="",
+            stderr=f"{error['code']}: {error['message']}\n",
+            execution_time_ms=0,
+            error=error,
+        )
+    if request.priority and request.priority.cpu_affinity:
+        error = priority.affinity_error(request.priority.cpu_affinity, os.sched_getaffinity(0))
+        if error:
+            return ExecuteResponse(
+                exit_code=1,
+                stdout="",
+                stderr=f"{error['code']}: {error['message']}\n",
+                execution_time_ms=0,
+                error=error,
+            )
+    if request.hooks and request.hooks.pre:
+        error = await run_hook("pre", request)
+        if error:
+            # The code never ran
+            return ExecuteResponse(
+                exit_code=1,
+                stdout="",
+                stderr=hooks.format_message(error),
+                execution_time_ms=int((time.perf_counter() - start_time) * 1000),
+                error=error,
+            )
+
+    response = await execute_via_nsenter(request, timer)
+
+    if request.hooks and request.hooks.post:
+        error = await run_hook("post", request, exit_code=response.exit_code)
+        # The code's own failure, if any, is the more useful error to report
+        if error and not response.error:
+            response.error = error
+    return response
+
+
+@app.post("/interrupt")
+async def interrupt_execution():
+    """Send SIGINT to the running execution (Ctrl-C), leaving the pod and workspace intact.
+
+    The interrupted /execute call returns normally with interrupted=true.
+    """
+    count = INTERRUPTS.interrupt_all()
+    print(f"[INTERRUPT] signalled={count}", flush=True)
+    return {"interrupted": count}
+
+
+@app.post("/kill")
+async def kill_executions():
+    """Send SIGKILL to every running execution; the pod and workspace are left as they are.
+
+    Used when a session is quarantined: the code gets no chance to clean up.
+    """
+    count = INTERRUPTS.kill_all()
+    print(f"[KILL] signalled={count}", flush=True)
+    return {"killed": count}
+
+
+@app.post("/render", response_model=RenderResponse)
+async def render_document(request: RenderRequest) -> RenderResponse:
+    """Render LaTeX to PDF or Markdown to HTML/PDF using toolchains in the main container.
+
+    The document is built in a scratch directory inside the working directory
+    so intermediate files (.aux, .log) never show up as generated artifacts;
+    only the final document is moved into the working directory root, under
+    a name no file there has yet (returned as ``output.path``).
+    """
+    if (request.source is None) == (request.path is None):
+        raise HTTPException(status_code=400, detail="Provide exactly one of 'source' or 'path'")
+
+    if (request.format, request.output) not in render.TOOLCHAINS:
+        raise HTTPException(
+            status_code=400,
+            detail=f"Unsupported conversion: {request.format} -> {request.output}",
+        )
+
+    available = await probe_binaries(render.required_binaries(request.format, request.output))
+    toolchain = render.select_toolchain(request.format, request.output, available)
+    if not toolchain:
+        tried = [t.name for t in render.TOOLCHAINS[(request.format, request.output)]]
+        raise HTTPException(
+            status_code=501,
+            detail=f"No toolchain for {request.format} -> {request.output} in this image (tried: {', '.join(tried)})",
+        )
+
+    start_time = time.perf_counter()
+    working_path = Path(WORKING_DIR).resolve()
+    build_dir = working_path / f".render-{uuid.uuid4().hex[:8]}"
+    build_dir.mkdir()
+
+    try:
+        if request.path is not None:
+            source_path = validate_path_within_working_dir(request.path)
+            if not source_path.is_file():
+                raise HTTPException(status_code=404, detail="Source file not found")
+            # The toolchain runs in the source's directory and may write there
+            check_path_writable(source_path, None)
+            # Run next to the source so relative includes (images, .bib) resolve
+            run_dir = source_path.parent
+        else:
+            source_path = build_dir / f"document{render.SOURCE_EXTENSIONS[request.format]}"
+            source_path.write_text(request.source)
+            run_dir = working_path
+
+        built_path = build_dir / render.built_artifact_name(toolchain, source_path.name, request.output)
+        cmd = toolchain.command(source=str(source_path), output=str(built_path))
+        print(f"[RENDER] toolchain={toolchain.name}, cmd={cmd}", flush=True)
+
+        exit_code, stdout, stderr = await run_in_main_container(cmd, str(run_dir), request.timeout)
+        log = (stdout + stderr)[-MAX_OUTPUT_SIZE:]
+
+        output = None
+        if exit_code == 0 and built_path.is_file():
+            name = render.unused_filename(
+                render.output_filename(request.path, request.output, request.filename),
+                lambda candidate: (working_path / candidate).exists(),
+            )
+            dest_path = working_path / name
+            shutil.move(str(built_path), dest_path)
+            output = FileInfo(
+                name=name,
+                path=name,
+                size=dest_path.stat().st_size,
+                mime_type="application/pdf" if request.output == "pdf" else "text/html",
+            )
+        elif exit_code == 0:
+            exit_code = 1
+            log += f"\n{toolchain.name} exited successfully but produced no output"
+
+        return RenderResponse(
+            exit_code=exit_code,
+            toolchain=toolchain.name,
+            output=output,
+            log=log,
+            execution_time_ms=int((time.perf_counter() - start_time) * 1000),
+        )
+    finally:
+        shutil.rmtree(build_dir, ignore_errors=True)
+
+
+async def stream_media_events(request: MediaRequest, cmd: list[str], output_path: Path) -> AsyncIterator[str]:
+    """Run ffmpeg and yield NDJSON progress events followed by a result event.
+
+    The output size limit is enforced twice: ffmpeg's own -fs stops writing at
+    the limit, and the process is killed if reported progress crosses it. An
+    output that hits the limit is truncated, so it is deleted rather than
+    returned as an artifact.
+    """
+    start_time = time.perf_counter()
+    parser = media.ProgressParser()
+    stderr_tail: deque[str] = deque(maxlen=50)
+    status = "completed"
+
+    try:
+        proc = await asyncio.create_subprocess_exec(
+            *build_container_command(cmd, WORKING_DIR),
+            stdout=asyncio.subprocess.PIPE,
+            stderr=asyncio.subprocess.PIPE,
+            cwd=WORKING_DIR,
+        )
+    except FileNotFoundError as e:
+        yield json.dumps({
+            "type": "result",
+            "exit_code": 127,
+            "status": "failed",
+            "output": None,
+            "stderr": f"Failed to start ffmpeg: {e}",
+            "execution_time_ms": int((time.perf_counter() - start_time) * 1000),
+        }) + "\n"
+        return
+
+    async def read_stderr() -> None:
+        # The input duration is only reported on stderr; it turns progress into a percentage
+        async for raw in proc.stderr:
+            line = raw.decode("utf-8", errors="replace")
+            if parser.duration_seconds is None:
+                parser.duration_seconds = media.parse_duration(line)
+            stderr_tail.append(line)
+
+    stderr_task = asyncio.create_task(read_stderr())
+    deadline = time.monotonic() + request.timeout
+
+    try:
+        yield json.dumps({"type": "start", "command": cmd}) + "\n"
+        while True:
+            remaining = deadline - time.monotonic()
+            if remaining <= 0:
+                raise TimeoutError
+            raw = await asyncio.wait_for(proc.stdout.readline(), timeout=remaining)
+            if not raw:
+                break
+            event = parser.feed(raw.decode("utf-8", errors="replace"))
+            if event is None:
+                continue
+            yield json.dumps(event) + "\n"
+            if event["total_size"] is not None and event["total_size"] >= request.max_output_size:
+                status = "output_limit_exceeded"
+                proc.kill()
+                break
+        await asyncio.wait_for(proc.wait(), timeout=max(deadline - time.monotonic(), 1))
+    except TimeoutError:
+        status = "timeout"
+        proc.kill()
+    finally:
+        # Also reached when the client disconnects mid-stream
+        if proc.returncode is None:
+            proc.kill()
+        await proc.wait()
+        await stderr_task
+
+    exit_code = proc.returncode or 0
+    output = None
+    if output_path.is_file() and output_path.stat().st_size >= request.max_output_size:
+        status = "output_limit_exceeded"
+    if status == "timeout":
+        exit_code = 124
+        stderr_tail.append(f"ffmpeg timed out after {request.timeout} seconds\n")
+    elif status == "output_limit_exceeded":
+        exit_code = exit_code or 1
+        output_path.unlink(missing_ok=True)
+        stderr_tail.append(f"Output exceeded the size limit of {request.max_output_size} bytes\n")
+    elif exit_code != 0:
+        status = "failed"
+    elif output_path.is_file():
+        name = str(output_path.relative_to(Path(WORKING_DIR).resolve()))
+        output = FileInfo(name=output_path.name, path=name, size=output_path.stat().st_size)
+
+    print(f"[MEDIA] status={status}, exit_code={exit_code}", flush=True)
+    yield json.dumps({
+        "type": "result",
+        "exit_code": exit_code,
+        "status": status,
+        "output": output.model_dump() if output else None,
+        "stderr": "".join(stderr_tail)[-MAX_OUTPUT_SIZE:],
+        "execution_time_ms": int((time.perf_counter() - start_time) * 1000),
+    }) + "\n"
+
+
+@app.post("/media")
+async def process_media(request: MediaRequest) -> StreamingResponse:
+    """Transcode or process a media file with ffmpeg in the main container.
+
+    Streams NDJSON: a start event, progress events as ffmpeg reports them,
+    and a final result event with the exit code and output file. An output
+    path that already exists is refused with 409 rather than overwritten.
+    """
+    input_path = validate_path_within_working_dir(request.input)
+    if not input_path.is_file():
+        raise HTTPException(status_code=404, detail="Input file not found")
+
+    output_path = validate_path_within_working_dir(request.output)
+    if output_path == input_path or output_path.is_dir():
+        raise HTTPException(status_code=400, detail="Output must be a new file path")
+    if output_path.exists():
+        # ffmpeg would overwrite it; the caller picks another name or deletes it first
+        raise HTTPException(status_code=409, detail=f"{request.output} already exists")
+    check_path_writable(output_path, None)
+
+    try:
+        cmd = media.build_ffmpeg_command(str(input_path), str(output_path), request.args, request.max_output_size)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e))
+
+    if "ffmpeg" not in await probe_binaries(["ffmpeg"]):
+        raise HTTPException(status_code=501, detail="ffmpeg is not installed in this image")
+
+    output_path.parent.mkdir(parents=True, exist_ok=True)
+    return StreamingResponse(
+        stream_media_events(request, cmd, output_path),
+        media_type="application/x-ndjson",
+    )
 
 
 @app.post("/files")
-async def upload_files(files: list[UploadFile] = File(...)):
-    """Upload files to the working directory."""
+async def upload_files(files: list[UploadFile] = File(...), workspace: str | None = None):
+    """Upload files to the working directory, or to a named workspace."""
+    base = workspace_dir(workspace)
+    check_workspace_writable(workspace)
     uploaded = []
 
     for file in files:
@@ -488,15 +1520,20 @@ async def upload_files(files: list[UploadFile] = File(...)):
             continue
 
         # Sanitize filename
+        if STRICT_MODE:
+            try:
+                strict.normalize_path(file.filename)
+            except strict.PathRejected as e:
+                raise HTTPException(status_code=400, detail=f"{file.filename!r}: {e}")
         safe_name = Path(file.filename).name
         if not safe_name or safe_name.startswith("."):
             continue
 
-        dest_path = Path(WORKING_DIR) / safe_name
+        dest_path = base / safe_name
+        content = await file.read()
+        check_path_writable(dest_path, workspace, len(content))
 
-        with open(dest_path, "wb") as f:
-            content = await file.read()
-            f.write(content)
+        STORAGE.write_file(dest_path, content)
 
         uploaded.append(FileInfo(
             name=safe_name,
@@ -508,9 +1545,9 @@ async def upload_files(files: list[UploadFile] = File(...)):
 
 
 @app.get("/files")
-async def list_files():
-    """List files in the working directory root."""
-    working_path = Path(WORKING_DIR)
+async def list_files(workspace: str | None = None):
+    """List files in the working directory root, or a named workspace's."""
+    working_path = workspace_dir(workspace)
     if not working_path.is_dir():
         raise HTTPException(status_code=404, detail="Working directory not found")
 
@@ -524,11 +1561,84 @@ async def list_files():
     return {"files": [f.model_dump() for f in files]}
 
 
+@app.post("/files/patch")
+async def patch_files(request: FilePatchRequest, workspace: str | None = None):
+    """Apply a unified diff: every hunk applies and the files are written, or nothing changes (409 with rejects)."""
+    base = workspace_dir(workspace)
+    check_workspace_writable(workspace)
+    try:
+        contents, files, rejects = await asyncio.to_thread(diffpatch.plan, base, request.diff, request.strip)
+    except diffpatch.PatchError as e:
+        raise HTTPException(status_code=400, detail=str(e))
+    if rejects:
+        raise HTTPException(status_code=409, detail={
+            "message": f"{len(rejects)} hunk(s) failed to apply; no files were changed",
+            "rejects": rejects,
+        })
+    if request.dry_run:
+        return {"applied": False, "files": files}
+
+    for path in contents:
+        check_path_writable(path, workspace)
+    growth = sum(len(data or b"") - (path.stat().st_size if path.is_file() else 0) for path, data in contents.items())
+    check_workspace_writable(workspace, max(0, growth))
+    for path, data in contents.items():
+        if data is None:
+            path.unlink(missing_ok=True)
+        else:
+            STORAGE.write_file(path, data)
+    return {"applied": True, "files": files}
+
+
+@app.get("/files/search")
+async def search_files(
+    q: str,
+    glob: str = "**/*",
+    regex: bool = True,
+    ignore_case: bool = False,
+    context: int = 2,
+    max_matches: int = search.MAX_MATCHES,
+    workspace: str | None = None,
+):
+    """Lines matching ``q`` in the working directory's (or a workspace's) files, with context."""
+    base = workspace_dir(workspace)
+    try:
+        return await search.run(
+            base.resolve(),
+            q,
+            glob=glob,
+            regex=regex,
+            ignore_case=ignore_case,
+            context=context,
+            max_matches=max_matches,
+        )
+    except search.SearchError as e:
+        raise HTTPException(status_code=400, detail=str(e))
+
+
+@app.get("/files/{path:path}/symbols")
+async def file_symbols(path: str, workspace: str | None = None):
+    """Functions, classes and other definitions in a source file, with their line ranges."""
+    base = workspace_dir(workspace)
+    file_path = validate_path_within_working_dir(path, base)
+    if file_path.is_dir() and (file_path / "symbols").exists():
+        # A file named "symbols" in a directory, not an outline of the directory
+        return await download_file(f"{path}/symbols", workspace)
+    if not file_path.is_file():
+        raise HTTPException(status_code=404, detail="File not found")
+    try:
+        language, found, parser = await asyncio.to_thread(symbols.outline, path, file_path.read_bytes())
+    except symbols.SymbolsError as e:
+        raise HTTPException(status_code=e.status, detail=str(e))
+    return {"path": path, "language": language, "parser": parser, "symbols": [s.to_dict() for s in found]}
+
+
 @app.get("/files/{path:path}")
-async def download_file(path: str):
-    """Download a file from the working directory."""
-    file_path = validate_path_within_working_dir(path)
-    working_path = Path(WORKING_DIR).resolve()
+async def download_file(path: str, workspace: str | None = None):
+    """Download a file from the working directory, or a named workspace."""
+    base = workspace_dir(workspace)
+    file_path = validate_path_within_working_dir(path, base)
+    working_path = base.resolve()
 
     if not file_path.exists():
         raise HTTPException(status_code=404, detail="File not found")
@@ -547,15 +1657,47 @@ async def download_file(path: str):
     return FileResponse(file_path)
 
 
+@app.patch("/files/{path:path}")
+async def patch_file(
+    request: Request,
+    path: str,
+    offset: int | None = None,
+    expected_size: int | None = None,
+    workspace: str | None = None,
+):
+    """Append the request body to a file, or write it at ``offset``, creating the file if needed.
+
+    With ``expected_size`` the write only happens if the file is that size,
+    so a retried append can't be applied twice.
+    """
+    base = workspace_dir(workspace)
+    check_workspace_writable(workspace)
+    file_path = validate_path_within_working_dir(path, base)
+    if any(part.startswith(".") for part in file_path.relative_to(base.resolve()).parts):
+        raise HTTPException(status_code=403, detail="Access denied")
+
+    data = await request.body()
+    try:
+        check_path_writable(file_path, workspace, filewrite.growth(file_path, len(data), offset))
+        size = STORAGE.write_at(file_path, data, offset=offset, expected_size=expected_size)
+    except filewrite.FileWriteError as e:
+        raise HTTPException(status_code=e.status, detail=str(e))
+    return {"path": path, "size": size, "written": len(data)}
+
+
 @app.delete("/files/{path:path}")
-async def delete_file(path: str):
-    """Delete a file from the working directory."""
-    file_path = validate_path_within_working_dir(path)
-    working_path = Path(WORKING_DIR).resolve()
+async def delete_file(path: str, workspace: str | None = None):
+    """Delete a file from the working directory, or a named workspace."""
+    base = workspace_dir(workspace)
+    check_workspace_writable(workspace)
+    file_path = validate_path_within_working_dir(path, base)
+    working_path = base.resolve()
 
     # Prevent deletion of the working directory itself
     if file_path == working_path:
         raise HTTPException(status_code=403, detail="Cannot delete working directory")
+    # Workspaces and their policies are deleted with DELETE /workspaces/{id}
+    check_path_writable(file_path, workspace)
 
     if not file_path.exists():
         raise HTTPException(status_code=404, detail="File not found")
@@ -568,6 +1710,165 @@ async def delete_file(path: str):
     return {"deleted": path}
 
 
+@app.get("/workspaces")
+async def list_workspaces():
+    """Named workspaces and their policies."""
+    return {"workspaces": [workspace.to_dict() for workspace in WORKSPACES.all()]}
+
+
+@app.put("/workspaces/{workspace_id}")
+async def put_workspace(workspace_id: str, request: WorkspaceRequest, response: Response):
+    """Create a workspace at /mnt/data/<workspace_id>, or replace an existing one's policy."""
+    policy = workspaces.WorkspacePolicy(**request.model_dump())
+    try:
+        workspace, created = WORKSPACES.put(workspace_id, policy)
+    except workspaces.WorkspaceError as e:
+        raise HTTPException(status_code=e.status, detail=str(e))
+    response.status_code = 201 if created else 200
+    return workspace.to_dict()
+
+
+@app.get("/workspaces/{workspace_id}")
+async def get_workspace(workspace_id: str):
+    """A workspace's policy and current usage."""
+    try:
+        workspace = WORKSPACES.get(workspace_id)
+    except workspaces.WorkspaceError as e:
+        raise HTTPException(status_code=e.status, detail=str(e))
+    usage = await asyncio.to_thread(WORKSPACES.usage, workspace_id)
+    return {**workspace.to_dict(), "usage_bytes": usage}
+
+
+@app.delete("/workspaces/{workspace_id}")
+async def delete_workspace(workspace_id: str):
+    """Delete a workspace and its files."""
+    try:
+        await asyncio.to_thread(WORKSPACES.delete, workspace_id)
+    except workspaces.WorkspaceError as e:
+        raise HTTPException(status_code=e.status, detail=str(e))
+    return {"deleted": workspace_id}
+
+
+@app.get("/sync/manifest")
+async def sync_manifest(checksums: bool = False):
+    """Size and mtime (and sha256 with checksums=true) of every file, to find what changed."""
+    working_path = Path(WORKING_DIR)
+    if not working_path.is_dir():
+        raise HTTPException(status_code=404, detail="Working directory not found")
+    return {"files": await asyncio.to_thread(sync.manifest, working_path, checksums)}
+
+
+def _read_sync_file(path: str) -> tuple[Path, bytes | None]:
+    file_path = validate_path_within_working_dir(path)
+    if file_path.is_dir():
+        raise HTTPException(status_code=400, detail="Path is a directory")
+    return file_path, file_path.read_bytes() if file_path.is_file() else None
+
+
+@app.get("/sync/signature/{path:path}")
+async def sync_signature(path: str, block_size: int = sync.DEFAULT_BLOCK_SIZE):
+    """Block signature of a file, for a client that wants to send a delta against it."""
+    if not sync.MIN_BLOCK_SIZE <= block_size <= sync.MAX_BLOCK_SIZE:
+        raise HTTPException(status_code=400, detail="block_size out of range")
+    _, data = _read_sync_file(path)
+    if data is None:
+        raise HTTPException(status_code=404, detail="File not found")
+    return {
+        "path": path,
+        "size": len(data),
+        "sha256": sync.file_sha256(data),
+        "block_size": block_size,
+        "blocks": await asyncio.to_thread(sync.signature, data, block_size),
+    }
+
+
+@app.post("/sync/delta/{path:path}")
+async def sync_delta(path: str, request: SyncSignature):
+    """Delta from the client's copy (described by its signature) to this file."""
+    _, data = _read_sync_file(path)
+    if data is None:
+        raise HTTPException(status_code=404, detail="File not found")
+    blocks = [block.model_dump() for block in request.blocks]
+    ops = await asyncio.to_thread(sync.compute_delta, data, blocks, request.block_size)
+    return {
+        "path": path,
+        "size": len(data),
+        "sha256": sync.file_sha256(data),
+        "block_size": request.block_size,
+        "ops": ops,
+    }
+
+
+@app.post("/sync/patch/{path:path}")
+async def sync_patch(path: str, request: SyncDelta):
+    """Apply a delta to a file (a missing file counts as empty), replacing it atomically where the storage can."""
+    file_path, data = _read_sync_file(path)
+    check_path_writable(file_path, None)
+    try:
+        updated = sync.apply_delta(data or b"", request.ops, request.block_size, MAX_SYNC_FILE_SIZE)
+    except sync.DeltaTooLarge as e:
+        raise HTTPException(status_code=413, detail=str(e))
+    except sync.DeltaError as e:
+        raise HTTPException(status_code=409, detail=str(e))
+    digest = sync.file_sha256(updated)
+    if request.sha256 and request.sha256 != digest:
+        raise HTTPException(status_code=409, detail="Checksum mismatch after applying delta")
+
+    STORAGE.write_file(file_path, updated)
+    return {"path": path, "size": len(updated), "sha256": digest}
+
+
+async def lsp_target(request: LspRequest) -> tuple[lsp.LanguageServer, Path, str]:
+    """The language server for the request's file (started if needed), the file, and its languageId."""
+    base = workspace_dir(request.workspace)
+    file_path = validate_path_within_working_dir(request.path, base)
+    try:
+        name, language_id = lsp.server_for(request.path)
+        server = await LSP.get(name, base)
+    except lsp.LspError as e:
+        raise HTTPException(status_code=e.status, detail=str(e))
+    return server, file_path, language_id
+
+
+@app.get("/lsp")
+async def lsp_status():
+    """Running language servers."""
+    return {"servers": LSP.status()}
+
+
+@app.post("/lsp/diagnostics")
+async def lsp_diagnostics(request: LspRequest):
+    """Errors and warnings the language server reports for a file."""
+    server, file_path, language_id = await lsp_target(request)
+    try:
+        diagnostics = await server.diagnostics(file_path, language_id)
+    except lsp.LspError as e:
+        raise HTTPException(status_code=e.status, detail=str(e))
+    return {"path": request.path, "server": server.name, "diagnostics": diagnostics}
+
+
+@app.post("/lsp/hover")
+async def lsp_hover(request: LspRequest):
+    """Type and documentation of the symbol at a position."""
+    server, file_path, language_id = await lsp_target(request)
+    try:
+        hover = await server.hover(file_path, language_id, request.line, request.column)
+    except lsp.LspError as e:
+        raise HTTPException(status_code=e.status, detail=str(e))
+    return {"path": request.path, "server": server.name, "hover": hover}
+
+
+@app.post("/lsp/definition")
+async def lsp_definition(request: LspRequest):
+    """Where the symbol at a position is defined."""
+    server, file_path, language_id = await lsp_target(request)
+    try:
+        locations = await server.definition(file_path, language_id, request.line, request.column)
+    except lsp.LspError as e:
+        raise HTTPException(status_code=e.status, detail=str(e))
+    return {"path": request.path, "server": server.name, "locations": locations}
+
+
 @app.get("/health", response_model=HealthResponse)
 async def health_check():
     """Health check endpoint."""
@@ -576,12 +1877,65 @@ async def health_check():
         language=LANGUAGE,
         working_dir=WORKING_DIR,
         timestamp=datetime.utcnow().isoformat(),
+        policy=POLICY.describe() if POLICY else None,
+        storage=STORAGE.describe(),
+        compile_cache=COMPILE_CACHE.describe() if COMPILE_CACHE else None,
+        strict=ANOMALIES.report() if STRICT_MODE else None,
+    )
+
+
+@app.get("/debug/bundle")
+async def debug_bundle():
+    """Support bundle (tar.gz) of logs, recent executions, redacted config, platform info and resource usage."""
+    main_pid = find_main_container_pid()
+    config = {
+        "version": VERSION,
+        "language": LANGUAGE,
+        "working_dir": WORKING_DIR,
+        "scratch_dir": SCRATCH_DIR,
+        "max_execution_time": MAX_EXECUTION_TIME,
+        "max_output_size": MAX_OUTPUT_SIZE,
+        "max_media_output_size": MAX_MEDIA_OUTPUT_SIZE,
+        "max_sync_file_size": MAX_SYNC_FILE_SIZE,
+        "main_process_name": MAIN_PROCESS_NAME,
+        "network_isolated": NETWORK_ISOLATED,
+        "strict_mode": ANOMALIES.report() if STRICT_MODE else None,
+        "storage": STORAGE.describe(),
+        "compile_cache": COMPILE_CACHE.describe() if COMPILE_CACHE else None,
+        "dns_upstream": DNS_UPSTREAM or None,
+        "dns_allowlist": DNS_ALLOWLIST,
+        "dns_resolver_listening": DNS_RESOLVER is not None,
+        "fault_injection": FAULTS.report() if FAULTS else None,
+        "policy": POLICY.describe() if POLICY else None,
+        "sidecar_env": debug.redact_env(dict(os.environ)),
+        "main_container_env": debug.redact_env(get_container_env(main_pid)) if main_pid else None,
+    }
+    bundle = debug.build_bundle(
+        {
+            "logs.txt": LOGS.text(),
+            "history.json": list(HISTORY.entries),
+            "config.json": config,
+            "platform.json": debug.platform_info(version=VERSION, language=LANGUAGE, main_pid=main_pid),
+            "resources.json": debug.resource_stats(WORKING_DIR),
+            "health.json": HEALTH.report(),
+            "rootfs.json": ROOTFS_REPORT,
+        }
+    )
+    return Response(
+        content=bundle,
+        media_type="application/gzip",
+        headers={"Content-Disposition": 'attachment; filename="support-bundle.tar.gz"'},
     )
 
 
 @app.get("/ready")
 async def readiness_check():
-    """Readiness check for Kubernetes."""
+    """Readiness check for Kubernetes and the API's pool.
+
+    Also 503 (detail.status "degraded", with the probe diagnostics) once
+    spawn, interpreter startup or disk latency has degraded past its
+    baseline, so the pod is drained before users notice.
+    """
     # Check if working directory is accessible
     if not os.path.isdir(WORKING_DIR):
         raise HTTPException(status_code=503, detail="Working directory not ready")
@@ -591,11 +1945,57 @@ async def readiness_check():
     if not main_pid:
         raise HTTPException(status_code=503, detail="Main container not found")
 
-    return {"status": "ready"}
+    report = HEALTH.report()
+    if report["degraded"]:
+        raise HTTPException(status_code=503, detail=report)
+    return report
+
+
+async def run_selftest() -> dict:
+    """Conformance report of this pod (see executor.selftest)."""
+    os.makedirs(WORKING_DIR, exist_ok=True)
+    return await selftest.run_selftest(
+        run=lambda args, timeout: run_in_main_container(args, WORKING_DIR, timeout),
+        probe_binaries=probe_binaries,
+        working_dir=WORKING_DIR,
+        language=LANGUAGE,
+        version=VERSION,
+    )
+
+
+async def run_bench(args) -> dict:
+    """Synthetic benchmark of this pod, through the /execute path (see executor.bench)."""
+    os.makedirs(WORKING_DIR, exist_ok=True)
+
+    async def execute(code: str, timeout: int) -> dict:
+        response = await execute_code(ExecuteRequest(code=code, timeout=min(timeout, MAX_EXECUTION_TIME)))
+        return response.model_dump()
+
+    return await bench.run_bench(execute, LANGUAGE, args)
 
 
 if __name__ == "__main__":
+    if sys.argv[1:2] == ["selftest"]:
+        report = asyncio.run(run_selftest())
+        print(json.dumps(report, indent=2), flush=True)
+        sys.exit(0 if report["ok"] else 1)
+    if sys.argv[1:2] == ["bench"]:
+        bench_args = bench.parse_args(sys.argv[2:])
+        try:
+            report = asyncio.run(run_bench(bench_args))
+        except ValueError as e:
+            print(f"bench: {e}", file=sys.stderr, flush=True)
+            sys.exit(2)
+        print(json.dumps(report, indent=2), flush=True)
+        sys.exit(0 if report["ok"] else 1)
+
     import uvicorn
 
     port = int(os.getenv("SIDECAR_PORT", "8080"))
-    uvicorn.run(app, host="0.0.0.0", port=port)
+    try:
+        sockets, addresses = listen.bind_all(listen.parse(os.getenv("SIDECAR_LISTEN", "")), port)
+    except (ValueError, OSError) as e:
+        print(f"[LISTEN] {e}", file=sys.stderr, flush=True)
+        sys.exit(2)
+    print(f"[LISTEN] {', '.join(addresses)}", flush=True)
+    uvicorn.Server(uvicorn.Config(app, port=port)).run(sockets=sockets)
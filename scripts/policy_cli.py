@@ -0,0 +1,115 @@
+#!/usr/bin/env python3
+"""
+Policy Bundle CLI
+
+Usage:
+  python scripts/policy_cli.py validate <bundle.json> [--pin VERSION]
+  python scripts/policy_cli.py evaluate <bundle.json> <request.json> [--pin VERSION]
+
+validate checks a bundle the way the API loads it at startup (format,
+pinned version and the bounds of every setting it sets) and prints its
+digest and settings. evaluate dry-runs an example request against it and
+prints what the request would be allowed to do, as POST
+/admin/policy/evaluate does. Settings the bundle doesn't set come from
+the environment and .env, as they would in the API.
+
+Examples:
+  # Check a bundle before rolling it out
+  python scripts/policy_cli.py validate policies/prod.json --pin 2026.10.1
+
+  # What could a Python execution reaching pypi.org and example.com do?
+  echo '{"lang": "py", "hosts": ["pypi.org", "example.com"]}' > request.json
+  python scripts/policy_cli.py evaluate policies/prod.json request.json
+"""
+
+import argparse
+import json
+import sys
+from pathlib import Path
+
+# Add src to path for imports
+sys.path.insert(0, str(Path(__file__).parent.parent))
+
+# Load .env file if it exists
+from dotenv import load_dotenv
+load_dotenv(Path(__file__).parent.parent / ".env")
+
+from pydantic import ValidationError
+
+from src.config import PolicyBundleError, Settings
+from src.models.policy import PolicyEvaluationRequest
+from src.services.policy import evaluate
+
+
+def load_settings(args) -> Settings:
+    """Settings with the bundle applied, exiting with the problems if it doesn't load."""
+    try:
+        return Settings(policy_bundle_path=args.bundle, policy_bundle_version=args.pin)
+    except (ValidationError, PolicyBundleError) as e:
+        print(f"Invalid: {e}", file=sys.stderr)
+        sys.exit(1)
+
+
+def cmd_validate(args):
+    """Validate a bundle and show what it sets."""
+    config = load_settings(args)
+    bundle = config.get_policy_bundle()
+
+    print(f"Valid: {bundle.name} {bundle.version}")
+    print(f"Digest: {bundle.digest()}")
+    if bundle.description:
+        print(f"Description: {bundle.description}")
+    print("\nSettings:")
+    for name, value in sorted(bundle.settings().items()):
+        print(f"  {name.upper():<32} {json.dumps(value)}")
+
+
+def cmd_evaluate(args):
+    """Dry-run an example request against a bundle."""
+    config = load_settings(args)
+    try:
+        request = PolicyEvaluationRequest.model_validate_json(Path(args.request).read_text())
+    except (OSError, ValidationError) as e:
+        print(f"Invalid request: {e}", file=sys.stderr)
+        sys.exit(1)
+
+    result = evaluate(request, config)
+    print(result.model_dump_json(indent=2))
+    if not result.allowed:
+        sys.exit(2)
+
+
+def main():
+    parser = argparse.ArgumentParser(
+        description="Policy Bundle CLI",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  %(prog)s validate policies/prod.json --pin 2026.10.1
+  %(prog)s evaluate policies/prod.json request.json
+"""
+    )
+    subparsers = parser.add_subparsers(dest="command", required=True)
+
+    # validate command
+    validate_parser = subparsers.add_parser("validate", help="Validate a policy bundle")
+    validate_parser.add_argument("bundle", help="Path to the bundle JSON")
+    validate_parser.add_argument("--pin", help="Version the bundle must be (as POLICY_BUNDLE_VERSION)")
+
+    # evaluate command
+    evaluate_parser = subparsers.add_parser("evaluate", help="Dry-run an example request against a bundle")
+    evaluate_parser.add_argument("bundle", help="Path to the bundle JSON")
+    evaluate_parser.add_argument("request", help="Path to the example request JSON (as POST /admin/policy/evaluate)")
+    evaluate_parser.add_argument("--pin", help="Version the bundle must be (as POLICY_BUNDLE_VERSION)")
+
+    args = parser.parse_args()
+
+    handlers = {
+        "validate": cmd_validate,
+        "evaluate": cmd_evaluate,
+    }
+    handlers[args.command](args)
+
+
+if __name__ == "__main__":
+    main()
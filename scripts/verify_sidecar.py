@@ -0,0 +1,81 @@
+#!/usr/bin/env python3
+"""
+Verify an implementation of the sidecar API.
+
+Runs the conformance checks (src/services/kubernetes/conformance.py)
+against a running sidecar and prints which compatibility level it
+satisfies: basic, stateful or full.
+
+Usage:
+  kubectl port-forward pod/<pod> 8080:8080
+  python scripts/verify_sidecar.py http://localhost:8080 --language py
+  python scripts/verify_sidecar.py http://localhost:8080 --language zig \\
+      --code @probe.zig --hang-code @hang.zig --json
+
+Exits 0 when the sidecar satisfies --require (default: basic), 1 otherwise.
+The checks upload, patch and delete a file and create a workspace, so run
+them against a pod that isn't serving executions.
+"""
+
+import argparse
+import asyncio
+import json
+import sys
+from pathlib import Path
+
+# Add src to path for imports
+sys.path.insert(0, str(Path(__file__).parent.parent))
+
+import httpx
+
+from src.services.kubernetes.conformance import LEVELS, run_conformance
+
+
+def read_code(value: str | None) -> str | None:
+    """Code given inline, or read from a file with @path."""
+    if value and value.startswith("@"):
+        return Path(value[1:]).read_text()
+    return value
+
+
+def print_report(report: dict) -> None:
+    print(f"Sidecar: {report['base_url']} ({report['language']})\n")
+    for check in report["checks"]:
+        status = "PASS" if check["ok"] else "FAIL"
+        detail = f"  {check['detail']}" if check["detail"] else ""
+        print(f"  {status}  {check['level']:<8}  {check['name']:<18}{check['ms']:>8.1f} ms{detail}")
+    print()
+    for level in LEVELS:
+        print(f"  {level:<8}  {'satisfied' if report['levels'][level] else 'not satisfied'}")
+    print(f"\nCompatibility level: {report['level'] or 'none'}")
+
+
+async def main() -> int:
+    parser = argparse.ArgumentParser(description="Verify an implementation of the sidecar API")
+    parser.add_argument("url", help="Sidecar base URL, e.g. http://localhost:8080")
+    parser.add_argument("--language", default="py", help="Language the sidecar's pod runs (default: py)")
+    parser.add_argument("--code", help="Code printing 'conformance-ok' (inline, or @file)")
+    parser.add_argument("--hang-code", help="Code that never finishes (inline, or @file)")
+    parser.add_argument("--require", choices=LEVELS, default="basic", help="Level needed to exit 0")
+    parser.add_argument("--json", action="store_true", help="Print the report as JSON")
+    args = parser.parse_args()
+
+    async with httpx.AsyncClient(timeout=60) as client:
+        try:
+            report = await run_conformance(
+                client, args.url, args.language, read_code(args.code), read_code(args.hang_code)
+            )
+        except ValueError as e:
+            print(f"Error: {e}", file=sys.stderr)
+            return 2
+
+    if args.json:
+        print(json.dumps(report, indent=2))
+    else:
+        print_report(report)
+    satisfied = LEVELS.index(report["level"]) if report["level"] else -1
+    return 0 if satisfied >= LEVELS.index(args.require) else 1
+
+
+if __name__ == "__main__":
+    sys.exit(asyncio.run(main()))
@@ -1,5 +1,5 @@
 """API endpoints for the Code Interpreter API."""
 
-from . import admin, dashboard_metrics, exec, files, health, state
+from . import admin, dag, dashboard_metrics, exec, files, health, sessions, state
 
-__all__ = ["files", "exec", "health", "state", "admin", "dashboard_metrics"]
+__all__ = ["files", "exec", "dag", "health", "sessions", "state", "admin", "dashboard_metrics"]
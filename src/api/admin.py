@@ -3,14 +3,33 @@
 from datetime import UTC, datetime, timezone
 from typing import Any, Dict, List, Optional
 
-from fastapi import APIRouter, Depends, Header, HTTPException, Query
+from fastapi import APIRouter, Depends, Header, HTTPException, Query, Request, Response
 from pydantic import BaseModel, Field
 
 from ..config import settings
+from ..dependencies.services import (
+    get_elevation_service,
+    get_hot_config_service,
+    get_image_catalog_service,
+    get_quarantine_service,
+    get_session_transfer_service,
+)
 from ..models.api_key import RateLimits as RateLimitsModel
+from ..models.elevation import ElevatedGrantRequest, ElevatedGrantResponse, ElevationAuditEntry
+from ..models.image_catalog import TenantImages, TenantImagesRecord
+from ..models.policy import PolicyEvaluation, PolicyEvaluationRequest
+from ..models.runtime_config import ConfigChange, RuntimeConfigPatch, RuntimeConfigResponse
+from ..models.scheduler import SchedulerState
+from ..models.session import QuarantineRecord, QuarantineRequest, SessionImportResult
 from ..services.api_key_manager import get_api_key_manager
+from ..services.concurrency import execution_gate
 from ..services.detailed_metrics import get_detailed_metrics_service
 from ..services.health import health_service
+from ..services.image_catalog import ImageCatalogError
+from ..services.policy import evaluate as evaluate_policy
+from ..services.session_transfer import SessionTransferError, max_bundle_size
+from ..utils.request_body import read_limited_body
+from ..utils.security import SecurityAudit
 
 router = APIRouter(prefix="/admin", tags=["admin"])
 
@@ -208,3 +227,250 @@ async def get_admin_stats(hours: int = Query(24, ge=1, le=168), _: str = Depends
         "period_hours": hours,
         "timestamp": datetime.now(UTC).isoformat(),
     }
+
+
+@router.get("/config", response_model=RuntimeConfigResponse, summary="Runtime-adjustable policies")
+async def get_runtime_config(_: str = Depends(verify_master_key)):
+    """Get the current values of the policies PATCH /admin/config can change."""
+    service = get_hot_config_service()
+    return RuntimeConfigResponse(config=service.current(), overrides=service.overridden())
+
+
+@router.patch("/config", response_model=RuntimeConfigResponse, summary="Change policies without a restart")
+async def update_runtime_config(
+    patch: RuntimeConfigPatch,
+    request: Request,
+    x_change_reason: str | None = Header(default=None),
+    _: str = Depends(verify_master_key),
+):
+    """Change runtime policies on every replica; running executions and sessions are not affected.
+
+    Only the fields present in the body are changed. Each change is
+    recorded in the audit log (GET /admin/config/audit).
+    """
+    service = get_hot_config_service()
+    actor = request.client.host if request.client else None
+    changes = await service.update(patch, actor=actor, reason=x_change_reason)
+    return RuntimeConfigResponse(config=service.current(), overrides=service.overridden(), changes=changes)
+
+
+@router.delete("/config", response_model=RuntimeConfigResponse, summary="Reset policies to their deployed values")
+async def reset_runtime_config(
+    request: Request,
+    setting: list[str] | None = Query(None, description="Settings to reset; all of them if omitted"),
+    x_change_reason: str | None = Header(default=None),
+    _: str = Depends(verify_master_key),
+):
+    """Drop runtime overrides on every replica, so the settings go back to their deployed values.
+
+    Each setting that changes is recorded in the audit log like a PATCH.
+    """
+    service = get_hot_config_service()
+    actor = request.client.host if request.client else None
+    try:
+        changes = await service.reset(setting, actor=actor, reason=x_change_reason)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e))
+    return RuntimeConfigResponse(config=service.current(), overrides=service.overridden(), changes=changes)
+
+
+# The same routes at /config, for control planes that address the service rather than its admin API
+config_router = APIRouter(tags=["admin"])
+for _method, _endpoint in (
+    ("GET", get_runtime_config),
+    ("PATCH", update_runtime_config),
+    ("DELETE", reset_runtime_config),
+):
+    config_router.add_api_route("/config", _endpoint, methods=[_method], response_model=RuntimeConfigResponse)
+
+
+@router.get("/config/audit", response_model=list[ConfigChange], summary="Runtime policy change history")
+async def get_runtime_config_audit(limit: int = Query(100, ge=1, le=1000), _: str = Depends(verify_master_key)):
+    """Most recent policy changes made through PATCH /admin/config, newest first."""
+    return await get_hot_config_service().audit(limit)
+
+
+@router.get("/scheduler", response_model=SchedulerState, summary="Execution slots and how long sessions wait")
+async def get_scheduler_state(
+    starved_only: bool = Query(False, description="Only sessions whose p95 wait is above QUEUE_WAIT_ALERT_P95_MS"),
+    limit: int = Query(50, ge=1, le=1000, description="Most sessions to return, highest p95 wait first"),
+    _: str = Depends(verify_master_key),
+):
+    """This replica's slots in use, the executions waiting for one and each session's recent waits.
+
+    Executions are limited per replica, so each replica reports its own.
+    """
+    return execution_gate.state(starved_only=starved_only, limit=limit)
+
+
+@router.get("/policy", summary="The policy bundle in effect")
+async def get_policy(_: str = Depends(verify_master_key)):
+    """Name, version and digest of the policy bundle, its contents and the settings it sets; null without one."""
+    bundle = settings.get_policy_bundle()
+    if bundle is None:
+        return None
+    return {**bundle.describe(), "bundle": bundle.model_dump(exclude_none=True), "settings": bundle.settings()}
+
+
+@router.post("/policy/evaluate", response_model=PolicyEvaluation, summary="Dry-run a request against the policy")
+async def evaluate_policy_request(data: PolicyEvaluationRequest, _: str = Depends(verify_master_key)):
+    """What an execution like this would be allowed to do, without running it.
+
+    Reports whether /exec would accept it (and the error codes it would
+    return), its limits, network access, which of ``hosts`` it could
+    reach and how its output would be filtered.
+    """
+    return evaluate_policy(data)
+
+
+@router.get("/image-catalog", summary="Catalog images and the API keys that may run them")
+async def get_image_catalog(_: str = Depends(verify_master_key)):
+    """IMAGE_CATALOG, and each API key's allowed and pinned images."""
+    return {
+        "images": {name: image.model_dump() for name, image in sorted(settings.image_catalog.items())},
+        "tenants": [record.model_dump(mode="json") for record in await get_image_catalog_service().list_tenants()],
+    }
+
+
+@router.put(
+    "/image-catalog/tenants/{api_key_hash}",
+    response_model=TenantImagesRecord,
+    summary="Set the catalog images an API key may run",
+)
+async def set_tenant_images(
+    api_key_hash: str, data: TenantImages, request: Request, _: str = Depends(verify_master_key)
+):
+    """Replace the key's allowed images and its pins (the image a language runs in without ``image``).
+
+    Takes effect on the key's next execution on every replica.
+    """
+    actor = request.client.host if request.client else None
+    try:
+        record = await get_image_catalog_service().set_tenant(api_key_hash, data, actor=actor)
+    except ImageCatalogError as e:
+        raise HTTPException(status_code=400, detail={"error": e.code, "message": str(e)})
+    SecurityAudit.log_tenant_images(api_key_hash, actor, images=record.images, pins=record.pins)
+    return record
+
+
+@router.delete("/image-catalog/tenants/{api_key_hash}", response_model=bool, summary="Take away an API key's images")
+async def delete_tenant_images(api_key_hash: str, request: Request, _: str = Depends(verify_master_key)):
+    """The key's executions go back to the languages' default images."""
+    if not await get_image_catalog_service().delete_tenant(api_key_hash):
+        raise HTTPException(status_code=404, detail="API key has no catalog images")
+    actor = request.client.host if request.client else None
+    SecurityAudit.log_tenant_images(api_key_hash, actor)
+    return True
+
+
+@router.post("/quarantine", response_model=QuarantineRecord, summary="Quarantine a session for investigation")
+async def quarantine_session(data: QuarantineRequest, request: Request, _: str = Depends(verify_master_key)):
+    """Kill a session's running executions and freeze it, keeping its data as evidence.
+
+    New executions, file and state changes in the session are rejected with
+    409 until the quarantine is lifted. Its files, state and cell history no
+    longer expire, and the quarantine is logged as a critical security event.
+    """
+    actor = request.client.host if request.client else None
+    record = await get_quarantine_service().quarantine(data.session_id, reason=data.reason, actor=actor)
+    if not record:
+        raise HTTPException(status_code=404, detail="Session not found")
+    return record
+
+
+@router.get("/quarantine", response_model=list[QuarantineRecord], summary="Quarantined sessions")
+async def list_quarantined_sessions(_: str = Depends(verify_master_key)):
+    """Sessions currently quarantined, most recent first."""
+    return await get_quarantine_service().list_quarantined()
+
+
+@router.delete("/quarantine/{session_id}", response_model=bool, summary="Lift a session's quarantine")
+async def release_session(
+    session_id: str,
+    request: Request,
+    x_change_reason: str | None = Header(default=None),
+    _: str = Depends(verify_master_key),
+):
+    """Let the session take work again; its data expires after a fresh session TTL."""
+    actor = request.client.host if request.client else None
+    if not await get_quarantine_service().release(session_id, reason=x_change_reason, actor=actor):
+        raise HTTPException(status_code=404, detail="Session is not quarantined")
+    return True
+
+
+@router.get("/sessions/{session_id}/export", summary="Export a session to move it to another cluster")
+async def export_session(
+    session_id: str,
+    request: Request,
+    include_env: bool = Query(False, description="Include environment variable values, not just their names"),
+    _: str = Depends(verify_master_key),
+):
+    """A tar.gz of the session's files, kernel checkpoint, cell history, metadata and installed packages.
+
+    Import it into another cluster with POST /admin/sessions/import. The
+    session keeps running here; delete it once the import succeeded.
+    """
+    actor = request.client.host if request.client else None
+    try:
+        bundle = await get_session_transfer_service().export(session_id, include_env=include_env)
+    except SessionTransferError as e:
+        raise HTTPException(status_code=404, detail=str(e))
+    SecurityAudit.log_session_transfer(session_id, "exported", actor, include_env=include_env, size=len(bundle))
+    return Response(
+        content=bundle,
+        media_type="application/gzip",
+        headers={"Content-Disposition": f'attachment; filename="session-{session_id}.tar.gz"'},
+    )
+
+
+@router.post("/sessions/import", response_model=SessionImportResult, summary="Import a session exported elsewhere")
+async def import_session(request: Request, _: str = Depends(verify_master_key)):
+    """Recreate a session from an export (the request body), under its original session and file ids.
+
+    409 if a session with that id already exists here, 413 if the body is
+    larger than any bundle extraction would accept. The packages the
+    session installed are returned, not reinstalled.
+    """
+    actor = request.client.host if request.client else None
+    content = await read_limited_body(request, max_bundle_size())
+    try:
+        result = await get_session_transfer_service().import_bundle(content)
+    except SessionTransferError as e:
+        status_code = 409 if e.code == "session_exists" else 400
+        raise HTTPException(status_code=status_code, detail={"error": e.code, "message": str(e)})
+    SecurityAudit.log_session_transfer(result.session_id, "imported", actor, files=result.files, cells=result.cells)
+    return result
+
+
+@router.post(
+    "/elevated-grants", response_model=ElevatedGrantResponse, summary="Approve elevated executions for a while"
+)
+async def issue_elevated_grant(data: ElevatedGrantRequest, request: Request, _: str = Depends(verify_master_key)):
+    """Issue a signed grant allowing a longer timeout, more memory or network access.
+
+    Executions pass the token as ``elevation_grant``; each use is checked
+    against the grant's expiry, key and session binding and use count, and
+    audited. The token is only returned here.
+    """
+    actor = request.client.host if request.client else None
+    return await get_elevation_service().issue(data, actor=actor)
+
+
+@router.delete("/elevated-grants/{grant_id}", response_model=bool, summary="Revoke an elevated grant")
+async def revoke_elevated_grant(
+    grant_id: str,
+    request: Request,
+    x_change_reason: str | None = Header(default=None),
+    _: str = Depends(verify_master_key),
+):
+    """Refuse further executions under the grant; running ones are not stopped."""
+    actor = request.client.host if request.client else None
+    if not await get_elevation_service().revoke(grant_id, actor=actor, reason=x_change_reason):
+        raise HTTPException(status_code=404, detail="Grant not found or expired")
+    return True
+
+
+@router.get("/elevated-grants/audit", response_model=list[ElevationAuditEntry], summary="Elevated grant history")
+async def get_elevated_grant_audit(limit: int = Query(100, ge=1, le=1000), _: str = Depends(verify_master_key)):
+    """Most recent grant events (issued, used, denied, revoked), newest first."""
+    return await get_elevation_service().audit(limit)
@@ -0,0 +1,18 @@
+"""Deployment context endpoint."""
+
+from fastapi import APIRouter
+
+from ..models.context import ContextResponse
+from ..services.context import build_context
+
+router = APIRouter()
+
+
+@router.get("/context", response_model=ContextResponse)
+async def get_context():
+    """Describe the sandbox: languages, limits, network, working directory and
+    operator-provided context (banner, environment variables, mounted data).
+
+    Clients can pass this to a model instead of guessing what the environment contains.
+    """
+    return build_context()
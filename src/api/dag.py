@@ -0,0 +1,82 @@
+"""DAG execution API endpoint.
+
+Runs a small graph of dependent execution steps in one session, in
+parallel where dependencies and scopes allow.
+"""
+
+import structlog
+from fastapi import APIRouter, Request
+
+from ..dependencies.services import (
+    CellHistoryServiceDep,
+    EnvironmentSnapshotServiceDep,
+    ExecutionServiceDep,
+    FileServiceDep,
+    SessionReportServiceDep,
+    SessionServiceDep,
+    StateArchivalServiceDep,
+    StateServiceDep,
+    TimeoutAdvisorDep,
+    WorkspaceLockServiceDep,
+)
+from ..models.dag import DagRequest, DagResponse
+from ..services.dag import DagRunner
+from ..services.orchestrator import ExecutionOrchestrator
+from ..utils.id_generator import generate_request_id
+
+logger = structlog.get_logger(__name__)
+router = APIRouter()
+
+
+@router.post("/dag", response_model=DagResponse)
+async def execute_dag(
+    request: DagRequest,
+    http_request: Request,
+    session_service: SessionServiceDep,
+    file_service: FileServiceDep,
+    execution_service: ExecutionServiceDep,
+    state_service: StateServiceDep,
+    state_archival_service: StateArchivalServiceDep,
+    workspace_lock_service: WorkspaceLockServiceDep = None,
+    cell_history_service: CellHistoryServiceDep = None,
+    timeout_advisor: TimeoutAdvisorDep = None,
+    env_snapshot_service: EnvironmentSnapshotServiceDep = None,
+    session_report_service: SessionReportServiceDep = None,
+):
+    """Execute a graph of named steps with dependencies.
+
+    Each step runs like an /exec call in a shared session once the steps it
+    depends_on have completed. Independent steps run in parallel when their
+    scopes are disjoint; dependents of a failed step are skipped.
+
+    Returns:
+        DagResponse with per-step results and a critical-path timing summary
+    """
+    request_id = generate_request_id()[:8]
+
+    api_key_hash = getattr(http_request.state, "api_key_hash", None)
+    is_env_key = getattr(http_request.state, "is_env_key", False)
+
+    logger.info(
+        "DAG execution request",
+        request_id=request_id,
+        steps=len(request.steps),
+        entity_id=request.entity_id,
+        api_key_hash=api_key_hash[:8] if api_key_hash else "unknown",
+    )
+
+    orchestrator = ExecutionOrchestrator(
+        session_service=session_service,
+        file_service=file_service,
+        execution_service=execution_service,
+        state_service=state_service,
+        state_archival_service=state_archival_service,
+        workspace_lock_service=workspace_lock_service,
+        cell_history_service=cell_history_service,
+        timeout_advisor=timeout_advisor,
+        env_snapshot_service=env_snapshot_service,
+        session_report_service=session_report_service,
+    )
+    runner = DagRunner(orchestrator, session_service)
+
+    return await runner.run(request, request_id, api_key_hash=api_key_hash, is_env_key=is_env_key)
@@ -0,0 +1,14 @@
+"""Shared dataset endpoints."""
+
+from fastapi import APIRouter
+
+from ..models.dataset import DatasetListResponse
+from ..services.datasets import list_datasets
+
+router = APIRouter()
+
+
+@router.get("/datasets", response_model=DatasetListResponse)
+async def get_datasets():
+    """List the read-only datasets mounted into every session."""
+    return DatasetListResponse(datasets=list_datasets())
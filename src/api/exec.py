@@ -8,13 +8,19 @@ import structlog
 from fastapi import APIRouter, Request
 
 from ..dependencies.services import (
+    CellHistoryServiceDep,
+    ElevationServiceDep,
+    EnvironmentSnapshotServiceDep,
     ExecutionServiceDep,
     FileServiceDep,
+    SessionReportServiceDep,
     SessionServiceDep,
     StateArchivalServiceDep,
     StateServiceDep,
+    TimeoutAdvisorDep,
+    WorkspaceLockServiceDep,
 )
-from ..models import ExecRequest, ExecResponse
+from ..models import ExecPlanResponse, ExecRequest, ExecResponse, TimeoutSuggestion, TimeoutSuggestionRequest
 from ..services.orchestrator import ExecutionOrchestrator
 from ..utils.id_generator import generate_request_id
 
@@ -31,6 +37,12 @@ async def execute_code(
     execution_service: ExecutionServiceDep,
     state_service: StateServiceDep,
     state_archival_service: StateArchivalServiceDep,
+    workspace_lock_service: WorkspaceLockServiceDep = None,
+    cell_history_service: CellHistoryServiceDep = None,
+    timeout_advisor: TimeoutAdvisorDep = None,
+    env_snapshot_service: EnvironmentSnapshotServiceDep = None,
+    session_report_service: SessionReportServiceDep = None,
+    elevation_service: ElevationServiceDep = None,
 ):
     """Execute code with specified language and parameters.
 
@@ -50,6 +62,12 @@ async def execute_code(
         execution_service: Code execution service
         state_service: Python state persistence service (Redis)
         state_archival_service: Python state archival service (MinIO)
+        workspace_lock_service: Serializes executions within a session unless scopes are disjoint
+        cell_history_service: Records the execution in the session's cell history
+        timeout_advisor: Records the duration and suggests a timeout for the next run
+        env_snapshot_service: Records what the execution changed in the session's environment
+        session_report_service: Tallies the execution for the session's end-of-session report
+        elevation_service: Checks the request's elevated grant, if it carries one
 
     Returns:
         ExecResponse with session_id, stdout, stderr, and generated files
@@ -77,6 +95,12 @@ async def execute_code(
         execution_service=execution_service,
         state_service=state_service,
         state_archival_service=state_archival_service,
+        workspace_lock_service=workspace_lock_service,
+        cell_history_service=cell_history_service,
+        timeout_advisor=timeout_advisor,
+        env_snapshot_service=env_snapshot_service,
+        session_report_service=session_report_service,
+        elevation_service=elevation_service,
     )
 
     # Execute via orchestrator (handles validation, session, files, execution, cleanup)
@@ -89,3 +113,73 @@ async def execute_code(
     )
 
     return response
+
+
+@router.post("/exec/plan", response_model=ExecPlanResponse)
+async def plan_execution(
+    request: ExecRequest,
+    http_request: Request,
+    session_service: SessionServiceDep,
+    file_service: FileServiceDep,
+    execution_service: ExecutionServiceDep,
+    workspace_lock_service: WorkspaceLockServiceDep = None,
+):
+    """Explain what POST /exec would enforce for a request, without running it.
+
+    The request is checked against the same rules as /exec (language, code,
+    workspace scope, env names, rate limits) and the plan lists every
+    violation together with the image, command, environment and limits the
+    execution would get. No session is created and nothing is executed, so
+    policy configurations can be debugged safely.
+
+    Args:
+        request: Execution request, as it would be sent to /exec
+        http_request: HTTP request for accessing state (api_key_hash)
+        session_service: Session management service
+        file_service: File storage service
+        execution_service: Code execution service (not called)
+        workspace_lock_service: Reports locks the execution would wait for
+
+    Returns:
+        ExecPlanResponse with the violations and what would be enforced
+    """
+    orchestrator = ExecutionOrchestrator(
+        session_service=session_service,
+        file_service=file_service,
+        execution_service=execution_service,
+        workspace_lock_service=workspace_lock_service,
+    )
+    plan = await orchestrator.plan(
+        request,
+        api_key_hash=getattr(http_request.state, "api_key_hash", None),
+        is_env_key=getattr(http_request.state, "is_env_key", False),
+    )
+
+    logger.info(
+        "Execution planned",
+        language=request.lang,
+        allowed=plan.allowed,
+        violations=[violation.code for violation in plan.violations],
+    )
+
+    return plan
+
+
+@router.post("/exec/timeout-suggestion", response_model=TimeoutSuggestion)
+async def suggest_timeout(request: TimeoutSuggestionRequest, timeout_advisor: TimeoutAdvisorDep):
+    """Suggest a timeout for code from how long it took to run before.
+
+    The suggestion is TIMEOUT_SUGGESTION_PERCENTILE of the code's recorded
+    durations times TIMEOUT_SUGGESTION_MULTIPLIER, capped at
+    MAX_EXECUTION_TIME. Without enough runs of the same code, the session's
+    runs in the language are used, and without those MAX_EXECUTION_TIME
+    itself (basis "default").
+
+    Args:
+        request: Code, language and optionally the session to fall back to
+        timeout_advisor: Execution duration history
+
+    Returns:
+        TimeoutSuggestion with the seconds and what they're based on
+    """
+    return await timeout_advisor.suggest(request.lang, request.code, request.session_id)
@@ -1,22 +1,41 @@
 """File management API endpoints."""
 
 # Standard library imports
+import asyncio
 from datetime import UTC, datetime, timezone
 from pathlib import Path
-from typing import List, Optional
+from typing import Annotated, List, Optional
 from urllib.parse import quote
 
 # Third-party imports
 import structlog
-from fastapi import APIRouter, File, Form, HTTPException, Query, UploadFile
+from fastapi import APIRouter, Depends, File, Form, HTTPException, Query, Request, UploadFile
 from fastapi.responses import Response, StreamingResponse
 from unidecode import unidecode
 
 # Local application imports
 from ..config import settings
-from ..dependencies import FileServiceDep, SessionServiceDep
+from ..dependencies import FileServiceDep, QuarantineServiceDep, SessionServiceDep, reject_quarantined_session
+from ..models.files import (
+    ArchiveExtractRequest,
+    ArchiveExtractResponse,
+    ExtractedFile,
+    FilePreviewColumn,
+    FilePreviewResponse,
+)
 from ..models.session import SessionCreate
+from ..services.archive import ArchiveError, ArchiveLimits, extract_archive, flat_name, unique_name
 from ..services.execution.output import OutputProcessor
+from ..services.preview import DEFAULT_PREVIEW_ROWS, MAX_PREVIEW_ROWS, PreviewError, PreviewFormat, preview_file
+from ..services.thumbnail import (
+    DEFAULT_THUMBNAIL_WIDTH,
+    MAX_THUMBNAIL_WIDTH,
+    MIN_THUMBNAIL_WIDTH,
+    ThumbnailError,
+    generate_thumbnail,
+)
+from ..utils.checksum import DEFAULT_ALGORITHM, ChecksumAlgorithm, compute_checksum, verify_checksum
+from ..utils.request_body import read_limited_body
 
 logger = structlog.get_logger(__name__)
 router = APIRouter()
@@ -48,6 +67,10 @@ async def upload_file(
     file: UploadFile | None = File(None),
     files: list[UploadFile] | None = File(None),
     entity_id: str | None = Form(None),
+    checksum: Annotated[
+        list[str] | None,
+        Form(description="Expected checksum per file, in upload order: '<algo>:<hex>' (bare hex means sha256)"),
+    ] = None,
     file_service: FileServiceDep = None,
     session_service: SessionServiceDep = None,
 ):
@@ -55,6 +78,8 @@ async def upload_file(
 
     Accepts files in either 'file' (singular) or 'files' (plural) field names.
     LibreChat uses 'file' while our tests use 'files'.
+
+    With expected checksums, nothing is stored unless every file matches.
     """
     try:
         # Handle both singular and plural field names
@@ -97,6 +122,9 @@ async def upload_file(
                 detail=f"Too many files. Maximum {settings.max_files_per_session} files allowed",
             )
 
+        contents = [await file.read() for file in upload_files]
+        verified = _verify_upload_checksums(upload_files, contents, checksum) if checksum else None
+
         uploaded_files = []
 
         # Create an actual session in Redis for this upload
@@ -107,10 +135,7 @@ async def upload_file(
         session = await session_service.create_session(SessionCreate(metadata=session_metadata))
         session_id = session.session_id
 
-        for file in upload_files:
-            # Read file content
-            content = await file.read()
-
+        for index, (file, content) in enumerate(zip(upload_files, contents)):
             # Sanitize filename before storage so the name on disk in the
             # execution pod matches what LibreChat reports to the model.
             sanitized_name = OutputProcessor.sanitize_filename(file.filename)
@@ -139,6 +164,8 @@ async def upload_file(
                     "contentType": file.content_type or "application/octet-stream",
                 }
             )
+            if verified:
+                uploaded_files[-1]["checksum"] = verified[index]
 
         logger.info(
             "Files uploaded successfully",
@@ -151,7 +178,10 @@ async def upload_file(
         return {
             "message": "success",
             "session_id": session_id,
-            "files": [{"filename": file["name"], "fileId": file["id"]} for file in uploaded_files],
+            "files": [
+                {"filename": file["name"], "fileId": file["id"], **({"checksum": file["checksum"]} if verified else {})}
+                for file in uploaded_files
+            ],
         }
 
     except HTTPException:
@@ -161,6 +191,43 @@ async def upload_file(
         raise HTTPException(status_code=500, detail="Failed to upload files")
 
 
+def _verify_upload_checksums(upload_files: list[UploadFile], contents: list[bytes], expected: list[str]) -> list[str]:
+    """Check each upload against its expected checksum.
+
+    Returns:
+        The verified checksums, as ``<algo>:<hex>``
+
+    Raises:
+        HTTPException: 400 if the checksums are malformed or don't line up
+            with the files, 422 if a file doesn't match
+    """
+    if len(expected) != len(upload_files):
+        raise HTTPException(
+            status_code=400,
+            detail={
+                "error": "invalid_checksum",
+                "message": f"Got {len(expected)} checksums for {len(upload_files)} files",
+            },
+        )
+    verified = []
+    for file, content, value in zip(upload_files, contents, expected):
+        try:
+            matches, actual = verify_checksum(content, value)
+        except ValueError as e:
+            raise HTTPException(status_code=400, detail={"error": "invalid_checksum", "message": str(e)})
+        if not matches:
+            logger.warning("Upload checksum mismatch", filename=file.filename, expected=value, actual=actual)
+            raise HTTPException(
+                status_code=422,
+                detail={
+                    "error": "checksum_mismatch",
+                    "message": f"Checksum mismatch for {file.filename}: expected {value}, got {actual}",
+                },
+            )
+        verified.append(actual)
+    return verified
+
+
 @router.get("/files/{session_id}")
 async def list_files(
     session_id: str,
@@ -243,6 +310,166 @@ async def list_files(
         raise HTTPException(status_code=404, detail="Session not found")
 
 
+@router.post("/files/extract", response_model=ArchiveExtractResponse)
+async def extract_file(
+    request: ArchiveExtractRequest,
+    file_service: FileServiceDep = None,
+    quarantine_service: QuarantineServiceDep = None,
+):
+    """Unpack a zip or tar archive stored in the session into the session's files.
+
+    Members are checked for path traversal and decompression bombs before
+    anything is stored; links and special files are skipped. The workspace
+    is flat, so a member's directories become part of its name
+    (``data/2024/a.csv`` -> ``data_2024_a.csv``). Members that end up with
+    the same name get a numbered suffix (``a_b-2.txt``).
+    """
+    # The session is named in the body, so the route dependency can't be used
+    await reject_quarantined_session(request.session_id, quarantine_service)
+    file_info = await file_service.get_file_info(request.session_id, request.file_id)
+    if not file_info:
+        raise HTTPException(status_code=404, detail="File not found")
+    content = await file_service.get_file_content(request.session_id, request.file_id)
+    if content is None:
+        raise HTTPException(status_code=404, detail="File content not found")
+
+    existing = await file_service.list_files(request.session_id)
+    limits = ArchiveLimits(
+        max_entries=settings.archive_max_entries,
+        max_files=settings.max_files_per_session - len(existing) + (1 if request.delete_archive else 0),
+        max_file_size=settings.max_file_size_mb * 1024 * 1024,
+        max_total_size=max(0, settings.max_total_file_size_mb * 1024 * 1024 - sum(f.size for f in existing)),
+        max_ratio=settings.archive_max_compression_ratio,
+    )
+    try:
+        extracted = extract_archive(content, limits)
+    except ArchiveError as e:
+        status_code = 413 if e.code == "archive_limit_exceeded" else 400
+        logger.warning(
+            "Archive extraction refused",
+            session_id=request.session_id,
+            file_id=request.file_id,
+            code=e.code,
+            error=str(e),
+        )
+        raise HTTPException(status_code=status_code, detail={"error": e.code, "message": str(e)})
+
+    files = []
+    taken: set[str] = set()
+    for path, data in extracted.files:
+        filename = unique_name(OutputProcessor.sanitize_filename(flat_name(path)), taken)
+        file_id = await file_service.store_uploaded_file(
+            session_id=request.session_id,
+            filename=filename,
+            content=data,
+        )
+        files.append(ExtractedFile(file_id=file_id, filename=filename, archive_path=path, size=len(data)))
+    if request.delete_archive:
+        await file_service.delete_file(request.session_id, request.file_id)
+
+    logger.info(
+        "Archive extracted",
+        session_id=request.session_id,
+        archive=file_info.filename,
+        files=len(files),
+        skipped=len(extracted.skipped),
+    )
+    return ArchiveExtractResponse(session_id=request.session_id, files=files, skipped=extracted.skipped)
+
+
+@router.get("/files/{session_id}/{file_id}/checksum")
+async def get_file_checksum(
+    session_id: str,
+    file_id: str,
+    algo: ChecksumAlgorithm = Query(DEFAULT_ALGORITHM, description="Hash algorithm"),
+    file_service: FileServiceDep = None,
+):
+    """Checksum of a stored file, to verify it end to end."""
+    file_info = await file_service.get_file_info(session_id, file_id)
+    if not file_info:
+        raise HTTPException(status_code=404, detail="File not found")
+
+    content = await file_service.get_file_content(session_id, file_id)
+    if content is None:
+        raise HTTPException(status_code=404, detail="File content not found")
+
+    return {
+        "file_id": file_id,
+        "filename": file_info.filename,
+        "size": len(content),
+        "algo": algo,
+        "checksum": compute_checksum(content, algo),
+    }
+
+
+@router.get("/files/{session_id}/{file_id}/preview", response_model=FilePreviewResponse)
+async def get_file_preview(
+    session_id: str,
+    file_id: str,
+    rows: int = Query(DEFAULT_PREVIEW_ROWS, ge=1, le=MAX_PREVIEW_ROWS, description="Rows to sample"),
+    format: PreviewFormat | None = Query(None, description="Override the format detected from the filename"),
+    file_service: FileServiceDep = None,
+):
+    """Schema and first rows of a CSV, TSV, JSON Lines or Parquet file, without running code."""
+    file_info = await file_service.get_file_info(session_id, file_id)
+    if not file_info:
+        raise HTTPException(status_code=404, detail="File not found")
+
+    content = await file_service.get_file_content(session_id, file_id)
+    if content is None:
+        raise HTTPException(status_code=404, detail="File content not found")
+
+    try:
+        # Parsing is CPU-bound; keep it off the event loop
+        preview = await asyncio.to_thread(preview_file, file_info.filename, content, rows, format)
+    except PreviewError as e:
+        status_code = 415 if e.code == "unsupported_preview_format" else 422
+        raise HTTPException(status_code=status_code, detail={"error": e.code, "message": str(e)})
+
+    return FilePreviewResponse(
+        file_id=file_id,
+        filename=file_info.filename,
+        format=preview.format,
+        columns=[FilePreviewColumn(name=c.name, type=c.type, nullable=c.nullable) for c in preview.columns],
+        rows=preview.rows,
+        truncated=preview.truncated,
+        total_rows=preview.total_rows,
+    )
+
+
+@router.get("/files/{session_id}/{file_id}/thumbnail")
+async def get_file_thumbnail(
+    session_id: str,
+    file_id: str,
+    w: int = Query(
+        DEFAULT_THUMBNAIL_WIDTH, ge=MIN_THUMBNAIL_WIDTH, le=MAX_THUMBNAIL_WIDTH, description="Width in pixels"
+    ),
+    file_service: FileServiceDep = None,
+):
+    """PNG thumbnail of an image file, generated on first request and cached."""
+    file_info = await file_service.get_file_info(session_id, file_id)
+    if not file_info:
+        raise HTTPException(status_code=404, detail="File not found")
+
+    thumbnail = await file_service.get_thumbnail(session_id, file_id, w)
+    if thumbnail is None:
+        content = await file_service.get_file_content(session_id, file_id)
+        if content is None:
+            raise HTTPException(status_code=404, detail="File content not found")
+        try:
+            thumbnail = await generate_thumbnail(content, w)
+        except ThumbnailError as e:
+            status_code = 415 if e.code == "unsupported_thumbnail_format" else 422
+            raise HTTPException(status_code=status_code, detail={"error": e.code, "message": str(e)})
+        await file_service.store_thumbnail(session_id, file_id, w, thumbnail)
+
+    return Response(
+        content=thumbnail,
+        media_type="image/png",
+        headers={"Cache-Control": "private, max-age=3600", "X-Content-Type-Options": "nosniff"},
+    )
+
+
 @router.get("/download/{session_id}/{file_id}")
 async def download_file(session_id: str, file_id: str, file_service: FileServiceDep = None):
     """Download a file directly - LibreChat compatible."""
@@ -323,7 +550,38 @@ async def download_file_options(session_id: str, file_id: str):
     )
 
 
-@router.delete("/files/{session_id}/{file_id}")
+@router.patch("/files/{session_id}/{file_id}", dependencies=[Depends(reject_quarantined_session)])
+async def write_to_file(
+    session_id: str,
+    file_id: str,
+    request: Request,
+    offset: int | None = Query(None, ge=0, description="Write at this byte offset instead of appending"),
+    expected_size: int | None = Query(None, ge=0, description="Only write if the file is this many bytes (409 if not)"),
+    file_service: FileServiceDep = None,
+):
+    """Append the request body to a stored file, or write it at ``offset``; the file keeps its id.
+
+    ``expected_size`` makes the write conditional on the file's current size,
+    so a client retrying an append whose response was lost can't apply it
+    twice. An offset past the end of the file is refused with 409.
+    """
+    max_size = settings.max_file_size_mb * 1024 * 1024
+    file_info = await file_service.get_file_info(session_id, file_id)
+    if not file_info:
+        raise HTTPException(status_code=404, detail="File not found")
+
+    data = await read_limited_body(request, max_size)
+    new_size = file_info.size + len(data) if offset is None else max(file_info.size, offset + len(data))
+    if new_size > max_size:
+        raise HTTPException(status_code=413, detail=f"File would exceed maximum size of {settings.max_file_size_mb}MB")
+
+    file_info = await file_service.write_file_range(session_id, file_id, data, offset, expected_size)
+    if not file_info:
+        raise HTTPException(status_code=404, detail="File not found")
+    return {"id": file_id, "name": file_info.filename, "size": file_info.size, "written": len(data)}
+
+
+@router.delete("/files/{session_id}/{file_id}", dependencies=[Depends(reject_quarantined_session)])
 async def delete_file(session_id: str, file_id: str, file_service: FileServiceDep = None):
     """Delete a file from the session - LibreChat compatible."""
     try:
@@ -1,16 +1,19 @@
 """Health check and monitoring endpoints."""
 
-from datetime import UTC
+from datetime import UTC, datetime, timedelta
 
 import structlog
 from fastapi import APIRouter, Depends, HTTPException, Query
-from fastapi.responses import JSONResponse
+from fastapi.responses import JSONResponse, PlainTextResponse
 
 from .._version import __version__
 from ..config import settings
 from ..dependencies.auth import verify_api_key
+from ..services.concurrency import execution_gate
 from ..services.health import HealthStatus, health_service
 from ..services.metrics import metrics_collector
+from ..services.prometheus import CONTENT_TYPE, render_runtime_stats, render_scheduler_state
+from ..services.sqlite_metrics import sqlite_metrics_service
 
 logger = structlog.get_logger(__name__)
 router = APIRouter()
@@ -294,6 +297,47 @@ async def get_language_metrics(
         raise HTTPException(status_code=500, detail="Failed to retrieve language metrics")
 
 
+async def _runtime_stats(hours: int) -> list[dict]:
+    if not settings.sqlite_metrics_enabled:
+        raise HTTPException(status_code=503, detail="Execution history is disabled (SQLITE_METRICS_ENABLED)")
+    end = datetime.now(UTC)
+    try:
+        return await sqlite_metrics_service.get_runtime_stats(start=end - timedelta(hours=hours), end=end)
+    except Exception as e:
+        logger.error("Failed to get runtime stats", error=str(e))
+        raise HTTPException(status_code=500, detail="Failed to retrieve runtime stats")
+
+
+@router.get("/stats", summary="Success rates and durations per language and image")
+async def get_runtime_stats(
+    hours: int = Query(24, ge=1, le=2160, description="Number of hours of history to include"),
+    _: str = Depends(verify_api_key),
+):
+    """Get success, error and timeout rates and p50/p95 durations per language and runtime image.
+
+    Computed from the execution history, so it covers every API replica and
+    survives restarts.
+    """
+    stats = await _runtime_stats(hours)
+    return {"runtimes": stats, "period_hours": hours}
+
+
+@router.get("/metrics/prometheus", summary="Per-language metrics for Prometheus")
+async def get_prometheus_metrics(
+    hours: int = Query(24, ge=1, le=2160, description="Number of hours of history to include"),
+    _: str = Depends(verify_api_key),
+):
+    """The /stats figures in the Prometheus text format, as gauges over the last ``hours``."""
+    stats = await _runtime_stats(hours)
+    return PlainTextResponse(render_runtime_stats(stats, hours), media_type=CONTENT_TYPE)
+
+
+@router.get("/metrics/prometheus/scheduler", summary="Execution slot metrics for Prometheus")
+async def get_scheduler_prometheus_metrics(_: str = Depends(verify_api_key)):
+    """This replica's execution slots, slot waits and starved sessions in the Prometheus text format."""
+    return PlainTextResponse(render_scheduler_state(execution_gate.state()), media_type=CONTENT_TYPE)
+
+
 @router.get("/metrics/by-api-key/{key_hash}", summary="Per-API-key metrics")
 async def get_api_key_metrics(
     key_hash: str,
@@ -0,0 +1,18 @@
+"""Image catalog endpoints."""
+
+from fastapi import APIRouter, Request
+
+from ..dependencies.services import get_image_catalog_service
+from ..models.image_catalog import ImageListResponse
+
+router = APIRouter()
+
+
+@router.get("/images", response_model=ImageListResponse)
+async def get_images(http_request: Request):
+    """List the catalog images this API key may run, to pass as ``image`` on /exec."""
+    images = await get_image_catalog_service().available(
+        getattr(http_request.state, "api_key_hash", None),
+        is_env_key=getattr(http_request.state, "is_env_key", False),
+    )
+    return ImageListResponse(images=images)
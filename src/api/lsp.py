@@ -0,0 +1,23 @@
+"""Language server endpoint.
+
+Asks a language server (pyright, gopls, typescript-language-server) about
+a file of a session's code: its diagnostics, the hover text at a position,
+or where the symbol at a position is defined. Nothing is executed.
+"""
+
+from fastapi import APIRouter
+
+from ..dependencies.services import LspProxyServiceDep
+from ..models.lsp import LspQueryRequest, LspQueryResponse
+
+router = APIRouter()
+
+
+@router.post("/lsp", response_model=LspQueryResponse)
+async def query_language_server(request: LspQueryRequest, lsp_service: LspProxyServiceDep):
+    """Run a diagnostics, hover or definition query in a warm pod of the language.
+
+    The pod sees the listed session files, and ``code`` (if given) at ``path``.
+    Lines and columns are 1-based.
+    """
+    return await lsp_service.query(request)
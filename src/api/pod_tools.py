@@ -0,0 +1,152 @@
+"""File tools run against a session's files in a warm pod.
+
+Renders LaTeX or Markdown documents with the toolchains in the language's
+image, and converts media files with its ffmpeg; the results are stored in
+the session like an execution's generated files. Searches the contents of
+a session's files, applies unified diffs to them, outlines source files and
+syncs stored files incrementally (rsync-style signatures and deltas).
+"""
+
+from fastapi import APIRouter, Depends, Query
+from fastapi.responses import StreamingResponse
+
+from ..dependencies.services import PodToolsServiceDep, QuarantineServiceDep, reject_quarantined_session
+from ..models.pod_tools import (
+    FilePatchRequest,
+    FilePatchResponse,
+    FileSearchResponse,
+    FileSymbolsResponse,
+    MediaRequest,
+    RenderRequest,
+    RenderResponse,
+    SyncDelta,
+    SyncDeltaResponse,
+    SyncPatchResponse,
+    SyncSignature,
+    SyncSignatureResponse,
+)
+
+router = APIRouter()
+
+
+@router.post("/render", response_model=RenderResponse)
+async def render_document(
+    request: RenderRequest, tools_service: PodToolsServiceDep, quarantine_service: QuarantineServiceDep
+):
+    """Render LaTeX to PDF or Markdown to HTML/PDF in a warm pod of ``language``.
+
+    The pod sees the listed session files, so ``path`` and the document's
+    includes (images, .bib) can name them. The rendered file is stored in
+    ``session_id`` (the files' session by default) and returned as ``file``.
+    """
+    session_id = request.session_id or (request.files[0].session_id if request.files else None)
+    if session_id:
+        await reject_quarantined_session(session_id, quarantine_service)
+    return await tools_service.render(request)
+
+
+@router.post("/media")
+async def process_media(
+    request: MediaRequest, tools_service: PodToolsServiceDep, quarantine_service: QuarantineServiceDep
+):
+    """Convert a session file with ffmpeg in a warm pod of ``language``, streaming NDJSON events.
+
+    A start event, then progress events as ffmpeg reports them, then a result
+    event with the exit code, status, ffmpeg's stderr and the stored output
+    as ``file``. ``output`` must not be the name of an uploaded file (409).
+    """
+    await reject_quarantined_session(request.session_id or request.input.session_id, quarantine_service)
+    events = await tools_service.media(request)
+    return StreamingResponse(events, media_type="application/x-ndjson")
+
+
+@router.get("/files/{session_id}/search", response_model=FileSearchResponse)
+async def search_session_files(
+    session_id: str,
+    tools_service: PodToolsServiceDep,
+    q: str = Query(..., min_length=1, description="Regex, or literal text with regex=false"),
+    glob: str = Query("**/*", description="Paths to search, e.g. **/*.py"),
+    regex: bool = Query(True, description="Treat q as a regex"),
+    ignore_case: bool = Query(False, description="Match case-insensitively"),
+    context: int = Query(2, ge=0, le=10, description="Lines before and after each match"),
+    max_matches: int | None = Query(None, ge=1, le=200, description="Stop after this many matches"),
+    language: str = Query("py", description="Language of the warm pod that runs the search"),
+):
+    """Search the contents of the session's files, without executing anything.
+
+    The files are uploaded to a warm pod and searched by its sidecar, with
+    the same bounds (200 matches, 5000 files, 1MiB per file, 5 seconds).
+    Each match names its file by name (``path``) and id.
+    """
+    return await tools_service.search(session_id, q, glob, regex, ignore_case, context, max_matches, language)
+
+
+@router.post(
+    "/files/{session_id}/patch",
+    response_model=FilePatchResponse,
+    dependencies=[Depends(reject_quarantined_session)],
+)
+async def patch_session_files(session_id: str, request: FilePatchRequest, tools_service: PodToolsServiceDep):
+    """Apply a unified diff to the session's files: every hunk applies, or nothing changes.
+
+    Paths in the diff are file names in the session (after ``strip``
+    components). Modified files keep their ids, created files are stored as
+    uploads and deleted files are removed. A hunk that doesn't apply fails
+    the whole diff with 409, listing the rejects.
+    """
+    return await tools_service.apply_patch(session_id, request)
+
+
+@router.get("/files/{session_id}/{file_id}/symbols", response_model=FileSymbolsResponse)
+async def session_file_symbols(
+    session_id: str,
+    file_id: str,
+    tools_service: PodToolsServiceDep,
+    language: str = Query("py", description="Language of the warm pod that parses the file"),
+):
+    """Functions, classes and other definitions in a session's source file, with their 1-based line ranges.
+
+    The file is parsed by a warm pod's sidecar (tree-sitter, or ``ast`` for
+    Python when the image lacks it); its type comes from its name.
+    """
+    return await tools_service.symbols(session_id, file_id, language)
+
+
+@router.get("/files/{session_id}/{file_id}/sync/signature", response_model=SyncSignatureResponse)
+async def session_file_signature(
+    session_id: str,
+    file_id: str,
+    tools_service: PodToolsServiceDep,
+    block_size: int = Query(8192, ge=512, le=1024 * 1024, description="Bytes per block"),
+    language: str = Query("py", description="Language of the warm pod that computes it"),
+):
+    """Block signature of a stored file, to compute a delta against before uploading a new version."""
+    return await tools_service.sync_signature(session_id, file_id, block_size, language)
+
+
+@router.post("/files/{session_id}/{file_id}/sync/delta", response_model=SyncDeltaResponse)
+async def session_file_delta(
+    session_id: str,
+    file_id: str,
+    signature: SyncSignature,
+    tools_service: PodToolsServiceDep,
+    language: str = Query("py", description="Language of the warm pod that computes it"),
+):
+    """Delta from the client's copy of a file (described by its signature) to the stored file, to download it."""
+    return await tools_service.sync_delta(session_id, file_id, signature, language)
+
+
+@router.post(
+    "/files/{session_id}/{file_id}/sync/patch",
+    response_model=SyncPatchResponse,
+    dependencies=[Depends(reject_quarantined_session)],
+)
+async def patch_session_file(
+    session_id: str,
+    file_id: str,
+    delta: SyncDelta,
+    tools_service: PodToolsServiceDep,
+    language: str = Query("py", description="Language of the warm pod that applies it"),
+):
+    """Apply a delta (made against the stored file's signature) to the stored file, which keeps its id."""
+    return await tools_service.sync_patch(session_id, file_id, delta, language)
@@ -0,0 +1,822 @@
+"""Session management API endpoints.
+
+Session-scoped settings that apply to every execution in a session,
+such as stored environment variables, advisory workspace locks, and
+control of running executions (interrupt, kernel restart) and
+termination with an end-of-session report, plus a summary of the
+variables in a session's persisted state, export of its dataframes, code
+completion against it, and the history of executed cells (with re-runs,
+diffs of modified re-runs, and export as a notebook, HTML page or
+script).
+"""
+
+from datetime import UTC, datetime, timedelta
+
+import structlog
+from fastapi import APIRouter, Depends, HTTPException, Query, Request, Response
+
+from ..config import settings
+from ..dependencies.services import (
+    CellHistoryServiceDep,
+    EnvironmentSnapshotServiceDep,
+    ExecutionServiceDep,
+    FileServiceDep,
+    SessionReportServiceDep,
+    SessionServiceDep,
+    StateArchivalServiceDep,
+    StateServiceDep,
+    TimeoutAdvisorDep,
+    VariableInspectorDep,
+    WorkspaceLockServiceDep,
+    reject_quarantined_session,
+)
+from ..models import ExecRequest, ExecResponse
+from ..models.cell import CellDiffRequest, CellDiffResponse, CellInfo
+from ..models.env_snapshot import SessionChangesResponse
+from ..models.session_report import SessionReport, SessionTerminationResponse
+from ..models.session import (
+    REDACTED_VALUE,
+    CompletionRequest,
+    CompletionResponse,
+    DataFrameExportResponse,
+    SessionEnvResponse,
+    SessionEnvUpdate,
+    SessionResponse,
+    SessionRestartResponse,
+    SessionVariablesResponse,
+    WorkspaceLockInfo,
+    WorkspaceLockRequest,
+    WorkspaceLockResponse,
+)
+from ..services.cells import diff_cells, file_digests, truncate_output
+from ..services.notebook import EXPORT_FORMATS, EXPORT_MEDIA_TYPES, load_images, render_export
+from ..services.orchestrator import ExecutionOrchestrator
+from ..services.variables import DATAFRAME_FORMATS, DATAFRAME_SAMPLES
+from ..services.workspace_lock import WORKSPACE_ROOT, normalize_scope_path
+from ..utils.id_generator import generate_request_id
+from ..utils.security import SecurityAudit, SecurityValidator
+
+logger = structlog.get_logger(__name__)
+router = APIRouter()
+
+DATAFRAME_MEDIA_TYPES = {"csv": "text/csv", "parquet": "application/vnd.apache.parquet"}
+
+
+def _redact(env: dict[str, str]) -> dict[str, str]:
+    """Replace every value with a fixed placeholder."""
+    return dict.fromkeys(sorted(env), REDACTED_VALUE)
+
+
+def _lock_info(lock: dict) -> WorkspaceLockInfo:
+    """Convert a stored lock record to its API representation."""
+    return WorkspaceLockInfo(
+        paths=[path or "/" for path in lock["paths"]],
+        owner=lock["owner"],
+        expires_at=datetime.fromtimestamp(lock["expires_at"], UTC),
+    )
+
+
+async def _require_session(session_id: str, session_service: SessionServiceDep) -> None:
+    """Raise 404 unless the session exists."""
+    if not await session_service.get_session(session_id):
+        raise HTTPException(
+            status_code=404,
+            detail={"error": "session_not_found", "message": "Session not found"},
+        )
+
+
+@router.get("/sessions/{session_id}", response_model=SessionResponse)
+async def get_session_status(session_id: str, session_service: SessionServiceDep) -> SessionResponse:
+    """Get a session's status, including how often its kernel was restarted.
+
+    Returns:
+        - 200: Session status
+        - 404: Session not found
+    """
+    session = await session_service.get_session(session_id)
+    if not session:
+        raise HTTPException(
+            status_code=404,
+            detail={"error": "session_not_found", "message": "Session not found"},
+        )
+
+    return SessionResponse(
+        session_id=session.session_id,
+        status=session.status,
+        created_at=session.created_at,
+        expires_at=session.expires_at,
+        restart_count=session.restart_count,
+        last_restart_at=session.last_restart_at,
+    )
+
+
+@router.delete(
+    "/sessions/{session_id}",
+    response_model=SessionTerminationResponse,
+    dependencies=[Depends(reject_quarantined_session)],
+)
+async def terminate_session(
+    session_id: str,
+    session_service: SessionServiceDep,
+    session_report_service: SessionReportServiceDep,
+) -> SessionTerminationResponse:
+    """Terminate a session and return its end-of-session report.
+
+    Its pods, files, state and history are deleted. The report (executions,
+    resources consumed, artifacts with sizes and SHA-256 digests, and policy
+    violations) is made before the files go, and stays available from
+    GET /sessions/{id}/report for SESSION_REPORT_RETENTION_HOURS.
+
+    Returns:
+        - 200: The session was terminated
+        - 404: Session not found
+        - 409: The session is quarantined
+    """
+    await _require_session(session_id, session_service)
+    terminated = await session_service.delete_session(session_id, reason="deleted")
+    report = await session_report_service.get(session_id)
+    logger.info("Session terminated", session_id=session_id[:12], report=report is not None)
+    return SessionTerminationResponse(session_id=session_id, terminated=terminated, report=report)
+
+
+@router.get("/sessions/{session_id}/report", response_model=SessionReport)
+async def get_session_report(
+    session_id: str,
+    session_service: SessionServiceDep,
+    session_report_service: SessionReportServiceDep,
+) -> SessionReport:
+    """Get a session's end-of-session report.
+
+    For a terminated session this is the report made when it ended, while
+    it's retained; for an active one, the report so far (ended_at is null).
+
+    Returns:
+        - 200: The report
+        - 404: Session not found, its report has expired, or SESSION_REPORT_ENABLED is off
+    """
+    if not settings.session_report_enabled:
+        raise HTTPException(
+            status_code=404,
+            detail={"error": "session_reports_disabled", "message": "Session reports are disabled"},
+        )
+    session = await session_service.get_session(session_id)
+    if session:
+        return await session_report_service.build(session_id, session)
+    report = await session_report_service.get(session_id)
+    if not report:
+        raise HTTPException(
+            status_code=404,
+            detail={"error": "session_report_not_found", "message": "Session not found, or its report has expired"},
+        )
+    return report
+
+
+@router.get("/sessions/{session_id}/variables", response_model=SessionVariablesResponse)
+async def get_session_variables(
+    session_id: str,
+    session_service: SessionServiceDep,
+    variable_inspector: VariableInspectorDep,
+) -> SessionVariablesResponse:
+    """List the variables in the session's persisted Python state.
+
+    Each variable has its type, shape or length, dtype and columns for
+    arrays and dataframes, approximate size, and a short preview. The state
+    is loaded in a sandbox, never in the API, and is left unchanged.
+
+    Returns:
+        - 200: Variable summaries (empty when the session has no state)
+        - 404: Session not found
+        - 422: The state could not be loaded for inspection
+    """
+    await _require_session(session_id, session_service)
+    return await variable_inspector.inspect(session_id)
+
+
+@router.get("/sessions/{session_id}/dataframes/{name}", response_model=DataFrameExportResponse)
+async def export_session_dataframe(
+    session_id: str,
+    name: str,
+    session_service: SessionServiceDep,
+    variable_inspector: VariableInspectorDep,
+    rows: int = Query(50, ge=1, description="Number of rows to return"),
+    format: str = Query("json", description="json (records), csv or parquet"),
+    sample: str = Query("head", description="Which rows: head, tail or random"),
+):
+    """Export rows of a dataframe (or Series, array, records list) from the session's state.
+
+    JSON returns records with column names and dtypes; CSV and Parquet are
+    returned as files, with the full row count in X-Total-Rows.
+
+    Returns:
+        - 200: Exported rows
+        - 400: Invalid parameters, variable isn't tabular, or export too large
+        - 404: Session or variable not found
+        - 422: The state could not be loaded for export
+    """
+    if not name.isidentifier():
+        raise HTTPException(
+            status_code=400,
+            detail={"error": "invalid_name", "message": "name must be a Python identifier"},
+        )
+    if format not in DATAFRAME_FORMATS:
+        raise HTTPException(
+            status_code=400,
+            detail={"error": "invalid_format", "message": f"format must be one of: {', '.join(DATAFRAME_FORMATS)}"},
+        )
+    if sample not in DATAFRAME_SAMPLES:
+        raise HTTPException(
+            status_code=400,
+            detail={"error": "invalid_sample", "message": f"sample must be one of: {', '.join(DATAFRAME_SAMPLES)}"},
+        )
+    if rows > settings.state_export_max_rows:
+        raise HTTPException(
+            status_code=400,
+            detail={"error": "too_many_rows", "message": f"rows may be at most {settings.state_export_max_rows}"},
+        )
+
+    await _require_session(session_id, session_service)
+
+    result = await variable_inspector.export_dataframe(session_id, name, rows, format, sample)
+
+    if format == "json":
+        return DataFrameExportResponse(
+            session_id=session_id,
+            name=name,
+            total_rows=result["total_rows"],
+            rows=result["rows"],
+            columns=result["columns"],
+            dtypes=result["dtypes"],
+            data=result["data"],
+        )
+
+    return Response(
+        content=result["data"],
+        media_type=DATAFRAME_MEDIA_TYPES[format],
+        headers={
+            "Content-Disposition": f'attachment; filename="{name}.{format}"',
+            "X-Total-Rows": str(result["total_rows"]),
+        },
+    )
+
+
+@router.post("/sessions/{session_id}/complete", response_model=CompletionResponse)
+async def complete_session_code(
+    session_id: str,
+    request: CompletionRequest,
+    session_service: SessionServiceDep,
+    variable_inspector: VariableInspectorDep,
+) -> CompletionResponse:
+    """Complete Python code at the cursor against the session's variables.
+
+    Returns completion matches, signature help for the call the cursor is
+    inside, and the docstring of the name under the cursor. The code itself
+    is never executed.
+
+    Returns:
+        - 200: Completions and help
+        - 400: Cursor outside the code
+        - 404: Session not found
+        - 422: Completion failed in the sandbox
+    """
+    cursor_pos = len(request.code) if request.cursor_pos is None else request.cursor_pos
+    if cursor_pos > len(request.code):
+        raise HTTPException(
+            status_code=400,
+            detail={"error": "invalid_cursor", "message": "cursor_pos is past the end of the code"},
+        )
+
+    await _require_session(session_id, session_service)
+    return await variable_inspector.complete(session_id, request.code, cursor_pos)
+
+
+@router.put(
+    "/sessions/{session_id}/env",
+    response_model=SessionEnvResponse,
+    dependencies=[Depends(reject_quarantined_session)],
+)
+async def set_session_env(
+    session_id: str,
+    request: SessionEnvUpdate,
+    session_service: SessionServiceDep,
+) -> SessionEnvResponse:
+    """Replace the environment variables applied to every execution in the session.
+
+    Request env passed to /exec still overrides stored values for that
+    execution. An empty object clears the stored env.
+
+    Returns:
+        - 200: Stored variable names (values redacted)
+        - 400: Invalid names or limits exceeded
+        - 404: Session not found
+    """
+    invalid = SecurityValidator.invalid_env_names(request.env)
+    if invalid:
+        raise HTTPException(
+            status_code=400,
+            detail={"error": "invalid_env_name", "message": f"Invalid names: {', '.join(invalid)}"},
+        )
+
+    if len(request.env) > settings.max_session_env_vars:
+        raise HTTPException(
+            status_code=400,
+            detail={
+                "error": "too_many_env_vars",
+                "message": f"At most {settings.max_session_env_vars} variables per session",
+            },
+        )
+
+    too_long = sorted(name for name, value in request.env.items() if len(value) > settings.max_session_env_value_length)
+    if too_long:
+        raise HTTPException(
+            status_code=400,
+            detail={
+                "error": "env_value_too_long",
+                "message": f"Values longer than {settings.max_session_env_value_length} characters: "
+                f"{', '.join(too_long)}",
+            },
+        )
+
+    await _require_session(session_id, session_service)
+
+    stored = await session_service.set_session_env(session_id, request.env)
+    SecurityAudit.log_session_env_update(session_id, list(request.env), success=stored)
+    if not stored:
+        raise HTTPException(
+            status_code=404,
+            detail={"error": "session_not_found", "message": "Session not found"},
+        )
+
+    return SessionEnvResponse(session_id=session_id, env=_redact(request.env))
+
+
+@router.get("/sessions/{session_id}/env", response_model=SessionEnvResponse)
+async def get_session_env(session_id: str, session_service: SessionServiceDep) -> SessionEnvResponse:
+    """List the environment variables stored for the session.
+
+    Values are never returned, only names; re-PUT the env to change them.
+
+    Returns:
+        - 200: Stored variable names (values redacted)
+        - 404: Session not found
+    """
+    await _require_session(session_id, session_service)
+    env = await session_service.get_session_env(session_id)
+    return SessionEnvResponse(session_id=session_id, env=_redact(env))
+
+
+@router.post(
+    "/sessions/{session_id}/locks",
+    response_model=WorkspaceLockResponse,
+    dependencies=[Depends(reject_quarantined_session)],
+)
+async def lock_workspace_path(
+    session_id: str,
+    request: WorkspaceLockRequest,
+    session_service: SessionServiceDep,
+    lock_service: WorkspaceLockServiceDep,
+) -> WorkspaceLockResponse:
+    """Take an advisory lock on a workspace path.
+
+    Executions whose scope overlaps the path wait for it to be released,
+    unless they pass the returned token as lock_token. Locks expire after
+    ttl_seconds so an abandoned lock can't block the session forever.
+
+    Returns:
+        - 200: Lock acquired, with its token
+        - 400: Invalid path or TTL
+        - 404: Session not found
+        - 409: Path overlaps a lock held by someone else
+    """
+    try:
+        path = normalize_scope_path(request.path)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail={"error": "invalid_path", "message": str(e)})
+
+    if request.ttl_seconds > settings.workspace_lock_max_ttl_seconds:
+        raise HTTPException(
+            status_code=400,
+            detail={
+                "error": "ttl_too_long",
+                "message": f"ttl_seconds may be at most {settings.workspace_lock_max_ttl_seconds}",
+            },
+        )
+
+    await _require_session(session_id, session_service)
+
+    token, conflicts = await lock_service.acquire(session_id, [path], owner="client", ttl_seconds=request.ttl_seconds)
+    if not token:
+        raise HTTPException(
+            status_code=409,
+            detail={
+                "error": "path_locked",
+                "message": "Path overlaps an existing lock",
+                "locks": [_lock_info(lock).model_dump() for lock in conflicts],
+            },
+        )
+
+    logger.info("Workspace lock acquired", session_id=session_id, path=path or "/", ttl_seconds=request.ttl_seconds)
+    return WorkspaceLockResponse(
+        token=token,
+        paths=[path or "/"],
+        owner="client",
+        expires_at=datetime.now(UTC) + timedelta(seconds=request.ttl_seconds),
+    )
+
+
+@router.get("/sessions/{session_id}/locks", response_model=list[WorkspaceLockInfo])
+async def list_workspace_locks(
+    session_id: str,
+    session_service: SessionServiceDep,
+    lock_service: WorkspaceLockServiceDep,
+) -> list[WorkspaceLockInfo]:
+    """List locks currently held in the session's workspace (tokens are not shown)."""
+    await _require_session(session_id, session_service)
+    return [_lock_info(lock) for lock in await lock_service.list_locks(session_id)]
+
+
+@router.delete("/sessions/{session_id}/locks/{token}")
+async def unlock_workspace_path(session_id: str, token: str, lock_service: WorkspaceLockServiceDep):
+    """Release a workspace lock by its token.
+
+    Returns:
+        - 200: Lock released
+        - 404: No such lock (never held, already released, or expired)
+    """
+    if not await lock_service.release(session_id, token):
+        raise HTTPException(
+            status_code=404,
+            detail={"error": "lock_not_found", "message": "Lock not found or already expired"},
+        )
+    logger.info("Workspace lock released", session_id=session_id)
+    return {"released": True}
+
+
+@router.post("/sessions/{session_id}/interrupt")
+async def interrupt_session(
+    session_id: str,
+    session_service: SessionServiceDep,
+    execution_service: ExecutionServiceDep,
+):
+    """Interrupt the session's running execution, like Ctrl-C in Jupyter.
+
+    The running code receives SIGINT (KeyboardInterrupt in Python) and its
+    /exec call returns what it produced so far, with files collected as
+    usual. Unlike a timeout, the session and its workspace are untouched.
+
+    Returns:
+        - 200: Number of executions interrupted
+        - 404: Session not found
+        - 409: Nothing is running in the session
+    """
+    await _require_session(session_id, session_service)
+
+    count = await execution_service.interrupt_session(session_id)
+    if not count:
+        raise HTTPException(
+            status_code=409,
+            detail={"error": "no_running_execution", "message": "No execution is running in this session"},
+        )
+
+    return {"session_id": session_id, "interrupted": count}
+
+
+@router.post(
+    "/sessions/{session_id}/restart",
+    response_model=SessionRestartResponse,
+    dependencies=[Depends(reject_quarantined_session)],
+)
+async def restart_session(
+    session_id: str,
+    session_service: SessionServiceDep,
+    execution_service: ExecutionServiceDep,
+    state_service: StateServiceDep,
+    state_archival_service: StateArchivalServiceDep,
+    lock_service: WorkspaceLockServiceDep,
+) -> SessionRestartResponse:
+    """Restart the session's kernel, clearing in-memory state.
+
+    Any running execution is interrupted first, and the restart waits for it
+    to finish so its state can't be saved after the reset. Persisted Python
+    state (variables, imports) is then discarded; files in the workspace and
+    packages installed into it are kept.
+
+    Returns:
+        - 200: Kernel restarted, with the session's restart count
+        - 404: Session not found
+        - 409: The workspace stayed locked (code ignoring the interrupt, or a client lock)
+    """
+    await _require_session(session_id, session_service)
+
+    interrupted = await execution_service.interrupt_session(session_id)
+
+    # Taking the whole workspace waits out in-flight executions and keeps new ones from starting mid-reset
+    token, conflicts = await lock_service.acquire(
+        session_id,
+        [WORKSPACE_ROOT],
+        owner="restart",
+        ttl_seconds=settings.session_lock_wait_seconds + 60,
+        wait_seconds=settings.session_lock_wait_seconds,
+    )
+    if not token:
+        raise HTTPException(
+            status_code=409,
+            detail={
+                "error": "session_busy",
+                "message": "Session workspace is still locked; retry the restart",
+                "locks": [_lock_info(lock).model_dump() for lock in conflicts],
+            },
+        )
+
+    try:
+        await state_service.delete_state(session_id)
+        if settings.state_archive_enabled:
+            await state_archival_service.delete_archived_state(session_id)
+        restart_count = await session_service.record_restart(session_id)
+    finally:
+        await lock_service.release(session_id, token)
+
+    if restart_count is None:
+        raise HTTPException(
+            status_code=404,
+            detail={"error": "session_not_found", "message": "Session not found"},
+        )
+
+    logger.info(
+        "Kernel restarted",
+        session_id=session_id[:12],
+        restart_count=restart_count,
+        interrupted=interrupted,
+    )
+    return SessionRestartResponse(session_id=session_id, restart_count=restart_count, interrupted=interrupted)
+
+
+@router.get("/sessions/{session_id}/cells", response_model=list[CellInfo])
+async def list_session_cells(
+    session_id: str,
+    session_service: SessionServiceDep,
+    cell_history_service: CellHistoryServiceDep,
+    limit: int | None = Query(None, ge=1, description="Only the most recent cells"),
+) -> list[CellInfo]:
+    """List the cells executed in the session, oldest first.
+
+    Each cell has its code, outputs (truncated to
+    SESSION_CELL_OUTPUT_MAX_CHARS), generated files and timing.
+
+    Returns:
+        - 200: Cells (empty if history is disabled)
+        - 404: Session not found
+    """
+    await _require_session(session_id, session_service)
+    return await cell_history_service.list_cells(session_id, limit=limit)
+
+
+@router.get("/sessions/{session_id}/changes", response_model=SessionChangesResponse)
+async def list_session_changes(
+    session_id: str,
+    session_service: SessionServiceDep,
+    env_snapshot_service: EnvironmentSnapshotServiceDep,
+    limit: int | None = Query(None, ge=1, description="Only the most recent executions"),
+    changed_only: bool = Query(False, description="Leave out executions that changed nothing"),
+) -> SessionChangesResponse:
+    """What each execution changed in the session's environment, oldest first.
+
+    Every execution is compared with the one before it: environment
+    variables added, removed or given a new value (names only), packages
+    its code installed, and workspace files added, removed or replaced.
+
+    Returns:
+        - 200: Changes (empty if SESSION_CHANGE_HISTORY_LIMIT is 0)
+        - 404: Session not found
+    """
+    await _require_session(session_id, session_service)
+    changes = await env_snapshot_service.list_changes(session_id, limit=limit, changed_only=changed_only)
+    return SessionChangesResponse(session_id=session_id, changes=changes)
+
+
+@router.get("/sessions/{session_id}/cells/{cell_id}", response_model=CellInfo)
+async def get_session_cell(
+    session_id: str,
+    cell_id: int,
+    cell_history_service: CellHistoryServiceDep,
+) -> CellInfo:
+    """Get one executed cell by number.
+
+    Returns:
+        - 200: The cell
+        - 404: No such cell (never run, or trimmed from the history)
+    """
+    cell = await cell_history_service.get_cell(session_id, cell_id)
+    if not cell:
+        raise HTTPException(
+            status_code=404,
+            detail={"error": "cell_not_found", "message": f"Cell {cell_id} not found"},
+        )
+    return cell
+
+
+async def _load_cell(session_id: str, cell_id: int, session_service, cell_history_service):
+    """Return (session, cell), raising 404 if either doesn't exist."""
+    session = await session_service.get_session(session_id)
+    if not session:
+        raise HTTPException(
+            status_code=404,
+            detail={"error": "session_not_found", "message": "Session not found"},
+        )
+
+    cell = await cell_history_service.get_cell(session_id, cell_id)
+    if not cell:
+        raise HTTPException(
+            status_code=404,
+            detail={"error": "cell_not_found", "message": f"Cell {cell_id} not found"},
+        )
+    return session, cell
+
+
+def _cell_request(session, cell: CellInfo, session_id: str, code: str | None = None, args=None) -> ExecRequest:
+    """Build an /exec request that runs a cell again, optionally with other code/args, in the same catalog image."""
+    return ExecRequest(
+        code=cell.code if code is None else code,
+        lang=cell.lang,
+        args=cell.args if args is None else args,
+        files=cell.input_files,
+        scope=cell.scope,
+        image=cell.image_name,
+        session_id=session_id,
+        user_id=session.metadata.get("user_id"),
+        entity_id=session.metadata.get("entity_id"),
+    )
+
+
+@router.post("/sessions/{session_id}/cells/{cell_id}/run", response_model=ExecResponse)
+async def rerun_session_cell(
+    session_id: str,
+    cell_id: int,
+    http_request: Request,
+    session_service: SessionServiceDep,
+    file_service: FileServiceDep,
+    execution_service: ExecutionServiceDep,
+    state_service: StateServiceDep,
+    state_archival_service: StateArchivalServiceDep,
+    cell_history_service: CellHistoryServiceDep,
+    workspace_lock_service: WorkspaceLockServiceDep = None,
+    timeout_advisor: TimeoutAdvisorDep = None,
+    env_snapshot_service: EnvironmentSnapshotServiceDep = None,
+    session_report_service: SessionReportServiceDep = None,
+) -> ExecResponse:
+    """Run a cell's code again in the session, like /exec.
+
+    The cell's code, language, args, input files and scope are reused.
+    Per-request env isn't stored with cells, so only the session env
+    applies. The run is recorded as a new cell that points back to this one.
+
+    Returns:
+        - 200: Execution result (same as /exec)
+        - 404: Session or cell not found
+    """
+    session, cell = await _load_cell(session_id, cell_id, session_service, cell_history_service)
+    request = _cell_request(session, cell, session_id)
+    request_id = generate_request_id()[:8]
+    logger.info("Re-running cell", request_id=request_id, session_id=session_id[:12], cell_id=cell_id)
+
+    orchestrator = ExecutionOrchestrator(
+        session_service=session_service,
+        file_service=file_service,
+        execution_service=execution_service,
+        state_service=state_service,
+        state_archival_service=state_archival_service,
+        workspace_lock_service=workspace_lock_service,
+        cell_history_service=cell_history_service,
+        timeout_advisor=timeout_advisor,
+        env_snapshot_service=env_snapshot_service,
+        session_report_service=session_report_service,
+    )
+    return await orchestrator.execute(
+        request,
+        request_id,
+        api_key_hash=getattr(http_request.state, "api_key_hash", None),
+        is_env_key=getattr(http_request.state, "is_env_key", False),
+        rerun_of=cell_id,
+    )
+
+
+@router.post("/sessions/{session_id}/cells/{cell_id}/diff", response_model=CellDiffResponse)
+async def diff_session_cell(
+    session_id: str,
+    cell_id: int,
+    body: CellDiffRequest,
+    http_request: Request,
+    session_service: SessionServiceDep,
+    file_service: FileServiceDep,
+    execution_service: ExecutionServiceDep,
+    state_service: StateServiceDep,
+    state_archival_service: StateArchivalServiceDep,
+    cell_history_service: CellHistoryServiceDep,
+    workspace_lock_service: WorkspaceLockServiceDep = None,
+    timeout_advisor: TimeoutAdvisorDep = None,
+    env_snapshot_service: EnvironmentSnapshotServiceDep = None,
+    session_report_service: SessionReportServiceDep = None,
+) -> CellDiffResponse:
+    """Run modified code in place of a cell and diff the outputs with the cell's.
+
+    Like /cells/{n}/run but with new code (and optionally args). The
+    response has the execution result plus what changed: status, exit
+    code, duration, unified diffs of stdout/stderr, and generated files
+    added, removed or modified (by content). Outputs are compared as
+    stored in the history, i.e. up to SESSION_CELL_OUTPUT_MAX_CHARS.
+
+    Returns:
+        - 200: Execution result and diff
+        - 404: Session or cell not found
+    """
+    session, previous = await _load_cell(session_id, cell_id, session_service, cell_history_service)
+    request = _cell_request(session, previous, session_id, code=body.code, args=body.args)
+    request_id = generate_request_id()[:8]
+    logger.info("Diffing cell", request_id=request_id, session_id=session_id[:12], cell_id=cell_id)
+
+    orchestrator = ExecutionOrchestrator(
+        session_service=session_service,
+        file_service=file_service,
+        execution_service=execution_service,
+        state_service=state_service,
+        state_archival_service=state_archival_service,
+        workspace_lock_service=workspace_lock_service,
+        cell_history_service=cell_history_service,
+        timeout_advisor=timeout_advisor,
+        env_snapshot_service=env_snapshot_service,
+        session_report_service=session_report_service,
+    )
+    ctx = await orchestrator.run(
+        request,
+        request_id,
+        api_key_hash=getattr(http_request.state, "api_key_hash", None),
+        is_env_key=getattr(http_request.state, "is_env_key", False),
+        rerun_of=cell_id,
+    )
+
+    current = ctx.cell
+    if current is None:
+        # Not recorded (history write failed): compare the run as it would have been stored
+        limit = settings.session_cell_output_max_chars
+        execution = ctx.execution
+        current = CellInfo(
+            cell_id=0,
+            code=request.code,
+            lang=request.lang,
+            status=execution.status.value if execution else "failed",
+            exit_code=execution.exit_code if execution else None,
+            stdout=truncate_output(ctx.stdout, limit)[0],
+            stderr=truncate_output(ctx.stderr, limit)[0],
+            files=ctx.response.files,
+            started_at=datetime.now(UTC),
+            duration_ms=execution.execution_time_ms if execution else None,
+        )
+
+    diff = diff_cells(
+        previous,
+        current,
+        await file_digests(file_service, session_id, previous.files),
+        await file_digests(file_service, session_id, current.files),
+    )
+    return CellDiffResponse(
+        previous_cell_id=cell_id,
+        cell_id=ctx.cell.cell_id if ctx.cell else None,
+        result=ctx.response,
+        diff=diff,
+    )
+
+
+@router.get("/sessions/{session_id}/export")
+async def export_session(
+    session_id: str,
+    session_service: SessionServiceDep,
+    file_service: FileServiceDep,
+    cell_history_service: CellHistoryServiceDep,
+    format: str = Query("ipynb", description="ipynb, html or py"),
+) -> Response:
+    """Download the session's executed cells as a notebook, page or script.
+
+    ipynb and html include each cell's outputs with generated images
+    embedded; py is a script of the Python cells. Outputs are the
+    truncated copies kept in the cell history.
+
+    Returns:
+        - 200: The exported file
+        - 400: Unsupported format
+        - 404: Session not found
+    """
+    if format not in EXPORT_FORMATS:
+        raise HTTPException(
+            status_code=400,
+            detail={"error": "invalid_format", "message": f"format must be one of: {', '.join(EXPORT_FORMATS)}"},
+        )
+    await _require_session(session_id, session_service)
+
+    cells = await cell_history_service.list_cells(session_id)
+    images = await load_images(file_service, session_id, cells) if format != "py" else {}
+    content = render_export(format, cells, images, session_id)
+
+    return Response(
+        content=content,
+        media_type=EXPORT_MEDIA_TYPES[format],
+        headers={"Content-Disposition": f'attachment; filename="session-{session_id[:12]}.{format}"'},
+    )
@@ -9,10 +9,10 @@ Wire format: Raw lz4-compressed binary (not base64).
 from typing import Optional
 
 import structlog
-from fastapi import APIRouter, Header, HTTPException, Request, Response
+from fastapi import APIRouter, Depends, Header, HTTPException, Request, Response
 
 from ..config import settings
-from ..dependencies.services import StateArchivalServiceDep, StateServiceDep
+from ..dependencies.services import StateArchivalServiceDep, StateServiceDep, reject_quarantined_session
 from ..models.state import StateInfo, StateUploadResponse
 
 logger = structlog.get_logger(__name__)
@@ -88,7 +88,12 @@ async def download_state(
     )
 
 
-@router.post("/state/{session_id}", status_code=201, response_model=StateUploadResponse)
+@router.post(
+    "/state/{session_id}",
+    status_code=201,
+    response_model=StateUploadResponse,
+    dependencies=[Depends(reject_quarantined_session)],
+)
 async def upload_state(
     session_id: str,
     request: Request,
@@ -197,7 +202,7 @@ async def get_state_info(
     return StateInfo(exists=False, session_id=session_id)
 
 
-@router.delete("/state/{session_id}", status_code=204)
+@router.delete("/state/{session_id}", status_code=204, dependencies=[Depends(reject_quarantined_session)])
 async def delete_state(
     session_id: str,
     state_service: StateServiceDep,
@@ -0,0 +1,117 @@
+"""Execution template endpoints.
+
+Operators register named, parameterized code (EXECUTION_TEMPLATES_PATH);
+clients list the templates and run them by name with arguments instead of
+sending code.
+"""
+
+import structlog
+from fastapi import APIRouter, HTTPException, Request
+
+from ..dependencies.services import (
+    CellHistoryServiceDep,
+    EnvironmentSnapshotServiceDep,
+    ExecutionServiceDep,
+    FileServiceDep,
+    SessionReportServiceDep,
+    SessionServiceDep,
+    StateArchivalServiceDep,
+    StateServiceDep,
+    TimeoutAdvisorDep,
+    WorkspaceLockServiceDep,
+)
+from ..models import ExecRequest, ExecResponse
+from ..models.template import ExecutionTemplate, TemplateListResponse, TemplateRunRequest
+from ..services.orchestrator import ExecutionOrchestrator
+from ..services.templates import TemplateError, get_templates, render_template
+from ..utils.id_generator import generate_request_id
+
+logger = structlog.get_logger(__name__)
+router = APIRouter()
+
+
+def _get_template(name: str) -> ExecutionTemplate:
+    template = get_templates().get(name)
+    if not template:
+        raise HTTPException(
+            status_code=404,
+            detail={"error": "template_not_found", "message": f"No execution template named {name}"},
+        )
+    return template
+
+
+@router.get("/templates", response_model=TemplateListResponse)
+async def list_templates():
+    """List the registered execution templates and their parameters."""
+    return TemplateListResponse(templates=list(get_templates().values()))
+
+
+@router.get("/templates/{name}", response_model=ExecutionTemplate)
+async def get_template(name: str):
+    """Get one execution template."""
+    return _get_template(name)
+
+
+@router.post("/templates/{name}/run", response_model=ExecResponse)
+async def run_template(
+    name: str,
+    body: TemplateRunRequest,
+    http_request: Request,
+    session_service: SessionServiceDep,
+    file_service: FileServiceDep,
+    execution_service: ExecutionServiceDep,
+    state_service: StateServiceDep,
+    state_archival_service: StateArchivalServiceDep,
+    workspace_lock_service: WorkspaceLockServiceDep = None,
+    cell_history_service: CellHistoryServiceDep = None,
+    timeout_advisor: TimeoutAdvisorDep = None,
+    env_snapshot_service: EnvironmentSnapshotServiceDep = None,
+    session_report_service: SessionReportServiceDep = None,
+):
+    """Run a template with the given arguments.
+
+    Arguments are validated against the template's parameters and expanded
+    as literals of the template's language (and PARAM_* environment
+    variables), then the code runs like an /exec request.
+    """
+    template = _get_template(name)
+    try:
+        code, env = render_template(template, body.params)
+    except TemplateError as e:
+        raise HTTPException(status_code=400, detail={"error": "invalid_template_params", "message": str(e)})
+
+    request_id = generate_request_id()[:8]
+    api_key_hash = getattr(http_request.state, "api_key_hash", None)
+    is_env_key = getattr(http_request.state, "is_env_key", False)
+
+    logger.info(
+        "Template execution request",
+        request_id=request_id,
+        template=name,
+        language=template.lang,
+        entity_id=body.entity_id,
+        api_key_hash=api_key_hash[:8] if api_key_hash else "unknown",
+    )
+
+    orchestrator = ExecutionOrchestrator(
+        session_service=session_service,
+        file_service=file_service,
+        execution_service=execution_service,
+        state_service=state_service,
+        state_archival_service=state_archival_service,
+        workspace_lock_service=workspace_lock_service,
+        cell_history_service=cell_history_service,
+        timeout_advisor=timeout_advisor,
+        env_snapshot_service=env_snapshot_service,
+        session_report_service=session_report_service,
+    )
+    request = ExecRequest(
+        code=code,
+        lang=template.lang,
+        env=env,
+        session_id=body.session_id,
+        files=body.files,
+        entity_id=body.entity_id,
+        user_id=body.user_id,
+    )
+    return await orchestrator.execute(request, request_id, api_key_hash=api_key_hash, is_env_key=is_env_key)
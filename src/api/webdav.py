@@ -0,0 +1,161 @@
+"""WebDAV access to session workspaces (WEBDAV_ENABLED).
+
+Clients authenticate like any other request; file managers that only speak
+HTTP Basic auth send the API key as the password.
+"""
+
+import mimetypes
+
+import structlog
+from fastapi import APIRouter, Depends, HTTPException, Request
+from fastapi.responses import Response
+
+from ..config import settings
+from ..dependencies import FileServiceDep, SessionServiceDep, reject_quarantined_session
+from ..services.execution.output import OutputProcessor
+from ..services.webdav import (
+    ALLOWED_METHODS,
+    DAV_CLASS,
+    XML_MEDIA_TYPE,
+    find_file,
+    multistatus,
+    parse_destination,
+    resource_href,
+)
+
+logger = structlog.get_logger(__name__)
+router = APIRouter()
+
+
+async def _require_session(session_id: str, session_service) -> None:
+    if not await session_service.get_session(session_id):
+        raise HTTPException(status_code=404, detail={"error": "session_not_found", "message": "Session not found"})
+
+
+async def _require_file(session_id: str, name: str, file_service):
+    file_info = find_file(await file_service.list_files(session_id), name)
+    if not file_info:
+        raise HTTPException(status_code=404, detail="File not found")
+    return file_info
+
+
+@router.options("/{session_id}/")
+@router.options("/{session_id}/{name}")
+async def dav_options(session_id: str, name: str | None = None):
+    """Advertise WebDAV support."""
+    return Response(status_code=200, headers={"DAV": DAV_CLASS, "Allow": ALLOWED_METHODS, "MS-Author-Via": "DAV"})
+
+
+@router.api_route("/{session_id}/", methods=["PROPFIND"])
+async def propfind_collection(
+    session_id: str,
+    request: Request,
+    file_service: FileServiceDep = None,
+    session_service: SessionServiceDep = None,
+):
+    """List the session's files (Depth 1) or describe the collection itself (Depth 0)."""
+    await _require_session(session_id, session_service)
+    files = [] if request.headers.get("depth") == "0" else await file_service.list_files(session_id)
+    return Response(content=multistatus(session_id, files), status_code=207, media_type=XML_MEDIA_TYPE)
+
+
+@router.api_route("/{session_id}/{name}", methods=["PROPFIND"])
+async def propfind_file(session_id: str, name: str, file_service: FileServiceDep = None):
+    """Describe one file."""
+    file_info = await _require_file(session_id, name, file_service)
+    body = multistatus(session_id, [file_info], include_collection=False)
+    return Response(content=body, status_code=207, media_type=XML_MEDIA_TYPE)
+
+
+@router.api_route("/{session_id}/{name}", methods=["GET", "HEAD"])
+async def get_file(session_id: str, name: str, request: Request, file_service: FileServiceDep = None):
+    """Download a file."""
+    file_info = await _require_file(session_id, name, file_service)
+    headers = {"ETag": f'"{file_info.file_id}"'}
+    media_type = file_info.content_type or mimetypes.guess_type(name)[0] or "application/octet-stream"
+    if request.method == "HEAD":
+        headers["Content-Length"] = str(file_info.size)
+        return Response(status_code=200, media_type=media_type, headers=headers)
+
+    content = await file_service.get_file_content(session_id, file_info.file_id)
+    if content is None:
+        raise HTTPException(status_code=404, detail="File content not found")
+    return Response(content=content, media_type=media_type, headers=headers)
+
+
+@router.put("/{session_id}/{name}", dependencies=[Depends(reject_quarantined_session)])
+async def put_file(
+    session_id: str,
+    name: str,
+    request: Request,
+    file_service: FileServiceDep = None,
+    session_service: SessionServiceDep = None,
+):
+    """Create or replace a file.
+
+    The file is stored under its workspace (sanitized) name, which the
+    Location header reports.
+    """
+    await _require_session(session_id, session_service)
+    content = await request.body()
+    if len(content) > settings.max_file_size_mb * 1024 * 1024:
+        raise HTTPException(status_code=413, detail=f"File exceeds {settings.max_file_size_mb}MB")
+
+    stored_name = OutputProcessor.sanitize_filename(name)
+    files = await file_service.list_files(session_id)
+    existing = find_file(files, stored_name)
+    if not existing and len(files) >= settings.max_files_per_session:
+        raise HTTPException(status_code=507, detail=f"Session already has {settings.max_files_per_session} files")
+
+    content_type = request.headers.get("content-type") or mimetypes.guess_type(stored_name)[0]
+    await file_service.store_uploaded_file(session_id, stored_name, content, content_type)
+    if existing:
+        await file_service.delete_file(session_id, existing.file_id)
+
+    logger.info("WebDAV upload", session_id=session_id, filename=stored_name, size=len(content))
+    return Response(
+        status_code=204 if existing else 201,
+        headers={"Location": resource_href(session_id, stored_name)},
+    )
+
+
+@router.delete("/{session_id}/{name}", dependencies=[Depends(reject_quarantined_session)])
+async def delete_file(session_id: str, name: str, file_service: FileServiceDep = None):
+    """Delete a file."""
+    file_info = await _require_file(session_id, name, file_service)
+    if not await file_service.delete_file(session_id, file_info.file_id):
+        raise HTTPException(status_code=500, detail="Failed to delete file")
+    return Response(status_code=204)
+
+
+@router.api_route("/{session_id}/{name}", methods=["COPY", "MOVE"], dependencies=[Depends(reject_quarantined_session)])
+async def copy_or_move_file(session_id: str, name: str, request: Request, file_service: FileServiceDep = None):
+    """Copy or rename a file within the session."""
+    destination = parse_destination(request.headers.get("destination"), session_id)
+    if not destination:
+        raise HTTPException(status_code=403, detail="Destination must be a file in the same session")
+    destination = OutputProcessor.sanitize_filename(destination)
+
+    files = await file_service.list_files(session_id)
+    source = find_file(files, name)
+    if not source:
+        raise HTTPException(status_code=404, detail="File not found")
+    if destination == name:
+        raise HTTPException(status_code=403, detail="Source and destination are the same")
+    existing = find_file(files, destination)
+    if existing and request.headers.get("overwrite", "T").upper() == "F":
+        raise HTTPException(status_code=412, detail="Destination exists")
+
+    content = await file_service.get_file_content(session_id, source.file_id)
+    if content is None:
+        raise HTTPException(status_code=404, detail="File content not found")
+    await file_service.store_uploaded_file(session_id, destination, content, source.content_type)
+    if existing:
+        await file_service.delete_file(session_id, existing.file_id)
+    if request.method == "MOVE":
+        await file_service.delete_file(session_id, source.file_id)
+
+    return Response(
+        status_code=204 if existing else 201,
+        headers={"Location": resource_href(session_id, destination)},
+    )
@@ -18,10 +18,11 @@ Usage:
     settings.get_redis_url()
 """
 
+import re
 from pathlib import Path
 from typing import Any, Dict, List, Literal, Optional
 
-from pydantic import Field, field_validator, model_validator
+from pydantic import BaseModel, Field, field_validator, model_validator
 from pydantic_settings import BaseSettings, SettingsConfigDict
 
 # Import grouped configurations
@@ -41,10 +42,59 @@ from .languages import (
 )
 from .logging import LoggingConfig
 from .minio import MinIOConfig
+from .policy import PolicyBundle, PolicyBundleError, load_bundle
 from .redis import RedisConfig
 from .resources import ResourcesConfig
 from .security import SecurityConfig
 
+# Filters implemented by src/services/output_filters.py
+OUTPUT_FILTER_NAMES = ("secrets", "pii", "profanity", "collapse", "max_lines")
+
+# Faults and options understood by the sidecar's FAULT_INJECTION (docker/sidecar/executor/faults.py)
+FAULT_NAMES = ("spawn_failure", "delay", "truncate", "drop")
+FAULT_OPTIONS = ("delay_ms", "seed")
+
+# Same rule as SecurityValidator.ENV_NAME_PATTERN (importing it here would be circular)
+ENV_NAME_PATTERN = re.compile(r"^[A-Za-z_][A-Za-z0-9_]*$")
+
+DATASET_NAME_PATTERN = re.compile(r"^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$")
+DATASET_DIGEST_PATTERN = re.compile(r"^sha256:[0-9a-f]{64}$")
+
+# Catalog image names, e.g. python:3.11-datasci
+CATALOG_IMAGE_NAME_PATTERN = re.compile(r"^[a-z0-9][a-z0-9._-]{0,62}:[A-Za-z0-9][A-Za-z0-9._-]{0,62}$")
+
+
+class DatasetDefinition(BaseModel):
+    """A shared read-only dataset, identified by the digest of its contents."""
+
+    digest: str = Field(..., description="sha256:<hex> of the dataset; its directory on the node is sha256/<hex>")
+    description: str | None = None
+    size_bytes: int | None = Field(default=None, ge=0)
+
+    @field_validator("digest")
+    @classmethod
+    def validate_digest(cls, v):
+        v = v.lower()
+        if not DATASET_DIGEST_PATTERN.match(v):
+            raise ValueError(f"Dataset digest must be sha256:<64 hex characters>, got {v!r}")
+        return v
+
+
+class CatalogImage(BaseModel):
+    """An image executions may ask for by name (ExecRequest.image)."""
+
+    language: str = Field(..., description="Language code the image runs, e.g. py")
+    image: str = Field(..., min_length=1, description="Image reference; pin it by digest (image@sha256:...)")
+    description: str | None = None
+
+    @field_validator("language")
+    @classmethod
+    def validate_language(cls, v):
+        v = v.lower()
+        if v not in LANGUAGES:
+            raise ValueError(f"Unknown language {v!r} (available: {', '.join(LANGUAGES)})")
+        return v
+
 
 class Settings(BaseSettings):
     """Application settings with environment variable support.
@@ -86,6 +136,18 @@ class Settings(BaseSettings):
         description="Master API key for admin operations (CLI key management)",
     )
     rate_limit_enabled: bool = Field(default=True, description="Enable per-key rate limiting for Redis-managed keys")
+    config_refresh_interval_seconds: int = Field(
+        default=30,
+        ge=0,
+        le=3600,
+        description="How often replicas reload policy changes made through /admin/config (0 only loads at startup)",
+    )
+    quarantine_poll_interval_seconds: int = Field(
+        default=5,
+        ge=0,
+        le=3600,
+        description="How often replicas recheck for quarantined sessions missed while disconnected (0 disables it)",
+    )
 
     # Redis Configuration
     redis_host: str = Field(default="localhost")
@@ -136,6 +198,20 @@ class Settings(BaseSettings):
         default="RuntimeDefault",
         description="Seccomp profile type for execution pods",
     )
+    k8s_read_only_root_filesystem: bool = Field(
+        default=False,
+        description="Read-only root filesystems in execution pods; temp files go to an emptyDir at /tmp",
+    )
+    workspace_storage_driver: Literal["local", "tmpfs", "object"] = Field(
+        default="local",
+        description="Volume execution pods' working directory is on: local (node disk), tmpfs (memory) or object "
+        "(FUSE-mounted object storage through WORKSPACE_STORAGE_CLASS)",
+    )
+    workspace_size_limit: str = Field(default="1Gi", description="Size of execution pods' working directory volume")
+    workspace_storage_class: str | None = Field(
+        default=None,
+        description="Storage class of a FUSE object storage CSI driver, for WORKSPACE_STORAGE_DRIVER=object",
+    )
     k8s_job_ttl_seconds: int = Field(
         default=60,
         ge=10,
@@ -175,6 +251,42 @@ class Settings(BaseSettings):
         description="Per-container process limit (cgroup pids_limit). Prevents fork bombs.",
     )
     max_open_files: int = Field(default=1024, ge=64, le=4096)
+    max_connections_per_execution: int | None = Field(
+        default=None,
+        ge=1,
+        description="Outbound TCP connections an execution may have open at once (unlimited if unset)",
+    )
+
+    # Elevated Executions - operator-approved grants of more than the limits above
+    elevated_grant_secret: str | None = Field(
+        default=None,
+        min_length=32,
+        description="Key signing elevated execution grants (POST /admin/elevated-grants); unset disables them",
+    )
+    elevated_grant_max_minutes: int = Field(
+        default=60, ge=1, le=1440, description="Longest lifetime of an elevated execution grant"
+    )
+    elevated_max_execution_time: int = Field(
+        default=1800, ge=1, le=86400, description="Highest timeout (seconds) a grant may allow"
+    )
+    elevated_max_memory_mb: int = Field(
+        default=4096, ge=64, le=65536, description="Highest pod memory limit a grant may allow"
+    )
+    elevated_allow_network: bool = Field(
+        default=False,
+        description="Whether grants may allow network access (needs the elevated NetworkPolicy in the Helm chart)",
+    )
+
+    # Execution Hooks - shell scripts the sidecar runs around every execution
+    exec_pre_hook: str | None = Field(
+        default=None,
+        description="Script run before each execution; if it fails the code doesn't run",
+    )
+    exec_post_hook: str | None = Field(
+        default=None,
+        description="Script run after each execution, with the code's exit status in EXEC_EXIT_CODE",
+    )
+    exec_hook_timeout_seconds: int = Field(default=10, ge=1, le=300, description="Time budget of each hook")
 
     # Resource Limits - Files
     max_file_size_mb: int = Field(default=10, ge=1, le=500)
@@ -182,16 +294,116 @@ class Settings(BaseSettings):
     max_files_per_session: int = Field(default=50, ge=1, le=500)
     max_output_files: int = Field(default=10, ge=1, le=100)
     max_filename_length: int = Field(default=255, ge=1, le=255)
+    archive_max_entries: int = Field(
+        default=10000, ge=1, le=1000000, description="Most entries (files, directories, links) an archive may list"
+    )
+    archive_max_compression_ratio: int = Field(
+        default=100, ge=1, le=10000, description="Largest extracted-to-archive size ratio (decompression bombs)"
+    )
 
     # Resource Limits - Sessions
     max_concurrent_executions: int = Field(default=10, ge=1, le=50)
     max_sessions_per_entity: int = Field(default=100, ge=1, le=1000)
+    max_dag_steps: int = Field(default=25, ge=1, le=200, description="Maximum steps in one /dag request")
+    dag_max_parallel_steps: int = Field(
+        default=4,
+        ge=1,
+        le=50,
+        description="Maximum steps of one /dag request running at the same time",
+    )
+    queue_wait_history_size: int = Field(
+        default=100, ge=1, le=10000, description="Execution slot waits kept per session for fairness statistics"
+    )
+    queue_wait_alert_p95_ms: int = Field(
+        default=10000,
+        ge=0,
+        description="Log a session as starved when its p95 wait for an execution slot is above this (0 disables)",
+    )
+    queue_wait_alert_min_samples: int = Field(
+        default=5, ge=1, description="Slot waits a session needs before it can be logged as starved"
+    )
 
     # Session Configuration
     session_ttl_hours: int = Field(default=24, ge=1, le=168)
     session_cleanup_interval_minutes: int = Field(default=10, ge=1, le=1440)
     session_id_length: int = Field(default=32, ge=16, le=64)
     enable_orphan_minio_cleanup: bool = Field(default=False)
+    max_session_env_vars: int = Field(
+        default=64,
+        ge=1,
+        le=1000,
+        description="Maximum environment variables stored per session",
+    )
+    max_session_env_value_length: int = Field(
+        default=8192,
+        ge=1,
+        le=65536,
+        description="Maximum length of a stored session environment variable value",
+    )
+    session_lock_wait_seconds: int = Field(
+        default=30,
+        ge=0,
+        le=600,
+        description="How long an execution waits for overlapping executions or workspace locks in its session",
+    )
+    workspace_lock_max_ttl_seconds: int = Field(
+        default=3600,
+        ge=1,
+        le=86400,
+        description="Maximum lifetime of a client-held workspace lock",
+    )
+    session_cell_history_limit: int = Field(
+        default=200,
+        ge=0,
+        le=10000,
+        description="Executed cells kept per session for GET /sessions/{id}/cells (0 disables history)",
+    )
+    session_cell_output_max_chars: int = Field(
+        default=10000,
+        ge=0,
+        le=1000000,
+        description="Maximum stdout/stderr characters stored per cell",
+    )
+    session_change_history_limit: int = Field(
+        default=100,
+        ge=0,
+        le=10000,
+        description="Environment changes (GET /sessions/{id}/changes) kept per session (0 disables)",
+    )
+    session_report_enabled: bool = Field(
+        default=True,
+        description="Report executions, resources, artifacts and policy violations when a session is terminated",
+    )
+    session_report_retention_hours: int = Field(
+        default=24,
+        ge=1,
+        le=720,
+        description="How long GET /sessions/{id}/report returns a terminated session's report",
+    )
+    session_report_max_violations: int = Field(
+        default=100,
+        ge=1,
+        le=10000,
+        description="Policy violations kept per session report (the most recent)",
+    )
+    session_report_archive: bool = Field(
+        default=False,
+        description="Also archive end-of-session reports to object storage as reports/<session_id>.json",
+    )
+    timeout_history_size: int = Field(
+        default=100,
+        ge=0,
+        le=10000,
+        description="Durations kept per code signature and per session for timeout suggestions (0 disables)",
+    )
+    timeout_history_ttl_hours: int = Field(default=168, ge=1, description="Expiry of a code signature's durations")
+    timeout_suggestion_min_samples: int = Field(
+        default=5, ge=1, description="Durations needed before a timeout is suggested"
+    )
+    timeout_suggestion_percentile: float = Field(default=95.0, gt=0, le=100)
+    timeout_suggestion_multiplier: float = Field(
+        default=1.5, ge=1.0, le=10.0, description="Headroom over the percentile duration"
+    )
 
     # Pod Configuration
     pod_ttl_minutes: int = Field(default=5, ge=1, le=1440)
@@ -246,6 +458,22 @@ class Settings(BaseSettings):
         description="Public DNS servers for WAN-access pods",
     )
 
+    # DNS Policy - execution pods resolve through the sidecar, which only answers allowlisted names
+    dns_policy_upstream: str | None = Field(
+        default=None,
+        description="DNS server (IP) the sidecar forwards allowed lookups to; setting it enables DNS allowlists",
+    )
+    dns_allowlist: list[str] = Field(
+        default_factory=list,
+        description="Names every execution may resolve (*.example.com for subdomains); empty allows all",
+    )
+
+    # Fault Injection - for resilience testing in staging, never in production
+    fault_injection: str | None = Field(
+        default=None,
+        description="Faults execution pods inject, e.g. spawn_failure=0.1,delay=0.2,truncate=0.05,drop=0.05",
+    )
+
     # Pod Hardening Configuration
     pod_mask_host_info: bool = Field(
         default=True,
@@ -270,6 +498,20 @@ class Settings(BaseSettings):
     state_capture_on_error: bool = Field(
         default=False, description="Capture and persist state even when execution fails"
     )
+    state_inspect_max_variables: int = Field(
+        default=200, ge=1, le=2000, description="Maximum variables listed by GET /sessions/{id}/variables"
+    )
+    state_inspect_preview_chars: int = Field(
+        default=200, ge=0, le=4000, description="Maximum characters in each variable's value preview"
+    )
+    state_export_max_rows: int = Field(
+        default=10000, ge=1, le=1000000, description="Maximum rows per GET /sessions/{id}/dataframes/{name}"
+    )
+    state_export_max_bytes: int = Field(
+        default=900000,
+        ge=1024,
+        description="Maximum encoded size of a dataframe export (keep below the sidecar's MAX_OUTPUT_SIZE)",
+    )
 
     # State Archival Configuration - Hybrid Redis + MinIO storage
     state_archive_enabled: bool = Field(
@@ -379,9 +621,132 @@ class Settings(BaseSettings):
     blocked_file_patterns: list[str] = Field(default_factory=lambda: ["*.exe", "*.dll", "*.so", "*.dylib", "*.bin"])
     enable_network_isolation: bool = Field(default=True)
     enable_filesystem_isolation: bool = Field(default=True)
+    sandbox_report: bool = Field(
+        default=False,
+        description="Return the protections each execution actually ran under (seccomp, cgroup limits, network) "
+        "in /exec responses",
+    )
+    sidecar_strict_mode: bool = Field(
+        default=False,
+        description="Sidecars validate requests strictly, reject unknown fields, normalize paths and log "
+        "refused requests as security events",
+    )
+
+    # Output Filters (applied to stdout/stderr before output leaves the API)
+    output_filters: list[str] = Field(
+        default_factory=list,
+        description="Output filters to apply, in order: secrets, pii, profanity, collapse, max_lines",
+    )
+    output_redact_patterns: list[str] = Field(
+        default_factory=list,
+        description="Extra regular expressions redacted by the secrets filter",
+    )
+    output_redaction_text: str = Field(default="[REDACTED]", description="Replacement for redacted text")
+    output_profanity_words: list[str] = Field(
+        default_factory=list,
+        description="Words masked by the profanity filter (case-insensitive, whole words)",
+    )
+    output_max_lines: int = Field(
+        default=1000,
+        ge=1,
+        description="Lines kept per stream by the max_lines filter",
+    )
+    output_collapse_min_repeats: int = Field(
+        default=3,
+        ge=2,
+        description="Identical consecutive lines the collapse filter replaces with one and a repeat count",
+    )
+    output_collapse_full_log: bool = Field(
+        default=True,
+        description="Keep the full stream as a generated file (stdout.full.log) when collapse shortened it",
+    )
+    artifact_secret_scan: Literal["off", "flag", "block"] = Field(
+        default="flag",
+        description="Scan output and generated files for credentials: off, flag (report), "
+        "or block (redact output, don't store files)",
+    )
+    artifact_secret_scan_max_bytes: int = Field(
+        default=10 * 1024 * 1024,
+        ge=0,
+        description="Generated files larger than this are not scanned",
+    )
+
+    # Execution Context (what every execution is told about this deployment)
+    context_env: dict[str, str] = Field(
+        default_factory=dict,
+        description="Environment variables provided to every execution, e.g. PLATFORM_DOCS_URL",
+    )
+    context_banner: str | None = Field(
+        default=None,
+        description="Free-text description of this deployment returned by GET /context",
+    )
+    context_mounts: dict[str, str] = Field(
+        default_factory=dict,
+        description="Paths available to executions and what they contain, returned by GET /context",
+    )
+
+    # Shared Datasets (read-only, content-addressed, mounted at /mnt/datasets/<name>)
+    datasets: dict[str, DatasetDefinition] = Field(
+        default_factory=dict,
+        description='Datasets by name: {"sales": {"digest": "sha256:<hex>", "description": "..."}}',
+    )
+    datasets_host_path: str = Field(
+        default="/var/lib/kubecoderun/datasets",
+        description="Directory on each node holding datasets as sha256/<hex>",
+    )
+
+    # Compile Caches (ccache and the Go build cache, shared read-write by the pods on a node at /mnt/cache)
+    compile_cache_enabled: bool = Field(
+        default=False, description="Mount a node directory of compiler caches into C, C++, Fortran and Go executions"
+    )
+    compile_cache_host_path: str = Field(
+        default="/var/lib/kubecoderun/compile-cache",
+        description="Directory on each node holding the caches; must exist and be writable by K8S_RUN_AS_USER",
+    )
+    compile_cache_max_size_mb: int = Field(
+        default=2048, ge=64, description="Size the sidecars keep the cache under, evicting least recently used files"
+    )
+
+    # Image Catalog (images executions can ask for by name; which API keys may use them is set at runtime)
+    image_catalog: dict[str, CatalogImage] = Field(
+        default_factory=dict,
+        description='Images by name: {"python:3.11-datasci": {"language": "py", "image": "registry/...@sha256:..."}}',
+    )
+
+    # Execution Templates (named, parameterized code run via /templates/{name}/run)
+    execution_templates_path: str | None = Field(
+        default=None,
+        description="JSON file of execution templates: {name: {lang, code, params}}",
+    )
+
+    # WebDAV Workspace Access (session files at /dav/{session_id}/)
+    webdav_enabled: bool = Field(default=False, description="Serve session workspaces over WebDAV")
+
+    # Policy Bundle - the security-relevant settings in one versioned file (see policy.py)
+    policy_bundle_path: str | None = Field(
+        default=None,
+        description="JSON policy bundle whose limits, allowlists, network and redaction rules replace the settings",
+    )
+    policy_bundle_version: str | None = Field(
+        default=None,
+        description="Version the policy bundle must have; any other version refuses to load",
+    )
 
     # Language Configuration - now uses LANGUAGES from languages.py
     supported_languages: dict[str, dict[str, Any]] = Field(default_factory=dict)
+    allowed_languages: list[str] = Field(
+        default_factory=list,
+        description="Languages executions may use (codes from LANGUAGES); empty allows all",
+    )
+
+    @model_validator(mode="before")
+    @classmethod
+    def _apply_policy_bundle(cls, data):
+        """Replace the settings the policy bundle sets, so they are validated like any other value."""
+        if isinstance(data, dict) and data.get("policy_bundle_path"):
+            bundle = load_bundle(data["policy_bundle_path"], data.get("policy_bundle_version") or None)
+            data.update(bundle.settings())
+        return data
 
     @model_validator(mode="before")
     @classmethod
@@ -407,12 +772,37 @@ class Settings(BaseSettings):
             }
         return data
 
+    # Error Localization
+    error_default_locale: str = Field(
+        default="en",
+        description="Locale for error messages when Accept-Language names none in the catalog",
+    )
+    error_catalog_path: str | None = Field(
+        default=None,
+        description="JSON file of extra/overriding error messages: {locale: {code: message}}",
+    )
+
     # Logging Configuration
     log_level: str = Field(default="INFO")
     log_format: str = Field(default="json")
     log_file: str | None = Field(default=None)
     log_max_size_mb: int = Field(default=100, ge=1)
     log_backup_count: int = Field(default=5, ge=1)
+    log_rotation: Literal["size", "time"] = Field(
+        default="size",
+        description="Rotate log files at LOG_MAX_SIZE_MB, or on the LOG_ROTATION_WHEN schedule",
+    )
+    log_rotation_when: Literal["S", "M", "H", "D", "midnight", "W0", "W1", "W2", "W3", "W4", "W5", "W6"] = Field(
+        default="midnight",
+        description="Schedule of time-based rotation (UTC); W0-W6 is weekly from Monday to Sunday",
+    )
+    log_retention_days: int | None = Field(
+        default=None, ge=1, description="Delete rotated log files older than this (kept by count only if unset)"
+    )
+    audit_log_file: str | None = Field(
+        default=None,
+        description="Separate file for security/audit events, rotated like LOG_FILE",
+    )
     enable_access_logs: bool = Field(default=True)
     enable_security_logs: bool = Field(default=True)
 
@@ -429,12 +819,97 @@ class Settings(BaseSettings):
     # VALIDATORS (preserved from original)
     # ========================================================================
 
+    @model_validator(mode="after")
+    def _check_workspace_storage(self):
+        """The object storage driver can't provision volumes without a storage class."""
+        if self.workspace_storage_driver == "object" and not self.workspace_storage_class:
+            raise ValueError("WORKSPACE_STORAGE_DRIVER=object needs WORKSPACE_STORAGE_CLASS")
+        return self
+
     @field_validator("api_keys", mode="before")
     @classmethod
     def parse_api_keys(cls, v):
         """Parse comma-separated API keys into a list."""
         return [key.strip() for key in v.split(",") if key.strip()] if v else None
 
+    @field_validator("output_filters")
+    @classmethod
+    def validate_output_filters(cls, v):
+        """Reject unknown output filter names."""
+        unknown = [name for name in v if name not in OUTPUT_FILTER_NAMES]
+        if unknown:
+            available = ", ".join(OUTPUT_FILTER_NAMES)
+            raise ValueError(f"Unknown output filters: {', '.join(unknown)} (available: {available})")
+        return v
+
+    @field_validator("output_redact_patterns")
+    @classmethod
+    def validate_output_redact_patterns(cls, v):
+        """Ensure redaction patterns are valid regular expressions."""
+        for pattern in v:
+            try:
+                re.compile(pattern)
+            except re.error as e:
+                raise ValueError(f"Invalid output redaction pattern {pattern!r}: {e}")
+        return v
+
+    @field_validator("context_env")
+    @classmethod
+    def validate_context_env(cls, v):
+        """Ensure context variables have valid environment variable names."""
+        invalid = sorted(name for name in v if not ENV_NAME_PATTERN.match(name))
+        if invalid:
+            raise ValueError(f"Invalid context environment variable names: {', '.join(invalid)}")
+        return v
+
+    @field_validator("datasets")
+    @classmethod
+    def validate_dataset_names(cls, v):
+        """Dataset names become directory names under /mnt/datasets."""
+        invalid = sorted(name for name in v if not DATASET_NAME_PATTERN.match(name) or name.strip(".") == "")
+        if invalid:
+            raise ValueError(f"Invalid dataset names: {', '.join(invalid)}")
+        return v
+
+    @field_validator("image_catalog")
+    @classmethod
+    def validate_image_catalog_names(cls, v):
+        """Catalog names are <runtime>:<version>, e.g. python:3.11-datasci."""
+        invalid = sorted(name for name in v if not CATALOG_IMAGE_NAME_PATTERN.match(name))
+        if invalid:
+            raise ValueError(f"Invalid image catalog names (expected <runtime>:<version>): {', '.join(invalid)}")
+        return v
+
+    @field_validator("allowed_languages")
+    @classmethod
+    def validate_allowed_languages(cls, v):
+        """Reject languages that don't exist, so a typo doesn't lock a language out unnoticed."""
+        v = [code.lower() for code in v]
+        unknown = [code for code in v if code not in LANGUAGES]
+        if unknown:
+            raise ValueError(f"Unknown languages: {', '.join(unknown)} (available: {', '.join(LANGUAGES)})")
+        return v
+
+    @field_validator("fault_injection")
+    @classmethod
+    def validate_fault_injection(cls, v):
+        """Check the spec here, as the sidecar won't start with an invalid one."""
+        if not v:
+            return None
+        for item in filter(None, (part.strip() for part in v.split(","))):
+            name, _, value = (part.strip() for part in item.partition("="))
+            try:
+                if name in FAULT_OPTIONS:
+                    int(value)
+                elif name not in FAULT_NAMES or not 0 <= float(value) <= 1:
+                    raise ValueError
+            except ValueError:
+                raise ValueError(
+                    f"Invalid fault {item!r}: expected <fault>=<probability 0-1> for {', '.join(FAULT_NAMES)}, "
+                    f"or <option>=<integer> for {', '.join(FAULT_OPTIONS)}"
+                )
+        return v
+
     @field_validator("minio_endpoint")
     @classmethod
     def validate_minio_endpoint(cls, v):
@@ -540,6 +1015,10 @@ class Settings(BaseSettings):
             log_file=self.log_file,
             log_max_size_mb=self.log_max_size_mb,
             log_backup_count=self.log_backup_count,
+            log_rotation=self.log_rotation,
+            log_rotation_when=self.log_rotation_when,
+            log_retention_days=self.log_retention_days,
+            audit_log_file=self.audit_log_file,
             enable_access_logs=self.enable_access_logs,
             health_check_interval=self.health_check_interval,
             health_check_timeout=self.health_check_timeout,
@@ -563,6 +1042,7 @@ class Settings(BaseSettings):
             memory_request=self.k8s_memory_request,
             run_as_user=self.k8s_run_as_user,
             seccomp_profile_type=self.k8s_seccomp_profile_type,
+            read_only_root_filesystem=self.k8s_read_only_root_filesystem,
             job_ttl_seconds_after_finished=self.k8s_job_ttl_seconds,
             job_active_deadline_seconds=self.k8s_job_deadline_seconds,
             image_registry=self.k8s_image_registry,
@@ -624,12 +1104,54 @@ class Settings(BaseSettings):
                     sidecar_memory_request=sidecar_memory_request,
                     image_pull_policy=self.k8s_image_pull_policy,
                     seccomp_profile_type=self.k8s_seccomp_profile_type,
+                    read_only_root_filesystem=self.k8s_read_only_root_filesystem,
                     network_isolated=self.enable_network_isolation,
+                    datasets=self.get_dataset_mounts(),
+                    compile_cache=self.get_compile_cache(),
+                    dns_policy=self.get_dns_policy(),
+                    fault_injection=self.fault_injection,
+                    policy_bundle=self.get_policy_bundle_json(),
+                    strict_mode=self.sidecar_strict_mode,
+                    workspace_storage=self.get_workspace_storage(),
                 )
             )
 
         return configs
 
+    def get_dataset_mounts(self):
+        """Read-only mounts for the configured datasets."""
+        from ..services.kubernetes.models import DatasetMount
+
+        root = self.datasets_host_path.rstrip("/")
+        return [
+            DatasetMount(name=name, host_path=f"{root}/{dataset.digest.replace(':', '/', 1)}")
+            for name, dataset in sorted(self.datasets.items())
+        ]
+
+    def get_compile_cache(self):
+        """The node's compiler cache directory, or None when compile caches are off."""
+        from ..services.kubernetes.models import CompileCache
+
+        if not self.compile_cache_enabled:
+            return None
+        return CompileCache(host_path=self.compile_cache_host_path, max_size_mb=self.compile_cache_max_size_mb)
+
+    def get_workspace_storage(self):
+        """Storage driver of execution pods' working directory."""
+        from ..services.kubernetes.storage import build_workspace_storage
+
+        return build_workspace_storage(
+            self.workspace_storage_driver, self.workspace_size_limit, self.workspace_storage_class
+        )
+
+    def get_dns_policy(self):
+        """DNS allowlisting for execution pods, if an upstream server is configured."""
+        from ..services.kubernetes.models import DnsPolicy
+
+        if not self.dns_policy_upstream:
+            return None
+        return DnsPolicy(upstream=self.dns_policy_upstream, allowlist=self.dns_allowlist)
+
     # ========================================================================
     # HELPER METHODS (preserved from original)
     # ========================================================================
@@ -684,6 +1206,21 @@ class Settings(BaseSettings):
         multiplier = self.get_language_config(language).get("memory_multiplier", 1.0)
         return int(self.max_memory_mb * multiplier)
 
+    def is_language_allowed(self, code: str) -> bool:
+        """Whether executions may use a (supported) language under ALLOWED_LANGUAGES."""
+        return not self.allowed_languages or code.lower() in self.allowed_languages
+
+    def get_policy_bundle(self) -> PolicyBundle | None:
+        """The policy bundle in effect, or None."""
+        if not self.policy_bundle_path:
+            return None
+        return load_bundle(self.policy_bundle_path, self.policy_bundle_version or None)
+
+    def get_policy_bundle_json(self) -> str | None:
+        """The policy bundle as sent to execution pods (POLICY_BUNDLE), or None."""
+        bundle = self.get_policy_bundle()
+        return bundle.canonical() if bundle else None
+
     def get_session_ttl_minutes(self) -> int:
         """Get session TTL in minutes for backward compatibility."""
         return self.session_ttl_hours * 60
@@ -715,6 +1252,9 @@ __all__ = [
     "ResourcesConfig",
     "LoggingConfig",
     "KubernetesConfig",
+    # Policy bundles
+    "PolicyBundle",
+    "PolicyBundleError",
     # Language configuration
     "LANGUAGES",
     "LanguageConfig",
@@ -38,6 +38,7 @@ class KubernetesConfig:
     run_as_group: int = 65532
     run_as_non_root: bool = True
     seccomp_profile_type: str = "RuntimeDefault"
+    read_only_root_filesystem: bool = False
 
     # Job settings (for languages with pool_size=0)
     job_ttl_seconds_after_finished: int = 60
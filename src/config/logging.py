@@ -1,5 +1,7 @@
 """Logging configuration."""
 
+from typing import Literal
+
 from pydantic import Field
 from pydantic_settings import BaseSettings, SettingsConfigDict
 
@@ -18,6 +20,10 @@ class LoggingConfig(BaseSettings):
     file: str | None = Field(default=None, alias="log_file")
     max_size_mb: int = Field(default=100, ge=1, alias="log_max_size_mb")
     backup_count: int = Field(default=5, ge=1, alias="log_backup_count")
+    rotation: Literal["size", "time"] = Field(default="size", alias="log_rotation")
+    rotation_when: str = Field(default="midnight", alias="log_rotation_when")
+    retention_days: int | None = Field(default=None, ge=1, alias="log_retention_days")
+    audit_file: str | None = Field(default=None, alias="audit_log_file")
     enable_access_logs: bool = Field(default=True)
 
     # Health Check
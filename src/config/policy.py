@@ -0,0 +1,145 @@
+"""Policy bundles: the security-relevant settings in one reviewable, versioned file.
+
+POLICY_BUNDLE_PATH points at a JSON file such as::
+
+    {
+      "name": "prod-default",
+      "version": "2026.10.1",
+      "description": "Default policy for the production cluster",
+      "limits": {"max_execution_time": 60, "max_memory_mb": 1024, "max_connections_per_execution": 20},
+      "languages": ["py", "js", "r"],
+      "network": {"isolation": true, "wan_access": false, "dns_allowlist": ["pypi.org", "*.pythonhosted.org"]},
+      "redaction": {"filters": ["secrets", "pii"], "patterns": ["ACME-[0-9]{8}"], "secret_scan": "block"}
+    }
+
+Every section and field is optional; the ones present replace the
+settings they map to (BUNDLE_SETTINGS), whatever their environment
+variables say, and are validated with the same bounds. POLICY_BUNDLE_VERSION
+pins the version: a different bundle refuses to load, so a replica never
+starts under a policy that wasn't reviewed.
+
+Execution pods get the bundle too (the sidecar's POLICY_BUNDLE), and
+report its name, version and digest. ``python scripts/policy_cli.py``
+validates a bundle and evaluates example requests against it.
+"""
+
+import hashlib
+import json
+from functools import lru_cache
+from typing import Any, Literal
+
+from pydantic import BaseModel, ConfigDict, Field, ValidationError
+
+
+class PolicyBundleError(ValueError):
+    """The policy bundle can't be read, is invalid or isn't the pinned version."""
+
+
+class _Section(BaseModel):
+    model_config = ConfigDict(extra="forbid")
+
+
+class PolicyLimits(_Section):
+    max_execution_time: int | None = None
+    max_memory_mb: int | None = None
+    max_cpus: float | None = None
+    max_concurrent_executions: int | None = None
+    max_connections_per_execution: int | None = None
+    max_output_files: int | None = None
+    max_file_size_mb: int | None = None
+    max_total_file_size_mb: int | None = None
+    max_files_per_session: int | None = None
+
+
+class PolicyNetwork(_Section):
+    isolation: bool | None = None
+    wan_access: bool | None = None
+    dns_allowlist: list[str] | None = None
+
+
+class PolicyRedaction(_Section):
+    filters: list[str] | None = None
+    patterns: list[str] | None = None
+    replacement: str | None = None
+    secret_scan: Literal["off", "flag", "block"] | None = None
+
+
+class PolicyBundle(_Section):
+    """A policy bundle (see the module docstring for the format)."""
+
+    name: str = Field(..., min_length=1, max_length=128)
+    version: str = Field(..., min_length=1, max_length=64)
+    description: str | None = None
+    limits: PolicyLimits = Field(default_factory=PolicyLimits)
+    languages: list[str] | None = Field(default=None, description="Languages executions may use; null allows all")
+    network: PolicyNetwork = Field(default_factory=PolicyNetwork)
+    redaction: PolicyRedaction = Field(default_factory=PolicyRedaction)
+
+    def settings(self) -> dict[str, Any]:
+        """The settings the bundle sets, by field name."""
+        values = {}
+        for section, fields in BUNDLE_SETTINGS.items():
+            data = getattr(self, section) if section != "languages" else self
+            for field, setting in fields.items():
+                value = getattr(data, field)
+                if value is not None:
+                    values[setting] = value
+        return values
+
+    def canonical(self) -> str:
+        """Compact JSON of the bundle with sorted keys, as sent to execution pods."""
+        return json.dumps(self.model_dump(exclude_none=True), sort_keys=True, separators=(",", ":"))
+
+    def digest(self) -> str:
+        """sha256:<hex> of the canonical JSON, identifying the exact policy."""
+        return "sha256:" + hashlib.sha256(self.canonical().encode("utf-8")).hexdigest()
+
+    def describe(self) -> dict[str, str]:
+        return {"name": self.name, "version": self.version, "digest": self.digest()}
+
+
+# Settings each bundle field replaces, by section
+BUNDLE_SETTINGS: dict[str, dict[str, str]] = {
+    "limits": {name: name for name in PolicyLimits.model_fields},
+    "languages": {"languages": "allowed_languages"},
+    "network": {
+        "isolation": "enable_network_isolation",
+        "wan_access": "enable_wan_access",
+        "dns_allowlist": "dns_allowlist",
+    },
+    "redaction": {
+        "filters": "output_filters",
+        "patterns": "output_redact_patterns",
+        "replacement": "output_redaction_text",
+        "secret_scan": "artifact_secret_scan",
+    },
+}
+
+
+def parse_bundle(text: str, pinned_version: str | None = None) -> PolicyBundle:
+    """Parse a bundle's JSON, checking it is the pinned version if one is given.
+
+    Raises:
+        PolicyBundleError: If the JSON or the bundle is invalid, or it isn't the pinned version
+    """
+    try:
+        bundle = PolicyBundle.model_validate_json(text)
+    except ValidationError as e:
+        problems = "; ".join(f"{'.'.join(map(str, err['loc'])) or 'bundle'}: {err['msg']}" for err in e.errors())
+        raise PolicyBundleError(f"Invalid policy bundle: {problems}")
+    if pinned_version and bundle.version != pinned_version:
+        raise PolicyBundleError(
+            f"Policy bundle {bundle.name} is version {bundle.version}, but POLICY_BUNDLE_VERSION pins {pinned_version}"
+        )
+    return bundle
+
+
+@lru_cache
+def load_bundle(path: str, pinned_version: str | None = None) -> PolicyBundle:
+    """Read and parse the bundle at ``path`` (cached: bundles are loaded at startup)."""
+    try:
+        with open(path, encoding="utf-8") as f:
+            text = f.read()
+    except OSError as e:
+        raise PolicyBundleError(f"Can't read policy bundle {path}: {e.strerror or e}")
+    return parse_bundle(text, pinned_version)
@@ -8,14 +8,31 @@ from .auth import (
     verify_api_key_optional,
 )
 from .services import (
+    CellHistoryServiceDep,
+    ElevationServiceDep,
     FileServiceDep,
+    HotConfigServiceDep,
+    LspProxyServiceDep,
+    QuarantineServiceDep,
     SessionServiceDep,
     StateArchivalServiceDep,
     StateServiceDep,
+    TimeoutAdvisorDep,
+    VariableInspectorDep,
+    WorkspaceLockServiceDep,
+    get_cell_history_service,
+    get_elevation_service,
     get_file_service,
+    get_hot_config_service,
+    get_lsp_proxy_service,
+    get_quarantine_service,
     get_session_service,
     get_state_archival_service,
     get_state_service,
+    get_timeout_advisor,
+    get_variable_inspector,
+    get_workspace_lock_service,
+    reject_quarantined_session,
 )
 
 __all__ = [
@@ -28,8 +45,25 @@ __all__ = [
     "get_session_service",
     "get_state_service",
     "get_state_archival_service",
+    "get_workspace_lock_service",
+    "get_variable_inspector",
+    "get_cell_history_service",
+    "get_timeout_advisor",
+    "get_hot_config_service",
+    "get_lsp_proxy_service",
+    "get_quarantine_service",
+    "get_elevation_service",
+    "reject_quarantined_session",
     "FileServiceDep",
     "SessionServiceDep",
     "StateServiceDep",
     "StateArchivalServiceDep",
+    "WorkspaceLockServiceDep",
+    "VariableInspectorDep",
+    "CellHistoryServiceDep",
+    "TimeoutAdvisorDep",
+    "HotConfigServiceDep",
+    "LspProxyServiceDep",
+    "QuarantineServiceDep",
+    "ElevationServiceDep",
 ]
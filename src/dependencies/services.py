@@ -7,17 +7,30 @@ from typing import Annotated
 import structlog
 
 # Third-party imports
-from fastapi import Depends
+from fastapi import Depends, HTTPException
 
 # Local application imports
 from ..services import CodeExecutionService, FileService, SessionService
+from ..services.cells import CellHistoryService
+from ..services.elevation import ElevationService
+from ..services.env_snapshots import EnvironmentSnapshotService
+from ..services.hot_config import HotConfigService
+from ..services.image_catalog import ImageCatalogService
 from ..services.interfaces import (
     ExecutionServiceInterface,
     FileServiceInterface,
     SessionServiceInterface,
 )
+from ..services.lsp import LspProxyService
+from ..services.pod_tools import PodToolsService
+from ..services.quarantine import QuarantineService
+from ..services.session_report import SessionReportService
+from ..services.session_transfer import SessionTransferService
 from ..services.state import StateService
 from ..services.state_archival import StateArchivalService
+from ..services.timeout_advisor import TimeoutAdvisor
+from ..services.variables import VariableInspector
+from ..services.workspace_lock import WorkspaceLockService
 
 logger = structlog.get_logger(__name__)
 
@@ -59,6 +72,42 @@ def get_state_archival_service() -> StateArchivalService:
     return StateArchivalService(state_service=state_service)
 
 
+@lru_cache
+def get_workspace_lock_service() -> WorkspaceLockService:
+    """Get workspace lock service instance for coordinating work within a session."""
+    return WorkspaceLockService()
+
+
+@lru_cache
+def get_cell_history_service() -> CellHistoryService:
+    """Get cell history service instance for recording executed cells."""
+    return CellHistoryService()
+
+
+@lru_cache
+def get_env_snapshot_service() -> EnvironmentSnapshotService:
+    """Get environment snapshot service instance for recording what executions change."""
+    return EnvironmentSnapshotService()
+
+
+@lru_cache
+def get_session_report_service() -> SessionReportService:
+    """Get session report service instance for tallying executions and reporting on ended sessions."""
+    return SessionReportService(file_service=get_file_service())
+
+
+@lru_cache
+def get_timeout_advisor() -> TimeoutAdvisor:
+    """Get timeout advisor instance for recording durations and suggesting timeouts."""
+    return TimeoutAdvisor()
+
+
+@lru_cache
+def get_hot_config_service() -> HotConfigService:
+    """Get hot configuration service instance for runtime policy changes."""
+    return HotConfigService()
+
+
 @lru_cache
 def get_execution_service() -> ExecutionServiceInterface:
     """Get execution service instance.
@@ -68,6 +117,26 @@ def get_execution_service() -> ExecutionServiceInterface:
     return CodeExecutionService()
 
 
+def get_lsp_proxy_service() -> LspProxyService:
+    """Get the language server query service (uses the Kubernetes manager registered at startup)."""
+    return LspProxyService(kubernetes_manager=get_kubernetes_manager(), file_service=get_file_service())
+
+
+def get_pod_tools_service() -> PodToolsService:
+    """Get the pod file tools service (uses the Kubernetes manager registered at startup)."""
+    return PodToolsService(kubernetes_manager=get_kubernetes_manager(), file_service=get_file_service())
+
+
+@lru_cache
+def get_variable_inspector() -> VariableInspector:
+    """Get the variable inspector for summarizing persisted Python state."""
+    return VariableInspector(
+        execution_service=get_execution_service(),
+        state_service=get_state_service(),
+        state_archival_service=get_state_archival_service(),
+    )
+
+
 def inject_kubernetes_manager_to_execution_service():
     """Inject Kubernetes manager into the execution service.
 
@@ -95,6 +164,7 @@ def get_session_service() -> SessionServiceInterface:
         # Wire up the dependencies
         session_service._execution_service = execution_service
         session_service._file_service = file_service
+        session_service._report_service = get_session_report_service()
 
         logger.info("Session service initialized with dependencies")
         return session_service
@@ -105,9 +175,66 @@ def get_session_service() -> SessionServiceInterface:
         return SessionService()
 
 
+@lru_cache
+def get_elevation_service() -> ElevationService:
+    """Get the elevated execution grant service."""
+    return ElevationService()
+
+
+@lru_cache
+def get_image_catalog_service() -> ImageCatalogService:
+    """Get the image catalog service (which catalog images each API key may run)."""
+    return ImageCatalogService()
+
+
+@lru_cache
+def get_quarantine_service() -> QuarantineService:
+    """Get the session quarantine service (freezes sessions and kills their executions)."""
+    return QuarantineService(
+        session_service=get_session_service(),
+        execution_service=get_execution_service(),
+        file_service=get_file_service(),
+        state_service=get_state_service(),
+        cell_history_service=get_cell_history_service(),
+        report_service=get_session_report_service(),
+    )
+
+
+@lru_cache
+def get_session_transfer_service() -> SessionTransferService:
+    """Get the session export/import service (migrates sessions between clusters)."""
+    return SessionTransferService(
+        session_service=get_session_service(),
+        file_service=get_file_service(),
+        state_service=get_state_service(),
+        state_archival_service=get_state_archival_service(),
+        cell_history_service=get_cell_history_service(),
+    )
+
+
 # Type aliases for dependency injection
 FileServiceDep = Annotated[FileServiceInterface, Depends(get_file_service)]
 SessionServiceDep = Annotated[SessionServiceInterface, Depends(get_session_service)]
 ExecutionServiceDep = Annotated[ExecutionServiceInterface, Depends(get_execution_service)]
 StateServiceDep = Annotated[StateService, Depends(get_state_service)]
 StateArchivalServiceDep = Annotated[StateArchivalService, Depends(get_state_archival_service)]
+WorkspaceLockServiceDep = Annotated[WorkspaceLockService, Depends(get_workspace_lock_service)]
+VariableInspectorDep = Annotated[VariableInspector, Depends(get_variable_inspector)]
+CellHistoryServiceDep = Annotated[CellHistoryService, Depends(get_cell_history_service)]
+EnvironmentSnapshotServiceDep = Annotated[EnvironmentSnapshotService, Depends(get_env_snapshot_service)]
+SessionReportServiceDep = Annotated[SessionReportService, Depends(get_session_report_service)]
+TimeoutAdvisorDep = Annotated[TimeoutAdvisor, Depends(get_timeout_advisor)]
+HotConfigServiceDep = Annotated[HotConfigService, Depends(get_hot_config_service)]
+LspProxyServiceDep = Annotated[LspProxyService, Depends(get_lsp_proxy_service)]
+PodToolsServiceDep = Annotated[PodToolsService, Depends(get_pod_tools_service)]
+QuarantineServiceDep = Annotated[QuarantineService, Depends(get_quarantine_service)]
+ElevationServiceDep = Annotated[ElevationService, Depends(get_elevation_service)]
+
+
+async def reject_quarantined_session(session_id: str, quarantine_service: QuarantineServiceDep) -> None:
+    """Raise 409 if the session is quarantined; a route dependency for endpoints that change a session."""
+    if await quarantine_service.is_quarantined(session_id):
+        raise HTTPException(
+            status_code=409,
+            detail={"error": "session_quarantined", "message": "Session is quarantined"},
+        )
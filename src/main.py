@@ -16,13 +16,30 @@ from pydantic import ValidationError
 
 # Local application imports
 from ._version import __version__
-from .api import admin, dashboard_metrics, exec, files, health, state
+from .api import (
+    admin,
+    context,
+    dag,
+    dashboard_metrics,
+    datasets,
+    exec,
+    files,
+    health,
+    images,
+    lsp,
+    pod_tools,
+    sessions,
+    state,
+    templates,
+    webdav,
+)
 from .config import settings
 from .middleware.metrics import MetricsMiddleware
 from .middleware.security import RequestLoggingMiddleware, SecurityMiddleware
 from .models.errors import CodeInterpreterException
 from .services.health import health_service
 from .services.metrics import metrics_collector
+from .services.webdav import DAV_PREFIX
 from .utils.config_validator import get_configuration_summary, validate_configuration
 from .utils.error_handlers import (
     code_interpreter_exception_handler,
@@ -104,6 +121,27 @@ async def lifespan(app: FastAPI):
         logger.error("Failed to start session cleanup task", error=str(e))
         # Don't fail startup if cleanup task fails
 
+    # Apply policy changes made through /admin/config and keep them in sync
+    try:
+        from .dependencies.services import get_hot_config_service
+
+        await get_hot_config_service().start_refresh_task()
+        logger.info(
+            "Config override refresh started",
+            interval_seconds=settings.config_refresh_interval_seconds,
+        )
+    except Exception as e:
+        logger.error("Failed to start config override refresh", error=str(e))
+        # Don't fail startup; deployed settings stay in effect
+
+    # Kill this replica's executions of sessions quarantined through any replica
+    try:
+        from .dependencies.services import get_quarantine_service
+
+        await get_quarantine_service().start_watch_task()
+    except Exception as e:
+        logger.error("Failed to start quarantine watch", error=str(e))
+
     # Start event-driven cleanup scheduler
     try:
         logger.info("Starting cleanup scheduler...")
@@ -134,6 +172,7 @@ async def lifespan(app: FastAPI):
         try:
             logger.info("Starting Kubernetes pod pool...")
             from .dependencies.services import (
+                get_hot_config_service,
                 inject_kubernetes_manager_to_execution_service,
                 set_kubernetes_manager,
             )
@@ -152,7 +191,15 @@ async def lifespan(app: FastAPI):
                 default_cpu_request=settings.k8s_cpu_request,
                 default_memory_request=settings.k8s_memory_request,
                 seccomp_profile_type=settings.k8s_seccomp_profile_type,
+                read_only_root_filesystem=settings.k8s_read_only_root_filesystem,
                 network_isolated=settings.enable_network_isolation,
+                datasets=settings.get_dataset_mounts(),
+                compile_cache=settings.get_compile_cache(),
+                dns_policy=settings.get_dns_policy(),
+                fault_injection=settings.fault_injection,
+                policy_bundle=settings.get_policy_bundle_json(),
+                strict_mode=settings.sidecar_strict_mode,
+                workspace_storage=settings.get_workspace_storage(),
             )
 
             await kubernetes_manager.start()
@@ -164,6 +211,9 @@ async def lifespan(app: FastAPI):
             # Register manager with health service for monitoring
             health_service.set_kubernetes_manager(kubernetes_manager)
 
+            # Network mode changes made at runtime apply to the pods it creates
+            get_hot_config_service().set_kubernetes_manager(kubernetes_manager)
+
             # Store manager reference in app state
             app.state.kubernetes_manager = kubernetes_manager
 
@@ -227,6 +277,22 @@ async def lifespan(app: FastAPI):
         except Exception as e:
             logger.error("Error stopping Kubernetes pod pool", error=str(e))
 
+    # Stop config override refresh
+    try:
+        from .dependencies.services import get_hot_config_service
+
+        await get_hot_config_service().stop_refresh_task()
+    except Exception as e:
+        logger.error("Error stopping config override refresh", error=str(e))
+
+    # Stop quarantine watch
+    try:
+        from .dependencies.services import get_quarantine_service
+
+        await get_quarantine_service().stop_watch_task()
+    except Exception as e:
+        logger.error("Error stopping quarantine watch", error=str(e))
+
     # Stop cleanup scheduler
     try:
         from .services.cleanup import cleanup_scheduler
@@ -317,12 +383,33 @@ app.include_router(files.router, tags=["files"])
 
 app.include_router(exec.router, tags=["exec"])
 
+app.include_router(dag.router, tags=["exec"])
+
 app.include_router(health.router, tags=["health", "monitoring"])
 
 app.include_router(state.router, tags=["state"])
 
+app.include_router(sessions.router, tags=["sessions"])
+
+app.include_router(context.router, tags=["context"])
+
+app.include_router(templates.router, tags=["templates"])
+
+app.include_router(datasets.router, tags=["datasets"])
+
+app.include_router(images.router, tags=["images"])
+
+app.include_router(lsp.router, tags=["lsp"])
+
+app.include_router(pod_tools.router, tags=["tools"])
+
+if settings.webdav_enabled:
+    app.include_router(webdav.router, prefix=DAV_PREFIX, tags=["webdav"])
+
 app.include_router(admin.router, prefix="/api/v1", tags=["admin"])
 
+app.include_router(admin.config_router)
+
 app.include_router(dashboard_metrics.router, prefix="/api/v1", tags=["admin-metrics"])
 
 # Admin Dashboard Frontend
@@ -1,6 +1,8 @@
 """Consolidated security middleware for the Code Interpreter API."""
 
 # Standard library imports
+import base64
+import binascii
 import time
 from typing import Callable, Optional
 
@@ -12,6 +14,7 @@ from fastapi.responses import JSONResponse
 # Local application imports
 from ..config import settings
 from ..services.auth import get_auth_service
+from ..services.webdav import DAV_PREFIX
 
 logger = structlog.get_logger(__name__)
 
@@ -30,6 +33,8 @@ class SecurityMiddleware:
             "/openapi.json",
             "/api/v1/admin",
             "/admin-dashboard",
+            # Alias of /api/v1/admin/config, which checks the master key itself
+            "/config",
         }
 
     async def __call__(self, scope: dict, receive: Callable, send: Callable):
@@ -97,9 +102,14 @@ class SecurityMiddleware:
                 await self._authenticate_request(request, scope)
 
         except HTTPException as e:
+            headers = dict(e.headers or {})
+            if e.status_code == 401 and self._is_dav_path(request):
+                # Lets file managers prompt for credentials
+                headers["WWW-Authenticate"] = 'Basic realm="workspace"'
             response = JSONResponse(
                 status_code=e.status_code,
                 content={"error": e.detail, "timestamp": time.time()},
+                headers=headers or None,
             )
             await response(scope, receive, send_wrapper)
             return
@@ -120,10 +130,12 @@ class SecurityMiddleware:
         # Only validate content type for non-file upload requests
         # File uploads are handled by the files API with specific validation
         # State uploads use raw binary (application/octet-stream)
+        # WebDAV PUTs carry the file's own content type
         if (
             request.method in ["POST", "PUT", "PATCH"]
             and not request.url.path.startswith("/upload")
             and not request.url.path.startswith("/state/")
+            and not self._is_dav_path(request)
         ):
             content_type = request.headers.get("content-type", "")
             allowed_types = [
@@ -136,6 +148,9 @@ class SecurityMiddleware:
             if not any(allowed in content_type for allowed in allowed_types):
                 raise HTTPException(status_code=415, detail=f"Unsupported content type: {content_type}")
 
+    def _is_dav_path(self, request: Request) -> bool:
+        return request.url.path.startswith(DAV_PREFIX + "/")
+
     def _should_skip_auth(self, request: Request) -> bool:
         """Check if authentication should be skipped."""
         path = request.url.path
@@ -229,6 +244,13 @@ class SecurityMiddleware:
                 return auth_header[7:]
             elif auth_header.startswith("ApiKey "):
                 return auth_header[7:]
+            elif auth_header.startswith("Basic "):
+                # HTTP Basic auth (WebDAV clients): the password is the API key
+                try:
+                    credentials = base64.b64decode(auth_header[6:], validate=True).decode("utf-8")
+                except (binascii.Error, UnicodeDecodeError):
+                    return None
+                return credentials.partition(":")[2] or None
 
         return None
 
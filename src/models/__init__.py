@@ -17,7 +17,31 @@ from .errors import (
     TimeoutError,
     ValidationError,
 )
-from .exec import ExecRequest, ExecResponse, FileRef, RequestFile
+from .cell import CellDiff, CellDiffRequest, CellDiffResponse, CellInfo, FileChanges, TextDiff, ValueChange
+from .context import ContextLanguage, ContextLimits, ContextMount, ContextNetwork, ContextResponse
+from .dag import DagRequest, DagResponse, DagStep, DagStepResult
+from .dataset import DatasetInfo, DatasetListResponse
+from .exec import (
+    ArtifactMetadata,
+    CompileCacheStats,
+    ExecError,
+    ExecPlanFile,
+    ExecPlanLimits,
+    ExecPlanRateLimit,
+    ExecPlanResponse,
+    ExecPriority,
+    ExecWorkspacePolicy,
+    ExecRequest,
+    ExecResponse,
+    ExecTimings,
+    FileRef,
+    RequestFile,
+    RetryPolicy,
+    SandboxReport,
+    SecretFinding,
+    TimeoutSuggestion,
+    TimeoutSuggestionRequest,
+)
 from .execution import (
     CodeExecution,
     ExecuteCodeRequest,
@@ -27,10 +51,15 @@ from .execution import (
     OutputType,
 )
 from .files import (
+    ArchiveExtractRequest,
+    ArchiveExtractResponse,
+    ExtractedFile,
     FileDeleteResponse,
     FileDownloadResponse,
     FileInfo,
     FileListResponse,
+    FilePreviewColumn,
+    FilePreviewResponse,
     FileUploadRequest,
     FileUploadResponse,
 )
@@ -39,12 +68,25 @@ from .session import (
     FileInfo as SessionFileInfo,
 )
 from .session import (
+    CompletionMatch,
+    CompletionRequest,
+    CompletionResponse,
+    DataFrameExportResponse,
     Session,
     SessionCreate,
+    SessionEnvResponse,
+    SessionEnvUpdate,
     SessionResponse,
+    SessionRestartResponse,
     SessionStatus,
+    SessionVariablesResponse,
+    VariableInfo,
+    WorkspaceLockInfo,
+    WorkspaceLockRequest,
+    WorkspaceLockResponse,
 )
 from .state import StateInfo, StateUploadResponse
+from .template import ExecutionTemplate, TemplateListResponse, TemplateParam, TemplateRunRequest
 
 __all__ = [
     # Session models
@@ -52,6 +94,18 @@ __all__ = [
     "SessionStatus",
     "SessionCreate",
     "SessionResponse",
+    "SessionRestartResponse",
+    "SessionVariablesResponse",
+    "VariableInfo",
+    "DataFrameExportResponse",
+    "CompletionRequest",
+    "CompletionMatch",
+    "CompletionResponse",
+    "SessionEnvUpdate",
+    "SessionEnvResponse",
+    "WorkspaceLockRequest",
+    "WorkspaceLockInfo",
+    "WorkspaceLockResponse",
     "SessionFileInfo",
     # Execution models
     "CodeExecution",
@@ -67,11 +121,58 @@ __all__ = [
     "FileListResponse",
     "FileDownloadResponse",
     "FileDeleteResponse",
+    "ArchiveExtractRequest",
+    "ArchiveExtractResponse",
+    "ExtractedFile",
+    "FilePreviewColumn",
+    "FilePreviewResponse",
     # Exec endpoint models
     "ExecRequest",
     "ExecResponse",
+    "ExecError",
+    "ExecTimings",
+    "CompileCacheStats",
+    "TimeoutSuggestion",
+    "TimeoutSuggestionRequest",
+    "RetryPolicy",
     "FileRef",
+    "ArtifactMetadata",
+    "ExecPlanResponse",
+    "ExecPlanFile",
+    "ExecPlanLimits",
+    "ExecPlanRateLimit",
+    "ExecPriority",
+    "ExecWorkspacePolicy",
     "RequestFile",
+    "SandboxReport",
+    "SecretFinding",
+    # DAG endpoint models
+    "DagStep",
+    "DagRequest",
+    "DagStepResult",
+    "DagResponse",
+    # Context endpoint models
+    "ContextResponse",
+    "ContextMount",
+    "ContextLanguage",
+    "ContextLimits",
+    "ContextNetwork",
+    # Template endpoint models
+    "ExecutionTemplate",
+    "TemplateParam",
+    "TemplateRunRequest",
+    "TemplateListResponse",
+    # Dataset endpoint models
+    "DatasetInfo",
+    "DatasetListResponse",
+    # Cell history models
+    "CellInfo",
+    "CellDiffRequest",
+    "CellDiffResponse",
+    "CellDiff",
+    "ValueChange",
+    "TextDiff",
+    "FileChanges",
     # Error models
     "ErrorType",
     "ErrorDetail",
@@ -0,0 +1,89 @@
+"""Models for session cell history (executed code cells)."""
+
+from datetime import datetime
+from typing import Any
+
+from pydantic import BaseModel, Field, field_serializer
+
+from .exec import ExecResponse, FileRef, RequestFile
+
+
+class CellInfo(BaseModel):
+    """An executed code cell: its input, outputs and timing."""
+
+    cell_id: int = Field(..., description="Execution count within the session, like Jupyter's In[n]")
+    execution_id: str | None = None
+    code: str
+    lang: str
+    args: Any | None = None
+    input_files: list[RequestFile] = Field(default_factory=list, description="Files mounted for the cell")
+    scope: list[str] | None = None
+    status: str = Field(..., description="Execution status: completed, failed, timeout or cancelled")
+    exit_code: int | None = None
+    stdout: str = ""
+    stderr: str = ""
+    output_truncated: bool = Field(default=False, description="Whether stdout/stderr were cut for storage")
+    files: list[FileRef] = Field(default_factory=list, description="Files the cell generated")
+    started_at: datetime
+    duration_ms: int | None = None
+    rerun_of: int | None = Field(default=None, description="Cell this one re-ran, if any")
+    provenance: dict[str, Any] | None = Field(
+        default=None, description="Command and interpreter binaries (path, sha256, version) that ran the cell"
+    )
+    image: str | None = Field(default=None, description="Image the cell ran in")
+    image_name: str | None = Field(default=None, description="Catalog name of the image, if it came from the catalog")
+
+    @field_serializer("started_at")
+    def serialize_datetime(self, value: datetime) -> str:
+        return value.isoformat()
+
+
+class CellDiffRequest(BaseModel):
+    """Modified code to run against a previous cell (POST /sessions/{id}/cells/{n}/diff)."""
+
+    code: str = Field(..., description="The modified code; language and input files are the cell's")
+    args: Any | None = Field(default=None, description="Command line arguments; defaults to the cell's")
+
+
+class ValueChange(BaseModel):
+    """A scalar result of the previous and the new run."""
+
+    previous: Any | None = None
+    current: Any | None = None
+    changed: bool
+
+
+class TextDiff(BaseModel):
+    """A stream output compared between runs."""
+
+    changed: bool
+    diff: str = Field(default="", description="Unified diff from the previous to the new output")
+
+
+class FileChanges(BaseModel):
+    """Generated files compared by name (and content) between runs."""
+
+    added: list[str] = Field(default_factory=list)
+    removed: list[str] = Field(default_factory=list)
+    modified: list[str] = Field(default_factory=list)
+    unchanged: list[str] = Field(default_factory=list)
+
+
+class CellDiff(BaseModel):
+    """Differences between a cell's run and its modified re-run."""
+
+    status: ValueChange
+    exit_code: ValueChange
+    duration_ms: ValueChange
+    stdout: TextDiff
+    stderr: TextDiff
+    files: FileChanges
+
+
+class CellDiffResponse(BaseModel):
+    """Result of running modified code, with its diff against the previous cell."""
+
+    previous_cell_id: int
+    cell_id: int | None = Field(default=None, description="Cell recorded for the new run")
+    result: ExecResponse
+    diff: CellDiff
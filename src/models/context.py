@@ -0,0 +1,50 @@
+"""Models for the deployment context endpoint (GET /context)."""
+
+from pydantic import BaseModel, Field
+
+
+class ContextMount(BaseModel):
+    """A path available to executions."""
+
+    path: str
+    description: str
+
+
+class ContextLanguage(BaseModel):
+    """A supported language and its limits."""
+
+    code: str
+    name: str
+    file_extension: str
+    timeout_seconds: int
+    memory_mb: int
+
+
+class ContextLimits(BaseModel):
+    """Resource limits applied to executions and files."""
+
+    max_execution_time: int = Field(..., description="Base timeout in seconds (languages may scale it)")
+    max_memory_mb: int
+    max_file_size_mb: int
+    max_files_per_session: int
+    max_output_files: int
+
+
+class ContextNetwork(BaseModel):
+    """Network access available to executions."""
+
+    isolated: bool = Field(..., description="Whether network isolation is enabled for execution pods")
+    wan_access: bool = Field(..., description="Whether WAN-only internet access is enabled")
+
+
+class ContextResponse(BaseModel):
+    """What the sandbox contains, for clients and the models driving them."""
+
+    banner: str | None = Field(default=None, description="Operator-provided description of this deployment")
+    working_directory: str = Field(..., description="Where code runs and files are uploaded")
+    env: dict[str, str] = Field(default_factory=dict, description="Environment variables set in every execution")
+    mounts: list[ContextMount] = Field(default_factory=list)
+    languages: list[ContextLanguage] = Field(default_factory=list)
+    limits: ContextLimits
+    network: ContextNetwork
+    state_persistence: bool = Field(..., description="Whether Python variables persist between executions")
@@ -0,0 +1,69 @@
+"""Models for the /dag endpoint (dependency graphs of execution steps)."""
+
+from typing import Any, Literal
+
+from pydantic import BaseModel, Field
+
+from .exec import FileRef, RequestFile
+
+
+class DagStep(BaseModel):
+    """A named execution step and the steps it depends on."""
+
+    name: str = Field(..., max_length=64, pattern=r"^[A-Za-z0-9_-]+$", description="Unique step name")
+    code: str = Field(..., description="The source code to be executed")
+    lang: str = Field(..., description="The programming language of the code")
+    depends_on: list[str] = Field(default_factory=list, description="Names of steps that must complete first")
+    args: Any | None = Field(default=None, description="Optional command line arguments (any JSON type)")
+    files: list[RequestFile] = Field(default_factory=list, description="File references to mount for this step")
+    env: dict[str, str] | None = Field(default=None, description="Optional environment variables for this step")
+    scope: list[str] | None = Field(
+        default=None,
+        description="Workspace paths this step touches; only steps with disjoint scopes run at the same time. "
+        "Omit to give the step the whole workspace.",
+    )
+    image: str | None = Field(default=None, description="Catalog image to run the step in, as in /exec")
+
+
+class DagRequest(BaseModel):
+    """Request model for /dag endpoint."""
+
+    steps: list[DagStep] = Field(..., min_length=1, description="Steps to run; all share one session")
+    session_id: str | None = Field(default=None, description="Optional existing session to run the steps in")
+    user_id: str | None = Field(default=None, description="Optional user identifier")
+    entity_id: str | None = Field(
+        default=None,
+        description="Optional assistant/agent identifier for file sharing",
+        max_length=40,
+        pattern=r"^[A-Za-z0-9_-]+$",
+    )
+    fail_fast: bool = Field(
+        default=False,
+        description="Stop starting new steps after any failure (otherwise only dependents are skipped)",
+    )
+
+
+class DagStepResult(BaseModel):
+    """Result of a single step."""
+
+    name: str
+    status: Literal["completed", "failed", "skipped"]
+    exit_code: int | None = None
+    stdout: str = ""
+    stderr: str = ""
+    files: list[FileRef] = Field(default_factory=list)
+    error: str | None = Field(default=None, description="Why the step failed or was skipped")
+    start_ms: int | None = Field(default=None, description="Start time relative to the start of the run")
+    duration_ms: int | None = None
+
+
+class DagResponse(BaseModel):
+    """Response model for /dag endpoint."""
+
+    session_id: str
+    status: Literal["completed", "failed"]
+    steps: list[DagStepResult]
+    total_time_ms: int = Field(..., description="Wall-clock time of the whole run")
+    serial_time_ms: int = Field(..., description="Sum of step durations, i.e. the time a sequential run would take")
+    critical_path: list[str] = Field(default_factory=list, description="Longest chain of dependent steps")
+    critical_path_ms: int = 0
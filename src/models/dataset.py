@@ -0,0 +1,19 @@
+"""Models for shared datasets (/datasets)."""
+
+from pydantic import BaseModel, Field
+
+
+class DatasetInfo(BaseModel):
+    """A shared dataset mounted read-only into every session."""
+
+    name: str
+    digest: str = Field(..., description="sha256:<hex> of the dataset's contents")
+    path: str = Field(..., description="Where executions see the dataset")
+    description: str | None = None
+    size_bytes: int | None = None
+
+
+class DatasetListResponse(BaseModel):
+    """Configured datasets (GET /datasets)."""
+
+    datasets: list[DatasetInfo]
@@ -0,0 +1,72 @@
+"""Models for elevated executions (operator-approved grants of extra capabilities)."""
+
+from datetime import datetime
+from typing import Literal
+
+from pydantic import BaseModel, ConfigDict, Field
+
+
+class ElevatedCapabilities(BaseModel):
+    """What a grant allows beyond the deployment's policy; omitted fields keep it."""
+
+    model_config = ConfigDict(extra="forbid")
+
+    network: bool = Field(default=False, description="Outbound network access (needs ELEVATED_ALLOW_NETWORK)")
+    max_execution_time: int | None = Field(
+        default=None, ge=1, description="Timeout in seconds, up to ELEVATED_MAX_EXECUTION_TIME"
+    )
+    memory_mb: int | None = Field(default=None, ge=256, description="Pod memory limit, up to ELEVATED_MAX_MEMORY_MB")
+    max_connections: int | None = Field(
+        default=None, ge=1, description="Outbound connections open at once, replacing MAX_CONNECTIONS_PER_EXECUTION"
+    )
+
+    def needs_dedicated_pod(self) -> bool:
+        """Network and memory are applied when a pod is created, so pooled pods can't be used."""
+        return self.network or self.memory_mb is not None
+
+
+class ElevatedGrantRequest(BaseModel):
+    """An operator approving elevated executions (POST /admin/elevated-grants)."""
+
+    capabilities: ElevatedCapabilities
+    reason: str = Field(..., min_length=1, max_length=1000, description="Why, for the audit trail")
+    ttl_minutes: int = Field(default=15, ge=1, description="Lifetime, up to ELEVATED_GRANT_MAX_MINUTES")
+    api_key_hash: str | None = Field(
+        default=None,
+        pattern=r"^[0-9a-f]{64}$",
+        description="Only this API key may use the grant (its full SHA-256 hash, as listed by GET /admin/keys)",
+    )
+    session_id: str | None = Field(default=None, description="Only executions in this session may use the grant")
+    max_uses: int | None = Field(default=None, ge=1, description="Executions the grant may be used for")
+
+
+class ElevatedGrant(BaseModel):
+    """An issued grant; its signed form is the token passed as ExecRequest.elevation_grant."""
+
+    grant_id: str
+    capabilities: ElevatedCapabilities
+    reason: str
+    approved_by: str | None = Field(default=None, description="Client address of the admin who issued it")
+    issued_at: datetime
+    expires_at: datetime
+    api_key_hash: str | None = None
+    session_id: str | None = None
+    max_uses: int | None = None
+
+
+class ElevatedGrantResponse(BaseModel):
+    """The grant and its token, shown once."""
+
+    token: str
+    grant: ElevatedGrant
+
+
+class ElevationAuditEntry(BaseModel):
+    """One event in the life of a grant."""
+
+    event: Literal["issued", "used", "denied", "revoked"]
+    grant_id: str | None = None
+    at: datetime
+    actor: str | None = Field(default=None, description="Admin address, or API key hash prefix for uses")
+    session_id: str | None = None
+    detail: str | None = None
@@ -0,0 +1,67 @@
+"""Models for a session's environment snapshots and the changes between them."""
+
+from datetime import datetime
+
+from pydantic import BaseModel, Field, field_serializer
+
+
+class SnapshotFile(BaseModel):
+    """A workspace file as it was after an execution."""
+
+    file_id: str
+    size: int
+
+
+class EnvironmentSnapshot(BaseModel):
+    """The environment a session was left in by one execution."""
+
+    cell_id: int | None = Field(default=None, description="Cell recorded for the execution, if history is on")
+    execution_id: str | None = None
+    taken_at: datetime
+    env: dict[str, str] = Field(
+        default_factory=dict, description="Environment variable names the execution ran with, and digests of values"
+    )
+    packages: list[str] = Field(default_factory=list, description="Packages installed so far, as manager:spec")
+    files: dict[str, SnapshotFile] = Field(default_factory=dict, description="Workspace files by path")
+
+    @field_serializer("taken_at")
+    def serialize_datetime(self, value: datetime) -> str:
+        return value.isoformat()
+
+
+class ChangeSet(BaseModel):
+    """Names added, removed and changed between two snapshots."""
+
+    added: list[str] = Field(default_factory=list)
+    removed: list[str] = Field(default_factory=list)
+    modified: list[str] = Field(default_factory=list)
+
+    @property
+    def changed(self) -> bool:
+        return bool(self.added or self.removed or self.modified)
+
+
+class EnvironmentChanges(BaseModel):
+    """What one execution changed in its session's environment, compared with the one before it."""
+
+    cell_id: int | None = None
+    execution_id: str | None = None
+    previous_cell_id: int | None = Field(
+        default=None, description="Cell compared against; null for the first snapshot kept"
+    )
+    previous_execution_id: str | None = None
+    taken_at: datetime
+    env: ChangeSet = Field(default_factory=ChangeSet, description="Variable names; values are never returned")
+    packages: ChangeSet = Field(default_factory=ChangeSet, description="Packages installed (and no longer listed)")
+    files: ChangeSet = Field(default_factory=ChangeSet, description="Workspace file paths")
+
+    @field_serializer("taken_at")
+    def serialize_datetime(self, value: datetime) -> str:
+        return value.isoformat()
+
+
+class SessionChangesResponse(BaseModel):
+    """Environment changes made by a session's recent executions, oldest first."""
+
+    session_id: str
+    changes: list[EnvironmentChanges] = Field(default_factory=list)
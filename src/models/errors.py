@@ -2,7 +2,7 @@
 
 import time
 from enum import Enum
-from typing import List, Optional
+from typing import Any, List, Optional
 
 from pydantic import BaseModel, ConfigDict, Field
 
@@ -35,8 +35,9 @@ class ErrorDetail(BaseModel):
 class ErrorResponse(BaseModel):
     """Standardized error response model."""
 
-    error: str = Field(..., description="Main error message")
+    error: str = Field(..., description="Main error message (localized per Accept-Language)")
     error_type: ErrorType = Field(..., description="Error category")
+    code: str | None = Field(None, description="Stable machine-readable error code, never localized")
     details: list[ErrorDetail] | None = Field(None, description="Additional error details")
     request_id: str | None = Field(None, description="Request identifier for tracking")
     timestamp: float = Field(default_factory=time.time, description="Error timestamp")
@@ -57,19 +58,25 @@ class CodeInterpreterException(Exception):
         status_code: int = 500,
         details: list[ErrorDetail] | None = None,
         request_id: str | None = None,
+        code: str | None = None,
+        params: dict[str, Any] | None = None,
     ):
         self.message = message
         self.error_type = error_type
         self.status_code = status_code
         self.details = details or []
         self.request_id = request_id
+        # Catalog key for localized messages (defaults to the error type) and its placeholders
+        self.code = code or error_type.value
+        self.params = params or {}
         super().__init__(message)
 
-    def to_response(self) -> ErrorResponse:
-        """Convert exception to error response model."""
+    def to_response(self, message: str | None = None) -> ErrorResponse:
+        """Convert exception to error response model, optionally with a localized message."""
         return ErrorResponse(
-            error=self.message,
+            error=message or self.message,
             error_type=self.error_type,
+            code=self.code,
             details=self.details if self.details else None,
             request_id=self.request_id,
         )
@@ -113,6 +120,7 @@ class ResourceNotFoundError(CodeInterpreterException):
         message = f"{resource} not found"
         if resource_id:
             message += f": {resource_id}"
+        kwargs.setdefault("params", {"resource": resource, "resource_id": resource_id or ""})
         super().__init__(
             message=message,
             error_type=ErrorType.RESOURCE_NOT_FOUND,
@@ -137,6 +145,7 @@ class ResourceExhaustedError(CodeInterpreterException):
     """Resource exhaustion errors."""
 
     def __init__(self, resource: str, **kwargs):
+        kwargs.setdefault("params", {"resource": resource})
         super().__init__(
             message=f"{resource} limit exceeded",
             error_type=ErrorType.RESOURCE_EXHAUSTED,
@@ -161,6 +170,7 @@ class TimeoutError(CodeInterpreterException):
     """Timeout related errors."""
 
     def __init__(self, operation: str, timeout: int, **kwargs):
+        kwargs.setdefault("params", {"operation": operation, "timeout": timeout})
         super().__init__(
             message=f"{operation} timed out after {timeout} seconds",
             error_type=ErrorType.TIMEOUT,
@@ -186,6 +196,7 @@ class ServiceUnavailableError(CodeInterpreterException):
 
     def __init__(self, service: str, message: str = None, **kwargs):
         error_message = message or f"{service} service is currently unavailable"
+        kwargs.setdefault("params", {"service": service})
         super().__init__(
             message=error_message,
             error_type=ErrorType.SERVICE_UNAVAILABLE,
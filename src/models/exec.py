@@ -1,11 +1,24 @@
 """Models for the /exec endpoint compatible with LibreChat API."""
 
 # Standard library imports
-from typing import Any, List, Optional
+from typing import Any, List, Literal, Optional
 
 # Third-party imports
 from pydantic import BaseModel, Field
 
+# Local imports
+from .errors import ErrorDetail
+
+
+class ArtifactMetadata(BaseModel):
+    """What could be read from a generated file's headers."""
+
+    width: int | None = Field(default=None, description="Image width in pixels")
+    height: int | None = Field(default=None, description="Image height in pixels")
+    columns: int | None = Field(default=None, description="Columns of a CSV, TSV or Parquet file")
+    rows: int | None = Field(default=None, description="Rows of a Parquet file")
+    pages: int | None = Field(default=None, description="Pages of a PDF")
+
 
 class FileRef(BaseModel):
     """File reference model for execution response."""
@@ -13,6 +26,10 @@ class FileRef(BaseModel):
     id: str
     name: str
     path: str | None = None  # Make path optional
+    # Set for files generated by the execution
+    content_type: str | None = Field(default=None, description="Type sniffed from the file's contents")
+    size: int | None = None
+    metadata: ArtifactMetadata | None = None
 
 
 class RequestFile(BaseModel):
@@ -23,6 +40,70 @@ class RequestFile(BaseModel):
     name: str
 
 
+class RetryPolicy(BaseModel):
+    """Opt-in retries for idempotent code that failed for a transient reason."""
+
+    max_attempts: int = Field(default=2, ge=1, le=5, description="Total attempts, including the first")
+    backoff_ms: int = Field(default=500, ge=0, le=30000, description="Delay before the first retry")
+    backoff_multiplier: float = Field(default=2.0, ge=1.0, le=10.0, description="Delay growth per retry")
+    retry_on: list[Literal["SPAWN_FAILED", "OOM", "TIMEOUT"]] = Field(
+        default_factory=lambda: ["SPAWN_FAILED"],
+        description="Failure classes to retry: SPAWN_FAILED (pod/sidecar unavailable), OOM (killed for memory), "
+        "TIMEOUT",
+    )
+    reduce_parallelism: bool = Field(
+        default=True,
+        description="After an OOM, retry with fewer threads (OMP_NUM_THREADS, GOMAXPROCS, ...)",
+    )
+
+
+class ExecPriority(BaseModel):
+    """Lower priority for background work sharing a pod with interactive executions.
+
+    Everything here can only deprioritize the execution relative to the
+    pod's other executions.
+    """
+
+    oom_score_adj: int | None = Field(
+        default=None,
+        ge=0,
+        le=1000,
+        description="Raise the OOM score adjustment to at least this, so the OOM killer picks this execution first",
+    )
+    cpu_weight: int | None = Field(
+        default=None,
+        ge=1,
+        le=100,
+        description="CPU share under contention, relative to 100 for other executions (applied as nice)",
+    )
+    nice: int | None = Field(default=None, ge=0, le=19, description="Niceness; with cpu_weight the lower priority wins")
+    io_class: Literal["best-effort", "idle"] | None = Field(
+        default=None, description="I/O scheduling class; idle only gets disk time no one else wants"
+    )
+    io_priority: int | None = Field(
+        default=None, ge=4, le=7, description="Best-effort I/O level, 4 (default) to 7 (lowest)"
+    )
+    cpu_affinity: list[int] | None = Field(
+        default=None,
+        min_length=1,
+        max_length=1024,
+        description="CPUs the execution may run on; must be online in the pod",
+    )
+
+
+class ExecWorkspacePolicy(BaseModel):
+    """Policy of the named workspace an execution runs in."""
+
+    quota_bytes: int | None = Field(
+        default=None, ge=0, description="Largest size of the workspace's files; an execution leaving it bigger fails"
+    )
+    read_only: bool = Field(
+        default=False,
+        description="The execution runs in a scratch copy and its changes are discarded; files are mounted first. "
+        "Advisory: code that leaves the workspace's directory can still change its files",
+    )
+
+
 class ExecRequest(BaseModel):
     """Request model for /exec endpoint."""
 
@@ -45,6 +126,189 @@ class ExecRequest(BaseModel):
         default_factory=list,
         description="Array of file references to be used during execution",
     )
+    env: dict[str, str] | None = Field(
+        default=None,
+        description="Optional environment variables; values may reference ${SESSION_ID} and ${WORKSPACE}",
+    )
+    template_code: bool = Field(
+        default=False,
+        description="Also expand ${...} session templates in the code before execution",
+    )
+    scope: list[str] | None = Field(
+        default=None,
+        description="Workspace paths this execution touches; executions in a session with disjoint scopes "
+        "may run in parallel. Omit to lock the whole workspace (serialized).",
+    )
+    lock_token: str | None = Field(
+        default=None,
+        description="Token of a workspace lock held by the client that this execution may run inside",
+    )
+    retry: RetryPolicy | None = Field(
+        default=None,
+        description="Retry transient failures; only for code that is safe to run more than once",
+    )
+    priority: ExecPriority | None = Field(
+        default=None,
+        description="Lower OOM/CPU/I/O priority, so background jobs yield to interactive executions under pressure",
+    )
+    max_connections: int | None = Field(
+        default=None,
+        ge=1,
+        description="Outbound TCP connections the execution may have open at once; it's killed above this. "
+        "Can only tighten MAX_CONNECTIONS_PER_EXECUTION.",
+    )
+    dns_allowlist: list[str] | None = Field(
+        default=None,
+        max_length=100,
+        description="Names the execution may resolve (*.example.com for subdomains), within DNS_ALLOWLIST; "
+        "other lookups fail. Needs DNS_POLICY_UPSTREAM.",
+    )
+    sandbox_report: bool | None = Field(
+        default=None, description="Include the sandbox block in the response; omit to follow SANDBOX_REPORT"
+    )
+    collapse_output: bool | None = Field(
+        default=None,
+        description="Collapse repeated lines and progress updates in stdout/stderr (the collapse output filter); "
+        "omit to follow OUTPUT_FILTERS",
+    )
+    workspace: str | None = Field(
+        default=None,
+        pattern=r"^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$",
+        description="Named workspace (/mnt/data/<workspace>) on the pod to run in, with workspace_policy; "
+        "files are mounted into it and generated files collected from it",
+    )
+    workspace_policy: ExecWorkspacePolicy | None = Field(
+        default=None, description="Quota and read-only flag of the workspace; needs workspace"
+    )
+    elevation_grant: str | None = Field(
+        default=None,
+        max_length=4096,
+        description="Token of an operator-issued elevated grant (POST /admin/elevated-grants) allowing a longer "
+        "timeout, more memory or network access for this execution; each use is audited",
+    )
+    image: str | None = Field(
+        default=None,
+        max_length=128,
+        description="Catalog image to run in, e.g. python:3.11-datasci (GET /images); it must run lang. "
+        "Defaults to the API key's pinned image for the language, if any, else the language's image",
+    )
+
+
+class SecretFinding(BaseModel):
+    """A credential detected in execution output or a generated file."""
+
+    source: str = Field(..., description="Where it was found: stdout, stderr or file")
+    file: str | None = Field(default=None, description="Generated file name, for file findings")
+    kind: str = Field(..., description="Credential type, e.g. aws_access_key or private_key")
+    line: int | None = Field(default=None, description="1-based line of the match")
+    action: str = Field(..., description="flagged (returned as is), redacted (output) or blocked (file not stored)")
+
+
+class ExecError(BaseModel):
+    """Structured reason an execution failed, stable for machines."""
+
+    code: str = Field(..., description="Error code, e.g. RUNTIME_NOT_FOUND, SPAWN_FAILED or HOOK_FAILED")
+    wanted: str | None = Field(default=None, description="Interpreter or compiler the language needed")
+    available: list[str] = Field(default_factory=list, description="Similar binaries that are installed")
+    suggestion: str | None = Field(default=None, description="Suggested substitute for the missing binary")
+    hook: Literal["pre", "post"] | None = Field(default=None, description="Operator hook that failed (HOOK_FAILED)")
+    exit_code: int | None = Field(default=None, description="Exit status of the failed hook")
+    message: str | None = Field(default=None, description="Details, e.g. a failed hook's stderr or a timeout")
+
+
+class ExecTimings(BaseModel):
+    """Where the time of an execution went, in milliseconds.
+
+    A slow queue_wait_ms or spawn_ms points at an overloaded service; slow
+    first_output_ms or total_ms with quick ones before it points at the code.
+    Phases that weren't reached are null.
+    """
+
+    queue_wait_ms: int | None = Field(
+        default=None, description="Waiting for a pod: from the warm pool, or for a Job's pod to start"
+    )
+    spawn_ms: int | None = Field(
+        default=None, description="From the pod receiving the code to its process running, pre-exec hook included"
+    )
+    first_output_ms: int | None = Field(
+        default=None, description="From the process starting to its first output; null if it printed nothing"
+    )
+    total_ms: int | None = Field(default=None, description="Time in the pod, hooks included (excludes queue_wait_ms)")
+
+
+class CompileCacheStats(BaseModel):
+    """Lookups in the node's compiler cache during an execution (COMPILE_CACHE_ENABLED).
+
+    ccache counts compilations, go counts packages. Counts are null when the
+    compiler reported nothing, e.g. the image has no ccache or the execution
+    was killed.
+    """
+
+    tool: Literal["ccache", "go"] = Field(..., description="Cache the compiler went through")
+    hits: int | None = Field(default=None, description="Compilations or packages taken from the cache")
+    misses: int | None = Field(default=None, description="Compilations or packages that had to be compiled")
+
+
+class SandboxCgroup(BaseModel):
+    """cgroup limits the code ran under, as the kernel reports them; "max" means unlimited."""
+
+    memory: str | None = Field(default=None, description="memory.max in bytes")
+    cpu: str | None = Field(default=None, description='cpu.max: quota and period in microseconds, e.g. "100000 100000"')
+    pids: str | None = Field(default=None, description="pids.max")
+
+
+class SandboxNetwork(BaseModel):
+    """Network the code ran with."""
+
+    mode: Literal["isolated", "open"] | None = Field(
+        default=None, description="isolated when the pod runs without network-dependent features"
+    )
+    interfaces: list[str] | None = Field(default=None, description="Interfaces in the pod other than loopback")
+    dns_policy: bool | None = Field(default=None, description="Lookups went through the allowlisting resolver")
+
+
+class SandboxReport(BaseModel):
+    """Protections an execution actually ran under, read from the kernel in its pod.
+
+    Fields the pod couldn't read are null. mismatches lists where what was
+    applied is weaker than this deployment's configuration.
+    """
+
+    cgroup: SandboxCgroup = Field(default_factory=SandboxCgroup)
+    seccomp: Literal["disabled", "strict", "filter"] | None = Field(default=None, description="Seccomp mode")
+    seccomp_filters: int | None = Field(default=None, description="Seccomp filters installed")
+    no_new_privs: bool | None = Field(default=None, description="Whether the code could gain privileges by exec")
+    uid: int | None = Field(default=None, description="Effective UID the code ran as")
+    landlock: Literal["not_applied", "unavailable"] | None = Field(
+        default=None,
+        description="not_applied: the kernel supports Landlock but no ruleset restricts the code; unavailable: "
+        "the kernel doesn't",
+    )
+    network: SandboxNetwork = Field(default_factory=SandboxNetwork)
+    mismatches: list[str] = Field(default_factory=list, description="Protections weaker than configured")
+
+
+class TimeoutSuggestion(BaseModel):
+    """A timeout for code like this, from how long it took before."""
+
+    seconds: int = Field(..., description="Suggested timeout, at most MAX_EXECUTION_TIME")
+    basis: Literal["code", "session", "default"] = Field(
+        ...,
+        description="History it's based on: runs of the same code, the session's runs in the language, "
+        "or none yet (MAX_EXECUTION_TIME)",
+    )
+    samples: int = Field(default=0, description="Durations the suggestion is based on")
+    percentile_ms: int | None = Field(
+        default=None, description="The TIMEOUT_SUGGESTION_PERCENTILE duration of those samples"
+    )
+
+
+class TimeoutSuggestionRequest(BaseModel):
+    """Code to suggest a timeout for (POST /exec/timeout-suggestion)."""
+
+    code: str
+    lang: str
+    session_id: str | None = Field(default=None, description="Fall back to this session's history in the language")
 
 
 class ExecResponse(BaseModel):
@@ -61,3 +325,82 @@ class ExecResponse(BaseModel):
     )
     state_size: int | None = Field(default=None, description="Compressed state size in bytes")
     state_hash: str | None = Field(default=None, description="SHA256 hash for ETag/change detection")
+    secret_findings: list[SecretFinding] = Field(
+        default_factory=list,
+        description="Credentials detected in output or generated files (ARTIFACT_SECRET_SCAN)",
+    )
+    error: ExecError | None = Field(default=None, description="Why the execution failed, when known")
+    attempts: int = Field(default=1, description="Times the code was run (more than 1 when retried)")
+    retried_on: list[str] = Field(default_factory=list, description="Failure class of each retried attempt")
+    network_connections: int | None = Field(
+        default=None,
+        description="Outbound TCP connections the execution opened; absent when networking is disabled",
+    )
+    dns_denied: list[str] = Field(default_factory=list, description="Names the DNS policy refused to resolve")
+    timings: ExecTimings | None = Field(default=None, description="Latency breakdown of the (last) attempt")
+    suggested_timeout: TimeoutSuggestion | None = Field(
+        default=None, description="Timeout for running this code again, once there's enough history"
+    )
+    sandbox: SandboxReport | None = Field(
+        default=None, description="Protections the (last) attempt actually ran under (SANDBOX_REPORT)"
+    )
+    compile_cache: CompileCacheStats | None = Field(
+        default=None, description="Compiler cache hits and misses of the (last) attempt; null without a cache"
+    )
+
+
+class ExecPlanFile(BaseModel):
+    """A requested file and where the execution would find it."""
+
+    id: str
+    name: str
+    session_id: str
+    found: bool = Field(..., description="False if the file doesn't exist; it would be skipped")
+    path: str | None = Field(default=None, description="Path the file would be mounted at")
+    size: int | None = None
+
+
+class ExecPlanLimits(BaseModel):
+    """Limits the execution would run under."""
+
+    timeout_seconds: int
+    cpu_limit: str = Field(..., description="CPU limit of the pod's sidecar, which user code inherits")
+    memory_limit: str = Field(..., description="Memory limit of the pod's sidecar, which user code inherits")
+    max_output_files: int
+    max_file_size_mb: int
+    network_isolated: bool
+    max_connections: int | None = Field(default=None, description="Outbound connections allowed open at once")
+
+
+class ExecPlanRateLimit(BaseModel):
+    """Usage of one of the API key's rate limits."""
+
+    period: str
+    limit: int | None = None
+    used: int
+    remaining: int | None = None
+    exceeded: bool
+
+
+class ExecPlanResponse(BaseModel):
+    """What POST /exec would enforce for a request, without running it."""
+
+    allowed: bool = Field(..., description="True if the request passes validation and is within its rate limits")
+    violations: list[ErrorDetail] = Field(default_factory=list, description="Why the request would be rejected")
+    warnings: list[str] = Field(default_factory=list, description="Problems that wouldn't reject the request")
+    language: str
+    image: str | None = Field(default=None, description="Container image of the language's pods")
+    command: str | None = Field(default=None, description="Command the language's code is run with")
+    binary: str | None = Field(default=None, description="Interpreter or compiler the command starts")
+    user_id: int | None = Field(default=None, description="UID the code runs as")
+    working_directory: str
+    session_id: str | None = Field(default=None, description="Existing session that would be reused, if any")
+    scope: list[str] = Field(default_factory=list, description="Normalized workspace paths the execution locks")
+    env: dict[str, str] = Field(
+        default_factory=dict,
+        description="Environment passed to the code: context env, then session env (values redacted), then request env",
+    )
+    limits: ExecPlanLimits
+    priority: ExecPriority | None = None
+    files: list[ExecPlanFile] = Field(default_factory=list)
+    rate_limits: list[ExecPlanRateLimit] = Field(default_factory=list)
@@ -3,11 +3,15 @@
 # Standard library imports
 from datetime import UTC, datetime, timezone
 from enum import Enum
-from typing import List, Optional
+from typing import Any, List, Optional
 
 # Third-party imports
 from pydantic import BaseModel, Field, field_serializer
 
+# Local imports
+from .elevation import ElevatedGrant
+from .exec import ExecPriority, ExecWorkspacePolicy
+
 
 class ExecutionStatus(str, Enum):
     """Execution status enumeration."""
@@ -62,10 +66,23 @@ class CodeExecution(BaseModel):
     outputs: list[ExecutionOutput] = Field(default_factory=list)
     exit_code: int | None = Field(default=None)
     error_message: str | None = Field(default=None)
+    error: dict[str, Any] | None = Field(default=None, description="Structured failure, e.g. RUNTIME_NOT_FOUND")
 
     # Resource usage
     execution_time_ms: int | None = Field(default=None)
     memory_peak_mb: float | None = Field(default=None)
+    network_connections: int | None = Field(default=None, description="Outbound connections opened, if counted")
+    dns_denied: list[str] = Field(default_factory=list, description="Lookups refused by the DNS policy")
+    timings: dict[str, int | None] | None = Field(default=None, description="Milliseconds per phase (ExecTimings)")
+    provenance: dict[str, Any] | None = Field(
+        default=None, description="Command and interpreter binaries (path, sha256, version) that ran the code"
+    )
+    sandbox: dict[str, Any] | None = Field(default=None, description="Protections the code ran under (SandboxReport)")
+    compile_cache: dict[str, Any] | None = Field(
+        default=None, description="Compiler cache hits and misses (CompileCacheStats)"
+    )
+    image: str | None = Field(default=None, description="Image the code ran in")
+    image_name: str | None = Field(default=None, description="Catalog name of the image, if it came from the catalog")
 
     @field_serializer("created_at", "started_at", "completed_at")
     def serialize_datetime(self, value: datetime | None) -> str | None:
@@ -78,6 +95,16 @@ class ExecuteCodeRequest(BaseModel):
     code: str = Field(..., description="Code to execute", min_length=1)
     language: str = Field(default="py", description="Programming language")
     timeout: int | None = Field(default=None, description="Execution timeout in seconds")
+    env: dict[str, str] = Field(default_factory=dict, description="Extra environment variables")
+    template_code: bool = Field(default=False, description="Expand ${...} session templates in the code")
+    priority: ExecPriority | None = Field(default=None, description="Lower OOM/CPU priority of the execution")
+    max_connections: int | None = Field(default=None, description="Outbound connections allowed open at once")
+    dns_allowlist: list[str] | None = Field(default=None, description="Names the execution may resolve")
+    workspace: str | None = Field(default=None, description="Named workspace on the pod to run in")
+    workspace_policy: ExecWorkspacePolicy | None = Field(default=None, description="Quota and read-only flag")
+    elevation: ElevatedGrant | None = Field(default=None, description="Elevated grant the execution runs under")
+    image: str | None = Field(default=None, description="Catalog image reference to run in instead of the default")
+    image_name: str | None = Field(default=None, description="Catalog name of the image (ExecRequest.image)")
 
 
 class ExecuteCodeResponse(BaseModel):
@@ -2,7 +2,7 @@
 
 # Standard library imports
 from datetime import datetime
-from typing import List, Optional
+from typing import Any, List, Optional
 
 # Third-party imports
 from pydantic import BaseModel, Field, field_serializer
@@ -39,6 +39,7 @@ class FileInfo(BaseModel):
     content_type: str
     created_at: datetime
     path: str = Field(..., description="File path in the session")
+    sha256: str | None = Field(default=None, description="Digest of the content, recorded when it was stored")
 
     @field_serializer("created_at")
     def serialize_created_at(self, value: datetime) -> str:
@@ -73,3 +74,56 @@ class FileDeleteResponse(BaseModel):
     filename: str
     deleted: bool
     message: str | None = None
+
+
+class ArchiveExtractRequest(BaseModel):
+    """Request to unpack an archive stored in a session (POST /files/extract)."""
+
+    session_id: str
+    file_id: str = Field(..., description="The zip or tar archive to extract")
+    delete_archive: bool = Field(default=False, description="Delete the archive after a successful extraction")
+
+
+class ExtractedFile(BaseModel):
+    """A file unpacked from an archive."""
+
+    file_id: str
+    filename: str = Field(
+        ...,
+        description="Name in the workspace; directories are folded into it, and a numbered suffix "
+        "keeps names unique within the archive",
+    )
+    archive_path: str = Field(..., description="Path inside the archive")
+    size: int
+
+
+class ArchiveExtractResponse(BaseModel):
+    """Result of extracting an archive."""
+
+    session_id: str
+    files: list[ExtractedFile]
+    skipped: list[str] = Field(default_factory=list, description="Links and special files that weren't extracted")
+
+
+class FilePreviewColumn(BaseModel):
+    """A column of a previewed file."""
+
+    name: str
+    type: str = Field(
+        ...,
+        description="integer, number, boolean, string, date, datetime, time, decimal, binary, "
+        "array, object, nested, mixed or null (no values in the sample)",
+    )
+    nullable: bool = Field(default=False, description="Whether the sample (or Parquet schema) allows missing values")
+
+
+class FilePreviewResponse(BaseModel):
+    """Schema and first rows of a tabular file (GET /files/{session_id}/{file_id}/preview)."""
+
+    file_id: str
+    filename: str
+    format: str = Field(..., description="csv, tsv, jsonl or parquet")
+    columns: list[FilePreviewColumn]
+    rows: list[list[Any]] = Field(..., description="Typed values in column order; null for missing values")
+    truncated: bool = Field(..., description="Whether the file has more rows than the sample")
+    total_rows: int | None = Field(default=None, description="Row count, when the format records it (Parquet)")
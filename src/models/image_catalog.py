@@ -0,0 +1,51 @@
+"""Models for the image catalog (IMAGE_CATALOG) and the images each API key may use."""
+
+from datetime import datetime
+
+from pydantic import BaseModel, ConfigDict, Field
+
+
+class TenantImages(BaseModel):
+    """Catalog images an API key may run (PUT /admin/image-catalog/tenants/{api_key_hash})."""
+
+    model_config = ConfigDict(extra="forbid")
+
+    images: list[str] = Field(default_factory=list, max_length=100, description="Catalog image names it may ask for")
+    pins: dict[str, str] = Field(
+        default_factory=dict,
+        description="Image its executions of a language run in when they don't ask for one: {lang: image name}",
+    )
+
+
+class TenantImagesRecord(TenantImages):
+    """An API key's catalog images and who set them."""
+
+    model_config = ConfigDict(extra="ignore")
+
+    api_key_hash: str
+    updated_at: datetime
+    updated_by: str | None = Field(default=None, description="Client address of the admin who set them")
+
+
+class CatalogImageInfo(BaseModel):
+    """A catalog image the caller may run (GET /images)."""
+
+    name: str = Field(..., description="Name to pass as ExecRequest.image, e.g. python:3.11-datasci")
+    language: str
+    image: str = Field(..., description="Image reference the execution runs in")
+    description: str | None = None
+    pinned: bool = Field(default=False, description="Executions of the language run in it when they don't ask")
+
+
+class ImageListResponse(BaseModel):
+    """Catalog images available to the caller."""
+
+    images: list[CatalogImageInfo] = Field(default_factory=list)
+
+
+class ResolvedImage(BaseModel):
+    """The catalog image an execution runs in."""
+
+    name: str
+    image: str
+    pinned: bool = Field(default=False, description="Chosen by the API key's pin rather than the request")
@@ -0,0 +1,30 @@
+"""Models for language server queries (/lsp)."""
+
+from typing import Any, Literal
+
+from pydantic import BaseModel, Field
+
+from .exec import RequestFile
+
+
+class LspQueryRequest(BaseModel):
+    """A question for a language server about one file of a session's code."""
+
+    language: str = Field(..., description="Language whose pod runs the server (py, js, ts, go)")
+    method: Literal["diagnostics", "hover", "definition"]
+    path: str = Field(..., min_length=1, description="File to ask about, relative to the working directory")
+    line: int = Field(default=1, ge=1, description="1-based line, for hover and definition")
+    column: int = Field(default=1, ge=1, description="1-based column, for hover and definition")
+    code: str | None = Field(default=None, description="Content of the file at path, if it isn't one of files")
+    files: list[RequestFile] = Field(default_factory=list, description="Session files the server should see")
+
+
+class LspQueryResponse(BaseModel):
+    """The language server's answer."""
+
+    method: str
+    path: str
+    server: str = Field(..., description="Language server that answered (python, go, typescript)")
+    diagnostics: list[dict[str, Any]] | None = None
+    hover: dict[str, Any] | None = None
+    locations: list[dict[str, Any]] | None = None
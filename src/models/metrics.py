@@ -60,6 +60,9 @@ class DetailedExecutionMetrics:
     files_generated: int = 0
     output_size_bytes: int = 0
     state_size_bytes: int | None = None
+    image: str | None = None  # Runtime image the execution ran on
+    compile_cache_hits: int | None = None  # Compiler cache lookups; None without a compile cache
+    compile_cache_misses: int | None = None
     timestamp: datetime = field(default_factory=lambda: datetime.now(UTC))
 
     def to_dict(self) -> dict[str, Any]:
@@ -80,6 +83,9 @@ class DetailedExecutionMetrics:
             "files_generated": self.files_generated,
             "output_size_bytes": self.output_size_bytes,
             "state_size_bytes": self.state_size_bytes,
+            "image": self.image,
+            "compile_cache_hits": self.compile_cache_hits,
+            "compile_cache_misses": self.compile_cache_misses,
             "timestamp": self.timestamp.isoformat(),
         }
 
@@ -108,6 +114,9 @@ class DetailedExecutionMetrics:
             files_generated=data.get("files_generated", 0),
             output_size_bytes=data.get("output_size_bytes", 0),
             state_size_bytes=data.get("state_size_bytes"),
+            image=data.get("image"),
+            compile_cache_hits=data.get("compile_cache_hits"),
+            compile_cache_misses=data.get("compile_cache_misses"),
             timestamp=timestamp,
         )
 
@@ -126,6 +135,9 @@ class LanguageMetrics:
     avg_execution_time_ms: float = 0
     avg_memory_mb: float = 0
     error_rate: float = 0.0  # Percentage (0-100)
+    compile_cache_hits: int = 0
+    compile_cache_misses: int = 0
+    compile_cache_hit_rate: float | None = None  # Percentage (0-100); None without cache lookups
 
     def to_dict(self) -> dict[str, Any]:
         """Convert to dictionary."""
@@ -140,6 +152,9 @@ class LanguageMetrics:
             "avg_execution_time_ms": self.avg_execution_time_ms,
             "avg_memory_mb": self.avg_memory_mb,
             "error_rate": self.error_rate,
+            "compile_cache_hits": self.compile_cache_hits,
+            "compile_cache_misses": self.compile_cache_misses,
+            "compile_cache_hit_rate": self.compile_cache_hit_rate,
         }
 
 
@@ -0,0 +1,164 @@
+"""Models for the file tools that run in a pod against a session's files (see services/pod_tools.py)."""
+
+from typing import Any, Literal
+
+from pydantic import BaseModel, Field
+
+from .exec import FileRef, RequestFile, SecretFinding
+
+
+class RenderRequest(BaseModel):
+    """A LaTeX or Markdown document to render into a session file."""
+
+    language: str = Field(default="py", description="Language whose pod image has the toolchain")
+    format: Literal["latex", "markdown"]
+    output: Literal["pdf", "html"] = "pdf"
+    source: str | None = Field(default=None, description="Inline document; give this or path")
+    path: str | None = Field(default=None, description="Name of one of files to render; give this or source")
+    filename: str | None = Field(default=None, description="Name of the rendered file, derived from path if omitted")
+    timeout: int = Field(default=60, ge=1, le=300)
+    files: list[RequestFile] = Field(default_factory=list, description="Session files the document needs")
+    session_id: str | None = Field(
+        default=None, description="Session to store the rendered file in; defaults to the files' session"
+    )
+
+
+class RenderResponse(BaseModel):
+    """Result of rendering a document."""
+
+    session_id: str
+    exit_code: int
+    toolchain: str
+    file: FileRef | None = Field(default=None, description="The rendered file, once stored in the session")
+    log: str
+    execution_time_ms: int
+    secret_findings: list[SecretFinding] = Field(default_factory=list)
+
+
+class MediaRequest(BaseModel):
+    """An ffmpeg conversion of a session file into a new session file."""
+
+    language: str = Field(default="py", description="Language whose pod image has ffmpeg")
+    input: RequestFile = Field(..., description="Session file to process")
+    output: str = Field(..., min_length=1, description="Name of the result; its extension selects the format")
+    args: list[str] = Field(default_factory=list, description='Output options, e.g. ["-c:v", "libx264"]')
+    max_output_size: int | None = Field(default=None, ge=1, description="Bytes; the sidecar's limit if omitted")
+    timeout: int | None = Field(default=None, ge=1, description="Seconds; the sidecar's limit if omitted")
+    session_id: str | None = Field(
+        default=None, description="Session to store the result in; defaults to the input's session"
+    )
+
+
+class FileSearchMatch(BaseModel):
+    """A line of a session file that matched a search."""
+
+    file_id: str | None = Field(default=None, description="Session file the match is in")
+    path: str
+    line: int
+    column: int
+    text: str
+    before: list[str] = Field(default_factory=list, description="Context lines before the match")
+    after: list[str] = Field(default_factory=list, description="Context lines after the match")
+
+
+class FileSearchResponse(BaseModel):
+    """Matches of a search over a session's files."""
+
+    session_id: str
+    matches: list[FileSearchMatch]
+    files_scanned: int
+    truncated: bool = Field(..., description="Set when a bound on matches, files, size or time cut the search short")
+
+
+class FilePatchRequest(BaseModel):
+    """A unified diff to apply to a session's files."""
+
+    diff: str = Field(..., min_length=1)
+    strip: int = Field(default=1, ge=0, description="Leading path components to drop, as patch -p")
+    dry_run: bool = Field(default=False, description="Report what would change without storing anything")
+    language: str = Field(default="py", description="Language of the warm pod that applies the diff")
+
+
+class PatchedFile(BaseModel):
+    """A session file the diff created, modified or deleted."""
+
+    path: str
+    status: Literal["created", "modified", "deleted"]
+    hunks: int
+    file_id: str | None = Field(default=None, description="The stored file; None once deleted, or not yet created")
+
+
+class FilePatchResponse(BaseModel):
+    """What applying a diff changed."""
+
+    session_id: str
+    applied: bool = Field(..., description="False for a dry run")
+    files: list[PatchedFile]
+
+
+class FileSymbol(BaseModel):
+    """A definition in a source file, and the definitions nested in it."""
+
+    name: str
+    kind: str = Field(..., description="function, method, class, interface, struct, enum, trait, impl, module or type")
+    start_line: int
+    end_line: int
+    children: list["FileSymbol"] = Field(default_factory=list)
+
+
+class FileSymbolsResponse(BaseModel):
+    """Outline of a session's source file."""
+
+    session_id: str
+    file_id: str
+    path: str
+    language: str
+    parser: str = Field(..., description="tree-sitter, or ast for the Python fallback")
+    symbols: list[FileSymbol]
+
+
+class SyncBlock(BaseModel):
+    """Checksums of one block of a file."""
+
+    weak: int
+    strong: str
+
+
+class SyncSignature(BaseModel):
+    """Block signature of a file: what the side holding it already has."""
+
+    block_size: int = Field(default=8192, ge=512, le=1024 * 1024)
+    blocks: list[SyncBlock] = Field(default_factory=list)
+
+
+class SyncSignatureResponse(SyncSignature):
+    """Block signature of a stored session file."""
+
+    file_id: str
+    size: int
+    sha256: str
+
+
+class SyncDelta(BaseModel):
+    """Operations that turn the old copy of a file into the new one."""
+
+    block_size: int = Field(default=8192, ge=512, le=1024 * 1024)
+    ops: list[dict[str, Any]] = Field(
+        default_factory=list, description='{"copy": first_block, "count": n} or {"data": base64}'
+    )
+    sha256: str = Field(..., description="Of the new copy; a patch whose result differs is refused")
+
+
+class SyncDeltaResponse(SyncDelta):
+    """Delta from a client's copy to a stored session file."""
+
+    file_id: str
+    size: int
+
+
+class SyncPatchResponse(BaseModel):
+    """A stored session file after a delta was applied to it."""
+
+    file_id: str
+    size: int
+    sha256: str
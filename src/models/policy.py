@@ -0,0 +1,48 @@
+"""Models for policy bundle dry-run evaluation."""
+
+from typing import Literal
+
+from pydantic import BaseModel, Field
+
+
+class PolicyEvaluationRequest(BaseModel):
+    """An example execution to check against the policy in effect (POST /admin/policy/evaluate)."""
+
+    lang: str = Field(..., description="Language of the execution")
+    hosts: list[str] = Field(default_factory=list, max_length=100, description="Names the code would connect to")
+    dns_allowlist: list[str] | None = Field(
+        default=None, max_length=100, description="The request's own DNS allowlist, as in /exec"
+    )
+    max_connections: int | None = Field(default=None, ge=1, description="The request's connection limit, as in /exec")
+
+
+class PolicyDenial(BaseModel):
+    """A reason the example request would be rejected."""
+
+    field: str
+    code: str = Field(..., description="The error code /exec would return, e.g. language_not_allowed")
+    message: str
+
+
+class PolicyEvaluation(BaseModel):
+    """What the example request would be allowed to do."""
+
+    policy: dict[str, str] | None = Field(default=None, description="Name, version and digest of the policy bundle")
+    allowed: bool
+    denials: list[PolicyDenial] = Field(default_factory=list)
+    timeout_seconds: int
+    memory_mb: int
+    cpus: float
+    max_connections: int | None = Field(default=None, description="Outbound connections it may open; null: no limit")
+    max_output_files: int
+    max_file_size_mb: int
+    network: Literal["none", "wan", "unrestricted"] = Field(
+        ..., description="none (isolated), wan (public internet only) or unrestricted"
+    )
+    dns_allowlist: list[str] | None = Field(
+        default=None,
+        description="Names it may resolve (the request's list, within the operator's); null when DNS isn't filtered",
+    )
+    hosts: dict[str, bool] = Field(default_factory=dict, description="Whether each host could be reached")
+    output_filters: list[str] = Field(default_factory=list)
+    secret_scan: str
@@ -0,0 +1,81 @@
+"""Models for the hot configuration admin API."""
+
+from datetime import datetime
+from typing import Any, Literal
+
+from pydantic import BaseModel, ConfigDict, Field, model_validator
+
+# Settings operators are likely to try, and why they need a redeploy instead
+RESTART_REQUIRED = {
+    "enable_wan_access": "enforced by the network policies the chart deploys",
+    "dns_allowlist": "applied when pods are created",
+    "dns_policy_upstream": "applied when pods are created",
+    "fault_injection": "applied when pods are created",
+    "max_memory_mb": "applied when pods are created",
+    "max_cpus": "applied when pods are created",
+}
+
+
+class RuntimeConfigPatch(BaseModel):
+    """Policies that can be changed at runtime; omitted fields are left as they are.
+
+    Bounds match the corresponding settings. Network isolation applies to
+    pods created after the change (warm pods of the old mode are replaced);
+    other settings that only take effect when pods are created (WAN access,
+    DNS allowlist, resources) or at startup aren't accepted.
+    """
+
+    model_config = ConfigDict(extra="forbid")
+
+    max_concurrent_executions: int | None = Field(default=None, ge=1, le=50)
+    max_execution_time: int | None = Field(default=None, ge=1, le=600)
+    max_connections_per_execution: int | None = Field(default=None, ge=1, description="null removes the limit")
+    max_output_files: int | None = Field(default=None, ge=1, le=100)
+    session_lock_wait_seconds: int | None = Field(default=None, ge=0, le=600)
+    max_dag_steps: int | None = Field(default=None, ge=1, le=200)
+    dag_max_parallel_steps: int | None = Field(default=None, ge=1, le=50)
+    artifact_secret_scan: Literal["off", "flag", "block"] | None = None
+    queue_wait_alert_p95_ms: int | None = Field(default=None, ge=0)
+    enable_network_isolation: bool | None = Field(
+        default=None, description="Network mode of new pods; egress itself follows the deployed network policy"
+    )
+
+    @model_validator(mode="before")
+    @classmethod
+    def _reject_restart_required(cls, data: Any) -> Any:
+        if isinstance(data, dict):
+            for name in data:
+                if name in RESTART_REQUIRED:
+                    raise ValueError(f"{name} can't be changed at runtime: {RESTART_REQUIRED[name]}")
+        return data
+
+    @model_validator(mode="after")
+    def _only_limit_nullable(self) -> "RuntimeConfigPatch":
+        """Only MAX_CONNECTIONS_PER_EXECUTION can be unset; null is not a value for the others."""
+        for name in self.model_fields_set:
+            if name != "max_connections_per_execution" and getattr(self, name) is None:
+                raise ValueError(f"{name} cannot be null")
+        return self
+
+    def changes(self) -> dict[str, Any]:
+        """Fields present in the request, including an explicit null limit."""
+        return self.model_dump(exclude_unset=True)
+
+
+class ConfigChange(BaseModel):
+    """Audit record of one setting changed through the API."""
+
+    setting: str
+    old: Any = None
+    new: Any = None
+    changed_at: datetime
+    actor: str | None = Field(default=None, description="Client address the change came from")
+    reason: str | None = Field(default=None, description="X-Change-Reason header of the request")
+
+
+class RuntimeConfigResponse(BaseModel):
+    """Current values of the runtime-adjustable policies."""
+
+    config: dict[str, Any]
+    overrides: list[str] = Field(default_factory=list, description="Settings changed from their deployed values")
+    changes: list[ConfigChange] = Field(default_factory=list, description="Changes made by this request")
@@ -0,0 +1,38 @@
+"""Models for the execution slot scheduler's state (GET /admin/scheduler)."""
+
+from pydantic import BaseModel, Field
+
+
+class QueuedExecution(BaseModel):
+    """An execution waiting for a slot."""
+
+    session_id: str | None = None
+    waiting_ms: int
+
+
+class SessionQueueStats(BaseModel):
+    """How long a session's recent executions waited for a slot."""
+
+    session_id: str
+    executions: int = Field(..., description="Slot requests in the window (QUEUE_WAIT_HISTORY_SIZE)")
+    p50_wait_ms: int
+    p95_wait_ms: int
+    max_wait_ms: int
+    waiting: int = Field(default=0, description="Executions waiting for a slot now")
+    starved: bool = Field(default=False, description="p95 wait is above QUEUE_WAIT_ALERT_P95_MS")
+
+
+class SchedulerState(BaseModel):
+    """This replica's execution slots and the waits for them."""
+
+    limit: int = Field(..., description="MAX_CONCURRENT_EXECUTIONS")
+    running: int
+    waiting: int
+    queued: list[QueuedExecution] = Field(default_factory=list, description="Waiting executions, longest first")
+    p50_wait_ms: int = Field(default=0, description="Across every session's recent slot requests")
+    p95_wait_ms: int = 0
+    slot_requests: int = Field(default=0, description="Since the replica started")
+    slot_timeouts: int = Field(default=0, description="Executions refused because no slot freed up")
+    alert_p95_ms: int = Field(..., description="QUEUE_WAIT_ALERT_P95_MS (0 = alerts off)")
+    starvation_alerts: int = Field(default=0, description="Times a session's p95 wait went above the threshold")
+    sessions: list[SessionQueueStats] = Field(default_factory=list, description="Highest p95 wait first")
@@ -8,6 +8,9 @@ from typing import Any, Dict, Optional
 # Third-party imports
 from pydantic import BaseModel, Field, field_serializer
 
+# Shown in place of stored session env values, which typically hold credentials
+REDACTED_VALUE = "********"
+
 
 class SessionStatus(str, Enum):
     """Session status enumeration."""
@@ -16,6 +19,7 @@ class SessionStatus(str, Enum):
     IDLE = "idle"
     TERMINATED = "terminated"
     ERROR = "error"
+    QUARANTINED = "quarantined"
 
 
 class FileInfo(BaseModel):
@@ -49,6 +53,10 @@ class Session(BaseModel):
     memory_usage_mb: float | None = Field(default=None, description="Current memory usage in MB")
     cpu_usage_percent: float | None = Field(default=None, description="Current CPU usage percentage")
 
+    # Kernel restarts (state cleared, workspace kept)
+    restart_count: int = Field(default=0, description="Number of kernel restarts")
+    last_restart_at: datetime | None = Field(default=None, description="Last kernel restart timestamp")
+
     # Metadata
     metadata: dict[str, Any] = Field(default_factory=dict, description="Additional session metadata")
 
@@ -56,6 +64,10 @@ class Session(BaseModel):
     def serialize_datetime(self, value: datetime) -> str:
         return value.isoformat()
 
+    @field_serializer("last_restart_at")
+    def serialize_optional_datetime(self, value: datetime | None) -> str | None:
+        return value.isoformat() if value else None
+
 
 class SessionCreate(BaseModel):
     """Request model for creating a new session."""
@@ -63,6 +75,51 @@ class SessionCreate(BaseModel):
     metadata: dict[str, Any] = Field(default_factory=dict, description="Optional session metadata")
 
 
+class SessionEnvUpdate(BaseModel):
+    """Request model for replacing a session's environment variables."""
+
+    env: dict[str, str] = Field(..., description="Variables applied to every execution in the session")
+
+
+class SessionEnvResponse(BaseModel):
+    """Response model for a session's environment variables.
+
+    Values are always redacted; they typically hold credentials.
+    """
+
+    session_id: str
+    env: dict[str, str] = Field(default_factory=dict, description="Variable names with redacted values")
+
+
+class WorkspaceLockRequest(BaseModel):
+    """Request model for taking an advisory workspace lock."""
+
+    path: str = Field(..., description="Workspace path to lock; '/' locks the whole workspace")
+    ttl_seconds: int = Field(default=300, ge=1, description="Lock lifetime; expired locks are released")
+
+
+class WorkspaceLockInfo(BaseModel):
+    """A lock held in a session's workspace (token omitted)."""
+
+    paths: list[str]
+    owner: str = Field(
+        ...,
+        description="'client' for advisory locks, 'execution' for running executions, "
+        "'restart' during a kernel restart",
+    )
+    expires_at: datetime
+
+    @field_serializer("expires_at")
+    def serialize_datetime(self, value: datetime) -> str:
+        return value.isoformat()
+
+
+class WorkspaceLockResponse(WorkspaceLockInfo):
+    """Response model for an acquired lock."""
+
+    token: str = Field(..., description="Pass to DELETE .../locks/{token} or as lock_token on /exec")
+
+
 class SessionResponse(BaseModel):
     """Response model for session operations."""
 
@@ -70,8 +127,124 @@ class SessionResponse(BaseModel):
     status: SessionStatus
     created_at: datetime
     expires_at: datetime
+    restart_count: int = 0
+    last_restart_at: datetime | None = None
     message: str | None = None
 
     @field_serializer("created_at", "expires_at")
     def serialize_datetime(self, value: datetime) -> str:
         return value.isoformat()
+
+    @field_serializer("last_restart_at")
+    def serialize_optional_datetime(self, value: datetime | None) -> str | None:
+        return value.isoformat() if value else None
+
+
+class SessionRestartResponse(BaseModel):
+    """Response model for a kernel restart."""
+
+    session_id: str
+    restart_count: int = Field(..., description="Restarts so far, including this one")
+    interrupted: int = Field(default=0, description="Running executions interrupted by the restart")
+
+
+class QuarantineRequest(BaseModel):
+    """Request to quarantine a session (POST /admin/quarantine)."""
+
+    session_id: str = Field(..., min_length=1)
+    reason: str | None = Field(default=None, max_length=1000, description="Why, for the audit trail")
+
+
+class QuarantineRecord(BaseModel):
+    """A quarantined session: frozen, with its running executions killed and its data kept."""
+
+    session_id: str
+    reason: str | None = None
+    actor: str | None = Field(default=None, description="Client address of the admin who quarantined it")
+    quarantined_at: datetime
+    killed: int = Field(default=0, description="Running executions killed by this replica")
+    preserved_keys: int = Field(default=0, description="Redis keys of the session kept without an expiry")
+
+
+class SessionImportResult(BaseModel):
+    """What POST /admin/sessions/import restored from a session export."""
+
+    session_id: str
+    exported_at: datetime
+    files: int = Field(default=0, description="Workspace files restored")
+    state_bytes: int = Field(default=0, description="Size of the restored kernel checkpoint, 0 if it had none")
+    cells: int = Field(default=0, description="Cells restored to the history")
+    env: list[str] = Field(default_factory=list, description="Environment variables restored")
+    env_missing: list[str] = Field(
+        default_factory=list, description="Variables exported without values; set them with PUT /sessions/{id}/env"
+    )
+    packages: list[dict[str, Any]] = Field(
+        default_factory=list, description="Packages the session installed, to reinstall before running code"
+    )
+
+
+class VariableInfo(BaseModel):
+    """Summary of one variable in a session's persisted state."""
+
+    name: str
+    type: str = Field(..., description="Qualified type name, e.g. 'int' or 'pandas.core.frame.DataFrame'")
+    shape: list[int] | None = Field(default=None, description="Array/dataframe shape")
+    length: int | None = Field(default=None, description="len() for other sized values")
+    dtype: str | None = None
+    columns: list[str] | None = Field(default=None, description="Dataframe columns (first 50)")
+    size_bytes: int | None = Field(default=None, description="Approximate in-memory size")
+    preview: str = Field(default="", description="Truncated repr of the value")
+
+
+class SessionVariablesResponse(BaseModel):
+    """Response model for a session's variables."""
+
+    session_id: str
+    has_state: bool = Field(..., description="Whether the session has persisted state")
+    variables: list[VariableInfo] = Field(default_factory=list)
+    total: int = Field(default=0, description="Number of variables before the listing limit")
+
+
+class DataFrameExportResponse(BaseModel):
+    """Response model for a dataframe exported as JSON records."""
+
+    session_id: str
+    name: str
+    total_rows: int = Field(..., description="Rows in the full dataframe")
+    rows: int = Field(..., description="Rows returned")
+    columns: list[str]
+    dtypes: dict[str, str]
+    data: list[dict[str, Any]] = Field(default_factory=list, description="Rows as records")
+
+
+class CompletionRequest(BaseModel):
+    """Request model for code completion."""
+
+    code: str = Field(..., max_length=100_000, description="Code being edited")
+    cursor_pos: int | None = Field(default=None, ge=0, description="Cursor offset in code; defaults to the end")
+
+
+class CompletionMatch(BaseModel):
+    """One completion candidate."""
+
+    text: str = Field(..., description="Replaces code[cursor_start:cursor_end]")
+    type: str = Field(..., description="keyword, module, class, function, instance or unknown")
+
+
+class ObjectHelp(BaseModel):
+    """Signature and docstring of a resolved name."""
+
+    name: str
+    type: str
+    signature: str | None = None
+    doc: str | None = None
+
+
+class CompletionResponse(BaseModel):
+    """Response model for code completion."""
+
+    matches: list[CompletionMatch] = Field(default_factory=list)
+    cursor_start: int
+    cursor_end: int
+    signature: ObjectHelp | None = Field(default=None, description="The call the cursor is inside")
+    inspect: ObjectHelp | None = Field(default=None, description="The name under the cursor")
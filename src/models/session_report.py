@@ -0,0 +1,92 @@
+"""Models for the end-of-session report (GET /sessions/{id}/report)."""
+
+from datetime import datetime
+
+from pydantic import BaseModel, Field, field_serializer
+
+
+class SessionViolation(BaseModel):
+    """A policy an execution ran into: a limit it hit or a protection that didn't hold."""
+
+    kind: str = Field(
+        ...,
+        description="connection_limit_exceeded, dns_denied, secret_detected or sandbox_mismatch",
+    )
+    execution_id: str | None = None
+    at: datetime
+    detail: str = Field(default="", description="What happened, e.g. the denied names; never a secret's value")
+
+    @field_serializer("at")
+    def serialize_datetime(self, value: datetime) -> str:
+        return value.isoformat()
+
+
+class SessionReportExecutions(BaseModel):
+    """Executions run in the session."""
+
+    total: int = 0
+    by_status: dict[str, int] = Field(default_factory=dict, description="completed, failed, timeout, cancelled")
+    by_language: dict[str, int] = Field(default_factory=dict)
+    first_at: datetime | None = None
+    last_at: datetime | None = None
+
+    @field_serializer("first_at", "last_at")
+    def serialize_datetime(self, value: datetime | None) -> str | None:
+        return value.isoformat() if value else None
+
+
+class SessionReportResources(BaseModel):
+    """Resources the session's executions consumed."""
+
+    execution_time_ms: int = Field(default=0, description="Total time code ran")
+    memory_peak_mb: float | None = Field(default=None, description="Highest memory peak of any execution")
+    network_connections: int = Field(default=0, description="Outbound connections opened, where counted")
+    artifact_bytes: int = Field(default=0, description="Total size of the session's files")
+
+
+class SessionArtifact(BaseModel):
+    """A file the session held when the report was made."""
+
+    file_id: str
+    filename: str
+    path: str
+    size: int
+    content_type: str
+    sha256: str | None = Field(default=None, description="Digest of the content; null if it couldn't be read")
+    created_at: datetime
+
+    @field_serializer("created_at")
+    def serialize_datetime(self, value: datetime) -> str:
+        return value.isoformat()
+
+
+class SessionReport(BaseModel):
+    """A complete account of what happened in a session's sandbox.
+
+    Made when the session is terminated (ended_at and reason set), or on
+    request while it's still active.
+    """
+
+    session_id: str
+    entity_id: str | None = None
+    created_at: datetime | None = None
+    ended_at: datetime | None = Field(default=None, description="When the session was terminated; null while active")
+    reason: str | None = Field(default=None, description="Why it ended: deleted, expired or forced")
+    executions: SessionReportExecutions = Field(default_factory=SessionReportExecutions)
+    resources: SessionReportResources = Field(default_factory=SessionReportResources)
+    artifacts: list[SessionArtifact] = Field(default_factory=list)
+    violations: list[SessionViolation] = Field(default_factory=list, description="Oldest first")
+    violations_dropped: int = Field(default=0, description="Older violations left out (SESSION_REPORT_MAX_VIOLATIONS)")
+    archive_key: str | None = Field(default=None, description="Object storage key of the archived copy, if any")
+
+    @field_serializer("created_at", "ended_at")
+    def serialize_datetime(self, value: datetime | None) -> str | None:
+        return value.isoformat() if value else None
+
+
+class SessionTerminationResponse(BaseModel):
+    """Result of DELETE /sessions/{id}: the session's end-of-session report."""
+
+    session_id: str
+    terminated: bool
+    report: SessionReport | None = Field(default=None, description="Null when SESSION_REPORT_ENABLED is off")
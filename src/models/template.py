@@ -0,0 +1,49 @@
+"""Models for operator-defined execution templates (/templates)."""
+
+from typing import Any, Literal
+
+from pydantic import BaseModel, Field
+
+from .exec import RequestFile
+
+
+class TemplateParam(BaseModel):
+    """A template parameter and the values it accepts."""
+
+    type: Literal["string", "integer", "number", "boolean", "path"] = Field(
+        default="string", description="path: a file or directory inside the workspace"
+    )
+    description: str | None = None
+    required: bool = True
+    default: Any | None = Field(default=None, description="Used when the parameter is omitted")
+    choices: list[Any] | None = Field(default=None, description="Allowed values")
+    pattern: str | None = Field(default=None, description="Regular expression a string must fully match")
+    max_length: int | None = Field(default=256, ge=1, description="Longest accepted string")
+    minimum: float | None = None
+    maximum: float | None = None
+
+
+class ExecutionTemplate(BaseModel):
+    """A named, parameterized piece of code registered by the operator."""
+
+    name: str
+    description: str | None = None
+    lang: str = Field(..., description="Language the code runs in")
+    code: str = Field(..., description="Code with {{param}} placeholders")
+    params: dict[str, TemplateParam] = Field(default_factory=dict)
+
+
+class TemplateRunRequest(BaseModel):
+    """Invocation of a template (POST /templates/{name}/run)."""
+
+    params: dict[str, Any] = Field(default_factory=dict, description="Argument values by parameter name")
+    session_id: str | None = Field(default=None, description="Session to run in; a new one is created if omitted")
+    files: list[RequestFile] = Field(default_factory=list, description="Files to mount, as for /exec")
+    entity_id: str | None = Field(default=None, max_length=40, pattern=r"^[A-Za-z0-9_-]+$")
+    user_id: str | None = None
+
+
+class TemplateListResponse(BaseModel):
+    """Registered templates (GET /templates)."""
+
+    templates: list[ExecutionTemplate]
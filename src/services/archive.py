@@ -0,0 +1,185 @@
+"""Safe extraction of zip and tar archives.
+
+Unpacking with ``unzip`` or ``tar`` inside an execution trusts the archive.
+Here every member is checked first:
+
+- Zip slip: absolute paths, drive letters and ``..`` components reject the
+  whole archive, since they only appear in crafted archives
+- Links, devices and other special files are skipped; only regular files
+  are extracted
+- Decompression bombs: the number of entries, each file's size, the total
+  size and the ratio of extracted to archive size are all capped, and
+  sizes are enforced on the bytes actually read, not the sizes the archive
+  declares
+"""
+
+import io
+import posixpath
+import stat
+import tarfile
+import zipfile
+import zlib
+from dataclasses import dataclass, field
+
+READ_CHUNK = 64 * 1024
+
+# What corrupt or unsupported archives raise while being read
+_READ_ERRORS = (zipfile.BadZipFile, tarfile.TarError, EOFError, OSError, NotImplementedError, RuntimeError, zlib.error)
+
+
+class ArchiveError(ValueError):
+    """The archive can't be extracted.
+
+    ``code`` is ``invalid_archive`` (unreadable or unsupported),
+    ``unsafe_archive`` (path traversal) or ``archive_limit_exceeded``.
+    """
+
+    def __init__(self, message: str, code: str = "invalid_archive"):
+        super().__init__(message)
+        self.code = code
+
+
+@dataclass
+class ArchiveLimits:
+    max_entries: int
+    max_files: int
+    max_file_size: int
+    max_total_size: int
+    max_ratio: int
+
+
+@dataclass
+class ExtractedArchive:
+    files: list[tuple[str, bytes]] = field(default_factory=list)
+    skipped: list[str] = field(default_factory=list)
+
+
+def safe_member_path(name: str) -> str | None:
+    """Normalized relative path of a member, or None for directory entries.
+
+    Raises:
+        ArchiveError: If the path would land outside the extraction root
+    """
+    path = name.replace("\\", "/")
+    parts = path.split("/")
+    if path.startswith("/") or ".." in parts or (parts[0].endswith(":") and len(parts[0]) == 2):
+        raise ArchiveError(f"Unsafe path in archive: {name}", code="unsafe_archive")
+    normalized = posixpath.normpath(path)
+    if normalized in ("", ".") or path.endswith("/"):
+        return None
+    return normalized
+
+
+class _Budget:
+    def __init__(self, limits: ArchiveLimits, archive_size: int):
+        self.limits = limits
+        self.max_total = min(limits.max_total_size, max(archive_size, 1) * limits.max_ratio)
+        self.total = 0
+
+    def read(self, stream, name: str) -> bytes:
+        """Read a member, stopping as soon as it exceeds a limit."""
+        chunks = []
+        size = 0
+        while chunk := stream.read(READ_CHUNK):
+            size += len(chunk)
+            self.total += len(chunk)
+            if size > self.limits.max_file_size:
+                raise ArchiveError(
+                    f"{name} exceeds the maximum file size of {self.limits.max_file_size} bytes",
+                    code="archive_limit_exceeded",
+                )
+            if self.total > self.max_total:
+                raise ArchiveError(
+                    f"Archive expands to more than {self.max_total} bytes "
+                    f"(limit {self.limits.max_total_size} bytes, ratio {self.limits.max_ratio}:1)",
+                    code="archive_limit_exceeded",
+                )
+            chunks.append(chunk)
+        return b"".join(chunks)
+
+
+def _check_counts(entries: int, files: int, limits: ArchiveLimits) -> None:
+    if entries > limits.max_entries:
+        raise ArchiveError(f"Archive has more than {limits.max_entries} entries", code="archive_limit_exceeded")
+    if files > limits.max_files:
+        raise ArchiveError(f"Archive has more than {limits.max_files} files", code="archive_limit_exceeded")
+
+
+def _extract_zip(archive: zipfile.ZipFile, budget: _Budget, limits: ArchiveLimits) -> ExtractedArchive:
+    members = archive.infolist()
+    _check_counts(len(members), 0, limits)
+    result = ExtractedArchive()
+    for info in members:
+        path = safe_member_path(info.filename)
+        if path is None or info.is_dir():
+            continue
+        # Unix type bits; zero for archives made on other systems
+        if stat.S_IFMT(info.external_attr >> 16) not in (0, stat.S_IFREG):
+            result.skipped.append(path)
+            continue
+        if info.flag_bits & 0x1:
+            raise ArchiveError(f"{info.filename} is encrypted")
+        _check_counts(len(members), len(result.files) + 1, limits)
+        with archive.open(info) as stream:
+            result.files.append((path, budget.read(stream, path)))
+    return result
+
+
+def _extract_tar(archive: tarfile.TarFile, budget: _Budget, limits: ArchiveLimits) -> ExtractedArchive:
+    result = ExtractedArchive()
+    entries = 0
+    # Iterating (rather than getmembers()) stops early on archives with huge member lists
+    for member in archive:
+        entries += 1
+        _check_counts(entries, len(result.files), limits)
+        path = safe_member_path(member.name)
+        if path is None or member.isdir():
+            continue
+        if not member.isfile():
+            result.skipped.append(path)
+            continue
+        _check_counts(entries, len(result.files) + 1, limits)
+        stream = archive.extractfile(member)
+        result.files.append((path, budget.read(stream, path) if stream else b""))
+    return result
+
+
+def extract_archive(content: bytes, limits: ArchiveLimits) -> ExtractedArchive:
+    """Extract a zip or (optionally compressed) tar archive held in memory.
+
+    Raises:
+        ArchiveError: If the archive is unreadable, unsafe or over a limit
+    """
+    budget = _Budget(limits, len(content))
+    try:
+        if zipfile.is_zipfile(io.BytesIO(content)):
+            with zipfile.ZipFile(io.BytesIO(content)) as archive:
+                return _extract_zip(archive, budget, limits)
+        with tarfile.open(fileobj=io.BytesIO(content), mode="r:*") as archive:
+            return _extract_tar(archive, budget, limits)
+    except ArchiveError:
+        raise
+    except _READ_ERRORS as e:
+        raise ArchiveError(f"Not a readable zip or tar archive: {e}")
+
+
+def flat_name(path: str) -> str:
+    """Workspace name for an extracted member: directories become part of the name."""
+    return path.replace("/", "_")
+
+
+def unique_name(name: str, taken: set[str]) -> str:
+    """``name``, or ``<stem>-2<ext>`` (then ``-3`` and so on) if it's in ``taken``.
+
+    Different members can flatten to the same name (``a/b.txt`` and
+    ``a_b.txt``); without a suffix the later one would shadow the earlier one
+    in the workspace. The chosen name is added to ``taken``.
+    """
+    candidate = name
+    stem, ext = posixpath.splitext(name)
+    number = 2
+    while candidate in taken:
+        candidate = f"{stem}-{number}{ext}"
+        number += 1
+    taken.add(candidate)
+    return candidate
@@ -0,0 +1,158 @@
+"""Content type and basic metadata of generated files.
+
+Executions write files with arbitrary names, so the content type is sniffed
+from the leading bytes (falling back to the extension), and cheap metadata
+is read where it helps a client decide how to render the file: image
+dimensions, the number of columns of a CSV or Parquet file and the page
+count of a PDF. Nothing is decoded beyond headers and footers.
+"""
+
+import mimetypes
+import re
+import struct
+from pathlib import Path
+
+from .parquet import MAGIC as PARQUET_MAGIC
+from .parquet import ParquetError, read_parquet
+from .preview import SNIFF_BYTES, PreviewError, preview_csv
+
+DEFAULT_CONTENT_TYPE = "application/octet-stream"
+
+_SIGNATURES = [
+    (b"\x89PNG\r\n\x1a\n", "image/png"),
+    (b"\xff\xd8\xff", "image/jpeg"),
+    (b"GIF87a", "image/gif"),
+    (b"GIF89a", "image/gif"),
+    (b"%PDF-", "application/pdf"),
+    (b"\x1f\x8b", "application/gzip"),
+    (b"PK\x03\x04", "application/zip"),
+    (b"PAR1", "application/vnd.apache.parquet"),
+    (b"SQLite format 3\x00", "application/vnd.sqlite3"),
+]
+
+# Zip-based formats, which mimetypes doesn't know on every platform
+_ZIP_FORMATS = {
+    ".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+    ".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+    ".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+    ".odt": "application/vnd.oasis.opendocument.text",
+    ".ods": "application/vnd.oasis.opendocument.spreadsheet",
+    ".epub": "application/epub+zip",
+    ".jar": "application/java-archive",
+}
+
+_BMP_HEADER_SIZES = {size.to_bytes(4, "little") for size in (12, 40, 52, 56, 108, 124)}
+
+# JPEG start-of-frame markers (SOF0..SOF15 without DHT, JPG and DAC)
+_JPEG_SOF = set(range(0xC0, 0xD0)) - {0xC4, 0xC8, 0xCC}
+_PDF_PAGE = re.compile(rb"/Type\s*/Page(?![a-zA-Z])")
+_PDF_COUNT = re.compile(rb"/Type\s*/Pages\b[^>]*?/Count\s+(\d+)|/Count\s+(\d+)[^>]*?/Type\s*/Pages\b", re.S)
+
+
+def _is_bmp(content: bytes) -> bool:
+    # "BM" alone is too common a start for text; also check the info header's size
+    return content[:2] == b"BM" and content[14:18] in _BMP_HEADER_SIZES
+
+
+def sniff_content_type(filename: str, content: bytes) -> str:
+    """Content type from magic bytes, then the extension, then whether it's text."""
+    for signature, content_type in _SIGNATURES:
+        if content.startswith(signature):
+            # Office documents, jars, etc. are zips; keep the more specific type
+            if content_type == "application/zip":
+                return _ZIP_FORMATS.get(Path(filename).suffix.lower(), content_type)
+            return content_type
+    if content[:4] == b"RIFF" and content[8:12] == b"WEBP":
+        return "image/webp"
+    if _is_bmp(content):
+        return "image/bmp"
+
+    head = content[:1024].lstrip()
+    if head.startswith(b"<svg") or (head.startswith(b"<?xml") and b"<svg" in head):
+        return "image/svg+xml"
+    guessed = mimetypes.guess_type(filename)[0]
+    if guessed:
+        return guessed
+    try:
+        content[:SNIFF_BYTES].decode("utf-8")
+    except UnicodeDecodeError as e:
+        # A multi-byte character cut off at the end of the sample is still text
+        if e.start < len(content[:SNIFF_BYTES]) - 3:
+            return DEFAULT_CONTENT_TYPE
+    return "text/plain"
+
+
+def image_size(content: bytes) -> tuple[int, int] | None:
+    """Width and height of a PNG, JPEG, GIF, BMP or WebP image, from its header."""
+    try:
+        if content.startswith(b"\x89PNG\r\n\x1a\n"):
+            return struct.unpack(">II", content[16:24])
+        if content[:6] in (b"GIF87a", b"GIF89a"):
+            return struct.unpack("<HH", content[6:10])
+        if _is_bmp(content):
+            width, height = struct.unpack("<ii", content[18:26])
+            return width, abs(height)
+        if content[:4] == b"RIFF" and content[8:12] == b"WEBP":
+            chunk = content[12:16]
+            if chunk == b"VP8 ":
+                width, height = struct.unpack("<HH", content[26:30])
+                return width & 0x3FFF, height & 0x3FFF
+            if chunk == b"VP8L":
+                bits = int.from_bytes(content[21:25], "little")
+                return (bits & 0x3FFF) + 1, ((bits >> 14) & 0x3FFF) + 1
+            if chunk == b"VP8X":
+                return int.from_bytes(content[24:27], "little") + 1, int.from_bytes(content[27:30], "little") + 1
+            return None
+        if content.startswith(b"\xff\xd8"):
+            pos = 2
+            while pos + 9 < len(content):
+                if content[pos] != 0xFF:
+                    return None
+                marker = content[pos + 1]
+                if marker in _JPEG_SOF:
+                    height, width = struct.unpack(">HH", content[pos + 5 : pos + 9])
+                    return width, height
+                if marker == 0xFF:
+                    pos += 1
+                    continue
+                pos += 2 + struct.unpack(">H", content[pos + 2 : pos + 4])[0]
+    except struct.error:
+        return None
+    return None
+
+
+def pdf_page_count(content: bytes) -> int | None:
+    """Page count of a PDF whose page tree isn't compressed, else None."""
+    pages = len(_PDF_PAGE.findall(content))
+    if pages:
+        return pages
+    counts = [int(a or b) for a, b in _PDF_COUNT.findall(content)]
+    return max(counts) if counts else None
+
+
+def describe_artifact(filename: str, content: bytes) -> tuple[str, dict[str, int]]:
+    """Content type and metadata (width/height, columns/rows, pages) of a file."""
+    content_type = sniff_content_type(filename, content)
+    metadata: dict[str, int] = {}
+    if content_type.startswith("image/"):
+        size = image_size(content)
+        if size:
+            metadata["width"], metadata["height"] = size
+    elif content_type == "application/pdf":
+        pages = pdf_page_count(content)
+        if pages is not None:
+            metadata["pages"] = pages
+    elif content_type in ("text/csv", "text/tab-separated-values"):
+        try:
+            header = preview_csv(content[:SNIFF_BYTES], rows=0)
+            metadata["columns"] = len(header.columns)
+        except PreviewError:
+            pass
+    elif content.startswith(PARQUET_MAGIC):
+        try:
+            parquet = read_parquet(content, rows=0)
+            metadata["columns"] = len(parquet.columns)
+            metadata["rows"] = parquet.total_rows
+        except ParquetError:
+            pass
+    return content_type, metadata
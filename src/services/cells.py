@@ -0,0 +1,201 @@
+"""Cell history - the code cells executed in each session.
+
+Every execution is recorded as a numbered cell with its input, outputs
+(truncated for storage) and timing, so clients can show a session's
+history, re-run a cell, or export the session as a notebook.
+
+Cells live in one Redis list per session, oldest first, capped at
+SESSION_CELL_HISTORY_LIMIT entries. A counter next to it numbers the cells
+so numbers stay stable as old cells are trimmed. Both keys expire with the
+session.
+
+diff_cells() compares a cell with a modified re-run of it, for clients
+that iterate on code (POST /sessions/{id}/cells/{n}/diff).
+"""
+
+import difflib
+import hashlib
+import json
+from typing import Any
+
+import redis.asyncio as redis
+import structlog
+
+from ..config import settings
+from ..core.pool import redis_pool
+from ..models.cell import CellDiff, CellInfo, FileChanges, TextDiff, ValueChange
+from ..models.exec import FileRef
+
+logger = structlog.get_logger(__name__)
+
+
+def truncate_output(text: str, limit: int) -> tuple[str, bool]:
+    """Cut text to limit characters. Returns (text, truncated)."""
+    if len(text) <= limit:
+        return text, False
+    return text[:limit], True
+
+
+def diff_text(previous: str, current: str, name: str) -> TextDiff:
+    """Unified diff of one output stream between two runs."""
+    if previous == current:
+        return TextDiff(changed=False)
+    lines = difflib.unified_diff(
+        previous.splitlines(keepends=True),
+        current.splitlines(keepends=True),
+        fromfile=f"previous/{name}",
+        tofile=f"current/{name}",
+    )
+    # Lines without a trailing newline (end of output) still need one in the diff
+    return TextDiff(changed=True, diff="".join(line if line.endswith("\n") else line + "\n" for line in lines))
+
+
+async def file_digests(file_service: Any, session_id: str, refs: list[FileRef]) -> dict[str, str | None]:
+    """SHA-256 of each generated file by name; None when the content can't be read."""
+    digests: dict[str, str | None] = {}
+    for ref in refs:
+        try:
+            content = await file_service.get_file_content(session_id, ref.id)
+        except Exception as e:
+            logger.warning("Failed to read file for diff", file_id=ref.id, error=str(e))
+            content = None
+        digests[ref.name] = hashlib.sha256(content).hexdigest() if content is not None else None
+    return digests
+
+
+def diff_files(previous: dict[str, str | None], current: dict[str, str | None]) -> FileChanges:
+    """Compare generated files by name, then by content digest.
+
+    Files whose content couldn't be read on either side count as modified.
+    """
+    shared = sorted(previous.keys() & current.keys())
+    modified = [name for name in shared if previous[name] is None or previous[name] != current[name]]
+    return FileChanges(
+        added=sorted(current.keys() - previous.keys()),
+        removed=sorted(previous.keys() - current.keys()),
+        modified=modified,
+        unchanged=[name for name in shared if name not in modified],
+    )
+
+
+def _change(previous: Any, current: Any) -> ValueChange:
+    return ValueChange(previous=previous, current=current, changed=previous != current)
+
+
+def diff_cells(
+    previous: CellInfo,
+    current: CellInfo,
+    previous_files: dict[str, str | None],
+    current_files: dict[str, str | None],
+) -> CellDiff:
+    """Compare a cell with a re-run of it.
+
+    Both cells hold outputs as stored in the history (truncated to
+    SESSION_CELL_OUTPUT_MAX_CHARS), so long outputs are compared up to
+    that limit. File digests come from file_digests().
+    """
+    return CellDiff(
+        status=_change(previous.status, current.status),
+        exit_code=_change(previous.exit_code, current.exit_code),
+        duration_ms=_change(previous.duration_ms, current.duration_ms),
+        stdout=diff_text(previous.stdout, current.stdout, "stdout"),
+        stderr=diff_text(previous.stderr, current.stderr, "stderr"),
+        files=diff_files(previous_files, current_files),
+    )
+
+
+class CellHistoryService:
+    """Records and lists executed cells per session in Redis."""
+
+    KEY_PREFIX = "session:cells:"
+
+    def __init__(self, redis_client: redis.Redis | None = None):
+        """Initialize the cell history service.
+
+        Args:
+            redis_client: Optional Redis client, uses shared pool if not provided
+        """
+        self.redis = redis_client or redis_pool.get_client()
+
+    def _cells_key(self, session_id: str) -> str:
+        """Generate Redis key for a session's cell list."""
+        return f"{self.KEY_PREFIX}{session_id}"
+
+    def _counter_key(self, session_id: str) -> str:
+        """Generate Redis key for a session's cell counter."""
+        return f"{self.KEY_PREFIX}{session_id}:count"
+
+    async def record(self, session_id: str, cell: dict[str, Any]) -> CellInfo | None:
+        """Number and store a cell. Returns None when history is disabled.
+
+        Args:
+            session_id: Session the cell ran in
+            cell: CellInfo fields except cell_id; stdout/stderr are truncated here
+        """
+        limit = settings.session_cell_history_limit
+        if limit <= 0:
+            return None
+
+        stdout, stdout_cut = truncate_output(cell.pop("stdout", ""), settings.session_cell_output_max_chars)
+        stderr, stderr_cut = truncate_output(cell.pop("stderr", ""), settings.session_cell_output_max_chars)
+        cell_id = await self.redis.incr(self._counter_key(session_id))
+        info = CellInfo(
+            cell_id=cell_id, stdout=stdout, stderr=stderr, output_truncated=stdout_cut or stderr_cut, **cell
+        )
+
+        ttl = settings.get_session_ttl_minutes() * 60
+        cells_key = self._cells_key(session_id)
+        pipe = await self.redis.pipeline(transaction=True)
+        try:
+            pipe.rpush(cells_key, info.model_dump_json())
+            pipe.ltrim(cells_key, -limit, -1)
+            pipe.expire(cells_key, ttl)
+            pipe.expire(self._counter_key(session_id), ttl)
+            await pipe.execute()
+        finally:
+            await pipe.reset()
+
+        return info
+
+    async def restore(self, session_id: str, cells: list[CellInfo]) -> int:
+        """Store cells exported from another cluster, keeping their numbers.
+
+        New cells are numbered after the highest restored one. Returns how
+        many cells were kept (the most recent SESSION_CELL_HISTORY_LIMIT).
+        """
+        limit = settings.session_cell_history_limit
+        if limit <= 0 or not cells:
+            return 0
+
+        cells = sorted(cells, key=lambda c: c.cell_id)[-limit:]
+        ttl = settings.get_session_ttl_minutes() * 60
+        cells_key = self._cells_key(session_id)
+        pipe = await self.redis.pipeline(transaction=True)
+        try:
+            pipe.delete(cells_key)
+            pipe.rpush(cells_key, *(cell.model_dump_json() for cell in cells))
+            pipe.expire(cells_key, ttl)
+            pipe.set(self._counter_key(session_id), cells[-1].cell_id, ex=ttl)
+            await pipe.execute()
+        finally:
+            await pipe.reset()
+
+        return len(cells)
+
+    async def list_cells(self, session_id: str, limit: int | None = None) -> list[CellInfo]:
+        """List a session's cells, oldest first (the most recent `limit` if given)."""
+        start = -limit if limit else 0
+        cells = []
+        for raw in await self.redis.lrange(self._cells_key(session_id), start, -1):
+            try:
+                cells.append(CellInfo(**json.loads(raw)))
+            except (TypeError, ValueError) as e:
+                logger.warning("Skipping corrupt cell record", session_id=session_id[:12], error=str(e))
+        return cells
+
+    async def get_cell(self, session_id: str, cell_id: int) -> CellInfo | None:
+        """Get one cell by number, or None if it doesn't exist (or was trimmed)."""
+        for cell in await self.list_cells(session_id):
+            if cell.cell_id == cell_id:
+                return cell
+        return None
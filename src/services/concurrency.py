@@ -0,0 +1,193 @@
+"""Per-replica limit on executions running at once.
+
+Executions past MAX_CONCURRENT_EXECUTIONS wait for a running one to
+finish. The limit is read every time a slot is requested, so lowering it
+at runtime lets running executions finish and holds new ones back until
+the count drops below it; raising it wakes waiting executions at once.
+
+How long each session's executions waited for a slot is kept (the last
+QUEUE_WAIT_HISTORY_SIZE per session) so operators can tell whether the
+limit is too low and whether some sessions wait far longer than others.
+A session whose p95 wait goes above QUEUE_WAIT_ALERT_P95_MS is logged as
+starved once, until its waits come back under the threshold.
+"""
+
+import asyncio
+import time
+from collections import OrderedDict, deque
+from collections.abc import AsyncIterator, Callable
+from contextlib import asynccontextmanager
+
+import structlog
+
+from ..config import settings
+from ..models.errors import ServiceUnavailableError
+from ..models.scheduler import QueuedExecution, SchedulerState, SessionQueueStats
+
+logger = structlog.get_logger(__name__)
+
+# Sessions whose waits are kept; the least recently seen are dropped past this
+MAX_TRACKED_SESSIONS = 1000
+# Recent slot requests across all sessions the replica-wide percentiles cover
+REPLICA_WAIT_HISTORY = 1000
+
+
+def percentile(values: list[int], p: float) -> int:
+    """Nearest-rank percentile: the smallest value with at least p% of values at or below it."""
+    if not values:
+        return 0
+    ordered = sorted(values)
+    rank = max(1, -(-len(ordered) * p // 100))
+    return ordered[int(rank) - 1]
+
+
+class ExecutionGate:
+    """Counts running executions and holds back those past the limit."""
+
+    def __init__(self, limit: Callable[[], int] | None = None):
+        self._limit = limit or (lambda: settings.max_concurrent_executions)
+        self._condition = asyncio.Condition()
+        self.running = 0
+        self.waiting = 0
+        self.slot_requests = 0
+        self.slot_timeouts = 0
+        self.starvation_alerts = 0
+        self._queued: dict[object, tuple[str | None, float]] = {}
+        self._session_waits: OrderedDict[str, deque[int]] = OrderedDict()
+        self._replica_waits: deque[int] = deque(maxlen=REPLICA_WAIT_HISTORY)
+        self._starved: set[str] = set()
+
+    @asynccontextmanager
+    async def slot(self, timeout: float, session_id: str | None = None) -> AsyncIterator[None]:
+        """Hold one execution slot for the duration of the block.
+
+        Args:
+            timeout: Seconds to wait for a slot
+            session_id: Session the execution belongs to, for its wait statistics
+
+        Raises:
+            ServiceUnavailableError: If no slot frees up within ``timeout`` seconds
+        """
+        started = time.monotonic()
+        async with self._condition:
+            if self.running >= self._limit():
+                self.waiting += 1
+                ticket = object()
+                self._queued[ticket] = (session_id, started)
+                try:
+                    await asyncio.wait_for(self._condition.wait_for(lambda: self.running < self._limit()), timeout)
+                except TimeoutError:
+                    self.slot_timeouts += 1
+                    self._record_wait(session_id, started)
+                    logger.warning(
+                        "No execution slot freed up", session_id=session_id, running=self.running, limit=self._limit()
+                    )
+                    raise ServiceUnavailableError(
+                        service="Code Execution",
+                        message=f"Too many concurrent executions (max {self._limit()}), try again later",
+                    )
+                finally:
+                    self.waiting -= 1
+                    del self._queued[ticket]
+            self._record_wait(session_id, started)
+            self.running += 1
+        try:
+            yield
+        finally:
+            async with self._condition:
+                self.running -= 1
+                self._condition.notify_all()
+
+    async def limit_changed(self) -> None:
+        """Wake waiting executions after MAX_CONCURRENT_EXECUTIONS changed."""
+        async with self._condition:
+            self._condition.notify_all()
+
+    def _record_wait(self, session_id: str | None, started: float) -> None:
+        """Keep a slot request's wait and alert if its session is now starved."""
+        wait_ms = int((time.monotonic() - started) * 1000)
+        self.slot_requests += 1
+        self._replica_waits.append(wait_ms)
+        if not session_id:
+            return
+
+        waits = self._session_waits.pop(session_id, None)
+        if waits is None or waits.maxlen != settings.queue_wait_history_size:
+            waits = deque(waits or (), maxlen=settings.queue_wait_history_size)
+        waits.append(wait_ms)
+        self._session_waits[session_id] = waits
+        while len(self._session_waits) > MAX_TRACKED_SESSIONS:
+            dropped, _ = self._session_waits.popitem(last=False)
+            self._starved.discard(dropped)
+
+        threshold = settings.queue_wait_alert_p95_ms
+        if not threshold or len(waits) < settings.queue_wait_alert_min_samples:
+            return
+        p95 = percentile(list(waits), 95)
+        if p95 > threshold and session_id not in self._starved:
+            self._starved.add(session_id)
+            self.starvation_alerts += 1
+            logger.warning(
+                "Session starved of execution slots",
+                session_id=session_id,
+                p95_wait_ms=p95,
+                threshold_ms=threshold,
+                running=self.running,
+                waiting=self.waiting,
+                limit=self._limit(),
+            )
+        elif p95 <= threshold and session_id in self._starved:
+            self._starved.discard(session_id)
+            logger.info("Session no longer starved of execution slots", session_id=session_id, p95_wait_ms=p95)
+
+    def state(self, starved_only: bool = False, limit: int | None = None) -> SchedulerState:
+        """Slots in use, the executions waiting for one and per-session waits, highest p95 first."""
+        now = time.monotonic()
+        queued = sorted(
+            (
+                QueuedExecution(session_id=session_id, waiting_ms=int((now - started) * 1000))
+                for session_id, started in self._queued.values()
+            ),
+            key=lambda q: q.waiting_ms,
+            reverse=True,
+        )
+        waiting_by_session: dict[str, int] = {}
+        for q in queued:
+            if q.session_id:
+                waiting_by_session[q.session_id] = waiting_by_session.get(q.session_id, 0) + 1
+
+        sessions = []
+        for session_id, waits in self._session_waits.items():
+            if starved_only and session_id not in self._starved:
+                continue
+            values = list(waits)
+            sessions.append(
+                SessionQueueStats(
+                    session_id=session_id,
+                    executions=len(values),
+                    p50_wait_ms=percentile(values, 50),
+                    p95_wait_ms=percentile(values, 95),
+                    max_wait_ms=max(values),
+                    waiting=waiting_by_session.get(session_id, 0),
+                    starved=session_id in self._starved,
+                )
+            )
+        sessions.sort(key=lambda s: (s.p95_wait_ms, s.max_wait_ms), reverse=True)
+
+        replica_waits = list(self._replica_waits)
+        return SchedulerState(
+            limit=self._limit(),
+            running=self.running,
+            waiting=self.waiting,
+            queued=queued,
+            p50_wait_ms=percentile(replica_waits, 50),
+            p95_wait_ms=percentile(replica_waits, 95),
+            slot_requests=self.slot_requests,
+            slot_timeouts=self.slot_timeouts,
+            alert_p95_ms=settings.queue_wait_alert_p95_ms,
+            starvation_alerts=self.starvation_alerts,
+            sessions=sessions[:limit] if limit else sessions,
+        )
+
+
+execution_gate = ExecutionGate()
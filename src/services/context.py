@@ -0,0 +1,50 @@
+"""Deployment context provided to executions.
+
+Operators describe the sandbox once instead of in every prompt:
+CONTEXT_ENV is set in every execution (below the session's and the
+request's own variables), and CONTEXT_BANNER and CONTEXT_MOUNTS are
+returned by GET /context together with the languages, limits and network
+access of this deployment.
+"""
+
+from ..config import settings
+from ..config.languages import LANGUAGES
+from ..models.context import ContextLanguage, ContextLimits, ContextMount, ContextNetwork, ContextResponse
+from .workspace_lock import WORKING_DIR_PREFIX
+
+
+def execution_env(session_env: dict[str, str] | None, request_env: dict[str, str] | None) -> dict[str, str]:
+    """Environment for an execution: deployment context, then session env, then request env."""
+    return {**settings.context_env, **(session_env or {}), **(request_env or {})}
+
+
+def build_context() -> ContextResponse:
+    """Describe the execution environment of this deployment."""
+    return ContextResponse(
+        banner=settings.context_banner,
+        working_directory=WORKING_DIR_PREFIX,
+        env=dict(settings.context_env),
+        mounts=[ContextMount(path=path, description=desc) for path, desc in settings.context_mounts.items()],
+        languages=[
+            ContextLanguage(
+                code=code,
+                name=lang.name,
+                file_extension=lang.file_extension,
+                timeout_seconds=settings.get_execution_timeout(code),
+                memory_mb=settings.get_memory_limit(code),
+            )
+            for code, lang in LANGUAGES.items()
+        ],
+        limits=ContextLimits(
+            max_execution_time=settings.max_execution_time,
+            max_memory_mb=settings.max_memory_mb,
+            max_file_size_mb=settings.max_file_size_mb,
+            max_files_per_session=settings.max_files_per_session,
+            max_output_files=settings.max_output_files,
+        ),
+        network=ContextNetwork(
+            isolated=settings.enable_network_isolation,
+            wan_access=settings.enable_wan_access,
+        ),
+        state_persistence=settings.state_persistence_enabled,
+    )
@@ -0,0 +1,326 @@
+"""DAG runner - executes a graph of dependent steps in one session.
+
+Steps start as soon as their dependencies have completed, up to a
+parallelism limit. All steps share one session (so they see each other's
+workspace), and two steps only run at the same time when their declared
+scopes are disjoint; steps without a scope get the whole workspace.
+
+Dependents of a failed step are skipped. Independent branches keep running
+unless the request asks to fail fast.
+"""
+
+import asyncio
+import time
+from typing import Any
+
+import structlog
+
+from ..config import settings
+from ..config.languages import is_supported_language
+from ..models import CodeInterpreterException, ExecRequest, SessionCreate, ValidationError
+from ..models.dag import DagRequest, DagResponse, DagStep, DagStepResult
+from ..models.errors import ErrorDetail
+from .interfaces import SessionServiceInterface
+from .orchestrator import ExecutionOrchestrator
+from .workspace_lock import WORKSPACE_ROOT, normalize_scope_path, paths_overlap
+
+logger = structlog.get_logger(__name__)
+
+
+def order_steps(steps: list[DagStep]) -> list[str]:
+    """Validate the graph and return step names in dependency order.
+
+    Ties keep request order, so results read in the order they were given.
+
+    Raises:
+        ValidationError: On duplicate names, unknown dependencies or cycles
+    """
+    details = []
+    names = [step.name for step in steps]
+    duplicates = sorted({name for name in names if names.count(name) > 1})
+    if duplicates:
+        details.append(
+            ErrorDetail(field="steps", message=f"Duplicate step names: {', '.join(duplicates)}", code="duplicate_step")
+        )
+
+    known = set(names)
+    for step in steps:
+        unknown = sorted(set(step.depends_on) - known)
+        if unknown:
+            details.append(
+                ErrorDetail(
+                    field=f"steps.{step.name}.depends_on",
+                    message=f"Unknown steps: {', '.join(unknown)}",
+                    code="unknown_dependency",
+                )
+            )
+    if details:
+        raise ValidationError(message="Invalid DAG", details=details)
+
+    deps = {step.name: set(step.depends_on) for step in steps}
+    ordered: list[str] = []
+    while len(ordered) < len(names):
+        done = set(ordered)
+        ready = [name for name in names if name not in done and deps[name] <= done]
+        if not ready:
+            cyclic = sorted(set(names) - done)
+            raise ValidationError(
+                message="Invalid DAG",
+                details=[
+                    ErrorDetail(field="steps", message=f"Cycle between steps: {', '.join(cyclic)}", code="cycle")
+                ],
+            )
+        ordered.extend(ready)
+    return ordered
+
+
+def _path_weight(item: tuple[int, list[str]]) -> tuple[int, int]:
+    """Order paths by duration, then by length (ties in sub-millisecond steps)."""
+    return item[0], len(item[1])
+
+
+def critical_path(deps: dict[str, list[str]], durations: dict[str, int], order: list[str]) -> tuple[list[str], int]:
+    """Longest chain of dependent steps, weighted by duration.
+
+    Steps that never ran (no duration) are left out.
+    """
+    best: dict[str, tuple[int, list[str]]] = {}
+    for name in order:
+        if name not in durations:
+            continue
+        prior = max((best[dep] for dep in deps[name] if dep in best), key=_path_weight, default=(0, []))
+        best[name] = (prior[0] + durations[name], prior[1] + [name])
+    if not best:
+        return [], 0
+    total, path = max(best.values(), key=_path_weight)
+    return path, total
+
+
+def scopes_conflict(a: list[str], b: list[str]) -> bool:
+    """Whether two normalized scopes overlap."""
+    return any(paths_overlap(p, q) for p in a for q in b)
+
+
+class DagRunner:
+    """Runs a DAG of execution steps through the orchestrator."""
+
+    def __init__(
+        self,
+        orchestrator: ExecutionOrchestrator,
+        session_service: SessionServiceInterface,
+        max_parallel: int | None = None,
+    ):
+        self.orchestrator = orchestrator
+        self.session_service = session_service
+        self.max_parallel = max_parallel or settings.dag_max_parallel_steps
+
+    def _validate(self, request: DagRequest) -> tuple[list[str], dict[str, list[str]]]:
+        """Check the request and return (order, normalized scopes)."""
+        if len(request.steps) > settings.max_dag_steps:
+            raise ValidationError(
+                message=f"Too many steps: {len(request.steps)} (max {settings.max_dag_steps})",
+                details=[ErrorDetail(field="steps", message="Too many steps", code="too_many_steps")],
+            )
+
+        order = order_steps(request.steps)
+
+        details = []
+        scopes = {}
+        for step in request.steps:
+            if not is_supported_language(step.lang):
+                details.append(
+                    ErrorDetail(
+                        field=f"steps.{step.name}.lang",
+                        message=f"Language '{step.lang}' is not supported",
+                        code="unsupported_language",
+                    )
+                )
+            elif not settings.is_language_allowed(step.lang):
+                details.append(
+                    ErrorDetail(
+                        field=f"steps.{step.name}.lang",
+                        message=f"Language '{step.lang}' is not allowed by this deployment's policy",
+                        code="language_not_allowed",
+                    )
+                )
+            try:
+                scopes[step.name] = sorted({normalize_scope_path(p) for p in step.scope or [WORKSPACE_ROOT]})
+            except ValueError as e:
+                details.append(ErrorDetail(field=f"steps.{step.name}.scope", message=str(e), code="invalid_scope"))
+        if details:
+            raise ValidationError(message="Invalid DAG", details=details)
+
+        return order, scopes
+
+    async def _resolve_session(self, request: DagRequest) -> str:
+        """Use the requested session if it is active, otherwise create one for the run."""
+        if request.session_id:
+            existing = await self.session_service.get_session(request.session_id)
+            if existing and existing.status.value == "active":
+                return request.session_id
+
+        metadata = {}
+        if request.entity_id:
+            metadata["entity_id"] = request.entity_id
+        if request.user_id:
+            metadata["user_id"] = request.user_id
+        session = await self.session_service.create_session(SessionCreate(metadata=metadata))
+        return session.session_id
+
+    async def _run_step(
+        self,
+        step: DagStep,
+        request: DagRequest,
+        session_id: str,
+        started: float,
+        request_id: str,
+        **orchestrator_kwargs: Any,
+    ) -> DagStepResult:
+        """Execute one step and convert the outcome to a step result."""
+        exec_request = ExecRequest(
+            code=step.code,
+            lang=step.lang,
+            args=step.args,
+            files=step.files,
+            env=step.env,
+            scope=step.scope,
+            image=step.image,
+            session_id=session_id,
+            user_id=request.user_id,
+            entity_id=request.entity_id,
+        )
+        step_start = time.monotonic()
+        start_ms = int((step_start - started) * 1000)
+        try:
+            ctx = await self.orchestrator.run(exec_request, f"{request_id}:{step.name}", **orchestrator_kwargs)
+        except CodeInterpreterException as e:
+            error = e.message
+        except Exception as e:
+            logger.error("DAG step crashed", step=step.name, error=str(e))
+            error = str(e)
+        else:
+            execution = ctx.execution
+            exit_code = execution.exit_code if execution else None
+            ok = execution is not None and execution.status.value == "completed" and not exit_code
+            return DagStepResult(
+                name=step.name,
+                status="completed" if ok else "failed",
+                exit_code=exit_code,
+                stdout=ctx.response.stdout,
+                stderr=ctx.response.stderr,
+                files=ctx.response.files,
+                error=None if ok else (execution.error_message if execution else None),
+                start_ms=start_ms,
+                duration_ms=int((time.monotonic() - step_start) * 1000),
+            )
+
+        return DagStepResult(
+            name=step.name,
+            status="failed",
+            error=error,
+            start_ms=start_ms,
+            duration_ms=int((time.monotonic() - step_start) * 1000),
+        )
+
+    async def _schedule(
+        self,
+        request: DagRequest,
+        order: list[str],
+        scopes: dict[str, list[str]],
+        session_id: str,
+        started: float,
+        request_id: str,
+        orchestrator_kwargs: dict[str, Any],
+    ) -> dict[str, DagStepResult]:
+        """Start steps as their dependencies finish until every step has a result."""
+        steps = {step.name: step for step in request.steps}
+        results: dict[str, DagStepResult] = {}
+        running: dict[asyncio.Task, str] = {}
+        stopped = False
+
+        try:
+            while True:
+                # Settle steps that can no longer run (in dependency order, so skips cascade)
+                for name in order:
+                    if name in results or name in running.values():
+                        continue
+                    if stopped:
+                        results[name] = DagStepResult(name=name, status="skipped", error="Run stopped after a failure")
+                        continue
+                    deps = steps[name].depends_on
+                    failed = [dep for dep in deps if dep in results and results[dep].status != "completed"]
+                    if failed:
+                        results[name] = DagStepResult(
+                            name=name, status="skipped", error=f"Dependency did not complete: {', '.join(failed)}"
+                        )
+
+                # Start ready steps that don't overlap anything running
+                for name in order:
+                    if len(running) >= self.max_parallel:
+                        break
+                    if name in results or name in running.values():
+                        continue
+                    if not all(dep in results for dep in steps[name].depends_on):
+                        continue
+                    if any(scopes_conflict(scopes[name], scopes[other]) for other in running.values()):
+                        continue
+                    task = asyncio.create_task(
+                        self._run_step(steps[name], request, session_id, started, request_id, **orchestrator_kwargs)
+                    )
+                    running[task] = name
+
+                if not running:
+                    return results
+
+                done, _ = await asyncio.wait(running, return_when=asyncio.FIRST_COMPLETED)
+                for task in done:
+                    name = running.pop(task)
+                    results[name] = task.result()
+                    if results[name].status == "failed" and request.fail_fast:
+                        stopped = True
+        finally:
+            # Don't leave steps running if the request is cancelled
+            for task in running:
+                task.cancel()
+
+    async def run(self, request: DagRequest, request_id: str = "", **orchestrator_kwargs: Any) -> DagResponse:
+        """Run all steps and summarize the timings.
+
+        Args:
+            request: The DAG to run
+            request_id: Request ID for logging; steps log as "<request_id>:<step>"
+            orchestrator_kwargs: Passed through to ExecutionOrchestrator.run (api_key_hash, is_env_key)
+
+        Raises:
+            ValidationError: If the graph or a step is invalid (nothing is run)
+        """
+        order, scopes = self._validate(request)
+        session_id = await self._resolve_session(request)
+        started = time.monotonic()
+
+        results = await self._schedule(request, order, scopes, session_id, started, request_id, orchestrator_kwargs)
+
+        durations = {name: r.duration_ms for name, r in results.items() if r.duration_ms is not None}
+        deps = {step.name: step.depends_on for step in request.steps}
+        path, path_ms = critical_path(deps, durations, order)
+        ordered_results = [results[step.name] for step in request.steps]
+        status = "completed" if all(r.status == "completed" for r in ordered_results) else "failed"
+
+        logger.info(
+            "DAG run finished",
+            request_id=request_id,
+            session_id=session_id[:12],
+            steps=len(order),
+            status=status,
+            critical_path_ms=path_ms,
+        )
+
+        return DagResponse(
+            session_id=session_id,
+            status=status,
+            steps=ordered_results,
+            total_time_ms=int((time.monotonic() - started) * 1000),
+            serial_time_ms=sum(durations.values()),
+            critical_path=path,
+            critical_path_ms=path_ms,
+        )
@@ -0,0 +1,26 @@
+"""Shared, content-addressed datasets.
+
+Operators place each dataset on the nodes under
+``DATASETS_HOST_PATH/sha256/<hex>`` and register it by name (DATASETS).
+Pods mount it read-only at ``/mnt/datasets/<name>``, so every session
+reads the same copy and a name always refers to exactly the contents its
+digest identifies.
+"""
+
+from ..config import settings
+from ..models.dataset import DatasetInfo
+from .kubernetes.models import DATASETS_MOUNT_ROOT
+
+
+def list_datasets() -> list[DatasetInfo]:
+    """The configured datasets, sorted by name."""
+    return [
+        DatasetInfo(
+            name=name,
+            digest=dataset.digest,
+            path=f"{DATASETS_MOUNT_ROOT}/{name}",
+            description=dataset.description,
+            size_bytes=dataset.size_bytes,
+        )
+        for name, dataset in sorted(settings.datasets.items())
+    ]
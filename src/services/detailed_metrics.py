@@ -167,6 +167,11 @@ class DetailedMetricsService:
             lang_error_key = f"lang:{metrics.language}:errors"
             pipe.hincrby(redis_key, lang_error_key, 1)
 
+        if metrics.compile_cache_hits is not None:
+            pipe.hincrby(redis_key, f"lang:{metrics.language}:cache_hits", metrics.compile_cache_hits)
+        if metrics.compile_cache_misses is not None:
+            pipe.hincrby(redis_key, f"lang:{metrics.language}:cache_misses", metrics.compile_cache_misses)
+
         # Container pool tracking
         if metrics.container_source == "pool_hit":
             pipe.hincrby(redis_key, "pool_hits", 1)
@@ -338,6 +343,20 @@ class DetailedMetricsService:
                                 error_data.decode() if isinstance(error_data, bytes) else error_data
                             )
 
+                        hits_key = f"lang:{lang}:cache_hits"
+                        hits_data = data.get(hits_key.encode() if isinstance(key, bytes) else hits_key)
+                        if hits_data:
+                            language_stats[lang].compile_cache_hits += int(
+                                hits_data.decode() if isinstance(hits_data, bytes) else hits_data
+                            )
+
+                        misses_key = f"lang:{lang}:cache_misses"
+                        misses_data = data.get(misses_key.encode() if isinstance(key, bytes) else misses_key)
+                        if misses_data:
+                            language_stats[lang].compile_cache_misses += int(
+                                misses_data.decode() if isinstance(misses_data, bytes) else misses_data
+                            )
+
             except Exception as e:
                 logger.warning("Failed to get language stats for hour", hour=hour_key, error=str(e))
 
@@ -347,6 +366,9 @@ class DetailedMetricsService:
             if stats.execution_count > 0:
                 stats.avg_execution_time_ms = stats.total_execution_time_ms / stats.execution_count
                 stats.error_rate = (stats.failure_count / stats.execution_count) * 100
+            lookups = stats.compile_cache_hits + stats.compile_cache_misses
+            if lookups > 0:
+                stats.compile_cache_hit_rate = (stats.compile_cache_hits / lookups) * 100
 
         return language_stats
 
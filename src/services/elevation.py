@@ -0,0 +1,263 @@
+"""Time-boxed elevated executions.
+
+Some executions legitimately need more than the deployment's policy: a
+longer timeout, more memory, or network access. Instead of loosening the
+policy for everyone, an operator issues a grant (POST
+/admin/elevated-grants) allowing specific capabilities for a few minutes,
+optionally bound to one API key, one session and a number of uses. The
+grant comes back as a token signed with ELEVATED_GRANT_SECRET, which the
+client passes as ``elevation_grant`` on /exec.
+
+The signature makes tokens unforgeable; Redis holds what a signature
+can't: revocation (the grant's key is deleted) and the number of uses.
+Every issue, use, refused use and revocation is appended to a capped audit
+list and logged as a security event.
+"""
+
+import base64
+import hashlib
+import hmac
+import secrets
+from datetime import UTC, datetime, timedelta
+
+import redis.asyncio as redis
+import structlog
+
+from ..config import settings
+from ..core.pool import redis_pool
+from ..models.elevation import (
+    ElevatedGrant,
+    ElevatedGrantRequest,
+    ElevatedGrantResponse,
+    ElevationAuditEntry,
+)
+from ..models.errors import AuthorizationError, ServiceUnavailableError, ValidationError
+from ..utils.security import SecurityAudit
+
+logger = structlog.get_logger(__name__)
+
+# Audit entries kept, newest first
+AUDIT_MAX_ENTRIES = 1000
+
+# Counts a use in one step: -1 if the grant's key is gone (revoked or expired), -2 if it is used up.
+# A separate EXISTS and INCR would let INCR recreate an expired key without a TTL, for unlimited uses.
+REDEEM_SCRIPT = """
+local uses = redis.call('GET', KEYS[1])
+if not uses then
+    return -1
+end
+local max_uses = tonumber(ARGV[1])
+if max_uses > 0 and tonumber(uses) >= max_uses then
+    return -2
+end
+return redis.call('INCR', KEYS[1])
+"""
+
+
+def _b64encode(data: bytes) -> str:
+    return base64.urlsafe_b64encode(data).rstrip(b"=").decode()
+
+
+def _b64decode(data: str) -> bytes:
+    return base64.urlsafe_b64decode(data + "=" * (-len(data) % 4))
+
+
+class ElevationService:
+    """Issues, checks, revokes and audits elevated execution grants."""
+
+    KEY_PREFIX = "elevation:"
+
+    def __init__(self, redis_client: redis.Redis | None = None):
+        """Initialize the elevation service.
+
+        Args:
+            redis_client: Optional Redis client, uses shared pool if not provided
+        """
+        self.redis = redis_client or redis_pool.get_client()
+
+    def _grant_key(self, grant_id: str) -> str:
+        return f"{self.KEY_PREFIX}grant:{grant_id}"
+
+    @property
+    def _audit_key(self) -> str:
+        return f"{self.KEY_PREFIX}audit"
+
+    @staticmethod
+    def _secret() -> bytes:
+        if not settings.elevated_grant_secret:
+            raise ServiceUnavailableError(
+                service="Elevated executions", message="Elevated executions are disabled (ELEVATED_GRANT_SECRET)"
+            )
+        return settings.elevated_grant_secret.encode()
+
+    def _sign(self, payload: str) -> str:
+        return _b64encode(hmac.new(self._secret(), payload.encode(), hashlib.sha256).digest())
+
+    @staticmethod
+    def _ceiling_errors(data: ElevatedGrantRequest) -> list[str]:
+        capabilities = data.capabilities
+        errors = []
+        if data.ttl_minutes > settings.elevated_grant_max_minutes:
+            errors.append(f"ttl_minutes is above ELEVATED_GRANT_MAX_MINUTES ({settings.elevated_grant_max_minutes})")
+        if capabilities.network and not settings.elevated_allow_network:
+            errors.append("network access is not allowed (ELEVATED_ALLOW_NETWORK)")
+        if (capabilities.max_execution_time or 0) > settings.elevated_max_execution_time:
+            errors.append(
+                f"max_execution_time is above ELEVATED_MAX_EXECUTION_TIME ({settings.elevated_max_execution_time})"
+            )
+        if (capabilities.memory_mb or 0) > settings.elevated_max_memory_mb:
+            errors.append(f"memory_mb is above ELEVATED_MAX_MEMORY_MB ({settings.elevated_max_memory_mb})")
+        return errors
+
+    async def issue(self, data: ElevatedGrantRequest, actor: str | None = None) -> ElevatedGrantResponse:
+        """Issue a grant and its token; the token is not stored and can't be shown again."""
+        self._secret()
+        errors = self._ceiling_errors(data)
+        if errors:
+            raise ValidationError(message="Grant exceeds the elevated limits: " + "; ".join(errors))
+
+        now = datetime.now(UTC)
+        grant = ElevatedGrant(
+            grant_id=secrets.token_hex(8),
+            capabilities=data.capabilities,
+            reason=data.reason,
+            approved_by=actor,
+            issued_at=now,
+            expires_at=now + timedelta(minutes=data.ttl_minutes),
+            api_key_hash=data.api_key_hash,
+            session_id=data.session_id,
+            max_uses=data.max_uses,
+        )
+        payload = _b64encode(grant.model_dump_json().encode())
+        token = f"{payload}.{self._sign(payload)}"
+
+        # Holds the use count; deleting it revokes the grant
+        await self.redis.set(self._grant_key(grant.grant_id), 0, ex=data.ttl_minutes * 60)
+        await self._audit(
+            "issued",
+            grant,
+            actor,
+            detail=data.reason,
+            capabilities=grant.capabilities.model_dump(exclude_none=True),
+            expires_at=grant.expires_at.isoformat(),
+        )
+        return ElevatedGrantResponse(token=token, grant=grant)
+
+    async def redeem(self, token: str, api_key_hash: str | None, session_id: str | None) -> ElevatedGrant:
+        """Check a grant token for one execution and count the use.
+
+        Raises AuthorizationError if the token is forged, expired, revoked,
+        used up, bound to another key or session, or asks for more than the
+        elevated limits now allow.
+        """
+        grant = self._verify(token)
+        if not grant:
+            await self._deny(None, api_key_hash, session_id, "invalid token")
+        refusal = await self._refusal(grant, api_key_hash, session_id)
+        if refusal:
+            await self._deny(grant, api_key_hash, session_id, refusal)
+
+        uses = await self.redis.eval(REDEEM_SCRIPT, 1, self._grant_key(grant.grant_id), grant.max_uses or 0)
+        if uses == -1:
+            await self._deny(grant, api_key_hash, session_id, "revoked")
+        if uses == -2:
+            await self._deny(grant, api_key_hash, session_id, f"used up ({grant.max_uses} uses)")
+
+        await self._audit("used", grant, self._key_actor(api_key_hash), session_id=session_id, detail=f"use {uses}")
+        return grant
+
+    async def check(self, token: str, api_key_hash: str | None, session_id: str | None) -> ElevatedGrant:
+        """Check a grant token like redeem() without counting a use or auditing it (for dry runs)."""
+        grant = self._verify(token)
+        refusal = await self._refusal(grant, api_key_hash, session_id) if grant else "invalid token"
+        if refusal:
+            raise AuthorizationError(message=f"Elevation grant refused: {refusal}")
+        return grant
+
+    async def _refusal(self, grant: ElevatedGrant, api_key_hash: str | None, session_id: str | None) -> str | None:
+        """Why the grant can't be used for the execution, or None if it can."""
+        if grant.expires_at <= datetime.now(UTC):
+            return "expired"
+        if grant.api_key_hash and not hmac.compare_digest(grant.api_key_hash, api_key_hash or ""):
+            return "issued for another API key"
+        if grant.session_id and grant.session_id != session_id:
+            return "issued for another session"
+        errors = self._ceiling_errors(
+            ElevatedGrantRequest(capabilities=grant.capabilities, reason=grant.reason, ttl_minutes=1)
+        )
+        if errors:
+            # The limits were lowered after the grant was issued
+            return "; ".join(errors)
+        uses = await self.redis.get(self._grant_key(grant.grant_id))
+        if uses is None:
+            return "revoked"
+        if grant.max_uses and int(uses) >= grant.max_uses:
+            return f"used up ({grant.max_uses} uses)"
+        return None
+
+    def _verify(self, token: str) -> ElevatedGrant | None:
+        payload, _, signature = token.partition(".")
+        if not (payload and signature) or not hmac.compare_digest(signature, self._sign(payload)):
+            return None
+        try:
+            return ElevatedGrant.model_validate_json(_b64decode(payload))
+        except ValueError:
+            return None
+
+    async def revoke(self, grant_id: str, actor: str | None = None, reason: str | None = None) -> bool:
+        """Revoke a grant; returns False if it doesn't exist or has expired."""
+        if not await self.redis.delete(self._grant_key(grant_id)):
+            return False
+        await self._audit("revoked", None, actor, grant_id=grant_id, detail=reason)
+        return True
+
+    async def audit(self, limit: int = 100) -> list[ElevationAuditEntry]:
+        """Most recent grant events, newest first."""
+        entries = []
+        for raw in await self.redis.lrange(self._audit_key, 0, limit - 1):
+            try:
+                entries.append(ElevationAuditEntry.model_validate_json(raw))
+            except ValueError:
+                continue
+        return entries
+
+    @staticmethod
+    def _key_actor(api_key_hash: str | None) -> str | None:
+        return api_key_hash[:16] if api_key_hash else None
+
+    async def _deny(
+        self, grant: ElevatedGrant | None, api_key_hash: str | None, session_id: str | None, detail: str
+    ) -> None:
+        await self._audit("denied", grant, self._key_actor(api_key_hash), session_id=session_id, detail=detail)
+        raise AuthorizationError(message=f"Elevation grant refused: {detail}")
+
+    async def _audit(
+        self,
+        event: str,
+        grant: ElevatedGrant | None,
+        actor: str | None,
+        grant_id: str | None = None,
+        session_id: str | None = None,
+        detail: str | None = None,
+        **extra,
+    ) -> None:
+        entry = ElevationAuditEntry(
+            event=event,
+            grant_id=grant.grant_id if grant else grant_id,
+            at=datetime.now(UTC),
+            actor=actor,
+            session_id=session_id,
+            detail=detail,
+        )
+        try:
+            pipe = await self.redis.pipeline(transaction=True)
+            try:
+                pipe.lpush(self._audit_key, entry.model_dump_json())
+                pipe.ltrim(self._audit_key, 0, AUDIT_MAX_ENTRIES - 1)
+                await pipe.execute()
+            finally:
+                await pipe.reset()
+        except Exception as e:
+            # The security event below still records it
+            logger.warning("Failed to store elevation audit entry", event=event, error=str(e))
+        SecurityAudit.log_elevation(event, entry.grant_id, actor, session_id, detail, **extra)
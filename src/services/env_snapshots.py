@@ -0,0 +1,152 @@
+"""Environment snapshots - what each execution changed in its session.
+
+After every execution the session's environment is snapshotted: the
+environment variables it ran with (names and value digests, never the
+values), the packages its cells have installed so far (found in the code,
+as for session export) and its workspace files. Each snapshot is compared
+with the one before it and the change is kept, so clients and agents can
+see what a step did (GET /sessions/{id}/changes).
+
+Only the latest snapshot and the changes are stored: one Redis list per
+session, oldest first, capped at SESSION_CHANGE_HISTORY_LIMIT entries and
+expiring with the session.
+"""
+
+import hashlib
+import json
+from collections.abc import Iterable
+from datetime import UTC, datetime
+
+import redis.asyncio as redis
+import structlog
+
+from ..config import settings
+from ..core.pool import redis_pool
+from ..models.env_snapshot import ChangeSet, EnvironmentChanges, EnvironmentSnapshot, SnapshotFile
+from ..models.files import FileInfo
+
+logger = structlog.get_logger(__name__)
+
+
+def env_digests(env: dict[str, str]) -> dict[str, str]:
+    """Variable names and short digests of their values, so changes show without storing values."""
+    return {name: hashlib.sha256(value.encode()).hexdigest()[:16] for name, value in env.items()}
+
+
+def _diff(previous: dict, current: dict) -> ChangeSet:
+    return ChangeSet(
+        added=sorted(current.keys() - previous.keys()),
+        removed=sorted(previous.keys() - current.keys()),
+        modified=sorted(name for name in current.keys() & previous.keys() if current[name] != previous[name]),
+    )
+
+
+def diff_snapshots(previous: EnvironmentSnapshot | None, current: EnvironmentSnapshot) -> EnvironmentChanges:
+    """Changes from previous to current; everything counts as added without a previous snapshot."""
+    return EnvironmentChanges(
+        cell_id=current.cell_id,
+        execution_id=current.execution_id,
+        previous_cell_id=previous.cell_id if previous else None,
+        previous_execution_id=previous.execution_id if previous else None,
+        taken_at=current.taken_at,
+        env=_diff(previous.env if previous else {}, current.env),
+        packages=_diff(dict.fromkeys(previous.packages) if previous else {}, dict.fromkeys(current.packages)),
+        files=_diff(previous.files if previous else {}, current.files),
+    )
+
+
+class EnvironmentSnapshotService:
+    """Snapshots sessions' environments after each execution and keeps the changes in Redis."""
+
+    KEY_PREFIX = "session:changes:"
+
+    def __init__(self, redis_client: redis.Redis | None = None):
+        """Initialize the environment snapshot service.
+
+        Args:
+            redis_client: Optional Redis client, uses shared pool if not provided
+        """
+        self.redis = redis_client or redis_pool.get_client()
+
+    def _changes_key(self, session_id: str) -> str:
+        """Generate Redis key for a session's change list."""
+        return f"{self.KEY_PREFIX}{session_id}"
+
+    def _snapshot_key(self, session_id: str) -> str:
+        """Generate Redis key for a session's latest snapshot."""
+        return f"{self.KEY_PREFIX}{session_id}:latest"
+
+    async def latest(self, session_id: str) -> EnvironmentSnapshot | None:
+        """The snapshot taken after the session's last execution, or None."""
+        data = await self.redis.get(self._snapshot_key(session_id))
+        try:
+            return EnvironmentSnapshot.model_validate_json(data) if data else None
+        except ValueError as e:
+            logger.warning("Ignoring corrupt environment snapshot", session_id=session_id[:12], error=str(e))
+            return None
+
+    async def record(
+        self,
+        session_id: str,
+        env: dict[str, str],
+        files: list[FileInfo],
+        installed: Iterable[str] = (),
+        cell_id: int | None = None,
+        execution_id: str | None = None,
+    ) -> EnvironmentChanges | None:
+        """Snapshot the session after an execution and store what changed. Returns None when disabled.
+
+        Args:
+            session_id: Session the execution ran in
+            env: Environment variables the execution ran with
+            files: The session's workspace files after the execution
+            installed: Packages the execution installed, as manager:spec
+            cell_id: Cell recorded for the execution
+            execution_id: The execution
+        """
+        limit = settings.session_change_history_limit
+        if limit <= 0:
+            return None
+
+        previous = await self.latest(session_id)
+        packages = list(previous.packages) if previous else []
+        packages.extend(p for p in dict.fromkeys(installed) if p not in packages)
+        snapshot = EnvironmentSnapshot(
+            cell_id=cell_id,
+            execution_id=execution_id,
+            taken_at=datetime.now(UTC),
+            env=env_digests(env),
+            packages=packages,
+            files={f.path: SnapshotFile(file_id=f.file_id, size=f.size) for f in files},
+        )
+        changes = diff_snapshots(previous, snapshot)
+
+        ttl = settings.get_session_ttl_minutes() * 60
+        changes_key = self._changes_key(session_id)
+        pipe = await self.redis.pipeline(transaction=True)
+        try:
+            pipe.set(self._snapshot_key(session_id), snapshot.model_dump_json(), ex=ttl)
+            pipe.rpush(changes_key, changes.model_dump_json())
+            pipe.ltrim(changes_key, -limit, -1)
+            pipe.expire(changes_key, ttl)
+            await pipe.execute()
+        finally:
+            await pipe.reset()
+
+        return changes
+
+    async def list_changes(
+        self, session_id: str, limit: int | None = None, changed_only: bool = False
+    ) -> list[EnvironmentChanges]:
+        """A session's changes, oldest first (from the most recent `limit` executions if given)."""
+        start = -limit if limit else 0
+        changes = []
+        for raw in await self.redis.lrange(self._changes_key(session_id), start, -1):
+            try:
+                change = EnvironmentChanges(**json.loads(raw))
+            except (TypeError, ValueError) as e:
+                logger.warning("Skipping corrupt change record", session_id=session_id[:12], error=str(e))
+                continue
+            if not changed_only or change.env.changed or change.packages.changed or change.files.changed:
+                changes.append(change)
+        return changes
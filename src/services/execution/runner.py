@@ -21,7 +21,9 @@ from ...models import (
     OutputType,
 )
 from ...utils.id_generator import generate_execution_id
-from ..kubernetes import ExecutionResult, KubernetesManager, PodHandle
+from ...utils.security import SecurityAudit
+from ..kubernetes import ExecutionOptions, ExecutionResult, KubernetesManager, PodHandle
+from ..kubernetes.models import SPAWN_FAILED, Elevation
 from ..metrics import ExecutionMetrics, metrics_collector
 from .output import OutputProcessor
 
@@ -168,6 +170,21 @@ class CodeExecutionRunner:
                 files=files,
                 initial_state=initial_state,
                 capture_state=capture_state,
+                options=ExecutionOptions(
+                    env=request.env,
+                    context={"SESSION_ID": session_id},
+                    template_code=request.template_code,
+                    pre_hook=settings.exec_pre_hook,
+                    post_hook=settings.exec_post_hook,
+                    hook_timeout=settings.exec_hook_timeout_seconds,
+                    priority=request.priority.model_dump(exclude_none=True) if request.priority else {},
+                    max_connections=request.max_connections,
+                    dns_allowlist=request.dns_allowlist,
+                    workspace=request.workspace,
+                    workspace_policy=request.workspace_policy.model_dump() if request.workspace_policy else {},
+                ),
+                elevation=self._pod_elevation(request),
+                image=request.image,
             )
 
             end_time = datetime.now(UTC)
@@ -188,7 +205,7 @@ class CodeExecutionRunner:
                     kw in request.code for kw in ["open(", "savefig", "to_csv", "write(", ".save("]
                 )
                 if should_detect_files:
-                    generated_files = await self._detect_generated_files(handle)
+                    generated_files = await self._detect_generated_files(handle, request.workspace)
 
             mounted_filenames = self._get_mounted_filenames(files)
             filtered_files = self._filter_generated_files(generated_files, mounted_filenames)
@@ -214,8 +231,24 @@ class CodeExecutionRunner:
             execution.exit_code = result.exit_code
             execution.execution_time_ms = execution_time_ms
 
-            if execution.status == ExecutionStatus.FAILED:
+            if result.interrupted:
+                execution.status = ExecutionStatus.CANCELLED
+                execution.error_message = "Execution interrupted"
+            elif execution.status == ExecutionStatus.FAILED:
                 execution.error_message = OutputProcessor.format_error_message(result.exit_code, result.stderr)
+            execution.error = result.error
+            execution.network_connections = result.connections
+            execution.dns_denied = result.dns_denied or []
+            execution.timings = result.timings
+            execution.provenance = result.provenance
+            if result.provenance:
+                SecurityAudit.log_execution_provenance(session_id, execution_id, request.language, result.provenance)
+            execution.sandbox = result.sandbox
+            execution.compile_cache = result.compile_cache
+            execution.image = result.image
+            execution.image_name = request.image_name
+            if request.image_name:
+                SecurityAudit.log_execution_image(session_id, execution_id, request.image_name, result.image)
 
             logger.info(
                 f"Code execution {execution_id} completed: status={execution.status}, "
@@ -254,6 +287,7 @@ class CodeExecutionRunner:
             execution.status = ExecutionStatus.FAILED
             execution.completed_at = datetime.now(UTC)
             execution.error_message = str(e)
+            execution.error = {"code": SPAWN_FAILED}
             execution.execution_time_ms = (
                 int((datetime.now(UTC) - execution.started_at).total_seconds() * 1000) if execution.started_at else 0
             )
@@ -290,6 +324,18 @@ class CodeExecutionRunner:
 
         return outputs
 
+    @staticmethod
+    def _pod_elevation(request: ExecuteCodeRequest) -> Elevation | None:
+        """Pod-level capabilities of an elevated execution (timeout and connections are passed as usual)."""
+        if not request.elevation:
+            return None
+        capabilities = request.elevation.capabilities
+        return Elevation(
+            grant_id=request.elevation.grant_id,
+            network=capabilities.network,
+            memory_mb=capabilities.memory_mb,
+        )
+
     def _get_mounted_filenames(self, files: list[dict[str, Any]] | None) -> set:
         """Get set of mounted filenames for filtering."""
         mounted = set()
@@ -332,8 +378,8 @@ class CodeExecutionRunner:
         except Exception as e:
             logger.error("Failed to record execution metrics", error=str(e))
 
-    async def _detect_generated_files(self, handle: PodHandle) -> list[dict[str, Any]]:
-        """Detect files generated during execution via sidecar HTTP API."""
+    async def _detect_generated_files(self, handle: PodHandle, workspace: str | None = None) -> list[dict[str, Any]]:
+        """Detect files generated during execution (in the named workspace, if any) via sidecar HTTP API."""
         if not handle or not handle.pod_ip:
             return []
 
@@ -341,7 +387,8 @@ class CodeExecutionRunner:
             import httpx
 
             async with httpx.AsyncClient(timeout=10.0) as client:
-                response = await client.get(f"{handle.sidecar_url}/files")
+                params = {"workspace": workspace} if workspace else None
+                response = await client.get(f"{handle.sidecar_url}/files", params=params)
                 if response.status_code == 200:
                     data = response.json()
                     files = data.get("files", [])
@@ -355,7 +402,7 @@ class CodeExecutionRunner:
                             continue
                         generated_files.append(
                             {
-                                "path": f"/mnt/data/{name}",
+                                "path": f"/mnt/data/{workspace}/{name}" if workspace else f"/mnt/data/{name}",
                                 "size": f.get("size", 0),
                                 "mime_type": OutputProcessor.guess_mime_type(name),
                             }
@@ -410,6 +457,27 @@ class CodeExecutionRunner:
             logger.error(f"Failed to cancel execution {execution_id}: {e}")
             return False
 
+    async def interrupt_session(self, session_id: str) -> int:
+        """Deliver an interrupt (KeyboardInterrupt in Python) to a session's running executions.
+
+        Unlike cancel_execution the pod is not destroyed: the execution
+        returns what it printed so far and its files are still collected.
+        """
+        count = await self.kubernetes_manager.interrupt_session(session_id)
+        logger.info("Interrupted session executions", session_id=session_id[:12], count=count)
+        return count
+
+    async def kill_session(self, session_id: str) -> int:
+        """Kill (SIGKILL) a session's running executions, for quarantine.
+
+        The pods aren't destroyed here; each execution ends as usual once its
+        process is gone.
+        """
+        count = await self.kubernetes_manager.kill_session(session_id)
+        if count:
+            logger.warning("Killed session executions", session_id=session_id[:12], count=count)
+        return count
+
     async def list_executions(self, session_id: str, limit: int = 100) -> list[CodeExecution]:
         """List executions for a session."""
         executions = [e for e in self.active_executions.values() if e.session_id == session_id]
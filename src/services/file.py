@@ -9,12 +9,16 @@ from typing import Any, Dict, List, Optional, Tuple
 import redis.asyncio as redis
 import structlog
 from minio.error import S3Error
+from redis.exceptions import WatchError
 
 from ..config import settings
 from ..models import FileInfo, FileUploadRequest
+from ..models.errors import ResourceConflictError
+from ..utils.checksum import compute_checksum
 from ..utils.id_generator import generate_file_id
 
 # Local application imports
+from .artifact_metadata import sniff_content_type
 from .interfaces import FileServiceInterface
 
 logger = structlog.get_logger()
@@ -53,6 +57,10 @@ class FileService(FileServiceInterface):
         """Generate S3 object key for a file."""
         return f"sessions/{session_id}/{file_type}/{file_id}"
 
+    def _get_thumbnail_key(self, session_id: str, file_id: str, width: int) -> str:
+        """Generate S3 object key for a cached thumbnail."""
+        return f"sessions/{session_id}/thumbnails/{file_id}/{width}.png"
+
     def _get_file_metadata_key(self, session_id: str, file_id: str) -> str:
         """Generate Redis key for file metadata."""
         return f"files:{session_id}:{file_id}"
@@ -237,6 +245,7 @@ class FileService(FileServiceInterface):
             content_type=metadata["content_type"],
             created_at=metadata["created_at"],
             path=metadata["path"],
+            sha256=metadata.get("sha256"),
         )
 
     async def list_files(self, session_id: str) -> list[FileInfo]:
@@ -305,6 +314,7 @@ class FileService(FileServiceInterface):
 
             # Delete metadata from Redis
             await self._delete_file_metadata(session_id, file_id)
+            await self._delete_thumbnails(session_id, file_id)
 
             logger.info("Deleted file", session_id=session_id, file_id=file_id)
             return True
@@ -340,6 +350,7 @@ class FileService(FileServiceInterface):
                     prefixes = [
                         f"sessions/{session_id}/uploads/",
                         f"sessions/{session_id}/outputs/",
+                        f"sessions/{session_id}/thumbnails/",
                     ]
                     for prefix in prefixes:
                         # MinIO list_objects returns an iterator; use recursive to get all
@@ -406,11 +417,12 @@ class FileService(FileServiceInterface):
             metadata = {
                 "file_id": file_id,
                 "filename": filename,
-                "content_type": "application/octet-stream",
+                "content_type": sniff_content_type(filename, content),
                 "object_key": object_key,
                 "session_id": session_id,
                 "created_at": datetime.now(UTC).isoformat(),
                 "size": len(content),
+                "sha256": compute_checksum(content),
                 "path": f"/outputs/{filename}",
                 "type": "output",  # Mark as execution output
             }
@@ -464,6 +476,55 @@ class FileService(FileServiceInterface):
             )
             return None
 
+    async def get_thumbnail(self, session_id: str, file_id: str, width: int) -> bytes | None:
+        """Get a cached thumbnail, or None if it hasn't been generated."""
+        try:
+            loop = asyncio.get_event_loop()
+            response = await loop.run_in_executor(
+                None,
+                self.minio_client.get_object,
+                self.bucket_name,
+                self._get_thumbnail_key(session_id, file_id, width),
+            )
+            content = response.read()
+            response.close()
+            response.release_conn()
+            return content
+        except S3Error:
+            return None
+
+    async def store_thumbnail(self, session_id: str, file_id: str, width: int, content: bytes) -> None:
+        """Cache a thumbnail; failures are logged, since it can be regenerated."""
+        try:
+            import io
+
+            loop = asyncio.get_event_loop()
+            await loop.run_in_executor(
+                None,
+                self.minio_client.put_object,
+                self.bucket_name,
+                self._get_thumbnail_key(session_id, file_id, width),
+                io.BytesIO(content),
+                len(content),
+                "image/png",
+            )
+        except S3Error as e:
+            logger.warning("Failed to cache thumbnail", error=str(e), session_id=session_id, file_id=file_id)
+
+    async def _delete_thumbnails(self, session_id: str, file_id: str) -> None:
+        """Remove a file's cached thumbnails."""
+        prefix = f"sessions/{session_id}/thumbnails/{file_id}/"
+        try:
+            loop = asyncio.get_event_loop()
+            objects = await loop.run_in_executor(
+                None,
+                lambda: list(self.minio_client.list_objects(self.bucket_name, prefix=prefix, recursive=True)),
+            )
+            for obj in objects:
+                await loop.run_in_executor(None, self.minio_client.remove_object, self.bucket_name, obj.object_name)
+        except S3Error as e:
+            logger.warning("Failed to delete thumbnails", error=str(e), session_id=session_id, file_id=file_id)
+
     async def store_uploaded_file(
         self,
         session_id: str,
@@ -506,6 +567,7 @@ class FileService(FileServiceInterface):
                 "session_id": session_id,
                 "created_at": datetime.now(UTC).isoformat(),
                 "size": len(content),
+                "sha256": compute_checksum(content),
                 "path": f"/{filename}",
                 "type": "upload",  # Mark as uploaded file
             }
@@ -531,6 +593,202 @@ class FileService(FileServiceInterface):
             )
             raise
 
+    async def update_file_content(self, session_id: str, file_id: str, content: bytes) -> FileInfo | None:
+        """Replace a stored file's content, keeping its id, name and path.
+
+        Returns the updated file info, or None if the file doesn't exist.
+        """
+        metadata = await self._get_file_metadata(session_id, file_id)
+        if not metadata:
+            return None
+
+        try:
+            from io import BytesIO
+
+            loop = asyncio.get_event_loop()
+            await loop.run_in_executor(
+                None,
+                self.minio_client.put_object,
+                self.bucket_name,
+                metadata["object_key"],
+                BytesIO(content),
+                len(content),
+                metadata["content_type"],
+            )
+            await self.redis_client.hset(
+                self._get_file_metadata_key(session_id, file_id),
+                mapping={"size": len(content), "sha256": compute_checksum(content)},
+            )
+        except S3Error as e:
+            logger.error(
+                "Failed to update file",
+                error=str(e),
+                session_id=session_id,
+                file_id=file_id,
+            )
+            raise
+
+        # Thumbnails are cached per file id, so the old content's would be served
+        await self._delete_thumbnails(session_id, file_id)
+        logger.info("Updated file content", session_id=session_id, file_id=file_id, size=len(content))
+        return await self.get_file_info(session_id, file_id)
+
+    async def write_file_range(
+        self,
+        session_id: str,
+        file_id: str,
+        data: bytes,
+        offset: int | None = None,
+        expected_size: int | None = None,
+    ) -> FileInfo | None:
+        """Append ``data`` to a stored file, or write it at ``offset``, keeping its id.
+
+        An offset write replaces bytes inside the file or extends it at its
+        end. The new content goes to a new object, which a Redis transaction
+        watching the file's metadata then swaps in; a write that raced
+        another is redone on the other's result, so concurrent appends don't
+        drop each other's bytes. With ``expected_size`` the write only
+        happens if the file is that size, so a retried append can't land twice.
+
+        Returns the updated file info, or None if the file doesn't exist.
+
+        Raises:
+            ResourceConflictError: If the file isn't ``expected_size`` bytes, or
+                ``offset`` is past its end
+        """
+        from io import BytesIO
+
+        metadata_key = self._get_file_metadata_key(session_id, file_id)
+        loop = asyncio.get_event_loop()
+        pipe = await self.redis_client.pipeline(transaction=True)
+        try:
+            while True:
+                await pipe.watch(metadata_key)
+                metadata = await pipe.hgetall(metadata_key)
+                if not metadata:
+                    await pipe.unwatch()
+                    return None
+                size = int(metadata["size"])
+                if expected_size is not None and size != expected_size:
+                    await pipe.unwatch()
+                    raise ResourceConflictError(f"File is {size} bytes, expected {expected_size}")
+                if offset is not None and offset > size:
+                    await pipe.unwatch()
+                    raise ResourceConflictError(f"offset {offset} is past the end of the file ({size} bytes)")
+
+                response = await loop.run_in_executor(
+                    None, self.minio_client.get_object, self.bucket_name, metadata["object_key"]
+                )
+                try:
+                    current = response.read()
+                finally:
+                    response.close()
+                    response.release_conn()
+                if offset is None:
+                    content = current + data
+                else:
+                    content = current[:offset] + data + current[offset + len(data) :]
+
+                # A new object of its own, so neither readers nor a write racing this one see it half done
+                object_key = f"{metadata['object_key'].split('~')[0]}~{generate_file_id()}"
+                await loop.run_in_executor(
+                    None,
+                    self.minio_client.put_object,
+                    self.bucket_name,
+                    object_key,
+                    BytesIO(content),
+                    len(content),
+                    metadata["content_type"],
+                )
+                try:
+                    pipe.multi()
+                    pipe.hset(
+                        metadata_key,
+                        mapping={"object_key": object_key, "size": len(content), "sha256": compute_checksum(content)},
+                    )
+                    await pipe.execute()
+                except WatchError:
+                    # Another write changed the file since it was read; redo this one on its result
+                    await loop.run_in_executor(None, self.minio_client.remove_object, self.bucket_name, object_key)
+                    continue
+                await loop.run_in_executor(
+                    None, self.minio_client.remove_object, self.bucket_name, metadata["object_key"]
+                )
+                break
+        except S3Error as e:
+            logger.error("Failed to write file", error=str(e), session_id=session_id, file_id=file_id)
+            raise
+        finally:
+            await pipe.reset()
+
+        await self._delete_thumbnails(session_id, file_id)
+        logger.info(
+            "Wrote to file",
+            session_id=session_id,
+            file_id=file_id,
+            offset=offset,
+            written=len(data),
+            size=len(content),
+        )
+        return await self.get_file_info(session_id, file_id)
+
+    async def restore_file(
+        self,
+        session_id: str,
+        file_id: str,
+        filename: str,
+        path: str,
+        content: bytes,
+        content_type: str,
+        created_at: datetime,
+    ) -> None:
+        """Store a file exported from another cluster under its original id.
+
+        Keeping the id keeps cell history and clients' file references valid.
+        Files under /outputs/ are stored as execution outputs, the rest as uploads.
+        """
+        await self._ensure_bucket_exists()
+
+        file_type = "output" if path.startswith("/outputs/") else "upload"
+        object_key = self._get_file_key(session_id, file_id, f"{file_type}s")
+
+        try:
+            from io import BytesIO
+
+            loop = asyncio.get_event_loop()
+            await loop.run_in_executor(
+                None,
+                self.minio_client.put_object,
+                self.bucket_name,
+                object_key,
+                BytesIO(content),
+                len(content),
+                content_type,
+            )
+
+            metadata = {
+                "file_id": file_id,
+                "filename": filename,
+                "content_type": content_type,
+                "object_key": object_key,
+                "session_id": session_id,
+                "created_at": created_at.isoformat(),
+                "size": len(content),
+                "sha256": compute_checksum(content),
+                "path": path,
+                "type": file_type,
+            }
+            await self._store_file_metadata(session_id, file_id, metadata)
+
+        except S3Error as e:
+            logger.error(
+                "Failed to restore file",
+                error=str(e),
+                session_id=session_id,
+                file_id=file_id,
+            )
+            raise
+
     async def cleanup_orphan_objects(self, batch_limit: int = 1000) -> int:
         """Delete MinIO objects under sessions/ whose sessions are not active in Redis.
 
@@ -0,0 +1,214 @@
+"""Runtime changes to per-request policies.
+
+The admin API can change the settings in RuntimeConfigPatch without a
+restart: they are read on every request, so assigning the new value to
+``settings`` takes effect for the next execution while running ones (and
+their sessions and pods) carry on untouched. ENABLE_NETWORK_ISOLATION is
+read when pods are created, so a change is also handed to the Kubernetes
+manager, which drains the warm pods of the old mode.
+
+Changes are stored in a Redis hash so every replica applies them: each
+replica loads the hash at startup and again every
+CONFIG_REFRESH_INTERVAL_SECONDS, and settings missing from it go back to
+their deployed values. A change is stored before it is applied, so a
+replica never runs with a value the others won't pick up. Each change is
+also appended to a capped audit list and logged as a security event.
+"""
+
+import asyncio
+import json
+from collections.abc import Callable
+from datetime import UTC, datetime
+from typing import Any
+
+import redis.asyncio as redis
+import structlog
+from pydantic import ValidationError
+
+from ..config import settings
+from ..core.pool import redis_pool
+from ..models.runtime_config import ConfigChange, RuntimeConfigPatch
+from ..utils.security import SecurityAudit
+from .concurrency import execution_gate
+
+logger = structlog.get_logger(__name__)
+
+# Audit entries kept, newest first
+AUDIT_MAX_ENTRIES = 1000
+
+
+class HotConfigService:
+    """Applies, stores and audits runtime policy changes."""
+
+    KEY_PREFIX = "config:"
+
+    def __init__(self, redis_client: redis.Redis | None = None):
+        """Initialize the hot configuration service.
+
+        Args:
+            redis_client: Optional Redis client, uses shared pool if not provided
+        """
+        self.redis = redis_client or redis_pool.get_client()
+        # Values the replica started with, to tell which settings are overridden
+        self.deployed = self.current()
+        self._refresh_task: asyncio.Task | None = None
+        self._kubernetes_manager = None
+
+    def set_kubernetes_manager(self, manager) -> None:
+        """Set the Kubernetes manager that network mode changes are applied to."""
+        self._kubernetes_manager = manager
+
+    @property
+    def _overrides_key(self) -> str:
+        return f"{self.KEY_PREFIX}overrides"
+
+    @property
+    def _audit_key(self) -> str:
+        return f"{self.KEY_PREFIX}audit"
+
+    @staticmethod
+    def current() -> dict[str, Any]:
+        """Current values of the runtime-adjustable settings."""
+        return {name: getattr(settings, name) for name in RuntimeConfigPatch.model_fields}
+
+    def overridden(self) -> list[str]:
+        """Settings whose value differs from the one the replica started with."""
+        current = self.current()
+        return sorted(name for name, value in current.items() if value != self.deployed[name])
+
+    async def _apply_locally(self, values: dict[str, Any]) -> dict[str, tuple[Any, Any]]:
+        """Assign values to settings; returns the (old, new) pairs that changed."""
+        changed = {}
+        for name, value in values.items():
+            old = getattr(settings, name)
+            if old != value:
+                setattr(settings, name, value)
+                changed[name] = (old, value)
+        if "max_concurrent_executions" in changed:
+            await execution_gate.limit_changed()
+        if "enable_network_isolation" in changed and self._kubernetes_manager:
+            await self._kubernetes_manager.set_network_isolated(settings.enable_network_isolation)
+        return changed
+
+    async def _store(
+        self, changes: list[ConfigChange], write: Callable[[Any], Any], actor: str | None, reason: str | None
+    ) -> None:
+        """Write the overrides with ``write`` and audit the changes, in one transaction."""
+        pipe = await self.redis.pipeline(transaction=True)
+        try:
+            write(pipe)
+            for change in changes:
+                pipe.lpush(self._audit_key, change.model_dump_json())
+            pipe.ltrim(self._audit_key, 0, AUDIT_MAX_ENTRIES - 1)
+            await pipe.execute()
+        finally:
+            await pipe.reset()
+        for change in changes:
+            SecurityAudit.log_config_change(change.setting, change.old, change.new, actor, reason)
+
+    @staticmethod
+    def _changes(values: dict[str, Any], actor: str | None, reason: str | None) -> list[ConfigChange]:
+        """Audit records of the values that differ from the current settings."""
+        now = datetime.now(UTC)
+        return [
+            ConfigChange(setting=name, old=old, new=value, changed_at=now, actor=actor, reason=reason)
+            for name, value in values.items()
+            if (old := getattr(settings, name)) != value
+        ]
+
+    async def update(
+        self, patch: RuntimeConfigPatch, actor: str | None = None, reason: str | None = None
+    ) -> list[ConfigChange]:
+        """Store a patch for every replica, then apply it here; returns what changed."""
+        changes = self._changes(patch.changes(), actor, reason)
+        if not changes:
+            return []
+
+        overrides = {c.setting: json.dumps(c.new) for c in changes}
+        await self._store(changes, lambda pipe: pipe.hset(self._overrides_key, mapping=overrides), actor, reason)
+        await self._apply_locally({c.setting: c.new for c in changes})
+        return changes
+
+    async def reset(
+        self, names: list[str] | None = None, actor: str | None = None, reason: str | None = None
+    ) -> list[ConfigChange]:
+        """Drop the overrides of ``names`` (all settings if None) so they go back to their deployed values.
+
+        Raises:
+            ValueError: If a name isn't a runtime-adjustable setting
+        """
+        names = sorted(set(names)) if names else sorted(RuntimeConfigPatch.model_fields)
+        unknown = [name for name in names if name not in RuntimeConfigPatch.model_fields]
+        if unknown:
+            raise ValueError(f"Not runtime-adjustable: {', '.join(unknown)}")
+
+        # Other replicas may still apply an override this one has already dropped, so always delete
+        changes = self._changes({name: self.deployed[name] for name in names}, actor, reason)
+        await self._store(changes, lambda pipe: pipe.hdel(self._overrides_key, *names), actor, reason)
+        await self._apply_locally({c.setting: c.new for c in changes})
+        return changes
+
+    async def load(self) -> int:
+        """Apply the stored overrides to this replica; returns how many settings changed."""
+        raw = await self.redis.hgetall(self._overrides_key)
+        values = {}
+        for name, value in raw.items():
+            try:
+                values[name] = json.loads(value)
+            except (TypeError, ValueError):
+                logger.warning("Ignoring unreadable config override", setting=name)
+        try:
+            patch = RuntimeConfigPatch(**values)
+        except ValidationError as e:
+            # Written by an older or newer version; keep what this version accepts
+            logger.warning("Ignoring invalid config overrides", error=str(e))
+            valid = {}
+            for name, value in values.items():
+                try:
+                    valid.update(RuntimeConfigPatch(**{name: value}).changes())
+                except ValidationError:
+                    continue
+            patch = RuntimeConfigPatch(**valid)
+
+        # Settings whose override was reset elsewhere go back to their deployed values
+        changed = await self._apply_locally({**self.deployed, **patch.changes()})
+        if changed:
+            logger.info("Applied config overrides", settings=sorted(changed))
+        return len(changed)
+
+    async def audit(self, limit: int = 100) -> list[ConfigChange]:
+        """Most recent changes, newest first."""
+        entries = []
+        for raw in await self.redis.lrange(self._audit_key, 0, limit - 1):
+            try:
+                entries.append(ConfigChange.model_validate_json(raw))
+            except ValueError:
+                continue
+        return entries
+
+    async def start_refresh_task(self) -> None:
+        """Load the stored overrides, then keep reloading them in the background."""
+        try:
+            await self.load()
+        except Exception as e:
+            logger.warning("Failed to load config overrides", error=str(e))
+        interval = settings.config_refresh_interval_seconds
+        if interval > 0 and (self._refresh_task is None or self._refresh_task.done()):
+            self._refresh_task = asyncio.create_task(self._refresh_loop(interval))
+
+    async def stop_refresh_task(self) -> None:
+        """Stop the background refresh task."""
+        if self._refresh_task and not self._refresh_task.done():
+            self._refresh_task.cancel()
+            try:
+                await self._refresh_task
+            except asyncio.CancelledError:
+                pass
+
+    async def _refresh_loop(self, interval: int) -> None:
+        while True:
+            await asyncio.sleep(interval)
+            try:
+                await self.load()
+            except Exception as e:
+                logger.warning("Failed to refresh config overrides", error=str(e))
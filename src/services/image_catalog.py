@@ -0,0 +1,160 @@
+"""Per-API-key image catalog.
+
+IMAGE_CATALOG names the images executions may ask for
+(``ExecRequest.image``, e.g. ``python:3.11-datasci``); which of them an
+API key ("tenant") may use is kept in Redis and set at runtime through
+PUT /admin/image-catalog/tenants/{api_key_hash}, together with pins: the
+image a key's executions of a language run in when they don't ask for
+one. The API key from the environment may use every catalog image.
+
+An execution in a catalog image other than its language's pool image
+runs in its own Job pod. The image it ran in is recorded on the
+execution and its cell and logged as an ``execution_image`` security
+event.
+"""
+
+from datetime import UTC, datetime
+
+import redis.asyncio as redis
+import structlog
+
+from ..config import settings
+from ..core.pool import redis_pool
+from ..models.image_catalog import CatalogImageInfo, ResolvedImage, TenantImages, TenantImagesRecord
+
+logger = structlog.get_logger(__name__)
+
+
+class ImageCatalogError(ValueError):
+    """An image request that can't be served; ``code`` is the error code /exec reports."""
+
+    def __init__(self, code: str, message: str):
+        super().__init__(message)
+        self.code = code
+
+
+class ImageCatalogService:
+    """Which catalog images each API key may run, and the image an execution runs in."""
+
+    KEY_PREFIX = "image_catalog:"
+
+    def __init__(self, redis_client: redis.Redis | None = None):
+        """Initialize the image catalog service.
+
+        Args:
+            redis_client: Optional Redis client, uses shared pool if not provided
+        """
+        self.redis = redis_client or redis_pool.get_client()
+
+    def _tenant_key(self, api_key_hash: str) -> str:
+        return f"{self.KEY_PREFIX}tenant:{api_key_hash}"
+
+    @property
+    def _index_key(self) -> str:
+        return f"{self.KEY_PREFIX}tenants"
+
+    @staticmethod
+    def catalog_errors(images: TenantImages) -> list[str]:
+        """Names that aren't in IMAGE_CATALOG and pins that don't match their image's language."""
+        catalog = settings.image_catalog
+        errors = [f"Unknown image {name}" for name in images.images if name not in catalog]
+        for lang, name in images.pins.items():
+            if name not in catalog:
+                errors.append(f"Unknown image {name} pinned for {lang}")
+            elif catalog[name].language != lang.lower():
+                errors.append(f"Image {name} runs {catalog[name].language}, not {lang}")
+        return errors
+
+    async def set_tenant(self, api_key_hash: str, images: TenantImages, actor: str | None = None) -> TenantImagesRecord:
+        """Replace the catalog images an API key may use.
+
+        Pinned images are allowed too, whether or not they're listed.
+
+        Raises:
+            ImageCatalogError: If an image isn't in the catalog or a pin's language doesn't match
+        """
+        errors = self.catalog_errors(images)
+        if errors:
+            raise ImageCatalogError("invalid_images", "; ".join(errors))
+        record = TenantImagesRecord(
+            api_key_hash=api_key_hash,
+            images=sorted(set(images.images)),
+            pins={lang.lower(): name for lang, name in images.pins.items()},
+            updated_at=datetime.now(UTC),
+            updated_by=actor,
+        )
+        await self.redis.set(self._tenant_key(api_key_hash), record.model_dump_json())
+        await self.redis.sadd(self._index_key, api_key_hash)
+        logger.info("Set tenant images", api_key_hash=api_key_hash[:8], images=record.images, pins=record.pins)
+        return record
+
+    async def get_tenant(self, api_key_hash: str) -> TenantImagesRecord | None:
+        """The catalog images an API key may use, or None if none were set."""
+        data = await self.redis.get(self._tenant_key(api_key_hash))
+        return TenantImagesRecord.model_validate_json(data) if data else None
+
+    async def delete_tenant(self, api_key_hash: str) -> bool:
+        """Take away an API key's catalog images; False if it had none."""
+        await self.redis.srem(self._index_key, api_key_hash)
+        return bool(await self.redis.delete(self._tenant_key(api_key_hash)))
+
+    async def list_tenants(self) -> list[TenantImagesRecord]:
+        """Every API key's catalog images."""
+        records = []
+        for api_key_hash in sorted(await self.redis.smembers(self._index_key)):
+            record = await self.get_tenant(api_key_hash)
+            if record:
+                records.append(record)
+        return records
+
+    async def available(self, api_key_hash: str | None, is_env_key: bool = False) -> list[CatalogImageInfo]:
+        """The catalog images the caller may ask for (GET /images)."""
+        catalog = settings.image_catalog
+        if not catalog:
+            return []
+        record = None if is_env_key or not api_key_hash else await self.get_tenant(api_key_hash)
+        if is_env_key:
+            names = set(catalog)
+        else:
+            names = set(record.images) | set(record.pins.values()) if record else set()
+        pinned = set(record.pins.values()) if record else set()
+        return [
+            CatalogImageInfo(
+                name=name,
+                language=catalog[name].language,
+                image=catalog[name].image,
+                description=catalog[name].description,
+                pinned=name in pinned,
+            )
+            for name in sorted(names)
+            if name in catalog
+        ]
+
+    async def resolve(
+        self, lang: str, name: str | None, api_key_hash: str | None, is_env_key: bool = False
+    ) -> ResolvedImage | None:
+        """The catalog image an execution runs in: the one it asks for, else its key's pin; None for the default.
+
+        Raises:
+            ImageCatalogError: unknown_image, image_language_mismatch or image_not_allowed
+        """
+        catalog = settings.image_catalog
+        if not name and not catalog:
+            return None
+        lang = lang.lower()
+        if name and name not in catalog:
+            raise ImageCatalogError("unknown_image", f"Image {name} is not in the image catalog")
+        if name and catalog[name].language != lang:
+            raise ImageCatalogError(
+                "image_language_mismatch", f"Image {name} runs {catalog[name].language}, not {lang}"
+            )
+
+        record = None if is_env_key or not api_key_hash else await self.get_tenant(api_key_hash)
+        if not name:
+            pinned = record.pins.get(lang) if record else None
+            if pinned not in catalog:
+                return None
+            return ResolvedImage(name=pinned, image=catalog[pinned].image, pinned=True)
+        if not is_env_key and not (record and (name in record.images or name in record.pins.values())):
+            raise ImageCatalogError("image_not_allowed", f"This API key may not use image {name}")
+        return ResolvedImage(name=name, image=catalog[name].image)
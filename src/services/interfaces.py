@@ -19,7 +19,7 @@ class SessionServiceInterface(ABC):
     """Interface for session management service."""
 
     @abstractmethod
-    async def create_session(self, request: SessionCreate) -> Session:
+    async def create_session(self, request: SessionCreate, session_id: str | None = None) -> Session:
         """Create a new code execution session."""
         pass
 
@@ -34,7 +34,7 @@ class SessionServiceInterface(ABC):
         pass
 
     @abstractmethod
-    async def delete_session(self, session_id: str) -> bool:
+    async def delete_session(self, session_id: str, reason: str = "deleted") -> bool:
         """Delete a session and cleanup resources."""
         pass
 
@@ -53,6 +53,21 @@ class SessionServiceInterface(ABC):
         """List sessions for an entity."""
         pass
 
+    @abstractmethod
+    async def set_session_env(self, session_id: str, env: dict[str, str]) -> bool:
+        """Replace the environment variables applied to executions in a session."""
+        pass
+
+    @abstractmethod
+    async def get_session_env(self, session_id: str) -> dict[str, str]:
+        """Get the environment variables stored for a session."""
+        pass
+
+    @abstractmethod
+    async def record_restart(self, session_id: str) -> int | None:
+        """Count a kernel restart and return the new restart count."""
+        pass
+
 
 class ExecutionServiceInterface(ABC):
     """Interface for code execution service."""
@@ -94,6 +109,16 @@ class ExecutionServiceInterface(ABC):
         """List executions for a session."""
         pass
 
+    @abstractmethod
+    async def interrupt_session(self, session_id: str) -> int:
+        """Interrupt a session's running executions. Returns how many were signalled."""
+        pass
+
+    @abstractmethod
+    async def kill_session(self, session_id: str) -> int:
+        """Kill a session's running executions, keeping their pods. Returns how many were killed."""
+        pass
+
 
 class FileServiceInterface(ABC):
     """Interface for file management service."""
@@ -5,10 +5,11 @@ This module provides Kubernetes-native pod and job execution.
 
 from .client import get_kubernetes_client
 from .manager import KubernetesManager
-from .models import ExecutionResult, PodHandle, PodStatus
+from .models import ExecutionOptions, ExecutionResult, PodHandle, PodStatus
 
 __all__ = [
     "PodHandle",
+    "ExecutionOptions",
     "ExecutionResult",
     "PodStatus",
     "get_kubernetes_client",
@@ -16,6 +16,9 @@ from kubernetes.client import (
     CoreV1Api,
 )
 
+from .models import COMPILE_CACHE_MOUNT_PATH, CompileCache, DatasetMount, DnsPolicy
+from .storage import WORKSPACE_VOLUME, LocalDiskStorage, WorkspaceStorage
+
 logger = structlog.get_logger(__name__)
 
 # Global client instances
@@ -193,6 +196,15 @@ def create_pod_manifest(
     sidecar_memory_request: str = "256Mi",
     seccomp_profile_type: str = "RuntimeDefault",
     network_isolated: bool = False,
+    datasets: list[DatasetMount] | None = None,
+    compile_cache: CompileCache | None = None,
+    dns_policy: DnsPolicy | None = None,
+    max_execution_time: int | None = None,
+    fault_injection: str | None = None,
+    read_only_root_filesystem: bool = False,
+    policy_bundle: str | None = None,
+    strict_mode: bool = False,
+    workspace_storage: WorkspaceStorage | None = None,
 ) -> client.V1Pod:
     """Create a Pod manifest for code execution.
 
@@ -211,24 +223,68 @@ def create_pod_manifest(
         run_as_user: UID to run containers as
         sidecar_port: Port for sidecar HTTP API
         seccomp_profile_type: Seccomp profile type (RuntimeDefault or Unconfined)
+        network_isolated: Whether network isolation is enabled
+        datasets: Shared datasets to mount read-only into the main container
+        compile_cache: Node directory of compiler caches to mount read-write into both containers
+        dns_policy: Resolve through the sidecar, which only answers allowlisted names
+        max_execution_time: Longest timeout the sidecar accepts (its default when unset)
+        fault_injection: Faults the sidecar injects for resilience testing (FAULT_INJECTION)
+        read_only_root_filesystem: Make both containers' root filesystems read-only; /tmp becomes an emptyDir
+        policy_bundle: Policy bundle JSON the sidecar reports and enforces (POLICY_BUNDLE)
+        strict_mode: Strict request validation and anomaly logging in the sidecar (STRICT_MODE)
+        workspace_storage: Storage driver of the /mnt/data volume (1Gi on local disk when unset)
 
     Returns:
         V1Pod manifest ready for creation.
     """
-    # Shared volume for code and data
-    shared_volume = client.V1Volume(
-        name="shared-data",
-        empty_dir=client.V1EmptyDirVolumeSource(
-            medium="",
-            size_limit="1Gi",
-        ),
-    )
+    # Shared volume for code and data, on the deployment's workspace storage
+    workspace_storage = workspace_storage or LocalDiskStorage()
+    shared_volume = workspace_storage.volume()
 
     shared_mount = client.V1VolumeMount(
-        name="shared-data",
+        name=WORKSPACE_VOLUME,
         mount_path="/mnt/data",
     )
 
+    # With read-only root filesystems, temp files and compiled binaries (the
+    # sidecar's SCRATCH_DIR) go to an emptyDir at /tmp in both containers
+    scratch_volumes = (
+        [client.V1Volume(name="scratch", empty_dir=client.V1EmptyDirVolumeSource(medium="", size_limit="1Gi"))]
+        if read_only_root_filesystem
+        else []
+    )
+    scratch_mounts = [client.V1VolumeMount(name="scratch", mount_path="/tmp")] if read_only_root_filesystem else []
+
+    # Shared datasets: content-addressed node directories, read-only in the main
+    # container (where code runs), so one copy serves every session on the node
+    dataset_volumes = [
+        client.V1Volume(
+            name=f"dataset-{index}",
+            host_path=client.V1HostPathVolumeSource(path=dataset.host_path, type="Directory"),
+        )
+        for index, dataset in enumerate(datasets or [])
+    ]
+    dataset_mounts = [
+        client.V1VolumeMount(name=volume.name, mount_path=dataset.mount_path, read_only=True)
+        for volume, dataset in zip(dataset_volumes, datasets or [])
+    ]
+
+    # Compiler caches: one node directory shared read-write by every execution
+    # pod on the node; the code writes to it, the sidecar evicts from it
+    cache_volumes = (
+        [
+            client.V1Volume(
+                name="compile-cache",
+                host_path=client.V1HostPathVolumeSource(path=compile_cache.host_path, type="Directory"),
+            )
+        ]
+        if compile_cache
+        else []
+    )
+    cache_mounts = (
+        [client.V1VolumeMount(name="compile-cache", mount_path=COMPILE_CACHE_MOUNT_PATH)] if compile_cache else []
+    )
+
     # Security context for main container
     security_context = client.V1SecurityContext(
         run_as_user=run_as_user,
@@ -236,6 +292,7 @@ def create_pod_manifest(
         run_as_non_root=True,
         allow_privilege_escalation=False,
         capabilities=client.V1Capabilities(drop=["ALL"]),
+        read_only_root_filesystem=read_only_root_filesystem or None,
     )
 
     # Security context for sidecar - needs elevated privileges for nsenter
@@ -265,6 +322,7 @@ def create_pod_manifest(
             add=["SYS_PTRACE", "SYS_ADMIN", "SYS_CHROOT"],
             drop=["ALL"],
         ),
+        read_only_root_filesystem=read_only_root_filesystem or None,
     )
 
     # Resource requirements
@@ -278,7 +336,7 @@ def create_pod_manifest(
         name="main",
         image=main_image,
         image_pull_policy=image_pull_policy,
-        volume_mounts=[shared_mount],
+        volume_mounts=[shared_mount, *scratch_mounts, *dataset_mounts, *cache_mounts],
         security_context=security_context,
         resources=resources,
         env=[
@@ -293,7 +351,7 @@ def create_pod_manifest(
         image=sidecar_image,
         image_pull_policy=image_pull_policy,
         ports=[client.V1ContainerPort(container_port=sidecar_port, name="http")],
-        volume_mounts=[shared_mount],
+        volume_mounts=[shared_mount, *scratch_mounts, *cache_mounts],
         security_context=sidecar_security_context,
         resources=client.V1ResourceRequirements(
             # CRITICAL: User code runs in the sidecar's cgroup via nsenter (Issue #32)
@@ -304,8 +362,33 @@ def create_pod_manifest(
         env=[
             client.V1EnvVar(name="LANGUAGE", value=language),
             client.V1EnvVar(name="WORKING_DIR", value="/mnt/data"),
+            client.V1EnvVar(name="WORKSPACE_STORAGE", value=workspace_storage.name),
             client.V1EnvVar(name="SIDECAR_PORT", value=str(sidecar_port)),
             client.V1EnvVar(name="NETWORK_ISOLATED", value=str(network_isolated).lower()),
+            *(
+                [
+                    client.V1EnvVar(name="DNS_UPSTREAM", value=dns_policy.upstream),
+                    client.V1EnvVar(name="DNS_ALLOWLIST", value=",".join(dns_policy.allowlist)),
+                ]
+                if dns_policy
+                else []
+            ),
+            *(
+                [client.V1EnvVar(name="MAX_EXECUTION_TIME", value=str(max_execution_time))]
+                if max_execution_time
+                else []
+            ),
+            *([client.V1EnvVar(name="FAULT_INJECTION", value=fault_injection)] if fault_injection else []),
+            *([client.V1EnvVar(name="POLICY_BUNDLE", value=policy_bundle)] if policy_bundle else []),
+            *([client.V1EnvVar(name="STRICT_MODE", value="true")] if strict_mode else []),
+            *(
+                [
+                    client.V1EnvVar(name="COMPILE_CACHE_DIR", value=COMPILE_CACHE_MOUNT_PATH),
+                    client.V1EnvVar(name="COMPILE_CACHE_MAX_MB", value=str(compile_cache.max_size_mb)),
+                ]
+                if compile_cache
+                else []
+            ),
         ],
         readiness_probe=client.V1Probe(
             http_get=client.V1HTTPGetAction(path="/ready", port=sidecar_port),
@@ -326,7 +409,7 @@ def create_pod_manifest(
     # Pod spec
     pod_spec = client.V1PodSpec(
         containers=[main_container, sidecar_container],
-        volumes=[shared_volume],
+        volumes=[shared_volume, *scratch_volumes, *dataset_volumes, *cache_volumes],
         restart_policy="Never",
         termination_grace_period_seconds=10,
         # Share process namespace so sidecar can use nsenter to execute in main container
@@ -339,7 +422,14 @@ def create_pod_manifest(
             # Apply seccomp profile to block dangerous syscalls
             # while preserving nsenter functionality for the sidecar
             seccomp_profile=client.V1SeccompProfile(type=seccomp_profile_type),
+            # The sidecar's DNS resolver listens on port 53 without NET_BIND_SERVICE
+            sysctls=(
+                [client.V1Sysctl(name="net.ipv4.ip_unprivileged_port_start", value="53")] if dns_policy else None
+            ),
         ),
+        # Lookups go to the sidecar's resolver, which enforces the allowlists
+        dns_policy="None" if dns_policy else None,
+        dns_config=client.V1PodDNSConfig(nameservers=["127.0.0.1", "::1"]) if dns_policy else None,
         # Prevent scheduling on same node as other execution pods
         # (optional, can be configured via affinity)
     )
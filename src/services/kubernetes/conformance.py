@@ -0,0 +1,326 @@
+"""Conformance checks for implementations of the sidecar API.
+
+The API only talks to execution pods through the sidecar's HTTP API, so
+any agent that implements it (e.g. for a runtime the Python sidecar
+can't host) can plug into the same pods. These checks run against a
+sidecar's base URL and report which compatibility level it satisfies:
+
+- ``basic``: health and readiness probes, /execute (output, exit code,
+  timeouts) and file upload, listing, download and deletion. Enough for
+  the API to run executions and return their files.
+- ``stateful``: state capture and restore, /interrupt and /kill, needed
+  for Python sessions, interrupting executions and session quarantine.
+- ``full``: diff and append writes, content search, sync manifests,
+  named workspaces, language server status and support bundles.
+
+Levels are cumulative: an agent satisfies a level when every check of it
+and of the levels below passes. ``scripts/verify_sidecar.py`` runs the
+checks from the command line.
+"""
+
+import gzip
+import time
+import uuid
+from collections.abc import Awaitable, Callable
+from typing import Any
+
+import httpx
+
+MARKER = "conformance-ok"
+EXECUTE_TIMEOUT = 30
+HANG_TIMEOUT = 2
+
+# Code printing MARKER, and code that never finishes, per language
+PROBE_CODE: dict[str, tuple[str, str]] = {
+    "py": (f"print('{MARKER}')", "import time\nwhile True:\n    time.sleep(1)"),
+    "js": (f"console.log('{MARKER}')", "setInterval(() => {}, 1000)"),
+    "ts": (f"console.log('{MARKER}')", "setInterval(() => {}, 1000)"),
+    "php": (f"<?php echo '{MARKER}';", "<?php while (true) { sleep(1); }"),
+    "r": (f"cat('{MARKER}')", "repeat { Sys.sleep(1) }"),
+    "go": (
+        f'package main\n\nimport "fmt"\n\nfunc main() {{\n\tfmt.Println("{MARKER}")\n}}\n',
+        'package main\n\nimport "time"\n\nfunc main() {\n\tfor {\n\t\ttime.Sleep(time.Second)\n\t}\n}\n',
+    ),
+}
+
+LEVELS = ["basic", "stateful", "full"]
+
+
+class CheckFailed(Exception):
+    """A response that doesn't match the sidecar API."""
+
+
+Check = Callable[["ConformanceRun"], Awaitable[str]]
+
+
+def _json(response: httpx.Response, *fields: str) -> dict[str, Any]:
+    if response.status_code >= 400:
+        raise CheckFailed(f"HTTP {response.status_code}: {response.text[:200]}")
+    try:
+        data = response.json()
+    except ValueError:
+        raise CheckFailed("Response is not JSON")
+    missing = [field for field in fields if field not in data]
+    if missing:
+        raise CheckFailed(f"Missing fields: {', '.join(missing)}")
+    return data
+
+
+class ConformanceRun:
+    """State shared by the checks of one run against a sidecar."""
+
+    def __init__(self, client: httpx.AsyncClient, base_url: str, code: str, hang_code: str):
+        self.client = client
+        self.base_url = base_url.rstrip("/")
+        self.code = code
+        self.hang_code = hang_code
+        self.filename = f"conformance-{uuid.uuid4().hex[:8]}.txt"
+        self.workspace = f"conformance-{uuid.uuid4().hex[:8]}"
+
+    def url(self, path: str) -> str:
+        return f"{self.base_url}{path}"
+
+    async def execute(self, code: str, timeout: int = EXECUTE_TIMEOUT, **extra) -> dict[str, Any]:
+        response = await self.client.post(
+            self.url("/execute"),
+            json={"code": code, "timeout": timeout, "working_dir": "/mnt/data", **extra},
+            timeout=timeout + 10,
+        )
+        data = _json(response, "exit_code", "stdout", "stderr", "execution_time_ms")
+        if not isinstance(data["exit_code"], int) or not isinstance(data["execution_time_ms"], int):
+            raise CheckFailed("exit_code and execution_time_ms must be integers")
+        return data
+
+
+async def check_health(run: ConformanceRun) -> str:
+    data = _json(await run.client.get(run.url("/health")), "status", "language")
+    return f"language {data['language']}"
+
+
+async def check_ready(run: ConformanceRun) -> str:
+    _json(await run.client.get(run.url("/ready")))
+    return ""
+
+
+async def check_execute(run: ConformanceRun) -> str:
+    data = await run.execute(run.code)
+    if data["exit_code"] != 0:
+        raise CheckFailed(f"exit {data['exit_code']}: {data['stderr'][:200]}")
+    if MARKER not in data["stdout"]:
+        raise CheckFailed(f"stdout doesn't contain {MARKER!r}")
+    return f"{data['execution_time_ms']} ms"
+
+
+async def check_execute_exit_code(run: ConformanceRun) -> str:
+    data = await run.execute("this is not valid code in any language {{{")
+    if data["exit_code"] == 0:
+        raise CheckFailed("Invalid code exited 0")
+    return f"exit {data['exit_code']}"
+
+
+async def check_execute_timeout(run: ConformanceRun) -> str:
+    start = time.perf_counter()
+    data = await run.execute(run.hang_code, timeout=HANG_TIMEOUT)
+    elapsed = time.perf_counter() - start
+    if data["exit_code"] != 124:
+        raise CheckFailed(f"Timed out execution exited {data['exit_code']}, not 124")
+    if elapsed > HANG_TIMEOUT + 5:
+        raise CheckFailed(f"Returned after {elapsed:.1f}s, for a {HANG_TIMEOUT}s timeout")
+    return f"{elapsed:.1f}s"
+
+
+async def check_upload(run: ConformanceRun) -> str:
+    response = await run.client.post(run.url("/files"), files={"files": (run.filename, MARKER.encode())})
+    uploaded = _json(response, "uploaded")["uploaded"]
+    if not any(f.get("name") == run.filename for f in uploaded):
+        raise CheckFailed(f"{run.filename} not in uploaded")
+    return ""
+
+
+async def check_list(run: ConformanceRun) -> str:
+    files = _json(await run.client.get(run.url("/files")), "files")["files"]
+    if not any(f.get("name") == run.filename for f in files):
+        raise CheckFailed(f"{run.filename} not listed")
+    return f"{len(files)} files"
+
+
+async def check_download(run: ConformanceRun) -> str:
+    response = await run.client.get(run.url(f"/files/{run.filename}"))
+    if response.status_code != 200:
+        raise CheckFailed(f"HTTP {response.status_code}")
+    if response.content != MARKER.encode():
+        raise CheckFailed("Downloaded content differs from the upload")
+    if (await run.client.get(run.url("/files/conformance-missing.txt"))).status_code != 404:
+        raise CheckFailed("Missing file is not a 404")
+    return ""
+
+
+async def check_path_traversal(run: ConformanceRun) -> str:
+    response = await run.client.get(run.url("/files/..%2F..%2Fetc%2Fpasswd"))
+    if response.status_code < 400:
+        raise CheckFailed("A path outside the working directory was served")
+    return ""
+
+
+async def check_state(run: ConformanceRun) -> str:
+    data = await run.execute(run.code, capture_state=True)
+    if not data.get("state"):
+        raise CheckFailed("No state returned with capture_state")
+    restored = await run.execute(run.code, initial_state=data["state"])
+    if restored["exit_code"] != 0:
+        raise CheckFailed(f"Restoring the state exited {restored['exit_code']}")
+    return f"{len(data['state'])} bytes"
+
+
+async def check_interrupt(run: ConformanceRun) -> str:
+    data = _json(await run.client.post(run.url("/interrupt")), "interrupted")
+    return f"{data['interrupted']} signalled"
+
+
+async def check_kill(run: ConformanceRun) -> str:
+    data = _json(await run.client.post(run.url("/kill")), "killed")
+    return f"{data['killed']} signalled"
+
+
+async def check_append(run: ConformanceRun) -> str:
+    _json(await run.client.patch(run.url(f"/files/{run.filename}"), content=b"\nappended"))
+    response = await run.client.get(run.url(f"/files/{run.filename}"))
+    if response.content != f"{MARKER}\nappended".encode():
+        raise CheckFailed("Append didn't add to the end of the file")
+    return ""
+
+
+async def check_patch(run: ConformanceRun) -> str:
+    diff = f"--- a/{run.filename}\n+++ b/{run.filename}\n@@ -1,2 +1,2 @@\n {MARKER}\n-appended\n+patched\n"
+    data = _json(await run.client.post(run.url("/files/patch"), json={"diff": diff}), "applied")
+    if not data["applied"]:
+        raise CheckFailed("Patch not applied")
+    return ""
+
+
+async def check_search(run: ConformanceRun) -> str:
+    response = await run.client.get(run.url("/files/search"), params={"q": "patched", "regex": False})
+    data = _json(response)
+    if run.filename not in str(data):
+        raise CheckFailed(f"{run.filename} not found by search")
+    return ""
+
+
+async def check_sync_manifest(run: ConformanceRun) -> str:
+    files = _json(await run.client.get(run.url("/sync/manifest")), "files")["files"]
+    if run.filename not in str(files):
+        raise CheckFailed(f"{run.filename} not in the manifest")
+    return ""
+
+
+async def check_workspaces(run: ConformanceRun) -> str:
+    created = await run.client.put(run.url(f"/workspaces/{run.workspace}"), json={"quota_bytes": 1024})
+    if created.status_code not in (200, 201):
+        raise CheckFailed(f"PUT /workspaces: HTTP {created.status_code}")
+    try:
+        response = await run.client.post(
+            run.url("/files"), params={"workspace": run.workspace}, files={"files": ("big.bin", b"0" * 2048)}
+        )
+        if response.status_code < 400:
+            raise CheckFailed("Upload over the workspace quota was accepted")
+        workspaces = _json(await run.client.get(run.url("/workspaces")), "workspaces")["workspaces"]
+        if run.workspace not in str(workspaces):
+            raise CheckFailed(f"{run.workspace} not listed")
+    finally:
+        await run.client.delete(run.url(f"/workspaces/{run.workspace}"))
+    return ""
+
+
+async def check_lsp(run: ConformanceRun) -> str:
+    data = _json(await run.client.get(run.url("/lsp")), "servers")
+    return f"{len(data['servers'])} servers"
+
+
+async def check_debug_bundle(run: ConformanceRun) -> str:
+    response = await run.client.get(run.url("/debug/bundle"))
+    if response.status_code != 200:
+        raise CheckFailed(f"HTTP {response.status_code}")
+    try:
+        gzip.decompress(response.content)
+    except OSError:
+        raise CheckFailed("Bundle is not gzip")
+    return f"{len(response.content)} bytes"
+
+
+async def check_delete(run: ConformanceRun) -> str:
+    _json(await run.client.delete(run.url(f"/files/{run.filename}")))
+    if (await run.client.get(run.url(f"/files/{run.filename}"))).status_code != 404:
+        raise CheckFailed("Deleted file is still served")
+    return ""
+
+
+# Run in this order: later checks use the file uploaded by earlier ones, and the last one deletes it
+CHECKS: list[tuple[str, str, Check]] = [
+    ("health", "basic", check_health),
+    ("ready", "basic", check_ready),
+    ("execute", "basic", check_execute),
+    ("execute_exit_code", "basic", check_execute_exit_code),
+    ("execute_timeout", "basic", check_execute_timeout),
+    ("upload", "basic", check_upload),
+    ("list", "basic", check_list),
+    ("download", "basic", check_download),
+    ("path_traversal", "basic", check_path_traversal),
+    ("state", "stateful", check_state),
+    ("interrupt", "stateful", check_interrupt),
+    ("kill", "stateful", check_kill),
+    ("append", "full", check_append),
+    ("patch", "full", check_patch),
+    ("search", "full", check_search),
+    ("sync_manifest", "full", check_sync_manifest),
+    ("workspaces", "full", check_workspaces),
+    ("lsp", "full", check_lsp),
+    ("debug_bundle", "full", check_debug_bundle),
+    ("delete", "basic", check_delete),
+]
+
+
+async def run_conformance(
+    client: httpx.AsyncClient,
+    base_url: str,
+    language: str,
+    code: str | None = None,
+    hang_code: str | None = None,
+) -> dict[str, Any]:
+    """Run every check against the sidecar at base_url.
+
+    code must print MARKER and hang_code must never finish; both default
+    to PROBE_CODE for the language. ``level`` in the report is the highest
+    level satisfied, or None when even ``basic`` isn't.
+    """
+    if code is None or hang_code is None:
+        if language not in PROBE_CODE:
+            raise ValueError(f"No probe code for {language!r}; pass code and hang_code")
+        code, hang_code = code or PROBE_CODE[language][0], hang_code or PROBE_CODE[language][1]
+    run = ConformanceRun(client, base_url, code, hang_code)
+
+    checks = []
+    for name, level, check in CHECKS:
+        start = time.perf_counter()
+        try:
+            ok, detail = True, await check(run)
+        except CheckFailed as e:
+            ok, detail = False, str(e)
+        except httpx.HTTPError as e:
+            ok, detail = False, f"{type(e).__name__}: {e}"
+        ms = round((time.perf_counter() - start) * 1000, 1)
+        checks.append({"name": name, "level": level, "ok": ok, "ms": ms, "detail": detail})
+
+    levels = {level: all(c["ok"] for c in checks if c["level"] == level) for level in LEVELS}
+    satisfied = None
+    for level in LEVELS:
+        if not levels[level]:
+            break
+        satisfied = level
+    return {
+        "base_url": run.base_url,
+        "language": language,
+        "level": satisfied,
+        "levels": levels,
+        "failed": [c["name"] for c in checks if not c["ok"]],
+        "checks": checks,
+    }
@@ -5,6 +5,7 @@ for each execution. This has higher latency but simpler management.
 """
 
 import asyncio
+import time
 from datetime import datetime
 from typing import Any, Dict, List, Optional
 from uuid import uuid4
@@ -20,11 +21,13 @@ from .client import (
     get_current_namespace,
 )
 from .models import (
+    ExecutionOptions,
     ExecutionResult,
     FileData,
     JobHandle,
     PodSpec,
 )
+from .workspace import upload_files
 
 logger = structlog.get_logger(__name__)
 
@@ -56,6 +59,11 @@ class JobExecutor:
         self.active_deadline_seconds = active_deadline_seconds
         self.sidecar_image = sidecar_image
         self._http_client: httpx.AsyncClient | None = None
+        self._active_jobs: dict[str, JobHandle] = {}  # job uid -> handle, while executing
+
+    def get_session_jobs(self, session_id: str) -> list[JobHandle]:
+        """Jobs currently executing code for a session."""
+        return [job for job in self._active_jobs.values() if job.session_id == session_id]
 
     async def _get_http_client(self) -> httpx.AsyncClient:
         """Get or create HTTP client for sidecar communication."""
@@ -124,9 +132,18 @@ class JobExecutor:
             sidecar_cpu_request=spec.sidecar_cpu_request,
             sidecar_memory_request=spec.sidecar_memory_request,
             seccomp_profile_type=spec.seccomp_profile_type,
+            read_only_root_filesystem=spec.read_only_root_filesystem,
             network_isolated=spec.network_isolated,
+            datasets=spec.datasets,
+            compile_cache=spec.compile_cache,
+            dns_policy=spec.dns_policy,
+            fault_injection=spec.fault_injection,
+            policy_bundle=spec.policy_bundle,
+            strict_mode=spec.strict_mode,
+            workspace_storage=spec.workspace_storage,
+            max_execution_time=spec.max_execution_time,
             ttl_seconds_after_finished=self.ttl_seconds_after_finished,
-            active_deadline_seconds=self.active_deadline_seconds,
+            active_deadline_seconds=spec.active_deadline_seconds or self.active_deadline_seconds,
         )
 
         try:
@@ -248,6 +265,7 @@ class JobExecutor:
         files: list[FileData] | None = None,
         initial_state: str | None = None,
         capture_state: bool = False,
+        options: ExecutionOptions | None = None,
     ) -> ExecutionResult:
         """Execute code in the job's pod.
 
@@ -258,32 +276,23 @@ class JobExecutor:
             files: Files to upload before execution
             initial_state: State to restore
             capture_state: Whether to capture state after execution
+            options: Extra sidecar options (env, template context)
 
         Returns:
             ExecutionResult with stdout, stderr, exit code
         """
         if not job.pod_ip:
-            return ExecutionResult(
-                exit_code=1,
-                stdout="",
-                stderr="Job pod not ready",
-                execution_time_ms=0,
-            )
+            return ExecutionResult.spawn_failed("Job pod not ready")
 
         sidecar_url = job.sidecar_url
         if not sidecar_url:
-            return ExecutionResult(
-                exit_code=1,
-                stdout="",
-                stderr="Job sidecar URL not available",
-                execution_time_ms=0,
-            )
+            return ExecutionResult.spawn_failed("Job sidecar URL not available")
 
         client = await self._get_http_client()
 
-        # Upload files if provided
-        if files:
-            await self._upload_files(client, sidecar_url, files)
+        # Upload files if provided (creating the execution's workspace first)
+        if files or (options and options.workspace):
+            await self._upload_files(client, sidecar_url, files or [], options)
 
         # Execute code
         try:
@@ -296,6 +305,8 @@ class JobExecutor:
                 request_data["initial_state"] = initial_state
             if capture_state:
                 request_data["capture_state"] = True
+            if options:
+                request_data.update(options.to_request_data())
 
             logger.debug(
                 "Sending execute request",
@@ -307,7 +318,8 @@ class JobExecutor:
             response = await client.post(
                 f"{sidecar_url}/execute",
                 json=request_data,
-                timeout=timeout + 10,  # Extra time for network
+                # Extra time for network, and for hooks run within the same request
+                timeout=timeout + 10 + (options.hook_seconds if options else 0),
             )
 
             if response.status_code == 200:
@@ -319,14 +331,17 @@ class JobExecutor:
                     execution_time_ms=data.get("execution_time_ms", 0),
                     state=data.get("state"),
                     state_errors=data.get("state_errors"),
+                    interrupted=data.get("interrupted", False),
+                    error=data.get("error"),
+                    connections=data.get("connections"),
+                    dns_denied=data.get("dns_denied"),
+                    timings=data.get("timings"),
+                    provenance=data.get("provenance"),
+                    sandbox=data.get("sandbox"),
+                    compile_cache=data.get("compile_cache"),
                 )
             else:
-                return ExecutionResult(
-                    exit_code=1,
-                    stdout="",
-                    stderr=f"Sidecar error: {response.status_code} - {response.text}",
-                    execution_time_ms=0,
-                )
+                return ExecutionResult.spawn_failed(f"Sidecar error: {response.status_code} - {response.text}")
 
         except httpx.TimeoutException:
             return ExecutionResult(
@@ -341,34 +356,17 @@ class JobExecutor:
                 job_name=job.name,
                 error=str(e),
             )
-            return ExecutionResult(
-                exit_code=1,
-                stdout="",
-                stderr=f"Execution error: {str(e)}",
-                execution_time_ms=0,
-            )
+            return ExecutionResult.spawn_failed(f"Execution error: {str(e)}")
 
     async def _upload_files(
         self,
         client: httpx.AsyncClient,
         sidecar_url: str,
         files: list[FileData],
+        options: ExecutionOptions | None = None,
     ):
         """Upload files to the pod."""
-        for file_data in files:
-            try:
-                files_payload = {"files": (file_data.filename, file_data.content)}
-                await client.post(
-                    f"{sidecar_url}/files",
-                    files=files_payload,
-                    timeout=30,
-                )
-            except Exception as e:
-                logger.warning(
-                    "Failed to upload file",
-                    filename=file_data.filename,
-                    error=str(e),
-                )
+        await upload_files(client, sidecar_url, files, options)
 
     async def delete_job(self, job: JobHandle):
         """Delete a job and its pods.
@@ -413,6 +411,7 @@ class JobExecutor:
         files: list[FileData] | None = None,
         initial_state: str | None = None,
         capture_state: bool = False,
+        options: ExecutionOptions | None = None,
     ) -> ExecutionResult:
         """Execute code by creating a job, waiting for ready, executing, and cleaning up.
 
@@ -426,11 +425,13 @@ class JobExecutor:
             files: Files to upload
             initial_state: State to restore
             capture_state: Whether to capture state
+            options: Extra sidecar options (env, template context)
 
         Returns:
             ExecutionResult
         """
         job = None
+        started = time.perf_counter()
         try:
             # Create job
             job = await self.create_job(spec, session_id)
@@ -438,12 +439,8 @@ class JobExecutor:
             # Wait for pod ready
             ready = await self.wait_for_pod_ready(job, timeout=60)
             if not ready:
-                return ExecutionResult(
-                    exit_code=1,
-                    stdout="",
-                    stderr="Job pod failed to start",
-                    execution_time_ms=0,
-                )
+                return ExecutionResult.spawn_failed("Job pod failed to start")
+            queue_wait_ms = int((time.perf_counter() - started) * 1000)
 
             # Log the job state before executing
             logger.info(
@@ -455,14 +452,20 @@ class JobExecutor:
             )
 
             # Execute code
-            result = await self.execute(
-                job,
-                code,
-                timeout=timeout,
-                files=files,
-                initial_state=initial_state,
-                capture_state=capture_state,
-            )
+            self._active_jobs[job.uid] = job
+            try:
+                result = await self.execute(
+                    job,
+                    code,
+                    timeout=timeout,
+                    files=files,
+                    initial_state=initial_state,
+                    capture_state=capture_state,
+                    options=options,
+                )
+            finally:
+                self._active_jobs.pop(job.uid, None)
+            result.timings = {"queue_wait_ms": queue_wait_ms, **(result.timings or {})}
 
             logger.info(
                 "Job execution completed",
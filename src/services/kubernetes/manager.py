@@ -4,6 +4,7 @@ This is the main entry point for Kubernetes-based code execution.
 """
 
 import asyncio
+import time
 from typing import Any, Dict, List, Optional, Tuple
 
 import structlog
@@ -18,6 +19,11 @@ from .client import (
 )
 from .job_executor import JobExecutor
 from .models import (
+    CompileCache,
+    DatasetMount,
+    DnsPolicy,
+    Elevation,
+    ExecutionOptions,
     ExecutionResult,
     FileData,
     PodHandle,
@@ -25,6 +31,7 @@ from .models import (
     PoolConfig,
 )
 from .pool import PodPoolManager
+from .storage import WorkspaceStorage
 
 logger = structlog.get_logger(__name__)
 
@@ -47,7 +54,15 @@ class KubernetesManager:
         default_cpu_request: str = "100m",
         default_memory_request: str = "128Mi",
         seccomp_profile_type: str = "RuntimeDefault",
+        read_only_root_filesystem: bool = False,
         network_isolated: bool = False,
+        datasets: list[DatasetMount] | None = None,
+        compile_cache: CompileCache | None = None,
+        dns_policy: DnsPolicy | None = None,
+        fault_injection: str | None = None,
+        policy_bundle: str | None = None,
+        strict_mode: bool = False,
+        workspace_storage: WorkspaceStorage | None = None,
     ):
         """Initialize the Kubernetes manager.
 
@@ -60,7 +75,15 @@ class KubernetesManager:
             default_cpu_request: Default CPU request for pods
             default_memory_request: Default memory request for pods
             seccomp_profile_type: Seccomp profile type (RuntimeDefault, Unconfined, Localhost)
+            read_only_root_filesystem: Read-only root filesystems in Job pods (pools take theirs from PoolConfig)
             network_isolated: Whether network isolation is enabled (disables network-dependent features)
+            datasets: Shared datasets mounted read-only into Job pods (pools take theirs from PoolConfig)
+            compile_cache: Node compiler caches mounted into Job pods (pools take theirs from PoolConfig)
+            dns_policy: DNS allowlisting for Job pods (pools take theirs from PoolConfig)
+            fault_injection: Sidecar FAULT_INJECTION spec for Job pods (pools take theirs from PoolConfig)
+            policy_bundle: Policy bundle JSON for Job pods' sidecars (pools take theirs from PoolConfig)
+            strict_mode: Sidecar STRICT_MODE for Job pods (pools take theirs from PoolConfig)
+            workspace_storage: Working directory volume of Job pods (pools take theirs from PoolConfig)
         """
         self.namespace = namespace or get_current_namespace()
         self.sidecar_image = sidecar_image
@@ -69,7 +92,15 @@ class KubernetesManager:
         self.default_cpu_request = default_cpu_request
         self.default_memory_request = default_memory_request
         self.seccomp_profile_type = seccomp_profile_type
+        self.read_only_root_filesystem = read_only_root_filesystem
         self.network_isolated = network_isolated
+        self.datasets = datasets or []
+        self.compile_cache = compile_cache
+        self.dns_policy = dns_policy
+        self.fault_injection = fault_injection
+        self.policy_bundle = policy_bundle
+        self.strict_mode = strict_mode
+        self.workspace_storage = workspace_storage
 
         # Pool manager for warm pods
         self._pool_manager = PodPoolManager(
@@ -219,11 +250,17 @@ class KubernetesManager:
         files: list[dict[str, Any]] | None = None,
         initial_state: str | None = None,
         capture_state: bool = False,
+        options: ExecutionOptions | None = None,
+        elevation: Elevation | None = None,
+        image: str | None = None,
     ) -> tuple[ExecutionResult, PodHandle | None, str]:
         """Execute code in a pod.
 
         Automatically chooses between warm pool and Job execution
-        based on language configuration.
+        based on language configuration. Elevated executions always run
+        in their own Job pod, labelled so they can be told apart (and, with
+        network access, selected by the elevated NetworkPolicy), as do
+        executions in an image other than the language's.
 
         Args:
             session_id: Session identifier
@@ -233,6 +270,9 @@ class KubernetesManager:
             files: Files to upload (list of dicts with filename, content)
             initial_state: State to restore (base64)
             capture_state: Whether to capture state after execution
+            options: Extra sidecar options (env, template context)
+            elevation: Capabilities of an elevated grant to apply to the pod
+            image: Image to run in instead of the language's (from the image catalog)
 
         Returns:
             Tuple of (ExecutionResult, PodHandle or None, source)
@@ -252,8 +292,12 @@ class KubernetesManager:
                 if isinstance(f.get("content"), bytes)
             ]
 
-        # Try to acquire from pool
-        handle, source = await self.acquire_pod(session_id, language)
+        # Try to acquire from pool (pooled pods can't be given an elevated pod's limits or labels, or another image)
+        default_image = self.get_image_for_language(language)
+        dedicated = elevation or (image and image != default_image)
+        started = time.perf_counter()
+        handle, source = (None, "job") if dedicated else await self.acquire_pod(session_id, language)
+        queue_wait_ms = int((time.perf_counter() - started) * 1000)
 
         if handle:
             # Execute using pool
@@ -264,13 +308,16 @@ class KubernetesManager:
                 files=file_data,
                 initial_state=initial_state,
                 capture_state=capture_state,
+                options=options,
             )
+            result.timings = {"queue_wait_ms": queue_wait_ms, **(result.timings or {})}
+            result.image = default_image
             return result, handle, source
         else:
             # Use Job execution
             spec = PodSpec(
                 language=language,
-                image=self.get_image_for_language(language),
+                image=image or default_image,
                 session_id=session_id,
                 namespace=self.namespace,
                 sidecar_image=self.sidecar_image,
@@ -279,8 +326,18 @@ class KubernetesManager:
                 cpu_request=self.default_cpu_request,
                 memory_request=self.default_memory_request,
                 seccomp_profile_type=self.seccomp_profile_type,
+                read_only_root_filesystem=self.read_only_root_filesystem,
                 network_isolated=self.network_isolated,
+                datasets=self.datasets,
+                compile_cache=self.compile_cache,
+                dns_policy=self.dns_policy,
+                fault_injection=self.fault_injection,
+                policy_bundle=self.policy_bundle,
+                strict_mode=self.strict_mode,
+                workspace_storage=self.workspace_storage,
             )
+            if elevation:
+                self._apply_elevation(spec, elevation, timeout)
 
             result = await self._job_executor.execute_with_job(
                 spec,
@@ -290,9 +347,33 @@ class KubernetesManager:
                 files=file_data,
                 initial_state=initial_state,
                 capture_state=capture_state,
+                options=options,
             )
+            result.image = spec.image
             return result, None, "job"
 
+    async def set_network_isolated(self, isolated: bool) -> int:
+        """Apply a runtime change of ENABLE_NETWORK_ISOLATION to the pods created from now on.
+
+        Job pods and pool refills get the new mode, and warm pods of the old
+        one are drained; returns how many were.
+        """
+        self.network_isolated = isolated
+        return await self._pool_manager.set_network_isolated(isolated)
+
+    def _apply_elevation(self, spec: PodSpec, elevation: Elevation, timeout: int) -> None:
+        """Give a Job pod the labels and limits of an elevated grant."""
+        spec.labels["kubecoderun.io/elevated"] = "true"
+        spec.labels["kubecoderun.io/elevation-grant"] = elevation.grant_id
+        if elevation.network:
+            spec.labels["kubecoderun.io/elevated-network"] = "true"
+            spec.network_isolated = False
+        if elevation.memory_mb:
+            # User code runs in the sidecar's cgroup, so its limit is the one that matters
+            spec.memory_limit = spec.sidecar_memory_limit = f"{elevation.memory_mb}Mi"
+        spec.max_execution_time = timeout
+        spec.active_deadline_seconds = max(self._job_executor.active_deadline_seconds, timeout + 60)
+
     async def destroy_pod(self, handle: PodHandle):
         """Destroy an execution pod.
 
@@ -382,6 +463,58 @@ class KubernetesManager:
 
         return None
 
+    async def interrupt_session(self, session_id: str) -> int:
+        """Send an interrupt (SIGINT) to a session's running executions.
+
+        Only executions started by this API instance are known here.
+
+        Args:
+            session_id: Session identifier
+
+        Returns:
+            Number of executions that were signalled
+        """
+        return await self._signal_session(session_id, "interrupt", "interrupted")
+
+    async def kill_session(self, session_id: str) -> int:
+        """Send SIGKILL to a session's running executions (quarantine).
+
+        Only executions started by this API instance are known here.
+
+        Args:
+            session_id: Session identifier
+
+        Returns:
+            Number of executions that were killed
+        """
+        return await self._signal_session(session_id, "kill", "killed")
+
+    async def _signal_session(self, session_id: str, endpoint: str, count_field: str) -> int:
+        """POST to a sidecar signal endpoint of every pod running the session's code."""
+        urls = [handle.sidecar_url for handle in self._pool_manager.get_session_pods(session_id) if handle.pod_ip]
+        urls += [job.sidecar_url for job in self._job_executor.get_session_jobs(session_id) if job.sidecar_url]
+        if not urls:
+            return 0
+
+        import httpx
+
+        count = 0
+        async with httpx.AsyncClient(timeout=10.0) as client:
+            for url in urls:
+                try:
+                    response = await client.post(f"{url}/{endpoint}")
+                    if response.status_code == 200:
+                        count += response.json().get(count_field, 0)
+                except Exception as e:
+                    logger.warning(
+                        f"Failed to {endpoint} execution",
+                        session_id=session_id[:12],
+                        sidecar_url=url,
+                        error=str(e),
+                    )
+
+        return count
+
     def get_pool_stats(self) -> dict[str, dict[str, int]]:
         """Get statistics for all pod pools."""
         return self._pool_manager.get_pool_stats()
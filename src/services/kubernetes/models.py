@@ -9,6 +9,8 @@ from datetime import UTC, datetime, timezone
 from enum import Enum
 from typing import Any, Dict, List, Optional
 
+from .storage import WorkspaceStorage
+
 
 class PodStatus(str, Enum):
     """Status of an execution pod."""
@@ -25,6 +27,11 @@ class PodStatus(str, Enum):
     EXECUTING = "executing"  # Currently running code
 
 
+def url_host(ip: str) -> str:
+    """An IP as the host of a URL: IPv6 addresses go in brackets."""
+    return f"[{ip}]" if ":" in ip else ip
+
+
 @dataclass
 class PodHandle:
     """Handle to a Kubernetes pod for execution.
@@ -48,7 +55,7 @@ class PodHandle:
     def sidecar_url(self) -> str:
         """Get the URL for the sidecar HTTP API."""
         if self.pod_ip:
-            return f"http://{self.pod_ip}:{self.sidecar_port}"
+            return f"http://{url_host(self.pod_ip)}:{self.sidecar_port}"
         return f"http://{self.name}.{self.namespace}:{self.sidecar_port}"
 
     @property
@@ -65,6 +72,12 @@ class PodHandle:
         return False
 
 
+# Error code for executions that failed before the code started
+SPAWN_FAILED = "SPAWN_FAILED"
+# Error code the sidecar reports for executions killed for opening too many connections
+CONNECTION_LIMIT_EXCEEDED = "CONNECTION_LIMIT_EXCEEDED"
+
+
 @dataclass
 class ExecutionResult:
     """Result of code execution in a pod.
@@ -78,6 +91,77 @@ class ExecutionResult:
     execution_time_ms: int
     state: str | None = None  # Base64-encoded state
     state_errors: list[str] | None = None
+    interrupted: bool = False  # Stopped by an interrupt request (SIGINT)
+    error: dict[str, Any] | None = None  # Structured failure, e.g. RUNTIME_NOT_FOUND or SPAWN_FAILED
+    connections: int | None = None  # Outbound connections the execution opened, if counted
+    dns_denied: list[str] | None = None  # Lookups refused by the pod's DNS policy, if it has one
+    # Milliseconds per phase: queue_wait_ms (added by the API), spawn_ms, first_output_ms, total_ms
+    timings: dict[str, int | None] | None = None
+    # Command and interpreter binaries (name, path, sha256, version) the sidecar ran the code with
+    provenance: dict[str, Any] | None = None
+    # Seccomp, cgroup limits, credentials and network the code inherited from the sidecar
+    sandbox: dict[str, Any] | None = None
+    # Compiler cache tool, hits and misses of the execution (None without a cache)
+    compile_cache: dict[str, Any] | None = None
+    image: str | None = None  # Image of the pod's main container (added by the API)
+
+    @classmethod
+    def spawn_failed(cls, stderr: str) -> "ExecutionResult":
+        """Result for code that never ran because the pod or sidecar wasn't reachable."""
+        return cls(exit_code=1, stdout="", stderr=stderr, execution_time_ms=0, error={"code": SPAWN_FAILED})
+
+
+@dataclass
+class ExecutionOptions:
+    """Per-execution options forwarded to the sidecar /execute endpoint.
+
+    Grouped so sidecar features don't each need another parameter threaded
+    through the manager, pool and job executor.
+    """
+
+    env: dict[str, str] = field(default_factory=dict)
+    context: dict[str, str] = field(default_factory=dict)  # Template variables, e.g. SESSION_ID
+    template_code: bool = False
+    # Operator hooks (EXEC_PRE_HOOK / EXEC_POST_HOOK) and the time budget of each
+    pre_hook: str | None = None
+    post_hook: str | None = None
+    hook_timeout: int = 10
+    # Lower OOM/CPU/I/O priority and CPU affinity of background executions (ExecPriority fields)
+    priority: dict[str, Any] = field(default_factory=dict)
+    # Outbound connections open at once before the execution is killed
+    max_connections: int | None = None
+    # Names the execution may resolve, within the pod's DNS policy
+    dns_allowlist: list[str] | None = None
+    # Named workspace on the pod; files are uploaded to and listed from it
+    workspace: str | None = None
+    # Its policy (quota_bytes, read_only), applied when the API creates it before uploading files
+    workspace_policy: dict[str, Any] = field(default_factory=dict)
+
+    @property
+    def hook_seconds(self) -> int:
+        """Longest time the hooks can add to the execution."""
+        return self.hook_timeout * (bool(self.pre_hook) + bool(self.post_hook))
+
+    def to_request_data(self) -> dict[str, Any]:
+        """Sidecar request fields, omitting defaults."""
+        data: dict[str, Any] = {}
+        if self.pre_hook or self.post_hook:
+            data["hooks"] = {"pre": self.pre_hook, "post": self.post_hook, "timeout": self.hook_timeout}
+        if self.env:
+            data["env"] = self.env
+        if self.context:
+            data["context"] = self.context
+        if self.template_code:
+            data["template_code"] = True
+        if self.priority:
+            data["priority"] = self.priority
+        if self.max_connections is not None:
+            data["max_connections"] = self.max_connections
+        if self.dns_allowlist is not None:
+            data["dns_allowlist"] = self.dns_allowlist
+        if self.workspace:
+            data["workspace"] = self.workspace
+        return data
 
 
 @dataclass
@@ -89,6 +173,40 @@ class FileData:
     session_id: str | None = None
 
 
+DATASETS_MOUNT_ROOT = "/mnt/datasets"
+
+
+@dataclass
+class DatasetMount:
+    """A shared dataset directory on the node, mounted read-only into execution pods."""
+
+    name: str
+    host_path: str
+
+    @property
+    def mount_path(self) -> str:
+        return f"{DATASETS_MOUNT_ROOT}/{self.name}"
+
+
+COMPILE_CACHE_MOUNT_PATH = "/mnt/cache"
+
+
+@dataclass
+class CompileCache:
+    """A node directory of compiler caches (ccache, Go build cache), mounted read-write into execution pods."""
+
+    host_path: str
+    max_size_mb: int = 2048  # The sidecars evict least recently used files above it
+
+
+@dataclass
+class DnsPolicy:
+    """Pod DNS through the sidecar's resolver, which only answers allowlisted names."""
+
+    upstream: str  # DNS server (IP) allowed lookups are forwarded to
+    allowlist: list[str] = field(default_factory=list)  # Applies to every execution; empty allows all
+
+
 @dataclass
 class PodSpec:
     """Specification for creating an execution pod."""
@@ -125,6 +243,43 @@ class PodSpec:
     # Network isolation mode - disables network-dependent features (e.g., Go module proxy)
     network_isolated: bool = False
 
+    # Shared read-only datasets
+    datasets: list[DatasetMount] = field(default_factory=list)
+
+    # Node-shared compiler caches
+    compile_cache: CompileCache | None = None
+
+    # DNS through the sidecar's allowlisting resolver
+    dns_policy: DnsPolicy | None = None
+
+    # Sidecar FAULT_INJECTION spec, for resilience testing
+    fault_injection: str | None = None
+
+    # Policy bundle the sidecar reports and enforces (POLICY_BUNDLE, canonical JSON)
+    policy_bundle: str | None = None
+
+    # Sidecar STRICT_MODE: strict request validation and anomaly logging
+    strict_mode: bool = False
+
+    # Read-only root filesystems in both containers, with an emptyDir at /tmp
+    read_only_root_filesystem: bool = False
+
+    # Volume the working directory is on; 1Gi on local disk when unset
+    workspace_storage: WorkspaceStorage | None = None
+
+    # Job deadline and the sidecar's timeout ceiling; their defaults when unset
+    active_deadline_seconds: int | None = None
+    max_execution_time: int | None = None
+
+
+@dataclass
+class Elevation:
+    """Capabilities of an elevated execution that are applied to its pod."""
+
+    grant_id: str
+    network: bool = False  # Labels the pod for the elevated NetworkPolicy
+    memory_mb: int | None = None
+
 
 @dataclass
 class PoolConfig:
@@ -154,6 +309,30 @@ class PoolConfig:
     # Network isolation mode - disables network-dependent features (e.g., Go module proxy)
     network_isolated: bool = False
 
+    # Shared read-only datasets
+    datasets: list[DatasetMount] = field(default_factory=list)
+
+    # Node-shared compiler caches
+    compile_cache: CompileCache | None = None
+
+    # DNS through the sidecar's allowlisting resolver
+    dns_policy: DnsPolicy | None = None
+
+    # Sidecar FAULT_INJECTION spec, for resilience testing
+    fault_injection: str | None = None
+
+    # Policy bundle the sidecar reports and enforces (POLICY_BUNDLE, canonical JSON)
+    policy_bundle: str | None = None
+
+    # Sidecar STRICT_MODE: strict request validation and anomaly logging
+    strict_mode: bool = False
+
+    # Read-only root filesystems in both containers, with an emptyDir at /tmp
+    read_only_root_filesystem: bool = False
+
+    # Volume the working directory is on; 1Gi on local disk when unset
+    workspace_storage: WorkspaceStorage | None = None
+
     @property
     def uses_pool(self) -> bool:
         """Whether this language uses a warm pod pool."""
@@ -170,6 +349,8 @@ class PooledPod:
     acquired_at: datetime | None = None
     created_at: datetime = field(default_factory=lambda: datetime.now(UTC))
     health_check_failures: int = 0
+    # Mode the pod was created with; the pool's can change at runtime
+    network_isolated: bool = False
 
     @property
     def is_available(self) -> bool:
@@ -200,7 +381,7 @@ class JobHandle:
     def sidecar_url(self) -> str | None:
         """Get the URL for the sidecar HTTP API."""
         if self.pod_ip:
-            return f"http://{self.pod_ip}:8080"
+            return f"http://{url_host(self.pod_ip)}:8080"
         return None
 
     @property
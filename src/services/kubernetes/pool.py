@@ -21,6 +21,7 @@ from .client import (
     get_current_namespace,
 )
 from .models import (
+    ExecutionOptions,
     ExecutionResult,
     FileData,
     PodHandle,
@@ -29,6 +30,7 @@ from .models import (
     PoolConfig,
     PooledPod,
 )
+from .workspace import upload_files
 
 logger = structlog.get_logger(__name__)
 
@@ -161,6 +163,7 @@ class PodPool:
             return None
 
         pod_name = self._generate_pod_name()
+        network_isolated = self.config.network_isolated
 
         labels = {
             "app.kubernetes.io/name": "kubecoderun",
@@ -186,7 +189,15 @@ class PodPool:
             sidecar_cpu_request=self.config.sidecar_cpu_request,
             sidecar_memory_request=self.config.sidecar_memory_request,
             seccomp_profile_type=self.config.seccomp_profile_type,
-            network_isolated=self.config.network_isolated,
+            read_only_root_filesystem=self.config.read_only_root_filesystem,
+            network_isolated=network_isolated,
+            datasets=self.config.datasets,
+            compile_cache=self.config.compile_cache,
+            dns_policy=self.config.dns_policy,
+            fault_injection=self.config.fault_injection,
+            policy_bundle=self.config.policy_bundle,
+            strict_mode=self.config.strict_mode,
+            workspace_storage=self.config.workspace_storage,
         )
 
         try:
@@ -207,7 +218,8 @@ class PodPool:
 
             # Wait for pod to be ready
             ready = await self._wait_for_pod_ready(handle)
-            if not ready:
+            # The network mode may have changed while the pod started
+            if not ready or network_isolated != self.config.network_isolated:
                 await self._delete_pod(handle)
                 return None
 
@@ -216,6 +228,7 @@ class PodPool:
             pooled_pod = PooledPod(
                 handle=handle,
                 language=self.language,
+                network_isolated=network_isolated,
             )
 
             async with self._lock:
@@ -304,6 +317,31 @@ class PodPool:
                     error=str(e),
                 )
 
+    async def drain_stale(self) -> int:
+        """Delete warm pods created with another network mode than the pool's; returns how many.
+
+        The replenish loop refills the pool with pods of the current mode.
+        Acquired pods keep theirs and are destroyed when released.
+        """
+        async with self._lock:
+            stale = [
+                p for p in self._pods.values() if p.is_available and p.network_isolated != self.config.network_isolated
+            ]
+            for pooled_pod in stale:
+                del self._pods[pooled_pod.handle.uid]
+            # Keep the others queued in order, so acquire never picks a drained pod
+            queued = []
+            while not self._available.empty():
+                queued.append(self._available.get_nowait())
+            for uid in queued:
+                if uid in self._pods:
+                    self._available.put_nowait(uid)
+        for pooled_pod in stale:
+            await self._delete_pod(pooled_pod.handle)
+        if stale:
+            logger.info("Drained warm pods of the old network mode", language=self.language, count=len(stale))
+        return len(stale)
+
     async def _replenish_loop(self):
         """Background task to maintain pool size."""
         while self._running:
@@ -334,7 +372,13 @@ class PodPool:
                 )
 
     async def _health_check_loop(self):
-        """Background task to check pod health."""
+        """Background task to check pod health.
+
+        Polls the sidecar's /ready, which also reports the pod degraded when
+        process spawn, interpreter startup or disk latency has slowed well past
+        its baseline. Degraded pods are drained right away; other failures are
+        tolerated twice.
+        """
         while self._running:
             try:
                 await asyncio.sleep(30)
@@ -345,22 +389,32 @@ class PodPool:
                 client = await self._get_http_client()
 
                 for pooled_pod in pods_to_check:
+                    degraded = None
                     try:
                         url = pooled_pod.handle.sidecar_url
                         response = await client.get(
-                            f"{url}/health",
+                            f"{url}/ready",
                             timeout=5,
                         )
-                        if response.status_code != 200:
-                            pooled_pod.health_check_failures += 1
-                        else:
+                        if response.status_code == 200:
                             pooled_pod.health_check_failures = 0
+                        else:
+                            pooled_pod.health_check_failures += 1
+                            degraded = self._degraded_report(response)
 
                     except Exception:
                         pooled_pod.health_check_failures += 1
 
+                    if degraded:
+                        logger.warning(
+                            "Draining degraded pod",
+                            pod_name=pooled_pod.handle.name,
+                            degraded=degraded.get("degraded"),
+                            checks=degraded.get("checks"),
+                        )
+
                     # Remove unhealthy pods
-                    if pooled_pod.health_check_failures >= 3:
+                    if degraded or pooled_pod.health_check_failures >= 3:
                         logger.warning(
                             "Removing unhealthy pod",
                             pod_name=pooled_pod.handle.name,
@@ -379,6 +433,17 @@ class PodPool:
                     error=str(e),
                 )
 
+    @staticmethod
+    def _degraded_report(response: httpx.Response) -> dict | None:
+        """The sidecar's diagnostics if /ready failed because the pod is degraded."""
+        if response.status_code != 503:
+            return None
+        try:
+            detail = response.json().get("detail")
+        except ValueError:
+            return None
+        return detail if isinstance(detail, dict) and detail.get("status") == "degraded" else None
+
     async def acquire(self, session_id: str, timeout: int = 10) -> PodHandle | None:
         """Acquire a warm pod from the pool.
 
@@ -439,7 +504,7 @@ class PodPool:
             if handle.session_id and handle.session_id in self._session_pods:
                 del self._session_pods[handle.session_id]
 
-            if destroy:
+            if destroy or pooled_pod.network_isolated != self.config.network_isolated:
                 # Remove from pool and delete
                 del self._pods[handle.uid]
                 await self._delete_pod(handle)
@@ -467,6 +532,7 @@ class PodPool:
         files: list[FileData] | None = None,
         initial_state: str | None = None,
         capture_state: bool = False,
+        options: ExecutionOptions | None = None,
     ) -> ExecutionResult:
         """Execute code in an acquired pod.
 
@@ -477,36 +543,20 @@ class PodPool:
             files: Files to upload
             initial_state: State to restore
             capture_state: Whether to capture state
+            options: Extra sidecar options (env, template context)
 
         Returns:
             ExecutionResult
         """
         if not handle.pod_ip:
-            return ExecutionResult(
-                exit_code=1,
-                stdout="",
-                stderr="Pod not ready",
-                execution_time_ms=0,
-            )
+            return ExecutionResult.spawn_failed("Pod not ready")
 
         client = await self._get_http_client()
         sidecar_url = handle.sidecar_url
 
-        # Upload files if provided
-        if files:
-            for file_data in files:
-                try:
-                    await client.post(
-                        f"{sidecar_url}/files",
-                        files={"files": (file_data.filename, file_data.content)},
-                        timeout=30,
-                    )
-                except Exception as e:
-                    logger.warning(
-                        "Failed to upload file",
-                        filename=file_data.filename,
-                        error=str(e),
-                    )
+        # Upload files if provided (creating the execution's workspace first)
+        if files or (options and options.workspace):
+            await upload_files(client, sidecar_url, files or [], options)
 
         # Execute code
         try:
@@ -519,11 +569,14 @@ class PodPool:
                 request_data["initial_state"] = initial_state
             if capture_state:
                 request_data["capture_state"] = True
+            if options:
+                request_data.update(options.to_request_data())
 
             response = await client.post(
                 f"{sidecar_url}/execute",
                 json=request_data,
-                timeout=timeout + 10,
+                # Hooks run within the same request
+                timeout=timeout + 10 + (options.hook_seconds if options else 0),
             )
 
             if response.status_code == 200:
@@ -535,14 +588,17 @@ class PodPool:
                     execution_time_ms=data.get("execution_time_ms", 0),
                     state=data.get("state"),
                     state_errors=data.get("state_errors"),
+                    interrupted=data.get("interrupted", False),
+                    error=data.get("error"),
+                    connections=data.get("connections"),
+                    dns_denied=data.get("dns_denied"),
+                    timings=data.get("timings"),
+                    provenance=data.get("provenance"),
+                    sandbox=data.get("sandbox"),
+                    compile_cache=data.get("compile_cache"),
                 )
             else:
-                return ExecutionResult(
-                    exit_code=1,
-                    stdout="",
-                    stderr=f"Sidecar error: {response.status_code}",
-                    execution_time_ms=0,
-                )
+                return ExecutionResult.spawn_failed(f"Sidecar error: {response.status_code}")
 
         except httpx.TimeoutException:
             return ExecutionResult(
@@ -557,12 +613,11 @@ class PodPool:
                 pod_name=handle.name,
                 error=str(e),
             )
-            return ExecutionResult(
-                exit_code=1,
-                stdout="",
-                stderr=f"Execution error: {str(e)}",
-                execution_time_ms=0,
-            )
+            return ExecutionResult.spawn_failed(f"Execution error: {str(e)}")
+
+    def get_session_pods(self, session_id: str) -> list[PodHandle]:
+        """Pods currently acquired by a session (one per running execution)."""
+        return [pod.handle for pod in self._pods.values() if pod.acquired and pod.handle.session_id == session_id]
 
     @property
     def available_count(self) -> int:
@@ -657,16 +712,12 @@ class PodPoolManager:
         files: list[FileData] | None = None,
         initial_state: str | None = None,
         capture_state: bool = False,
+        options: ExecutionOptions | None = None,
     ) -> ExecutionResult:
         """Execute code in an acquired pod."""
         pool = self._pools.get(handle.language)
         if not pool:
-            return ExecutionResult(
-                exit_code=1,
-                stdout="",
-                stderr=f"No pool for language: {handle.language}",
-                execution_time_ms=0,
-            )
+            return ExecutionResult.spawn_failed(f"No pool for language: {handle.language}")
         return await pool.execute(
             handle,
             code,
@@ -674,8 +725,22 @@ class PodPoolManager:
             files,
             initial_state,
             capture_state,
+            options,
         )
 
+    async def set_network_isolated(self, isolated: bool) -> int:
+        """Create pods with network isolation on or off from now on; returns the warm pods drained."""
+        for config in self._configs.values():
+            config.network_isolated = isolated
+        drained = 0
+        for pool in self._pools.values():
+            drained += await pool.drain_stale()
+        return drained
+
+    def get_session_pods(self, session_id: str) -> list[PodHandle]:
+        """Pods acquired by a session across all pools."""
+        return [handle for pool in self._pools.values() for handle in pool.get_session_pods(session_id)]
+
     def get_pool_stats(self) -> dict[str, dict[str, int]]:
         """Get statistics for all pools."""
         stats = {}
@@ -0,0 +1,104 @@
+"""Storage drivers for the working directory of execution pods.
+
+Every pod mounts one volume at /mnt/data, shared by the main container
+(where code runs) and the sidecar (which serves the file API). The driver,
+chosen per deployment with WORKSPACE_STORAGE_DRIVER, decides what backs it:
+
+- ``local``: an emptyDir on the node's disk (the default).
+- ``tmpfs``: an emptyDir in memory, for diskless nodes. Its files count
+  against the pod's memory limit.
+- ``object``: a generic ephemeral volume from WORKSPACE_STORAGE_CLASS, a
+  storage class of a FUSE object storage CSI driver (csi-s3, GeeseFS,
+  mountpoint-s3, ...), for datasets larger than a node's disk. Each pod
+  gets its own volume, deleted with the pod.
+
+The sidecar is told the driver in WORKSPACE_STORAGE, and writes files the
+way the storage supports (see docker/sidecar/executor/storage.py).
+"""
+
+from abc import ABC, abstractmethod
+
+from kubernetes import client
+
+WORKSPACE_VOLUME = "shared-data"
+WORKSPACE_STORAGE_DRIVERS = ("local", "tmpfs", "object")
+
+
+class WorkspaceStorage(ABC):
+    """The volume an execution pod's working directory is on."""
+
+    name: str
+
+    def __init__(self, size_limit: str = "1Gi"):
+        self.size_limit = size_limit
+
+    @abstractmethod
+    def volume(self) -> client.V1Volume:
+        """The pod volume for /mnt/data."""
+
+
+class LocalDiskStorage(WorkspaceStorage):
+    """emptyDir on the node's disk."""
+
+    name = "local"
+
+    def volume(self) -> client.V1Volume:
+        return client.V1Volume(
+            name=WORKSPACE_VOLUME,
+            empty_dir=client.V1EmptyDirVolumeSource(medium="", size_limit=self.size_limit),
+        )
+
+
+class TmpfsStorage(WorkspaceStorage):
+    """emptyDir in memory; counts against the pod's memory limit."""
+
+    name = "tmpfs"
+
+    def volume(self) -> client.V1Volume:
+        return client.V1Volume(
+            name=WORKSPACE_VOLUME,
+            empty_dir=client.V1EmptyDirVolumeSource(medium="Memory", size_limit=self.size_limit),
+        )
+
+
+class ObjectStorage(WorkspaceStorage):
+    """Per-pod ephemeral volume from a FUSE object storage CSI driver's storage class."""
+
+    name = "object"
+
+    def __init__(self, storage_class: str, size_limit: str = "1Gi"):
+        super().__init__(size_limit)
+        self.storage_class = storage_class
+
+    def volume(self) -> client.V1Volume:
+        return client.V1Volume(
+            name=WORKSPACE_VOLUME,
+            ephemeral=client.V1EphemeralVolumeSource(
+                volume_claim_template=client.V1PersistentVolumeClaimTemplate(
+                    metadata=client.V1ObjectMeta(labels={"app.kubernetes.io/component": "workspace"}),
+                    spec=client.V1PersistentVolumeClaimSpec(
+                        access_modes=["ReadWriteOnce"],
+                        storage_class_name=self.storage_class,
+                        resources=client.V1VolumeResourceRequirements(requests={"storage": self.size_limit}),
+                    ),
+                )
+            ),
+        )
+
+
+def build_workspace_storage(driver: str, size_limit: str = "1Gi", storage_class: str | None = None) -> WorkspaceStorage:
+    """Create the driver named ``driver``.
+
+    Raises:
+        ValueError: For an unknown driver, or ``object`` without a storage class
+    """
+    if driver == "local":
+        return LocalDiskStorage(size_limit)
+    if driver == "tmpfs":
+        return TmpfsStorage(size_limit)
+    if driver == "object":
+        if not storage_class:
+            raise ValueError("The object workspace storage driver needs WORKSPACE_STORAGE_CLASS")
+        return ObjectStorage(storage_class, size_limit)
+    expected = ", ".join(WORKSPACE_STORAGE_DRIVERS)
+    raise ValueError(f"Unknown workspace storage driver {driver!r}, expected one of: {expected}")
@@ -0,0 +1,61 @@
+"""File upload to execution pods, into a named workspace if the execution has one.
+
+An execution can run in a named workspace (/mnt/data/<workspace>) with its
+own quota and read-only flag instead of the working directory root. The
+sidecar keeps the workspaces and enforces their policies; the API creates
+the workspace before uploading the execution's files into it, and makes it
+read-only only after the upload, so a read-only workspace still gets its
+inputs.
+"""
+
+from typing import Any
+
+import httpx
+import structlog
+
+from .models import ExecutionOptions, FileData
+
+logger = structlog.get_logger(__name__)
+
+
+async def _put_workspace(client: httpx.AsyncClient, sidecar_url: str, workspace: str, policy: dict[str, Any]) -> None:
+    try:
+        response = await client.put(f"{sidecar_url}/workspaces/{workspace}", json=policy, timeout=30)
+        if response.status_code >= 400:
+            logger.warning("Failed to set up workspace", workspace=workspace, status=response.status_code)
+    except Exception as e:
+        # The execution then fails with WORKSPACE_NOT_FOUND, or runs under the old policy
+        logger.warning("Failed to set up workspace", workspace=workspace, error=str(e))
+
+
+async def upload_files(
+    client: httpx.AsyncClient,
+    sidecar_url: str,
+    files: list[FileData],
+    options: ExecutionOptions | None = None,
+) -> None:
+    """Upload files to the pod's working directory, or to the execution's workspace (created first)."""
+    workspace = options.workspace if options else None
+    policy = dict(options.workspace_policy) if workspace else {}
+    read_only = policy.pop("read_only", False)
+    if workspace:
+        await _put_workspace(client, sidecar_url, workspace, policy)
+
+    for file_data in files:
+        try:
+            kwargs: dict[str, Any] = {"params": {"workspace": workspace}} if workspace else {}
+            await client.post(
+                f"{sidecar_url}/files",
+                files={"files": (file_data.filename, file_data.content)},
+                timeout=30,
+                **kwargs,
+            )
+        except Exception as e:
+            logger.warning(
+                "Failed to upload file",
+                filename=file_data.filename,
+                error=str(e),
+            )
+
+    if workspace and read_only:
+        await _put_workspace(client, sidecar_url, workspace, {**policy, "read_only": True})
@@ -0,0 +1,101 @@
+"""Language server queries against a session's files.
+
+Pods are single-use, so each query takes a warm pod of the language,
+uploads the requested session files (and the inline code, at its path),
+asks the sidecar's language server (see docker/sidecar/executor/lsp.py)
+and destroys the pod. Only languages with a warm pool can be queried:
+Job pods run one execution and exit.
+"""
+
+from typing import Any
+
+import httpx
+import structlog
+
+from ..models.errors import ExternalServiceError, ResourceNotFoundError, ServiceUnavailableError, ValidationError
+from ..models.lsp import LspQueryRequest, LspQueryResponse
+from ..utils.id_generator import generate_session_id
+from .kubernetes.models import FileData
+from .kubernetes.workspace import upload_files
+
+logger = structlog.get_logger(__name__)
+
+# Starting a server and waiting for its diagnostics can take a while on a fresh pod
+LSP_QUERY_TIMEOUT = 90.0
+
+
+class LspProxyService:
+    """Runs language server queries in warm pods."""
+
+    def __init__(self, kubernetes_manager: Any, file_service: Any):
+        self.kubernetes_manager = kubernetes_manager
+        self.file_service = file_service
+
+    async def _session_files(self, request: LspQueryRequest) -> list[FileData]:
+        files = []
+        for file_ref in request.files:
+            info = await self.file_service.get_file_info(file_ref.session_id, file_ref.id)
+            content = await self.file_service.get_file_content(file_ref.session_id, file_ref.id) if info else None
+            if content is None:
+                raise ResourceNotFoundError("File", file_ref.id)
+            files.append(FileData(filename=info.filename, content=content, session_id=file_ref.session_id))
+        return files
+
+    async def query(self, request: LspQueryRequest) -> LspQueryResponse:
+        """Ask a language server about ``request.path``.
+
+        Raises:
+            ServiceUnavailableError: If the language has no warm pod available
+            ResourceNotFoundError: If a file (or the path in the pod) doesn't exist
+            ValidationError: If the sidecar has no language server for the file type
+            ExternalServiceError: If the language server failed
+        """
+        if not self.kubernetes_manager:
+            raise ServiceUnavailableError("Language Server", "Kubernetes is not available")
+        files = await self._session_files(request)
+
+        session_id = request.files[0].session_id if request.files else generate_session_id()
+        handle, _ = await self.kubernetes_manager.acquire_pod(session_id, request.language)
+        if not handle:
+            raise ServiceUnavailableError(
+                "Language Server", f"Language server queries need a warm pod pool for {request.language}"
+            )
+        try:
+            async with httpx.AsyncClient(timeout=LSP_QUERY_TIMEOUT) as client:
+                if files:
+                    await upload_files(client, handle.sidecar_url, files)
+                if request.code is not None:
+                    await client.patch(
+                        f"{handle.sidecar_url}/files/{request.path}",
+                        content=request.code.encode(),
+                        params={"offset": 0},
+                    )
+                response = await client.post(
+                    f"{handle.sidecar_url}/lsp/{request.method}",
+                    json={"path": request.path, "line": request.line, "column": request.column},
+                )
+        except httpx.HTTPError as e:
+            logger.warning("Language server query failed", method=request.method, error=str(e))
+            raise ExternalServiceError("Language Server", f"Language server query failed: {e}")
+        finally:
+            await self.kubernetes_manager.destroy_pod(handle)
+
+        if response.status_code >= 400:
+            detail = response.json().get("detail", response.text) if response.content else response.reason_phrase
+            if response.status_code == 404:
+                raise ResourceNotFoundError("File", request.path)
+            if response.status_code in (400, 415):
+                raise ValidationError(str(detail))
+            if response.status_code == 503:
+                raise ServiceUnavailableError("Language Server", str(detail))
+            raise ExternalServiceError("Language Server", str(detail))
+
+        data = response.json()
+        return LspQueryResponse(
+            method=request.method,
+            path=request.path,
+            server=data.get("server", ""),
+            diagnostics=data.get("diagnostics"),
+            hover=data.get("hover"),
+            locations=data.get("locations"),
+        )
@@ -0,0 +1,230 @@
+"""Session export - a session's cell history as a notebook, page or script.
+
+GET /sessions/{id}/export assembles the recorded cells (see cells.py) into:
+
+- ipynb: a Jupyter notebook (nbformat 4) with stdout/stderr as stream
+  outputs and generated images embedded as display data
+- html: a standalone page with the same content
+- py: a script of the Python cells in percent format (``# %%``); cells in
+  other languages are kept as comments
+
+Outputs are the truncated copies stored with each cell, so long outputs are
+cut in the export as well.
+"""
+
+import base64
+import html
+import json
+from collections import Counter
+from pathlib import PurePosixPath
+from typing import Any
+
+import structlog
+
+from ..config.languages import get_language
+from ..models.cell import CellInfo
+
+logger = structlog.get_logger(__name__)
+
+EXPORT_FORMATS = ("ipynb", "html", "py")
+
+EXPORT_MEDIA_TYPES = {
+    "ipynb": "application/x-ipynb+json",
+    "html": "text/html; charset=utf-8",
+    "py": "text/x-python; charset=utf-8",
+}
+
+IMAGE_MIME_TYPES = {
+    ".png": "image/png",
+    ".jpg": "image/jpeg",
+    ".jpeg": "image/jpeg",
+    ".gif": "image/gif",
+    ".svg": "image/svg+xml",
+}
+
+# Larger images are linked by name instead of embedded
+MAX_EMBEDDED_IMAGE_BYTES = 5 * 1024 * 1024
+
+# Jupyter kernels for the languages that commonly have one
+KERNELSPECS = {
+    "py": {"name": "python3", "display_name": "Python 3", "language": "python"},
+    "r": {"name": "ir", "display_name": "R", "language": "R"},
+    "js": {"name": "javascript", "display_name": "JavaScript (Node.js)", "language": "javascript"},
+    "ts": {"name": "tslab", "display_name": "TypeScript", "language": "typescript"},
+}
+
+# Image payloads keyed by file id: (mime type, content)
+Images = dict[str, tuple[str, bytes]]
+
+
+def image_mime_type(filename: str) -> str | None:
+    """Mime type of an image file that can be embedded, by extension."""
+    return IMAGE_MIME_TYPES.get(PurePosixPath(filename).suffix.lower())
+
+
+async def load_images(file_service: Any, session_id: str, cells: list[CellInfo]) -> Images:
+    """Fetch the images generated by the cells so they can be embedded.
+
+    Missing or oversized files are skipped; the export then only names them.
+    """
+    images: Images = {}
+    for cell in cells:
+        for ref in cell.files:
+            mime = image_mime_type(ref.name)
+            if not mime or ref.id in images:
+                continue
+            try:
+                content = await file_service.get_file_content(session_id, ref.id)
+            except Exception as e:
+                logger.warning("Failed to load image for export", file_id=ref.id, error=str(e))
+                continue
+            if content and len(content) <= MAX_EMBEDDED_IMAGE_BYTES:
+                images[ref.id] = (mime, content)
+    return images
+
+
+def _language_name(lang: str) -> str:
+    config = get_language(lang)
+    return config.name if config else lang
+
+
+def _kernelspec(cells: list[CellInfo]) -> dict[str, str]:
+    """Kernel for the session's most used language."""
+    lang = Counter(cell.lang for cell in cells).most_common(1)[0][0] if cells else "py"
+    name = _language_name(lang)
+    return KERNELSPECS.get(lang, {"name": lang, "display_name": name, "language": name.lower()})
+
+
+def _lines(text: str) -> list[str]:
+    """Split text the way nbformat stores multiline strings."""
+    return text.splitlines(keepends=True)
+
+
+def _cell_outputs(cell: CellInfo, images: Images) -> list[dict[str, Any]]:
+    outputs: list[dict[str, Any]] = []
+    for name, text in (("stdout", cell.stdout), ("stderr", cell.stderr)):
+        if text:
+            outputs.append({"output_type": "stream", "name": name, "text": _lines(text)})
+    for ref in cell.files:
+        if ref.id in images:
+            mime, content = images[ref.id]
+            data = content.decode("utf-8", "replace") if mime == "image/svg+xml" else base64.b64encode(content).decode()
+            outputs.append(
+                {"output_type": "display_data", "data": {mime: data, "text/plain": [ref.name]}, "metadata": {}}
+            )
+    return outputs
+
+
+def build_notebook(cells: list[CellInfo], images: Images, session_id: str) -> dict[str, Any]:
+    """Assemble cells into an nbformat 4 notebook."""
+    kernelspec = _kernelspec(cells)
+    nb_cells = []
+    for cell in cells:
+        nb_cells.append(
+            {
+                "id": f"cell-{cell.cell_id}",
+                "cell_type": "code",
+                "execution_count": cell.cell_id,
+                "metadata": {
+                    "kubecoderun": {
+                        "lang": cell.lang,
+                        "status": cell.status,
+                        "exit_code": cell.exit_code,
+                        "started_at": cell.started_at.isoformat(),
+                        "duration_ms": cell.duration_ms,
+                        "files": [ref.name for ref in cell.files],
+                        "output_truncated": cell.output_truncated,
+                        "rerun_of": cell.rerun_of,
+                    }
+                },
+                "source": _lines(cell.code),
+                "outputs": _cell_outputs(cell, images),
+            }
+        )
+    return {
+        "nbformat": 4,
+        "nbformat_minor": 5,
+        "metadata": {
+            "kernelspec": kernelspec,
+            "language_info": {"name": kernelspec["language"]},
+            "kubecoderun": {"session_id": session_id},
+        },
+        "cells": nb_cells,
+    }
+
+
+def render_ipynb(cells: list[CellInfo], images: Images, session_id: str) -> str:
+    """Render cells as notebook JSON."""
+    return json.dumps(build_notebook(cells, images, session_id), indent=1, ensure_ascii=False) + "\n"
+
+
+_HTML_STYLE = """
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; }
+.cell { margin-bottom: 1.5em; }
+.prompt { color: #888; font-size: 0.85em; }
+pre { background: #f6f8fa; padding: 0.75em; overflow-x: auto; margin: 0.25em 0; }
+pre.stderr { background: #fff0f0; }
+.failed .prompt { color: #c00; }
+img { max-width: 100%; }
+"""
+
+
+def render_html(cells: list[CellInfo], images: Images, session_id: str) -> str:
+    """Render cells as a standalone HTML page."""
+    title = html.escape(f"Session {session_id}")
+    parts = [
+        "<!DOCTYPE html>",
+        '<html><head><meta charset="utf-8">',
+        f"<title>{title}</title><style>{_HTML_STYLE}</style></head><body>",
+        f"<h1>{title}</h1>",
+    ]
+    for cell in cells:
+        css = "cell" if cell.status == "completed" else "cell failed"
+        prompt = f"In [{cell.cell_id}] {_language_name(cell.lang)} - {cell.status}"
+        if cell.duration_ms is not None:
+            prompt += f" ({cell.duration_ms} ms)"
+        parts.append(f'<div class="{css}"><div class="prompt">{html.escape(prompt)}</div>')
+        parts.append(f'<pre class="code">{html.escape(cell.code)}</pre>')
+        if cell.stdout:
+            parts.append(f'<pre class="stdout">{html.escape(cell.stdout)}</pre>')
+        if cell.stderr:
+            parts.append(f'<pre class="stderr">{html.escape(cell.stderr)}</pre>')
+        for ref in cell.files:
+            if ref.id in images:
+                mime, content = images[ref.id]
+                src = f"data:{mime};base64,{base64.b64encode(content).decode()}"
+                parts.append(f'<img src="{src}" alt="{html.escape(ref.name, quote=True)}">')
+            else:
+                parts.append(f'<div class="prompt">Generated file: {html.escape(ref.name)}</div>')
+        if cell.output_truncated:
+            parts.append('<div class="prompt">Output truncated</div>')
+        parts.append("</div>")
+    parts.append("</body></html>")
+    return "\n".join(parts) + "\n"
+
+
+def render_script(cells: list[CellInfo], session_id: str) -> str:
+    """Render the Python cells as a percent-format script.
+
+    Cells in other languages are kept, commented out, so the script shows
+    the whole session but still runs as Python.
+    """
+    parts = [f"# Exported from session {session_id}\n"]
+    for cell in cells:
+        if cell.lang == "py":
+            parts.append(f"# %% In [{cell.cell_id}]\n{cell.code.rstrip()}\n")
+        else:
+            commented = "\n".join(f"# {line}" if line else "#" for line in cell.code.rstrip().splitlines())
+            parts.append(f"# %% In [{cell.cell_id}] ({_language_name(cell.lang)}, not Python)\n{commented}\n")
+    return "\n".join(parts)
+
+
+def render_export(fmt: str, cells: list[CellInfo], images: Images, session_id: str) -> str:
+    """Render cells in one of EXPORT_FORMATS."""
+    if fmt == "ipynb":
+        return render_ipynb(cells, images, session_id)
+    if fmt == "html":
+        return render_html(cells, images, session_id)
+    if fmt == "py":
+        return render_script(cells, session_id)
+    raise ValueError(f"Unsupported export format: {fmt}")
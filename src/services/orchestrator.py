@@ -17,37 +17,80 @@ Usage:
 
 import asyncio
 import base64
-from dataclasses import dataclass
-from datetime import datetime
+from dataclasses import dataclass, field
+from datetime import UTC, datetime
 from typing import Any, Dict, List, Optional
 
 import structlog
+from pydantic import ValidationError as PydanticValidationError
 
 from ..config import settings
-from ..config.languages import is_supported_language
+from ..config.languages import get_language, is_supported_language
 from ..core.events import ExecutionCompleted, event_bus
 from ..models import (
+    ArtifactMetadata,
+    AuthorizationError,
+    CellInfo,
     CodeExecution,
+    CompileCacheStats,
+    ExecError,
+    ExecPlanFile,
+    ExecPlanLimits,
+    ExecPlanRateLimit,
+    ExecPlanResponse,
     ExecRequest,
     ExecResponse,
+    ExecTimings,
     ExecuteCodeRequest,
     ExecutionError,
     FileRef,
+    RequestFile,
+    ResourceConflictError,
     ResourceNotFoundError,
+    SandboxReport,
+    SecretFinding,
     ServiceUnavailableError,
     SessionCreate,
+    SessionStatus,
     TimeoutError,
     ValidationError,
 )
+from ..models.elevation import ElevatedCapabilities, ElevatedGrant
 from ..models.errors import ErrorDetail
+from ..models.image_catalog import ResolvedImage
 from ..models.metrics import DetailedExecutionMetrics
+from ..models.session import REDACTED_VALUE
+from ..utils.security import SecurityValidator
+from .api_key_manager import get_api_key_manager
+from .artifact_metadata import describe_artifact
+from .cells import CellHistoryService
+from .concurrency import execution_gate
+from .context import execution_env
+from .elevation import ElevationService
+from .env_snapshots import EnvironmentSnapshotService
+from .image_catalog import ImageCatalogError, ImageCatalogService
 from .interfaces import (
     ExecutionServiceInterface,
     FileServiceInterface,
     SessionServiceInterface,
 )
+from .kubernetes.models import CONNECTION_LIMIT_EXCEEDED, SPAWN_FAILED
+from .output_filters import filter_stream
+from .retry import OOM, backoff_seconds, classify_failure, reduced_parallelism_env
+from .sandbox_report import build_sandbox_report
+from .secret_scan import audit_findings, scan_file, scan_output
+from .session_report import SessionReportService, execution_violations
+from .session_transfer import installed_packages
 from .state import StateService
 from .state_archival import StateArchivalService
+from .timeout_advisor import TimeoutAdvisor
+from .workspace_lock import (
+    WORKING_DIR_PREFIX,
+    WORKSPACE_ROOT,
+    WorkspaceLockService,
+    normalize_scope_path,
+    paths_overlap,
+)
 
 logger = structlog.get_logger(__name__)
 
@@ -64,11 +107,33 @@ class ExecutionContext:
     generated_files: list[FileRef] | None = None
     stdout: str = ""
     stderr: str = ""
+    # Streams as they were before the collapse filter shortened them, by name (OUTPUT_COLLAPSE_FULL_LOG)
+    full_logs: dict[str, str] | None = None
     container: Any | None = None  # Container used for execution (avoids session lookup)
     # State persistence fields
     initial_state: str | None = None
     new_state: str | None = None
     state_errors: list[str] | None = None
+    # Environment variables stored on the session (PUT /sessions/{id}/env)
+    session_env: dict[str, str] | None = None
+    # Workspace locking (normalized scope and the token held while executing)
+    scope: list[str] | None = None
+    lock_token: str | None = None
+    response: ExecResponse | None = None
+    # Cell history (the cell re-run by POST /sessions/{id}/cells/{n}/run, and the cell recorded)
+    rerun_of: int | None = None
+    cell: CellInfo | None = None
+    # Credentials found in output/generated files (ARTIFACT_SECRET_SCAN)
+    secret_findings: list[SecretFinding] | None = None
+    # Protections the execution ran under, whether or not the response includes them
+    sandbox: SandboxReport | None = None
+    # Retries (ExecRequest.retry): attempts made and the failure class of each retried one
+    attempts: int = 1
+    retried_on: list[str] = field(default_factory=list)
+    # Elevated grant the execution runs under (ExecRequest.elevation_grant)
+    elevation: ElevatedGrant | None = None
+    # Catalog image the execution runs in (ExecRequest.image or the API key's pin); None for the language's image
+    image: ResolvedImage | None = None
     # Metrics tracking fields
     api_key_hash: str | None = None
     is_env_key: bool = False
@@ -96,12 +161,32 @@ class ExecutionOrchestrator:
         execution_service: ExecutionServiceInterface,
         state_service: StateService | None = None,
         state_archival_service: StateArchivalService | None = None,
+        workspace_lock_service: WorkspaceLockService | None = None,
+        cell_history_service: CellHistoryService | None = None,
+        timeout_advisor: TimeoutAdvisor | None = None,
+        elevation_service: ElevationService | None = None,
+        image_catalog_service: ImageCatalogService | None = None,
+        env_snapshot_service: EnvironmentSnapshotService | None = None,
+        session_report_service: SessionReportService | None = None,
     ):
         self.session_service = session_service
         self.file_service = file_service
         self.execution_service = execution_service
         self.state_service = state_service or StateService()
         self.state_archival_service = state_archival_service
+        # Without a lock service executions in a session are not coordinated
+        self.workspace_lock_service = workspace_lock_service
+        # Without a cell history service executions are not recorded as cells
+        self.cell_history_service = cell_history_service
+        # Without a timeout advisor durations aren't recorded and no timeout is suggested
+        self.timeout_advisor = timeout_advisor
+        # Without an elevation service requests carrying a grant are refused
+        self.elevation_service = elevation_service
+        self.image_catalog_service = image_catalog_service or ImageCatalogService()
+        # Without an environment snapshot service what executions change isn't recorded
+        self.env_snapshot_service = env_snapshot_service
+        # Without a session report service executions aren't tallied for the end-of-session report
+        self.session_report_service = session_report_service
 
     async def execute(
         self,
@@ -109,6 +194,7 @@ class ExecutionOrchestrator:
         request_id: str = "",
         api_key_hash: str | None = None,
         is_env_key: bool = False,
+        rerun_of: int | None = None,
     ) -> ExecResponse:
         """Execute code and return LibreChat-compatible response.
 
@@ -117,33 +203,64 @@ class ExecutionOrchestrator:
             request_id: Optional request ID for logging
             api_key_hash: Hash of the API key for metrics tracking
             is_env_key: True if using env var API key (no rate limiting)
+            rerun_of: Cell number this execution re-runs, recorded in the cell history
 
         Returns:
             ExecResponse: LibreChat-compatible response with session_id, files, stdout, stderr
         """
+        ctx = await self.run(request, request_id, api_key_hash=api_key_hash, is_env_key=is_env_key, rerun_of=rerun_of)
+        return ctx.response
+
+    async def run(
+        self,
+        request: ExecRequest,
+        request_id: str = "",
+        api_key_hash: str | None = None,
+        is_env_key: bool = False,
+        rerun_of: int | None = None,
+    ) -> ExecutionContext:
+        """Run the execution pipeline and return its context.
+
+        Same as execute(), but callers that need more than the LibreChat
+        response (e.g. the exit code in ctx.execution) get the whole context;
+        the response is in ctx.response.
+        """
         ctx = ExecutionContext(
             request=request,
             request_id=request_id,
             api_key_hash=api_key_hash,
             is_env_key=is_env_key,
             execution_start_time=datetime.now(),
+            rerun_of=rerun_of,
         )
 
         try:
             # Step 1: Validate request
             self._validate_request(ctx)
 
+            # Step 1.5: Pick the catalog image, if the request asks for one or its API key pins one
+            await self._resolve_image(ctx)
+
             # Step 2: Get or create session
             ctx.session_id = await self._get_or_create_session(ctx)
 
+            # Step 2.05: Check the elevated grant, if the request carries one
+            await self._redeem_elevation(ctx)
+
+            # Step 2.1: Wait for overlapping executions/locks in the session
+            await self._acquire_workspace_lock(ctx)
+
             # Step 2.5: Load previous state (Python only)
             await self._load_state(ctx)
 
+            # Step 2.6: Load stored session environment
+            await self._load_session_env(ctx)
+
             # Step 3: Mount files
             ctx.mounted_files = await self._mount_files(ctx)
 
-            # Step 4: Execute code (with state)
-            ctx.execution = await self._execute_code(ctx)
+            # Step 4: Execute code (with state), retrying transient failures if requested
+            ctx.execution = await self._execute_with_retry(ctx)
 
             # Step 5: Handle generated files
             ctx.generated_files = await self._handle_generated_files(ctx)
@@ -151,21 +268,38 @@ class ExecutionOrchestrator:
             # Step 6: Extract outputs
             self._extract_outputs(ctx)
 
+            # Step 6.1: Keep the full streams collapse shortened as generated files
+            await self._store_full_logs(ctx)
+
             # Step 6.5: Save new state (Python only)
             await self._save_state(ctx)
 
             # Step 7: Build response
-            response = self._build_response(ctx)
+            ctx.response = self._build_response(ctx)
+
+            # Step 7.5: Record the execution in the session's cell history
+            await self._record_cell(ctx)
+
+            # Step 7.55: Snapshot the session's environment and record what the execution changed
+            await self._record_changes(ctx)
+
+            # Step 7.6: Record the duration and suggest a timeout for the next run
+            await self._suggest_timeout(ctx)
+
+            # Step 7.7: Tally the execution and the policies it ran into for the end-of-session report
+            await self._record_report(ctx)
 
             # Step 8: Cleanup
             await self._cleanup(ctx)
 
-            return response
+            return ctx
 
         except (
             ValidationError,
+            AuthorizationError,
             ExecutionError,
             TimeoutError,
+            ResourceConflictError,
             ResourceNotFoundError,
             ServiceUnavailableError,
         ):
@@ -179,39 +313,261 @@ class ExecutionOrchestrator:
                 service="Code Execution",
                 message=f"Unexpected error during code execution: {str(e)}",
             )
+        finally:
+            await self._release_workspace_lock(ctx)
+
+    async def plan(
+        self,
+        request: ExecRequest,
+        api_key_hash: str | None = None,
+        is_env_key: bool = False,
+    ) -> ExecPlanResponse:
+        """Describe what executing the request would enforce, without running it.
+
+        The request goes through execute()'s validation and its session and
+        file lookups, but no session is created, nothing is locked and no
+        code runs. Every violation is reported, not just the first.
+        """
+        ctx = ExecutionContext(request=request, request_id="", api_key_hash=api_key_hash, is_env_key=is_env_key)
+        violations = [detail for error in self._request_errors(ctx) for detail in error.details]
+        warnings = []
+        try:
+            await self._resolve_image(ctx)
+        except (ValidationError, AuthorizationError) as e:
+            violations.extend(e.details)
+
+        ctx.session_id = await self._find_session(ctx)
+        if request.elevation_grant:
+            try:
+                await self._check_elevation(ctx)
+            except (AuthorizationError, ServiceUnavailableError) as e:
+                violations.append(ErrorDetail(field="elevation_grant", message=e.message, code="elevation_refused"))
+        if ctx.session_id:
+            await self._load_session_env(ctx)
+            if self.workspace_lock_service and not request.lock_token and ctx.scope:
+                locks = await self.workspace_lock_service.list_locks(ctx.session_id)
+                held = sorted(
+                    {
+                        path or "/"
+                        for lock in locks
+                        for path in lock["paths"]
+                        if any(paths_overlap(path, p) for p in ctx.scope)
+                    }
+                )
+                if held:
+                    warnings.append(
+                        f"Workspace is locked ({', '.join(held)}); the execution would wait up to "
+                        f"{settings.session_lock_wait_seconds}s for the lock"
+                    )
+
+        files = []
+        for file_ref in request.files:
+            file_info = await self._find_file(file_ref)
+            if not file_info:
+                warnings.append(f"File {file_ref.name} ({file_ref.id}) not found; it would not be mounted")
+            files.append(
+                ExecPlanFile(
+                    id=file_ref.id,
+                    name=file_ref.name,
+                    session_id=file_ref.session_id,
+                    found=file_info is not None,
+                    path=file_info.path if file_info else None,
+                    size=file_info.size if file_info else None,
+                )
+            )
+
+        rate_limits = []
+        if api_key_hash and not is_env_key and settings.rate_limit_enabled:
+            try:
+                manager = await get_api_key_manager()
+                statuses = await manager.get_rate_limit_status(api_key_hash)
+            except Exception as e:
+                logger.warning("Failed to get rate limit status", error=str(e))
+                warnings.append("Rate limit usage is unavailable")
+                statuses = []
+            for status in statuses:
+                if status.limit is None:
+                    continue
+                rate_limits.append(
+                    ExecPlanRateLimit(
+                        period=status.period,
+                        limit=status.limit,
+                        used=status.used,
+                        remaining=status.remaining,
+                        exceeded=status.is_exceeded,
+                    )
+                )
+                if status.is_exceeded:
+                    violations.append(
+                        ErrorDetail(
+                            message=f"Rate limit exceeded: {status.used}/{status.limit} {status.period}",
+                            code="rate_limit_exceeded",
+                        )
+                    )
+
+        language = get_language(request.lang)
+        image = settings.get_image_for_language(language.code) if language else None
+        capabilities = ctx.elevation.capabilities if ctx.elevation else ElevatedCapabilities()
+        return ExecPlanResponse(
+            allowed=not violations,
+            violations=violations,
+            warnings=warnings,
+            language=request.lang,
+            image=ctx.image.image if ctx.image else image,
+            command=language.execution_command if language else None,
+            binary=language.execution_command.split()[0] if language else None,
+            user_id=language.user_id if language else None,
+            working_directory=f"{WORKING_DIR_PREFIX}/{request.workspace}" if request.workspace else WORKING_DIR_PREFIX,
+            session_id=ctx.session_id,
+            scope=[path or "/" for path in ctx.scope or []],
+            # Stored session values are never returned (see GET /sessions/{id}/env)
+            env=execution_env(dict.fromkeys(ctx.session_env or {}, REDACTED_VALUE), request.env),
+            limits=ExecPlanLimits(
+                timeout_seconds=self._timeout(ctx),
+                cpu_limit=settings.k8s_sidecar_cpu_limit,
+                # An elevated grant's memory and network are applied to a dedicated pod
+                memory_limit=(
+                    f"{capabilities.memory_mb}Mi" if capabilities.memory_mb else settings.k8s_sidecar_memory_limit
+                ),
+                max_output_files=settings.max_output_files,
+                max_file_size_mb=settings.max_file_size_mb,
+                network_isolated=settings.enable_network_isolation and not capabilities.network,
+                max_connections=self._max_connections(request, ctx.elevation),
+            ),
+            priority=request.priority,
+            files=files,
+            rate_limits=rate_limits,
+        )
 
     def _validate_request(self, ctx: ExecutionContext) -> None:
         """Validate the execution request."""
+        errors = self._request_errors(ctx)
+        if errors:
+            raise errors[0]
+
+    def _request_errors(self, ctx: ExecutionContext) -> list[ValidationError]:
+        """Check the request against every validation rule, normalizing its scope."""
         request = ctx.request
+        errors = []
 
         # Validate language
         if not is_supported_language(request.lang):
             logger.error("Unsupported language", language=request.lang)
-            raise ValidationError(
-                message=f"Unsupported programming language: {request.lang}",
-                details=[
-                    ErrorDetail(
-                        field="lang",
-                        message=f"Language '{request.lang}' is not supported",
-                        code="unsupported_language",
-                    )
-                ],
+            errors.append(
+                ValidationError(
+                    message=f"Unsupported programming language: {request.lang}",
+                    details=[
+                        ErrorDetail(
+                            field="lang",
+                            message=f"Language '{request.lang}' is not supported",
+                            code="unsupported_language",
+                        )
+                    ],
+                )
+            )
+        elif not settings.is_language_allowed(request.lang):
+            errors.append(
+                ValidationError(
+                    message=f"Language not allowed: {request.lang}",
+                    details=[
+                        ErrorDetail(
+                            field="lang",
+                            message=f"Language '{request.lang}' is not allowed by this deployment's policy",
+                            code="language_not_allowed",
+                        )
+                    ],
+                )
             )
 
         # Validate code content
         if not request.code or not request.code.strip():
             logger.error("Empty code provided")
-            raise ValidationError(
-                message="Code cannot be empty",
-                details=[
-                    ErrorDetail(
-                        field="code",
-                        message="Code field is required and cannot be empty",
-                        code="empty_code",
+            errors.append(
+                ValidationError(
+                    message="Code cannot be empty",
+                    details=[
+                        ErrorDetail(
+                            field="code",
+                            message="Code field is required and cannot be empty",
+                            code="empty_code",
+                        )
+                    ],
+                )
+            )
+
+        # Validate and normalize the declared workspace scope
+        try:
+            ctx.scope = sorted({normalize_scope_path(path) for path in request.scope or [WORKSPACE_ROOT]})
+        except ValueError as e:
+            errors.append(
+                ValidationError(
+                    message="Invalid execution scope",
+                    details=[ErrorDetail(field="scope", message=str(e), code="invalid_scope")],
+                )
+            )
+
+        # Validate environment variable names
+        invalid_env = SecurityValidator.invalid_env_names(request.env or {})
+        if invalid_env:
+            errors.append(
+                ValidationError(
+                    message="Invalid environment variable names",
+                    details=[
+                        ErrorDetail(
+                            field="env",
+                            message=f"Invalid names: {', '.join(invalid_env)}",
+                            code="invalid_env_name",
+                        )
+                    ],
+                )
+            )
+
+        # Validate the DNS allowlist; it can only be enforced by pods resolving through the sidecar
+        if request.dns_allowlist is not None:
+            invalid_dns = SecurityValidator.invalid_dns_patterns(request.dns_allowlist)
+            if not settings.dns_policy_upstream:
+                errors.append(
+                    ValidationError(
+                        message="DNS allowlists aren't enabled",
+                        details=[
+                            ErrorDetail(
+                                field="dns_allowlist",
+                                message="Set DNS_POLICY_UPSTREAM to enforce DNS allowlists",
+                                code="dns_policy_unavailable",
+                            )
+                        ],
+                    )
+                )
+            elif invalid_dns:
+                errors.append(
+                    ValidationError(
+                        message="Invalid DNS allowlist",
+                        details=[
+                            ErrorDetail(
+                                field="dns_allowlist",
+                                message=f"Not a name or *.name: {', '.join(invalid_dns)}",
+                                code="invalid_dns_allowlist",
+                            )
+                        ],
                     )
-                ],
+                )
+
+        if request.workspace_policy and not request.workspace:
+            errors.append(
+                ValidationError(
+                    message="workspace_policy needs a workspace",
+                    details=[
+                        ErrorDetail(
+                            field="workspace_policy",
+                            message="Name the workspace the policy applies to",
+                            code="workspace_required",
+                        )
+                    ],
+                )
             )
 
+        return errors
+
     async def _get_or_create_session(self, ctx: ExecutionContext) -> str:
         """Get existing session or create new one.
 
@@ -221,18 +577,44 @@ class ExecutionOrchestrator:
         3. Reuse session by entity_id (for session continuity within same entity)
         4. Create new session
         """
+        session_id = await self._find_session(ctx)
+        if session_id:
+            return session_id
+
+        request = ctx.request
+        metadata = {}
+        if request.entity_id:
+            metadata["entity_id"] = request.entity_id
+        if request.user_id:
+            metadata["user_id"] = request.user_id
+
+        session = await self.session_service.create_session(SessionCreate(metadata=metadata))
+        logger.info("Created new session", session_id=session.session_id)
+        return session.session_id
+
+    @staticmethod
+    def _reject_quarantined(session: Any) -> None:
+        """Quarantined sessions (POST /admin/quarantine) take no new executions."""
+        if session and session.status == SessionStatus.QUARANTINED:
+            raise ResourceConflictError(message=f"Session {session.session_id} is quarantined")
+
+    async def _find_session(self, ctx: ExecutionContext) -> str | None:
+        """The active session the request would reuse, in _get_or_create_session's priority order."""
         request = ctx.request
 
         # Priority 1: Use explicit session_id from request (for state persistence)
         if request.session_id:
             try:
                 existing = await self.session_service.get_session(request.session_id)
+                self._reject_quarantined(existing)
                 if existing and existing.status.value == "active":
                     logger.info(
                         "Reusing session from request",
                         session_id=request.session_id[:12],
                     )
                     return request.session_id
+            except ResourceConflictError:
+                raise
             except Exception as e:
                 logger.warning(
                     "Error looking up session from request",
@@ -246,12 +628,15 @@ class ExecutionOrchestrator:
                 if file_ref.session_id:
                     try:
                         existing = await self.session_service.get_session(file_ref.session_id)
+                        self._reject_quarantined(existing)
                         if existing and existing.status.value == "active":
                             logger.info(
                                 "Reusing session from file reference",
                                 session_id=file_ref.session_id,
                             )
                             return file_ref.session_id
+                    except ResourceConflictError:
+                        raise
                     except Exception as e:
                         logger.warning(
                             "Error looking up session",
@@ -279,16 +664,7 @@ class ExecutionOrchestrator:
                     error=str(e),
                 )
 
-        # Create new session
-        metadata = {}
-        if request.entity_id:
-            metadata["entity_id"] = request.entity_id
-        if request.user_id:
-            metadata["user_id"] = request.user_id
-
-        session = await self.session_service.create_session(SessionCreate(metadata=metadata))
-        logger.info("Created new session", session_id=session.session_id)
-        return session.session_id
+        return None
 
     async def _mount_files(self, ctx: ExecutionContext) -> list[dict[str, Any]]:
         """Mount files for code execution."""
@@ -299,17 +675,7 @@ class ExecutionOrchestrator:
         mounted_ids = set()
 
         for file_ref in ctx.request.files:
-            # Get file info - try by ID first
-            file_info = await self.file_service.get_file_info(file_ref.session_id, file_ref.id)
-
-            # Fallback: lookup by name or ID match
-            if not file_info:
-                session_files = await self.file_service.list_files(file_ref.session_id)
-                for f in session_files:
-                    if f.filename == file_ref.name or f.filename == file_ref.id or f.file_id == file_ref.id:
-                        file_info = f
-                        break
-
+            file_info = await self._find_file(file_ref)
             if not file_info:
                 logger.warning(
                     "File not found",
@@ -358,6 +724,97 @@ class ExecutionOrchestrator:
 
         return mounted
 
+    async def _find_file(self, file_ref: RequestFile) -> Any | None:
+        """Info of a requested file, looked up by ID, then by name."""
+        # Get file info - try by ID first
+        file_info = await self.file_service.get_file_info(file_ref.session_id, file_ref.id)
+        if file_info:
+            return file_info
+
+        # Fallback: lookup by name or ID match
+        session_files = await self.file_service.list_files(file_ref.session_id)
+        for f in session_files:
+            if f.filename == file_ref.name or f.filename == file_ref.id or f.file_id == file_ref.id:
+                return f
+        return None
+
+    async def _resolve_image(self, ctx: ExecutionContext) -> None:
+        """Resolve the catalog image the execution runs in (ValidationError or AuthorizationError if refused)."""
+        try:
+            ctx.image = await self.image_catalog_service.resolve(
+                ctx.request.lang, ctx.request.image, ctx.api_key_hash, ctx.is_env_key
+            )
+        except ImageCatalogError as e:
+            error = AuthorizationError if e.code == "image_not_allowed" else ValidationError
+            raise error(message=str(e), details=[ErrorDetail(field="image", message=str(e), code=e.code)])
+
+    async def _redeem_elevation(self, ctx: ExecutionContext) -> None:
+        """Check and count a use of the request's elevated grant (AuthorizationError if refused)."""
+        if not ctx.request.elevation_grant:
+            return
+        if not self.elevation_service:
+            raise ServiceUnavailableError(service="Elevated executions", message="Elevated executions are disabled")
+        ctx.elevation = await self.elevation_service.redeem(
+            ctx.request.elevation_grant, ctx.api_key_hash, ctx.session_id
+        )
+        logger.warning(
+            "Running elevated execution",
+            session_id=ctx.session_id[:12],
+            grant_id=ctx.elevation.grant_id,
+            capabilities=ctx.elevation.capabilities.model_dump(exclude_none=True),
+        )
+
+    async def _check_elevation(self, ctx: ExecutionContext) -> None:
+        """Check the request's elevated grant like _redeem_elevation(), without using it up."""
+        if not self.elevation_service:
+            raise ServiceUnavailableError(service="Elevated executions", message="Elevated executions are disabled")
+        ctx.elevation = await self.elevation_service.check(
+            ctx.request.elevation_grant, ctx.api_key_hash, ctx.session_id
+        )
+
+    async def _acquire_workspace_lock(self, ctx: ExecutionContext) -> None:
+        """Lock the execution's scope, waiting for overlapping work to finish.
+
+        Executions without a scope lock the whole workspace, so they run one
+        at a time per session. The lock outlives the execution timeout by a
+        margin so it can't expire while files and state are still being saved.
+        """
+        if not self.workspace_lock_service:
+            return
+
+        ctx.lock_token, conflicts = await self.workspace_lock_service.acquire(
+            ctx.session_id,
+            ctx.scope or [WORKSPACE_ROOT],
+            owner="execution",
+            ttl_seconds=self._timeout(ctx) + 60,
+            wait_seconds=settings.session_lock_wait_seconds,
+            holder_token=ctx.request.lock_token,
+        )
+        if not ctx.lock_token:
+            held = sorted({path or "/" for lock in conflicts for path in lock["paths"]})
+            logger.warning("Session workspace busy", session_id=ctx.session_id[:12], held=held)
+            raise ResourceConflictError(
+                message=f"Session workspace is busy (locked: {', '.join(held)}); retry or declare a disjoint scope",
+            )
+
+    async def _release_workspace_lock(self, ctx: ExecutionContext) -> None:
+        """Release the execution's workspace lock, if one was taken."""
+        if not (self.workspace_lock_service and ctx.lock_token):
+            return
+        try:
+            await self.workspace_lock_service.release(ctx.session_id, ctx.lock_token)
+        except Exception as e:
+            # The lock expires on its own; don't mask the execution result
+            logger.warning("Failed to release workspace lock", session_id=ctx.session_id[:12], error=str(e))
+        ctx.lock_token = None
+
+    async def _load_session_env(self, ctx: ExecutionContext) -> None:
+        """Load environment variables stored for the session."""
+        try:
+            ctx.session_env = await self.session_service.get_session_env(ctx.session_id)
+        except Exception as e:
+            logger.warning("Failed to load session env", session_id=ctx.session_id[:12], error=str(e))
+
     async def _load_state(self, ctx: ExecutionContext) -> None:
         """Load previous state from Redis (or MinIO fallback) for Python sessions.
 
@@ -446,31 +903,75 @@ class ExecutionOrchestrator:
                     warning=error,
                 )
 
-    async def _execute_code(self, ctx: ExecutionContext) -> Any:
+    async def _execute_with_retry(self, ctx: ExecutionContext) -> Any:
+        """Execute the code, running it again per the request's retry policy."""
+        policy = ctx.request.retry
+        execution = await self._execute_code(ctx)
+        if not policy:
+            return execution
+
+        retry_env: dict[str, str] = {}
+        while ctx.attempts < policy.max_attempts:
+            failure = classify_failure(execution)
+            if failure not in policy.retry_on:
+                break
+
+            ctx.retried_on.append(failure)
+            if failure == OOM and policy.reduce_parallelism:
+                retry_env = reduced_parallelism_env(ctx.retried_on.count(OOM))
+            delay = backoff_seconds(policy, len(ctx.retried_on))
+            logger.info(
+                "Retrying execution",
+                session_id=ctx.session_id[:12],
+                failure=failure,
+                attempt=ctx.attempts + 1,
+                delay_seconds=delay,
+            )
+            await asyncio.sleep(delay)
+
+            ctx.attempts += 1
+            execution = await self._execute_code(ctx, retry_env)
+
+        return execution
+
+    async def _execute_code(self, ctx: ExecutionContext, retry_env: dict[str, str] | None = None) -> Any:
         """Execute the code with optional state persistence."""
         exec_request = ExecuteCodeRequest(
             code=ctx.request.code,
             language=ctx.request.lang,
-            timeout=settings.max_execution_time,
+            timeout=self._timeout(ctx),
+            # Request env overrides stored session env (and deployment context) for this execution only;
+            # retry_env (reduced parallelism after an OOM) overrides both
+            env={**execution_env(ctx.session_env, ctx.request.env), **(retry_env or {})},
+            template_code=ctx.request.template_code,
+            priority=ctx.request.priority,
+            max_connections=self._max_connections(ctx.request, ctx.elevation),
+            dns_allowlist=ctx.request.dns_allowlist,
+            workspace=ctx.request.workspace,
+            workspace_policy=ctx.request.workspace_policy,
+            elevation=ctx.elevation,
+            image=ctx.image.image if ctx.image else None,
+            image_name=ctx.image.name if ctx.image else None,
         )
 
         # Determine if we should use state persistence (Python only)
         use_state = settings.state_persistence_enabled and ctx.request.lang == "py"
 
         # execute_code returns (execution, container, new_state, state_errors, container_source) tuple
-        (
-            execution,
-            ctx.container,
-            ctx.new_state,
-            ctx.state_errors,
-            ctx.container_source,
-        ) = await self.execution_service.execute_code(
-            ctx.session_id,
-            exec_request,
-            ctx.mounted_files,
-            initial_state=ctx.initial_state if use_state else None,
-            capture_state=use_state,
-        )
+        async with execution_gate.slot(timeout=self._timeout(ctx), session_id=ctx.session_id):
+            (
+                execution,
+                ctx.container,
+                ctx.new_state,
+                ctx.state_errors,
+                ctx.container_source,
+            ) = await self.execution_service.execute_code(
+                ctx.session_id,
+                exec_request,
+                ctx.mounted_files,
+                initial_state=ctx.initial_state if use_state else None,
+                capture_state=use_state,
+            )
 
         logger.info(
             "Code execution completed",
@@ -479,9 +980,38 @@ class ExecutionOrchestrator:
             pod_name=(ctx.container.name if ctx.container and hasattr(ctx.container, "name") else None),
             has_state=ctx.new_state is not None,
         )
+        if execution.error and execution.error.get("code") == CONNECTION_LIMIT_EXCEEDED:
+            # Abuse signal for operators who allow networking
+            logger.warning(
+                "Execution killed for exceeding its connection limit",
+                session_id=ctx.session_id,
+                api_key_hash=ctx.api_key_hash[:16] if ctx.api_key_hash else None,
+                connections=execution.network_connections,
+            )
+        if execution.dns_denied:
+            logger.info("DNS lookups denied", session_id=ctx.session_id, names=execution.dns_denied[:10])
 
         return execution
 
+    @staticmethod
+    def _max_connections(request: ExecRequest, elevation: ElevatedGrant | None = None) -> int | None:
+        """Connection limit of the execution: the request's, within MAX_CONNECTIONS_PER_EXECUTION.
+
+        An elevated grant's limit replaces MAX_CONNECTIONS_PER_EXECUTION.
+        """
+        ceiling = settings.max_connections_per_execution
+        if elevation and elevation.capabilities.max_connections:
+            ceiling = elevation.capabilities.max_connections
+        limits = [limit for limit in (ceiling, request.max_connections) if limit]
+        return min(limits) if limits else None
+
+    @staticmethod
+    def _timeout(ctx: ExecutionContext) -> int:
+        """Execution timeout: MAX_EXECUTION_TIME, or the elevated grant's."""
+        if ctx.elevation and ctx.elevation.capabilities.max_execution_time:
+            return ctx.elevation.capabilities.max_execution_time
+        return settings.max_execution_time
+
     async def _handle_generated_files(self, ctx: ExecutionContext) -> list[FileRef]:
         """Handle files generated during execution."""
         generated = []
@@ -503,10 +1033,26 @@ class ExecutionOrchestrator:
                 # Get file content from container (use ctx.container directly, no session lookup)
                 file_content = await self._get_file_from_container(ctx.container, file_path)
 
+                findings = scan_file(filename, file_content)
+                if findings:
+                    ctx.secret_findings = (ctx.secret_findings or []) + findings
+                    if findings[0].action == "blocked":
+                        logger.warning("Generated file blocked by secret scan", filename=filename)
+                        continue
+
                 # Store the file
                 file_id = await self.file_service.store_execution_output_file(ctx.session_id, filename, file_content)
 
-                generated.append(FileRef(id=file_id, name=filename))
+                content_type, metadata = describe_artifact(filename, file_content)
+                generated.append(
+                    FileRef(
+                        id=file_id,
+                        name=filename,
+                        content_type=content_type,
+                        size=len(file_content),
+                        metadata=ArtifactMetadata(**metadata) if metadata else None,
+                    )
+                )
                 logger.info(
                     "Generated file stored",
                     session_id=ctx.session_id,
@@ -529,8 +1075,8 @@ class ExecutionOrchestrator:
         if not container:
             return f"# Pod not found for file: {file_path}\n".encode()
 
-        # Extract filename from path
-        filename = file_path.split("/")[-1] if "/" in file_path else file_path
+        # Path relative to the working directory: the file's name, or <workspace>/<name>
+        filename = file_path.removeprefix(f"{WORKING_DIR_PREFIX}/")
 
         kubernetes_manager = self.execution_service.kubernetes_manager
         content = await kubernetes_manager.copy_file_from_pod(container, filename)
@@ -561,10 +1107,49 @@ class ExecutionOrchestrator:
         if ctx.execution.status.value == "failed" and ctx.execution.error_message and not ctx.stderr:
             ctx.stderr = ctx.execution.error_message
 
+        # Scan for credentials before filters can redact them (files were scanned in step 5)
+        ctx.stdout, stdout_findings = scan_output("stdout", ctx.stdout)
+        ctx.stderr, stderr_findings = scan_output("stderr", ctx.stderr)
+        ctx.secret_findings = (ctx.secret_findings or []) + stdout_findings + stderr_findings
+        audit_findings(ctx.session_id, ctx.secret_findings)
+
+        # Redact/trim per OUTPUT_FILTERS before output is returned or recorded
+        full_logs = {}
+        for stream in ("stdout", "stderr"):
+            text, full = filter_stream(getattr(ctx, stream), stream, collapse=ctx.request.collapse_output)
+            setattr(ctx, stream, text)
+            if full is not None:
+                full_logs[stream] = full
+        ctx.full_logs = full_logs or None
+
         # Ensure stdout ends with newline (LibreChat compatibility)
         if ctx.stdout and not ctx.stdout.endswith("\n"):
             ctx.stdout += "\n"
 
+    async def _store_full_logs(self, ctx: ExecutionContext) -> None:
+        """Store the full streams the collapse filter shortened as <stream>.full.log generated files.
+
+        Logs larger than MAX_FILE_SIZE_MB aren't kept; a storage failure is
+        logged and the collapsed output is returned as usual.
+        """
+        if not ctx.full_logs or not settings.output_collapse_full_log or not ctx.session_id:
+            return
+
+        for stream, text in ctx.full_logs.items():
+            filename = f"{stream}.full.log"
+            content = text.encode()
+            if len(content) > settings.max_file_size_mb * 1024 * 1024:
+                logger.info("Full log too large to keep", stream=stream, size=len(content))
+                continue
+            try:
+                file_id = await self.file_service.store_execution_output_file(ctx.session_id, filename, content)
+            except Exception as e:
+                logger.error("Failed to store full log", stream=stream, error=str(e))
+                continue
+            ctx.generated_files = (ctx.generated_files or []) + [
+                FileRef(id=file_id, name=filename, content_type="text/plain", size=len(content))
+            ]
+
     def _build_response(self, ctx: ExecutionContext) -> ExecResponse:
         """Build the LibreChat-compatible response with state info."""
         # Compute state info for Python executions
@@ -591,8 +1176,142 @@ class ExecutionOrchestrator:
             has_state=has_state,
             state_size=state_size,
             state_hash=state_hash,
+            secret_findings=ctx.secret_findings or [],
+            error=self._execution_error(ctx),
+            attempts=ctx.attempts,
+            retried_on=ctx.retried_on,
+            network_connections=ctx.execution.network_connections if ctx.execution else None,
+            dns_denied=ctx.execution.dns_denied if ctx.execution else [],
+            timings=ExecTimings(**ctx.execution.timings) if ctx.execution and ctx.execution.timings else None,
+            sandbox=self._sandbox_report(ctx),
+            compile_cache=(
+                CompileCacheStats(**ctx.execution.compile_cache)
+                if ctx.execution and ctx.execution.compile_cache
+                else None
+            ),
         )
 
+    @staticmethod
+    def _sandbox_report(ctx: ExecutionContext) -> SandboxReport | None:
+        """Protections the execution ran under, if the request or SANDBOX_REPORT asks for them.
+
+        Mismatches with the configuration are audited either way.
+        """
+        if not ctx.execution:
+            return None
+        report = build_sandbox_report(ctx.execution.sandbox, ctx.session_id, ctx.execution.execution_id)
+        ctx.sandbox = report
+        enabled = ctx.request.sandbox_report if ctx.request.sandbox_report is not None else settings.sandbox_report
+        return report if enabled else None
+
+    @staticmethod
+    def _execution_error(ctx: ExecutionContext) -> ExecError | None:
+        """Structured failure reported by the sidecar, if any."""
+        error = ctx.execution.error if ctx.execution else None
+        if not error:
+            return None
+        try:
+            return ExecError.model_validate(error)
+        except PydanticValidationError:
+            logger.warning("Ignoring malformed execution error", session_id=ctx.session_id[:12], error=error)
+            return None
+
+    async def _record_cell(self, ctx: ExecutionContext) -> None:
+        """Append the execution to the session's cell history.
+
+        History is best-effort: a Redis failure is logged and the execution
+        result is returned as usual.
+        """
+        if not self.cell_history_service:
+            return
+
+        execution = ctx.execution
+        started_at = execution.started_at if execution and execution.started_at else datetime.now(UTC)
+        try:
+            ctx.cell = await self.cell_history_service.record(
+                ctx.session_id,
+                {
+                    "execution_id": execution.execution_id if execution else None,
+                    "code": ctx.request.code,
+                    "lang": ctx.request.lang,
+                    "args": ctx.request.args,
+                    "input_files": ctx.request.files,
+                    "scope": ctx.request.scope,
+                    "status": execution.status.value if execution else "failed",
+                    "exit_code": execution.exit_code if execution else None,
+                    "stdout": ctx.stdout,
+                    "stderr": ctx.stderr,
+                    "files": ctx.generated_files or [],
+                    "started_at": started_at,
+                    "duration_ms": execution.execution_time_ms if execution else None,
+                    "rerun_of": ctx.rerun_of,
+                    "provenance": execution.provenance if execution else None,
+                    "image": execution.image if execution else None,
+                    "image_name": execution.image_name if execution else None,
+                },
+            )
+        except Exception as e:
+            logger.warning("Failed to record cell", session_id=ctx.session_id[:12], error=str(e))
+
+    async def _record_changes(self, ctx: ExecutionContext) -> None:
+        """Snapshot the environment variables, installed packages and workspace files the execution left.
+
+        Best-effort like the cell history: a failure is logged and the
+        execution result is returned as usual.
+        """
+        if not self.env_snapshot_service:
+            return
+
+        try:
+            installed = installed_packages([ctx.cell]) if ctx.cell else []
+            await self.env_snapshot_service.record(
+                ctx.session_id,
+                env=execution_env(ctx.session_env, ctx.request.env),
+                files=await self.file_service.list_files(ctx.session_id),
+                installed=[f"{p['manager']}:{p['spec']}" for p in installed],
+                cell_id=ctx.cell.cell_id if ctx.cell else None,
+                execution_id=ctx.execution.execution_id if ctx.execution else None,
+            )
+        except Exception as e:
+            logger.warning("Failed to record environment changes", session_id=ctx.session_id[:12], error=str(e))
+
+    async def _suggest_timeout(self, ctx: ExecutionContext) -> None:
+        """Record how long the code ran and return a timeout suggestion based on its history.
+
+        Executions that never ran (spawn failures) or were interrupted aren't
+        recorded. Like the cell history this is best-effort.
+        """
+        execution = ctx.execution
+        if not self.timeout_advisor or not execution or execution.execution_time_ms is None:
+            return
+        if execution.status.value == "cancelled" or (execution.error or {}).get("code") == SPAWN_FAILED:
+            return
+
+        request = ctx.request
+        try:
+            await self.timeout_advisor.record(ctx.session_id, request.lang, request.code, execution.execution_time_ms)
+            suggestion = await self.timeout_advisor.suggest(request.lang, request.code, ctx.session_id)
+        except Exception as e:
+            logger.warning("Failed to record execution duration", session_id=ctx.session_id[:12], error=str(e))
+            return
+        if suggestion.basis != "default":
+            ctx.response.suggested_timeout = suggestion
+
+    async def _record_report(self, ctx: ExecutionContext) -> None:
+        """Add the execution to its session's end-of-session report.
+
+        Best-effort like the cell history: a failure is logged and the
+        execution result is returned as usual.
+        """
+        if not self.session_report_service or not ctx.execution:
+            return
+
+        try:
+            violations = execution_violations(ctx.execution, ctx.secret_findings, ctx.sandbox)
+            await self.session_report_service.record_execution(ctx.session_id, ctx.execution, violations)
+        except Exception as e:
+            logger.warning("Failed to tally execution for session report", session_id=ctx.session_id[:12], error=str(e))
+
     async def _cleanup(self, ctx: ExecutionContext) -> None:
         """Cleanup resources after execution.
 
@@ -686,6 +1405,8 @@ class ExecutionOrchestrator:
             # Get state size if available
             state_size = len(ctx.new_state.encode()) if ctx.new_state else None
 
+            compile_cache = (ctx.execution.compile_cache if ctx.execution else None) or {}
+
             metrics = DetailedExecutionMetrics(
                 execution_id=(ctx.execution.execution_id if ctx.execution else ctx.request_id),
                 session_id=ctx.session_id or "",
@@ -701,6 +1422,9 @@ class ExecutionOrchestrator:
                 files_generated=files_generated,
                 output_size_bytes=output_size,
                 state_size_bytes=state_size,
+                image=settings.get_image_for_language(ctx.request.lang),
+                compile_cache_hits=compile_cache.get("hits"),
+                compile_cache_misses=compile_cache.get("misses"),
             )
 
             await service.record_execution(metrics)
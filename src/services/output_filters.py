@@ -0,0 +1,285 @@
+"""Output filters - server-side post-processing of execution output.
+
+OUTPUT_FILTERS lists the filters to run, in order, over stdout and stderr
+before they are returned, recorded in the cell history or shown in
+variable previews:
+
+- secrets: redacts well-known credential formats (cloud keys, tokens,
+  private keys, ``password=...`` assignments), this deployment's own
+  secrets, and OUTPUT_REDACT_PATTERNS
+- pii: redacts email addresses, card numbers (Luhn-checked), US social
+  security numbers and international phone numbers
+- profanity: masks OUTPUT_PROFANITY_WORDS
+- collapse: replaces runs of identical lines with the line and a
+  ``[line repeated N×]`` note, and carriage-return progress updates with
+  their final state, for loop-heavy output sent to LLMs
+- max_lines: keeps the first OUTPUT_MAX_LINES lines of each stream
+
+Executions can turn collapse on or off with ``collapse_output``. When it
+shortens a stream the full stream (with the other filters but without
+collapse and max_lines) can be kept as a generated file
+(OUTPUT_COLLAPSE_FULL_LOG).
+
+Filters only see text output; generated files are returned unchanged.
+"""
+
+import re
+from abc import ABC, abstractmethod
+from collections.abc import Iterable
+
+import structlog
+
+from ..config import settings
+
+logger = structlog.get_logger(__name__)
+
+# Credential formats with a recognizable shape, by kind (also used by secret_scan.py)
+SECRET_PATTERNS = {
+    "private_key": r"-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----",
+    "aws_access_key": r"\b(?:AKIA|ASIA)[0-9A-Z]{16}\b",
+    "github_token": r"\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b",
+    "gitlab_token": r"\bglpat-[A-Za-z0-9_-]{20,}\b",
+    "slack_token": r"\bxox[abposr]-[A-Za-z0-9-]{10,}\b",
+    "llm_api_key": r"\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}\b",  # OpenAI / Anthropic
+    "stripe_key": r"\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}\b",
+    "google_api_key": r"\bAIza[0-9A-Za-z_-]{35}\b",
+    "jwt": r"\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\b",
+    "bearer_token": r"(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*",
+    "url_credentials": r"(?i)\b[a-z]+://[^\s:/@]+:[^\s@/]+@",
+}
+
+# "password = hunter2" style assignments: the name is kept, the value redacted
+SECRET_ASSIGNMENT = re.compile(
+    r"(?i)\b([\w.-]*(?:password|passwd|secret|token|api[_-]?key|access[_-]?key|secret[_-]?key|private[_-]?key)"
+    r"[\"']?\s*[:=]\s*[\"']?)"
+    r"([^\s\"',;]{6,})"
+)
+
+# This deployment's own secrets are redacted literally when at least this long
+MIN_LITERAL_SECRET_LENGTH = 8
+
+EMAIL = re.compile(r"\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b")
+CARD_NUMBER = re.compile(r"\b(?:\d[ -]?){12,18}\d\b")
+US_SSN = re.compile(r"\b(?!000|666|9\d\d)\d{3}-(?!00)\d{2}-(?!0000)\d{4}\b")
+PHONE = re.compile(r"(?<![\w+])\+\d{1,3}[ .-]?(?:\(\d{1,4}\)[ .-]?)?\d{2,4}(?:[ .-]?\d{2,4}){1,3}\b")
+
+
+class OutputFilter(ABC):
+    """A transformation applied to each output stream."""
+
+    name: str
+
+    @abstractmethod
+    def apply(self, text: str) -> tuple[str, int]:
+        """Filter text. Returns (filtered text, number of changes made)."""
+
+
+class SecretRedactionFilter(OutputFilter):
+    """Redacts credentials and configured patterns."""
+
+    name = "secrets"
+
+    def __init__(self, patterns: Iterable[str], literals: Iterable[str], replacement: str):
+        self.replacement = replacement
+        self.patterns = [re.compile(p) for p in [*SECRET_PATTERNS.values(), *patterns]]
+        literals = sorted({s for s in literals if s and len(s) >= MIN_LITERAL_SECRET_LENGTH}, key=len, reverse=True)
+        self.literals = re.compile("|".join(re.escape(s) for s in literals)) if literals else None
+
+    def apply(self, text: str) -> tuple[str, int]:
+        total = 0
+        if self.literals:
+            text, count = self.literals.subn(self.replacement, text)
+            total += count
+        for pattern in self.patterns:
+            text, count = pattern.subn(self.replacement, text)
+            total += count
+        text, count = SECRET_ASSIGNMENT.subn(lambda m: m.group(1) + self.replacement, text)
+        return text, total + count
+
+
+def is_card_number(candidate: str) -> bool:
+    """Whether a digit run (spaces/dashes allowed) is a Luhn-valid card number."""
+    digits = re.sub(r"\D", "", candidate)
+    if not 13 <= len(digits) <= 19:
+        return False
+    total = 0
+    for i, char in enumerate(reversed(digits)):
+        n = int(char)
+        if i % 2:
+            n = n * 2 - 9 if n > 4 else n * 2
+        total += n
+    return total % 10 == 0
+
+
+class PIIFilter(OutputFilter):
+    """Redacts personal data with a recognizable shape."""
+
+    name = "pii"
+
+    def __init__(self, replacement: str):
+        self.replacement = replacement
+
+    def apply(self, text: str) -> tuple[str, int]:
+        total = 0
+        for pattern in (EMAIL, US_SSN, PHONE):
+            text, count = pattern.subn(self.replacement, text)
+            total += count
+        cards = sum(1 for m in CARD_NUMBER.finditer(text) if is_card_number(m.group(0)))
+        if cards:
+            text = CARD_NUMBER.sub(lambda m: self.replacement if is_card_number(m.group(0)) else m.group(0), text)
+        return text, total + cards
+
+
+class ProfanityFilter(OutputFilter):
+    """Masks listed words, keeping their first letter."""
+
+    name = "profanity"
+
+    def __init__(self, words: Iterable[str]):
+        words = sorted({w.strip() for w in words if w.strip()}, key=len, reverse=True)
+        self.pattern = re.compile(r"(?i)\b(?:" + "|".join(re.escape(w) for w in words) + r")\b") if words else None
+
+    def apply(self, text: str) -> tuple[str, int]:
+        if not self.pattern:
+            return text, 0
+        return self.pattern.subn(lambda m: m.group(0)[0] + "*" * (len(m.group(0)) - 1), text)
+
+
+class CollapseFilter(OutputFilter):
+    """Collapses repeated lines and carriage-return progress updates into short summaries."""
+
+    name = "collapse"
+
+    def __init__(self, min_repeats: int):
+        self.min_repeats = min_repeats
+
+    def apply(self, text: str) -> tuple[str, int]:
+        lines = text.split("\n")
+        changes = 0
+
+        # A line redrawn with \r (progress bars) keeps what a terminal would show last
+        for i, line in enumerate(lines):
+            if "\r" in line:
+                updates = [segment for segment in line.split("\r") if segment]
+                if len(updates) > 1:
+                    lines[i] = f"{updates[-1]} [progress updated {len(updates)}×]"
+                    changes += len(updates) - 1
+
+        collapsed = []
+        i = 0
+        while i < len(lines):
+            run = 1
+            while i + run < len(lines) and lines[i + run] == lines[i]:
+                run += 1
+            # Blank lines (and the empty string after a final newline) are left alone
+            if run >= self.min_repeats and lines[i].strip():
+                collapsed.extend([lines[i], f"[line repeated {run}×]"])
+                changes += run - 1
+            else:
+                collapsed.extend(lines[i : i + run])
+            i += run
+        return "\n".join(collapsed), changes
+
+
+class MaxLinesFilter(OutputFilter):
+    """Keeps the first lines of a stream and notes how many were dropped."""
+
+    name = "max_lines"
+
+    def __init__(self, max_lines: int):
+        self.max_lines = max_lines
+
+    def apply(self, text: str) -> tuple[str, int]:
+        lines = text.splitlines(keepends=True)
+        if len(lines) <= self.max_lines:
+            return text, 0
+        dropped = len(lines) - self.max_lines
+        kept = "".join(lines[: self.max_lines])
+        if not kept.endswith("\n"):
+            kept += "\n"
+        return f"{kept}... [{dropped} more lines truncated]\n", dropped
+
+
+def deployment_secrets() -> list[str]:
+    """Secrets from this deployment's configuration that must never be echoed."""
+    return [
+        settings.api_key,
+        settings.master_api_key,
+        settings.redis_password,
+        settings.minio_secret_key,
+        *settings.get_valid_api_keys(),
+    ]
+
+
+def build_filter(name: str) -> OutputFilter:
+    """Create a filter by its OUTPUT_FILTERS name from the current settings."""
+    if name == "secrets":
+        return SecretRedactionFilter(
+            settings.output_redact_patterns, deployment_secrets(), settings.output_redaction_text
+        )
+    if name == "pii":
+        return PIIFilter(settings.output_redaction_text)
+    if name == "profanity":
+        return ProfanityFilter(settings.output_profanity_words)
+    if name == "collapse":
+        return CollapseFilter(settings.output_collapse_min_repeats)
+    if name == "max_lines":
+        return MaxLinesFilter(settings.output_max_lines)
+    raise ValueError(f"Unknown output filter: {name}")
+
+
+class OutputFilterChain:
+    """Runs filters in order over a text."""
+
+    def __init__(self, filters: list[OutputFilter]):
+        self.filters = filters
+
+    @classmethod
+    def from_settings(cls) -> "OutputFilterChain":
+        """Build the chain configured by OUTPUT_FILTERS."""
+        return cls([build_filter(name) for name in settings.output_filters])
+
+    def apply(self, text: str) -> tuple[str, dict[str, int]]:
+        """Filter text. Returns (filtered text, changes per filter that changed anything)."""
+        changes = {}
+        for output_filter in self.filters:
+            if not text:
+                break
+            text, count = output_filter.apply(text)
+            if count:
+                changes[output_filter.name] = count
+        return text, changes
+
+
+def filter_names(collapse: bool | None = None) -> list[str]:
+    """OUTPUT_FILTERS with collapse added (before max_lines) or removed as an execution asked."""
+    names = list(settings.output_filters)
+    if collapse is False:
+        names = [name for name in names if name != "collapse"]
+    elif collapse and "collapse" not in names:
+        names.insert(names.index("max_lines") if "max_lines" in names else len(names), "collapse")
+    return names
+
+
+def filter_stream(text: str, stream: str = "stdout", collapse: bool | None = None) -> tuple[str, str | None]:
+    """Apply the filters to one output stream.
+
+    Returns (filtered text, full text): the full text is only returned when
+    collapse shortened the stream, and has every other filter except
+    max_lines applied, so it can be kept without leaking what was redacted.
+    """
+    names = filter_names(collapse)
+    if not names or not text:
+        return text, None
+    filtered, changes = OutputFilterChain([build_filter(name) for name in names]).apply(text)
+    if changes:
+        logger.info("Output filtered", stream=stream, changes=changes)
+    if "collapse" not in changes:
+        return filtered, None
+    full, _ = OutputFilterChain([build_filter(n) for n in names if n not in ("collapse", "max_lines")]).apply(text)
+    return filtered, full
+
+
+def filter_output(text: str, stream: str = "stdout") -> str:
+    """Apply the configured filters to one output stream."""
+    return filter_stream(text, stream)[0]
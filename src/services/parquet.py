@@ -0,0 +1,514 @@
+"""Minimal Parquet reader for file previews.
+
+Reads the schema and the first rows of a Parquet file without pyarrow.
+Only what previews need is supported:
+
+- Flat columns; nested (list, map, struct) columns are reported in the
+  schema but their values aren't decoded
+- PLAIN and dictionary encodings, v1 and v2 data pages
+- Uncompressed, Snappy and gzip column chunks (zstd on Python 3.14+)
+
+Only the footer and the leading pages of each column are read, and only
+the values of the rows requested are decoded, so the work is proportional
+to the rows requested rather than the file size. Pages are decompressed
+up to MAX_PAGE_SIZE, so a small file can't expand into a large one.
+"""
+
+import base64
+import math
+import struct
+import uuid
+import zlib
+from dataclasses import dataclass, field
+from datetime import UTC, date, datetime, timedelta
+from decimal import Decimal
+
+MAGIC = b"PAR1"
+# Largest page, compressed or not, a preview reads
+MAX_PAGE_SIZE = 16 * 1024 * 1024
+
+# parquet.thrift enums
+_TYPES = ["BOOLEAN", "INT32", "INT64", "INT96", "FLOAT", "DOUBLE", "BYTE_ARRAY", "FIXED_LEN_BYTE_ARRAY"]
+_OPTIONAL = 1
+_REPEATED = 2
+_CODECS = {0: "UNCOMPRESSED", 1: "SNAPPY", 2: "GZIP", 3: "LZO", 4: "BROTLI", 5: "LZ4", 6: "ZSTD", 7: "LZ4_RAW"}
+_PLAIN = 0
+_PLAIN_DICTIONARY = 2
+_RLE_DICTIONARY = 8
+_DATA_PAGE = 0
+_DICTIONARY_PAGE = 2
+_DATA_PAGE_V2 = 3
+
+# Converted types (legacy annotations)
+_UTF8, _ENUM, _DECIMAL, _DATE = 0, 4, 5, 6
+_TIME_MILLIS, _TIME_MICROS, _TIMESTAMP_MILLIS, _TIMESTAMP_MICROS = 7, 8, 9, 10
+_UNSIGNED = (11, 12, 13, 14)  # UINT_8 .. UINT_64
+_JSON = 19
+
+_JULIAN_EPOCH = 2440588  # Julian day of 1970-01-01
+_EPOCH = datetime(1970, 1, 1, tzinfo=UTC)
+_DIVISORS = {"millis": 1_000, "micros": 1_000_000, "nanos": 1_000_000_000}
+
+
+class ParquetError(ValueError):
+    """The file isn't Parquet, or uses something the reader doesn't support."""
+
+
+class _Thrift:
+    """Decodes thrift compact-protocol structs into {field_id: value} dicts."""
+
+    def __init__(self, data: bytes, pos: int = 0):
+        self.data = data
+        self.pos = pos
+
+    def _byte(self) -> int:
+        if self.pos >= len(self.data):
+            raise ParquetError("Truncated Parquet metadata")
+        value = self.data[self.pos]
+        self.pos += 1
+        return value
+
+    def _varint(self) -> int:
+        result = shift = 0
+        while True:
+            byte = self._byte()
+            result |= (byte & 0x7F) << shift
+            if not byte & 0x80:
+                return result
+            shift += 7
+
+    def _zigzag(self) -> int:
+        n = self._varint()
+        return (n >> 1) ^ -(n & 1)
+
+    def _value(self, kind: int):
+        if kind in (1, 2):
+            return kind == 1
+        if kind == 3:
+            return struct.unpack("b", bytes([self._byte()]))[0]
+        if kind in (4, 5, 6):
+            return self._zigzag()
+        if kind == 7:
+            value = struct.unpack_from("<d", self.data, self.pos)[0]
+            self.pos += 8
+            return value
+        if kind == 8:
+            length = self._varint()
+            value = self.data[self.pos : self.pos + length]
+            self.pos += length
+            return value
+        if kind in (9, 10):
+            header = self._byte()
+            size, element = header >> 4, header & 0x0F
+            if size == 15:
+                size = self._varint()
+            if element in (1, 2):
+                return [self._byte() == 1 for _ in range(size)]
+            return [self._value(element) for _ in range(size)]
+        if kind == 11:
+            size = self._varint()
+            types = self._byte() if size else 0
+            return {self._value(types >> 4): self._value(types & 0x0F) for _ in range(size)}
+        if kind == 12:
+            return self.struct()
+        raise ParquetError(f"Invalid thrift type {kind}")
+
+    def struct(self) -> dict[int, object]:
+        fields: dict[int, object] = {}
+        last = 0
+        while True:
+            header = self._byte()
+            if header == 0:
+                return fields
+            delta, kind = header >> 4, header & 0x0F
+            last = last + delta if delta else self._zigzag()
+            fields[last] = self._value(kind)
+
+
+@dataclass
+class ParquetColumn:
+    name: str
+    physical_type: str | None
+    type: str
+    nullable: bool
+    nested: bool = False
+    leaves: int = 1
+    type_length: int | None = None
+    converted_type: int | None = None
+    logical_type: dict = field(default_factory=dict)
+    scale: int = 0
+
+
+@dataclass
+class ParquetPreview:
+    columns: list[ParquetColumn]
+    rows: list[list]
+    total_rows: int
+
+
+def _preview_type(physical: str | None, converted: int | None, logical: dict) -> str:
+    if 1 in logical or 4 in logical or 12 in logical or converted in (_UTF8, _ENUM, _JSON):
+        return "string"
+    if 5 in logical or converted == _DECIMAL:
+        return "decimal"
+    if 6 in logical or converted == _DATE:
+        return "date"
+    if 7 in logical or converted in (_TIME_MILLIS, _TIME_MICROS):
+        return "time"
+    if 8 in logical or converted in (_TIMESTAMP_MILLIS, _TIMESTAMP_MICROS) or physical == "INT96":
+        return "datetime"
+    if 14 in logical:
+        return "string"
+    return {
+        "BOOLEAN": "boolean",
+        "INT32": "integer",
+        "INT64": "integer",
+        "FLOAT": "number",
+        "DOUBLE": "number",
+    }.get(physical, "binary")
+
+
+def _columns(schema: list[dict]) -> list[ParquetColumn]:
+    """Top-level columns; the first schema element is the root."""
+    columns = []
+    index = 1
+    for _ in range(schema[0].get(5, 0) if schema else 0):
+        element = schema[index]
+        name = element.get(4, b"").decode("utf-8", "replace")
+        children = element.get(5, 0)
+        # Walk the whole subtree of nested columns, counting its leaves (one column chunk each)
+        subtree, pending, leaves = 1, children, 0 if children else 1
+        while pending:
+            grandchildren = schema[index + subtree].get(5, 0)
+            pending += grandchildren - 1
+            leaves += 0 if grandchildren else 1
+            subtree += 1
+        repetition = element.get(3, 0)
+        if children or repetition == _REPEATED:
+            columns.append(
+                ParquetColumn(name=name, physical_type=None, type="nested", nullable=True, nested=True, leaves=leaves)
+            )
+        else:
+            physical = _TYPES[element.get(1, 6)] if 0 <= element.get(1, 6) < len(_TYPES) else None
+            logical = element.get(10) or {}
+            if 5 in logical:
+                scale = logical[5].get(1, 0)
+            else:
+                scale = element.get(7, 0)
+            columns.append(
+                ParquetColumn(
+                    name=name,
+                    physical_type=physical,
+                    type=_preview_type(physical, element.get(6), logical),
+                    nullable=repetition == _OPTIONAL,
+                    type_length=element.get(2),
+                    converted_type=element.get(6),
+                    logical_type=logical,
+                    scale=scale,
+                )
+            )
+        index += subtree
+    return columns
+
+
+def snappy_decompress(data: bytes, max_size: int = MAX_PAGE_SIZE) -> bytes:
+    """Decompress a raw (unframed) Snappy block of at most ``max_size`` bytes."""
+    reader = _Thrift(data)
+    expected = reader._varint()
+    if expected > max_size:
+        raise ParquetError(f"Page larger than {max_size} bytes")
+    pos = reader.pos
+    out = bytearray()
+    try:
+        while pos < len(data):
+            if len(out) > expected:
+                raise ParquetError("Corrupt Snappy data")
+            tag = data[pos]
+            pos += 1
+            kind = tag & 3
+            if kind == 0:
+                length = tag >> 2
+                if length >= 60:
+                    extra = length - 59
+                    length = int.from_bytes(data[pos : pos + extra], "little")
+                    pos += extra
+                length += 1
+                out += data[pos : pos + length]
+                pos += length
+                continue
+            if kind == 1:
+                length = ((tag >> 2) & 7) + 4
+                offset = ((tag >> 5) << 8) | data[pos]
+                pos += 1
+            elif kind == 2:
+                length = (tag >> 2) + 1
+                offset = int.from_bytes(data[pos : pos + 2], "little")
+                pos += 2
+            else:
+                length = (tag >> 2) + 1
+                offset = int.from_bytes(data[pos : pos + 4], "little")
+                pos += 4
+            if not 0 < offset <= len(out):
+                raise ParquetError("Corrupt Snappy data")
+            start = len(out) - offset
+            if offset >= length:
+                out += out[start : start + length]
+            else:
+                for i in range(length):
+                    out.append(out[start + i])
+    except IndexError:
+        raise ParquetError("Truncated Snappy data")
+    if len(out) != expected:
+        raise ParquetError("Corrupt Snappy data")
+    return bytes(out)
+
+
+def _decompress(data: bytes, codec: int) -> bytes:
+    if codec == 0:
+        return data
+    if codec == 1:
+        return snappy_decompress(data)
+    if codec == 2:
+        decompressor = zlib.decompressobj(16 + zlib.MAX_WBITS)
+        try:
+            page = decompressor.decompress(data, MAX_PAGE_SIZE + 1)
+        except zlib.error as e:
+            raise ParquetError(f"Corrupt gzip data: {e}")
+        if len(page) > MAX_PAGE_SIZE:
+            raise ParquetError(f"Page larger than {MAX_PAGE_SIZE} bytes")
+        if not decompressor.eof:
+            raise ParquetError("Corrupt gzip data: truncated")
+        return page
+    if codec == 6:
+        try:
+            from compression import zstd
+        except ImportError:
+            raise ParquetError("zstd-compressed Parquet needs Python 3.14 or later to preview")
+        try:
+            page = zstd.ZstdDecompressor().decompress(data, max_length=MAX_PAGE_SIZE + 1)
+        except zstd.ZstdError as e:
+            raise ParquetError(f"Corrupt zstd data: {e}")
+        if len(page) > MAX_PAGE_SIZE:
+            raise ParquetError(f"Page larger than {MAX_PAGE_SIZE} bytes")
+        return page
+    raise ParquetError(f"{_CODECS.get(codec, codec)} compression isn't supported for preview")
+
+
+def _bit_width(max_value: int) -> int:
+    return max_value.bit_length()
+
+
+def decode_hybrid(data: bytes, pos: int, bit_width: int, count: int) -> list[int]:
+    """Decode ``count`` values of the RLE/bit-packed hybrid encoding starting at pos."""
+    values: list[int] = []
+    reader = _Thrift(data, pos)
+    byte_width = (bit_width + 7) // 8
+    mask = (1 << bit_width) - 1
+    while len(values) < count and reader.pos < len(data):
+        header = reader._varint()
+        # Runs are clamped to the values still wanted, whatever length the header claims
+        remaining = count - len(values)
+        if header & 1:
+            groups = header >> 1
+            wanted = min(groups * 8, remaining)
+            chunk = int.from_bytes(data[reader.pos : reader.pos + (wanted * bit_width + 7) // 8], "little")
+            reader.pos += groups * bit_width
+            values.extend((chunk >> (i * bit_width)) & mask for i in range(wanted))
+        else:
+            value = int.from_bytes(data[reader.pos : reader.pos + byte_width], "little")
+            reader.pos += byte_width
+            values.extend([value] * min(header >> 1, remaining))
+    if len(values) < count:
+        raise ParquetError("Truncated RLE data")
+    return values[:count]
+
+
+def _plain(data: bytes, pos: int, physical: str, count: int, type_length: int | None) -> list:
+    try:
+        if physical == "BOOLEAN":
+            return [bool((data[pos + i // 8] >> (i % 8)) & 1) for i in range(count)]
+        if physical in ("INT32", "INT64", "FLOAT", "DOUBLE"):
+            fmt = {"INT32": "i", "INT64": "q", "FLOAT": "f", "DOUBLE": "d"}[physical]
+            return list(struct.unpack_from(f"<{count}{fmt}", data, pos))
+        if physical == "INT96":
+            return [data[pos + 12 * i : pos + 12 * (i + 1)] for i in range(count)]
+        if physical == "FIXED_LEN_BYTE_ARRAY":
+            width = type_length or 0
+            return [data[pos + width * i : pos + width * (i + 1)] for i in range(count)]
+        values = []
+        for _ in range(count):
+            length = struct.unpack_from("<i", data, pos)[0]
+            pos += 4
+            values.append(data[pos : pos + length])
+            pos += length
+        return values
+    except (struct.error, IndexError):
+        raise ParquetError("Truncated column data")
+
+
+def _read_column(content: bytes, chunk: dict, column: ParquetColumn, needed: int) -> list:
+    """The first ``needed`` values of a flat column chunk, with None for nulls."""
+    meta = chunk.get(3)
+    if not meta:
+        raise ParquetError("Column chunks in external files aren't supported")
+    codec = meta.get(4, 0)
+    pos = meta.get(11) or meta.get(9)
+    max_def = 1 if column.nullable else 0
+    dictionary: list | None = None
+    values: list = []
+    while len(values) < needed:
+        reader = _Thrift(content, pos)
+        header = reader.struct()
+        compressed_size = header.get(3, 0)
+        if max(compressed_size, header.get(2, 0)) > MAX_PAGE_SIZE:
+            raise ParquetError(f"Page larger than {MAX_PAGE_SIZE} bytes")
+        body_start = reader.pos
+        body = content[body_start : body_start + compressed_size]
+        pos = body_start + compressed_size
+        page_type = header.get(1)
+        if page_type == _DICTIONARY_PAGE:
+            page = _decompress(body, codec)
+            dictionary = _plain(page, 0, column.physical_type, header[7].get(1, 0), column.type_length)
+            continue
+        if page_type == _DATA_PAGE:
+            page_header = header.get(5, {})
+            # Values past the rows still needed aren't decoded
+            count = min(page_header.get(1, 0), needed - len(values))
+            encoding = page_header.get(2, _PLAIN)
+            page = _decompress(body, codec)
+            offset = 0
+            if max_def:
+                length = struct.unpack_from("<i", page, 0)[0]
+                levels = decode_hybrid(page[4 : 4 + length], 0, _bit_width(max_def), count)
+                offset = 4 + length
+            else:
+                levels = [0] * count
+        elif page_type == _DATA_PAGE_V2:
+            page_header = header.get(8, {})
+            count = min(page_header.get(1, 0), needed - len(values))
+            encoding = page_header.get(4, _PLAIN)
+            rep_length, def_length = page_header.get(6, 0), page_header.get(5, 0)
+            levels_end = rep_length + def_length
+            if max_def:
+                levels = decode_hybrid(body[rep_length:levels_end], 0, _bit_width(max_def), count)
+            else:
+                levels = [0] * count
+            page = body[:levels_end]
+            page += _decompress(body[levels_end:], codec) if page_header.get(7, True) else body[levels_end:]
+            offset = levels_end
+        else:
+            continue
+
+        present = sum(1 for level in levels if level == max_def)
+        if encoding == _PLAIN:
+            decoded = _plain(page, offset, column.physical_type, present, column.type_length)
+        elif encoding in (_PLAIN_DICTIONARY, _RLE_DICTIONARY):
+            if dictionary is None:
+                raise ParquetError("Dictionary-encoded page without a dictionary")
+            indices = decode_hybrid(page, offset + 1, page[offset], present) if present else []
+            try:
+                decoded = [dictionary[i] for i in indices]
+            except IndexError:
+                raise ParquetError("Dictionary index out of range")
+        else:
+            raise ParquetError(f"Encoding {encoding} of column {column.name} isn't supported for preview")
+
+        it = iter(decoded)
+        values.extend(next(it) if level == max_def else None for level in levels)
+    return values[:needed]
+
+
+def _timestamp(value: int, unit: str) -> str:
+    return (_EPOCH + timedelta(microseconds=value * 1_000_000 // _DIVISORS[unit])).isoformat()
+
+
+def _unit(logical: dict) -> str:
+    unit = logical.get(2) or {}
+    return "millis" if 1 in unit else "nanos" if 3 in unit else "micros"
+
+
+def _unsigned(column: ParquetColumn) -> bool:
+    integer = column.logical_type.get(10)
+    if integer is not None:
+        return integer.get(2) is False
+    return column.converted_type in _UNSIGNED
+
+
+def convert_value(value, column: ParquetColumn):
+    """A JSON-friendly rendering of a raw Parquet value."""
+    if value is None:
+        return None
+    logical, converted = column.logical_type, column.converted_type
+    try:
+        if column.type == "string":
+            if 14 in logical:
+                return str(uuid.UUID(bytes=bytes(value)))
+            return bytes(value).decode("utf-8", "replace")
+        if column.type == "decimal":
+            unscaled = value if isinstance(value, int) else int.from_bytes(value, "big", signed=True)
+            return str(Decimal(unscaled).scaleb(-column.scale))
+        if column.type == "date":
+            return (date(1970, 1, 1) + timedelta(days=value)).isoformat()
+        if column.type == "time":
+            unit = _unit(logical[7]) if 7 in logical else "millis" if converted == _TIME_MILLIS else "micros"
+            return (datetime.min + timedelta(microseconds=value * 1_000_000 // _DIVISORS[unit])).time().isoformat()
+        if column.type == "datetime":
+            if column.physical_type == "INT96":
+                nanos, day = struct.unpack("<qi", value)
+                return _timestamp((day - _JULIAN_EPOCH) * 86_400_000_000_000 + nanos, "nanos")
+            unit = _unit(logical[8]) if 8 in logical else "millis" if converted == _TIMESTAMP_MILLIS else "micros"
+            return _timestamp(value, unit)
+    except (ValueError, OverflowError, struct.error):
+        return None
+    if column.type == "integer" and value < 0 and _unsigned(column):
+        return value + (1 << (32 if column.physical_type == "INT32" else 64))
+    if isinstance(value, float) and not math.isfinite(value):
+        return str(value)
+    if isinstance(value, bytes):
+        try:
+            return value.decode("utf-8")
+        except UnicodeDecodeError:
+            return base64.b64encode(value).decode("ascii")
+    return value
+
+
+def read_parquet(content: bytes, rows: int) -> ParquetPreview:
+    """Schema, the first ``rows`` rows and the row count of a Parquet file.
+
+    Raises:
+        ParquetError: If the file isn't valid Parquet or needs an unsupported feature
+    """
+    if len(content) < 12 or content[:4] != MAGIC or content[-4:] != MAGIC:
+        raise ParquetError("Not a Parquet file")
+    footer_length = struct.unpack("<i", content[-8:-4])[0]
+    if not 0 < footer_length <= len(content) - 12:
+        raise ParquetError("Invalid Parquet footer")
+    try:
+        metadata = _Thrift(content[-8 - footer_length : -8]).struct()
+        columns = _columns(metadata.get(2) or [])
+    except (IndexError, struct.error, TypeError, AttributeError):
+        raise ParquetError("Invalid Parquet metadata")
+
+    sample: list[list] = []
+    for row_group in metadata.get(4) or []:
+        needed = min(rows - len(sample), row_group.get(3, 0))
+        if needed <= 0:
+            break
+        chunks = row_group.get(1) or []
+        if len(chunks) != sum(c.leaves for c in columns):
+            raise ParquetError("Row group doesn't match the schema")
+        values = []
+        chunk_index = 0
+        for column in columns:
+            chunk = chunks[chunk_index]
+            chunk_index += column.leaves
+            if column.nested:
+                values.append([None] * needed)
+                continue
+            try:
+                raw = _read_column(content, chunk, column, needed)
+            except (IndexError, struct.error, TypeError, KeyError):
+                raise ParquetError(f"Invalid data in column {column.name}")
+            values.append([convert_value(v, column) for v in raw])
+        sample.extend([list(row) for row in zip(*values)] if values else [[] for _ in range(needed)])
+    return ParquetPreview(columns=columns, rows=sample, total_rows=metadata.get(3, 0))
@@ -0,0 +1,445 @@
+"""File tools that run in a pod against a session's files.
+
+The sidecar can render documents, process media, search, outline and
+patch files, but only in its own working directory, and the API's pods are
+single-use. So each call here takes a warm pod of the language, uploads the
+session files it needs, calls the sidecar (see docker/sidecar/main.py), and
+stores what it produced back in the session the way /exec stores generated
+files (secret scan, then FileService), before destroying the pod. Only
+languages with a warm pool can be used: Job pods run one execution and exit.
+"""
+
+import json
+from collections.abc import AsyncIterator
+from contextlib import AsyncExitStack, asynccontextmanager
+from typing import Any
+
+import httpx
+import structlog
+
+from ..models.errors import (
+    CodeInterpreterException,
+    ErrorDetail,
+    ExternalServiceError,
+    ResourceConflictError,
+    ResourceNotFoundError,
+    ServiceUnavailableError,
+    ValidationError,
+)
+from ..models.exec import ArtifactMetadata, FileRef, RequestFile, SecretFinding
+from ..config import settings
+from ..models.pod_tools import (
+    FilePatchRequest,
+    FilePatchResponse,
+    FileSearchMatch,
+    FileSearchResponse,
+    FileSymbolsResponse,
+    MediaRequest,
+    PatchedFile,
+    RenderRequest,
+    RenderResponse,
+    SyncDelta,
+    SyncDeltaResponse,
+    SyncPatchResponse,
+    SyncSignature,
+    SyncSignatureResponse,
+)
+from ..utils.id_generator import generate_session_id
+from .artifact_metadata import describe_artifact
+from .kubernetes.models import FileData
+from .kubernetes.workspace import upload_files
+from .secret_scan import audit_findings, scan_file
+
+logger = structlog.get_logger(__name__)
+
+# Leaves time for the upload and download around the sidecar's own timeout
+POD_TOOL_TIMEOUT_MARGIN = 30.0
+# The sidecar stops a search after 5 seconds and kills it a second later
+SEARCH_TIMEOUT = 10.0
+PATCH_TIMEOUT = 30.0
+SYMBOLS_TIMEOUT = 30.0
+SYNC_TIMEOUT = 60.0
+
+
+def _reject_detail(reject: dict[str, Any]) -> ErrorDetail:
+    """A hunk the sidecar couldn't apply, as an error detail naming the file (and the hunk's header)."""
+    message = f"{reject['reason']} at {reject['hunk']}" if reject.get("hunk") else reject["reason"]
+    return ErrorDetail(field=reject["path"], message=message)
+
+
+class PodToolsService:
+    """Runs the sidecar's file tools in warm pods and stores their results in the session."""
+
+    def __init__(self, kubernetes_manager: Any, file_service: Any):
+        self.kubernetes_manager = kubernetes_manager
+        self.file_service = file_service
+
+    async def _session_file(self, session_id: str, file_id: str) -> FileData:
+        info = await self.file_service.get_file_info(session_id, file_id)
+        content = await self.file_service.get_file_content(session_id, file_id) if info else None
+        if content is None:
+            raise ResourceNotFoundError("File", file_id)
+        return FileData(filename=info.filename, content=content, session_id=session_id)
+
+    async def _session_files(self, file_refs: list[RequestFile]) -> list[FileData]:
+        return [await self._session_file(file_ref.session_id, file_ref.id) for file_ref in file_refs]
+
+    async def _all_session_files(self, session_id: str) -> tuple[list[FileData], dict[str, str]]:
+        """Every file of a session, and their ids by name.
+
+        Files are uploaded by name, so of several with the same name only the
+        newest is used, as in the session's working directory.
+        """
+        newest = {info.filename: info for info in await self.file_service.list_files(session_id)}
+        files, file_ids = [], {}
+        for name, info in newest.items():
+            content = await self.file_service.get_file_content(session_id, info.file_id)
+            if content is not None:
+                files.append(FileData(filename=name, content=content, session_id=session_id))
+                file_ids[name] = info.file_id
+        return files, file_ids
+
+    @asynccontextmanager
+    async def _pod(
+        self, tool: str, session_id: str, language: str, files: list[FileData], timeout: float
+    ) -> AsyncIterator[tuple[httpx.AsyncClient, str]]:
+        """A warm pod with ``files`` in its working directory: the client and sidecar URL.
+
+        Raises:
+            ServiceUnavailableError: If the language has no warm pod available
+            ExternalServiceError: If talking to the sidecar failed
+        """
+        if not self.kubernetes_manager:
+            raise ServiceUnavailableError(tool, "Kubernetes is not available")
+        handle, _ = await self.kubernetes_manager.acquire_pod(session_id, language)
+        if not handle:
+            raise ServiceUnavailableError(tool, f"{tool} needs a warm pod pool for {language}")
+        try:
+            async with httpx.AsyncClient(timeout=timeout + POD_TOOL_TIMEOUT_MARGIN) as client:
+                if files:
+                    await upload_files(client, handle.sidecar_url, files)
+                yield client, handle.sidecar_url
+        except httpx.HTTPError as e:
+            logger.warning("Pod tool failed", tool=tool, error=str(e))
+            raise ExternalServiceError(tool, f"{tool} failed: {e}")
+        finally:
+            await self.kubernetes_manager.destroy_pod(handle)
+
+    @staticmethod
+    def _raise_for_status(tool: str, response: httpx.Response, path: str | None = None) -> None:
+        """Map a sidecar error response to the API's errors."""
+        if response.status_code < 400:
+            return
+        detail = response.json().get("detail", response.text) if response.content else response.reason_phrase
+        if response.status_code == 404:
+            raise ResourceNotFoundError("File", path or str(detail))
+        if response.status_code == 409:
+            raise ResourceConflictError(str(detail))
+        if response.status_code in (400, 413, 415, 422):
+            raise ValidationError(str(detail))
+        if response.status_code in (501, 503):
+            raise ServiceUnavailableError(tool, str(detail))
+        raise ExternalServiceError(tool, str(detail))
+
+    async def _store_output(
+        self, client: httpx.AsyncClient, sidecar_url: str, session_id: str, name: str, path: str | None = None
+    ) -> tuple[FileRef | None, list[SecretFinding]]:
+        """Download a file the sidecar produced (at ``path``, else ``name``) and store it in the session.
+
+        Returns no file ref when the secret scan blocked it.
+        """
+        response = await client.get(f"{sidecar_url}/files/{path or name}")
+        self._raise_for_status("File download", response, name)
+        content = response.content
+
+        findings = scan_file(name, content)
+        audit_findings(session_id, findings)
+        if findings and findings[0].action == "blocked":
+            logger.warning("Pod tool output blocked by secret scan", filename=name)
+            return None, findings
+
+        file_id = await self.file_service.store_execution_output_file(session_id, name, content)
+        content_type, metadata = describe_artifact(name, content)
+        file_ref = FileRef(
+            id=file_id,
+            name=name,
+            content_type=content_type,
+            size=len(content),
+            metadata=ArtifactMetadata(**metadata) if metadata else None,
+        )
+        logger.info("Pod tool output stored", session_id=session_id, filename=name, file_id=file_id)
+        return file_ref, findings
+
+    async def render(self, request: RenderRequest) -> RenderResponse:
+        """Render a document with the toolchain in the pod's image and store the result in the session.
+
+        Raises:
+            ServiceUnavailableError: If the language has no warm pod, or its image no toolchain for the conversion
+            ResourceNotFoundError: If a file (or ``path`` among them) doesn't exist
+            ValidationError: If the sidecar rejected the request
+            ExternalServiceError: If the sidecar failed
+        """
+        if (request.source is None) == (request.path is None):
+            raise ValidationError("Provide exactly one of 'source' or 'path'")
+        files = await self._session_files(request.files)
+        session_id = request.session_id or (request.files[0].session_id if request.files else generate_session_id())
+
+        body = request.model_dump(include={"format", "output", "source", "path", "filename", "timeout"})
+        async with self._pod("Rendering", session_id, request.language, files, request.timeout) as (client, url):
+            response = await client.post(f"{url}/render", json=body)
+            self._raise_for_status("Rendering", response, request.path)
+            data = response.json()
+            file_ref, findings = None, []
+            if data.get("output"):
+                file_ref, findings = await self._store_output(client, url, session_id, data["output"]["name"])
+
+        return RenderResponse(
+            session_id=session_id,
+            exit_code=data["exit_code"],
+            toolchain=data["toolchain"],
+            file=file_ref,
+            log=data.get("log", ""),
+            execution_time_ms=data.get("execution_time_ms", 0),
+            secret_findings=findings,
+        )
+
+    async def media(self, request: MediaRequest) -> AsyncIterator[str]:
+        """Start an ffmpeg conversion in a pod and return its NDJSON events.
+
+        The sidecar's start and progress events are passed through; its final
+        result event gets the stored output as ``file`` (and the session it
+        is in) instead of the path in the pod. The pod is destroyed once the
+        events are consumed, or the client goes away.
+
+        Raises:
+            ServiceUnavailableError: If the language has no warm pod, or its image no ffmpeg
+            ResourceNotFoundError: If the input file doesn't exist
+            ValidationError: If the sidecar rejected the args or output path
+            ResourceConflictError: If ``output`` is the name of another uploaded file
+            ExternalServiceError: If the sidecar failed
+        """
+        files = await self._session_files([request.input])
+        session_id = request.session_id or request.input.session_id
+        body = {
+            "input": files[0].filename,
+            **request.model_dump(include={"output", "args", "max_output_size", "timeout"}, exclude_none=True),
+        }
+
+        stack = AsyncExitStack()
+        timeout = request.timeout or settings.max_execution_time
+        client, url = await stack.enter_async_context(
+            self._pod("Media processing", session_id, request.language, files, timeout)
+        )
+        try:
+            response = await stack.enter_async_context(client.stream("POST", f"{url}/media", json=body))
+            if response.status_code >= 400:
+                await response.aread()
+                self._raise_for_status("Media processing", response, request.input.id)
+        except httpx.HTTPError as e:
+            await stack.aclose()
+            raise ExternalServiceError("Media processing", f"Media processing failed: {e}")
+        except BaseException:
+            await stack.aclose()
+            raise
+        return self._media_events(stack, client, url, session_id, response)
+
+    async def _media_events(
+        self, stack: AsyncExitStack, client: httpx.AsyncClient, url: str, session_id: str, response: httpx.Response
+    ) -> AsyncIterator[str]:
+        async with stack:
+            try:
+                async for line in response.aiter_lines():
+                    if not line.strip():
+                        continue
+                    event = json.loads(line)
+                    if event.get("type") == "result":
+                        output = event.pop("output", None)
+                        file_ref, findings = None, []
+                        if output:
+                            file_ref, findings = await self._store_output(
+                                client, url, session_id, output["name"], output["path"]
+                            )
+                        event["session_id"] = session_id
+                        event["file"] = file_ref.model_dump() if file_ref else None
+                        event["secret_findings"] = [finding.model_dump() for finding in findings]
+                    yield json.dumps(event) + "\n"
+            except (httpx.HTTPError, CodeInterpreterException) as e:
+                # The response has started, so the failure can only be reported as the last event
+                logger.warning("Media processing stream failed", session_id=session_id, error=str(e))
+                failed = {
+                    "type": "result",
+                    "exit_code": 1,
+                    "status": "failed",
+                    "session_id": session_id,
+                    "file": None,
+                    "stderr": f"Media processing failed: {e}",
+                }
+                yield json.dumps(failed) + "\n"
+
+    async def search(
+        self,
+        session_id: str,
+        query: str,
+        glob: str = "**/*",
+        regex: bool = True,
+        ignore_case: bool = False,
+        context: int = 2,
+        max_matches: int | None = None,
+        language: str = "py",
+    ) -> FileSearchResponse:
+        """Search the contents of a session's files with the sidecar's bounded search.
+
+        Raises:
+            ServiceUnavailableError: If the language has no warm pod available
+            ValidationError: If the query or glob is invalid
+            ExternalServiceError: If the sidecar failed
+        """
+        files, file_ids = await self._all_session_files(session_id)
+        params = {"q": query, "glob": glob, "regex": regex, "ignore_case": ignore_case, "context": context}
+        if max_matches:
+            params["max_matches"] = max_matches
+        async with self._pod("File search", session_id, language, files, SEARCH_TIMEOUT) as (client, url):
+            response = await client.get(f"{url}/files/search", params=params)
+            self._raise_for_status("File search", response)
+        data = response.json()
+        return FileSearchResponse(
+            session_id=session_id,
+            matches=[FileSearchMatch(file_id=file_ids.get(match["path"]), **match) for match in data["matches"]],
+            files_scanned=data["files_scanned"],
+            truncated=data["truncated"],
+        )
+
+    async def apply_patch(self, session_id: str, request: FilePatchRequest) -> FilePatchResponse:
+        """Apply a unified diff to a session's files: all of it, or nothing.
+
+        The sidecar applies the diff to the uploaded files; created files are
+        stored as uploads, modified ones updated in place (keeping their ids)
+        and deleted ones removed from the session.
+
+        Raises:
+            ServiceUnavailableError: If the language has no warm pod available
+            ValidationError: If the diff is malformed or a path is outside the working directory
+            ResourceConflictError: If a hunk didn't apply, with the rejects as details
+            ExternalServiceError: If the sidecar failed
+        """
+        files, file_ids = await self._all_session_files(session_id)
+        body = request.model_dump(include={"diff", "strip", "dry_run"})
+        changed: dict[str, bytes] = {}
+        async with self._pod("Patching", session_id, request.language, files, PATCH_TIMEOUT) as (client, url):
+            response = await client.post(f"{url}/files/patch", json=body)
+            if response.status_code == 409:
+                detail = response.json()["detail"]
+                raise ResourceConflictError(detail["message"], details=[_reject_detail(r) for r in detail["rejects"]])
+            self._raise_for_status("Patching", response)
+            data = response.json()
+            for summary in data["files"]:
+                if data["applied"] and summary["status"] != "deleted":
+                    download = await client.get(f"{url}/files/{summary['path']}")
+                    self._raise_for_status("File download", download, summary["path"])
+                    changed[summary["path"]] = download.content
+
+        patched = []
+        for summary in data["files"]:
+            path, status = summary["path"], summary["status"]
+            file_id = file_ids.get(path)
+            if data["applied"]:
+                if status == "created":
+                    file_id = await self.file_service.store_uploaded_file(session_id, path, changed[path])
+                elif status == "modified":
+                    await self.file_service.update_file_content(session_id, file_id, changed[path])
+                else:
+                    await self.file_service.delete_file(session_id, file_id)
+                    file_id = None
+            patched.append(PatchedFile(path=path, status=status, hunks=summary["hunks"], file_id=file_id))
+        logger.info("Patched session files", session_id=session_id, files=len(patched), applied=data["applied"])
+        return FilePatchResponse(session_id=session_id, applied=data["applied"], files=patched)
+
+    async def symbols(self, session_id: str, file_id: str, language: str = "py") -> FileSymbolsResponse:
+        """Outline a session's source file with the sidecar's parsers; nothing is executed.
+
+        Raises:
+            ResourceNotFoundError: If the file doesn't exist
+            ValidationError: If the file type isn't supported (or the file is too large)
+            ServiceUnavailableError: If the language has no warm pod, or the sidecar no parser for the file
+            ExternalServiceError: If the sidecar failed
+        """
+        source = await self._session_file(session_id, file_id)
+        path = source.filename
+        async with self._pod("Code outline", session_id, language, [source], SYMBOLS_TIMEOUT) as (client, url):
+            response = await client.get(f"{url}/files/{path}/symbols")
+            self._raise_for_status("Code outline", response, path)
+        return FileSymbolsResponse(session_id=session_id, file_id=file_id, **response.json())
+
+    async def sync_signature(
+        self, session_id: str, file_id: str, block_size: int, language: str = "py"
+    ) -> SyncSignatureResponse:
+        """Block signature of a stored file, for a client that wants to send a delta against it.
+
+        Raises:
+            ResourceNotFoundError: If the file doesn't exist
+            ServiceUnavailableError: If the language has no warm pod available
+            ExternalServiceError: If the sidecar failed
+        """
+        source = await self._session_file(session_id, file_id)
+        async with self._pod("Sync", session_id, language, [source], SYNC_TIMEOUT) as (client, url):
+            response = await client.get(f"{url}/sync/signature/{source.filename}", params={"block_size": block_size})
+            self._raise_for_status("Sync", response, source.filename)
+        data = response.json()
+        return SyncSignatureResponse(
+            file_id=file_id,
+            size=data["size"],
+            sha256=data["sha256"],
+            block_size=data["block_size"],
+            blocks=data["blocks"],
+        )
+
+    async def sync_delta(
+        self, session_id: str, file_id: str, signature: SyncSignature, language: str = "py"
+    ) -> SyncDeltaResponse:
+        """Delta from a client's copy (described by its signature) to a stored file.
+
+        Raises:
+            ResourceNotFoundError: If the file doesn't exist
+            ServiceUnavailableError: If the language has no warm pod available
+            ExternalServiceError: If the sidecar failed
+        """
+        source = await self._session_file(session_id, file_id)
+        async with self._pod("Sync", session_id, language, [source], SYNC_TIMEOUT) as (client, url):
+            response = await client.post(f"{url}/sync/delta/{source.filename}", json=signature.model_dump())
+            self._raise_for_status("Sync", response, source.filename)
+        data = response.json()
+        return SyncDeltaResponse(
+            file_id=file_id, size=data["size"], sha256=data["sha256"], block_size=data["block_size"], ops=data["ops"]
+        )
+
+    async def sync_patch(
+        self, session_id: str, file_id: str, delta: SyncDelta, language: str = "py"
+    ) -> SyncPatchResponse:
+        """Apply a client's delta to a stored file, which keeps its id.
+
+        The delta's copy operations refer to the blocks of the stored file's
+        signature, so one made against a copy that has changed since fails
+        the ``sha256`` check (409) rather than storing a corrupt file.
+
+        Raises:
+            ResourceNotFoundError: If the file doesn't exist
+            ResourceConflictError: If the delta doesn't apply or the result doesn't match ``sha256``
+            ValidationError: If the result would be over the file size limit
+            ServiceUnavailableError: If the language has no warm pod available
+            ExternalServiceError: If the sidecar failed
+        """
+        source = await self._session_file(session_id, file_id)
+        async with self._pod("Sync", session_id, language, [source], SYNC_TIMEOUT) as (client, url):
+            response = await client.post(f"{url}/sync/patch/{source.filename}", json=delta.model_dump())
+            self._raise_for_status("Sync", response, source.filename)
+            data = response.json()
+            max_size = settings.max_file_size_mb * 1024 * 1024
+            if data["size"] > max_size:
+                raise ValidationError(f"Patched file would exceed maximum size of {settings.max_file_size_mb}MB")
+            download = await client.get(f"{url}/files/{source.filename}")
+            self._raise_for_status("File download", download, source.filename)
+
+        if not await self.file_service.update_file_content(session_id, file_id, download.content):
+            raise ResourceNotFoundError("File", file_id)
+        logger.info("Synced session file", session_id=session_id, file_id=file_id, size=data["size"])
+        return SyncPatchResponse(file_id=file_id, size=data["size"], sha256=data["sha256"])
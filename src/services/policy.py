@@ -0,0 +1,107 @@
+"""Dry-run evaluation of the policy in effect.
+
+evaluate() reports what an example execution would be allowed to do under
+the current settings (including the policy bundle, see
+src/config/policy.py): whether /exec would accept it and why not, its
+timeout, memory, CPUs and connection limit, its network access, which
+hosts it could reach and how its output would be filtered. It applies
+the same rules as execution without running anything, for security
+reviews and for checking a bundle before rolling it out
+(POST /admin/policy/evaluate, ``scripts/policy_cli.py evaluate``).
+"""
+
+from typing import Any
+
+from ..config import settings as default_settings
+from ..config.languages import is_supported_language
+from ..models.policy import PolicyDenial, PolicyEvaluation, PolicyEvaluationRequest
+from ..utils.security import SecurityValidator
+
+
+def dns_allows(name: str, allowlist: list[str]) -> bool:
+    """Whether a name matches an allowlist, as the sidecar's resolver decides (executor.dns.matches)."""
+    name = name.lower().rstrip(".")
+    for pattern in allowlist:
+        pattern = pattern.lower().rstrip(".")
+        if pattern.startswith("*."):
+            if name.endswith(pattern[1:]):
+                return True
+        elif name == pattern:
+            return True
+    return False
+
+
+def _network(config: Any) -> str:
+    if config.enable_wan_access:
+        return "wan"
+    return "none" if config.enable_network_isolation else "unrestricted"
+
+
+def evaluate(request: PolicyEvaluationRequest, config: Any = None) -> PolicyEvaluation:
+    """What ``request`` would be allowed to do under ``config`` (the settings in effect by default)."""
+    config = config or default_settings
+    denials: list[PolicyDenial] = []
+    lang = request.lang.lower()
+
+    if not is_supported_language(lang):
+        denials.append(
+            PolicyDenial(field="lang", code="unsupported_language", message=f"Language '{lang}' is not supported")
+        )
+    elif not config.is_language_allowed(lang):
+        denials.append(
+            PolicyDenial(
+                field="lang",
+                code="language_not_allowed",
+                message=f"Language '{lang}' is not in ALLOWED_LANGUAGES ({', '.join(config.allowed_languages)})",
+            )
+        )
+
+    dns_filtered = bool(config.dns_policy_upstream)
+    if request.dns_allowlist is not None:
+        invalid = SecurityValidator.invalid_dns_patterns(request.dns_allowlist)
+        if not dns_filtered:
+            denials.append(
+                PolicyDenial(
+                    field="dns_allowlist",
+                    code="dns_policy_unavailable",
+                    message="Set DNS_POLICY_UPSTREAM to enforce DNS allowlists",
+                )
+            )
+        elif invalid:
+            denials.append(
+                PolicyDenial(
+                    field="dns_allowlist",
+                    code="invalid_dns_allowlist",
+                    message=f"Invalid entries: {', '.join(invalid)}",
+                )
+            )
+
+    operator_names = config.dns_allowlist if dns_filtered else []
+    request_names = request.dns_allowlist if dns_filtered else None
+    resolvable = request_names if request_names is not None else operator_names or None
+    network = _network(config)
+
+    def reachable(host: str) -> bool:
+        if network == "none":
+            return False
+        if operator_names and not dns_allows(host, operator_names):
+            return False
+        return request_names is None or dns_allows(host, request_names)
+
+    limits = [limit for limit in (config.max_connections_per_execution, request.max_connections) if limit]
+    return PolicyEvaluation(
+        policy=config.get_policy_bundle().describe() if config.policy_bundle_path else None,
+        allowed=not denials,
+        denials=denials,
+        timeout_seconds=config.get_execution_timeout(lang),
+        memory_mb=config.get_memory_limit(lang),
+        cpus=config.max_cpus,
+        max_connections=min(limits) if limits else None,
+        max_output_files=config.max_output_files,
+        max_file_size_mb=config.max_file_size_mb,
+        network=network,
+        dns_allowlist=resolvable,
+        hosts={host: reachable(host) for host in request.hosts},
+        output_filters=list(config.output_filters),
+        secret_scan=config.artifact_secret_scan,
+    )
@@ -0,0 +1,249 @@
+"""Previews of tabular files without running user code.
+
+CSV/TSV, JSON Lines and Parquet files are parsed natively and a sample of
+typed rows is returned with the schema. Parsing stops after the requested
+rows, so a preview costs the same on a small file and a large one.
+"""
+
+import csv
+import io
+import json
+import math
+import re
+from dataclasses import dataclass, field
+from datetime import date, datetime
+from typing import Literal
+
+from .parquet import MAGIC, ParquetError, read_parquet
+
+PreviewFormat = Literal["csv", "tsv", "jsonl", "parquet"]
+
+DEFAULT_PREVIEW_ROWS = 100
+MAX_PREVIEW_ROWS = 1000
+# Longer strings are cut so a preview stays small
+MAX_CELL_CHARS = 1000
+SNIFF_BYTES = 64 * 1024
+
+_EXTENSIONS: dict[str, PreviewFormat] = {
+    ".csv": "csv",
+    ".tsv": "tsv",
+    ".tab": "tsv",
+    ".jsonl": "jsonl",
+    ".ndjson": "jsonl",
+    ".parquet": "parquet",
+    ".pq": "parquet",
+}
+
+_INTEGER = re.compile(r"^[+-]?\d+$")
+_NUMBER = re.compile(r"^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$")
+_BOOLEANS = {"true": True, "false": False}
+
+
+class PreviewError(ValueError):
+    """The file can't be previewed.
+
+    ``code`` is ``unsupported_preview_format`` or ``invalid_preview_file``.
+    """
+
+    def __init__(self, message: str, code: str = "invalid_preview_file"):
+        super().__init__(message)
+        self.code = code
+
+
+@dataclass
+class PreviewColumn:
+    name: str
+    type: str
+    nullable: bool = False
+
+
+@dataclass
+class FilePreview:
+    format: PreviewFormat
+    columns: list[PreviewColumn] = field(default_factory=list)
+    rows: list[list] = field(default_factory=list)
+    truncated: bool = False
+    total_rows: int | None = None
+
+
+def detect_format(filename: str, content: bytes) -> PreviewFormat | None:
+    """Preview format from the file extension, or the Parquet magic bytes."""
+    name = filename.lower()
+    for extension, preview_format in _EXTENSIONS.items():
+        if name.endswith(extension):
+            return preview_format
+    if content[:4] == MAGIC and content[-4:] == MAGIC:
+        return "parquet"
+    return None
+
+
+def _cell(value):
+    if isinstance(value, str) and len(value) > MAX_CELL_CHARS:
+        return value[:MAX_CELL_CHARS]
+    return value
+
+
+def _infer_type(value: str) -> str:
+    if _INTEGER.match(value):
+        return "integer"
+    if _NUMBER.match(value):
+        return "number"
+    if value.lower() in _BOOLEANS:
+        return "boolean"
+    for parse, kind in ((date.fromisoformat, "date"), (datetime.fromisoformat, "datetime")):
+        try:
+            parse(value)
+            return kind
+        except ValueError:
+            pass
+    return "string"
+
+
+def _merge_types(types: set[str]) -> str:
+    if not types:
+        return "null"
+    if len(types) == 1:
+        return types.pop()
+    if types == {"integer", "number"}:
+        return "number"
+    if types == {"date", "datetime"}:
+        return "datetime"
+    return "string"
+
+
+def _convert_text(value: str, kind: str):
+    if value == "":
+        return None
+    if kind == "integer":
+        return int(value)
+    if kind == "number":
+        return float(value)
+    if kind == "boolean":
+        return _BOOLEANS[value.lower()]
+    return _cell(value)
+
+
+def preview_csv(content: bytes, rows: int, delimiter: str | None = None) -> FilePreview:
+    """First rows of a delimited file; the first row is the header."""
+    text = io.TextIOWrapper(io.BytesIO(content), encoding="utf-8-sig", errors="replace", newline="")
+    if delimiter is None:
+        sample = content[:SNIFF_BYTES].decode("utf-8-sig", errors="ignore")
+        try:
+            delimiter = csv.Sniffer().sniff(sample, delimiters=",;\t|").delimiter
+        except csv.Error:
+            delimiter = ","
+    reader = csv.reader(text, delimiter=delimiter)
+    try:
+        header = next(reader, None)
+        if header is None:
+            return FilePreview(format="tsv" if delimiter == "\t" else "csv")
+        raw_rows = []
+        truncated = False
+        for record in reader:
+            if not record:
+                continue
+            if len(raw_rows) == rows:
+                truncated = True
+                break
+            raw_rows.append(record)
+    except csv.Error as e:
+        raise PreviewError(f"Invalid CSV at line {reader.line_num}: {e}")
+
+    width = max([len(header), *(len(r) for r in raw_rows)])
+    names = [header[i] if i < len(header) and header[i] else f"column_{i + 1}" for i in range(width)]
+    padded = [r + [""] * (width - len(r)) for r in raw_rows]
+    columns = []
+    for i, name in enumerate(names):
+        values = [r[i] for r in padded]
+        kind = _merge_types({_infer_type(v) for v in values if v != ""})
+        columns.append(PreviewColumn(name=name, type=kind, nullable=any(v == "" for v in values)))
+    converted = [[_convert_text(v, c.type) for v, c in zip(r, columns)] for r in padded]
+    return FilePreview(
+        format="tsv" if delimiter == "\t" else "csv", columns=columns, rows=converted, truncated=truncated
+    )
+
+
+def _json_type(value) -> str | None:
+    if value is None:
+        return None
+    if isinstance(value, bool):
+        return "boolean"
+    if isinstance(value, int):
+        return "integer"
+    if isinstance(value, float):
+        return "number"
+    if isinstance(value, str):
+        return "string"
+    return "array" if isinstance(value, list) else "object"
+
+
+def _json_value(value):
+    if isinstance(value, float) and not math.isfinite(value):
+        return str(value)
+    if isinstance(value, (dict, list)):
+        return _cell(json.dumps(value, ensure_ascii=False))
+    return _cell(value)
+
+
+def preview_jsonl(content: bytes, rows: int) -> FilePreview:
+    """First records of a JSON Lines file; columns are the keys in order of appearance."""
+    records = []
+    truncated = False
+    for number, line in enumerate(io.BytesIO(content), start=1):
+        if not line.strip():
+            continue
+        if len(records) == rows:
+            truncated = True
+            break
+        try:
+            record = json.loads(line)
+        except ValueError as e:
+            raise PreviewError(f"Invalid JSON on line {number}: {e}")
+        records.append(record if isinstance(record, dict) else {"value": record})
+
+    names: dict[str, None] = {}
+    for record in records:
+        names.update(dict.fromkeys(record))
+    columns = []
+    for name in names:
+        values = [record.get(name) for record in records]
+        types = {t for t in map(_json_type, values) if t}
+        kind = _merge_types(types) if types <= {"integer", "number"} or len(types) <= 1 else "mixed"
+        columns.append(PreviewColumn(name=name, type=kind, nullable=any(v is None for v in values)))
+    converted = [[_json_value(record.get(c.name)) for c in columns] for record in records]
+    return FilePreview(format="jsonl", columns=columns, rows=converted, truncated=truncated)
+
+
+def preview_parquet(content: bytes, rows: int) -> FilePreview:
+    try:
+        parquet = read_parquet(content, rows)
+    except ParquetError as e:
+        raise PreviewError(str(e))
+    return FilePreview(
+        format="parquet",
+        columns=[PreviewColumn(name=c.name, type=c.type, nullable=c.nullable) for c in parquet.columns],
+        rows=[[_cell(v) for v in row] for row in parquet.rows],
+        truncated=parquet.total_rows > len(parquet.rows),
+        total_rows=parquet.total_rows,
+    )
+
+
+def preview_file(
+    filename: str, content: bytes, rows: int = DEFAULT_PREVIEW_ROWS, preview_format: PreviewFormat | None = None
+) -> FilePreview:
+    """Schema and first rows of a tabular file.
+
+    Raises:
+        PreviewError: If the format is unknown or the file can't be parsed
+    """
+    preview_format = preview_format or detect_format(filename, content)
+    if preview_format == "parquet":
+        return preview_parquet(content, rows)
+    if preview_format == "jsonl":
+        return preview_jsonl(content, rows)
+    if preview_format in ("csv", "tsv"):
+        return preview_csv(content, rows, delimiter="\t" if preview_format == "tsv" else None)
+    raise PreviewError(
+        f"Can't preview {filename}: only CSV, TSV, JSON Lines and Parquet files are supported",
+        code="unsupported_preview_format",
+    )
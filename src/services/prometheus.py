@@ -0,0 +1,99 @@
+"""Prometheus text exposition of execution history.
+
+Renders the per-language, per-image outcome rates and duration
+percentiles from the SQLite metrics history (see
+SQLiteMetricsService.get_runtime_stats) as gauges over a trailing window,
+so a scrape shows which runtimes are failing or slowing down on which
+images without a Prometheus client library in the API. The execution
+slot gauges (render_scheduler_state) are this replica's own.
+"""
+
+from typing import Any
+
+from ..models.scheduler import SchedulerState
+
+CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+# (metric, stats field, help text); every gauge is labelled by language and image
+GAUGES = [
+    ("kubecoderun_language_executions", "execution_count", "Executions in the window"),
+    ("kubecoderun_language_success_ratio", "success_rate", "Share of executions that completed"),
+    ("kubecoderun_language_error_ratio", "error_rate", "Share of executions that failed"),
+    ("kubecoderun_language_timeout_ratio", "timeout_rate", "Share of executions that timed out"),
+]
+DURATION_METRIC = "kubecoderun_language_duration_ms"
+
+
+def _escape(value: str) -> str:
+    return value.replace("\\", "\\\\").replace('"', '\\"').replace("\n", "\\n")
+
+
+def _labels(**labels: str) -> str:
+    return "{" + ",".join(f'{name}="{_escape(value)}"' for name, value in labels.items()) + "}"
+
+
+def render_runtime_stats(stats: list[dict[str, Any]], hours: int) -> str:
+    """Exposition text for runtime stats; ratios are 0-1, durations in milliseconds."""
+    lines = []
+    for metric, key, help_text in GAUGES:
+        lines.append(f"# HELP {metric} {help_text} (last {hours}h)")
+        lines.append(f"# TYPE {metric} gauge")
+        for row in stats:
+            value = row[key] / 100 if key.endswith("_rate") else row[key]
+            labels = _labels(language=row["language"], image=row["image"] or "")
+            lines.append(f"{metric}{labels} {value:g}")
+
+    lines.append(f"# HELP {DURATION_METRIC} Execution duration percentiles (last {hours}h)")
+    lines.append(f"# TYPE {DURATION_METRIC} gauge")
+    for row in stats:
+        for quantile, key in (("0.5", "p50_ms"), ("0.95", "p95_ms")):
+            labels = _labels(language=row["language"], image=row["image"] or "", quantile=quantile)
+            lines.append(f"{DURATION_METRIC}{labels} {row[key]:g}")
+    return "\n".join(lines) + "\n"
+
+
+def render_scheduler_state(state: SchedulerState) -> str:
+    """Exposition text for this replica's execution slots and the waits for them."""
+    lines = []
+
+    def metric(name: str, kind: str, help_text: str, samples: list[tuple[str, float]]) -> None:
+        lines.append(f"# HELP {name} {help_text}")
+        lines.append(f"# TYPE {name} {kind}")
+        lines.extend(f"{name}{labels} {value:g}" for labels, value in samples)
+
+    metric("kubecoderun_execution_slots_limit", "gauge", "MAX_CONCURRENT_EXECUTIONS", [("", state.limit)])
+    metric("kubecoderun_execution_slots_running", "gauge", "Executions holding a slot", [("", state.running)])
+    metric("kubecoderun_execution_slots_waiting", "gauge", "Executions waiting for a slot", [("", state.waiting)])
+    metric(
+        "kubecoderun_execution_slot_wait_ms",
+        "gauge",
+        "Slot wait percentiles over recent executions",
+        [(_labels(quantile="0.5"), state.p50_wait_ms), (_labels(quantile="0.95"), state.p95_wait_ms)],
+    )
+    metric("kubecoderun_execution_slot_requests_total", "counter", "Slot requests", [("", state.slot_requests)])
+    metric(
+        "kubecoderun_execution_slot_timeouts_total",
+        "counter",
+        "Executions refused because no slot freed up",
+        [("", state.slot_timeouts)],
+    )
+    metric(
+        "kubecoderun_session_starvation_alerts_total",
+        "counter",
+        "Times a session's p95 slot wait went above QUEUE_WAIT_ALERT_P95_MS",
+        [("", state.starvation_alerts)],
+    )
+    starved = [s for s in state.sessions if s.starved]
+    metric(
+        "kubecoderun_starved_sessions",
+        "gauge",
+        "Sessions whose p95 slot wait is above the threshold",
+        [("", len(starved))],
+    )
+    metric(
+        "kubecoderun_session_slot_wait_p95_ms",
+        "gauge",
+        "p95 slot wait of each starved session",
+        [(_labels(session_id=s.session_id), s.p95_wait_ms) for s in starved],
+    )
+    return "\n".join(lines) + "\n"
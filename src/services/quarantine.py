@@ -0,0 +1,253 @@
+"""Session quarantine for incident response.
+
+POST /admin/quarantine freezes a session instead of deleting it: the
+session is marked quarantined so new executions and changes to its files,
+state or settings are rejected, its running executions are killed
+(SIGKILL; their pods are kept), and the expiry is removed from the
+session's Redis keys (metadata, env, file records, state, cell history,
+report tallies) so nothing is cleaned up while it is investigated. Its files in MinIO are
+kept because the session stays in the session index. Releasing it gives
+the keys that had an expiry a fresh session TTL; keys that had none keep
+none.
+
+Executions are only known to the replica that started them, so the
+session id is published on a Redis channel every replica subscribes to, and
+each kills its own executions of it. A replica misses announcements while
+it is disconnected from Redis, so every replica also rereads the quarantine
+index every QUARANTINE_POLL_INTERVAL_SECONDS and kills its executions of
+quarantined sessions.
+"""
+
+import asyncio
+from datetime import UTC, datetime, timedelta
+from typing import Any
+
+import redis.asyncio as redis
+import structlog
+
+from ..config import settings
+from ..core.pool import redis_pool
+from ..models.session import QuarantineRecord, SessionStatus
+from ..utils.security import SecurityAudit
+
+logger = structlog.get_logger(__name__)
+
+# Wait before subscribing again after losing the Redis connection
+_RESUBSCRIBE_DELAY_SECONDS = 5
+
+
+class QuarantineService:
+    """Quarantines sessions and keeps their executions stopped on every replica."""
+
+    KEY_PREFIX = "quarantine:"
+
+    def __init__(
+        self,
+        session_service: Any,
+        execution_service: Any,
+        file_service: Any = None,
+        state_service: Any = None,
+        cell_history_service: Any = None,
+        report_service: Any = None,
+        redis_client: redis.Redis | None = None,
+    ):
+        """Initialize the quarantine service.
+
+        Args:
+            session_service: Session service, to freeze and unfreeze sessions
+            execution_service: Execution service, to kill running executions
+            file_service: Optional file service, whose file records are preserved
+            state_service: Optional state service, whose saved state is preserved
+            cell_history_service: Optional cell history service, whose cells are preserved
+            report_service: Optional session report service, whose tallies and violations are preserved
+            redis_client: Optional Redis client, uses shared pool if not provided
+        """
+        self.redis = redis_client or redis_pool.get_client()
+        self.session_service = session_service
+        self.execution_service = execution_service
+        self.file_service = file_service
+        self.state_service = state_service
+        self.cell_history_service = cell_history_service
+        self.report_service = report_service
+        self._watch_task: asyncio.Task | None = None
+
+    def _record_key(self, session_id: str) -> str:
+        return f"{self.KEY_PREFIX}{session_id}"
+
+    def _expiring_key(self, session_id: str) -> str:
+        """Generate Redis key for the session keys that had an expiry when quarantined."""
+        return f"{self.KEY_PREFIX}{session_id}:expiring"
+
+    @property
+    def _index_key(self) -> str:
+        return f"{self.KEY_PREFIX}index"
+
+    @property
+    def _kill_channel(self) -> str:
+        return f"{self.KEY_PREFIX}kill"
+
+    async def _session_keys(self, session_id: str) -> list[str]:
+        """The session's Redis keys, from the key helpers of the services that own them."""
+        keys = [self.session_service._session_key(session_id), self.session_service._session_env_key(session_id)]
+        if self.file_service:
+            files_key = self.file_service._get_session_files_key(session_id)
+            keys.append(files_key)
+            for file_id in sorted(await self.redis.smembers(files_key)):
+                keys.append(self.file_service._get_file_metadata_key(session_id, file_id))
+        if self.state_service:
+            keys += [
+                self.state_service._state_key(session_id),
+                self.state_service._hash_key(session_id),
+                self.state_service._meta_key(session_id),
+                self.state_service._upload_marker_key(session_id),
+            ]
+        if self.cell_history_service:
+            keys += [
+                self.cell_history_service._cells_key(session_id),
+                self.cell_history_service._counter_key(session_id),
+            ]
+        if self.report_service:
+            keys += [self.report_service._tally_key(session_id), self.report_service._violations_key(session_id)]
+        return keys
+
+    async def quarantine(
+        self, session_id: str, reason: str | None = None, actor: str | None = None
+    ) -> QuarantineRecord | None:
+        """Freeze a session, kill its running executions and keep its data.
+
+        Quarantining a session again kills whatever is running again and
+        keeps the first record's time and reason. Returns None if the
+        session doesn't exist.
+        """
+        if not await self.session_service.get_session(session_id):
+            return None
+        existing = await self.get(session_id)
+
+        # Freeze first so nothing new starts while running executions are killed
+        await self.session_service.update_session(session_id, status=SessionStatus.QUARANTINED.value)
+        await self.redis.sadd(self._index_key, session_id)
+
+        killed = await self.execution_service.kill_session(session_id)
+        # The other replicas kill theirs when they hear of it
+        await self.redis.publish(self._kill_channel, session_id)
+
+        keys, expiring = [], []
+        for key in await self._session_keys(session_id):
+            ttl = await self.redis.ttl(key)
+            if ttl == -2:
+                # No such key
+                continue
+            if ttl > 0:
+                expiring.append(key)
+                await self.redis.persist(key)
+            keys.append(key)
+        if expiring:
+            # Added to, so quarantining again (once expiries are gone) doesn't forget them
+            await self.redis.sadd(self._expiring_key(session_id), *expiring)
+
+        record = QuarantineRecord(
+            session_id=session_id,
+            reason=existing.reason if existing else reason,
+            actor=existing.actor if existing else actor,
+            quarantined_at=existing.quarantined_at if existing else datetime.now(UTC),
+            killed=killed,
+            preserved_keys=len(keys),
+        )
+        await self.redis.set(self._record_key(session_id), record.model_dump_json())
+
+        SecurityAudit.log_quarantine(session_id, "quarantined", actor, reason, killed=killed, preserved_keys=len(keys))
+        return record
+
+    async def release(self, session_id: str, reason: str | None = None, actor: str | None = None) -> bool:
+        """Lift a quarantine: the session accepts work again and expires after a fresh session TTL.
+
+        Returns False if the session isn't quarantined.
+        """
+        if not await self.redis.sismember(self._index_key, session_id):
+            return False
+
+        ttl_seconds = int(settings.get_session_ttl_minutes() * 60)
+        await self.session_service.update_session(
+            session_id,
+            status=SessionStatus.ACTIVE.value,
+            expires_at=datetime.now(UTC) + timedelta(seconds=ttl_seconds),
+        )
+        for key in await self.redis.smembers(self._expiring_key(session_id)):
+            await self.redis.expire(key, ttl_seconds)
+
+        pipe = await self.redis.pipeline(transaction=True)
+        try:
+            pipe.srem(self._index_key, session_id)
+            pipe.delete(self._record_key(session_id), self._expiring_key(session_id))
+            await pipe.execute()
+        finally:
+            await pipe.reset()
+
+        SecurityAudit.log_quarantine(session_id, "released", actor, reason)
+        return True
+
+    async def is_quarantined(self, session_id: str) -> bool:
+        """Whether the session is quarantined."""
+        return bool(await self.redis.sismember(self._index_key, session_id))
+
+    async def get(self, session_id: str) -> QuarantineRecord | None:
+        """The quarantine record of a session, if it is quarantined."""
+        raw = await self.redis.get(self._record_key(session_id))
+        if not raw:
+            return None
+        try:
+            return QuarantineRecord.model_validate_json(raw)
+        except ValueError:
+            logger.warning("Ignoring unreadable quarantine record", session_id=session_id[:12])
+            return None
+
+    async def list_quarantined(self) -> list[QuarantineRecord]:
+        """All quarantined sessions, most recently quarantined first."""
+        records = [await self.get(session_id) for session_id in await self.redis.smembers(self._index_key)]
+        return sorted((r for r in records if r), key=lambda r: r.quarantined_at, reverse=True)
+
+    async def kill_quarantined(self) -> int:
+        """Kill this replica's executions of quarantined sessions; returns how many were killed."""
+        killed = 0
+        for session_id in await self.redis.smembers(self._index_key):
+            killed += await self.execution_service.kill_session(session_id)
+        return killed
+
+    async def start_watch_task(self) -> None:
+        """Keep killing local executions of sessions quarantined through any replica."""
+        if self._watch_task is None or self._watch_task.done():
+            self._watch_task = asyncio.create_task(self._watch_loop(settings.quarantine_poll_interval_seconds))
+
+    async def stop_watch_task(self) -> None:
+        """Stop the background watch task."""
+        if self._watch_task and not self._watch_task.done():
+            self._watch_task.cancel()
+            try:
+                await self._watch_task
+            except asyncio.CancelledError:
+                pass
+
+    async def _watch_loop(self, interval: int) -> None:
+        """Kill local executions of each announced session, and of all quarantined ones every ``interval`` seconds.
+
+        An interval of 0 only acts on announcements.
+        """
+        loop = asyncio.get_running_loop()
+        next_check = loop.time() + interval
+        while True:
+            pubsub = self.redis.pubsub()
+            try:
+                await pubsub.subscribe(self._kill_channel)
+                while True:
+                    timeout = max(next_check - loop.time(), 0) if interval else None
+                    message = await pubsub.get_message(ignore_subscribe_messages=True, timeout=timeout)
+                    if message:
+                        await self.execution_service.kill_session(message["data"])
+                    if interval and loop.time() >= next_check:
+                        await self.kill_quarantined()
+                        next_check = loop.time() + interval
+            except Exception as e:
+                logger.warning("Failed to watch quarantined sessions", error=str(e))
+                await asyncio.sleep(_RESUBSCRIBE_DELAY_SECONDS)
+            finally:
+                await pubsub.aclose()
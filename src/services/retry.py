@@ -0,0 +1,63 @@
+"""Retry policy for transient execution failures.
+
+A request's ``retry`` block (RetryPolicy) opts into running the code again
+when an attempt failed for a reason unrelated to the code itself:
+
+- SPAWN_FAILED: the pod or sidecar wasn't available, the code never ran
+- OOM: the process was killed for using too much memory; with
+  reduce_parallelism the retry caps common thread-pool variables, halving
+  them on each attempt
+- TIMEOUT: the attempt hit the execution timeout
+
+Clients must only opt in for idempotent code: a retried attempt runs in
+the same workspace, after whatever the failed attempt wrote.
+"""
+
+from ..config import settings
+from ..models import CodeExecution, ExecutionStatus, RetryPolicy
+from .kubernetes.models import SPAWN_FAILED
+
+OOM = "OOM"
+TIMEOUT = "TIMEOUT"
+
+# Exit status of a SIGKILLed process (the OOM killer), as a shell and as asyncio report it
+OOM_EXIT_CODES = (137, -9)
+OOM_MARKERS = ("MemoryError", "std::bad_alloc", "fatal error: runtime: out of memory", "JavaScript heap out of memory")
+
+# Thread-pool sizes honoured by common runtimes and numeric libraries
+PARALLELISM_ENV_VARS = (
+    "OMP_NUM_THREADS",
+    "OPENBLAS_NUM_THREADS",
+    "MKL_NUM_THREADS",
+    "NUMEXPR_NUM_THREADS",
+    "VECLIB_MAXIMUM_THREADS",
+    "RAYON_NUM_THREADS",
+    "GOMAXPROCS",
+)
+
+
+def classify_failure(execution: CodeExecution) -> str | None:
+    """The retryable failure class of an attempt, or None if it succeeded or failed in the code."""
+    if execution.status == ExecutionStatus.TIMEOUT:
+        return TIMEOUT
+    if execution.status != ExecutionStatus.FAILED:
+        return None
+    if execution.error and execution.error.get("code") == SPAWN_FAILED:
+        return SPAWN_FAILED
+    if execution.exit_code in OOM_EXIT_CODES:
+        return OOM
+    stderr = "".join(o.content for o in execution.outputs if o.type.value == "stderr")
+    if any(marker in stderr for marker in OOM_MARKERS):
+        return OOM
+    return None
+
+
+def backoff_seconds(policy: RetryPolicy, retry_number: int) -> float:
+    """Delay before the nth retry (1-based)."""
+    return policy.backoff_ms / 1000 * policy.backoff_multiplier ** (retry_number - 1)
+
+
+def reduced_parallelism_env(oom_count: int) -> dict[str, str]:
+    """Thread caps after the given number of OOM attempts: half the CPUs, then a quarter, down to 1."""
+    threads = max(1, int(settings.max_cpus) >> oom_count)
+    return {name: str(threads) for name in PARALLELISM_ENV_VARS}
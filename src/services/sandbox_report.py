@@ -0,0 +1,70 @@
+"""Sandbox reports - which protections an execution actually ran under.
+
+The sidecar reads them from the kernel for every execution (seccomp mode,
+no_new_privs, effective UID, cgroup limits, Landlock support and the
+pod's network; see docker/sidecar/executor/sandbox.py). Here they are
+compared with this deployment's configuration: anything weaker than
+configured is listed in the report's mismatches and logged as a security
+event whether or not the client asked for the report.
+
+Responses include the report when SANDBOX_REPORT is on, or when the
+request sets sandbox_report.
+"""
+
+import structlog
+from pydantic import ValidationError
+
+from ..config import settings
+from ..models.exec import SandboxReport
+from ..utils.security import SecurityAudit
+
+logger = structlog.get_logger(__name__)
+
+
+def _unlimited(value: str | None) -> bool:
+    return value is not None and value.split()[0] == "max"
+
+
+def find_mismatches(report: SandboxReport) -> list[str]:
+    """Protections in the report weaker than configured; unreadable fields aren't compared."""
+    mismatches = []
+    seccomp_profile = settings.k8s_seccomp_profile_type
+    if seccomp_profile != "Unconfined" and report.seccomp == "disabled":
+        mismatches.append(f"seccomp: {seccomp_profile} configured, disabled applied")
+    if settings.k8s_sidecar_memory_limit and _unlimited(report.cgroup.memory):
+        mismatches.append(f"memory: {settings.k8s_sidecar_memory_limit} configured, unlimited applied")
+    if settings.k8s_sidecar_cpu_limit and _unlimited(report.cgroup.cpu):
+        mismatches.append(f"cpu: {settings.k8s_sidecar_cpu_limit} configured, unlimited applied")
+    if settings.enable_network_isolation and report.network.mode == "open":
+        mismatches.append("network: isolated configured, open applied")
+    if report.uid is not None and report.uid != settings.k8s_run_as_user:
+        mismatches.append(f"uid: {settings.k8s_run_as_user} configured, {report.uid} applied")
+    return mismatches
+
+
+def build_sandbox_report(
+    observed: dict | None, session_id: str | None = None, execution_id: str | None = None
+) -> SandboxReport | None:
+    """The report for what the sidecar observed, with mismatches audited; None without one.
+
+    Args:
+        observed: The sandbox block of the sidecar's /execute response
+        session_id: Session the execution ran in, for the security log
+        execution_id: The execution, for the security log
+    """
+    if not observed:
+        return None
+    try:
+        report = SandboxReport.model_validate(observed)
+    except ValidationError as e:
+        logger.warning("Ignoring malformed sandbox report", session_id=session_id, error=str(e))
+        return None
+
+    report.mismatches = find_mismatches(report)
+    if report.mismatches:
+        SecurityAudit.log_security_event(
+            "sandbox_mismatch",
+            {"session_id": session_id, "execution_id": execution_id, "mismatches": report.mismatches},
+            severity="warning",
+        )
+    return report
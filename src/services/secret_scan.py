@@ -0,0 +1,101 @@
+"""Secret scanning of execution output and generated files.
+
+Code can read a mounted credential and print it or write it into a file it
+returns. ARTIFACT_SECRET_SCAN looks for well-known credential formats (the
+kinds in output_filters.SECRET_PATTERNS, plus this deployment's own
+secrets) and applies a policy:
+
+- off: no scanning
+- flag: findings are reported in the response (secret_findings), output
+  and files are returned unchanged
+- block: matches in stdout/stderr are redacted; files containing a
+  credential are not stored or returned
+
+Binary files and files over ARTIFACT_SECRET_SCAN_MAX_BYTES are skipped.
+"""
+
+import re
+
+from ..config import settings
+from ..models.exec import SecretFinding
+from ..utils.security import SecurityAudit
+from .output_filters import MIN_LITERAL_SECRET_LENGTH, SECRET_PATTERNS, deployment_secrets
+
+# Files with a NUL byte in this prefix are treated as binary
+BINARY_SNIFF_BYTES = 8192
+
+
+def _patterns() -> dict[str, re.Pattern]:
+    patterns = {kind: re.compile(pattern) for kind, pattern in SECRET_PATTERNS.items()}
+    literals = sorted(
+        {s for s in deployment_secrets() if s and len(s) >= MIN_LITERAL_SECRET_LENGTH}, key=len, reverse=True
+    )
+    if literals:
+        patterns["deployment_secret"] = re.compile("|".join(re.escape(s) for s in literals))
+    return patterns
+
+
+def scan_text(text: str) -> list[tuple[str, int]]:
+    """Find credentials in text. Returns (kind, 1-based line) pairs in order of appearance."""
+    findings = set()
+    for kind, pattern in _patterns().items():
+        for match in pattern.finditer(text):
+            findings.add((match.start(), kind, text.count("\n", 0, match.start()) + 1))
+    return [(kind, line) for _, kind, line in sorted(findings)]
+
+
+def redact_text(text: str) -> str:
+    """Replace every credential scan_text() would find."""
+    for pattern in _patterns().values():
+        text = pattern.sub(settings.output_redaction_text, text)
+    return text
+
+
+def is_scannable(content: bytes) -> bool:
+    """Whether a file is small enough and looks like text."""
+    return len(content) <= settings.artifact_secret_scan_max_bytes and b"\0" not in content[:BINARY_SNIFF_BYTES]
+
+
+def scan_output(stream: str, text: str) -> tuple[str, list[SecretFinding]]:
+    """Scan an output stream per policy. Returns (text to return, findings)."""
+    policy = settings.artifact_secret_scan
+    if policy == "off" or not text:
+        return text, []
+
+    found = scan_text(text)
+    if not found:
+        return text, []
+
+    action = "redacted" if policy == "block" else "flagged"
+    if policy == "block":
+        text = redact_text(text)
+    return text, [SecretFinding(source=stream, kind=kind, line=line, action=action) for kind, line in found]
+
+
+def scan_file(filename: str, content: bytes) -> list[SecretFinding]:
+    """Scan a generated file per policy.
+
+    The findings' action is "blocked" when the policy is block; the caller
+    must then drop the file.
+    """
+    policy = settings.artifact_secret_scan
+    if policy == "off" or not content or not is_scannable(content):
+        return []
+
+    found = scan_text(content.decode("utf-8", errors="replace"))
+    action = "blocked" if policy == "block" else "flagged"
+    return [SecretFinding(source="file", file=filename, kind=kind, line=line, action=action) for kind, line in found]
+
+
+def audit_findings(session_id: str | None, findings: list[SecretFinding]) -> None:
+    """Record findings in the security log (kinds and locations, never the secrets)."""
+    if not findings:
+        return
+    SecurityAudit.log_security_event(
+        "secret_detected",
+        {
+            "session_id": session_id,
+            "findings": [f.model_dump(exclude_none=True) for f in findings],
+        },
+        severity="warning",
+    )
@@ -29,12 +29,15 @@ class SessionService(SessionServiceInterface):
         redis_client: redis.Redis | None = None,
         execution_service=None,
         file_service=None,
+        report_service=None,
     ):
         """Initialize the session service with Redis client."""
         self.redis = redis_client or redis_pool.get_client()
         self._cleanup_task: asyncio.Task | None = None
         self._execution_service = execution_service
         self._file_service = file_service
+        # Without a report service sessions end without an end-of-session report
+        self._report_service = report_service
         self._redis_available = False
         logger.info("Redis client created", url=settings.get_redis_url().split("@")[-1])
 
@@ -132,9 +135,16 @@ class SessionService(SessionServiceInterface):
         """Generate Redis key for entity-based session grouping."""
         return f"entity_sessions:{entity_id}"
 
-    async def create_session(self, request: SessionCreate) -> Session:
-        """Create a new code execution session."""
-        session_id = self._generate_session_id()
+    def _session_env_key(self, session_id: str) -> str:
+        """Generate Redis key for the session's stored environment variables."""
+        return f"session_env:{session_id}"
+
+    async def create_session(self, request: SessionCreate, session_id: str | None = None) -> Session:
+        """Create a new code execution session.
+
+        ``session_id`` keeps the id of a session imported from another cluster.
+        """
+        session_id = session_id or self._generate_session_id()
         now = datetime.now(UTC)
         expires_at = now + timedelta(minutes=settings.get_session_ttl_minutes())
 
@@ -202,9 +212,11 @@ class SessionService(SessionServiceInterface):
             return None
 
         # Convert ISO strings back to datetime objects
-        for key in ["created_at", "last_activity", "expires_at"]:
+        for key in ["created_at", "last_activity", "expires_at", "last_restart_at"]:
             if key in session_data and session_data[key]:
                 session_data[key] = datetime.fromisoformat(session_data[key])
+        if not session_data.get("last_restart_at"):
+            session_data["last_restart_at"] = None
 
         # Parse JSON fields
         if "files" in session_data and session_data["files"]:
@@ -267,8 +279,13 @@ class SessionService(SessionServiceInterface):
         # Return updated session
         return await self.get_session(session_id)
 
-    async def delete_session(self, session_id: str) -> bool:
-        """Delete a session and cleanup resources."""
+    async def delete_session(self, session_id: str, reason: str = "deleted") -> bool:
+        """Delete a session and cleanup resources.
+
+        Args:
+            session_id: The session
+            reason: Why it ends (deleted, expired or forced), recorded in its end-of-session report
+        """
         session_key = self._session_key(session_id)
 
         # Get session data to check for entity_id before deletion
@@ -277,6 +294,14 @@ class SessionService(SessionServiceInterface):
         if session and session.metadata:
             entity_id = session.metadata.get("entity_id")
 
+        # Report on the session BEFORE its files are deleted (their digests are part of it)
+        if self._report_service and session:
+            try:
+                await self._report_service.finalize(session_id, session, reason)
+            except Exception as e:
+                logger.error("Failed to generate session report", session_id=session_id, error=str(e))
+                # Continue with session deletion even if the report fails
+
         # Clean up execution resources (containers) BEFORE deleting session
         if self._execution_service:
             try:
@@ -310,8 +335,8 @@ class SessionService(SessionServiceInterface):
         # Use transaction to ensure atomicity
         pipe = await self.redis.pipeline(transaction=True)
         try:
-            # Remove session data
-            pipe.delete(session_key)
+            # Remove session data and its stored env
+            pipe.delete(session_key, self._session_env_key(session_id))
             # Remove from session index
             pipe.srem(self._session_index_key(), session_id)
 
@@ -330,6 +355,52 @@ class SessionService(SessionServiceInterface):
 
         return deleted
 
+    async def set_session_env(self, session_id: str, env: dict[str, str]) -> bool:
+        """Replace the environment variables applied to executions in a session.
+
+        The env hash expires together with the session. Returns False if the
+        session does not exist.
+        """
+        session_key = self._session_key(session_id)
+        ttl = await self.redis.ttl(session_key)
+        if ttl == -2:  # Key does not exist
+            return False
+
+        env_key = self._session_env_key(session_id)
+        pipe = await self.redis.pipeline(transaction=True)
+        try:
+            pipe.delete(env_key)
+            if env:
+                pipe.hset(env_key, mapping=env)
+                if ttl > 0:
+                    pipe.expire(env_key, ttl)
+            await pipe.execute()
+        finally:
+            await pipe.reset()
+
+        # Never log values, they commonly hold API keys
+        logger.info("Session env updated", session_id=session_id, names=sorted(env))
+        return True
+
+    async def get_session_env(self, session_id: str) -> dict[str, str]:
+        """Get the environment variables stored for a session."""
+        return await self.redis.hgetall(self._session_env_key(session_id)) or {}
+
+    async def record_restart(self, session_id: str) -> int | None:
+        """Count a kernel restart. Returns the new restart count, or None if the session is gone."""
+        session_key = self._session_key(session_id)
+        if not await self.redis.exists(session_key):
+            return None
+
+        pipe = await self.redis.pipeline(transaction=True)
+        try:
+            pipe.hincrby(session_key, "restart_count", 1)
+            pipe.hset(session_key, "last_restart_at", datetime.now(UTC).isoformat())
+            count, _ = await pipe.execute()
+        finally:
+            await pipe.reset()
+        return int(count)
+
     async def list_sessions(self, limit: int = 100, offset: int = 0) -> list[Session]:
         """List all active sessions."""
         # Get all session IDs from the index
@@ -386,6 +457,10 @@ class SessionService(SessionServiceInterface):
                 cleaned_count += 1
                 continue
 
+            if session.status == SessionStatus.QUARANTINED:
+                # Kept for investigation until the quarantine is lifted
+                continue
+
             if session.expires_at < now:
                 logger.info(
                     "Cleaning up expired session",
@@ -393,7 +468,7 @@ class SessionService(SessionServiceInterface):
                     expired_at=session.expires_at.isoformat(),
                     current_time=now.isoformat(),
                 )
-                await self.delete_session(session_id)
+                await self.delete_session(session_id, reason="expired")
                 cleaned_count += 1
 
         if cleaned_count > 0:
@@ -411,7 +486,7 @@ class SessionService(SessionServiceInterface):
         cleaned_count = 0
 
         for session_id in session_ids:
-            await self.delete_session(session_id)
+            await self.delete_session(session_id, reason="forced")
             cleaned_count += 1
 
         logger.info("Force cleaned all sessions", cleaned_count=cleaned_count)
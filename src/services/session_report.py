@@ -0,0 +1,275 @@
+"""End-of-session reports - a complete account of what happened in a sandbox.
+
+While a session is active every execution is tallied: its status,
+language, run time, memory peak and outbound connections, and the
+policies it ran into (connection limit, denied DNS lookups, credentials in
+its output or files, protections weaker than configured). When the session
+is terminated, by DELETE /sessions/{id} or on expiry, the tallies are
+combined with its files (sizes and the SHA-256 digests recorded when they
+were stored; files stored without one, such as presigned uploads, are read
+before they are deleted) into a report that is:
+
+- returned by DELETE /sessions/{id}, and kept for
+  SESSION_REPORT_RETENTION_HOURS for GET /sessions/{id}/report
+- logged as a ``session_report`` security event (with the counts, not the
+  file list)
+- archived to object storage as reports/<session_id>.json with
+  SESSION_REPORT_ARCHIVE, where it stays until the bucket's lifecycle
+  rules remove it
+
+The tallies are one Redis hash and one list of violations per session,
+capped at SESSION_REPORT_MAX_VIOLATIONS entries and expiring with the
+session.
+"""
+
+import asyncio
+import io
+import json
+from datetime import UTC, datetime
+from typing import Any
+
+import redis.asyncio as redis
+import structlog
+
+from ..config import settings
+from ..core.pool import redis_pool
+from ..models.exec import SandboxReport, SecretFinding
+from ..models.execution import CodeExecution
+from ..models.session import Session
+from ..models.session_report import (
+    SessionArtifact,
+    SessionReport,
+    SessionReportExecutions,
+    SessionReportResources,
+    SessionViolation,
+)
+from ..utils.checksum import compute_checksum
+from ..utils.security import SecurityAudit
+from .kubernetes.models import CONNECTION_LIMIT_EXCEEDED
+
+logger = structlog.get_logger(__name__)
+
+ARCHIVE_PREFIX = "reports"
+
+
+def execution_violations(
+    execution: CodeExecution,
+    secret_findings: list[SecretFinding] | None = None,
+    sandbox: SandboxReport | None = None,
+) -> list[SessionViolation]:
+    """Policies one execution ran into."""
+    now = datetime.now(UTC)
+    violations = []
+
+    def add(kind: str, detail: str) -> None:
+        violations.append(SessionViolation(kind=kind, execution_id=execution.execution_id, at=now, detail=detail))
+
+    if (execution.error or {}).get("code") == CONNECTION_LIMIT_EXCEEDED:
+        add("connection_limit_exceeded", f"{execution.network_connections or 0} connections")
+    if execution.dns_denied:
+        add("dns_denied", ", ".join(execution.dns_denied[:10]))
+    for finding in secret_findings or []:
+        where = finding.file or finding.source
+        line = f" line {finding.line}" if finding.line else ""
+        add("secret_detected", f"{finding.kind} in {where}{line} ({finding.action})")
+    for mismatch in sandbox.mismatches if sandbox else []:
+        add("sandbox_mismatch", mismatch)
+    return violations
+
+
+class SessionReportService:
+    """Tallies sessions' executions in Redis and reports on them when they end."""
+
+    KEY_PREFIX = "session:report:"
+
+    def __init__(self, redis_client: redis.Redis | None = None, file_service: Any = None, minio_client: Any = None):
+        """Initialize the session report service.
+
+        Args:
+            redis_client: Optional Redis client, uses shared pool if not provided
+            file_service: Lists and reads the session's files; without it reports have no artifacts
+            minio_client: Optional MinIO client for SESSION_REPORT_ARCHIVE, created when first needed
+        """
+        self.redis = redis_client or redis_pool.get_client()
+        self.file_service = file_service
+        self._minio_client = minio_client
+
+    def _tally_key(self, session_id: str) -> str:
+        """Generate Redis key for a session's execution tallies."""
+        return f"{self.KEY_PREFIX}{session_id}"
+
+    def _violations_key(self, session_id: str) -> str:
+        """Generate Redis key for a session's violations."""
+        return f"{self.KEY_PREFIX}{session_id}:violations"
+
+    def _report_key(self, session_id: str) -> str:
+        """Generate Redis key for a terminated session's report."""
+        return f"{self.KEY_PREFIX}{session_id}:final"
+
+    async def record_execution(
+        self, session_id: str, execution: CodeExecution, violations: list[SessionViolation] | None = None
+    ) -> None:
+        """Add an execution, and the policies it ran into, to the session's tallies."""
+        if not settings.session_report_enabled:
+            return
+
+        key = self._tally_key(session_id)
+        ttl = settings.get_session_ttl_minutes() * 60
+        peak = await self.redis.hget(key, "memory_peak_mb")
+        now = (execution.completed_at or datetime.now(UTC)).isoformat()
+
+        pipe = await self.redis.pipeline(transaction=True)
+        try:
+            pipe.hincrby(key, "executions", 1)
+            pipe.hincrby(key, f"status:{execution.status.value}", 1)
+            pipe.hincrby(key, f"lang:{execution.language}", 1)
+            pipe.hincrby(key, "execution_time_ms", execution.execution_time_ms or 0)
+            pipe.hincrby(key, "network_connections", execution.network_connections or 0)
+            if execution.memory_peak_mb is not None and (peak is None or execution.memory_peak_mb > float(peak)):
+                pipe.hset(key, "memory_peak_mb", execution.memory_peak_mb)
+            pipe.hsetnx(key, "first_at", now)
+            pipe.hset(key, "last_at", now)
+            pipe.expire(key, ttl)
+            if violations:
+                violations_key = self._violations_key(session_id)
+                pipe.hincrby(key, "violations", len(violations))
+                pipe.rpush(violations_key, *(v.model_dump_json() for v in violations))
+                pipe.ltrim(violations_key, -settings.session_report_max_violations, -1)
+                pipe.expire(violations_key, ttl)
+            await pipe.execute()
+        finally:
+            await pipe.reset()
+
+    async def build(
+        self, session_id: str, session: Session | None = None, reason: str | None = None, ended: bool = False
+    ) -> SessionReport:
+        """The session's report from its tallies and current files."""
+        tally = await self.redis.hgetall(self._tally_key(session_id)) or {}
+        violations = []
+        for raw in await self.redis.lrange(self._violations_key(session_id), 0, -1):
+            try:
+                violations.append(SessionViolation(**json.loads(raw)))
+            except (TypeError, ValueError) as e:
+                logger.warning("Skipping corrupt violation record", session_id=session_id[:12], error=str(e))
+
+        executions = SessionReportExecutions(
+            total=int(tally.get("executions", 0)),
+            by_status={k.split(":", 1)[1]: int(v) for k, v in tally.items() if k.startswith("status:")},
+            by_language={k.split(":", 1)[1]: int(v) for k, v in tally.items() if k.startswith("lang:")},
+            first_at=datetime.fromisoformat(tally["first_at"]) if tally.get("first_at") else None,
+            last_at=datetime.fromisoformat(tally["last_at"]) if tally.get("last_at") else None,
+        )
+        artifacts = await self._artifacts(session_id)
+        return SessionReport(
+            session_id=session_id,
+            entity_id=(session.metadata or {}).get("entity_id") if session else None,
+            created_at=session.created_at if session else None,
+            ended_at=datetime.now(UTC) if ended else None,
+            reason=reason,
+            executions=executions,
+            resources=SessionReportResources(
+                execution_time_ms=int(tally.get("execution_time_ms", 0)),
+                memory_peak_mb=float(tally["memory_peak_mb"]) if tally.get("memory_peak_mb") else None,
+                network_connections=int(tally.get("network_connections", 0)),
+                artifact_bytes=sum(a.size for a in artifacts),
+            ),
+            artifacts=artifacts,
+            violations=violations,
+            violations_dropped=max(int(tally.get("violations", 0)) - len(violations), 0),
+        )
+
+    async def _artifacts(self, session_id: str) -> list[SessionArtifact]:
+        """The session's files with digests of their content."""
+        if not self.file_service:
+            return []
+        artifacts = []
+        for info in await self.file_service.list_files(session_id):
+            sha256 = info.sha256
+            if sha256 is None:
+                try:
+                    content = await self.file_service.get_file_content(session_id, info.file_id)
+                except Exception as e:
+                    logger.warning("Failed to read file for session report", file_id=info.file_id, error=str(e))
+                    content = None
+                sha256 = compute_checksum(content) if content is not None else None
+            artifacts.append(
+                SessionArtifact(
+                    file_id=info.file_id,
+                    filename=info.filename,
+                    path=info.path,
+                    size=info.size,
+                    content_type=info.content_type,
+                    sha256=sha256,
+                    created_at=info.created_at,
+                )
+            )
+        return artifacts
+
+    async def finalize(self, session_id: str, session: Session | None, reason: str) -> SessionReport | None:
+        """Report on a session being terminated, before its files are deleted. Returns None when disabled.
+
+        The report is kept for GET /sessions/{id}/report, audited, and
+        archived with SESSION_REPORT_ARCHIVE; the tallies are deleted.
+        """
+        if not settings.session_report_enabled:
+            return None
+
+        report = await self.build(session_id, session, reason=reason, ended=True)
+        if settings.session_report_archive:
+            report.archive_key = await self._archive(report)
+
+        pipe = await self.redis.pipeline(transaction=True)
+        try:
+            pipe.set(
+                self._report_key(session_id),
+                report.model_dump_json(),
+                ex=settings.session_report_retention_hours * 3600,
+            )
+            pipe.delete(self._tally_key(session_id), self._violations_key(session_id))
+            await pipe.execute()
+        finally:
+            await pipe.reset()
+
+        SecurityAudit.log_session_report(
+            session_id,
+            reason,
+            executions=report.executions.total,
+            execution_time_ms=report.resources.execution_time_ms,
+            artifacts=len(report.artifacts),
+            artifact_bytes=report.resources.artifact_bytes,
+            violations=len(report.violations) + report.violations_dropped,
+            archive_key=report.archive_key,
+        )
+        return report
+
+    async def get(self, session_id: str) -> SessionReport | None:
+        """The report of a terminated session, while it's retained."""
+        data = await self.redis.get(self._report_key(session_id))
+        try:
+            return SessionReport.model_validate_json(data) if data else None
+        except ValueError as e:
+            logger.warning("Ignoring corrupt session report", session_id=session_id[:12], error=str(e))
+            return None
+
+    async def _archive(self, report: SessionReport) -> str | None:
+        """Upload the report to object storage; returns its key, or None if the upload failed."""
+        object_key = f"{ARCHIVE_PREFIX}/{report.session_id}.json"
+        data = report.model_dump_json().encode()
+        try:
+            if self._minio_client is None:
+                self._minio_client = settings.minio.create_client()
+            loop = asyncio.get_event_loop()
+            await loop.run_in_executor(
+                None,
+                lambda: self._minio_client.put_object(
+                    settings.minio_bucket,
+                    object_key,
+                    io.BytesIO(data),
+                    len(data),
+                    content_type="application/json",
+                ),
+            )
+        except Exception as e:
+            logger.error("Failed to archive session report", session_id=report.session_id[:12], error=str(e))
+            return None
+        return object_key
@@ -0,0 +1,333 @@
+"""Session export and import, for moving long-lived sessions between clusters.
+
+GET /admin/sessions/{id}/export bundles everything a session needs to carry
+on elsewhere into one tar.gz:
+
+- manifest.json: format version, the session's metadata, its files'
+  metadata, environment variable names (values only with include_env), the
+  kernel checkpoint's info and the packages its cells installed
+- files/<file_id>: each workspace file
+- state.bin: the kernel checkpoint (raw state), read back from the archive
+  in MinIO when it's no longer in Redis
+- cells.jsonl: the cell history, one cell per line
+
+POST /admin/sessions/import restores a bundle into this cluster under the
+same session and file ids, so clients (and cell history) can keep using
+them after a migration or region failover. Bundles are extracted with the
+same limits as uploaded archives. Installed packages aren't reinstalled:
+pods are shared between sessions, so the manifest is returned for the
+client to replay before running code.
+"""
+
+import io
+import json
+import re
+import shlex
+import tarfile
+from dataclasses import dataclass, field
+from datetime import UTC, datetime
+from typing import Any
+
+import structlog
+
+from ..config import settings
+from ..models.cell import CellInfo
+from ..models.session import SessionCreate, SessionImportResult
+from .archive import ArchiveError, ArchiveLimits, extract_archive
+
+logger = structlog.get_logger(__name__)
+
+FORMAT_VERSION = 1
+# Room in a bundle for manifest.json and cells.jsonl on top of files and state
+METADATA_ALLOWANCE = 16 * 1024 * 1024
+# Tar header and padding of each member, on top of its content
+_TAR_MEMBER_OVERHEAD = 1024
+# Session and file ids become Redis keys and MinIO paths
+_ID = re.compile(r"^[A-Za-z0-9_-]{1,64}$")
+
+# pip/npm/yarn/pnpm installs in shell cells or notebook magics (!pip, %pip), one command per match
+_INSTALL_COMMAND = re.compile(
+    r"(?:^|[;&|]|[!%])\s*(?:(?:python3?\s+-m\s+)?(?P<pip>pip3?)\s+install|(?P<npm>npm)\s+(?:install|i|add)"
+    r"|(?P<yarn>yarn|pnpm)\s+add)\s+(?P<args>[^;&|\n]+)",
+    re.MULTILINE,
+)
+_R_INSTALL = re.compile(r"install\.packages\s*\((?P<args>[^)]*)\)")
+_QUOTED = re.compile(r"""["']([A-Za-z0-9._]+)["']""")
+# Options whose value is the next argument, not a package
+_VALUE_OPTIONS = {
+    "-r",
+    "--requirement",
+    "-c",
+    "--constraint",
+    "-i",
+    "--index-url",
+    "--extra-index-url",
+    "-f",
+    "--find-links",
+    "-t",
+    "--target",
+    "--registry",
+}
+
+
+class SessionTransferError(ValueError):
+    """A session can't be exported or imported.
+
+    ``code`` is ``not_found``, ``session_exists``, ``invalid_bundle`` or,
+    from archive extraction, ``invalid_archive``, ``unsafe_archive`` or
+    ``archive_limit_exceeded``.
+    """
+
+    def __init__(self, message: str, code: str = "invalid_bundle"):
+        super().__init__(message)
+        self.code = code
+
+
+def _install_args(args: str) -> list[str]:
+    try:
+        tokens = shlex.split(args, comments=True)
+    except ValueError:
+        tokens = args.split()
+    specs: list[str] = []
+    skip_next = False
+    for token in tokens:
+        if skip_next:
+            skip_next = False
+        elif token in _VALUE_OPTIONS:
+            skip_next = True
+        elif not token.startswith("-"):
+            specs.append(token)
+    return specs
+
+
+def installed_packages(cells: list[CellInfo]) -> list[dict[str, Any]]:
+    """Packages installed by the session's completed cells, in install order, without repeats.
+
+    Each is {"manager": "pip" | "npm" | "yarn" | "pnpm" | "cran", "spec", "cell_id"}.
+    Installs hidden in code (e.g. subprocess calls) aren't found.
+    """
+    packages: list[dict[str, Any]] = []
+    seen: set[tuple[str, str]] = set()
+
+    def add(manager: str, spec: str, cell_id: int) -> None:
+        if (manager, spec) not in seen:
+            seen.add((manager, spec))
+            packages.append({"manager": manager, "spec": spec, "cell_id": cell_id})
+
+    for cell in cells:
+        if cell.status != "completed":
+            continue
+        for match in _INSTALL_COMMAND.finditer(cell.code):
+            manager = "pip" if match["pip"] else match["npm"] or match["yarn"]
+            for spec in _install_args(match["args"]):
+                add(manager, spec, cell.cell_id)
+        for match in _R_INSTALL.finditer(cell.code):
+            for name in _QUOTED.findall(match["args"]):
+                add("cran", name, cell.cell_id)
+    return packages
+
+
+@dataclass
+class _Bundle:
+    manifest: dict[str, Any]
+    files: dict[str, bytes] = field(default_factory=dict)
+    state: bytes | None = None
+    cells: list[CellInfo] = field(default_factory=list)
+
+
+def _add_member(archive: tarfile.TarFile, name: str, content: bytes) -> None:
+    info = tarfile.TarInfo(name)
+    info.size = len(content)
+    info.mtime = int(datetime.now(UTC).timestamp())
+    archive.addfile(info, io.BytesIO(content))
+
+
+def _import_limits() -> ArchiveLimits:
+    max_files = settings.max_files_per_session + 3  # manifest.json, state.bin, cells.jsonl
+    return ArchiveLimits(
+        max_entries=max(settings.archive_max_entries, max_files),
+        max_files=max_files,
+        max_file_size=max(settings.max_file_size_mb, settings.state_max_size_mb) * 1024 * 1024 + METADATA_ALLOWANCE,
+        max_total_size=(settings.max_total_file_size_mb + settings.state_max_size_mb) * 1024 * 1024
+        + METADATA_ALLOWANCE,
+        max_ratio=settings.archive_max_compression_ratio,
+    )
+
+
+def max_bundle_size() -> int:
+    """Largest bundle import accepts: what extraction allows, plus tar framing and incompressible gzip."""
+    limits = _import_limits()
+    return limits.max_total_size + limits.max_total_size // 100 + limits.max_entries * _TAR_MEMBER_OVERHEAD
+
+
+def _read_bundle(content: bytes) -> _Bundle:
+    members = dict(extract_archive(content, _import_limits()).files)
+    try:
+        manifest = json.loads(members.pop("manifest.json"))
+    except (KeyError, ValueError):
+        raise SessionTransferError("Bundle has no readable manifest.json")
+    if not isinstance(manifest, dict) or manifest.get("format_version") != FORMAT_VERSION:
+        raise SessionTransferError(f"Unsupported bundle format; expected format_version {FORMAT_VERSION}")
+
+    bundle = _Bundle(manifest=manifest, state=members.pop("state.bin", None))
+    try:
+        for line in members.pop("cells.jsonl", b"").decode("utf-8").splitlines():
+            if line.strip():
+                bundle.cells.append(CellInfo(**json.loads(line)))
+    except (TypeError, ValueError) as e:
+        raise SessionTransferError(f"Bundle has unreadable cells.jsonl: {e}")
+    for path, data in members.items():
+        if path.startswith("files/"):
+            bundle.files[path[len("files/") :]] = data
+    return bundle
+
+
+class SessionTransferService:
+    """Exports sessions as self-contained bundles and imports them into this cluster."""
+
+    def __init__(
+        self,
+        session_service: Any,
+        file_service: Any,
+        state_service: Any,
+        state_archival_service: Any,
+        cell_history_service: Any,
+    ):
+        """Initialize the session transfer service.
+
+        Args:
+            session_service: Session service, for metadata and environment variables
+            file_service: File service, for workspace files
+            state_service: State service, for the kernel checkpoint in Redis
+            state_archival_service: State archival service, for checkpoints archived to MinIO
+            cell_history_service: Cell history service
+        """
+        self.session_service = session_service
+        self.file_service = file_service
+        self.state_service = state_service
+        self.state_archival_service = state_archival_service
+        self.cell_history_service = cell_history_service
+
+    async def _state(self, session_id: str) -> bytes | None:
+        raw = await self.state_service.get_state_raw(session_id)
+        if raw is None and await self.state_archival_service.restore_state(session_id):
+            raw = await self.state_service.get_state_raw(session_id)
+        return raw
+
+    async def export(self, session_id: str, include_env: bool = False) -> bytes:
+        """A tar.gz bundle of the session.
+
+        Raises:
+            SessionTransferError: If the session doesn't exist
+        """
+        session = await self.session_service.get_session(session_id)
+        if not session:
+            raise SessionTransferError("Session not found", code="not_found")
+
+        files = []
+        archive_buffer = io.BytesIO()
+        with tarfile.open(fileobj=archive_buffer, mode="w:gz") as archive:
+            for info in await self.file_service.list_files(session_id):
+                content = await self.file_service.get_file_content(session_id, info.file_id)
+                if content is None:
+                    logger.warning(
+                        "Skipping unreadable file in export", session_id=session_id[:12], file_id=info.file_id
+                    )
+                    continue
+                _add_member(archive, f"files/{info.file_id}", content)
+                files.append(info.model_dump(mode="json"))
+
+            state = await self._state(session_id)
+            if state is not None:
+                _add_member(archive, "state.bin", state)
+
+            cells = await self.cell_history_service.list_cells(session_id)
+            _add_member(archive, "cells.jsonl", "".join(c.model_dump_json() + "\n" for c in cells).encode("utf-8"))
+
+            env = await self.session_service.get_session_env(session_id)
+            manifest = {
+                "format_version": FORMAT_VERSION,
+                "exported_at": datetime.now(UTC).isoformat(),
+                "session": session.model_dump(mode="json", include={"session_id", "created_at", "metadata"}),
+                "files": files,
+                "state": {"size": len(state)} if state is not None else None,
+                "env": env if include_env else dict.fromkeys(env),
+                "packages": installed_packages(cells),
+            }
+            _add_member(archive, "manifest.json", json.dumps(manifest, indent=2).encode("utf-8"))
+
+        logger.info(
+            "Exported session",
+            session_id=session_id[:12],
+            files=len(files),
+            state_bytes=len(state) if state is not None else 0,
+            cells=len(cells),
+        )
+        return archive_buffer.getvalue()
+
+    async def import_bundle(self, content: bytes) -> SessionImportResult:
+        """Recreate an exported session here, under its original session and file ids.
+
+        Raises:
+            SessionTransferError: If the bundle is invalid or a session with its id already exists
+        """
+        try:
+            bundle = _read_bundle(content)
+        except ArchiveError as e:
+            raise SessionTransferError(str(e), code=e.code)
+
+        manifest = bundle.manifest
+        session = manifest.get("session") if isinstance(manifest.get("session"), dict) else {}
+        session_id = session.get("session_id")
+        if not isinstance(session_id, str) or not _ID.match(session_id):
+            raise SessionTransferError("Bundle manifest has no valid session id")
+        try:
+            files = [
+                (f["file_id"], f["filename"], f["path"], f.get("content_type"), datetime.fromisoformat(f["created_at"]))
+                for f in manifest.get("files") or []
+            ]
+        except (KeyError, TypeError, ValueError) as e:
+            raise SessionTransferError(f"Bundle manifest has an invalid file entry: {e}")
+        if sorted(f[0] for f in files) != sorted(bundle.files) or not all(_ID.match(f[0]) for f in files):
+            raise SessionTransferError("Bundle files don't match its manifest")
+        env = manifest.get("env") if isinstance(manifest.get("env"), dict) else {}
+        values = {name: value for name, value in env.items() if isinstance(value, str)}
+
+        if await self.session_service.get_session(session_id):
+            raise SessionTransferError("A session with this id already exists", code="session_exists")
+
+        metadata = session.get("metadata") or {}
+        await self.session_service.create_session(SessionCreate(metadata=metadata), session_id=session_id)
+        try:
+            for file_id, filename, path, content_type, created_at in files:
+                await self.file_service.restore_file(
+                    session_id,
+                    file_id,
+                    filename,
+                    path,
+                    bundle.files[file_id],
+                    content_type or "application/octet-stream",
+                    created_at,
+                )
+            if bundle.state is not None and not await self.state_service.save_state_raw(session_id, bundle.state):
+                raise RuntimeError("Failed to restore the kernel checkpoint")
+            cells = await self.cell_history_service.restore(session_id, bundle.cells)
+            if values:
+                await self.session_service.set_session_env(session_id, values)
+        except Exception:
+            # Don't leave a half-imported session behind; the import can be retried
+            await self.session_service.delete_session(session_id)
+            raise
+
+        result = SessionImportResult(
+            session_id=session_id,
+            exported_at=manifest.get("exported_at") or datetime.now(UTC),
+            files=len(files),
+            state_bytes=len(bundle.state or b""),
+            cells=cells,
+            env=sorted(values),
+            env_missing=sorted(name for name in env if name not in values),
+            packages=manifest.get("packages") or [],
+        )
+        logger.info("Imported session", session_id=session_id[:12], files=result.files, cells=cells)
+        return result
@@ -37,6 +37,7 @@ CREATE TABLE IF NOT EXISTS executions (
     files_generated INTEGER DEFAULT 0,
     output_size_bytes INTEGER DEFAULT 0,
     state_size_bytes INTEGER,
+    image TEXT,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -121,6 +122,7 @@ class SQLiteMetricsService:
 
         # Create schema
         await self._db.executescript(SCHEMA_SQL)
+        await self._migrate()
         await self._db.commit()
 
         self._running = True
@@ -132,6 +134,13 @@ class SQLiteMetricsService:
 
         logger.info("SQLite metrics service started", db_path=self.db_path)
 
+    async def _migrate(self) -> None:
+        """Add columns introduced after a database was created."""
+        cursor = await self._db.execute("PRAGMA table_info(executions)")
+        columns = {row["name"] async for row in cursor}
+        if "image" not in columns:
+            await self._db.execute("ALTER TABLE executions ADD COLUMN image TEXT")
+
     async def stop(self) -> None:
         """Flush pending writes and close connection."""
         if not self._running:
@@ -203,8 +212,8 @@ class SQLiteMetricsService:
                     execution_id, session_id, api_key_hash, user_id, entity_id,
                     language, status, execution_time_ms, memory_peak_mb, cpu_time_ms,
                     container_source, files_uploaded, files_generated,
-                    output_size_bytes, state_size_bytes, created_at
-                ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+                    output_size_bytes, state_size_bytes, image, created_at
+                ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
                 """,
                 [
                     (
@@ -223,6 +232,7 @@ class SQLiteMetricsService:
                         m.files_generated,
                         m.output_size_bytes,
                         m.state_size_bytes,
+                        m.image,
                         (m.timestamp.isoformat() if m.timestamp else datetime.now(UTC).isoformat()),
                     )
                     for m in batch
@@ -650,6 +660,62 @@ class SQLiteMetricsService:
 
         return [{"language": row["language"], "count": row["count"]} async for row in cursor]
 
+    async def get_runtime_stats(self, start: datetime, end: datetime) -> list[dict[str, Any]]:
+        """Outcome rates and p50/p95 durations per language and image, busiest first."""
+        if not self._db:
+            return []
+
+        # Nearest-rank percentiles: the smallest duration with at least p of its group at or below it
+        cursor = await self._db.execute(
+            """
+            WITH ranked AS (
+                SELECT
+                    language,
+                    COALESCE(image, '') AS image,
+                    status,
+                    execution_time_ms,
+                    ROW_NUMBER() OVER w AS rank,
+                    COUNT(*) OVER (PARTITION BY language, COALESCE(image, '')) AS total
+                FROM executions
+                WHERE created_at >= ? AND created_at <= ?
+                WINDOW w AS (PARTITION BY language, COALESCE(image, '') ORDER BY execution_time_ms)
+            )
+            SELECT
+                language,
+                image,
+                COUNT(*) AS execution_count,
+                SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END) AS success_count,
+                SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END) AS failure_count,
+                SUM(CASE WHEN status = 'timeout' THEN 1 ELSE 0 END) AS timeout_count,
+                MIN(CASE WHEN rank >= 0.5 * total THEN execution_time_ms END) AS p50_ms,
+                MIN(CASE WHEN rank >= 0.95 * total THEN execution_time_ms END) AS p95_ms
+            FROM ranked
+            GROUP BY language, image
+            ORDER BY execution_count DESC, language, image
+            """,
+            (start.isoformat(), end.isoformat()),
+        )
+
+        stats = []
+        async for row in cursor:
+            total = row["execution_count"]
+            stats.append(
+                {
+                    "language": row["language"],
+                    "image": row["image"] or None,
+                    "execution_count": total,
+                    "success_count": row["success_count"] or 0,
+                    "failure_count": row["failure_count"] or 0,
+                    "timeout_count": row["timeout_count"] or 0,
+                    "success_rate": round((row["success_count"] or 0) / total * 100, 1),
+                    "error_rate": round((row["failure_count"] or 0) / total * 100, 1),
+                    "timeout_rate": round((row["timeout_count"] or 0) / total * 100, 1),
+                    "p50_ms": round(row["p50_ms"] or 0, 1),
+                    "p95_ms": round(row["p95_ms"] or 0, 1),
+                }
+            )
+        return stats
+
 
 # Global service instance
 sqlite_metrics_service = SQLiteMetricsService()
@@ -0,0 +1,224 @@
+"""Operator-defined execution templates.
+
+EXECUTION_TEMPLATES_PATH points at a JSON file of named, parameterized code
+snippets, e.g.::
+
+    {
+      "convert_csv": {
+        "description": "Convert a CSV file to another format",
+        "lang": "py",
+        "code": "import pandas as pd\\npd.read_csv({{file}}).to_{{format}}(...)",
+        "params": {
+          "file": {"type": "path"},
+          "format": {"choices": ["json", "parquet"]}
+        }
+      }
+    }
+
+Clients run a template by name with arguments instead of sending code.
+Every argument is validated against its parameter (type, choices,
+pattern, range, length) and then reaches the code in two ways:
+
+- ``{{name}}`` placeholders are replaced with a literal in the template's
+  language (a quoted, escaped string; a number; a boolean), never with
+  raw text, so an argument can't break out into code
+- ``PARAM_<NAME>`` environment variables carry the same values
+
+Control characters are rejected in string arguments.
+"""
+
+import json
+import math
+import re
+from functools import lru_cache
+from typing import Any
+
+import structlog
+from pydantic import ValidationError as PydanticValidationError
+
+from ..config import settings
+from ..config.languages import is_supported_language
+from ..models.template import ExecutionTemplate, TemplateParam
+from .workspace_lock import normalize_scope_path
+
+logger = structlog.get_logger(__name__)
+
+NAME_PATTERN = re.compile(r"^[A-Za-z_][A-Za-z0-9_]*$")
+PLACEHOLDER = re.compile(r"\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}")
+CONTROL_CHARS = re.compile(r"[\x00-\x1f\x7f]")
+ENV_PREFIX = "PARAM_"
+
+
+class TemplateError(ValueError):
+    """A template definition or invocation is invalid."""
+
+
+def _json_string(value: str) -> str:
+    # Without control characters only \\ and \" need escaping, which every
+    # C-family language (and R) reads the same way
+    return json.dumps(value, ensure_ascii=False)
+
+
+def _php_string(value: str) -> str:
+    # Single quotes: no $variable interpolation
+    return "'" + value.replace("\\", "\\\\").replace("'", "\\'") + "'"
+
+
+def _fortran_string(value: str) -> str:
+    return "'" + value.replace("'", "''") + "'"
+
+
+STRING_LITERALS = {"py": repr, "php": _php_string, "f90": _fortran_string}
+BOOLEAN_LITERALS = {
+    "py": ("True", "False"),
+    "r": ("TRUE", "FALSE"),
+    "c": ("1", "0"),
+    "f90": (".true.", ".false."),
+}
+
+
+def literal(value: Any, lang: str) -> str:
+    """A validated argument as a literal in the given language."""
+    if isinstance(value, bool):
+        true, false = BOOLEAN_LITERALS.get(lang, ("true", "false"))
+        return true if value else false
+    if isinstance(value, int | float):
+        return repr(value)
+    return STRING_LITERALS.get(lang, _json_string)(value)
+
+
+def _env_value(value: Any) -> str:
+    if isinstance(value, bool):
+        return "true" if value else "false"
+    return str(value)
+
+
+def validate_template(name: str, template: ExecutionTemplate) -> None:
+    """Check a template definition.
+
+    Raises:
+        TemplateError: If the name, language, parameters or placeholders are invalid
+    """
+    if not NAME_PATTERN.match(name):
+        raise TemplateError(f"Invalid template name: {name}")
+    if not is_supported_language(template.lang):
+        raise TemplateError(f"Template {name}: unsupported language {template.lang}")
+    for param_name, param in template.params.items():
+        if not NAME_PATTERN.match(param_name):
+            raise TemplateError(f"Template {name}: invalid parameter name {param_name}")
+        if param.pattern:
+            try:
+                re.compile(param.pattern)
+            except re.error as e:
+                raise TemplateError(f"Template {name}: invalid pattern for {param_name}: {e}")
+        if param.default is not None:
+            coerce_argument(param_name, param, param.default)
+    unknown = sorted(set(PLACEHOLDER.findall(template.code)) - set(template.params))
+    if unknown:
+        raise TemplateError(f"Template {name}: placeholders without a parameter: {', '.join(unknown)}")
+
+
+def load_templates(data: Any) -> dict[str, ExecutionTemplate]:
+    """Parse template definitions, skipping (and logging) invalid ones."""
+    templates = {}
+    for name, definition in (data or {}).items():
+        try:
+            template = ExecutionTemplate.model_validate({**definition, "name": name})
+            validate_template(name, template)
+        except (PydanticValidationError, TemplateError, TypeError) as e:
+            logger.error("Invalid execution template", template=name, error=str(e))
+            continue
+        templates[name] = template
+    return templates
+
+
+@lru_cache(maxsize=1)
+def get_templates() -> dict[str, ExecutionTemplate]:
+    """Templates from EXECUTION_TEMPLATES_PATH (loaded once)."""
+    if not settings.execution_templates_path:
+        return {}
+    try:
+        with open(settings.execution_templates_path, encoding="utf-8") as f:
+            data = json.load(f)
+    except (OSError, ValueError) as e:
+        logger.error("Failed to load execution templates", path=settings.execution_templates_path, error=str(e))
+        return {}
+    if not isinstance(data, dict):
+        logger.error("Execution templates file must contain an object", path=settings.execution_templates_path)
+        return {}
+    return load_templates(data)
+
+
+def coerce_argument(name: str, param: TemplateParam, value: Any) -> Any:
+    """Validate an argument against its parameter and convert it to the parameter's type.
+
+    Raises:
+        TemplateError: If the value isn't acceptable
+    """
+    if param.type == "boolean":
+        if not isinstance(value, bool):
+            raise TemplateError(f"{name} must be a boolean")
+    elif param.type == "integer":
+        if isinstance(value, bool) or not isinstance(value, int):
+            raise TemplateError(f"{name} must be an integer")
+    elif param.type == "number":
+        if isinstance(value, bool) or not isinstance(value, int | float) or not math.isfinite(value):
+            raise TemplateError(f"{name} must be a finite number")
+    else:
+        if not isinstance(value, str):
+            raise TemplateError(f"{name} must be a string")
+        if CONTROL_CHARS.search(value):
+            raise TemplateError(f"{name} must not contain control characters")
+        if param.max_length is not None and len(value) > param.max_length:
+            raise TemplateError(f"{name} must be at most {param.max_length} characters")
+        if param.type == "path":
+            try:
+                value = normalize_scope_path(value)
+            except ValueError:
+                raise TemplateError(f"{name} must be a path inside the workspace")
+            if not value:
+                raise TemplateError(f"{name} must name a file or directory in the workspace")
+        if param.pattern and not re.fullmatch(param.pattern, value):
+            raise TemplateError(f"{name} does not match the required pattern")
+
+    if param.choices is not None and value not in param.choices:
+        raise TemplateError(f"{name} must be one of: {', '.join(str(c) for c in param.choices)}")
+    if isinstance(value, int | float) and not isinstance(value, bool):
+        if param.minimum is not None and value < param.minimum:
+            raise TemplateError(f"{name} must be at least {param.minimum}")
+        if param.maximum is not None and value > param.maximum:
+            raise TemplateError(f"{name} must be at most {param.maximum}")
+    return value
+
+
+def render_template(template: ExecutionTemplate, arguments: dict[str, Any]) -> tuple[str, dict[str, str]]:
+    """Validate arguments and expand the template.
+
+    Returns:
+        Tuple of (code, PARAM_* environment variables)
+
+    Raises:
+        TemplateError: If an argument is unknown, missing or invalid
+    """
+    unknown = sorted(set(arguments) - set(template.params))
+    if unknown:
+        raise TemplateError(f"Unknown parameters: {', '.join(unknown)}")
+
+    values = {}
+    for name, param in template.params.items():
+        if arguments.get(name) is not None:
+            values[name] = coerce_argument(name, param, arguments[name])
+        elif param.default is not None:
+            values[name] = coerce_argument(name, param, param.default)
+        elif param.required:
+            raise TemplateError(f"Missing required parameter: {name}")
+
+    def replace(match: re.Match) -> str:
+        name = match.group(1)
+        if name not in values:
+            raise TemplateError(f"Parameter {name} is used by the template but has no value")
+        return literal(values[name], template.lang.lower())
+
+    code = PLACEHOLDER.sub(replace, template.code)
+    env = {f"{ENV_PREFIX}{name.upper()}": _env_value(value) for name, value in values.items()}
+    return code, env
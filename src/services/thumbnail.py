@@ -0,0 +1,270 @@
+"""PNG thumbnails for image artifacts.
+
+Plots and charts written by executions are PNGs, so thumbnails are made
+natively: the PNG is decoded, sampled down to the requested width and
+re-encoded, without an imaging library. Other formats are rejected rather
+than served as-is (an SVG served inline could carry scripts).
+"""
+
+import asyncio
+import struct
+import zlib
+from dataclasses import dataclass, field
+
+PNG_SIGNATURE = b"\x89PNG\r\n\x1a\n"
+
+DEFAULT_THUMBNAIL_WIDTH = 256
+MIN_THUMBNAIL_WIDTH = 16
+MAX_THUMBNAIL_WIDTH = 1024
+# Larger images aren't decoded; decoding is pure Python, so its time and memory grow with the pixels
+MAX_SOURCE_PIXELS = 4_000_000
+# Thumbnails decoded at once per replica; further requests wait for a slot
+MAX_CONCURRENT_THUMBNAILS = 2
+# Samples per axis averaged into each thumbnail pixel
+SUPERSAMPLING = 4
+
+_CHANNELS = {0: 1, 2: 3, 3: 1, 4: 2, 6: 4}
+
+_slots = asyncio.Semaphore(MAX_CONCURRENT_THUMBNAILS)
+
+
+class ThumbnailError(ValueError):
+    """No thumbnail can be made.
+
+    ``code`` is ``unsupported_thumbnail_format`` or ``invalid_image``.
+    """
+
+    def __init__(self, message: str, code: str = "invalid_image"):
+        super().__init__(message)
+        self.code = code
+
+
+@dataclass
+class _Png:
+    width: int
+    height: int
+    bit_depth: int
+    color_type: int
+    palette: list[tuple[int, int, int, int]] = field(default_factory=list)
+    transparent: tuple[int, ...] | None = None
+    rows: list[bytes] = field(default_factory=list)
+
+    @property
+    def has_alpha(self) -> bool:
+        return self.color_type in (4, 6) or self.transparent is not None or any(a < 255 for *_, a in self.palette)
+
+
+def is_png(content: bytes) -> bool:
+    return content.startswith(PNG_SIGNATURE)
+
+
+def _add_bytes(a: bytes, b: bytes, low: int, high: int) -> bytes:
+    """Bytewise (a + b) mod 256, done on whole rows at once."""
+    x, y = int.from_bytes(a, "little"), int.from_bytes(b, "little")
+    return (((x & low) + (y & low)) ^ ((x ^ y) & high)).to_bytes(len(a), "little")
+
+
+def _unfilter(raw: bytes, height: int, stride: int, bpp: int) -> list[bytes]:
+    rows = []
+    prev = bytes(stride)
+    low = int.from_bytes(b"\x7f" * stride, "little")
+    high = int.from_bytes(b"\x80" * stride, "little")
+    for y in range(height):
+        start = y * (stride + 1)
+        filter_type = raw[start]
+        line = bytearray(raw[start + 1 : start + 1 + stride])
+        if filter_type == 1:
+            for i in range(bpp, stride):
+                line[i] = (line[i] + line[i - bpp]) & 0xFF
+        elif filter_type == 2:
+            line = bytearray(_add_bytes(line, prev, low, high))
+        elif filter_type == 3:
+            for i in range(min(bpp, stride)):
+                line[i] = (line[i] + (prev[i] >> 1)) & 0xFF
+            for i in range(bpp, stride):
+                line[i] = (line[i] + ((line[i - bpp] + prev[i]) >> 1)) & 0xFF
+        elif filter_type == 4:
+            # With no left neighbour the Paeth predictor is the byte above
+            for i in range(min(bpp, stride)):
+                line[i] = (line[i] + prev[i]) & 0xFF
+            for i in range(bpp, stride):
+                a, b, c = line[i - bpp], prev[i], prev[i - bpp]
+                pa, pb = b - c, a - c
+                pc = pa + pb
+                pa, pb, pc = (pa if pa >= 0 else -pa), (pb if pb >= 0 else -pb), (pc if pc >= 0 else -pc)
+                line[i] = (line[i] + (a if pa <= pb and pa <= pc else b if pb <= pc else c)) & 0xFF
+        elif filter_type != 0:
+            raise ThumbnailError(f"Invalid PNG filter type {filter_type}")
+        rows.append(bytes(line))
+        prev = rows[-1]
+    return rows
+
+
+def _decode_png(content: bytes) -> _Png:
+    if not is_png(content):
+        raise ThumbnailError("Thumbnails are only generated for PNG images", code="unsupported_thumbnail_format")
+
+    pos = len(PNG_SIGNATURE)
+    header = None
+    palette: list[tuple[int, int, int, int]] = []
+    transparency = b""
+    idat = []
+    while pos + 8 <= len(content):
+        length, kind = struct.unpack(">I4s", content[pos : pos + 8])
+        data = content[pos + 8 : pos + 8 + length]
+        pos += 12 + length
+        if kind == b"IHDR":
+            header = struct.unpack(">IIBBBBB", data[:13])
+        elif kind == b"PLTE":
+            palette = [(data[i], data[i + 1], data[i + 2], 255) for i in range(0, len(data) - 2, 3)]
+        elif kind == b"tRNS":
+            transparency = data
+        elif kind == b"IDAT":
+            idat.append(data)
+        elif kind == b"IEND":
+            break
+    if header is None or len(header) < 7:
+        raise ThumbnailError("Invalid PNG: missing header")
+
+    width, height, bit_depth, color_type, _, _, interlace = header
+    if color_type not in _CHANNELS or bit_depth not in (1, 2, 4, 8, 16):
+        raise ThumbnailError(f"Invalid PNG color type {color_type} / bit depth {bit_depth}")
+    if interlace:
+        raise ThumbnailError("Interlaced PNGs aren't supported", code="unsupported_thumbnail_format")
+    if not width or not height:
+        raise ThumbnailError("Invalid PNG: empty image")
+    if width * height > MAX_SOURCE_PIXELS:
+        raise ThumbnailError(f"Image is larger than {MAX_SOURCE_PIXELS} pixels")
+
+    channels = _CHANNELS[color_type]
+    stride = (width * channels * bit_depth + 7) // 8
+    expected = height * (stride + 1)
+    try:
+        raw = zlib.decompressobj().decompress(b"".join(idat), expected)
+    except zlib.error as e:
+        raise ThumbnailError(f"Invalid PNG data: {e}")
+    if len(raw) < expected:
+        raise ThumbnailError("Invalid PNG: truncated image data")
+
+    png = _Png(width=width, height=height, bit_depth=bit_depth, color_type=color_type)
+    if color_type == 3:
+        png.palette = [
+            (r, g, b, transparency[i] if i < len(transparency) else 255) for i, (r, g, b, _) in enumerate(palette)
+        ]
+    elif transparency and color_type in (0, 2):
+        png.transparent = struct.unpack(f">{channels}H", transparency[: 2 * channels])
+    png.rows = _unfilter(raw, height, stride, max(1, channels * bit_depth // 8))
+    return png
+
+
+def _pixel(png: _Png, row: bytes, x: int) -> tuple[int, int, int, int]:
+    """A pixel as 8-bit RGBA."""
+    depth = png.bit_depth
+    if depth < 8:
+        bit = x * depth
+        value = (row[bit // 8] >> (8 - depth - bit % 8)) & ((1 << depth) - 1)
+        if png.color_type == 3:
+            return png.palette[value] if value < len(png.palette) else (0, 0, 0, 255)
+        gray = value * 255 // ((1 << depth) - 1)
+        alpha = 0 if png.transparent and png.transparent[0] == value else 255
+        return gray, gray, gray, alpha
+
+    step = depth // 8
+    channels = _CHANNELS[png.color_type]
+    offset = x * channels * step
+    samples = [int.from_bytes(row[offset + c * step : offset + (c + 1) * step], "big") for c in range(channels)]
+    top = [s >> 8 if step == 2 else s for s in samples]
+    if png.color_type == 3:
+        return png.palette[samples[0]] if samples[0] < len(png.palette) else (0, 0, 0, 255)
+    if png.color_type in (0, 2):
+        alpha = 0 if png.transparent and tuple(samples) == png.transparent else 255
+        return (top[0], top[0], top[0], alpha) if png.color_type == 0 else (top[0], top[1], top[2], alpha)
+    if png.color_type == 4:
+        return top[0], top[0], top[0], top[1]
+    return top[0], top[1], top[2], top[3]
+
+
+def _channels(png: _Png, row: bytes) -> tuple:
+    """A row as separate 8-bit red, green, blue and alpha sequences."""
+    step = png.bit_depth // 8
+    if step and png.color_type in (2, 6) and png.transparent is None:
+        channels = _CHANNELS[png.color_type] * step
+        opaque = bytes([255]) * png.width
+        return (
+            row[0::channels],
+            row[step::channels],
+            row[2 * step :: channels],
+            row[3 * step :: channels] if png.color_type == 6 else opaque,
+        )
+    pixels = [_pixel(png, row, x) for x in range(png.width)]
+    return tuple(zip(*pixels))
+
+
+def _sample_positions(out_size: int, in_size: int) -> list[list[int]]:
+    """Source coordinates averaged into each output coordinate."""
+    scale = in_size / out_size
+    samples = max(1, min(SUPERSAMPLING, int(scale)))
+    return [
+        [min(in_size - 1, int((index + (j + 0.5) / samples) * scale)) for j in range(samples)]
+        for index in range(out_size)
+    ]
+
+
+def _chunk(kind: bytes, data: bytes) -> bytes:
+    return struct.pack(">I", len(data)) + kind + data + struct.pack(">I", zlib.crc32(kind + data))
+
+
+def encode_png(width: int, height: int, rows: list[bytes], alpha: bool) -> bytes:
+    """8-bit RGB or RGBA PNG from unfiltered rows."""
+    header = struct.pack(">IIBBBBB", width, height, 8, 6 if alpha else 2, 0, 0, 0)
+    raw = b"".join(b"\x00" + row for row in rows)
+    return PNG_SIGNATURE + _chunk(b"IHDR", header) + _chunk(b"IDAT", zlib.compress(raw, 9)) + _chunk(b"IEND", b"")
+
+
+def make_thumbnail(content: bytes, width: int = DEFAULT_THUMBNAIL_WIDTH) -> bytes:
+    """A PNG at most ``width`` pixels wide, keeping the aspect ratio.
+
+    Images that are already narrow enough are returned unchanged.
+
+    Raises:
+        ThumbnailError: If the image isn't a PNG or can't be decoded
+    """
+    png = _decode_png(content)
+    if png.width <= width:
+        return content
+
+    out_width = width
+    out_height = max(1, round(png.height * width / png.width))
+    columns = _sample_positions(out_width, png.width)
+    lines = _sample_positions(out_height, png.height)
+    alpha = png.has_alpha
+    rows = []
+    for ys in lines:
+        r_sum, g_sum, b_sum, a_sum = ([0] * out_width for _ in range(4))
+        for y in ys:
+            reds, greens, blues, alphas = _channels(png, png.rows[y])
+            for index, xs in enumerate(columns):
+                for x in xs:
+                    pa = alphas[x]
+                    r_sum[index] += reds[x] * pa
+                    g_sum[index] += greens[x] * pa
+                    b_sum[index] += blues[x] * pa
+                    a_sum[index] += pa
+        count = len(ys) * len(columns[0])
+        out = bytearray()
+        for r, g, b, a in zip(r_sum, g_sum, b_sum, a_sum):
+            out += bytes((r // a, g // a, b // a)) if a else b"\x00\x00\x00"
+            if alpha:
+                out.append(a // count)
+        rows.append(bytes(out))
+    return encode_png(out_width, out_height, rows, alpha)
+
+
+async def generate_thumbnail(content: bytes, width: int = DEFAULT_THUMBNAIL_WIDTH) -> bytes:
+    """make_thumbnail off the event loop, at most MAX_CONCURRENT_THUMBNAILS at a time.
+
+    Raises:
+        ThumbnailError: As make_thumbnail
+    """
+    async with _slots:
+        return await asyncio.to_thread(make_thumbnail, content, width)
@@ -0,0 +1,113 @@
+"""Timeout suggestions from execution history.
+
+Durations of finished executions are kept twice in Redis: per code
+signature (language plus a hash of the code with trailing whitespace and
+blank lines ignored, shared across sessions) and per session and
+language. The suggested timeout is a percentile of the durations times a
+headroom multiplier (p95 x 1.5 by default), capped at MAX_EXECUTION_TIME.
+The code's own history is preferred; the session's other runs in the
+language are the fallback for code that hasn't run often enough yet.
+
+Executions that timed out are recorded with the timeout as their
+duration, a lower bound, so code that keeps timing out is suggested the
+maximum.
+"""
+
+import hashlib
+import math
+
+import redis.asyncio as redis
+import structlog
+
+from ..config import settings
+from ..core.pool import redis_pool
+from ..models.exec import TimeoutSuggestion
+
+logger = structlog.get_logger(__name__)
+
+
+def code_signature(lang: str, code: str) -> str:
+    """Hash of the language and code, ignoring trailing whitespace and blank lines."""
+    normalized = "\n".join(line.rstrip() for line in code.splitlines() if line.strip())
+    return hashlib.sha256(f"{lang.lower()}\0{normalized}".encode()).hexdigest()[:32]
+
+
+def percentile(values: list[int], pct: float) -> int:
+    """Nearest-rank percentile of a non-empty list."""
+    ordered = sorted(values)
+    return ordered[max(1, math.ceil(pct / 100 * len(ordered))) - 1]
+
+
+def suggest(durations_ms: list[int], basis: str) -> TimeoutSuggestion:
+    """Suggestion from a list of durations, using the TIMEOUT_SUGGESTION_* settings."""
+    value = percentile(durations_ms, settings.timeout_suggestion_percentile)
+    seconds = math.ceil(value * settings.timeout_suggestion_multiplier / 1000)
+    return TimeoutSuggestion(
+        seconds=min(settings.max_execution_time, max(1, seconds)),
+        basis=basis,
+        samples=len(durations_ms),
+        percentile_ms=value,
+    )
+
+
+class TimeoutAdvisor:
+    """Records execution durations and suggests timeouts from them."""
+
+    KEY_PREFIX = "timeouts:"
+
+    def __init__(self, redis_client: redis.Redis | None = None):
+        """Initialize the timeout advisor.
+
+        Args:
+            redis_client: Optional Redis client, uses shared pool if not provided
+        """
+        self.redis = redis_client or redis_pool.get_client()
+
+    def _code_key(self, lang: str, code: str) -> str:
+        """Generate Redis key for the durations of a code signature."""
+        return f"{self.KEY_PREFIX}code:{code_signature(lang, code)}"
+
+    def _session_key(self, session_id: str, lang: str) -> str:
+        """Generate Redis key for a session's durations in a language."""
+        return f"{self.KEY_PREFIX}session:{session_id}:{lang.lower()}"
+
+    async def record(self, session_id: str, lang: str, code: str, duration_ms: int) -> None:
+        """Add an execution's duration to its code's and session's history."""
+        size = settings.timeout_history_size
+        if size <= 0:
+            return
+
+        keys = {
+            self._code_key(lang, code): settings.timeout_history_ttl_hours * 3600,
+            self._session_key(session_id, lang): settings.get_session_ttl_minutes() * 60,
+        }
+        pipe = await self.redis.pipeline(transaction=True)
+        try:
+            for key, ttl in keys.items():
+                pipe.rpush(key, duration_ms)
+                pipe.ltrim(key, -size, -1)
+                pipe.expire(key, ttl)
+            await pipe.execute()
+        finally:
+            await pipe.reset()
+
+    async def _durations(self, key: str) -> list[int]:
+        durations = []
+        for raw in await self.redis.lrange(key, 0, -1):
+            try:
+                durations.append(int(raw))
+            except (TypeError, ValueError):
+                continue
+        return durations
+
+    async def suggest(self, lang: str, code: str, session_id: str | None = None) -> TimeoutSuggestion:
+        """Suggested timeout for code, from its own history, else the session's, else MAX_EXECUTION_TIME."""
+        min_samples = settings.timeout_suggestion_min_samples
+        durations = await self._durations(self._code_key(lang, code))
+        if len(durations) >= min_samples:
+            return suggest(durations, "code")
+        if session_id:
+            durations = await self._durations(self._session_key(session_id, lang))
+            if len(durations) >= min_samples:
+                return suggest(durations, "session")
+        return TimeoutSuggestion(seconds=settings.max_execution_time, basis="default")
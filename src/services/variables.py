@@ -0,0 +1,429 @@
+"""Variable inspection for stateful Python sessions.
+
+Persisted state is pickled user data, so it is never unpickled in the API
+process. Instead a small helper runs in a sandbox with the session's state
+restored and prints its result as JSON: a description of the namespace
+(names, types, shapes and sizes, short previews), rows exported from a
+dataframe, or completions and signature help for code being typed. Nothing
+is written back, so inspecting a session doesn't change its state.
+"""
+
+import base64
+import inspect
+import json
+from collections.abc import Callable
+from typing import Any
+
+import structlog
+
+from ..config import settings
+from ..models import ExecuteCodeRequest, ExecutionError, ResourceNotFoundError, ValidationError
+from ..models.session import CompletionResponse, SessionVariablesResponse, VariableInfo
+from .interfaces import ExecutionServiceInterface
+from .output_filters import filter_output
+from .state import StateService
+from .state_archival import StateArchivalService
+
+logger = structlog.get_logger(__name__)
+
+# Prefix of the stdout line carrying the summary
+VARIABLES_MARKER = "__CODE_INTERPRETER_VARIABLES__"
+
+# Inspection only reads the namespace; it shouldn't need the full execution timeout
+INSPECT_TIMEOUT_SECONDS = 30
+
+MAX_COMPLETIONS = 100
+COMPLETION_DOC_CHARS = 2000
+
+DATAFRAME_FORMATS = ("json", "csv", "parquet")
+DATAFRAME_SAMPLES = ("head", "tail", "random")
+
+
+def summarize_namespace(namespace: dict, max_variables: int, preview_chars: int) -> dict:
+    """Describe the user variables in a namespace.
+
+    Runs inside the sandbox, so it is self-contained and only uses the
+    standard library; numpy arrays and pandas objects are recognised by
+    their attributes. Private names and modules are left out.
+    """
+    import reprlib
+    import sys
+    import types
+
+    short_repr = reprlib.Repr()
+    short_repr.maxstring = short_repr.maxother = max(preview_chars, 10)
+
+    names = sorted(
+        name
+        for name, value in namespace.items()
+        if not name.startswith("_") and not isinstance(value, types.ModuleType)
+    )
+    variables = []
+    for name in names[:max_variables]:
+        value = namespace[name]
+        kind = type(value)
+        module = kind.__module__
+        info = {"name": name, "type": kind.__qualname__ if module == "builtins" else f"{module}.{kind.__qualname__}"}
+        try:
+            shape = getattr(value, "shape", None)
+            if isinstance(shape, tuple) and all(isinstance(n, int) for n in shape):
+                info["shape"] = list(shape)
+            elif hasattr(value, "__len__") and not isinstance(value, type):
+                info["length"] = len(value)
+
+            dtype = getattr(value, "dtype", None)
+            if dtype is not None and not callable(dtype):
+                info["dtype"] = str(dtype)
+            columns = getattr(value, "columns", None)
+            if columns is not None and hasattr(value, "dtypes"):
+                info["columns"] = [str(column) for column in list(columns)[:50]]
+
+            nbytes = getattr(value, "nbytes", None)
+            if isinstance(nbytes, int):
+                info["size_bytes"] = nbytes
+            elif info.get("columns") is not None and callable(getattr(value, "memory_usage", None)):
+                info["size_bytes"] = int(value.memory_usage(deep=False).sum())
+            else:
+                info["size_bytes"] = sys.getsizeof(value)
+        except Exception:
+            # Odd objects (lazy proxies, broken properties) still get listed
+            pass
+
+        try:
+            preview = short_repr.repr(value)
+        except Exception as e:
+            preview = f"<repr failed: {type(e).__name__}>"
+        info["preview"] = preview[:preview_chars]
+        variables.append(info)
+
+    return {"variables": variables, "total": len(names)}
+
+
+def export_dataframe(namespace: dict, name: str, rows: int, fmt: str, sample: str, max_bytes: int) -> dict:
+    """Export up to `rows` rows of a tabular variable.
+
+    Runs inside the sandbox like summarize_namespace. Series, numpy arrays,
+    polars/arrow tables and lists of records are converted to a pandas
+    DataFrame first. CSV is returned as text and Parquet as base64; results
+    over max_bytes are refused rather than cut off by the stdout limit.
+    """
+    import base64
+    import io
+    import json
+
+    if name.startswith("_") or name not in namespace:
+        return {"error": "not_found"}
+
+    import pandas as pd
+
+    value = namespace[name]
+    if hasattr(value, "to_pandas") and not isinstance(value, (pd.DataFrame, pd.Series)):
+        value = value.to_pandas()
+    if isinstance(value, pd.Series):
+        value = value.to_frame()
+    elif not isinstance(value, pd.DataFrame):
+        try:
+            value = pd.DataFrame(value)
+        except Exception:
+            return {"error": "unsupported_type", "type": type(value).__name__}
+
+    if sample == "tail":
+        part = value.tail(rows)
+    elif sample == "random":
+        part = value.sample(n=min(rows, len(value)), random_state=0).sort_index()
+    else:
+        part = value.head(rows)
+    part = part.rename(columns=str)
+
+    result = {
+        "total_rows": len(value),
+        "rows": len(part),
+        "columns": list(part.columns),
+        "dtypes": {column: str(dtype) for column, dtype in part.dtypes.items()},
+    }
+    try:
+        if fmt == "csv":
+            result["data"] = part.to_csv(index=False)
+        elif fmt == "parquet":
+            buffer = io.BytesIO()
+            part.to_parquet(buffer, index=False)
+            result["data"] = base64.b64encode(buffer.getvalue()).decode()
+        else:
+            result["data"] = json.loads(part.to_json(orient="records", date_format="iso"))
+    except Exception as e:
+        # e.g. mixed-type object columns that Parquet can't store
+        return {"error": "export_failed", "message": f"{type(e).__name__}: {e}"}
+
+    size = len(json.dumps(result, default=str))
+    if size > max_bytes:
+        return {"error": "too_large", "size": size}
+    return result
+
+
+def complete_code(namespace: dict, code: str, cursor_pos: int, max_matches: int, doc_chars: int) -> dict:
+    """Completions at the cursor plus help for the call and name around it.
+
+    Runs inside the sandbox like summarize_namespace, using rlcompleter for
+    matches. Names are resolved by lookup and getattr only; user code is
+    never executed, although attribute completion may evaluate properties.
+    """
+    import builtins
+    import inspect
+    import keyword
+    import re
+    import rlcompleter
+
+    def resolve(dotted):
+        parts = dotted.split(".")
+        if parts[0] in namespace:
+            obj = namespace[parts[0]]
+        elif hasattr(builtins, parts[0]):
+            obj = getattr(builtins, parts[0])
+        else:
+            return None
+        for part in parts[1:]:
+            obj = getattr(obj, part, None)
+            if obj is None:
+                return None
+        return obj
+
+    def kind(name, obj):
+        if keyword.iskeyword(name):
+            return "keyword"
+        if obj is None:
+            return "instance" if name.split(".")[0] in namespace else "unknown"
+        if inspect.ismodule(obj):
+            return "module"
+        if inspect.isclass(obj):
+            return "class"
+        return "function" if callable(obj) else "instance"
+
+    def describe(name, obj):
+        info = {"name": name, "type": kind(name, obj), "signature": None, "doc": None}
+        try:
+            if callable(obj):
+                info["signature"] = f"{name.rsplit('.', 1)[-1]}{inspect.signature(obj)}"
+        except (TypeError, ValueError):
+            # Builtins without introspectable signatures
+            pass
+        doc = inspect.getdoc(obj)
+        if doc:
+            info["doc"] = doc[:doc_chars]
+        return info
+
+    before = code[:cursor_pos]
+    prefix = re.search(r"[A-Za-z_][\w.]*$", before)
+    prefix = prefix.group(0) if prefix else ""
+    rest = re.match(r"\w*", code[cursor_pos:]).group(0)
+
+    matches = []
+    if prefix:
+        completer = rlcompleter.Completer(namespace)
+        seen = set()
+        state = 0
+        while len(matches) < max_matches:
+            try:
+                match = completer.complete(prefix, state)
+            except Exception:
+                # Attribute completion evaluates the base expression; it may raise
+                break
+            if match is None:
+                break
+            state += 1
+            # rlcompleter decorates matches: "f(" / "f()" for callables, "try:" / "for " for keywords
+            text = match.removesuffix("()").rstrip("(: ")
+            if text in seen:
+                continue
+            seen.add(text)
+            matches.append({"text": text, "type": kind(text, resolve(text))})
+
+    # Signature help for the innermost call the cursor is in
+    call = None
+    depth = 0
+    for i in range(len(before) - 1, -1, -1):
+        if before[i] == ")":
+            depth += 1
+        elif before[i] == "(":
+            if depth == 0:
+                callee = re.search(r"[A-Za-z_][\w.]*$", before[:i])
+                if callee:
+                    obj = resolve(callee.group(0))
+                    if obj is not None:
+                        call = describe(callee.group(0), obj)
+                break
+            depth -= 1
+
+    # Help for the whole name under the cursor (e.g. Shift-Tab in Jupyter)
+    inspected = None
+    if prefix or rest:
+        name = prefix + rest
+        obj = resolve(name)
+        if obj is not None:
+            inspected = describe(name, obj)
+
+    return {
+        "matches": matches,
+        "cursor_start": cursor_pos - len(prefix),
+        "cursor_end": cursor_pos,
+        "signature": call,
+        "inspect": inspected,
+    }
+
+
+def build_sandbox_code(helper: Callable, *args: Any) -> str:
+    """Python source that calls a helper on the restored namespace and prints its result."""
+    return "\n".join(
+        [
+            # Snapshot before the helper's own names are defined
+            "_ci_namespace = dict(globals())",
+            inspect.getsource(helper),
+            "import json as _ci_json",
+            f"_ci_result = {helper.__name__}(_ci_namespace, {', '.join(repr(arg) for arg in args)})",
+            f"print({VARIABLES_MARKER!r} + _ci_json.dumps(_ci_result, default=str))",
+        ]
+    )
+
+
+def build_inspection_code(max_variables: int, preview_chars: int) -> str:
+    """Python source that prints the summary of the restored namespace."""
+    return build_sandbox_code(summarize_namespace, max_variables, preview_chars)
+
+
+def parse_inspection_output(stdout: str) -> dict[str, Any]:
+    """Extract the summary printed by the inspection code.
+
+    Raises:
+        ValueError: If the output has no summary line
+    """
+    for line in reversed(stdout.splitlines()):
+        if line.startswith(VARIABLES_MARKER):
+            return json.loads(line[len(VARIABLES_MARKER) :])
+    raise ValueError("No variable summary in output")
+
+
+class VariableInspector:
+    """Summarizes the persisted Python state of a session."""
+
+    def __init__(
+        self,
+        execution_service: ExecutionServiceInterface,
+        state_service: StateService,
+        state_archival_service: StateArchivalService | None = None,
+    ):
+        self.execution_service = execution_service
+        self.state_service = state_service
+        self.state_archival_service = state_archival_service
+
+    async def _load_state(self, session_id: str) -> str | None:
+        """Load the session's state from Redis, falling back to the MinIO archive."""
+        state = await self.state_service.get_state(session_id)
+        if not state and self.state_archival_service and settings.state_archive_enabled:
+            state = await self.state_archival_service.restore_state(session_id)
+        return state
+
+    async def _run(self, session_id: str, state: str | None, code: str, purpose: str) -> dict[str, Any]:
+        """Run helper code against the state in a throwaway sandbox and return its result.
+
+        Raises:
+            ExecutionError: If the helper printed no result
+        """
+        request = ExecuteCodeRequest(
+            code=code,
+            language="py",
+            timeout=min(INSPECT_TIMEOUT_SECONDS, settings.max_execution_time),
+        )
+        execution, handle, _, _, _ = await self.execution_service.execute_code(
+            session_id, request, initial_state=state, capture_state=False
+        )
+
+        try:
+            stdout = "\n".join(output.content for output in execution.outputs if output.type.value == "stdout")
+            try:
+                return parse_inspection_output(stdout)
+            except ValueError:
+                logger.warning(
+                    f"{purpose.capitalize()} failed",
+                    session_id=session_id[:12],
+                    status=execution.status.value,
+                    error=execution.error_message,
+                )
+                raise ExecutionError(message=f"{purpose.capitalize()} failed; the session state could not be loaded")
+        finally:
+            if handle:
+                try:
+                    await self.execution_service.kubernetes_manager.destroy_pod(handle)
+                except Exception as e:
+                    logger.warning("Failed to destroy inspection pod", error=str(e))
+
+    async def inspect(self, session_id: str) -> SessionVariablesResponse:
+        """List the session's variables.
+
+        Raises:
+            ExecutionError: If the summarizer fails in the sandbox
+        """
+        state = await self._load_state(session_id)
+        if not state:
+            return SessionVariablesResponse(session_id=session_id, has_state=False)
+
+        code = build_inspection_code(settings.state_inspect_max_variables, settings.state_inspect_preview_chars)
+        summary = await self._run(session_id, state, code, "variable inspection")
+
+        for info in summary["variables"]:
+            # Previews are reprs of user data, so they go through the output filters too
+            if info.get("preview"):
+                info["preview"] = filter_output(info["preview"], "preview")
+
+        return SessionVariablesResponse(
+            session_id=session_id,
+            has_state=True,
+            variables=[VariableInfo(**info) for info in summary["variables"]],
+            total=summary["total"],
+        )
+
+    async def export_dataframe(
+        self, session_id: str, name: str, rows: int, fmt: str = "json", sample: str = "head"
+    ) -> dict[str, Any]:
+        """Export rows of a dataframe-like variable.
+
+        Returns the helper's result: total_rows, rows, columns, dtypes and
+        data (records for json, text for csv, bytes for parquet).
+
+        Raises:
+            ResourceNotFoundError: If the session has no state or no such variable
+            ValidationError: If the variable isn't tabular
+            ExecutionError: If the export fails in the sandbox
+        """
+        state = await self._load_state(session_id)
+        if not state:
+            raise ResourceNotFoundError("Variable", name)
+
+        code = build_sandbox_code(export_dataframe, name, rows, fmt, sample, settings.state_export_max_bytes)
+        result = await self._run(session_id, state, code, "dataframe export")
+
+        if result.get("error") == "not_found":
+            raise ResourceNotFoundError("Variable", name)
+        if result.get("error") == "unsupported_type":
+            raise ValidationError(message=f"Variable '{name}' ({result['type']}) can't be converted to a dataframe")
+        if result.get("error") == "too_large":
+            raise ValidationError(
+                message=f"Export of '{name}' is too large ({result['size']} bytes, "
+                f"max {settings.state_export_max_bytes}); request fewer rows"
+            )
+        if result.get("error") == "export_failed":
+            raise ExecutionError(message=f"Exporting '{name}' as {fmt} failed: {result['message']}")
+        if fmt == "parquet":
+            result["data"] = base64.b64decode(result["data"])
+        return result
+
+    async def complete(self, session_id: str, code: str, cursor_pos: int) -> CompletionResponse:
+        """Complete code at the cursor against the session's variables.
+
+        Works without state too (builtins and keywords only).
+
+        Raises:
+            ExecutionError: If completion fails in the sandbox
+        """
+        state = await self._load_state(session_id)
+        helper_code = build_sandbox_code(complete_code, code, cursor_pos, MAX_COMPLETIONS, COMPLETION_DOC_CHARS)
+        result = await self._run(session_id, state, helper_code, "completion")
+        return CompletionResponse(**result)
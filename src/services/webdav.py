@@ -0,0 +1,101 @@
+"""WebDAV view of session workspaces.
+
+Each session's files (what executions see in /mnt/data) are exposed as a
+flat WebDAV collection at ``/dav/{session_id}/`` so they can be mounted in
+a file manager or IDE. Resources are named as they appear in the
+workspace, i.e. by their sanitized filename. Only class 1 WebDAV is
+offered: there are no locks and no sub-collections.
+"""
+
+from datetime import UTC, datetime
+from email.utils import format_datetime
+from urllib.parse import quote, unquote, urlsplit
+from xml.etree import ElementTree
+
+from ..models import FileInfo
+from .execution.output import OutputProcessor
+
+DAV_PREFIX = "/dav"
+DAV_NS = "DAV:"
+DAV_CLASS = "1"
+ALLOWED_METHODS = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, COPY, MOVE"
+XML_MEDIA_TYPE = 'application/xml; charset="utf-8"'
+
+ElementTree.register_namespace("D", DAV_NS)
+
+
+def resource_name(file_info: FileInfo) -> str:
+    """A file's name in the workspace."""
+    return OutputProcessor.sanitize_filename(file_info.filename)
+
+
+def collection_href(session_id: str) -> str:
+    return f"{DAV_PREFIX}/{quote(session_id, safe='')}/"
+
+
+def resource_href(session_id: str, name: str) -> str:
+    return collection_href(session_id) + quote(name, safe="")
+
+
+def find_file(files: list[FileInfo], name: str) -> FileInfo | None:
+    """The file a resource name refers to; the newest one if several share it."""
+    matches = [f for f in files if resource_name(f) == name]
+    return max(matches, key=lambda f: _as_utc(f.created_at)) if matches else None
+
+
+def parse_destination(destination: str | None, session_id: str) -> str | None:
+    """Resource name from a COPY/MOVE Destination header, if it's in the same session."""
+    if not destination:
+        return None
+    path = unquote(urlsplit(destination).path)
+    prefix = f"{DAV_PREFIX}/{session_id}/"
+    if not path.startswith(prefix):
+        return None
+    name = path[len(prefix) :]
+    return name if name and "/" not in name else None
+
+
+def _as_utc(value: datetime) -> datetime:
+    return value.replace(tzinfo=UTC) if value.tzinfo is None else value
+
+
+def _sub(parent: ElementTree.Element, tag: str, text: str | None = None) -> ElementTree.Element:
+    element = ElementTree.SubElement(parent, f"{{{DAV_NS}}}{tag}")
+    if text is not None:
+        element.text = text
+    return element
+
+
+def _response(multistatus: ElementTree.Element, href: str, props: dict[str, str], collection: bool) -> None:
+    response = _sub(multistatus, "response")
+    _sub(response, "href", href)
+    propstat = _sub(response, "propstat")
+    prop = _sub(propstat, "prop")
+    resourcetype = _sub(prop, "resourcetype")
+    if collection:
+        _sub(resourcetype, "collection")
+    for name, value in props.items():
+        _sub(prop, name, value)
+    _sub(propstat, "status", "HTTP/1.1 200 OK")
+
+
+def multistatus(session_id: str, files: list[FileInfo], include_collection: bool = True) -> bytes:
+    """PROPFIND response body for the session collection and/or its files."""
+    root = ElementTree.Element(f"{{{DAV_NS}}}multistatus")
+    if include_collection:
+        _response(root, collection_href(session_id), {"displayname": session_id}, collection=True)
+    for file_info in files:
+        name = resource_name(file_info)
+        _response(
+            root,
+            resource_href(session_id, name),
+            {
+                "displayname": name,
+                "getcontentlength": str(file_info.size),
+                "getcontenttype": file_info.content_type or "application/octet-stream",
+                "getlastmodified": format_datetime(_as_utc(file_info.created_at).astimezone(UTC), usegmt=True),
+                "getetag": f'"{file_info.file_id}"',
+            },
+            collection=False,
+        )
+    return ElementTree.tostring(root, encoding="utf-8", xml_declaration=True)
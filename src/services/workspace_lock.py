@@ -0,0 +1,186 @@
+"""Workspace locks for coordinating work within a session.
+
+Executions lock the workspace paths they declare (their scope) for their
+duration; without a scope they lock the whole workspace, so executions in a
+session are serialized by default. Clients can also take advisory locks on
+paths to coordinate their own steps, and pass the lock token to executions
+that should run inside the locked paths.
+
+Locks live in one Redis hash per session (token -> lock record) and are
+updated with optimistic transactions so overlapping requests can't both win.
+Every lock carries an expiry so a crashed holder can't wedge a session.
+"""
+
+import asyncio
+import json
+import posixpath
+import secrets
+import time
+from typing import Any
+
+import redis.asyncio as redis
+import structlog
+from redis.exceptions import WatchError
+
+from ..core.pool import redis_pool
+
+logger = structlog.get_logger(__name__)
+
+# Scope covering the entire workspace
+WORKSPACE_ROOT = ""
+
+# Paths are relative to the working directory; absolute paths under it are accepted too
+WORKING_DIR_PREFIX = "/mnt/data"
+
+
+def normalize_scope_path(path: str) -> str:
+    """Normalize a scope path to a workspace-relative POSIX path.
+
+    ``""``, ``"."``, ``"/"`` and the working directory itself all mean the
+    whole workspace.
+
+    Raises:
+        ValueError: If the path escapes the workspace
+    """
+    path = path.strip().replace("\\", "/")
+    if path == WORKING_DIR_PREFIX or path.startswith(WORKING_DIR_PREFIX + "/"):
+        path = path[len(WORKING_DIR_PREFIX) :]
+    normalized = posixpath.normpath("/" + path).lstrip("/")
+    if ".." in path.split("/"):
+        raise ValueError(f"Scope path escapes the workspace: {path}")
+    return "" if normalized == "." else normalized
+
+
+def paths_overlap(a: str, b: str) -> bool:
+    """Whether two normalized paths refer to overlapping parts of the workspace."""
+    if a == WORKSPACE_ROOT or b == WORKSPACE_ROOT or a == b:
+        return True
+    return a.startswith(b + "/") or b.startswith(a + "/")
+
+
+def find_conflicts(
+    held: dict[str, dict[str, Any]],
+    paths: list[str],
+    now: float,
+    holder_token: str | None = None,
+) -> list[dict[str, Any]]:
+    """Return unexpired locks that overlap any of the requested paths.
+
+    Locks owned by holder_token don't conflict, so an execution can run
+    inside a client lock it was given the token for.
+    """
+    conflicts = []
+    for token, lock in held.items():
+        if token == holder_token or lock["expires_at"] <= now:
+            continue
+        if any(paths_overlap(p, q) for p in paths for q in lock["paths"]):
+            conflicts.append(lock)
+    return conflicts
+
+
+class WorkspaceLockService:
+    """Manages per-session workspace locks in Redis."""
+
+    KEY_PREFIX = "session:locks:"
+
+    # Interval between acquisition attempts while waiting
+    POLL_INTERVAL_SECONDS = 0.25
+
+    def __init__(self, redis_client: redis.Redis | None = None):
+        """Initialize the lock service.
+
+        Args:
+            redis_client: Optional Redis client, uses shared pool if not provided
+        """
+        self.redis = redis_client or redis_pool.get_client()
+
+    def _locks_key(self, session_id: str) -> str:
+        """Generate Redis key for a session's lock hash."""
+        return f"{self.KEY_PREFIX}{session_id}"
+
+    @staticmethod
+    def _parse(raw: dict[str, str]) -> dict[str, dict[str, Any]]:
+        """Decode stored lock records, skipping corrupt entries."""
+        held = {}
+        for token, value in raw.items():
+            try:
+                held[token] = json.loads(value)
+            except (TypeError, ValueError):
+                continue
+        return held
+
+    async def try_acquire(
+        self,
+        session_id: str,
+        paths: list[str],
+        owner: str,
+        ttl_seconds: int,
+        holder_token: str | None = None,
+    ) -> tuple[str | None, list[dict[str, Any]]]:
+        """Try to lock paths once.
+
+        Returns:
+            Tuple of (token, conflicts). Token is None when overlapping locks
+            are held; conflicts then lists them.
+        """
+        key = self._locks_key(session_id)
+        pipe = await self.redis.pipeline(transaction=True)
+        try:
+            while True:
+                try:
+                    await pipe.watch(key)
+                    now = time.time()
+                    held = self._parse(await pipe.hgetall(key))
+                    conflicts = find_conflicts(held, paths, now, holder_token)
+                    if conflicts:
+                        await pipe.unwatch()
+                        return None, conflicts
+
+                    token = secrets.token_urlsafe(16)
+                    lock = {"paths": paths, "owner": owner, "expires_at": now + ttl_seconds}
+                    expired = [t for t, record in held.items() if record["expires_at"] <= now]
+                    # The hash lives as long as its longest-lived lock
+                    key_ttl = max([ttl_seconds] + [int(r["expires_at"] - now) + 1 for r in held.values()])
+
+                    pipe.multi()
+                    if expired:
+                        pipe.hdel(key, *expired)
+                    pipe.hset(key, token, json.dumps(lock))
+                    pipe.expire(key, key_ttl)
+                    await pipe.execute()
+                    return token, []
+                except WatchError:
+                    # Another request changed the locks between read and write; re-evaluate
+                    continue
+        finally:
+            await pipe.reset()
+
+    async def acquire(
+        self,
+        session_id: str,
+        paths: list[str],
+        owner: str,
+        ttl_seconds: int,
+        wait_seconds: float = 0,
+        holder_token: str | None = None,
+    ) -> tuple[str | None, list[dict[str, Any]]]:
+        """Lock paths, waiting up to wait_seconds for overlapping locks to clear."""
+        deadline = time.monotonic() + wait_seconds
+        while True:
+            token, conflicts = await self.try_acquire(session_id, paths, owner, ttl_seconds, holder_token)
+            if token or time.monotonic() >= deadline:
+                return token, conflicts
+            await asyncio.sleep(self.POLL_INTERVAL_SECONDS)
+
+    async def release(self, session_id: str, token: str) -> bool:
+        """Release a lock. Returns False if it was not held (or already expired)."""
+        return bool(await self.redis.hdel(self._locks_key(session_id), token))
+
+    async def list_locks(self, session_id: str) -> list[dict[str, Any]]:
+        """List unexpired locks for a session, without their tokens."""
+        now = time.time()
+        held = self._parse(await self.redis.hgetall(self._locks_key(session_id)) or {})
+        return sorted(
+            (lock for lock in held.values() if lock["expires_at"] > now),
+            key=lambda lock: lock["expires_at"],
+        )
@@ -0,0 +1,51 @@
+"""File checksums for end-to-end integrity checks.
+
+Expected checksums are written ``<algo>:<hex digest>`` (``sha256:9f86…``);
+a bare hex digest means sha256.
+"""
+
+import hashlib
+import hmac
+from typing import Literal
+
+ChecksumAlgorithm = Literal["md5", "sha1", "sha256", "sha512"]
+SUPPORTED_ALGORITHMS: tuple[str, ...] = ("md5", "sha1", "sha256", "sha512")
+DEFAULT_ALGORITHM = "sha256"
+
+
+def compute_checksum(content: bytes, algo: str = DEFAULT_ALGORITHM) -> str:
+    """Hex digest of content."""
+    if algo not in SUPPORTED_ALGORITHMS:
+        raise ValueError(f"Unsupported checksum algorithm: {algo}")
+    return hashlib.new(algo, content).hexdigest()
+
+
+def parse_checksum(value: str) -> tuple[str, str]:
+    """Split an expected checksum into (algorithm, lowercase hex digest).
+
+    Raises:
+        ValueError: If the algorithm is unsupported or the digest has the wrong form
+    """
+    algo, _, digest = value.strip().rpartition(":")
+    algo = algo.lower() or DEFAULT_ALGORITHM
+    digest = digest.lower()
+    if algo not in SUPPORTED_ALGORITHMS:
+        raise ValueError(f"Unsupported checksum algorithm: {algo}")
+    expected_length = hashlib.new(algo).digest_size * 2
+    if len(digest) != expected_length or any(c not in "0123456789abcdef" for c in digest):
+        raise ValueError(f"Invalid {algo} checksum: expected {expected_length} hex characters")
+    return algo, digest
+
+
+def verify_checksum(content: bytes, expected: str) -> tuple[bool, str]:
+    """Check content against an expected checksum.
+
+    Returns:
+        Tuple of (matches, actual checksum as ``<algo>:<hex>``)
+
+    Raises:
+        ValueError: If the expected checksum is malformed
+    """
+    algo, digest = parse_checksum(expected)
+    actual = compute_checksum(content, algo)
+    return hmac.compare_digest(actual, digest), f"{algo}:{actual}"
@@ -1,6 +1,7 @@
 """Configuration validation utilities."""
 
 import logging
+import os
 from typing import Any, Dict, List
 
 import redis
@@ -80,6 +81,23 @@ class ConfigValidator:
         if not settings.enable_filesystem_isolation:
             self.warnings.append("Filesystem isolation is disabled - security risk")
 
+        if settings.fault_injection:
+            self.warnings.append(f"Fault injection is enabled ({settings.fault_injection}) - for testing only")
+
+        unpinned = sorted(name for name, entry in settings.image_catalog.items() if "@sha256:" not in entry.image)
+        if unpinned:
+            self.warnings.append(
+                f"Image catalog entries not pinned by digest: {', '.join(unpinned)} - the image that ran can change"
+            )
+
+        bundle = settings.get_policy_bundle()
+        if bundle:
+            ignored = sorted(name.upper() for name in bundle.settings() if name.upper() in os.environ)
+            if ignored:
+                self.warnings.append(
+                    f"Policy bundle {bundle.name} {bundle.version} overrides {', '.join(ignored)} - environment ignored"
+                )
+
     def _validate_resource_limits(self):
         """Validate resource limit configuration."""
         # Check critical limit conflicts
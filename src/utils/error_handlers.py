@@ -22,9 +22,21 @@ from ..models.errors import (
     ValidationError,
 )
 
+from .i18n import DEFAULT_LOCALE, localize, negotiate_locale
+
 logger = structlog.get_logger(__name__)
 
 
+def _localized_response(status_code: int, error_response: ErrorResponse, locale: str) -> JSONResponse:
+    """Build the JSON error response, labelling translated messages with Content-Language."""
+    headers = {"Content-Language": locale} if locale != DEFAULT_LOCALE else None
+    return JSONResponse(status_code=status_code, content=error_response.model_dump(), headers=headers)
+
+
+def _request_locale(request: Request) -> str:
+    return negotiate_locale(request.headers.get("accept-language"))
+
+
 def generate_request_id() -> str:
     """Generate a unique request ID for error tracking."""
     from .id_generator import generate_request_id as gen_id
@@ -62,9 +74,10 @@ async def code_interpreter_exception_handler(request: Request, exc: CodeInterpre
     else:
         logger.info("Error handled", **log_data)
 
-    # Return standardized error response
-    error_response = exc.to_response()
-    return JSONResponse(status_code=exc.status_code, content=error_response.model_dump())
+    # Return standardized error response (message localized, code unchanged)
+    locale = _request_locale(request)
+    error_response = exc.to_response(localize(exc.code, exc.message, locale, exc.params))
+    return _localized_response(exc.status_code, error_response, locale)
 
 
 async def http_exception_handler(request: Request, exc: HTTPException) -> JSONResponse:
@@ -102,10 +115,21 @@ async def http_exception_handler(request: Request, exc: HTTPException) -> JSONRe
         client_ip=(getattr(request.client, "host", "unknown") if request.client else "unknown"),
     )
 
+    # Detail dicts carry a stable code in "error"; only their "message" is localized
+    locale = _request_locale(request)
+    detail = exc.detail
+    code = error_type.value
+    if isinstance(detail, dict) and isinstance(detail.get("error"), str):
+        code = detail["error"]
+        if "message" in detail:
+            detail = {**detail, "message": localize(code, str(detail["message"]), locale, detail)}
+    elif isinstance(detail, str):
+        detail = localize(code, detail, locale)
+
     # Create standardized error response
-    error_response = ErrorResponse(error=str(exc.detail), error_type=error_type, request_id=request_id)
+    error_response = ErrorResponse(error=str(detail), error_type=error_type, code=code, request_id=request_id)
 
-    return JSONResponse(status_code=exc.status_code, content=error_response.model_dump())
+    return _localized_response(exc.status_code, error_response, locale)
 
 
 async def validation_exception_handler(
@@ -132,14 +156,16 @@ async def validation_exception_handler(
     )
 
     # Create standardized error response
+    locale = _request_locale(request)
     error_response = ErrorResponse(
-        error="Request validation failed",
+        error=localize("request_validation", "Request validation failed", locale),
         error_type=ErrorType.VALIDATION,
+        code="request_validation",
         details=details,
         request_id=request_id,
     )
 
-    return JSONResponse(status_code=422, content=error_response.model_dump())
+    return _localized_response(422, error_response, locale)
 
 
 async def general_exception_handler(request: Request, exc: Exception) -> JSONResponse:
@@ -160,13 +186,15 @@ async def general_exception_handler(request: Request, exc: Exception) -> JSONRes
     )
 
     # Create generic error response (don't expose internal details)
+    locale = _request_locale(request)
     error_response = ErrorResponse(
-        error="An unexpected error occurred",
+        error=localize(ErrorType.INTERNAL_SERVER.value, "An unexpected error occurred", locale),
         error_type=ErrorType.INTERNAL_SERVER,
+        code=ErrorType.INTERNAL_SERVER.value,
         request_id=request_id,
     )
 
-    return JSONResponse(status_code=500, content=error_response.model_dump())
+    return _localized_response(500, error_response, locale)
 
 
 # Utility functions for common error scenarios
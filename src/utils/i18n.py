@@ -0,0 +1,184 @@
+"""Localized error messages.
+
+Error responses keep a stable machine-readable ``code`` (and ``error_type``);
+only the human-readable ``error`` message is translated. The locale comes
+from the request's Accept-Language header, falling back to
+ERROR_DEFAULT_LOCALE. English messages are the ones raised in code, so "en"
+needs no catalog.
+
+Catalog entries are keyed by error code: an ErrorType value, the "error"
+code of an HTTPException detail dict (e.g. ``session_not_found``), or
+``request_validation``. Templates may use the exception's params, such as
+``{timeout}`` for timeouts. ERROR_CATALOG_PATH adds locales or overrides
+messages: ``{"de": {"session_busy": "..."}}``.
+"""
+
+import json
+from functools import lru_cache
+from typing import Any
+
+import structlog
+
+from ..config import settings
+
+logger = structlog.get_logger(__name__)
+
+DEFAULT_LOCALE = "en"
+
+BUILTIN_CATALOG: dict[str, dict[str, str]] = {
+    "de": {
+        "authentication": "Authentifizierung fehlgeschlagen",
+        "authorization": "Zugriff verweigert",
+        "validation": "Ungültige Anfrage",
+        "request_validation": "Validierung der Anfrage fehlgeschlagen",
+        "resource_not_found": "Ressource nicht gefunden",
+        "resource_conflict": "Konflikt mit dem aktuellen Zustand der Ressource",
+        "resource_exhausted": "Kontingent überschritten: {resource}",
+        "execution_failed": "Die Codeausführung ist fehlgeschlagen",
+        "timeout": "Zeitüberschreitung nach {timeout} Sekunden",
+        "rate_limited": "Zu viele Anfragen, bitte später erneut versuchen",
+        "internal_server": "Ein unerwarteter Fehler ist aufgetreten",
+        "service_unavailable": "Der Dienst ist vorübergehend nicht verfügbar",
+        "external_service": "Fehler in einem externen Dienst",
+        "session_not_found": "Sitzung nicht gefunden",
+        "session_busy": "Die Sitzung ist mit einer anderen Ausführung beschäftigt",
+        "cell_not_found": "Zelle nicht gefunden",
+        "state_not_found": "Kein gespeicherter Zustand für diese Sitzung",
+        "path_locked": "Der Pfad ist durch eine andere Ausführung gesperrt",
+        "template_not_found": "Vorlage nicht gefunden",
+        "checksum_mismatch": "Die Prüfsumme der Datei stimmt nicht überein",
+        "invalid_checksum": "Ungültige Prüfsumme",
+        "invalid_archive": "Das Archiv kann nicht gelesen werden",
+        "unsafe_archive": "Das Archiv enthält unsichere Pfade",
+        "archive_limit_exceeded": "Das Archiv überschreitet die Entpackungsgrenzen",
+        "unsupported_preview_format": "Für dieses Dateiformat ist keine Vorschau verfügbar",
+        "invalid_preview_file": "Die Datei kann für die Vorschau nicht gelesen werden",
+        "unsupported_thumbnail_format": "Miniaturansichten gibt es nur für PNG-Bilder",
+        "invalid_image": "Das Bild kann nicht gelesen werden",
+    },
+    "es": {
+        "authentication": "Error de autenticación",
+        "authorization": "Acceso denegado",
+        "validation": "Solicitud no válida",
+        "request_validation": "La validación de la solicitud falló",
+        "resource_not_found": "Recurso no encontrado",
+        "resource_conflict": "Conflicto con el estado actual del recurso",
+        "resource_exhausted": "Cuota superada: {resource}",
+        "execution_failed": "La ejecución del código falló",
+        "timeout": "Tiempo de espera agotado tras {timeout} segundos",
+        "rate_limited": "Demasiadas solicitudes, inténtelo más tarde",
+        "internal_server": "Se produjo un error inesperado",
+        "service_unavailable": "El servicio no está disponible temporalmente",
+        "external_service": "Error en un servicio externo",
+        "session_not_found": "Sesión no encontrada",
+        "session_busy": "La sesión está ocupada con otra ejecución",
+        "cell_not_found": "Celda no encontrada",
+        "state_not_found": "No hay estado guardado para esta sesión",
+        "path_locked": "La ruta está bloqueada por otra ejecución",
+        "template_not_found": "Plantilla no encontrada",
+        "checksum_mismatch": "La suma de comprobación del archivo no coincide",
+        "invalid_checksum": "Suma de comprobación no válida",
+        "invalid_archive": "No se puede leer el archivo comprimido",
+        "unsafe_archive": "El archivo comprimido contiene rutas inseguras",
+        "archive_limit_exceeded": "El archivo comprimido supera los límites de extracción",
+        "unsupported_preview_format": "No hay vista previa disponible para este formato de archivo",
+        "invalid_preview_file": "No se puede leer el archivo para la vista previa",
+        "unsupported_thumbnail_format": "Solo se generan miniaturas de imágenes PNG",
+        "invalid_image": "No se puede leer la imagen",
+    },
+    "fr": {
+        "authentication": "Échec de l'authentification",
+        "authorization": "Accès refusé",
+        "validation": "Requête invalide",
+        "request_validation": "La validation de la requête a échoué",
+        "resource_not_found": "Ressource introuvable",
+        "resource_conflict": "Conflit avec l'état actuel de la ressource",
+        "resource_exhausted": "Quota dépassé : {resource}",
+        "execution_failed": "L'exécution du code a échoué",
+        "timeout": "Délai dépassé après {timeout} secondes",
+        "rate_limited": "Trop de requêtes, veuillez réessayer plus tard",
+        "internal_server": "Une erreur inattendue s'est produite",
+        "service_unavailable": "Le service est temporairement indisponible",
+        "external_service": "Erreur d'un service externe",
+        "session_not_found": "Session introuvable",
+        "session_busy": "La session est occupée par une autre exécution",
+        "cell_not_found": "Cellule introuvable",
+        "state_not_found": "Aucun état enregistré pour cette session",
+        "path_locked": "Le chemin est verrouillé par une autre exécution",
+        "template_not_found": "Modèle introuvable",
+        "checksum_mismatch": "La somme de contrôle du fichier ne correspond pas",
+        "invalid_checksum": "Somme de contrôle invalide",
+        "invalid_archive": "L'archive est illisible",
+        "unsafe_archive": "L'archive contient des chemins dangereux",
+        "archive_limit_exceeded": "L'archive dépasse les limites d'extraction",
+        "unsupported_preview_format": "Aucun aperçu n'est disponible pour ce format de fichier",
+        "invalid_preview_file": "Le fichier ne peut pas être lu pour l'aperçu",
+        "unsupported_thumbnail_format": "Les miniatures ne sont générées que pour les images PNG",
+        "invalid_image": "L'image ne peut pas être lue",
+    },
+}
+
+
+@lru_cache(maxsize=1)
+def get_catalog() -> dict[str, dict[str, str]]:
+    """Built-in messages merged with ERROR_CATALOG_PATH (loaded once)."""
+    catalog = {locale: dict(messages) for locale, messages in BUILTIN_CATALOG.items()}
+    if not settings.error_catalog_path:
+        return catalog
+
+    try:
+        with open(settings.error_catalog_path, encoding="utf-8") as f:
+            extra = json.load(f)
+        for locale, messages in extra.items():
+            catalog.setdefault(locale.lower(), {}).update({str(k): str(v) for k, v in messages.items()})
+    except (OSError, ValueError, AttributeError) as e:
+        logger.error("Failed to load error catalog", path=settings.error_catalog_path, error=str(e))
+    return catalog
+
+
+def _parse_accept_language(header: str) -> list[str]:
+    """Language tags from an Accept-Language header, most preferred first."""
+    ranked = []
+    for position, part in enumerate(header.split(",")):
+        tag, _, params = part.strip().partition(";")
+        if not tag:
+            continue
+        quality = 1.0
+        for param in params.split(";"):
+            name, _, value = param.strip().partition("=")
+            if name == "q":
+                try:
+                    quality = float(value)
+                except ValueError:
+                    quality = 0.0
+        if quality > 0:
+            ranked.append((-quality, position, tag.strip().lower()))
+    return [tag for _, _, tag in sorted(ranked)]
+
+
+def negotiate_locale(accept_language: str | None) -> str:
+    """Pick the catalog locale for an Accept-Language header.
+
+    Tags match exactly ("pt-br") or by primary language ("de-AT" -> "de").
+    """
+    catalog = get_catalog()
+    available = {DEFAULT_LOCALE, *catalog}
+    for tag in _parse_accept_language(accept_language) if isinstance(accept_language, str) else []:
+        if tag == "*":
+            break
+        for candidate in (tag, tag.split("-")[0]):
+            if candidate in available:
+                return candidate
+    default = settings.error_default_locale.lower()
+    return default if default in available else DEFAULT_LOCALE
+
+
+def localize(code: str | None, message: str, locale: str, params: dict[str, Any] | None = None) -> str:
+    """Translate an error message, keeping the original when there's no usable entry."""
+    template = get_catalog().get(locale, {}).get(code or "")
+    if not template:
+        return message
+    try:
+        return template.format(**(params or {}))
+    except (KeyError, IndexError, ValueError):
+        return message
@@ -0,0 +1,81 @@
+"""Rotation and retention for log files written inside the container.
+
+Long-lived pods write their logs to ephemeral storage, so every log file
+is rotated by size or on a schedule, keeps at most ``backup_count`` old
+files, and can also drop rotated files older than a retention period.
+"""
+
+import logging
+import logging.handlers
+import os
+import time
+from pathlib import Path
+
+ROTATIONS = ("size", "time")
+# TimedRotatingFileHandler schedules
+ROTATION_WHENS = ("S", "M", "H", "D", "midnight", *(f"W{day}" for day in range(7)))
+
+
+class _RetentionMixin:
+    """Deletes rotated files older than ``retention_seconds`` after each rollover."""
+
+    baseFilename: str
+    retention_seconds: float | None = None
+
+    def prune(self) -> list[Path]:
+        """Remove expired rotated files; returns the removed paths."""
+        if not self.retention_seconds:
+            return []
+        base = Path(self.baseFilename)
+        cutoff = time.time() - self.retention_seconds
+        removed = []
+        for path in base.parent.glob(f"{base.name}.*"):
+            try:
+                if path.stat().st_mtime < cutoff:
+                    path.unlink()
+                    removed.append(path)
+            except OSError:
+                continue
+        return removed
+
+    def doRollover(self) -> None:
+        super().doRollover()  # type: ignore[misc]
+        self.prune()
+
+
+class SizeRotatingFileHandler(_RetentionMixin, logging.handlers.RotatingFileHandler):
+    """Rotates when the file would grow past ``maxBytes``."""
+
+
+class TimeRotatingFileHandler(_RetentionMixin, logging.handlers.TimedRotatingFileHandler):
+    """Rotates on a schedule (UTC)."""
+
+
+def rotating_file_handler(
+    path: str | os.PathLike,
+    rotation: str = "size",
+    max_bytes: int = 100 * 1024 * 1024,
+    backup_count: int = 5,
+    when: str = "midnight",
+    retention_days: int | None = None,
+) -> logging.Handler:
+    """File handler rotating by size or on a schedule, pruning expired rotated files.
+
+    Raises:
+        ValueError: If ``rotation`` or ``when`` isn't known
+    """
+    if rotation not in ROTATIONS:
+        raise ValueError(f"Unknown log rotation {rotation!r} (available: {', '.join(ROTATIONS)})")
+    if when not in ROTATION_WHENS:
+        raise ValueError(f"Unknown log rotation schedule {when!r} (available: {', '.join(ROTATION_WHENS)})")
+
+    path = Path(path)
+    path.parent.mkdir(parents=True, exist_ok=True)
+    if rotation == "time":
+        handler = TimeRotatingFileHandler(path, when=when, backupCount=backup_count, encoding="utf-8", utc=True)
+    else:
+        handler = SizeRotatingFileHandler(path, maxBytes=max_bytes, backupCount=backup_count, encoding="utf-8")
+    handler.retention_seconds = retention_days * 86400 if retention_days else None
+    # Files left by earlier runs of the container expire too
+    handler.prune()
+    return handler
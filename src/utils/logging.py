@@ -2,7 +2,6 @@
 
 # Standard library imports
 import logging
-import logging.handlers
 import sys
 from pathlib import Path
 from typing import Any, Dict
@@ -13,6 +12,7 @@ import structlog
 # Local application imports
 from .._version import __version__
 from ..config import settings
+from .log_rotation import rotating_file_handler
 
 
 def setup_logging() -> None:
@@ -55,25 +55,22 @@ def setup_logging() -> None:
     # Setup file logging if configured
     if settings.log_file:
         setup_file_logging()
+    if settings.audit_log_file:
+        setup_audit_logging()
 
     # Configure third-party loggers
     configure_third_party_loggers()
 
 
-def setup_file_logging() -> None:
-    """Setup file-based logging with rotation."""
-    if not settings.log_file:
-        return
-
-    log_file_path = Path(settings.log_file)
-    log_file_path.parent.mkdir(parents=True, exist_ok=True)
-
-    # Create rotating file handler
-    file_handler = logging.handlers.RotatingFileHandler(
-        filename=log_file_path,
-        maxBytes=settings.log_max_size_mb * 1024 * 1024,
-        backupCount=settings.log_backup_count,
-        encoding="utf-8",
+def _file_handler(path: str) -> logging.Handler:
+    """Rotating file handler for a log file, with retention and the configured format."""
+    file_handler = rotating_file_handler(
+        Path(path),
+        rotation=settings.log_rotation,
+        max_bytes=settings.log_max_size_mb * 1024 * 1024,
+        backup_count=settings.log_backup_count,
+        when=settings.log_rotation_when,
+        retention_days=settings.log_retention_days,
     )
 
     # Set formatter based on log format
@@ -84,10 +81,28 @@ def setup_file_logging() -> None:
 
     file_handler.setFormatter(formatter)
     file_handler.setLevel(getattr(logging, settings.log_level.upper(), logging.INFO))
+    return file_handler
+
+
+def setup_file_logging() -> None:
+    """Setup file-based logging with rotation."""
+    if not settings.log_file:
+        return
 
     # Add handler to root logger
     root_logger = logging.getLogger()
-    root_logger.addHandler(file_handler)
+    root_logger.addHandler(_file_handler(settings.log_file))
+
+
+def setup_audit_logging() -> None:
+    """Also write security/audit events to their own rotating file."""
+    if not settings.audit_log_file:
+        return
+
+    audit_handler = _file_handler(settings.audit_log_file)
+    # get_security_logger() and SecurityAudit; events still reach the main log too
+    for name in ("security", "src.utils.security"):
+        logging.getLogger(name).addHandler(audit_handler)
 
 
 def configure_third_party_loggers() -> None:
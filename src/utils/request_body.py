@@ -0,0 +1,24 @@
+"""Reading raw request bodies with a size cap.
+
+Routes that take a raw body (rather than a form upload, which Starlette
+spools to disk) would otherwise buffer whatever a client sends.
+"""
+
+from fastapi import HTTPException, Request
+
+
+async def read_limited_body(request: Request, limit: int) -> bytes:
+    """The request body, refused with 413 once it is over ``limit`` bytes.
+
+    A Content-Length over the limit is refused before anything is read;
+    without one (chunked uploads) the body is read until it passes the limit.
+    """
+    declared = request.headers.get("content-length", "")
+    if declared.isdigit() and int(declared) > limit:
+        raise HTTPException(status_code=413, detail=f"Request body exceeds the limit of {limit} bytes")
+    body = bytearray()
+    async for chunk in request.stream():
+        body += chunk
+        if len(body) > limit:
+            raise HTTPException(status_code=413, detail=f"Request body exceeds the limit of {limit} bytes")
+    return bytes(body)
@@ -73,6 +73,13 @@ class SecurityValidator:
     # Maximum filename length
     MAX_FILENAME_LENGTH = 255
 
+    # Environment variable names must be shell identifiers
+    ENV_NAME_PATTERN = re.compile(r"^[A-Za-z_][A-Za-z0-9_]*$")
+
+    # DNS allowlist entries: a name, or *.name for its subdomains
+    DNS_LABEL = r"[A-Za-z0-9_]([A-Za-z0-9_-]{0,61}[A-Za-z0-9])?"
+    DNS_PATTERN = re.compile(rf"^(\*\.)?({DNS_LABEL}\.)*{DNS_LABEL}\.?$")
+
     @classmethod
     def validate_filename(cls, filename: str) -> bool:
         """Validate uploaded filename for security."""
@@ -107,6 +114,16 @@ class SecurityValidator:
 
         return True
 
+    @classmethod
+    def invalid_env_names(cls, names) -> list[str]:
+        """Return the environment variable names that aren't valid identifiers."""
+        return sorted(name for name in names if not cls.ENV_NAME_PATTERN.match(name))
+
+    @classmethod
+    def invalid_dns_patterns(cls, patterns) -> list[str]:
+        """Return the DNS allowlist entries that aren't names or *.name wildcards."""
+        return [pattern for pattern in patterns if len(pattern) > 253 or not cls.DNS_PATTERN.match(pattern)]
+
     @classmethod
     def validate_code_content(cls, code: str, language: str) -> dict[str, Any]:
         """
@@ -288,6 +305,128 @@ class SecurityAudit:
             },
         )
 
+    @staticmethod
+    def log_session_env_update(session_id: str, names: list[str], success: bool):
+        """Log session environment changes; values are never recorded."""
+        SecurityAudit.log_security_event(
+            "session_env_update",
+            {
+                "session_id": session_id,
+                "names": sorted(names),
+                "success": success,
+            },
+        )
+
+    @staticmethod
+    def log_config_change(setting: str, old: Any, new: Any, actor: str | None, reason: str | None):
+        """Log a runtime policy change made through the admin API."""
+        SecurityAudit.log_security_event(
+            "config_change",
+            {
+                "setting": setting,
+                "old": old,
+                "new": new,
+                "actor": actor,
+                "reason": reason,
+            },
+            severity="warning",
+        )
+
+    @staticmethod
+    def log_quarantine(session_id: str, action: str, actor: str | None, reason: str | None, **details: Any):
+        """Log a session being quarantined or released through the admin API."""
+        SecurityAudit.log_security_event(
+            f"session_{action}",
+            {
+                "session_id": session_id,
+                "actor": actor,
+                "reason": reason,
+                **details,
+            },
+            severity="critical" if action == "quarantined" else "warning",
+        )
+
+    @staticmethod
+    def log_session_transfer(session_id: str, action: str, actor: str | None, **details: Any):
+        """Log a session being exported from or imported into this cluster through the admin API."""
+        SecurityAudit.log_security_event(
+            f"session_{action}",
+            {
+                "session_id": session_id,
+                "actor": actor,
+                **details,
+            },
+            severity="warning",
+        )
+
+    @staticmethod
+    def log_session_report(session_id: str, reason: str | None, **details: Any):
+        """Log the end-of-session report of a terminated session (its counts, not its file list)."""
+        SecurityAudit.log_security_event(
+            "session_report",
+            {
+                "session_id": session_id,
+                "reason": reason,
+                **details,
+            },
+            severity="warning" if details.get("violations") else "info",
+        )
+
+    @staticmethod
+    def log_elevation(
+        event: str,
+        grant_id: str | None,
+        actor: str | None,
+        session_id: str | None,
+        detail: str | None,
+        **details: Any,
+    ):
+        """Log an elevated execution grant being issued, used, refused or revoked."""
+        SecurityAudit.log_security_event(
+            f"elevation_{event}",
+            {
+                "grant_id": grant_id,
+                "actor": actor,
+                "session_id": session_id,
+                "detail": detail,
+                **details,
+            },
+            severity="critical" if event == "denied" else "warning",
+        )
+
+    @staticmethod
+    def log_execution_provenance(session_id: str, execution_id: str, language: str, provenance: dict[str, Any]):
+        """Log the command and interpreter binaries an execution ran with, for post-incident analysis."""
+        SecurityAudit.log_security_event(
+            "execution_provenance",
+            {
+                "session_id": session_id,
+                "execution_id": execution_id,
+                "language": language,
+                "command": provenance.get("command"),
+                "binaries": provenance.get("binaries"),
+            },
+        )
+
+    @staticmethod
+    def log_execution_image(session_id: str, execution_id: str, image_name: str, image: str | None):
+        """Log the catalog image an execution ran in (ExecRequest.image or the API key's pin)."""
+        SecurityAudit.log_security_event(
+            "execution_image",
+            {"session_id": session_id, "execution_id": execution_id, "image_name": image_name, "image": image},
+        )
+
+    @staticmethod
+    def log_tenant_images(
+        api_key_hash: str, actor: str | None, images: list[str] | None = None, pins: dict[str, str] | None = None
+    ):
+        """Log a change to the catalog images an API key may run; no images means they were taken away."""
+        SecurityAudit.log_security_event(
+            "tenant_images_changed",
+            {"api_key_hash": api_key_hash[:16], "actor": actor, "images": images or [], "pins": pins or {}},
+            severity="warning",
+        )
+
     @staticmethod
     def log_code_execution(
         session_id: str,
@@ -2,7 +2,9 @@
 
 import asyncio
 import os
+import sys
 from datetime import UTC, datetime, timezone
+from pathlib import Path
 from typing import AsyncGenerator, Generator
 from unittest.mock import AsyncMock, MagicMock, patch
 
@@ -23,6 +25,9 @@ os.environ["MINIO_ACCESS_KEY"] = "minioadmin"
 os.environ["MINIO_SECRET_KEY"] = "minioadmin"
 os.environ["MINIO_SECURE"] = "false"
 
+# Make the sidecar's helper package (docker/sidecar/executor) importable in tests
+sys.path.insert(0, str(Path(__file__).resolve().parent.parent / "docker" / "sidecar"))
+
 from src.config import settings
 from src.models import Session, SessionCreate, SessionStatus
 from src.services.auth import AuthenticationService
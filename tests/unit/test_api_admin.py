@@ -11,13 +11,31 @@ from src.api.admin import (
     ApiKeyUpdate,
     RateLimitsUpdate,
     create_key,
+    delete_tenant_images,
     get_admin_stats,
+    get_runtime_config,
+    get_scheduler_state,
+    import_session,
+    issue_elevated_grant,
     list_keys,
+    quarantine_session,
+    release_session,
+    reset_runtime_config,
+    revoke_elevated_grant,
     revoke_key,
+    set_tenant_images,
     update_key,
+    update_runtime_config,
     verify_master_key,
 )
 from src.models.api_key import ApiKeyRecord, RateLimits
+from src.models.elevation import ElevatedCapabilities, ElevatedGrantRequest
+from src.models.image_catalog import TenantImages
+from src.models.runtime_config import RuntimeConfigPatch
+from src.models.scheduler import SchedulerState
+from src.models.session import QuarantineRecord, QuarantineRequest
+from src.services.image_catalog import ImageCatalogError
+from src.services.session_transfer import SessionTransferError
 
 
 @pytest.fixture
@@ -374,6 +392,259 @@ class TestGetAdminStats:
                 assert result["period_hours"] == 48
 
 
+class TestRuntimeConfig:
+    """Tests for the runtime policy endpoints."""
+
+    @pytest.mark.asyncio
+    async def test_get_runtime_config(self):
+        """Test current values and overrides are returned."""
+        with patch("src.api.admin.get_hot_config_service") as mock_get_service:
+            mock_service = MagicMock()
+            mock_service.current.return_value = {"max_execution_time": 60}
+            mock_service.overridden.return_value = ["max_execution_time"]
+            mock_get_service.return_value = mock_service
+
+            result = await get_runtime_config("master-key")
+
+            assert result.config == {"max_execution_time": 60}
+            assert result.overrides == ["max_execution_time"]
+            assert result.changes == []
+
+    @pytest.mark.asyncio
+    async def test_update_runtime_config_records_actor_and_reason(self):
+        """Test the client address and change reason reach the audit log."""
+        with patch("src.api.admin.get_hot_config_service") as mock_get_service:
+            mock_service = MagicMock()
+            mock_service.update = AsyncMock(return_value=[])
+            mock_service.current.return_value = {}
+            mock_service.overridden.return_value = []
+            mock_get_service.return_value = mock_service
+            request = MagicMock()
+            request.client.host = "10.0.0.5"
+            patch_ = RuntimeConfigPatch(max_concurrent_executions=20)
+
+            await update_runtime_config(patch_, request, "load test", "master-key")
+
+            mock_service.update.assert_called_once_with(patch_, actor="10.0.0.5", reason="load test")
+
+    @pytest.mark.asyncio
+    async def test_reset_runtime_config(self):
+        """Test settings are reset by name, and unknown names are a bad request."""
+        with patch("src.api.admin.get_hot_config_service") as mock_get_service:
+            mock_service = MagicMock()
+            mock_service.reset = AsyncMock(return_value=[])
+            mock_service.current.return_value = {}
+            mock_service.overridden.return_value = []
+            mock_get_service.return_value = mock_service
+            request = MagicMock()
+            request.client.host = "10.0.0.5"
+
+            await reset_runtime_config(request, ["max_execution_time"], None, "master-key")
+            mock_service.reset.assert_called_once_with(["max_execution_time"], actor="10.0.0.5", reason=None)
+
+            mock_service.reset.side_effect = ValueError("Not runtime-adjustable: api_key")
+            with pytest.raises(HTTPException) as exc_info:
+                await reset_runtime_config(request, ["api_key"], None, "master-key")
+            assert exc_info.value.status_code == 400
+
+
+class TestSchedulerState:
+    """Tests for the execution scheduler state endpoint."""
+
+    @pytest.mark.asyncio
+    async def test_get_scheduler_state(self):
+        """Test the filters reach the gate."""
+        state = SchedulerState(limit=10, running=10, waiting=2, alert_p95_ms=10000)
+        with patch("src.api.admin.execution_gate") as mock_gate:
+            mock_gate.state.return_value = state
+
+            result = await get_scheduler_state(starved_only=True, limit=20, _="master-key")
+
+            assert result is state
+            mock_gate.state.assert_called_once_with(starved_only=True, limit=20)
+
+
+class TestQuarantine:
+    """Tests for the session quarantine endpoints."""
+
+    @pytest.mark.asyncio
+    async def test_quarantine_session(self):
+        """Test the request's reason and client address are recorded."""
+        record = QuarantineRecord(session_id="s1", quarantined_at=datetime.now(UTC), killed=1)
+        with patch("src.api.admin.get_quarantine_service") as mock_get_service:
+            mock_service = MagicMock()
+            mock_service.quarantine = AsyncMock(return_value=record)
+            mock_get_service.return_value = mock_service
+            request = MagicMock()
+            request.client.host = "10.0.0.5"
+
+            result = await quarantine_session(QuarantineRequest(session_id="s1", reason="exfil"), request, "master-key")
+
+            assert result is record
+            mock_service.quarantine.assert_called_once_with("s1", reason="exfil", actor="10.0.0.5")
+
+    @pytest.mark.asyncio
+    async def test_quarantine_unknown_session(self):
+        """Test 404 for a session that doesn't exist."""
+        with patch("src.api.admin.get_quarantine_service") as mock_get_service:
+            mock_service = MagicMock()
+            mock_service.quarantine = AsyncMock(return_value=None)
+            mock_get_service.return_value = mock_service
+
+            with pytest.raises(HTTPException) as exc_info:
+                await quarantine_session(QuarantineRequest(session_id="gone"), MagicMock(), "master-key")
+
+            assert exc_info.value.status_code == 404
+
+    @pytest.mark.asyncio
+    async def test_release_not_quarantined(self):
+        """Test 404 when lifting a quarantine that isn't there."""
+        with patch("src.api.admin.get_quarantine_service") as mock_get_service:
+            mock_service = MagicMock()
+            mock_service.release = AsyncMock(return_value=False)
+            mock_get_service.return_value = mock_service
+
+            with pytest.raises(HTTPException) as exc_info:
+                await release_session("s1", MagicMock(), None, "master-key")
+
+            assert exc_info.value.status_code == 404
+
+
+async def _chunks(*chunks: bytes):
+    for chunk in chunks:
+        yield chunk
+
+
+class TestSessionTransfer:
+    """Tests for the session export and import endpoints."""
+
+    @pytest.mark.asyncio
+    @pytest.mark.parametrize("code,status_code", [("session_exists", 409), ("unsafe_archive", 400)])
+    async def test_import_errors(self, code, status_code):
+        """Test an existing session is a conflict and a bad bundle a bad request."""
+        with patch("src.api.admin.get_session_transfer_service") as mock_get_service:
+            mock_service = MagicMock()
+            mock_service.import_bundle = AsyncMock(side_effect=SessionTransferError("nope", code=code))
+            mock_get_service.return_value = mock_service
+            request = MagicMock()
+            request.headers = {}
+            request.stream = lambda: _chunks(b"bundle")
+
+            with pytest.raises(HTTPException) as exc_info:
+                await import_session(request, "master-key")
+
+            assert exc_info.value.status_code == status_code
+            assert exc_info.value.detail["error"] == code
+
+    @pytest.mark.asyncio
+    async def test_import_refuses_oversized_bodies(self):
+        """Test a body past the bundle limit is refused before it is read or extracted."""
+        with (
+            patch("src.api.admin.get_session_transfer_service") as mock_get_service,
+            patch("src.api.admin.max_bundle_size", return_value=4),
+        ):
+            request = MagicMock()
+            request.headers = {}
+            request.stream = lambda: _chunks(b"bun", b"dle")
+
+            with pytest.raises(HTTPException) as exc_info:
+                await import_session(request, "master-key")
+
+            assert exc_info.value.status_code == 413
+            mock_get_service.return_value.import_bundle.assert_not_called()
+
+            request.headers = {"content-length": "100"}
+            request.stream = MagicMock()
+            with pytest.raises(HTTPException) as exc_info:
+                await import_session(request, "master-key")
+
+            assert exc_info.value.status_code == 413
+            request.stream.assert_not_called()
+
+
+class TestImageCatalog:
+    """Tests for the tenant image endpoints."""
+
+    @pytest.mark.asyncio
+    async def test_set_records_actor(self):
+        """Test the images are set on behalf of the client address and audited."""
+        data = TenantImages(images=["python:3.11-datasci"])
+        with (
+            patch("src.api.admin.get_image_catalog_service") as mock_get_service,
+            patch("src.api.admin.SecurityAudit") as mock_audit,
+        ):
+            mock_service = MagicMock()
+            mock_service.set_tenant = AsyncMock(return_value=MagicMock(images=data.images, pins={}))
+            mock_get_service.return_value = mock_service
+            request = MagicMock()
+            request.client.host = "10.0.0.5"
+
+            await set_tenant_images("keyhash", data, request, "master-key")
+
+            mock_service.set_tenant.assert_called_once_with("keyhash", data, actor="10.0.0.5")
+            mock_audit.log_tenant_images.assert_called_once_with(
+                "keyhash", "10.0.0.5", images=["python:3.11-datasci"], pins={}
+            )
+
+    @pytest.mark.asyncio
+    async def test_set_unknown_images(self):
+        """Test 400 with the error code for images that aren't in the catalog."""
+        with patch("src.api.admin.get_image_catalog_service") as mock_get_service:
+            mock_service = MagicMock()
+            mock_service.set_tenant = AsyncMock(side_effect=ImageCatalogError("invalid_images", "Unknown image x:1"))
+            mock_get_service.return_value = mock_service
+
+            with pytest.raises(HTTPException) as exc_info:
+                await set_tenant_images("keyhash", TenantImages(images=["x:1"]), MagicMock(), "master-key")
+
+            assert exc_info.value.status_code == 400
+            assert exc_info.value.detail["error"] == "invalid_images"
+
+    @pytest.mark.asyncio
+    async def test_delete_unknown_tenant(self):
+        """Test 404 when the key has no catalog images."""
+        with patch("src.api.admin.get_image_catalog_service") as mock_get_service:
+            mock_service = MagicMock()
+            mock_service.delete_tenant = AsyncMock(return_value=False)
+            mock_get_service.return_value = mock_service
+
+            with pytest.raises(HTTPException) as exc_info:
+                await delete_tenant_images("keyhash", MagicMock(), "master-key")
+
+            assert exc_info.value.status_code == 404
+
+
+class TestElevatedGrants:
+    """Tests for the elevated grant endpoints."""
+
+    @pytest.mark.asyncio
+    async def test_issue_records_approver(self):
+        """Test the grant is issued on behalf of the client address."""
+        data = ElevatedGrantRequest(capabilities=ElevatedCapabilities(network=True), reason="fetch dataset")
+        with patch("src.api.admin.get_elevation_service") as mock_get_service:
+            mock_service = MagicMock()
+            mock_service.issue = AsyncMock(return_value="issued")
+            mock_get_service.return_value = mock_service
+            request = MagicMock()
+            request.client.host = "10.0.0.5"
+
+            assert await issue_elevated_grant(data, request, "master-key") == "issued"
+            mock_service.issue.assert_called_once_with(data, actor="10.0.0.5")
+
+    @pytest.mark.asyncio
+    async def test_revoke_unknown_grant(self):
+        """Test 404 when revoking a grant that doesn't exist."""
+        with patch("src.api.admin.get_elevation_service") as mock_get_service:
+            mock_service = MagicMock()
+            mock_service.revoke = AsyncMock(return_value=False)
+            mock_get_service.return_value = mock_service
+
+            with pytest.raises(HTTPException) as exc_info:
+                await revoke_elevated_grant("gone", MagicMock(), None, "master-key")
+
+            assert exc_info.value.status_code == 404
+
+
 class TestModels:
     """Tests for admin API models."""
 
@@ -7,8 +7,8 @@ import pytest
 from fastapi import Request
 from fastapi.testclient import TestClient
 
-from src.api.exec import execute_code, router
-from src.models.exec import ExecRequest, ExecResponse
+from src.api.exec import execute_code, plan_execution, router
+from src.models.exec import ExecPlanLimits, ExecPlanResponse, ExecRequest, ExecResponse
 
 
 @pytest.fixture
@@ -150,6 +150,8 @@ class TestExecuteCodeEndpoint:
             stderr="",
         )
 
+        mock_workspace_lock_service = MagicMock()
+
         with patch("src.api.exec.ExecutionOrchestrator") as MockOrchestrator:
             mock_orchestrator = MagicMock()
             mock_orchestrator.execute = AsyncMock(return_value=expected_response)
@@ -163,6 +165,7 @@ class TestExecuteCodeEndpoint:
                 execution_service=mock_execution_service,
                 state_service=mock_state_service,
                 state_archival_service=mock_state_archival_service,
+                workspace_lock_service=mock_workspace_lock_service,
             )
 
             # Verify orchestrator was created with all services
@@ -172,6 +175,7 @@ class TestExecuteCodeEndpoint:
                 execution_service=mock_execution_service,
                 state_service=mock_state_service,
                 state_archival_service=mock_state_archival_service,
+                workspace_lock_service=mock_workspace_lock_service,
             )
 
     @pytest.mark.asyncio
@@ -297,3 +301,46 @@ class TestExecuteCodeEndpoint:
         call_args = mock_orchestrator.execute.call_args
         assert call_args.args[0].entity_id == "entity-123"
         assert call_args.args[0].user_id == "user-456"
+
+
+class TestPlanExecutionEndpoint:
+    """Tests for plan_execution endpoint."""
+
+    @pytest.mark.asyncio
+    async def test_plan_passes_api_key_info(
+        self, mock_request, mock_session_service, mock_file_service, mock_execution_service
+    ):
+        """Test the plan is made with the caller's API key and nothing is executed."""
+        exec_request = ExecRequest(code="print('hello')", lang="py")
+        expected_plan = ExecPlanResponse(
+            allowed=True,
+            language="py",
+            working_directory="/mnt/data",
+            limits=ExecPlanLimits(
+                timeout_seconds=30,
+                cpu_limit="500m",
+                memory_limit="512Mi",
+                max_output_files=10,
+                max_file_size_mb=10,
+                network_isolated=True,
+            ),
+        )
+
+        with patch("src.api.exec.ExecutionOrchestrator") as MockOrchestrator:
+            mock_orchestrator = MagicMock()
+            mock_orchestrator.plan = AsyncMock(return_value=expected_plan)
+            MockOrchestrator.return_value = mock_orchestrator
+
+            response = await plan_execution(
+                request=exec_request,
+                http_request=mock_request,
+                session_service=mock_session_service,
+                file_service=mock_file_service,
+                execution_service=mock_execution_service,
+            )
+
+        assert response is expected_plan
+        call_kwargs = mock_orchestrator.plan.call_args.kwargs
+        assert call_kwargs["api_key_hash"] == "abc123hash"
+        assert call_kwargs["is_env_key"] is False
+        mock_orchestrator.execute.assert_not_called()
@@ -1,5 +1,8 @@
 """Unit tests for Files API endpoints."""
 
+import hashlib
+import io
+import zipfile
 from datetime import UTC, datetime
 from unittest.mock import AsyncMock, MagicMock, patch
 
@@ -12,9 +15,13 @@ from src.api.files import (
     delete_file,
     download_file,
     download_file_options,
+    extract_file,
+    get_file_checksum,
     list_files,
     upload_file,
+    write_to_file,
 )
+from src.models.files import ArchiveExtractRequest
 
 
 @pytest.fixture
@@ -39,6 +46,14 @@ def mock_session_service():
     return service
 
 
+@pytest.fixture
+def mock_quarantine_service():
+    """Create a mock quarantine service with no quarantined sessions."""
+    service = MagicMock()
+    service.is_quarantined = AsyncMock(return_value=False)
+    return service
+
+
 @pytest.fixture
 def mock_upload_file():
     """Create a mock UploadFile."""
@@ -63,6 +78,18 @@ def mock_file_info():
     return info
 
 
+def body_request(data: bytes, content_length: int | None = None):
+    """A mock request whose raw body is ``data``."""
+    request = MagicMock()
+    request.headers = {"content-length": str(len(data) if content_length is None else content_length)}
+
+    async def stream():
+        yield data
+
+    request.stream = stream
+    return request
+
+
 class TestAsciiFilename:
     """Tests for _ascii_fallback_filename helper."""
 
@@ -256,6 +283,228 @@ class TestUploadFile:
                 assert exc_info.value.status_code == 500
 
 
+class TestUploadChecksums:
+    """Tests for checksum verification on upload."""
+
+    @pytest.mark.asyncio
+    async def test_matching_checksum(self, mock_file_service, mock_session_service, mock_upload_file):
+        """Test a matching checksum is verified and returned."""
+        digest = hashlib.sha256(b"test content").hexdigest()
+
+        result = await upload_file(
+            file=mock_upload_file,
+            files=None,
+            entity_id=None,
+            checksum=[f"sha256:{digest}"],
+            file_service=mock_file_service,
+            session_service=mock_session_service,
+        )
+
+        assert result["files"][0]["checksum"] == f"sha256:{digest}"
+        mock_file_service.store_uploaded_file.assert_called_once()
+
+    @pytest.mark.asyncio
+    async def test_mismatch_stores_nothing(self, mock_file_service, mock_session_service, mock_upload_file):
+        """Test a mismatch fails the upload before anything is stored."""
+        with pytest.raises(HTTPException) as exc_info:
+            await upload_file(
+                file=mock_upload_file,
+                files=None,
+                entity_id=None,
+                checksum=["sha256:" + "0" * 64],
+                file_service=mock_file_service,
+                session_service=mock_session_service,
+            )
+
+        assert exc_info.value.status_code == 422
+        assert exc_info.value.detail["error"] == "checksum_mismatch"
+        mock_session_service.create_session.assert_not_called()
+        mock_file_service.store_uploaded_file.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_checksum_count_must_match_files(self, mock_file_service, mock_session_service, mock_upload_file):
+        """Test one checksum is required per file."""
+        digest = hashlib.sha256(b"test content").hexdigest()
+
+        with pytest.raises(HTTPException) as exc_info:
+            await upload_file(
+                file=None,
+                files=[mock_upload_file, mock_upload_file],
+                entity_id=None,
+                checksum=[digest],
+                file_service=mock_file_service,
+                session_service=mock_session_service,
+            )
+
+        assert exc_info.value.status_code == 400
+        assert exc_info.value.detail["error"] == "invalid_checksum"
+
+    @pytest.mark.asyncio
+    async def test_malformed_checksum(self, mock_file_service, mock_session_service, mock_upload_file):
+        """Test a malformed checksum is rejected."""
+        with pytest.raises(HTTPException) as exc_info:
+            await upload_file(
+                file=mock_upload_file,
+                files=None,
+                entity_id=None,
+                checksum=["sha256:abc"],
+                file_service=mock_file_service,
+                session_service=mock_session_service,
+            )
+
+        assert exc_info.value.status_code == 400
+
+
+def _zip(entries: dict[str, bytes]) -> bytes:
+    buffer = io.BytesIO()
+    with zipfile.ZipFile(buffer, "w", zipfile.ZIP_DEFLATED) as archive:
+        for name, data in entries.items():
+            archive.writestr(name, data)
+    return buffer.getvalue()
+
+
+class TestExtractFile:
+    """Tests for extract_file endpoint."""
+
+    @pytest.fixture
+    def extract_settings(self):
+        with patch("src.api.files.settings") as mock_settings:
+            mock_settings.archive_max_entries = 100
+            mock_settings.archive_max_compression_ratio = 100
+            mock_settings.max_files_per_session = 10
+            mock_settings.max_file_size_mb = 1
+            mock_settings.max_total_file_size_mb = 10
+            yield mock_settings
+
+    @pytest.mark.asyncio
+    async def test_extract(self, mock_file_service, mock_quarantine_service, mock_file_info, extract_settings):
+        """Test members are stored under flattened workspace names."""
+        mock_file_service.get_file_info.return_value = mock_file_info
+        mock_file_service.get_file_content.return_value = _zip({"data/2024/a b.csv": b"a,b\n", "README": b"hi"})
+        mock_file_service.list_files.return_value = [mock_file_info]
+
+        result = await extract_file(
+            ArchiveExtractRequest(session_id="session-123", file_id="file-123", delete_archive=True),
+            file_service=mock_file_service,
+            quarantine_service=mock_quarantine_service,
+        )
+
+        assert [(f.filename, f.archive_path) for f in result.files] == [
+            ("data_2024_a_b.csv", "data/2024/a b.csv"),
+            ("README", "README"),
+        ]
+        assert mock_file_service.store_uploaded_file.call_count == 2
+        mock_file_service.delete_file.assert_awaited_once_with("session-123", "file-123")
+
+    @pytest.mark.asyncio
+    async def test_colliding_names(self, mock_file_service, mock_quarantine_service, mock_file_info, extract_settings):
+        """Test members that flatten to the same name are all kept under unique names."""
+        mock_file_service.get_file_info.return_value = mock_file_info
+        mock_file_service.get_file_content.return_value = _zip({"a/b.txt": b"1", "a_b.txt": b"2"})
+        mock_file_service.list_files.return_value = [mock_file_info]
+
+        result = await extract_file(
+            ArchiveExtractRequest(session_id="session-123", file_id="file-123"),
+            file_service=mock_file_service,
+            quarantine_service=mock_quarantine_service,
+        )
+
+        assert [(f.filename, f.archive_path) for f in result.files] == [
+            ("a_b.txt", "a/b.txt"),
+            ("a_b-2.txt", "a_b.txt"),
+        ]
+        stored = [call.kwargs["filename"] for call in mock_file_service.store_uploaded_file.call_args_list]
+        assert stored == ["a_b.txt", "a_b-2.txt"]
+
+    @pytest.mark.asyncio
+    async def test_unsafe_archive(self, mock_file_service, mock_quarantine_service, mock_file_info, extract_settings):
+        """Test zip slip rejects the archive and stores nothing."""
+        mock_file_service.get_file_info.return_value = mock_file_info
+        mock_file_service.get_file_content.return_value = _zip({"../evil.sh": b"x"})
+
+        with pytest.raises(HTTPException) as exc_info:
+            await extract_file(
+                ArchiveExtractRequest(session_id="session-123", file_id="file-123"),
+                file_service=mock_file_service,
+                quarantine_service=mock_quarantine_service,
+            )
+
+        assert exc_info.value.status_code == 400
+        assert exc_info.value.detail["error"] == "unsafe_archive"
+        mock_file_service.store_uploaded_file.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_session_file_limit(
+        self, mock_file_service, mock_quarantine_service, mock_file_info, extract_settings
+    ):
+        """Test extraction can't take the session past its file limit."""
+        extract_settings.max_files_per_session = 2
+        mock_file_service.get_file_info.return_value = mock_file_info
+        mock_file_service.get_file_content.return_value = _zip({"a": b"1", "b": b"2"})
+        mock_file_service.list_files.return_value = [mock_file_info]
+
+        with pytest.raises(HTTPException) as exc_info:
+            await extract_file(
+                ArchiveExtractRequest(session_id="session-123", file_id="file-123"),
+                file_service=mock_file_service,
+                quarantine_service=mock_quarantine_service,
+            )
+
+        assert exc_info.value.status_code == 413
+
+    @pytest.mark.asyncio
+    async def test_archive_not_found(self, mock_file_service, mock_quarantine_service):
+        """Test a missing archive raises 404."""
+        with pytest.raises(HTTPException) as exc_info:
+            await extract_file(
+                ArchiveExtractRequest(session_id="session-123", file_id="missing"),
+                file_service=mock_file_service,
+                quarantine_service=mock_quarantine_service,
+            )
+
+        assert exc_info.value.status_code == 404
+
+    @pytest.mark.asyncio
+    async def test_quarantined_session(self, mock_file_service, mock_quarantine_service):
+        """Test archives can't be unpacked into a quarantined session."""
+        mock_quarantine_service.is_quarantined.return_value = True
+
+        with pytest.raises(HTTPException) as exc_info:
+            await extract_file(
+                ArchiveExtractRequest(session_id="session-123", file_id="file-123"),
+                file_service=mock_file_service,
+                quarantine_service=mock_quarantine_service,
+            )
+
+        assert exc_info.value.status_code == 409
+        mock_quarantine_service.is_quarantined.assert_awaited_once_with("session-123")
+        mock_file_service.get_file_content.assert_not_called()
+
+
+class TestGetFileChecksum:
+    """Tests for get_file_checksum endpoint."""
+
+    @pytest.mark.asyncio
+    async def test_checksum(self, mock_file_service, mock_file_info):
+        """Test the checksum of a stored file."""
+        mock_file_service.get_file_info.return_value = mock_file_info
+        mock_file_service.get_file_content.return_value = b"test content"
+
+        result = await get_file_checksum("session-123", "file-123", algo="md5", file_service=mock_file_service)
+
+        assert result["algo"] == "md5"
+        assert result["checksum"] == hashlib.md5(b"test content").hexdigest()
+        assert result["size"] == 12
+
+    @pytest.mark.asyncio
+    async def test_checksum_not_found(self, mock_file_service):
+        """Test checksum of a missing file raises 404."""
+        with pytest.raises(HTTPException) as exc_info:
+            await get_file_checksum("session-123", "missing", algo="sha256", file_service=mock_file_service)
+
+        assert exc_info.value.status_code == 404
+
+
 class TestListFiles:
     """Tests for list_files endpoint."""
 
@@ -472,3 +721,56 @@ class TestDeleteFile:
             )
 
         assert exc_info.value.status_code == 500
+
+
+class TestWriteToFile:
+    """Tests for appending to and writing into stored files."""
+
+    @pytest.mark.asyncio
+    async def test_append(self, mock_file_service, mock_file_info):
+        """The body is appended and the new size returned."""
+        mock_file_service.get_file_info.return_value = mock_file_info
+        updated = MagicMock(filename="test.txt", size=105)
+        mock_file_service.write_file_range = AsyncMock(return_value=updated)
+
+        response = await write_to_file("session-123", "file-123", body_request(b"hello"), None, 100, mock_file_service)
+
+        mock_file_service.write_file_range.assert_awaited_once_with("session-123", "file-123", b"hello", None, 100)
+        assert response == {"id": "file-123", "name": "test.txt", "size": 105, "written": 5}
+
+    @pytest.mark.asyncio
+    async def test_missing_file(self, mock_file_service):
+        """Writes to an unknown file are 404s."""
+        with pytest.raises(HTTPException) as exc_info:
+            await write_to_file("session-123", "gone", body_request(b"x"), None, None, mock_file_service)
+        assert exc_info.value.status_code == 404
+
+    @pytest.mark.asyncio
+    async def test_declared_body_over_limit(self, mock_file_service, mock_file_info):
+        """A Content-Length over the file size limit is refused before reading."""
+        mock_file_service.get_file_info.return_value = mock_file_info
+        mock_file_service.write_file_range = AsyncMock()
+
+        with patch("src.api.files.settings") as mock_settings:
+            mock_settings.max_file_size_mb = 1
+            with pytest.raises(HTTPException) as exc_info:
+                request = body_request(b"x", content_length=2 * 1024 * 1024)
+                await write_to_file("session-123", "file-123", request, None, None, mock_file_service)
+
+        assert exc_info.value.status_code == 413
+        mock_file_service.write_file_range.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_result_over_limit(self, mock_file_service, mock_file_info):
+        """An append that would take the file past the size limit is refused."""
+        mock_file_info.size = 1024 * 1024 - 2
+        mock_file_service.get_file_info.return_value = mock_file_info
+        mock_file_service.write_file_range = AsyncMock()
+
+        with patch("src.api.files.settings") as mock_settings:
+            mock_settings.max_file_size_mb = 1
+            with pytest.raises(HTTPException) as exc_info:
+                await write_to_file("session-123", "file-123", body_request(b"abc"), None, None, mock_file_service)
+
+        assert exc_info.value.status_code == 413
+        mock_file_service.write_file_range.assert_not_called()
@@ -0,0 +1,836 @@
+"""Unit tests for Session API endpoints."""
+
+from datetime import UTC, datetime
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+from fastapi import HTTPException
+
+from src.api.sessions import (
+    REDACTED_VALUE,
+    complete_session_code,
+    diff_session_cell,
+    export_session,
+    export_session_dataframe,
+    get_session_cell,
+    get_session_env,
+    get_session_report,
+    get_session_status,
+    get_session_variables,
+    interrupt_session,
+    list_session_cells,
+    list_session_changes,
+    list_workspace_locks,
+    lock_workspace_path,
+    rerun_session_cell,
+    restart_session,
+    set_session_env,
+    terminate_session,
+    unlock_workspace_path,
+)
+from src.models.cell import CellDiffRequest, CellInfo
+from src.models.env_snapshot import ChangeSet, EnvironmentChanges
+from src.models.exec import ExecResponse, FileRef, RequestFile
+from src.models.session import (
+    CompletionRequest,
+    CompletionResponse,
+    Session,
+    SessionEnvUpdate,
+    SessionStatus,
+    SessionVariablesResponse,
+    WorkspaceLockRequest,
+)
+from src.models.session_report import SessionReport
+
+
+@pytest.fixture
+def mock_session_service():
+    """Create a mock session service with an existing session."""
+    service = MagicMock()
+    service.get_session = AsyncMock(return_value=MagicMock())
+    service.set_session_env = AsyncMock(return_value=True)
+    service.get_session_env = AsyncMock(return_value={})
+    service.record_restart = AsyncMock(return_value=1)
+    return service
+
+
+@pytest.fixture
+def mock_lock_service():
+    """Create a mock workspace lock service that grants locks."""
+    service = MagicMock()
+    service.acquire = AsyncMock(return_value=("lock-token", []))
+    service.release = AsyncMock(return_value=True)
+    service.list_locks = AsyncMock(return_value=[])
+    return service
+
+
+class TestSetSessionEnv:
+    """Tests for PUT /sessions/{id}/env."""
+
+    @pytest.mark.asyncio
+    async def test_set_env_redacts_values(self, mock_session_service):
+        """Stored env is echoed back with values redacted."""
+        request = SessionEnvUpdate(env={"API_KEY": "sk-secret", "REGION": "eu"})
+
+        response = await set_session_env("session-123", request, mock_session_service)
+
+        mock_session_service.set_session_env.assert_called_once_with("session-123", request.env)
+        assert response.env == {"API_KEY": REDACTED_VALUE, "REGION": REDACTED_VALUE}
+        assert "sk-secret" not in response.model_dump_json()
+
+    @pytest.mark.asyncio
+    async def test_set_env_audit_omits_values(self, mock_session_service):
+        """The audit record lists names only."""
+        request = SessionEnvUpdate(env={"API_KEY": "sk-secret"})
+
+        with patch("src.api.sessions.SecurityAudit") as mock_audit:
+            await set_session_env("session-123", request, mock_session_service)
+
+        mock_audit.log_session_env_update.assert_called_once_with("session-123", ["API_KEY"], success=True)
+
+    @pytest.mark.asyncio
+    async def test_set_env_invalid_name(self, mock_session_service):
+        """Names that aren't identifiers are rejected."""
+        request = SessionEnvUpdate(env={"BAD-NAME": "1"})
+
+        with pytest.raises(HTTPException) as exc_info:
+            await set_session_env("session-123", request, mock_session_service)
+
+        assert exc_info.value.status_code == 400
+        assert exc_info.value.detail["error"] == "invalid_env_name"
+        mock_session_service.set_session_env.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_set_env_too_many_vars(self, mock_session_service):
+        """The per-session variable limit is enforced."""
+        request = SessionEnvUpdate(env={"A": "1", "B": "2"})
+
+        with patch("src.api.sessions.settings") as mock_settings:
+            mock_settings.max_session_env_vars = 1
+            mock_settings.max_session_env_value_length = 100
+
+            with pytest.raises(HTTPException) as exc_info:
+                await set_session_env("session-123", request, mock_session_service)
+
+        assert exc_info.value.detail["error"] == "too_many_env_vars"
+
+    @pytest.mark.asyncio
+    async def test_set_env_value_too_long(self, mock_session_service):
+        """Oversized values are rejected by name."""
+        request = SessionEnvUpdate(env={"BLOB": "x" * 11})
+
+        with patch("src.api.sessions.settings") as mock_settings:
+            mock_settings.max_session_env_vars = 10
+            mock_settings.max_session_env_value_length = 10
+
+            with pytest.raises(HTTPException) as exc_info:
+                await set_session_env("session-123", request, mock_session_service)
+
+        assert exc_info.value.detail["error"] == "env_value_too_long"
+        assert "BLOB" in exc_info.value.detail["message"]
+
+    @pytest.mark.asyncio
+    async def test_set_env_session_not_found(self, mock_session_service):
+        """Unknown sessions return 404."""
+        mock_session_service.get_session.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await set_session_env("missing", SessionEnvUpdate(env={"A": "1"}), mock_session_service)
+
+        assert exc_info.value.status_code == 404
+
+
+class TestGetSessionEnv:
+    """Tests for GET /sessions/{id}/env."""
+
+    @pytest.mark.asyncio
+    async def test_get_env_never_returns_values(self, mock_session_service):
+        """Only names are exposed."""
+        mock_session_service.get_session_env.return_value = {"API_KEY": "sk-secret"}
+
+        response = await get_session_env("session-123", mock_session_service)
+
+        assert response.session_id == "session-123"
+        assert response.env == {"API_KEY": REDACTED_VALUE}
+
+    @pytest.mark.asyncio
+    async def test_get_env_session_not_found(self, mock_session_service):
+        """Unknown sessions return 404."""
+        mock_session_service.get_session.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await get_session_env("missing", mock_session_service)
+
+        assert exc_info.value.status_code == 404
+
+
+class TestWorkspaceLocks:
+    """Tests for the /sessions/{id}/locks endpoints."""
+
+    @pytest.mark.asyncio
+    async def test_lock_path(self, mock_session_service, mock_lock_service):
+        """A free path is locked without waiting."""
+        request = WorkspaceLockRequest(path="/mnt/data/out/", ttl_seconds=120)
+
+        response = await lock_workspace_path("session-123", request, mock_session_service, mock_lock_service)
+
+        mock_lock_service.acquire.assert_called_once_with("session-123", ["out"], owner="client", ttl_seconds=120)
+        assert response.token == "lock-token"
+        assert response.paths == ["out"]
+
+    @pytest.mark.asyncio
+    async def test_lock_whole_workspace(self, mock_session_service, mock_lock_service):
+        """'/' locks the workspace root."""
+        response = await lock_workspace_path(
+            "session-123", WorkspaceLockRequest(path="/"), mock_session_service, mock_lock_service
+        )
+
+        assert mock_lock_service.acquire.call_args[0][1] == [""]
+        assert response.paths == ["/"]
+
+    @pytest.mark.asyncio
+    async def test_lock_conflict(self, mock_session_service, mock_lock_service):
+        """Overlapping locks return 409 with the held paths."""
+        mock_lock_service.acquire.return_value = (
+            None,
+            [{"paths": [""], "owner": "execution", "expires_at": 1700000000.0}],
+        )
+
+        with pytest.raises(HTTPException) as exc_info:
+            await lock_workspace_path(
+                "session-123", WorkspaceLockRequest(path="out"), mock_session_service, mock_lock_service
+            )
+
+        assert exc_info.value.status_code == 409
+        assert exc_info.value.detail["locks"][0]["paths"] == ["/"]
+        assert exc_info.value.detail["locks"][0]["owner"] == "execution"
+
+    @pytest.mark.asyncio
+    async def test_lock_invalid_path(self, mock_session_service, mock_lock_service):
+        """Paths escaping the workspace are rejected."""
+        with pytest.raises(HTTPException) as exc_info:
+            await lock_workspace_path(
+                "session-123", WorkspaceLockRequest(path="../etc"), mock_session_service, mock_lock_service
+            )
+
+        assert exc_info.value.status_code == 400
+        mock_lock_service.acquire.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_lock_ttl_capped(self, mock_session_service, mock_lock_service):
+        """TTLs above the configured maximum are rejected."""
+        with patch("src.api.sessions.settings") as mock_settings:
+            mock_settings.workspace_lock_max_ttl_seconds = 60
+
+            with pytest.raises(HTTPException) as exc_info:
+                await lock_workspace_path(
+                    "session-123",
+                    WorkspaceLockRequest(path="out", ttl_seconds=61),
+                    mock_session_service,
+                    mock_lock_service,
+                )
+
+        assert exc_info.value.detail["error"] == "ttl_too_long"
+
+    @pytest.mark.asyncio
+    async def test_lock_session_not_found(self, mock_session_service, mock_lock_service):
+        """Unknown sessions return 404."""
+        mock_session_service.get_session.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await lock_workspace_path(
+                "missing", WorkspaceLockRequest(path="out"), mock_session_service, mock_lock_service
+            )
+
+        assert exc_info.value.status_code == 404
+
+    @pytest.mark.asyncio
+    async def test_list_locks(self, mock_session_service, mock_lock_service):
+        """Held locks are listed without tokens."""
+        mock_lock_service.list_locks.return_value = [
+            {"paths": ["out"], "owner": "client", "expires_at": 1700000000.0},
+        ]
+
+        locks = await list_workspace_locks("session-123", mock_session_service, mock_lock_service)
+
+        assert len(locks) == 1
+        assert locks[0].paths == ["out"]
+        assert "token" not in locks[0].model_dump()
+
+    @pytest.mark.asyncio
+    async def test_unlock(self, mock_lock_service):
+        """Releasing a held lock succeeds."""
+        result = await unlock_workspace_path("session-123", "lock-token", mock_lock_service)
+
+        mock_lock_service.release.assert_called_once_with("session-123", "lock-token")
+        assert result == {"released": True}
+
+    @pytest.mark.asyncio
+    async def test_unlock_not_held(self, mock_lock_service):
+        """Releasing an unknown or expired lock returns 404."""
+        mock_lock_service.release.return_value = False
+
+        with pytest.raises(HTTPException) as exc_info:
+            await unlock_workspace_path("session-123", "stale", mock_lock_service)
+
+        assert exc_info.value.status_code == 404
+
+
+class TestInterruptSession:
+    """Tests for POST /sessions/{id}/interrupt."""
+
+    @pytest.mark.asyncio
+    async def test_interrupt_running_execution(self, mock_session_service):
+        """The running execution is interrupted."""
+        execution_service = MagicMock()
+        execution_service.interrupt_session = AsyncMock(return_value=1)
+
+        result = await interrupt_session("session-123", mock_session_service, execution_service)
+
+        execution_service.interrupt_session.assert_called_once_with("session-123")
+        assert result == {"session_id": "session-123", "interrupted": 1}
+
+    @pytest.mark.asyncio
+    async def test_interrupt_idle_session(self, mock_session_service):
+        """Interrupting an idle session is a conflict."""
+        execution_service = MagicMock()
+        execution_service.interrupt_session = AsyncMock(return_value=0)
+
+        with pytest.raises(HTTPException) as exc_info:
+            await interrupt_session("session-123", mock_session_service, execution_service)
+
+        assert exc_info.value.status_code == 409
+        assert exc_info.value.detail["error"] == "no_running_execution"
+
+    @pytest.mark.asyncio
+    async def test_interrupt_session_not_found(self, mock_session_service):
+        """Unknown sessions return 404."""
+        mock_session_service.get_session.return_value = None
+        execution_service = MagicMock()
+        execution_service.interrupt_session = AsyncMock(return_value=0)
+
+        with pytest.raises(HTTPException) as exc_info:
+            await interrupt_session("missing", mock_session_service, execution_service)
+
+        assert exc_info.value.status_code == 404
+        execution_service.interrupt_session.assert_not_called()
+
+
+class TestGetSessionStatus:
+    """Tests for GET /sessions/{id}."""
+
+    @pytest.mark.asyncio
+    async def test_status_includes_restarts(self, mock_session_service):
+        """Restart count and time are reported."""
+        restarted = datetime(2024, 1, 1, tzinfo=UTC)
+        mock_session_service.get_session.return_value = Session(
+            session_id="session-123",
+            expires_at=datetime(2024, 1, 2, tzinfo=UTC),
+            restart_count=2,
+            last_restart_at=restarted,
+        )
+
+        response = await get_session_status("session-123", mock_session_service)
+
+        assert response.status == SessionStatus.ACTIVE
+        assert response.restart_count == 2
+        assert response.model_dump()["last_restart_at"] == restarted.isoformat()
+
+    @pytest.mark.asyncio
+    async def test_status_not_found(self, mock_session_service):
+        """Unknown sessions return 404."""
+        mock_session_service.get_session.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await get_session_status("missing", mock_session_service)
+
+        assert exc_info.value.status_code == 404
+
+
+class TestSessionReport:
+    """Tests for DELETE /sessions/{id} and GET /sessions/{id}/report."""
+
+    @pytest.fixture
+    def report_service(self):
+        service = MagicMock()
+        service.get = AsyncMock(return_value=SessionReport(session_id="session-123", reason="deleted"))
+        service.build = AsyncMock(return_value=SessionReport(session_id="session-123"))
+        return service
+
+    @pytest.mark.asyncio
+    async def test_terminate_returns_report(self, mock_session_service, report_service):
+        """The session is deleted and the report made as it ended is returned."""
+        mock_session_service.delete_session = AsyncMock(return_value=True)
+
+        response = await terminate_session("session-123", mock_session_service, report_service)
+
+        mock_session_service.delete_session.assert_called_once_with("session-123", reason="deleted")
+        assert response.terminated is True
+        assert response.report.reason == "deleted"
+
+    @pytest.mark.asyncio
+    async def test_terminate_not_found(self, mock_session_service, report_service):
+        mock_session_service.get_session.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await terminate_session("missing", mock_session_service, report_service)
+
+        assert exc_info.value.status_code == 404
+
+    @pytest.mark.asyncio
+    async def test_active_session_report_so_far(self, mock_session_service, report_service):
+        """An active session gets a live report."""
+        response = await get_session_report("session-123", mock_session_service, report_service)
+
+        assert response.ended_at is None
+        report_service.build.assert_called_once_with("session-123", mock_session_service.get_session.return_value)
+        report_service.get.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_terminated_session_report(self, mock_session_service, report_service):
+        """A terminated session's report is returned while it's retained, then 404."""
+        mock_session_service.get_session.return_value = None
+
+        response = await get_session_report("session-123", mock_session_service, report_service)
+        assert response.reason == "deleted"
+
+        report_service.get.return_value = None
+        with pytest.raises(HTTPException) as exc_info:
+            await get_session_report("session-123", mock_session_service, report_service)
+        assert exc_info.value.status_code == 404
+
+    @pytest.mark.asyncio
+    async def test_reports_disabled(self, mock_session_service, report_service):
+        with patch("src.api.sessions.settings") as mock_settings:
+            mock_settings.session_report_enabled = False
+            with pytest.raises(HTTPException) as exc_info:
+                await get_session_report("session-123", mock_session_service, report_service)
+
+        assert exc_info.value.status_code == 404
+
+
+class TestRestartSession:
+    """Tests for POST /sessions/{id}/restart."""
+
+    @pytest.fixture
+    def services(self, mock_session_service, mock_lock_service):
+        execution_service = MagicMock()
+        execution_service.interrupt_session = AsyncMock(return_value=1)
+        state_service = MagicMock()
+        state_service.delete_state = AsyncMock(return_value=True)
+        archival_service = MagicMock()
+        archival_service.delete_archived_state = AsyncMock(return_value=True)
+        return mock_session_service, execution_service, state_service, archival_service, mock_lock_service
+
+    @pytest.mark.asyncio
+    async def test_restart_clears_state(self, services):
+        """Running code is interrupted, state is cleared and the restart counted."""
+        session_service, execution_service, state_service, archival_service, lock_service = services
+
+        with patch("src.api.sessions.settings") as mock_settings:
+            mock_settings.session_lock_wait_seconds = 5
+            mock_settings.state_archive_enabled = True
+            response = await restart_session("session-123", *services)
+
+        execution_service.interrupt_session.assert_called_once_with("session-123")
+        state_service.delete_state.assert_called_once_with("session-123")
+        archival_service.delete_archived_state.assert_called_once_with("session-123")
+        session_service.record_restart.assert_called_once_with("session-123")
+        assert lock_service.acquire.call_args.args[1] == [""]
+        assert lock_service.acquire.call_args.kwargs["wait_seconds"] == 5
+        lock_service.release.assert_called_once_with("session-123", "lock-token")
+        assert response.restart_count == 1
+        assert response.interrupted == 1
+
+    @pytest.mark.asyncio
+    async def test_restart_workspace_busy(self, services):
+        """If the workspace stays locked nothing is cleared."""
+        session_service, _, state_service, _, lock_service = services
+        expires = datetime(2024, 1, 1, tzinfo=UTC).timestamp()
+        lock_service.acquire.return_value = (None, [{"paths": [""], "owner": "client", "expires_at": expires}])
+
+        with patch("src.api.sessions.settings") as mock_settings:
+            mock_settings.session_lock_wait_seconds = 0
+            with pytest.raises(HTTPException) as exc_info:
+                await restart_session("session-123", *services)
+
+        assert exc_info.value.status_code == 409
+        assert exc_info.value.detail["error"] == "session_busy"
+        state_service.delete_state.assert_not_called()
+        session_service.record_restart.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_restart_releases_lock_on_error(self, services):
+        """The restart lock is released even if clearing state fails."""
+        _, _, state_service, _, lock_service = services
+        state_service.delete_state.side_effect = RuntimeError("redis down")
+
+        with patch("src.api.sessions.settings") as mock_settings:
+            mock_settings.session_lock_wait_seconds = 0
+            mock_settings.state_archive_enabled = False
+            with pytest.raises(RuntimeError):
+                await restart_session("session-123", *services)
+
+        lock_service.release.assert_called_once_with("session-123", "lock-token")
+
+    @pytest.mark.asyncio
+    async def test_restart_session_not_found(self, services):
+        """Unknown sessions return 404 without touching the kernel."""
+        session_service, execution_service, _, _, _ = services
+        session_service.get_session.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await restart_session("missing", *services)
+
+        assert exc_info.value.status_code == 404
+        execution_service.interrupt_session.assert_not_called()
+
+
+class TestGetSessionVariables:
+    """Tests for GET /sessions/{id}/variables."""
+
+    @pytest.mark.asyncio
+    async def test_variables_listed(self, mock_session_service):
+        """The inspector's summary is returned."""
+        inspector = MagicMock()
+        inspector.inspect = AsyncMock(return_value=SessionVariablesResponse(session_id="session-123", has_state=False))
+
+        response = await get_session_variables("session-123", mock_session_service, inspector)
+
+        inspector.inspect.assert_called_once_with("session-123")
+        assert response.has_state is False
+
+    @pytest.mark.asyncio
+    async def test_variables_session_not_found(self, mock_session_service):
+        """Unknown sessions return 404 without inspecting anything."""
+        mock_session_service.get_session.return_value = None
+        inspector = MagicMock()
+        inspector.inspect = AsyncMock()
+
+        with pytest.raises(HTTPException) as exc_info:
+            await get_session_variables("missing", mock_session_service, inspector)
+
+        assert exc_info.value.status_code == 404
+        inspector.inspect.assert_not_called()
+
+
+class TestExportSessionDataframe:
+    """Tests for GET /sessions/{id}/dataframes/{name}."""
+
+    @pytest.fixture
+    def inspector(self):
+        inspector = MagicMock()
+        inspector.export_dataframe = AsyncMock(
+            return_value={"total_rows": 10, "rows": 1, "columns": ["a"], "dtypes": {"a": "int64"}, "data": [{"a": 1}]}
+        )
+        return inspector
+
+    @pytest.mark.asyncio
+    async def test_export_json(self, mock_session_service, inspector):
+        """JSON exports are returned as records."""
+        response = await export_session_dataframe(
+            "session-123", "df", mock_session_service, inspector, rows=1, format="json", sample="head"
+        )
+
+        inspector.export_dataframe.assert_called_once_with("session-123", "df", 1, "json", "head")
+        assert response.total_rows == 10
+        assert response.data == [{"a": 1}]
+
+    @pytest.mark.asyncio
+    async def test_export_csv_file(self, mock_session_service, inspector):
+        """CSV exports are returned as a download."""
+        inspector.export_dataframe.return_value = {"total_rows": 10, "rows": 1, "data": "a\n1\n"}
+
+        response = await export_session_dataframe(
+            "session-123", "df", mock_session_service, inspector, rows=1, format="csv", sample="tail"
+        )
+
+        assert response.media_type == "text/csv"
+        assert response.body == b"a\n1\n"
+        assert response.headers["x-total-rows"] == "10"
+        assert 'filename="df.csv"' in response.headers["content-disposition"]
+
+    @pytest.mark.asyncio
+    @pytest.mark.parametrize(
+        ("name", "params", "error"),
+        [
+            ("df; import os", {}, "invalid_name"),
+            ("df", {"format": "xlsx"}, "invalid_format"),
+            ("df", {"sample": "middle"}, "invalid_sample"),
+            ("df", {"rows": 10_001}, "too_many_rows"),
+        ],
+    )
+    async def test_export_invalid_params(self, mock_session_service, inspector, name, params, error):
+        """Parameters are checked before anything runs."""
+        kwargs = {"rows": 50, "format": "json", "sample": "head", **params}
+
+        with patch("src.api.sessions.settings") as mock_settings:
+            mock_settings.state_export_max_rows = 10_000
+            with pytest.raises(HTTPException) as exc_info:
+                await export_session_dataframe("session-123", name, mock_session_service, inspector, **kwargs)
+
+        assert exc_info.value.status_code == 400
+        assert exc_info.value.detail["error"] == error
+        inspector.export_dataframe.assert_not_called()
+
+
+class TestCompleteSessionCode:
+    """Tests for POST /sessions/{id}/complete."""
+
+    @pytest.fixture
+    def inspector(self):
+        inspector = MagicMock()
+        inspector.complete = AsyncMock(return_value=CompletionResponse(cursor_start=0, cursor_end=3))
+        return inspector
+
+    @pytest.mark.asyncio
+    async def test_cursor_defaults_to_end(self, mock_session_service, inspector):
+        """Without cursor_pos the code is completed at its end."""
+        await complete_session_code("session-123", CompletionRequest(code="pri"), mock_session_service, inspector)
+
+        inspector.complete.assert_called_once_with("session-123", "pri", 3)
+
+    @pytest.mark.asyncio
+    async def test_cursor_past_end(self, mock_session_service, inspector):
+        """A cursor beyond the code is rejected."""
+        request = CompletionRequest(code="pri", cursor_pos=10)
+
+        with pytest.raises(HTTPException) as exc_info:
+            await complete_session_code("session-123", request, mock_session_service, inspector)
+
+        assert exc_info.value.status_code == 400
+        inspector.complete.assert_not_called()
+
+
+def _cell(cell_id=1, **overrides):
+    fields = {
+        "code": "print(1)",
+        "lang": "py",
+        "status": "completed",
+        "started_at": datetime(2025, 1, 1, tzinfo=UTC),
+    }
+    fields.update(overrides)
+    return CellInfo(cell_id=cell_id, **fields)
+
+
+@pytest.fixture
+def mock_cell_service():
+    """Create a mock cell history service."""
+    service = MagicMock()
+    service.list_cells = AsyncMock(return_value=[_cell(1), _cell(2)])
+    service.get_cell = AsyncMock(return_value=_cell(1))
+    return service
+
+
+class TestSessionChanges:
+    """Tests for the environment changes endpoint."""
+
+    @pytest.mark.asyncio
+    async def test_list_changes(self, mock_session_service):
+        """Changes are listed for existing sessions with the filters passed on."""
+        change = EnvironmentChanges(
+            cell_id=2, previous_cell_id=1, taken_at=datetime(2025, 1, 1, tzinfo=UTC), env=ChangeSet(added=["MODE"])
+        )
+        service = MagicMock()
+        service.list_changes = AsyncMock(return_value=[change])
+
+        response = await list_session_changes("session-123", mock_session_service, service, limit=5, changed_only=True)
+
+        assert response.session_id == "session-123"
+        assert response.changes == [change]
+        service.list_changes.assert_called_once_with("session-123", limit=5, changed_only=True)
+
+    @pytest.mark.asyncio
+    async def test_list_changes_session_not_found(self, mock_session_service):
+        mock_session_service.get_session.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await list_session_changes("missing", mock_session_service, MagicMock(), limit=None, changed_only=False)
+
+        assert exc_info.value.status_code == 404
+
+
+class TestSessionCells:
+    """Tests for the session cell history endpoints."""
+
+    @pytest.mark.asyncio
+    async def test_list_cells(self, mock_session_service, mock_cell_service):
+        """Cells are listed for existing sessions."""
+        cells = await list_session_cells("session-123", mock_session_service, mock_cell_service, limit=10)
+
+        assert [c.cell_id for c in cells] == [1, 2]
+        mock_cell_service.list_cells.assert_called_once_with("session-123", limit=10)
+
+    @pytest.mark.asyncio
+    async def test_list_cells_session_not_found(self, mock_session_service, mock_cell_service):
+        mock_session_service.get_session.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await list_session_cells("missing", mock_session_service, mock_cell_service, limit=None)
+
+        assert exc_info.value.status_code == 404
+
+    @pytest.mark.asyncio
+    async def test_get_cell_not_found(self, mock_cell_service):
+        mock_cell_service.get_cell.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await get_session_cell("session-123", 9, mock_cell_service)
+
+        assert exc_info.value.status_code == 404
+        assert exc_info.value.detail["error"] == "cell_not_found"
+
+    @pytest.mark.asyncio
+    async def test_rerun_cell(self, mock_session_service, mock_cell_service):
+        """A re-run replays the cell's request and points back to it."""
+        mock_session_service.get_session.return_value = MagicMock(metadata={"user_id": "u1", "entity_id": "e1"})
+        mock_cell_service.get_cell.return_value = _cell(
+            3, args=["-v"], scope=["src"], input_files=[RequestFile(id="f1", session_id="session-123", name="a.txt")]
+        )
+        http_request = MagicMock()
+        http_request.state.api_key_hash = "hash"
+        http_request.state.is_env_key = False
+
+        with patch("src.api.sessions.ExecutionOrchestrator") as mock_orchestrator_cls:
+            mock_orchestrator_cls.return_value.execute = AsyncMock(return_value="response")
+            result = await rerun_session_cell(
+                "session-123",
+                3,
+                http_request,
+                mock_session_service,
+                MagicMock(),
+                MagicMock(),
+                MagicMock(),
+                MagicMock(),
+                mock_cell_service,
+            )
+
+        assert result == "response"
+        execute = mock_orchestrator_cls.return_value.execute
+        request = execute.call_args[0][0]
+        assert request.code == "print(1)"
+        assert request.args == ["-v"]
+        assert request.scope == ["src"]
+        assert request.files[0].id == "f1"
+        assert request.session_id == "session-123"
+        assert request.user_id == "u1"
+        assert request.entity_id == "e1"
+        assert execute.call_args.kwargs["rerun_of"] == 3
+        assert execute.call_args.kwargs["api_key_hash"] == "hash"
+
+    @pytest.mark.asyncio
+    async def test_rerun_missing_cell(self, mock_session_service, mock_cell_service):
+        mock_cell_service.get_cell.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await rerun_session_cell(
+                "session-123",
+                9,
+                MagicMock(),
+                mock_session_service,
+                MagicMock(),
+                MagicMock(),
+                MagicMock(),
+                MagicMock(),
+                mock_cell_service,
+            )
+
+        assert exc_info.value.status_code == 404
+
+
+class TestDiffSessionCell:
+    """Tests for POST /sessions/{id}/cells/{n}/diff."""
+
+    @pytest.mark.asyncio
+    async def test_diff_against_previous_run(self, mock_session_service, mock_cell_service):
+        """Modified code runs with the cell's settings and the outputs are diffed."""
+        mock_session_service.get_session.return_value = MagicMock(metadata={})
+        mock_cell_service.get_cell.return_value = _cell(
+            3, stdout="1\n", args=["-v"], files=[FileRef(id="old", name="plot.png")]
+        )
+        file_service = MagicMock()
+        file_service.get_file_content = AsyncMock(side_effect=lambda sid, fid: {"old": b"a", "new": b"b"}[fid])
+        ctx = MagicMock()
+        ctx.cell = _cell(4, code="print(2)", stdout="2\n", files=[FileRef(id="new", name="plot.png")])
+        ctx.response = ExecResponse(session_id="session-123", stdout="2\n")
+
+        with patch("src.api.sessions.ExecutionOrchestrator") as mock_orchestrator_cls:
+            mock_orchestrator_cls.return_value.run = AsyncMock(return_value=ctx)
+            result = await diff_session_cell(
+                "session-123",
+                3,
+                CellDiffRequest(code="print(2)"),
+                MagicMock(),
+                mock_session_service,
+                file_service,
+                MagicMock(),
+                MagicMock(),
+                MagicMock(),
+                mock_cell_service,
+            )
+
+        run = mock_orchestrator_cls.return_value.run
+        request = run.call_args[0][0]
+        assert request.code == "print(2)"
+        assert request.args == ["-v"]
+        assert run.call_args.kwargs["rerun_of"] == 3
+        assert result.previous_cell_id == 3
+        assert result.cell_id == 4
+        assert result.diff.stdout.changed is True
+        assert "-1\n+2\n" in result.diff.stdout.diff
+        assert result.diff.files.modified == ["plot.png"]
+        assert result.diff.status.changed is False
+
+    @pytest.mark.asyncio
+    async def test_diff_missing_cell(self, mock_session_service, mock_cell_service):
+        mock_cell_service.get_cell.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await diff_session_cell(
+                "session-123",
+                9,
+                CellDiffRequest(code="x"),
+                MagicMock(),
+                mock_session_service,
+                MagicMock(),
+                MagicMock(),
+                MagicMock(),
+                MagicMock(),
+                mock_cell_service,
+            )
+
+        assert exc_info.value.status_code == 404
+
+
+class TestExportSession:
+    """Tests for GET /sessions/{id}/export."""
+
+    @pytest.mark.asyncio
+    async def test_export_notebook(self, mock_session_service, mock_cell_service):
+        """The cell history is returned as a downloadable notebook."""
+        file_service = MagicMock()
+        file_service.get_file_content = AsyncMock(return_value=None)
+
+        response = await export_session("session-123", mock_session_service, file_service, mock_cell_service, "ipynb")
+
+        assert response.media_type == "application/x-ipynb+json"
+        assert response.headers["content-disposition"] == 'attachment; filename="session-session-123.ipynb"'
+        assert b'"execution_count": 2' in response.body
+
+    @pytest.mark.asyncio
+    async def test_export_script_skips_images(self, mock_session_service, mock_cell_service):
+        file_service = MagicMock()
+        file_service.get_file_content = AsyncMock()
+
+        response = await export_session("session-123", mock_session_service, file_service, mock_cell_service, "py")
+
+        assert b"# %% In [1]" in response.body
+        file_service.get_file_content.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_invalid_format(self, mock_session_service, mock_cell_service):
+        with pytest.raises(HTTPException) as exc_info:
+            await export_session("session-123", mock_session_service, MagicMock(), mock_cell_service, "pdf")
+
+        assert exc_info.value.status_code == 400
+        mock_cell_service.list_cells.assert_not_called()
@@ -0,0 +1,129 @@
+"""Unit tests for safe archive extraction."""
+
+import io
+import tarfile
+import zipfile
+
+import pytest
+
+from src.services.archive import (
+    ArchiveError,
+    ArchiveLimits,
+    extract_archive,
+    flat_name,
+    safe_member_path,
+    unique_name,
+)
+
+LIMITS = ArchiveLimits(max_entries=100, max_files=10, max_file_size=1_000_000, max_total_size=5_000_000, max_ratio=100)
+
+
+def make_zip(entries: dict[str, bytes], compression=zipfile.ZIP_DEFLATED) -> bytes:
+    buffer = io.BytesIO()
+    with zipfile.ZipFile(buffer, "w", compression) as archive:
+        for name, data in entries.items():
+            archive.writestr(name, data)
+    return buffer.getvalue()
+
+
+def make_tar(entries: dict[str, bytes], mode: str = "w:gz") -> bytes:
+    buffer = io.BytesIO()
+    with tarfile.open(fileobj=buffer, mode=mode) as archive:
+        for name, data in entries.items():
+            info = tarfile.TarInfo(name)
+            info.size = len(data)
+            archive.addfile(info, io.BytesIO(data))
+    return buffer.getvalue()
+
+
+class TestSafeMemberPath:
+    @pytest.mark.parametrize("name", ["../evil", "/etc/passwd", "a/../../b", "C:/windows", "..\\evil"])
+    def test_rejects_traversal(self, name):
+        with pytest.raises(ArchiveError) as exc_info:
+            safe_member_path(name)
+
+        assert exc_info.value.code == "unsafe_archive"
+
+    def test_normalizes(self):
+        assert safe_member_path("./data//a.csv") == "data/a.csv"
+        assert safe_member_path("data/") is None
+
+
+class TestExtractArchive:
+    def test_zip(self):
+        result = extract_archive(make_zip({"data/a.csv": b"a,b\n", "dir/": b"", "b.txt": b"hi"}), LIMITS)
+
+        assert result.files == [("data/a.csv", b"a,b\n"), ("b.txt", b"hi")]
+        assert result.skipped == []
+
+    @pytest.mark.parametrize("mode", ["w", "w:gz", "w:bz2", "w:xz"])
+    def test_tar(self, mode):
+        result = extract_archive(make_tar({"x/y.txt": b"hello"}, mode), LIMITS)
+
+        assert result.files == [("x/y.txt", b"hello")]
+
+    def test_zip_slip_rejects_archive(self):
+        with pytest.raises(ArchiveError) as exc_info:
+            extract_archive(make_zip({"ok.txt": b"x", "../../etc/cron.d/job": b"x"}), LIMITS)
+
+        assert exc_info.value.code == "unsafe_archive"
+
+    def test_links_are_skipped(self):
+        buffer = io.BytesIO()
+        with tarfile.open(fileobj=buffer, mode="w") as archive:
+            link = tarfile.TarInfo("passwd")
+            link.type = tarfile.SYMTYPE
+            link.linkname = "/etc/passwd"
+            archive.addfile(link)
+        buffer_zip = io.BytesIO()
+        with zipfile.ZipFile(buffer_zip, "w") as archive:
+            info = zipfile.ZipInfo("link")
+            info.external_attr = 0o120777 << 16
+            archive.writestr(info, "/etc/passwd")
+
+        assert extract_archive(buffer.getvalue(), LIMITS).skipped == ["passwd"]
+        assert extract_archive(buffer_zip.getvalue(), LIMITS).skipped == ["link"]
+
+    def test_compression_ratio_bomb(self):
+        with pytest.raises(ArchiveError) as exc_info:
+            extract_archive(make_zip({"bomb": b"\0" * 900_000}), LIMITS)
+
+        assert exc_info.value.code == "archive_limit_exceeded"
+
+    def test_file_size_limit(self):
+        limits = ArchiveLimits(max_entries=100, max_files=10, max_file_size=10, max_total_size=1000, max_ratio=1000)
+
+        with pytest.raises(ArchiveError, match="maximum file size"):
+            extract_archive(make_zip({"big": b"x" * 11}, zipfile.ZIP_STORED), limits)
+
+    def test_file_count_limit(self):
+        limits = ArchiveLimits(max_entries=100, max_files=2, max_file_size=100, max_total_size=1000, max_ratio=1000)
+
+        with pytest.raises(ArchiveError, match="more than 2 files"):
+            extract_archive(make_zip({f"{i}.txt": b"x" for i in range(3)}), limits)
+
+    def test_entry_limit(self):
+        limits = ArchiveLimits(max_entries=2, max_files=10, max_file_size=100, max_total_size=1000, max_ratio=1000)
+
+        with pytest.raises(ArchiveError, match="more than 2 entries"):
+            extract_archive(make_tar({f"{i}.txt": b"x" for i in range(3)}), limits)
+
+    def test_not_an_archive(self):
+        with pytest.raises(ArchiveError) as exc_info:
+            extract_archive(b"garbage" * 10, LIMITS)
+
+        assert exc_info.value.code == "invalid_archive"
+
+
+def test_flat_name():
+    assert flat_name("data/2024/a.csv") == "data_2024_a.csv"
+
+
+def test_unique_name():
+    taken = set()
+    assert [unique_name(flat_name(p), taken) for p in ["a/b.txt", "a_b.txt", "a/b.txt", "README"]] == [
+        "a_b.txt",
+        "a_b-2.txt",
+        "a_b-3.txt",
+        "README",
+    ]
@@ -0,0 +1,62 @@
+"""Unit tests for generated-file content types and metadata."""
+
+import struct
+
+import pytest
+
+from src.services.artifact_metadata import describe_artifact, image_size, pdf_page_count, sniff_content_type
+
+PNG = b"\x89PNG\r\n\x1a\n" + struct.pack(">I", 13) + b"IHDR" + struct.pack(">IIBBBBB", 640, 480, 8, 6, 0, 0, 0)
+JPEG = (
+    b"\xff\xd8"
+    + b"\xff\xe0"
+    + struct.pack(">H", 16)
+    + b"JFIF\x00" + b"\x00" * 9
+    + b"\xff\xc0"
+    + struct.pack(">HBHH", 17, 8, 200, 300)
+    + b"\x00" * 10
+)
+PDF = b"%PDF-1.7\n1 0 obj << /Type /Pages /Kids [2 0 R 3 0 R] /Count 2 >> endobj\n" + (
+    b"2 0 obj << /Type /Page /Parent 1 0 R >> endobj\n3 0 obj << /Type /Page /Parent 1 0 R >> endobj\n"
+)
+
+
+class TestSniffContentType:
+    @pytest.mark.parametrize(
+        "filename,content,expected",
+        [
+            ("chart", PNG, "image/png"),
+            ("photo.bin", JPEG, "image/jpeg"),
+            ("report.txt", PDF, "application/pdf"),
+            ("book.xlsx", b"PK\x03\x04rest", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"),
+            ("bundle", b"PK\x03\x04rest", "application/zip"),
+            ("drawing", b'<?xml version="1.0"?>\n<svg xmlns="http://www.w3.org/2000/svg"/>', "image/svg+xml"),
+            ("data.csv", b"a,b\n1,2\n", "text/csv"),
+            ("notes", "héllo".encode(), "text/plain"),
+            ("blob", b"\x00\xff\xfe\x80" * 100, "application/octet-stream"),
+        ],
+    )
+    def test_types(self, filename, content, expected):
+        assert sniff_content_type(filename, content) == expected
+
+
+class TestMetadata:
+    def test_image_sizes(self):
+        assert image_size(PNG) == (640, 480)
+        assert image_size(JPEG) == (300, 200)
+        assert image_size(b"GIF89a" + struct.pack("<HH", 32, 16)) == (32, 16)
+        assert image_size(b"\x89PNG\r\n\x1a\n") is None
+
+    def test_pdf_pages(self):
+        assert pdf_page_count(PDF) == 2
+        assert pdf_page_count(b"%PDF-1.7\n<< /Type /Pages /Count 12 >>") == 12
+        assert pdf_page_count(b"%PDF-1.7\nstream...") is None
+
+    def test_describe(self):
+        assert describe_artifact("plot.png", PNG) == ("image/png", {"width": 640, "height": 480})
+        assert describe_artifact("out.csv", b"a;b;c\n1;2;3\n") == ("text/csv", {"columns": 3})
+        assert describe_artifact("doc.pdf", PDF) == ("application/pdf", {"pages": 2})
+        assert describe_artifact("log.txt", b"hello") == ("text/plain", {})
+
+    def test_text_starting_like_bmp(self):
+        assert describe_artifact("notes", b"BMW sales were up") == ("text/plain", {})
@@ -0,0 +1,224 @@
+"""Unit tests for session cell history."""
+
+from datetime import UTC, datetime
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from src.models.cell import CellInfo
+from src.models.exec import FileRef
+from src.services.cells import (
+    CellHistoryService,
+    diff_cells,
+    diff_files,
+    diff_text,
+    file_digests,
+    truncate_output,
+)
+
+
+@pytest.fixture
+def mock_pipeline():
+    """Create a mock transactional pipeline."""
+    pipe = MagicMock()
+    pipe.execute = AsyncMock(return_value=[])
+    pipe.reset = AsyncMock()
+    return pipe
+
+
+@pytest.fixture
+def mock_redis(mock_pipeline):
+    """Create a mock Redis client."""
+    client = MagicMock()
+    client.pipeline = AsyncMock(return_value=mock_pipeline)
+    client.incr = AsyncMock(return_value=1)
+    client.lrange = AsyncMock(return_value=[])
+    return client
+
+
+@pytest.fixture
+def cell_service(mock_redis):
+    """Create a cell history service with mocked Redis."""
+    return CellHistoryService(redis_client=mock_redis)
+
+
+@pytest.fixture
+def mock_settings():
+    with patch("src.services.cells.settings") as mock:
+        mock.session_cell_history_limit = 200
+        mock.session_cell_output_max_chars = 10
+        mock.get_session_ttl_minutes.return_value = 60
+        yield mock
+
+
+def _cell(**overrides):
+    cell = {
+        "execution_id": "exec-1",
+        "code": "print('hi')",
+        "lang": "py",
+        "status": "completed",
+        "exit_code": 0,
+        "stdout": "hi\n",
+        "stderr": "",
+        "started_at": datetime(2025, 1, 1, tzinfo=UTC),
+        "duration_ms": 12,
+    }
+    cell.update(overrides)
+    return cell
+
+
+def _stored(cell_id, **overrides):
+    return CellInfo(cell_id=cell_id, **_cell(**overrides)).model_dump_json()
+
+
+class TestTruncateOutput:
+    """Tests for output truncation."""
+
+    def test_short_text_untouched(self):
+        assert truncate_output("abc", 10) == ("abc", False)
+
+    def test_long_text_cut(self):
+        assert truncate_output("abcdef", 4) == ("abcd", True)
+
+
+class TestRecord:
+    """Tests for recording cells."""
+
+    @pytest.mark.asyncio
+    async def test_record_numbers_and_stores(self, cell_service, mock_redis, mock_pipeline, mock_settings):
+        """Cells are numbered from the counter and appended, trimmed and expired."""
+        mock_redis.incr.return_value = 7
+
+        info = await cell_service.record("session-123", _cell())
+
+        assert info.cell_id == 7
+        assert info.output_truncated is False
+        mock_redis.incr.assert_called_once_with("session:cells:session-123:count")
+        mock_pipeline.rpush.assert_called_once()
+        key, payload = mock_pipeline.rpush.call_args[0]
+        assert key == "session:cells:session-123"
+        assert CellInfo.model_validate_json(payload).cell_id == 7
+        mock_pipeline.ltrim.assert_called_once_with("session:cells:session-123", -200, -1)
+        mock_pipeline.expire.assert_any_call("session:cells:session-123", 3600)
+        mock_pipeline.expire.assert_any_call("session:cells:session-123:count", 3600)
+        mock_pipeline.reset.assert_called_once()
+
+    @pytest.mark.asyncio
+    async def test_record_truncates_output(self, cell_service, mock_settings):
+        """Long stdout/stderr is cut to SESSION_CELL_OUTPUT_MAX_CHARS."""
+        info = await cell_service.record("session-123", _cell(stdout="x" * 50, stderr="err"))
+
+        assert info.stdout == "x" * 10
+        assert info.stderr == "err"
+        assert info.output_truncated is True
+
+    @pytest.mark.asyncio
+    async def test_record_disabled(self, cell_service, mock_redis, mock_settings):
+        """A history limit of 0 disables recording."""
+        mock_settings.session_cell_history_limit = 0
+
+        assert await cell_service.record("session-123", _cell()) is None
+        mock_redis.incr.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_restore_keeps_numbers(self, cell_service, mock_pipeline, mock_settings):
+        """Imported cells keep their numbers and new cells continue after the last one."""
+        mock_settings.session_cell_history_limit = 2
+        cells = [CellInfo(cell_id=n, **_cell()) for n in (9, 3, 5)]
+
+        assert await cell_service.restore("session-123", cells) == 2
+
+        key, *payloads = mock_pipeline.rpush.call_args[0]
+        assert key == "session:cells:session-123"
+        assert [CellInfo.model_validate_json(p).cell_id for p in payloads] == [5, 9]
+        mock_pipeline.set.assert_called_once_with("session:cells:session-123:count", 9, ex=3600)
+
+
+class TestListCells:
+    """Tests for listing and fetching cells."""
+
+    @pytest.mark.asyncio
+    async def test_list_cells(self, cell_service, mock_redis):
+        """Cells come back oldest first."""
+        mock_redis.lrange.return_value = [_stored(1), _stored(2, code="x = 1")]
+
+        cells = await cell_service.list_cells("session-123")
+
+        assert [c.cell_id for c in cells] == [1, 2]
+        assert cells[1].code == "x = 1"
+        mock_redis.lrange.assert_called_once_with("session:cells:session-123", 0, -1)
+
+    @pytest.mark.asyncio
+    async def test_list_recent_cells(self, cell_service, mock_redis):
+        """A limit reads only the tail of the list."""
+        await cell_service.list_cells("session-123", limit=5)
+
+        mock_redis.lrange.assert_called_once_with("session:cells:session-123", -5, -1)
+
+    @pytest.mark.asyncio
+    async def test_corrupt_records_skipped(self, cell_service, mock_redis):
+        """Records that don't parse are skipped."""
+        mock_redis.lrange.return_value = ["not json", '{"cell_id": 2}', _stored(3)]
+
+        cells = await cell_service.list_cells("session-123")
+
+        assert [c.cell_id for c in cells] == [3]
+
+    @pytest.mark.asyncio
+    async def test_get_cell(self, cell_service, mock_redis):
+        """Cells are found by number; missing ones return None."""
+        mock_redis.lrange.return_value = [_stored(4), _stored(5)]
+
+        assert (await cell_service.get_cell("session-123", 5)).cell_id == 5
+        assert await cell_service.get_cell("session-123", 1) is None
+
+
+class TestDiff:
+    """Tests for comparing a cell with a modified re-run."""
+
+    def test_unchanged_text(self):
+        assert diff_text("same\n", "same\n", "stdout").model_dump() == {"changed": False, "diff": ""}
+
+    def test_text_diff(self):
+        diff = diff_text("a\nb\n", "a\nc", "stdout")
+
+        assert diff.changed is True
+        assert diff.diff == "--- previous/stdout\n+++ current/stdout\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n"
+
+    def test_file_changes(self):
+        """Files are matched by name and compared by digest."""
+        changes = diff_files(
+            {"kept.png": "aa", "edited.csv": "bb", "gone.txt": "cc", "unread.bin": None},
+            {"kept.png": "aa", "edited.csv": "xx", "new.txt": "dd", "unread.bin": None},
+        )
+
+        assert changes.added == ["new.txt"]
+        assert changes.removed == ["gone.txt"]
+        assert changes.modified == ["edited.csv", "unread.bin"]
+        assert changes.unchanged == ["kept.png"]
+
+    @pytest.mark.asyncio
+    async def test_file_digests(self):
+        file_service = MagicMock()
+        file_service.get_file_content = AsyncMock(side_effect=[b"abc", Exception("MinIO down")])
+
+        digests = await file_digests(
+            file_service, "session-123", [FileRef(id="f1", name="a.txt"), FileRef(id="f2", name="b.txt")]
+        )
+
+        assert digests == {
+            "a.txt": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+            "b.txt": None,
+        }
+
+    def test_diff_cells(self):
+        previous = CellInfo(cell_id=1, **_cell(exit_code=1, status="failed", stderr="boom\n"))
+        current = CellInfo(cell_id=2, **_cell(duration_ms=12))
+
+        diff = diff_cells(previous, current, {}, {})
+
+        assert diff.status.model_dump() == {"previous": "failed", "current": "completed", "changed": True}
+        assert diff.exit_code.changed is True
+        assert diff.duration_ms.changed is False
+        assert diff.stdout.changed is False
+        assert diff.stderr.changed is True
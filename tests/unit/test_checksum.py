@@ -0,0 +1,44 @@
+"""Unit tests for file checksums."""
+
+import hashlib
+
+import pytest
+
+from src.utils.checksum import compute_checksum, parse_checksum, verify_checksum
+
+CONTENT = b"a,b\n1,2\n"
+SHA256 = hashlib.sha256(CONTENT).hexdigest()
+
+
+class TestComputeChecksum:
+    @pytest.mark.parametrize("algo", ["md5", "sha1", "sha256", "sha512"])
+    def test_algorithms(self, algo):
+        assert compute_checksum(CONTENT, algo) == hashlib.new(algo, CONTENT).hexdigest()
+
+    def test_unsupported(self):
+        with pytest.raises(ValueError, match="Unsupported"):
+            compute_checksum(CONTENT, "crc32")
+
+
+class TestParseChecksum:
+    def test_prefixed(self):
+        assert parse_checksum(f"SHA256:{SHA256.upper()}") == ("sha256", SHA256)
+
+    def test_bare_digest_is_sha256(self):
+        assert parse_checksum(SHA256) == ("sha256", SHA256)
+
+    @pytest.mark.parametrize("value", ["sha256:abc", f"md5:{SHA256}", "blake3:00", "sha256:" + "z" * 64])
+    def test_invalid(self, value):
+        with pytest.raises(ValueError):
+            parse_checksum(value)
+
+
+class TestVerifyChecksum:
+    def test_match(self):
+        assert verify_checksum(CONTENT, f"sha256:{SHA256}") == (True, f"sha256:{SHA256}")
+
+    def test_mismatch(self):
+        matches, actual = verify_checksum(CONTENT, "md5:" + "0" * 32)
+
+        assert matches is False
+        assert actual == f"md5:{hashlib.md5(CONTENT).hexdigest()}"
@@ -0,0 +1,142 @@
+"""Unit tests for execution slot wait statistics and starvation alerts."""
+
+import asyncio
+import time
+from types import SimpleNamespace
+from unittest.mock import patch
+
+import pytest
+
+from src.models.errors import ServiceUnavailableError
+from src.services import concurrency
+from src.services.concurrency import ExecutionGate, percentile
+
+
+@pytest.fixture
+def mock_settings():
+    values = SimpleNamespace(queue_wait_history_size=10, queue_wait_alert_p95_ms=1000, queue_wait_alert_min_samples=3)
+    with patch("src.services.concurrency.settings", values):
+        yield values
+
+
+def waited(gate, session_id, seconds):
+    """Record a slot request that waited ``seconds``."""
+    gate._record_wait(session_id, time.monotonic() - seconds)
+
+
+class TestPercentile:
+    """Tests for the nearest-rank percentile."""
+
+    def test_nearest_rank(self):
+        values = list(range(1, 21))
+
+        assert percentile(values, 95) == 19
+        assert percentile(values, 50) == 10
+        assert percentile([7], 95) == 7
+        assert percentile([], 95) == 0
+
+
+class TestSchedulerState:
+    """Tests for the state reported by GET /admin/scheduler."""
+
+    @pytest.mark.asyncio
+    async def test_waiting_executions_are_listed(self, mock_settings):
+        gate = ExecutionGate(limit=lambda: 1)
+        release = asyncio.Event()
+
+        async def hold(session_id):
+            async with gate.slot(timeout=5, session_id=session_id):
+                await release.wait()
+
+        holder = asyncio.create_task(hold("busy"))
+        await asyncio.sleep(0.01)
+        waiter = asyncio.create_task(hold("patient"))
+        await asyncio.sleep(0.02)
+
+        state = gate.state()
+        assert (state.limit, state.running, state.waiting) == (1, 1, 1)
+        assert [q.session_id for q in state.queued] == ["patient"]
+        assert state.queued[0].waiting_ms >= 10
+
+        release.set()
+        await asyncio.gather(holder, waiter)
+        state = gate.state()
+        assert state.queued == [] and state.slot_requests == 2
+        patient = next(s for s in state.sessions if s.session_id == "patient")
+        assert patient.executions == 1 and patient.max_wait_ms >= 10
+
+    def test_sessions_sorted_by_p95(self, mock_settings):
+        gate = ExecutionGate(limit=lambda: 10)
+        waited(gate, "fast", 0)
+        waited(gate, "slow", 0.5)
+        waited(gate, "slow", 0.2)
+
+        state = gate.state()
+
+        assert [s.session_id for s in state.sessions] == ["slow", "fast"]
+        assert state.sessions[0].executions == 2
+        assert state.sessions[0].p95_wait_ms >= 500
+        assert len(gate.state(limit=1).sessions) == 1
+
+    @pytest.mark.asyncio
+    async def test_timed_out_waits_are_counted(self, mock_settings):
+        gate = ExecutionGate(limit=lambda: 0)
+
+        with pytest.raises(ServiceUnavailableError):
+            async with gate.slot(timeout=0.01, session_id="s1"):
+                pass
+
+        state = gate.state()
+        assert state.slot_timeouts == 1 and state.waiting == 0
+        assert state.sessions[0].session_id == "s1"
+
+    def test_history_is_bounded(self, mock_settings):
+        gate = ExecutionGate(limit=lambda: 10)
+        for _ in range(15):
+            waited(gate, "s1", 0)
+
+        assert gate.state().sessions[0].executions == 10
+
+    def test_least_recent_sessions_are_dropped(self, mock_settings):
+        gate = ExecutionGate(limit=lambda: 10)
+        with patch.object(concurrency, "MAX_TRACKED_SESSIONS", 2):
+            for session_id in ("a", "b", "c"):
+                waited(gate, session_id, 0)
+
+        assert sorted(s.session_id for s in gate.state().sessions) == ["b", "c"]
+
+
+class TestStarvationAlerts:
+    """Tests for alerting on sessions that wait too long."""
+
+    def test_alerts_once_until_waits_recover(self, mock_settings):
+        gate = ExecutionGate(limit=lambda: 10)
+        with patch.object(concurrency, "logger") as mock_logger:
+            for _ in range(4):
+                waited(gate, "s1", 2)
+
+            assert gate.starvation_alerts == 1
+            mock_logger.warning.assert_called_once()
+            assert mock_logger.warning.call_args.kwargs["session_id"] == "s1"
+            assert gate.state(starved_only=True).sessions[0].starved
+
+            for _ in range(10):
+                waited(gate, "s1", 0)
+
+        assert gate.state(starved_only=True).sessions == []
+        mock_logger.info.assert_called_once()
+
+    def test_needs_min_samples(self, mock_settings):
+        gate = ExecutionGate(limit=lambda: 10)
+        waited(gate, "s1", 2)
+        waited(gate, "s1", 2)
+
+        assert gate.starvation_alerts == 0
+
+    def test_disabled(self, mock_settings):
+        mock_settings.queue_wait_alert_p95_ms = 0
+        gate = ExecutionGate(limit=lambda: 10)
+        for _ in range(5):
+            waited(gate, "s1", 2)
+
+        assert gate.starvation_alerts == 0
@@ -128,6 +128,37 @@ class TestValidateSecurityConfig:
 
         assert any("Filesystem isolation" in w for w in validator.warnings)
 
+    def test_fault_injection_warning(self):
+        """Test warning when fault injection is enabled."""
+        validator = ConfigValidator()
+
+        with patch("src.utils.config_validator.settings") as mock_settings:
+            mock_settings.allowed_file_extensions = [".txt"]
+            mock_settings.enable_network_isolation = True
+            mock_settings.enable_filesystem_isolation = True
+            mock_settings.fault_injection = "drop=0.1"
+
+            validator._validate_security_config()
+
+        assert any("Fault injection is enabled (drop=0.1)" in w for w in validator.warnings)
+
+    def test_unpinned_catalog_image_warning(self):
+        """Test warning for catalog images referenced by tag instead of digest."""
+        validator = ConfigValidator()
+
+        with patch("src.utils.config_validator.settings") as mock_settings:
+            mock_settings.allowed_file_extensions = [".txt"]
+            mock_settings.enable_network_isolation = True
+            mock_settings.enable_filesystem_isolation = True
+            mock_settings.image_catalog = {
+                "python:3.11-datasci": MagicMock(image="registry/py-datasci:3.11"),
+                "python:3.12": MagicMock(image="registry/py@sha256:" + "0" * 64),
+            }
+
+            validator._validate_security_config()
+
+        assert any("not pinned by digest: python:3.11-datasci -" in w for w in validator.warnings)
+
 
 class TestValidateResourceLimits:
     """Tests for _validate_resource_limits method."""
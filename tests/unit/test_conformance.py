@@ -0,0 +1,150 @@
+"""Unit tests for the sidecar API conformance checks."""
+
+import gzip
+import json
+
+import httpx
+import pytest
+
+from src.services.kubernetes.conformance import MARKER, run_conformance
+
+
+class FakeSidecar:
+    """In-memory implementation of the sidecar API; ``missing`` paths return 404."""
+
+    def __init__(self, missing=(), timeout_exit_code=124):
+        self.files = {}
+        self.workspaces = {}
+        self.missing = set(missing)
+        self.timeout_exit_code = timeout_exit_code
+
+    def execute(self, body):
+        if body["code"] == "hang":
+            return {"exit_code": self.timeout_exit_code, "stdout": "", "stderr": "timed out", "execution_time_ms": 2000}
+        if body["code"] != "ok":
+            return {"exit_code": 1, "stdout": "", "stderr": "SyntaxError", "execution_time_ms": 5}
+        return {
+            "exit_code": 0,
+            "stdout": f"{MARKER}\n",
+            "stderr": "",
+            "execution_time_ms": 5,
+            "state": "c3RhdGU=" if body.get("capture_state") else None,
+        }
+
+    def upload(self, request):
+        name = request.content.split(b'filename="')[1].split(b'"')[0].decode()
+        content = request.content.split(b"\r\n\r\n", 1)[1].rsplit(b"\r\n--", 1)[0]
+        workspace = request.url.params.get("workspace")
+        if workspace and len(content) > self.workspaces[workspace]["quota_bytes"]:
+            return httpx.Response(413, json={"detail": "Workspace quota exceeded"})
+        self.files[name] = content
+        return httpx.Response(200, json={"uploaded": [{"name": name, "path": name, "size": len(content)}]})
+
+    def handler(self, request):
+        path, method = request.url.path, request.method
+        if path in self.missing:
+            return httpx.Response(404, json={"detail": "Not Found"})
+        name = path.removeprefix("/files/")
+        if path == "/health":
+            return httpx.Response(200, json={"status": "healthy", "language": "py"})
+        if path == "/ready":
+            return httpx.Response(200, json={"degraded": False})
+        if path == "/execute":
+            return httpx.Response(200, json=self.execute(json.loads(request.content)))
+        if path == "/files" and method == "POST":
+            return self.upload(request)
+        if path == "/files":
+            return httpx.Response(200, json={"files": [{"name": n, "size": len(c)} for n, c in self.files.items()]})
+        if path == "/interrupt":
+            return httpx.Response(200, json={"interrupted": 0})
+        if path == "/kill":
+            return httpx.Response(200, json={"killed": 0})
+        if path == "/files/patch":
+            for n in self.files:
+                self.files[n] = self.files[n].replace(b"appended", b"patched")
+            return httpx.Response(200, json={"applied": True, "files": list(self.files)})
+        if path == "/files/search":
+            hits = [n for n, c in self.files.items() if request.url.params["q"].encode() in c]
+            return httpx.Response(200, json={"matches": [{"path": n} for n in hits]})
+        if path == "/sync/manifest":
+            return httpx.Response(200, json={"files": {n: {"size": len(c)} for n, c in self.files.items()}})
+        if path.startswith("/workspaces/"):
+            workspace = path.removeprefix("/workspaces/")
+            if method == "DELETE":
+                self.workspaces.pop(workspace, None)
+                return httpx.Response(200, json={"deleted": workspace})
+            self.workspaces[workspace] = json.loads(request.content)
+            return httpx.Response(201, json={"id": workspace})
+        if path == "/workspaces":
+            return httpx.Response(200, json={"workspaces": [{"id": w} for w in self.workspaces]})
+        if path == "/lsp":
+            return httpx.Response(200, json={"servers": []})
+        if path == "/debug/bundle":
+            return httpx.Response(200, content=gzip.compress(b"bundle"))
+        if ".." in name:
+            return httpx.Response(403, json={"detail": "Access denied"})
+        if method == "PATCH":
+            self.files[name] = self.files.get(name, b"") + request.content
+            return httpx.Response(200, json={"path": name, "size": len(self.files[name])})
+        if name not in self.files:
+            return httpx.Response(404, json={"detail": "File not found"})
+        if method == "DELETE":
+            del self.files[name]
+            return httpx.Response(200, json={"deleted": name})
+        return httpx.Response(200, content=self.files[name])
+
+
+async def verify(sidecar, **kwargs):
+    kwargs.setdefault("code", "ok")
+    kwargs.setdefault("hang_code", "hang")
+    async with httpx.AsyncClient(transport=httpx.MockTransport(sidecar.handler)) as client:
+        return await run_conformance(client, "http://sidecar:8080/", "zig", **kwargs)
+
+
+class TestRunConformance:
+    """Tests for run_conformance."""
+
+    @pytest.mark.asyncio
+    async def test_complete_implementation_is_full(self):
+        sidecar = FakeSidecar()
+
+        report = await verify(sidecar)
+
+        assert report["failed"] == []
+        assert report["level"] == "full"
+        assert report["base_url"] == "http://sidecar:8080"
+        assert sidecar.files == {}
+        assert sidecar.workspaces == {}
+
+    @pytest.mark.asyncio
+    async def test_levels_are_cumulative(self):
+        report = await verify(FakeSidecar(missing={"/kill", "/lsp"}))
+
+        assert report["failed"] == ["kill", "lsp"]
+        assert report["levels"] == {"basic": True, "stateful": False, "full": False}
+        assert report["level"] == "basic"
+
+    @pytest.mark.asyncio
+    async def test_timeout_must_exit_124(self):
+        report = await verify(FakeSidecar(timeout_exit_code=0))
+
+        assert report["level"] is None
+        check = next(c for c in report["checks"] if c["name"] == "execute_timeout")
+        assert check["detail"] == "Timed out execution exited 0, not 124"
+
+    @pytest.mark.asyncio
+    async def test_connection_errors_fail_checks(self):
+        def refuse(request):
+            raise httpx.ConnectError("Connection refused")
+
+        async with httpx.AsyncClient(transport=httpx.MockTransport(refuse)) as client:
+            report = await run_conformance(client, "http://sidecar:8080", "py")
+
+        assert report["level"] is None
+        assert report["checks"][0]["detail"] == "ConnectError: Connection refused"
+
+    @pytest.mark.asyncio
+    async def test_unknown_language_needs_code(self):
+        async with httpx.AsyncClient() as client:
+            with pytest.raises(ValueError, match="zig"):
+                await run_conformance(client, "http://sidecar:8080", "zig")
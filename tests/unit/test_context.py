@@ -0,0 +1,65 @@
+"""Unit tests for the deployment context."""
+
+from unittest.mock import patch
+
+import pytest
+
+from src.api.context import get_context
+from src.services.context import build_context, execution_env
+
+
+@pytest.fixture
+def mock_settings():
+    with patch("src.services.context.settings") as mock:
+        mock.context_env = {"PLATFORM_DOCS_URL": "https://docs.example.com"}
+        mock.context_banner = "Internal analytics sandbox"
+        mock.context_mounts = {"/mnt/datasets/sales": "Read-only sales data (Parquet)"}
+        mock.max_execution_time = 30
+        mock.max_memory_mb = 512
+        mock.max_file_size_mb = 10
+        mock.max_files_per_session = 50
+        mock.max_output_files = 10
+        mock.enable_network_isolation = True
+        mock.enable_wan_access = False
+        mock.state_persistence_enabled = True
+        mock.get_execution_timeout.side_effect = lambda code: 60 if code == "java" else 30
+        mock.get_memory_limit.return_value = 512
+        yield mock
+
+
+class TestExecutionEnv:
+    def test_layers(self, mock_settings):
+        env = execution_env({"A": "session"}, {"A": "request", "PLATFORM_DOCS_URL": "override"})
+
+        assert env == {"PLATFORM_DOCS_URL": "override", "A": "request"}
+
+    def test_context_only(self, mock_settings):
+        assert execution_env(None, None) == {"PLATFORM_DOCS_URL": "https://docs.example.com"}
+
+
+class TestBuildContext:
+    def test_describes_deployment(self, mock_settings):
+        context = build_context()
+
+        assert context.banner == "Internal analytics sandbox"
+        assert context.working_directory == "/mnt/data"
+        assert context.env == {"PLATFORM_DOCS_URL": "https://docs.example.com"}
+        assert [(m.path, m.description) for m in context.mounts] == [
+            ("/mnt/datasets/sales", "Read-only sales data (Parquet)")
+        ]
+        assert context.limits.max_files_per_session == 50
+        assert context.network.isolated is True
+        assert context.state_persistence is True
+
+    def test_languages(self, mock_settings):
+        languages = {lang.code: lang for lang in build_context().languages}
+
+        assert languages["py"].name == "Python"
+        assert languages["py"].timeout_seconds == 30
+        assert languages["java"].timeout_seconds == 60
+
+    @pytest.mark.asyncio
+    async def test_endpoint(self, mock_settings):
+        response = await get_context()
+
+        assert response.banner == "Internal analytics sandbox"
@@ -0,0 +1,231 @@
+"""Unit tests for the DAG runner."""
+
+import asyncio
+from types import SimpleNamespace
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from src.models import ExecResponse, ExecutionStatus, ValidationError
+from src.models.dag import DagRequest, DagStep
+from src.services.dag import DagRunner, critical_path, order_steps
+
+
+def _step(name, depends_on=(), scope=None, code="print(1)"):
+    return DagStep(name=name, code=code, lang="py", depends_on=list(depends_on), scope=scope)
+
+
+def _ctx(stdout="", exit_code=0, status=ExecutionStatus.COMPLETED):
+    execution = SimpleNamespace(status=status, exit_code=exit_code, error_message="boom" if exit_code else None)
+    return SimpleNamespace(
+        execution=execution,
+        response=ExecResponse(session_id="session-123", stdout=stdout),
+    )
+
+
+@pytest.fixture
+def mock_session_service():
+    """Create a mock session service that creates a new session."""
+    service = MagicMock()
+    service.get_session = AsyncMock(return_value=None)
+    service.create_session = AsyncMock(return_value=SimpleNamespace(session_id="session-123"))
+    return service
+
+
+@pytest.fixture
+def mock_orchestrator():
+    """Create a mock orchestrator whose steps succeed."""
+    orchestrator = MagicMock()
+    orchestrator.run = AsyncMock(side_effect=lambda request, *args, **kwargs: _ctx(stdout=request.code))
+    return orchestrator
+
+
+@pytest.fixture
+def runner(mock_orchestrator, mock_session_service):
+    """Create a DAG runner with mocked services."""
+    return DagRunner(mock_orchestrator, mock_session_service, max_parallel=4)
+
+
+class TestOrderSteps:
+    """Tests for graph validation and ordering."""
+
+    def test_dependency_order(self):
+        """Steps come after their dependencies; ties keep request order."""
+        steps = [_step("report", ["a", "b"]), _step("a"), _step("b", ["a"])]
+        assert order_steps(steps) == ["a", "b", "report"]
+
+    def test_duplicate_names(self):
+        """Step names must be unique."""
+        with pytest.raises(ValidationError) as exc_info:
+            order_steps([_step("a"), _step("a")])
+        assert exc_info.value.details[0].code == "duplicate_step"
+
+    def test_unknown_dependency(self):
+        """Dependencies must name steps in the request."""
+        with pytest.raises(ValidationError) as exc_info:
+            order_steps([_step("a", ["missing"])])
+        assert exc_info.value.details[0].field == "steps.a.depends_on"
+
+    def test_cycle(self):
+        """Cycles are rejected and the steps involved are named."""
+        with pytest.raises(ValidationError) as exc_info:
+            order_steps([_step("root"), _step("a", ["b"]), _step("b", ["a"])])
+        assert exc_info.value.details[0].code == "cycle"
+        assert "a, b" in exc_info.value.details[0].message
+
+
+class TestCriticalPath:
+    """Tests for the critical path summary."""
+
+    def test_longest_weighted_chain(self):
+        """The path with the largest total duration wins, not the longest one."""
+        deps = {"a": [], "b": ["a"], "c": ["b"], "slow": [], "end": ["c", "slow"]}
+        durations = {"a": 10, "b": 10, "c": 10, "slow": 100, "end": 5}
+        assert critical_path(deps, durations, ["a", "slow", "b", "c", "end"]) == (["slow", "end"], 105)
+
+    def test_steps_without_duration_ignored(self):
+        """Skipped steps don't contribute."""
+        assert critical_path({"a": [], "b": ["a"]}, {"a": 7}, ["a", "b"]) == (["a"], 7)
+        assert critical_path({"a": []}, {}, ["a"]) == ([], 0)
+
+
+class TestDagRunner:
+    """Tests for DagRunner.run."""
+
+    @pytest.mark.asyncio
+    async def test_steps_share_one_session(self, runner, mock_orchestrator):
+        """Every step runs in the session created for the run."""
+        request = DagRequest(steps=[_step("a"), _step("b", ["a"])], entity_id="agent-1")
+
+        response = await runner.run(request, "req-1", api_key_hash="hash")
+
+        assert response.session_id == "session-123"
+        assert response.status == "completed"
+        assert [s.name for s in response.steps] == ["a", "b"]
+        for call in mock_orchestrator.run.call_args_list:
+            assert call.args[0].session_id == "session-123"
+            assert call.kwargs == {"api_key_hash": "hash"}
+        assert mock_orchestrator.run.call_args_list[1].args[1] == "req-1:b"
+
+    @pytest.mark.asyncio
+    async def test_existing_session_reused(self, runner, mock_session_service):
+        """An active session_id is used instead of creating one."""
+        mock_session_service.get_session.return_value = SimpleNamespace(status=SimpleNamespace(value="active"))
+
+        response = await runner.run(DagRequest(steps=[_step("a")], session_id="existing"))
+
+        assert response.session_id == "existing"
+        mock_session_service.create_session.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_disjoint_scopes_run_in_parallel(self, runner, mock_orchestrator):
+        """Independent steps with disjoint scopes overlap in time."""
+        active = 0
+        peak = 0
+
+        async def run(request, *args, **kwargs):
+            nonlocal active, peak
+            active += 1
+            peak = max(peak, active)
+            await asyncio.sleep(0.01)
+            active -= 1
+            return _ctx()
+
+        mock_orchestrator.run.side_effect = run
+        request = DagRequest(steps=[_step("a", scope=["a"]), _step("b", scope=["b"]), _step("c", scope=["c"])])
+
+        await runner.run(request)
+
+        assert peak == 3
+
+    @pytest.mark.asyncio
+    async def test_unscoped_steps_serialized(self, runner, mock_orchestrator):
+        """Steps that claim the whole workspace never overlap."""
+        active = 0
+        peak = 0
+
+        async def run(request, *args, **kwargs):
+            nonlocal active, peak
+            active += 1
+            peak = max(peak, active)
+            await asyncio.sleep(0.01)
+            active -= 1
+            return _ctx()
+
+        mock_orchestrator.run.side_effect = run
+
+        await runner.run(DagRequest(steps=[_step("a"), _step("b", scope=["b"])]))
+
+        assert peak == 1
+
+    @pytest.mark.asyncio
+    async def test_failure_skips_dependents_only(self, runner, mock_orchestrator):
+        """A failed step skips what depends on it; other branches still run."""
+
+        async def run(request, *args, **kwargs):
+            if request.code == "fail":
+                return _ctx(exit_code=1, status=ExecutionStatus.FAILED)
+            return _ctx()
+
+        mock_orchestrator.run.side_effect = run
+        steps = [
+            _step("bad", code="fail", scope=["x"]),
+            _step("after_bad", ["bad"]),
+            _step("after_after", ["after_bad"]),
+            _step("other", scope=["y"]),
+        ]
+
+        response = await runner.run(DagRequest(steps=steps))
+
+        by_name = {s.name: s for s in response.steps}
+        assert response.status == "failed"
+        assert by_name["bad"].status == "failed"
+        assert by_name["bad"].exit_code == 1
+        assert by_name["after_bad"].status == "skipped"
+        assert by_name["after_after"].status == "skipped"
+        assert "after_bad" in by_name["after_after"].error
+        assert mock_orchestrator.run.call_count == 2
+
+    @pytest.mark.asyncio
+    async def test_fail_fast_stops_new_steps(self, runner, mock_orchestrator):
+        """With fail_fast nothing new starts after a failure."""
+        from src.models import ExecutionError
+
+        mock_orchestrator.run.side_effect = ExecutionError("kaput")
+
+        response = await runner.run(DagRequest(steps=[_step("a"), _step("b")], fail_fast=True))
+
+        assert response.steps[0].status == "failed"
+        assert response.steps[0].error == "kaput"
+        assert response.steps[1].status == "skipped"
+        assert mock_orchestrator.run.call_count == 1
+
+    @pytest.mark.asyncio
+    async def test_timing_summary(self, runner):
+        """Durations feed the serial time and critical path."""
+        response = await runner.run(DagRequest(steps=[_step("a"), _step("b", ["a"])]))
+
+        assert response.critical_path == ["a", "b"]
+        assert response.serial_time_ms == sum(s.duration_ms for s in response.steps)
+        assert response.steps[1].start_ms >= response.steps[0].start_ms
+
+    @pytest.mark.asyncio
+    async def test_invalid_language_runs_nothing(self, runner, mock_orchestrator, mock_session_service):
+        """Validation happens before any session or step is touched."""
+        request = DagRequest(steps=[DagStep(name="a", code="x", lang="cobol")])
+
+        with pytest.raises(ValidationError) as exc_info:
+            await runner.run(request)
+
+        assert exc_info.value.details[0].field == "steps.a.lang"
+        mock_session_service.create_session.assert_not_called()
+        mock_orchestrator.run.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_too_many_steps(self, runner):
+        """The step limit is enforced."""
+        with patch("src.services.dag.settings") as mock_settings:
+            mock_settings.max_dag_steps = 1
+
+            with pytest.raises(ValidationError, match="Too many steps"):
+                await runner.run(DagRequest(steps=[_step("a"), _step("b")]))
@@ -0,0 +1,60 @@
+"""Unit tests for shared datasets."""
+
+from unittest.mock import patch
+
+import pytest
+from pydantic import ValidationError
+
+from src.api.datasets import get_datasets
+from src.config import DatasetDefinition, Settings
+
+DIGEST = "sha256:" + "ab" * 32
+
+
+class TestSettings:
+    def test_dataset_mounts(self):
+        settings = Settings(
+            datasets={"sales": {"digest": DIGEST}, "census": {"digest": "sha256:" + "CD" * 32}},
+            datasets_host_path="/data/",
+        )
+
+        mounts = settings.get_dataset_mounts()
+
+        assert [m.name for m in mounts] == ["census", "sales"]
+        assert mounts[0].host_path == "/data/sha256/" + "cd" * 32
+        assert mounts[1].host_path == "/data/sha256/" + "ab" * 32
+        assert mounts[1].mount_path == "/mnt/datasets/sales"
+
+    def test_pool_configs_carry_datasets(self):
+        settings = Settings(datasets={"sales": {"digest": DIGEST}}, pod_pool_py=1)
+
+        configs = settings.get_pool_configs()
+
+        assert all([m.name for m in c.datasets] == ["sales"] for c in configs)
+
+    @pytest.mark.parametrize("digest", ["ab" * 32, "sha256:abc", "md5:" + "ab" * 16])
+    def test_rejects_bad_digest(self, digest):
+        with pytest.raises(ValidationError):
+            DatasetDefinition(digest=digest)
+
+    @pytest.mark.parametrize("name", ["../etc", "a/b", "..", ""])
+    def test_rejects_bad_name(self, name):
+        with pytest.raises(ValidationError):
+            Settings(datasets={name: {"digest": DIGEST}})
+
+
+class TestEndpoint:
+    @pytest.mark.asyncio
+    async def test_lists_datasets(self):
+        datasets = {"sales": DatasetDefinition(digest=DIGEST, description="Sales (Parquet)", size_bytes=2048)}
+        with patch("src.services.datasets.settings") as mock_settings:
+            mock_settings.datasets = datasets
+
+            response = await get_datasets()
+
+        assert len(response.datasets) == 1
+        dataset = response.datasets[0]
+        assert dataset.name == "sales"
+        assert dataset.path == "/mnt/datasets/sales"
+        assert dataset.digest == DIGEST
+        assert dataset.size_bytes == 2048
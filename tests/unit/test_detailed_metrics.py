@@ -295,6 +295,23 @@ class TestGetLanguageStats:
         assert result["python"].execution_count == 50
         assert result["python"].failure_count == 5
 
+    @pytest.mark.asyncio
+    async def test_get_language_stats_compile_cache(self, detailed_metrics_service, mock_redis):
+        """Test compiler cache hits and misses add up to a hit rate per language."""
+        mock_redis.hgetall.return_value = {
+            b"lang:cpp:count": b"10",
+            b"lang:cpp:time_ms": b"9000.0",
+            b"lang:cpp:cache_hits": b"6",
+            b"lang:cpp:cache_misses": b"2",
+            b"lang:python:count": b"5",
+        }
+
+        result = await detailed_metrics_service.get_language_stats(hours=1)
+
+        assert (result["cpp"].compile_cache_hits, result["cpp"].compile_cache_misses) == (6, 2)
+        assert result["cpp"].compile_cache_hit_rate == 75.0
+        assert result["python"].compile_cache_hit_rate is None
+
     @pytest.mark.asyncio
     async def test_get_language_stats_error(self, detailed_metrics_service, mock_redis):
         """Test handling errors in get_language_stats."""
@@ -370,6 +387,29 @@ class TestUpdateHourlyAggregates:
 
         mock_redis.pipeline.assert_called()
 
+    @pytest.mark.asyncio
+    async def test_update_hourly_compile_cache(self, detailed_metrics_service, mock_redis):
+        """Test compiler cache hits and misses are counted per language."""
+        metrics = DetailedExecutionMetrics(
+            execution_id="exec-123",
+            session_id="session-123",
+            api_key_hash="abc123def456",
+            user_id=None,
+            entity_id=None,
+            language="go",
+            execution_time_ms=800.0,
+            status="completed",
+            compile_cache_hits=40,
+            compile_cache_misses=1,
+        )
+
+        await detailed_metrics_service._update_hourly_aggregates(metrics)
+
+        mock_pipe = mock_redis.pipeline.return_value
+        redis_key = mock_pipe.hincrby.call_args_list[0].args[0]
+        mock_pipe.hincrby.assert_any_call(redis_key, "lang:go:cache_hits", 40)
+        mock_pipe.hincrby.assert_any_call(redis_key, "lang:go:cache_misses", 1)
+
 
 class TestUpdateApiKeyMetrics:
     """Tests for _update_api_key_metrics method."""
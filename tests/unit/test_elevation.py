@@ -0,0 +1,205 @@
+"""Unit tests for elevated execution grants."""
+
+from datetime import UTC, datetime, timedelta
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+from pydantic import ValidationError as PydanticValidationError
+
+from src.models.elevation import ElevatedCapabilities, ElevatedGrantRequest
+from src.models.errors import AuthorizationError, ServiceUnavailableError, ValidationError
+from src.services.elevation import ElevationService
+
+
+@pytest.fixture
+def mock_settings():
+    with patch("src.services.elevation.settings") as mock:
+        mock.elevated_grant_secret = "s" * 32
+        mock.elevated_grant_max_minutes = 60
+        mock.elevated_max_execution_time = 1800
+        mock.elevated_max_memory_mb = 4096
+        mock.elevated_allow_network = True
+        yield mock
+
+
+@pytest.fixture
+def mock_redis():
+    """Create a mock Redis client backed by a dict."""
+    store = {}
+    audit = []
+
+    async def redeem(script, numkeys, key, max_uses):
+        # REDEEM_SCRIPT
+        if key not in store:
+            return -1
+        if max_uses and int(store[key]) >= max_uses:
+            return -2
+        store[key] = int(store[key]) + 1
+        return store[key]
+
+    pipe = MagicMock()
+    pipe.lpush = MagicMock(side_effect=lambda key, value: audit.insert(0, value))
+    pipe.execute = AsyncMock(return_value=[])
+    pipe.reset = AsyncMock()
+
+    client = MagicMock()
+    client.store = store
+    client.set = AsyncMock(side_effect=lambda key, value, ex=None: store.__setitem__(key, value))
+    client.exists = AsyncMock(side_effect=lambda key: int(key in store))
+    client.get = AsyncMock(side_effect=lambda key: store.get(key))
+    client.eval = AsyncMock(side_effect=redeem)
+    client.delete = AsyncMock(side_effect=lambda key: int(store.pop(key, None) is not None))
+    client.pipeline = AsyncMock(return_value=pipe)
+    client.lrange = AsyncMock(side_effect=lambda key, start, end: audit[start : end + 1])
+    return client
+
+
+@pytest.fixture
+def service(mock_redis, mock_settings):
+    return ElevationService(redis_client=mock_redis)
+
+
+def grant_request(**kwargs):
+    kwargs.setdefault("capabilities", ElevatedCapabilities(network=True, max_execution_time=600))
+    kwargs.setdefault("reason", "nightly backfill")
+    return ElevatedGrantRequest(**kwargs)
+
+
+class TestIssue:
+    """Tests for issuing grants."""
+
+    @pytest.mark.asyncio
+    async def test_issue_returns_signed_token(self, service, mock_redis):
+        issued = await service.issue(grant_request(ttl_minutes=10), actor="10.0.0.5")
+
+        assert issued.grant.approved_by == "10.0.0.5"
+        assert issued.grant.expires_at - issued.grant.issued_at == timedelta(minutes=10)
+        assert mock_redis.store[f"elevation:grant:{issued.grant.grant_id}"] == 0
+        mock_redis.set.assert_awaited_once_with(f"elevation:grant:{issued.grant.grant_id}", 0, ex=600)
+        assert (await service.audit())[0].event == "issued"
+
+    @pytest.mark.asyncio
+    async def test_issue_within_ceilings(self, service, mock_settings):
+        mock_settings.elevated_allow_network = False
+
+        with pytest.raises(ValidationError, match="ELEVATED_ALLOW_NETWORK"):
+            await service.issue(grant_request())
+        with pytest.raises(ValidationError, match="ELEVATED_GRANT_MAX_MINUTES"):
+            await service.issue(grant_request(capabilities=ElevatedCapabilities(), ttl_minutes=120))
+
+    @pytest.mark.asyncio
+    async def test_disabled_without_secret(self, service, mock_settings):
+        mock_settings.elevated_grant_secret = None
+
+        with pytest.raises(ServiceUnavailableError):
+            await service.issue(grant_request())
+
+
+class TestRedeem:
+    """Tests for using grants."""
+
+    @pytest.mark.asyncio
+    async def test_redeem_counts_uses(self, service, mock_redis):
+        issued = await service.issue(grant_request(max_uses=2))
+
+        for _ in range(2):
+            grant = await service.redeem(issued.token, "key-hash", "s1")
+        assert grant.grant_id == issued.grant.grant_id
+        with pytest.raises(AuthorizationError, match="used up"):
+            await service.redeem(issued.token, "key-hash", "s1")
+
+        assert [entry.event for entry in await service.audit()] == ["denied", "used", "used", "issued"]
+
+    @pytest.mark.asyncio
+    async def test_forged_token(self, service):
+        issued = await service.issue(grant_request())
+        payload, _, signature = issued.token.partition(".")
+
+        with pytest.raises(AuthorizationError, match="invalid token"):
+            await service.redeem(f"{payload}x.{signature}", None, None)
+        with pytest.raises(AuthorizationError, match="invalid token"):
+            await service.redeem("not-a-token", None, None)
+
+    @pytest.mark.asyncio
+    async def test_bound_to_key_and_session(self, service):
+        key_hash = "abcd1234" * 8
+        issued = await service.issue(grant_request(api_key_hash=key_hash, session_id="s1"))
+
+        assert await service.redeem(issued.token, key_hash, "s1")
+        with pytest.raises(AuthorizationError, match="another API key"):
+            await service.redeem(issued.token, "other", "s1")
+        with pytest.raises(AuthorizationError, match="another API key"):
+            # Another key sharing the bound hash's prefix
+            await service.redeem(issued.token, "abcd1234" + "f" * 56, "s1")
+        with pytest.raises(AuthorizationError, match="another session"):
+            await service.redeem(issued.token, key_hash, "s2")
+
+    def test_key_binding_needs_full_hash(self):
+        """A short hash would bind the grant to every key starting with it."""
+        with pytest.raises(PydanticValidationError):
+            grant_request(api_key_hash="a")
+        with pytest.raises(PydanticValidationError):
+            grant_request(api_key_hash="abcd1234" * 2)
+
+    @pytest.mark.asyncio
+    async def test_expired(self, service):
+        issued = await service.issue(grant_request(ttl_minutes=1))
+
+        with patch("src.services.elevation.datetime") as mock_datetime:
+            mock_datetime.now.return_value = datetime.now(UTC) + timedelta(minutes=2)
+            with pytest.raises(AuthorizationError, match="expired"):
+                await service.redeem(issued.token, None, None)
+
+    @pytest.mark.asyncio
+    async def test_revoked(self, service):
+        issued = await service.issue(grant_request())
+
+        assert await service.revoke(issued.grant.grant_id, actor="10.0.0.5") is True
+        with pytest.raises(AuthorizationError, match="revoked"):
+            await service.redeem(issued.token, None, None)
+        assert await service.revoke(issued.grant.grant_id) is False
+
+    @pytest.mark.asyncio
+    async def test_expired_between_check_and_use(self, service, mock_redis):
+        """A grant whose key expires after it was checked isn't used, and its key isn't recreated."""
+        issued = await service.issue(grant_request())
+        key = f"elevation:grant:{issued.grant.grant_id}"
+        mock_redis.get.side_effect = lambda k: mock_redis.store.pop(k, None) if k == key else None
+
+        with pytest.raises(AuthorizationError, match="revoked"):
+            await service.redeem(issued.token, None, None)
+
+        assert key not in mock_redis.store
+        assert mock_redis.eval.await_args.args[2:] == (key, 0)
+
+    @pytest.mark.asyncio
+    async def test_limits_lowered_after_issue(self, service, mock_settings):
+        issued = await service.issue(grant_request())
+        mock_settings.elevated_allow_network = False
+
+        with pytest.raises(AuthorizationError, match="ELEVATED_ALLOW_NETWORK"):
+            await service.redeem(issued.token, None, None)
+
+    @pytest.mark.asyncio
+    async def test_check_does_not_use_grant(self, service, mock_redis):
+        """Dry runs check a grant without counting a use or auditing it."""
+        issued = await service.issue(grant_request(max_uses=1))
+
+        assert (await service.check(issued.token, "key-hash", "s1")).grant_id == issued.grant.grant_id
+        assert mock_redis.store[f"elevation:grant:{issued.grant.grant_id}"] == 0
+        assert [entry.event for entry in await service.audit()] == ["issued"]
+
+        await service.redeem(issued.token, "key-hash", "s1")
+        with pytest.raises(AuthorizationError, match="used up"):
+            await service.check(issued.token, "key-hash", "s1")
+        with pytest.raises(AuthorizationError, match="invalid token"):
+            await service.check("not-a-token", None, None)
+
+    @pytest.mark.asyncio
+    async def test_uses_logged_as_security_events(self, service):
+        issued = await service.issue(grant_request())
+
+        with patch("src.services.elevation.SecurityAudit") as audit:
+            await service.redeem(issued.token, "key-hash", "s1")
+
+        audit.log_elevation.assert_called_once_with("used", issued.grant.grant_id, "key-hash", "s1", "use 1")
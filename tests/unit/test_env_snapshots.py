@@ -0,0 +1,155 @@
+"""Unit tests for environment snapshots and the changes between executions."""
+
+from datetime import UTC, datetime
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from src.models.env_snapshot import EnvironmentSnapshot, SnapshotFile
+from src.models.files import FileInfo
+from src.services.env_snapshots import EnvironmentSnapshotService, diff_snapshots, env_digests
+
+
+@pytest.fixture
+def mock_pipeline():
+    """Create a mock transactional pipeline."""
+    pipe = MagicMock()
+    pipe.execute = AsyncMock(return_value=[])
+    pipe.reset = AsyncMock()
+    return pipe
+
+
+@pytest.fixture
+def mock_redis(mock_pipeline):
+    """Create a mock Redis client."""
+    client = MagicMock()
+    client.pipeline = AsyncMock(return_value=mock_pipeline)
+    client.get = AsyncMock(return_value=None)
+    client.lrange = AsyncMock(return_value=[])
+    return client
+
+
+@pytest.fixture
+def service(mock_redis):
+    """Create an environment snapshot service with mocked Redis."""
+    return EnvironmentSnapshotService(redis_client=mock_redis)
+
+
+@pytest.fixture
+def mock_settings():
+    with patch("src.services.env_snapshots.settings") as mock:
+        mock.session_change_history_limit = 100
+        mock.get_session_ttl_minutes.return_value = 60
+        yield mock
+
+
+def _file(path, file_id="f1", size=10):
+    return FileInfo(
+        file_id=file_id, filename=path, size=size, content_type="text/plain", created_at=datetime.now(UTC), path=path
+    )
+
+
+def _snapshot(**overrides):
+    values = {"cell_id": 1, "execution_id": "exec-1", "taken_at": datetime.now(UTC)}
+    values.update(overrides)
+    return EnvironmentSnapshot(**values)
+
+
+class TestDiffSnapshots:
+    """Tests for comparing consecutive snapshots."""
+
+    def test_changes(self):
+        previous = _snapshot(
+            env=env_digests({"A": "1", "B": "2", "C": "3"}),
+            packages=["pip:pandas"],
+            files={"a.csv": SnapshotFile(file_id="f1", size=10), "b.txt": SnapshotFile(file_id="f2", size=5)},
+        )
+        current = _snapshot(
+            cell_id=2,
+            execution_id="exec-2",
+            env=env_digests({"A": "1", "B": "changed", "D": "4"}),
+            packages=["pip:pandas", "pip:polars"],
+            files={"a.csv": SnapshotFile(file_id="f3", size=12), "c.png": SnapshotFile(file_id="f4", size=1)},
+        )
+
+        changes = diff_snapshots(previous, current)
+
+        assert (changes.cell_id, changes.previous_cell_id, changes.previous_execution_id) == (2, 1, "exec-1")
+        assert (changes.env.added, changes.env.removed, changes.env.modified) == (["D"], ["C"], ["B"])
+        assert changes.packages.added == ["pip:polars"] and not changes.packages.removed
+        assert (changes.files.added, changes.files.removed, changes.files.modified) == (["c.png"], ["b.txt"], ["a.csv"])
+
+    def test_first_snapshot(self):
+        current = _snapshot(env=env_digests({"A": "1"}), files={"a": SnapshotFile(file_id="f1", size=1)})
+
+        changes = diff_snapshots(None, current)
+
+        assert changes.previous_cell_id is None
+        assert changes.env.added == ["A"] and changes.files.added == ["a"]
+
+    def test_values_are_not_kept(self):
+        digests = env_digests({"TOKEN": "s3cret"})
+
+        assert "s3cret" not in digests["TOKEN"] and len(digests["TOKEN"]) == 16
+        assert digests == env_digests({"TOKEN": "s3cret"})
+
+
+class TestRecord:
+    """Tests for snapshotting a session after an execution."""
+
+    @pytest.mark.asyncio
+    async def test_record_compares_with_latest(self, service, mock_redis, mock_pipeline, mock_settings):
+        mock_redis.get.return_value = _snapshot(
+            env=env_digests({"A": "1"}), packages=["pip:pandas"], files={"a.csv": SnapshotFile(file_id="f1", size=10)}
+        ).model_dump_json()
+
+        changes = await service.record(
+            "session-1",
+            env={"A": "1", "B": "2"},
+            files=[_file("a.csv"), _file("out.png", file_id="f2")],
+            installed=["pip:pandas", "pip:polars"],
+            cell_id=2,
+            execution_id="exec-2",
+        )
+
+        assert changes.env.added == ["B"] and not changes.env.modified
+        assert changes.packages.added == ["pip:polars"]
+        assert changes.files.added == ["out.png"] and not changes.files.modified
+        latest = EnvironmentSnapshot.model_validate_json(mock_pipeline.set.call_args.args[1])
+        assert latest.packages == ["pip:pandas", "pip:polars"]
+        assert mock_pipeline.set.call_args.kwargs["ex"] == 3600
+        mock_pipeline.ltrim.assert_called_once_with("session:changes:session-1", -100, -1)
+
+    @pytest.mark.asyncio
+    async def test_disabled(self, service, mock_redis, mock_settings):
+        mock_settings.session_change_history_limit = 0
+
+        assert await service.record("session-1", env={}, files=[]) is None
+        mock_redis.pipeline.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_corrupt_latest_snapshot_is_ignored(self, service, mock_redis, mock_settings):
+        mock_redis.get.return_value = "not json"
+
+        changes = await service.record("session-1", env={"A": "1"}, files=[])
+
+        assert changes.previous_cell_id is None and changes.env.added == ["A"]
+
+
+class TestListChanges:
+    """Tests for reading a session's changes."""
+
+    @pytest.mark.asyncio
+    async def test_list_changes(self, service, mock_redis):
+        unchanged = diff_snapshots(_snapshot(), _snapshot(cell_id=2))
+        changed = diff_snapshots(_snapshot(cell_id=2), _snapshot(cell_id=3, env={"A": "x"}))
+        mock_redis.lrange.return_value = [unchanged.model_dump_json(), "garbage", changed.model_dump_json()]
+
+        assert [c.cell_id for c in await service.list_changes("session-1")] == [2, 3]
+        assert [c.cell_id for c in await service.list_changes("session-1", changed_only=True)] == [3]
+
+    @pytest.mark.asyncio
+    async def test_limit(self, service, mock_redis):
+        await service.list_changes("session-1", limit=5)
+
+        mock_redis.lrange.assert_awaited_once_with("session:changes:session-1", -5, -1)
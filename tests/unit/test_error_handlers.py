@@ -1,5 +1,6 @@
 """Unit tests for Error Handlers."""
 
+import json
 from unittest.mock import MagicMock, patch
 
 import pytest
@@ -11,6 +12,7 @@ from src.models.errors import (
     CodeInterpreterException,
     ErrorDetail,
     ErrorType,
+    TimeoutError,
 )
 from src.utils.error_handlers import (
     code_interpreter_exception_handler,
@@ -345,6 +347,62 @@ class TestGeneralExceptionHandler:
         assert response.status_code == 500
 
 
+class TestLocalizedErrors:
+    """Tests for Accept-Language localization of error messages."""
+
+    @pytest.fixture
+    def german_request(self, mock_request):
+        mock_request.headers = {"accept-language": "de-DE,de;q=0.9,en;q=0.5"}
+        return mock_request
+
+    @pytest.mark.asyncio
+    async def test_exception_message_localized_code_stable(self, german_request):
+        exc = TimeoutError(operation="Execution", timeout=30)
+
+        response = await code_interpreter_exception_handler(german_request, exc)
+
+        body = json.loads(response.body)
+        assert body["error"] == "Zeitüberschreitung nach 30 Sekunden"
+        assert body["code"] == "timeout"
+        assert body["error_type"] == "timeout"
+        assert response.headers["content-language"] == "de"
+
+    @pytest.mark.asyncio
+    async def test_english_unchanged(self, mock_request):
+        mock_request.headers = {"accept-language": "en-US"}
+        exc = TimeoutError(operation="Execution", timeout=30)
+
+        response = await code_interpreter_exception_handler(mock_request, exc)
+
+        body = json.loads(response.body)
+        assert body["error"] == "Execution timed out after 30 seconds"
+        assert "content-language" not in response.headers
+
+    @pytest.mark.asyncio
+    async def test_http_detail_dict_keeps_error_code(self, german_request):
+        exc = HTTPException(status_code=404, detail={"error": "session_not_found", "message": "Session not found"})
+
+        response = await http_exception_handler(german_request, exc)
+
+        body = json.loads(response.body)
+        assert body["code"] == "session_not_found"
+        assert "Sitzung nicht gefunden" in body["error"]
+        assert "session_not_found" in body["error"]
+
+    @pytest.mark.asyncio
+    async def test_validation_and_internal_codes(self, german_request):
+        validation_exc = MagicMock(spec=RequestValidationError)
+        validation_exc.errors.return_value = []
+
+        validation = json.loads((await validation_exception_handler(german_request, validation_exc)).body)
+        internal = json.loads((await general_exception_handler(german_request, Exception("boom"))).body)
+
+        assert validation["code"] == "request_validation"
+        assert validation["error"] == "Validierung der Anfrage fehlgeschlagen"
+        assert internal["code"] == "internal_server"
+        assert internal["error"] == "Ein unerwarteter Fehler ist aufgetreten"
+
+
 class TestCreateValidationError:
     """Tests for create_validation_error utility."""
 
@@ -204,6 +204,45 @@ class TestExecute:
         assert len(execution.outputs) == 1
         assert execution.outputs[0].type == OutputType.STDERR
 
+    @pytest.mark.asyncio
+    async def test_execute_interrupted(self, runner, mock_kubernetes_manager, sample_request):
+        """Test an interrupted execution is cancelled but keeps its output."""
+        result = ExecutionResult(
+            stdout="partial\n",
+            stderr="Traceback (most recent call last):\nKeyboardInterrupt\n",
+            exit_code=130,
+            execution_time_ms=500,
+            interrupted=True,
+        )
+        mock_kubernetes_manager.execute_code.return_value = (result, None, "pool_hit")
+
+        with patch("src.services.execution.runner.metrics_collector"):
+            execution, _, _, _, _ = await runner.execute("session-123", sample_request)
+
+        assert execution.status == ExecutionStatus.CANCELLED
+        assert execution.exit_code == 130
+        assert execution.error_message == "Execution interrupted"
+        assert "partial" in execution.outputs[0].content
+
+    @pytest.mark.asyncio
+    async def test_execute_runtime_not_found(self, runner, mock_kubernetes_manager, sample_request):
+        """Structured sidecar errors are kept on the execution."""
+        error = {"code": "RUNTIME_NOT_FOUND", "wanted": "python", "available": ["python3"], "suggestion": "python3"}
+        result = ExecutionResult(
+            stdout="",
+            stderr="RUNTIME_NOT_FOUND: 'python' is not installed in this image.\n",
+            exit_code=127,
+            execution_time_ms=5,
+            error=error,
+        )
+        mock_kubernetes_manager.execute_code.return_value = (result, None, "pool_hit")
+
+        with patch("src.services.execution.runner.metrics_collector"):
+            execution, _, _, _, _ = await runner.execute("session-123", sample_request)
+
+        assert execution.status == ExecutionStatus.FAILED
+        assert execution.error == error
+
     @pytest.mark.asyncio
     async def test_execute_with_state(self, runner, mock_kubernetes_manager, sample_request):
         """Test execution with state capture."""
@@ -227,6 +266,47 @@ class TestExecute:
         assert new_state == "base64encodedstate=="
         assert state_errors == ["Warning: skipped large object"]
 
+    @pytest.mark.asyncio
+    async def test_execute_records_provenance(self, runner, mock_kubernetes_manager, sample_request):
+        """Test the resolved command and binaries are recorded and audited."""
+        provenance = {
+            "command": ["/usr/bin/python3", "-"],
+            "binaries": [{"name": "python3", "path": "/usr/bin/python3.12", "sha256": "ab" * 32, "version": "3.12"}],
+        }
+        result = ExecutionResult(stdout="", stderr="", exit_code=0, execution_time_ms=10, provenance=provenance)
+        mock_kubernetes_manager.execute_code.return_value = (result, None, "pool_hit")
+
+        with (
+            patch("src.services.execution.runner.metrics_collector"),
+            patch("src.services.execution.runner.SecurityAudit") as audit,
+        ):
+            execution, _, _, _, _ = await runner.execute("session-123", sample_request)
+
+        assert execution.provenance == provenance
+        audit.log_execution_provenance.assert_called_once_with(
+            "session-123", execution.execution_id, "python", provenance
+        )
+
+    @pytest.mark.asyncio
+    async def test_execute_records_catalog_image(self, runner, mock_kubernetes_manager):
+        """Test a catalog image is passed to the pod and the image that ran is recorded and audited."""
+        image = "registry/py-datasci@sha256:abc"
+        request = ExecuteCodeRequest(code="print(1)", language="python", image=image, image_name="python:3.11-datasci")
+        result = ExecutionResult(stdout="", stderr="", exit_code=0, execution_time_ms=10, image=image)
+        mock_kubernetes_manager.execute_code.return_value = (result, None, "job")
+
+        with (
+            patch("src.services.execution.runner.metrics_collector"),
+            patch("src.services.execution.runner.SecurityAudit") as audit,
+        ):
+            execution, _, _, _, _ = await runner.execute("session-123", request)
+
+        assert mock_kubernetes_manager.execute_code.call_args.kwargs["image"] == image
+        assert (execution.image, execution.image_name) == (image, "python:3.11-datasci")
+        audit.log_execution_image.assert_called_once_with(
+            "session-123", execution.execution_id, "python:3.11-datasci", image
+        )
+
     @pytest.mark.asyncio
     async def test_execute_timeout(self, runner, mock_kubernetes_manager, sample_request):
         """Test execution timeout."""
@@ -489,6 +569,31 @@ class TestDetectGeneratedFiles:
         assert len(result) == 1
         assert result[0]["path"] == "/mnt/data/output.txt"
 
+    @pytest.mark.asyncio
+    async def test_detect_in_workspace(self, runner):
+        """Test files are listed from the named workspace."""
+        handle = MagicMock(
+            pod_ip="10.0.0.1",
+            sidecar_url="http://10.0.0.1:8080",
+            name="test-pod",
+        )
+
+        mock_response = MagicMock()
+        mock_response.status_code = 200
+        mock_response.json.return_value = {"files": [{"name": "output.txt", "size": 100}]}
+
+        with patch("httpx.AsyncClient") as mock_client_cls:
+            mock_client = AsyncMock()
+            mock_client.__aenter__.return_value = mock_client
+            mock_client.__aexit__.return_value = None
+            mock_client.get.return_value = mock_response
+            mock_client_cls.return_value = mock_client
+
+            result = await runner._detect_generated_files(handle, "ws-1")
+
+        mock_client.get.assert_called_once_with("http://10.0.0.1:8080/files", params={"workspace": "ws-1"})
+        assert result[0]["path"] == "/mnt/data/ws-1/output.txt"
+
     @pytest.mark.asyncio
     async def test_detect_handles_error(self, runner):
         """Test graceful error handling."""
@@ -639,6 +744,30 @@ class TestCancelExecution:
         assert result is False
 
 
+class TestInterruptSession:
+    """Tests for interrupt_session method."""
+
+    @pytest.mark.asyncio
+    async def test_interrupt_delegates_to_manager(self, runner, mock_kubernetes_manager):
+        """Test the interrupt is delivered without destroying the pod."""
+        mock_kubernetes_manager.interrupt_session = AsyncMock(return_value=1)
+
+        assert await runner.interrupt_session("session-123") == 1
+
+        mock_kubernetes_manager.interrupt_session.assert_called_once_with("session-123")
+        mock_kubernetes_manager.destroy_pod.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_kill_delegates_to_manager(self, runner, mock_kubernetes_manager):
+        """Test a kill leaves the pod for the execution to finish with."""
+        mock_kubernetes_manager.kill_session = AsyncMock(return_value=2)
+
+        assert await runner.kill_session("session-123") == 2
+
+        mock_kubernetes_manager.kill_session.assert_called_once_with("session-123")
+        mock_kubernetes_manager.destroy_pod.assert_not_called()
+
+
 class TestListExecutions:
     """Tests for list_executions method."""
 
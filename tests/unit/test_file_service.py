@@ -1,6 +1,7 @@
 """Unit tests for File Service."""
 
 import asyncio
+import hashlib
 from datetime import datetime
 from io import BytesIO
 from unittest.mock import AsyncMock, MagicMock, patch
@@ -492,6 +493,19 @@ class TestStoreUploadedFile:
         assert file_id == "file-upload-123"
         mock_minio_client.put_object.assert_called_once()
 
+    @pytest.mark.asyncio
+    async def test_store_uploaded_file_records_digest(self, file_service, mock_minio_client, mock_redis_client):
+        """The content's digest is kept with the file, so reports needn't read it back."""
+        with patch("src.services.file.generate_file_id", return_value="file-upload-123"):
+            await file_service.store_uploaded_file("session-123", "uploaded.txt", b"uploaded content")
+
+        stored = mock_redis_client.hset.call_args.kwargs["mapping"]
+        assert stored["sha256"] == hashlib.sha256(b"uploaded content").hexdigest()
+
+        mock_redis_client.hgetall.return_value = {**stored, "size": str(stored["size"])}
+        info = await file_service.get_file_info("session-123", "file-upload-123")
+        assert info.sha256 == stored["sha256"]
+
     @pytest.mark.asyncio
     async def test_store_uploaded_file_with_none_content_type(self, file_service, mock_minio_client, mock_redis_client):
         """Test upload with no content type uses default."""
@@ -508,6 +522,160 @@ class TestStoreUploadedFile:
         assert file_id == "file-upload-123"
 
 
+class TestUpdateFileContent:
+    """Tests for update_file_content method."""
+
+    METADATA = {
+        "file_id": "file-456",
+        "filename": "notes.txt",
+        "size": "5",
+        "content_type": "text/plain",
+        "object_key": "sessions/session-123/uploads/file-456",
+        "path": "/notes.txt",
+        "created_at": "2024-01-01T00:00:00",
+    }
+
+    @pytest.mark.asyncio
+    async def test_replaces_content_under_the_same_key(self, file_service, mock_minio_client, mock_redis_client):
+        """The object is overwritten in place and the size updated."""
+        mock_redis_client.hgetall.return_value = dict(self.METADATA)
+
+        result = await file_service.update_file_content("session-123", "file-456", b"hello world")
+
+        args = mock_minio_client.put_object.call_args.args
+        assert args[1] == "sessions/session-123/uploads/file-456"
+        assert args[2].read() == b"hello world" and args[3] == 11
+        mock_redis_client.hset.assert_awaited_once_with(
+            "files:session-123:file-456",
+            mapping={"size": 11, "sha256": hashlib.sha256(b"hello world").hexdigest()},
+        )
+        assert result.file_id == "file-456"
+
+    @pytest.mark.asyncio
+    async def test_drops_cached_thumbnails(self, file_service, mock_minio_client, mock_redis_client):
+        """A thumbnail of the old content isn't served for the new one."""
+        mock_redis_client.hgetall.return_value = dict(self.METADATA)
+        thumbnail = "sessions/session-123/thumbnails/file-456/64.png"
+        mock_minio_client.list_objects.return_value = [MagicMock(object_name=thumbnail)]
+
+        await file_service.update_file_content("session-123", "file-456", b"new")
+
+        mock_minio_client.remove_object.assert_called_once_with("test-bucket", thumbnail)
+
+    @pytest.mark.asyncio
+    async def test_missing_file(self, file_service, mock_minio_client, mock_redis_client):
+        """Nothing is written for a file that doesn't exist."""
+        mock_redis_client.hgetall.return_value = {}
+
+        assert await file_service.update_file_content("session-123", "gone", b"x") is None
+        mock_minio_client.put_object.assert_not_called()
+
+
+class TestWriteFileRange:
+    """Tests for write_file_range method."""
+
+    METADATA = {
+        "file_id": "file-456",
+        "filename": "log.txt",
+        "size": "5",
+        "content_type": "text/plain",
+        "object_key": "sessions/session-123/uploads/file-456",
+        "path": "/log.txt",
+        "created_at": "2024-01-01T00:00:00",
+    }
+
+    @pytest.fixture
+    def pipe(self, mock_redis_client):
+        pipe = MagicMock()
+        pipe.watch = AsyncMock()
+        pipe.unwatch = AsyncMock()
+        pipe.hgetall = AsyncMock(return_value=dict(self.METADATA))
+        pipe.execute = AsyncMock(return_value=[])
+        pipe.reset = AsyncMock()
+        mock_redis_client.pipeline = AsyncMock(return_value=pipe)
+        mock_redis_client.hgetall.return_value = dict(self.METADATA)
+        return pipe
+
+    @pytest.fixture(autouse=True)
+    def stored(self, mock_minio_client):
+        response = MagicMock()
+        response.read.return_value = b"hello"
+        mock_minio_client.get_object.return_value = response
+
+    def written(self, mock_minio_client):
+        args = mock_minio_client.put_object.call_args.args
+        return args[1], args[2].read()
+
+    @pytest.mark.asyncio
+    async def test_append_swaps_in_a_new_object(self, file_service, mock_minio_client, pipe):
+        """The appended content goes to a new object that replaces the old one."""
+        with patch("src.services.file.generate_file_id", return_value="v1"):
+            await file_service.write_file_range("session-123", "file-456", b" world")
+
+        assert self.written(mock_minio_client) == ("sessions/session-123/uploads/file-456~v1", b"hello world")
+        pipe.hset.assert_called_once_with(
+            "files:session-123:file-456",
+            mapping={
+                "object_key": "sessions/session-123/uploads/file-456~v1",
+                "size": 11,
+                "sha256": hashlib.sha256(b"hello world").hexdigest(),
+            },
+        )
+        mock_minio_client.remove_object.assert_called_once_with("test-bucket", "sessions/session-123/uploads/file-456")
+
+    @pytest.mark.asyncio
+    async def test_offset_write(self, file_service, mock_minio_client, pipe):
+        """An offset write replaces bytes and extends the file past its end."""
+        await file_service.write_file_range("session-123", "file-456", b"p me!", offset=3)
+
+        assert self.written(mock_minio_client)[1] == b"help me!"
+
+    @pytest.mark.asyncio
+    async def test_expected_size_mismatch(self, file_service, mock_minio_client, pipe):
+        """A retried append that already landed is refused."""
+        from src.models.errors import ResourceConflictError
+
+        with pytest.raises(ResourceConflictError, match="expected 3"):
+            await file_service.write_file_range("session-123", "file-456", b"x", expected_size=3)
+        mock_minio_client.put_object.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_offset_past_end(self, file_service, pipe):
+        """Writes can't leave a hole."""
+        from src.models.errors import ResourceConflictError
+
+        with pytest.raises(ResourceConflictError, match="past the end"):
+            await file_service.write_file_range("session-123", "file-456", b"x", offset=6)
+
+    @pytest.mark.asyncio
+    async def test_lost_race_is_redone(self, file_service, mock_minio_client, pipe):
+        """A write that raced another is applied again on the other's result."""
+        from redis.exceptions import WatchError
+
+        raced = {**self.METADATA, "size": "7", "object_key": "sessions/session-123/uploads/file-456~other"}
+        pipe.hgetall.side_effect = [dict(self.METADATA), raced]
+        pipe.execute.side_effect = [WatchError(), []]
+        first, second = MagicMock(), MagicMock()
+        first.read.return_value, second.read.return_value = b"hello", b"hello!!"
+        mock_minio_client.get_object.side_effect = [first, second]
+
+        with patch("src.services.file.generate_file_id", side_effect=["v1", "v2"]):
+            await file_service.write_file_range("session-123", "file-456", b"?")
+
+        assert self.written(mock_minio_client) == ("sessions/session-123/uploads/file-456~v2", b"hello!!?")
+        removed = [call.args[1].rsplit("~", 1)[1] for call in mock_minio_client.remove_object.call_args_list]
+        # The losing attempt's object, then the one the other write had swapped in
+        assert removed[:2] == ["v1", "other"]
+
+    @pytest.mark.asyncio
+    async def test_missing_file(self, file_service, mock_minio_client, pipe):
+        """Nothing is written for a file that doesn't exist."""
+        pipe.hgetall.return_value = {}
+
+        assert await file_service.write_file_range("session-123", "gone", b"x") is None
+        mock_minio_client.put_object.assert_not_called()
+
+
 class TestConfirmUpload:
     """Tests for confirm_upload method."""
 
@@ -771,3 +939,42 @@ class TestCloseMethod:
 
         # Should not raise
         await file_service.close()
+
+
+class TestThumbnailCache:
+    """Tests for cached thumbnails."""
+
+    @pytest.mark.asyncio
+    async def test_get_thumbnail_missing(self, file_service, mock_minio_client):
+        """Test a thumbnail that hasn't been generated yet."""
+        mock_minio_client.get_object.side_effect = S3Error(
+            "Error", "NoSuchKey", "resource", "request_id", "host_id", "response"
+        )
+
+        assert await file_service.get_thumbnail("session-123", "file-456", 256) is None
+
+    @pytest.mark.asyncio
+    async def test_store_thumbnail(self, file_service, mock_minio_client):
+        """Test thumbnails are stored per file and width."""
+        await file_service.store_thumbnail("session-123", "file-456", 256, b"png")
+
+        args = mock_minio_client.put_object.call_args[0]
+        assert args[1] == "sessions/session-123/thumbnails/file-456/256.png"
+
+    @pytest.mark.asyncio
+    async def test_delete_file_removes_thumbnails(self, file_service, mock_minio_client, mock_redis_client):
+        """Test deleting a file also deletes its thumbnails."""
+        mock_redis_client.hgetall.return_value = {
+            "file_id": "file-456",
+            "filename": "plot.png",
+            "size": "1024",
+            "object_key": "sessions/session-123/outputs/file-456",
+        }
+        thumbnail = MagicMock()
+        thumbnail.object_name = "sessions/session-123/thumbnails/file-456/256.png"
+        mock_minio_client.list_objects.return_value = [thumbnail]
+
+        assert await file_service.delete_file("session-123", "file-456") is True
+
+        removed = [c[0][1] for c in mock_minio_client.remove_object.call_args_list]
+        assert removed == ["sessions/session-123/outputs/file-456", thumbnail.object_name]
@@ -0,0 +1,273 @@
+"""Unit tests for runtime policy changes."""
+
+import asyncio
+import json
+from types import SimpleNamespace
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+from pydantic import ValidationError
+
+from src.models.errors import ServiceUnavailableError
+from src.models.runtime_config import ConfigChange, RuntimeConfigPatch
+from src.services.concurrency import ExecutionGate
+from src.services.hot_config import HotConfigService
+
+
+@pytest.fixture
+def mock_pipeline():
+    """Create a mock transactional pipeline."""
+    pipe = MagicMock()
+    pipe.execute = AsyncMock(return_value=[])
+    pipe.reset = AsyncMock()
+    return pipe
+
+
+@pytest.fixture
+def mock_redis(mock_pipeline):
+    """Create a mock Redis client."""
+    client = MagicMock()
+    client.pipeline = AsyncMock(return_value=mock_pipeline)
+    client.hgetall = AsyncMock(return_value={})
+    client.lrange = AsyncMock(return_value=[])
+    return client
+
+
+@pytest.fixture
+def mock_settings():
+    values = SimpleNamespace(
+        max_concurrent_executions=10,
+        max_execution_time=30,
+        max_connections_per_execution=None,
+        max_output_files=10,
+        session_lock_wait_seconds=30,
+        max_dag_steps=25,
+        dag_max_parallel_steps=4,
+        artifact_secret_scan="flag",
+        queue_wait_alert_p95_ms=10000,
+        enable_network_isolation=True,
+        config_refresh_interval_seconds=0,
+    )
+    with patch("src.services.hot_config.settings", values):
+        yield values
+
+
+@pytest.fixture
+def service(mock_redis, mock_settings):
+    """Create a hot configuration service with mocked Redis."""
+    return HotConfigService(redis_client=mock_redis)
+
+
+class TestRuntimeConfigPatch:
+    """Tests for validating policy changes."""
+
+    def test_only_present_fields_are_changes(self):
+        patch_ = RuntimeConfigPatch(max_execution_time=60)
+        assert patch_.changes() == {"max_execution_time": 60}
+
+    def test_connection_limit_can_be_removed(self):
+        assert RuntimeConfigPatch(max_connections_per_execution=None).changes() == {
+            "max_connections_per_execution": None
+        }
+
+    def test_other_settings_cannot_be_null(self):
+        with pytest.raises(ValidationError, match="max_execution_time cannot be null"):
+            RuntimeConfigPatch(max_execution_time=None)
+
+    def test_bounds_match_settings(self):
+        with pytest.raises(ValidationError):
+            RuntimeConfigPatch(max_execution_time=601)
+        with pytest.raises(ValidationError):
+            RuntimeConfigPatch(artifact_secret_scan="loud")
+
+    def test_pod_level_settings_are_rejected_with_reason(self):
+        with pytest.raises(ValidationError, match="applied when pods are created"):
+            RuntimeConfigPatch(max_memory_mb=1024)
+        with pytest.raises(ValidationError, match="network policies"):
+            RuntimeConfigPatch(enable_wan_access=True)
+
+    def test_unknown_settings_are_rejected(self):
+        with pytest.raises(ValidationError):
+            RuntimeConfigPatch(api_key="x")
+
+
+class TestUpdate:
+    """Tests for applying and auditing changes."""
+
+    @pytest.mark.asyncio
+    async def test_applies_stores_and_audits(self, service, mock_settings, mock_pipeline):
+        with patch("src.services.hot_config.SecurityAudit") as audit:
+            changes = await service.update(
+                RuntimeConfigPatch(max_execution_time=60, max_output_files=10), actor="10.0.0.1", reason="incident"
+            )
+
+        assert mock_settings.max_execution_time == 60
+        # Unchanged values aren't recorded
+        assert [(c.setting, c.old, c.new) for c in changes] == [("max_execution_time", 30, 60)]
+        mock_pipeline.hset.assert_called_once_with("config:overrides", mapping={"max_execution_time": "60"})
+        entry = ConfigChange.model_validate_json(mock_pipeline.lpush.call_args[0][1])
+        assert entry.actor == "10.0.0.1" and entry.reason == "incident"
+        mock_pipeline.ltrim.assert_called_once_with("config:audit", 0, 999)
+        audit.log_config_change.assert_called_once_with("max_execution_time", 30, 60, "10.0.0.1", "incident")
+        assert service.overridden() == ["max_execution_time"]
+
+    @pytest.mark.asyncio
+    async def test_nothing_is_applied_when_storing_fails(self, service, mock_settings, mock_pipeline):
+        mock_pipeline.execute.side_effect = ConnectionError("redis down")
+
+        with pytest.raises(ConnectionError):
+            await service.update(RuntimeConfigPatch(max_execution_time=60))
+
+        assert mock_settings.max_execution_time == 30
+        assert service.overridden() == []
+
+    @pytest.mark.asyncio
+    async def test_no_op_patch_writes_nothing(self, service, mock_redis):
+        assert await service.update(RuntimeConfigPatch(max_execution_time=30)) == []
+        mock_redis.pipeline.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_concurrency_change_wakes_waiting_executions(self, service):
+        with patch("src.services.hot_config.execution_gate") as gate, patch("src.services.hot_config.SecurityAudit"):
+            gate.limit_changed = AsyncMock()
+            await service.update(RuntimeConfigPatch(max_concurrent_executions=20))
+        gate.limit_changed.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_network_mode_change_is_applied_to_new_pods(self, service, mock_settings):
+        manager = MagicMock()
+        manager.set_network_isolated = AsyncMock(return_value=2)
+        service.set_kubernetes_manager(manager)
+
+        with patch("src.services.hot_config.SecurityAudit"):
+            await service.update(RuntimeConfigPatch(enable_network_isolation=False))
+
+        assert mock_settings.enable_network_isolation is False
+        manager.set_network_isolated.assert_awaited_once_with(False)
+
+
+class TestReset:
+    """Tests for going back to the deployed values."""
+
+    @pytest.mark.asyncio
+    async def test_reset_restores_deployed_values(self, service, mock_settings, mock_pipeline):
+        with patch("src.services.hot_config.SecurityAudit"):
+            await service.update(RuntimeConfigPatch(max_execution_time=60, max_dag_steps=50))
+            changes = await service.reset(["max_execution_time"], actor="10.0.0.1")
+
+        assert [(c.setting, c.old, c.new) for c in changes] == [("max_execution_time", 60, 30)]
+        mock_pipeline.hdel.assert_called_once_with("config:overrides", "max_execution_time")
+        assert mock_settings.max_execution_time == 30
+        assert service.overridden() == ["max_dag_steps"]
+
+    @pytest.mark.asyncio
+    async def test_reset_all_deletes_every_override(self, service, mock_pipeline):
+        with patch("src.services.hot_config.SecurityAudit"):
+            assert await service.reset() == []
+
+        # Overrides stored by other replicas are dropped even if this one has none
+        assert set(mock_pipeline.hdel.call_args[0][1:]) == set(RuntimeConfigPatch.model_fields)
+
+    @pytest.mark.asyncio
+    async def test_reset_rejects_unknown_settings(self, service, mock_redis):
+        with pytest.raises(ValueError, match="api_key"):
+            await service.reset(["api_key"])
+        mock_redis.pipeline.assert_not_called()
+
+
+class TestLoad:
+    """Tests for applying overrides stored by other replicas."""
+
+    @pytest.mark.asyncio
+    async def test_applies_stored_overrides(self, service, mock_redis, mock_settings):
+        mock_redis.hgetall.return_value = {"max_execution_time": "90", "max_connections_per_execution": "5"}
+
+        assert await service.load() == 2
+        assert mock_settings.max_execution_time == 90
+        assert mock_settings.max_connections_per_execution == 5
+
+    @pytest.mark.asyncio
+    async def test_skips_invalid_overrides(self, service, mock_redis, mock_settings):
+        mock_redis.hgetall.return_value = {
+            "max_execution_time": "90",
+            "max_dag_steps": "5000",
+            "removed_setting": "1",
+            "max_output_files": "not json",
+        }
+
+        assert await service.load() == 1
+        assert mock_settings.max_execution_time == 90
+        assert mock_settings.max_dag_steps == 25
+
+    @pytest.mark.asyncio
+    async def test_overrides_reset_elsewhere_go_back_to_deployed_values(self, service, mock_redis, mock_settings):
+        mock_redis.hgetall.return_value = {"max_execution_time": "90"}
+        await service.load()
+
+        mock_redis.hgetall.return_value = {}
+        assert await service.load() == 1
+        assert mock_settings.max_execution_time == 30
+
+    @pytest.mark.asyncio
+    async def test_audit_is_newest_first_and_skips_garbage(self, service, mock_redis):
+        entry = ConfigChange(setting="max_dag_steps", old=25, new=50, changed_at="2026-01-01T00:00:00Z")
+        mock_redis.lrange.return_value = [entry.model_dump_json(), "garbage"]
+
+        assert await service.audit(limit=10) == [entry]
+        mock_redis.lrange.assert_awaited_once_with("config:audit", 0, 9)
+
+
+class TestExecutionGate:
+    """Tests for the per-replica concurrency limit."""
+
+    @pytest.mark.asyncio
+    async def test_holds_executions_past_the_limit(self):
+        limit = {"value": 1}
+        gate = ExecutionGate(limit=lambda: limit["value"])
+        started = []
+
+        async def run(name):
+            async with gate.slot(timeout=5):
+                started.append(name)
+                await asyncio.sleep(0.05)
+
+        first = asyncio.create_task(run("a"))
+        await asyncio.sleep(0.01)
+        second = asyncio.create_task(run("b"))
+        await asyncio.sleep(0.01)
+        assert started == ["a"] and gate.waiting == 1
+
+        await asyncio.gather(first, second)
+        assert started == ["a", "b"] and gate.running == 0
+
+    @pytest.mark.asyncio
+    async def test_raising_the_limit_releases_waiters(self):
+        limit = {"value": 1}
+        gate = ExecutionGate(limit=lambda: limit["value"])
+        release = asyncio.Event()
+
+        async def hold():
+            async with gate.slot(timeout=5):
+                await release.wait()
+
+        holder = asyncio.create_task(hold())
+        await asyncio.sleep(0.01)
+        waiter = asyncio.create_task(hold())
+        await asyncio.sleep(0.01)
+        assert gate.running == 1
+
+        limit["value"] = 2
+        await gate.limit_changed()
+        await asyncio.sleep(0.01)
+        assert gate.running == 2
+
+        release.set()
+        await asyncio.gather(holder, waiter)
+
+    @pytest.mark.asyncio
+    async def test_times_out_when_no_slot_frees_up(self):
+        gate = ExecutionGate(limit=lambda: 0)
+        with pytest.raises(ServiceUnavailableError, match="Too many concurrent executions"):
+            async with gate.slot(timeout=0.01):
+                pass
+        assert gate.waiting == 0 and gate.running == 0
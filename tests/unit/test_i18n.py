@@ -0,0 +1,88 @@
+"""Unit tests for localized error messages."""
+
+import json
+from unittest.mock import patch
+
+import pytest
+
+from src.utils.i18n import get_catalog, localize, negotiate_locale
+
+
+@pytest.fixture
+def mock_settings():
+    with patch("src.utils.i18n.settings") as mock:
+        mock.error_default_locale = "en"
+        mock.error_catalog_path = None
+        get_catalog.cache_clear()
+        yield mock
+    get_catalog.cache_clear()
+
+
+class TestNegotiateLocale:
+    """Tests for Accept-Language negotiation."""
+
+    @pytest.mark.parametrize(
+        "header,expected",
+        [
+            ("de", "de"),
+            ("de-AT,de;q=0.9", "de"),
+            ("ja, fr;q=0.8, es;q=0.9", "es"),
+            ("fr;q=0, de;q=0.5", "de"),
+            ("EN-us", "en"),
+            ("ja", "en"),
+            ("*", "en"),
+            ("", "en"),
+            (None, "en"),
+        ],
+    )
+    def test_negotiation(self, mock_settings, header, expected):
+        assert negotiate_locale(header) == expected
+
+    def test_default_locale(self, mock_settings):
+        mock_settings.error_default_locale = "FR"
+
+        assert negotiate_locale("ja") == "fr"
+
+    def test_unknown_default_falls_back_to_english(self, mock_settings):
+        mock_settings.error_default_locale = "xx"
+
+        assert negotiate_locale(None) == "en"
+
+
+class TestLocalize:
+    """Tests for message lookup."""
+
+    def test_translates_with_params(self, mock_settings):
+        assert localize("timeout", "Execution timed out after 30 seconds", "de", {"timeout": 30}) == (
+            "Zeitüberschreitung nach 30 Sekunden"
+        )
+
+    def test_english_keeps_original(self, mock_settings):
+        assert localize("timeout", "Execution timed out", "en", {"timeout": 30}) == "Execution timed out"
+
+    def test_unknown_code_keeps_original(self, mock_settings):
+        assert localize("no_such_code", "Original", "de") == "Original"
+
+    def test_missing_params_keep_original(self, mock_settings):
+        assert localize("timeout", "Original", "fr") == "Original"
+
+
+class TestCatalogFile:
+    """Tests for ERROR_CATALOG_PATH."""
+
+    def test_file_adds_and_overrides(self, mock_settings, tmp_path):
+        path = tmp_path / "errors.json"
+        path.write_text(json.dumps({"PT-BR": {"timeout": "Tempo esgotado"}, "de": {"session_busy": "Belegt"}}))
+        mock_settings.error_catalog_path = str(path)
+
+        assert negotiate_locale("pt-BR") == "pt-br"
+        assert localize("timeout", "Timed out", "pt-br") == "Tempo esgotado"
+        assert localize("session_busy", "Busy", "de") == "Belegt"
+        assert localize("cell_not_found", "Not found", "de") == "Zelle nicht gefunden"
+
+    def test_invalid_file_uses_builtin(self, mock_settings, tmp_path):
+        path = tmp_path / "errors.json"
+        path.write_text("{not json")
+        mock_settings.error_catalog_path = str(path)
+
+        assert localize("session_busy", "Busy", "es") == "La sesión está ocupada con otra ejecución"
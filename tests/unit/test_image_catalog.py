@@ -0,0 +1,162 @@
+"""Unit tests for the per-API-key image catalog."""
+
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+from pydantic import ValidationError
+
+from src.config import CatalogImage, Settings
+from src.models.image_catalog import TenantImages
+from src.services.image_catalog import ImageCatalogError, ImageCatalogService
+
+DATASCI = "registry/python-datasci@sha256:" + "a" * 64
+CATALOG = {
+    "python:3.11-datasci": CatalogImage(language="py", image=DATASCI, description="pandas, polars, sklearn"),
+    "python:3.12": CatalogImage(language="py", image="registry/python:3.12"),
+    "node:20": CatalogImage(language="js", image="registry/node:20"),
+}
+
+
+@pytest.fixture
+def mock_settings():
+    with patch("src.services.image_catalog.settings") as mock:
+        mock.image_catalog = CATALOG
+        yield mock
+
+
+@pytest.fixture
+def mock_redis():
+    """Create a mock Redis client backed by a dict."""
+    store, index = {}, set()
+    client = MagicMock()
+    client.set = AsyncMock(side_effect=lambda key, value: store.__setitem__(key, value))
+    client.get = AsyncMock(side_effect=lambda key: store.get(key))
+    client.delete = AsyncMock(side_effect=lambda key: 1 if store.pop(key, None) else 0)
+    client.sadd = AsyncMock(side_effect=lambda key, value: index.add(value))
+    client.srem = AsyncMock(side_effect=lambda key, value: index.discard(value))
+    client.smembers = AsyncMock(side_effect=lambda key: set(index))
+    return client
+
+
+@pytest.fixture
+def service(mock_settings, mock_redis):
+    return ImageCatalogService(redis_client=mock_redis)
+
+
+class TestCatalogSettings:
+    """Tests for IMAGE_CATALOG validation."""
+
+    def test_valid_catalog(self):
+        settings = Settings(image_catalog={"python:3.11-datasci": {"language": "PY", "image": DATASCI}})
+
+        assert settings.image_catalog["python:3.11-datasci"].language == "py"
+
+    @pytest.mark.parametrize(
+        "catalog",
+        [
+            {"python": {"language": "py", "image": "x"}},
+            {"Python:3.11": {"language": "py", "image": "x"}},
+            {"cobol:85": {"language": "cobol", "image": "x"}},
+        ],
+    )
+    def test_invalid_catalog(self, catalog):
+        with pytest.raises(ValidationError):
+            Settings(image_catalog=catalog)
+
+
+class TestTenants:
+    """Tests for managing the images each API key may run."""
+
+    @pytest.mark.asyncio
+    async def test_set_and_get(self, service):
+        record = await service.set_tenant(
+            "hash-1", TenantImages(images=["node:20", "node:20"], pins={"PY": "python:3.11-datasci"}), actor="10.0.0.1"
+        )
+
+        assert record.images == ["node:20"]
+        assert record.pins == {"py": "python:3.11-datasci"}
+        assert (await service.get_tenant("hash-1")).updated_by == "10.0.0.1"
+        assert [r.api_key_hash for r in await service.list_tenants()] == ["hash-1"]
+
+    @pytest.mark.asyncio
+    async def test_unknown_images_are_refused(self, service):
+        with pytest.raises(ImageCatalogError) as exc_info:
+            await service.set_tenant("hash-1", TenantImages(images=["ruby:3"], pins={"js": "python:3.12"}))
+
+        assert exc_info.value.code == "invalid_images"
+        assert "Unknown image ruby:3" in str(exc_info.value)
+        assert "python:3.12 runs py, not js" in str(exc_info.value)
+
+    @pytest.mark.asyncio
+    async def test_delete(self, service):
+        await service.set_tenant("hash-1", TenantImages(images=["node:20"]))
+
+        assert await service.delete_tenant("hash-1")
+        assert not await service.delete_tenant("hash-1")
+        assert await service.list_tenants() == []
+
+    @pytest.mark.asyncio
+    async def test_available(self, service):
+        await service.set_tenant("hash-1", TenantImages(images=["node:20"], pins={"py": "python:3.11-datasci"}))
+
+        images = await service.available("hash-1")
+
+        assert [(i.name, i.pinned) for i in images] == [("node:20", False), ("python:3.11-datasci", True)]
+        assert images[1].image == DATASCI
+        assert await service.available("hash-2") == []
+        assert len(await service.available(None, is_env_key=True)) == 3
+
+
+class TestResolve:
+    """Tests for choosing the image an execution runs in."""
+
+    @pytest.mark.asyncio
+    async def test_requested_image(self, service):
+        await service.set_tenant("hash-1", TenantImages(images=["python:3.11-datasci"]))
+
+        image = await service.resolve("py", "python:3.11-datasci", "hash-1")
+
+        assert (image.name, image.image, image.pinned) == ("python:3.11-datasci", DATASCI, False)
+
+    @pytest.mark.asyncio
+    async def test_pinned_image(self, service):
+        await service.set_tenant("hash-1", TenantImages(pins={"py": "python:3.12"}))
+
+        image = await service.resolve("PY", None, "hash-1")
+
+        assert (image.name, image.pinned) == ("python:3.12", True)
+        assert await service.resolve("js", None, "hash-1") is None
+        assert (await service.resolve("py", "python:3.12", "hash-1")).pinned is False
+
+    @pytest.mark.asyncio
+    async def test_default_image(self, service):
+        assert await service.resolve("py", None, "hash-1") is None
+        assert await service.resolve("py", None, None, is_env_key=True) is None
+
+    @pytest.mark.asyncio
+    async def test_env_key_may_use_any_image(self, service):
+        assert (await service.resolve("js", "node:20", None, is_env_key=True)).image == "registry/node:20"
+
+    @pytest.mark.asyncio
+    @pytest.mark.parametrize(
+        "lang,name,code",
+        [
+            ("py", "ruby:3", "unknown_image"),
+            ("js", "python:3.12", "image_language_mismatch"),
+            ("py", "python:3.12", "image_not_allowed"),
+        ],
+    )
+    async def test_refused(self, service, lang, name, code):
+        await service.set_tenant("hash-1", TenantImages(images=["node:20"]))
+
+        with pytest.raises(ImageCatalogError) as exc_info:
+            await service.resolve(lang, name, "hash-1")
+
+        assert exc_info.value.code == code
+
+    @pytest.mark.asyncio
+    async def test_without_catalog_no_lookup(self, mock_settings, mock_redis):
+        mock_settings.image_catalog = {}
+
+        assert await ImageCatalogService(redis_client=mock_redis).resolve("py", None, "hash-1") is None
+        mock_redis.get.assert_not_called()
@@ -7,7 +7,7 @@ import pytest
 from kubernetes.client import ApiException
 
 from src.services.kubernetes.job_executor import JobExecutor
-from src.services.kubernetes.models import ExecutionResult, FileData, JobHandle, PodSpec
+from src.services.kubernetes.models import ExecutionOptions, ExecutionResult, FileData, JobHandle, PodSpec
 
 
 @pytest.fixture
@@ -290,6 +290,7 @@ class TestExecute:
 
         assert result.exit_code == 1
         assert "Job pod not ready" in result.stderr
+        assert result.error == {"code": "SPAWN_FAILED"}
 
     @pytest.mark.asyncio
     async def test_execute_no_sidecar_url(self, job_executor, job_handle):
@@ -414,6 +415,26 @@ class TestUploadFiles:
         await job_executor._upload_files(mock_client, "http://10.0.0.1:8080", files)
 
 
+    @pytest.mark.asyncio
+    async def test_upload_files_into_read_only_workspace(self, job_executor):
+        """Test the workspace is created, filled, then made read-only."""
+        mock_client = AsyncMock()
+        mock_client.put = AsyncMock(return_value=MagicMock(status_code=201))
+        mock_client.post = AsyncMock(return_value=MagicMock(status_code=200))
+        options = ExecutionOptions(workspace="ws-1", workspace_policy={"quota_bytes": 1024, "read_only": True})
+
+        files = [FileData(filename="input.csv", content=b"a,b")]
+
+        await job_executor._upload_files(mock_client, "http://10.0.0.1:8080", files, options)
+
+        puts = [(call.args[0], call.kwargs["json"]) for call in mock_client.put.call_args_list]
+        assert puts == [
+            ("http://10.0.0.1:8080/workspaces/ws-1", {"quota_bytes": 1024}),
+            ("http://10.0.0.1:8080/workspaces/ws-1", {"quota_bytes": 1024, "read_only": True}),
+        ]
+        assert mock_client.post.call_args.kwargs["params"] == {"workspace": "ws-1"}
+
+
 class TestDeleteJob:
     """Tests for delete_job method."""
 
@@ -477,6 +498,26 @@ class TestExecuteWithJob:
         assert result.exit_code == 0
         assert result.stdout == "Hello"
 
+    @pytest.mark.asyncio
+    async def test_execute_with_job_reports_queue_wait(self, job_executor, pod_spec, job_handle):
+        """Test the time to get the Job's pod running is added to the sidecar's timings."""
+        mock_result = ExecutionResult(
+            exit_code=0,
+            stdout="Hello",
+            stderr="",
+            execution_time_ms=100,
+            timings={"spawn_ms": 5, "first_output_ms": 20, "total_ms": 40},
+        )
+
+        with patch.object(job_executor, "create_job", return_value=job_handle):
+            with patch.object(job_executor, "wait_for_pod_ready", return_value=True):
+                with patch.object(job_executor, "execute", return_value=mock_result):
+                    with patch.object(job_executor, "delete_job", return_value=None):
+                        result = await job_executor.execute_with_job(pod_spec, "session-123", "print('Hello')")
+
+        assert result.timings["queue_wait_ms"] >= 0
+        assert result.timings["total_ms"] == 40
+
     @pytest.mark.asyncio
     async def test_execute_with_job_pod_not_ready(self, job_executor, pod_spec, job_handle):
         """Test execution when pod fails to start."""
@@ -7,6 +7,8 @@ import pytest
 from kubernetes.client import ApiException
 
 from src.services.kubernetes import client
+from src.services.kubernetes.models import CompileCache, DatasetMount, DnsPolicy
+from src.services.kubernetes.storage import ObjectStorage, TmpfsStorage, build_workspace_storage
 
 
 @pytest.fixture(autouse=True)
@@ -509,3 +511,210 @@ class TestCreatePodManifest:
         env_dict = {e.name: e.value for e in sidecar.env}
         assert "NETWORK_ISOLATED" in env_dict
         assert env_dict["NETWORK_ISOLATED"] == "false"
+
+    def test_create_pod_manifest_datasets(self):
+        """Test datasets are mounted read-only into the main container only."""
+        pod = client.create_pod_manifest(
+            name="test-pod",
+            namespace="test-ns",
+            main_image="python:3.12",
+            sidecar_image="sidecar:latest",
+            language="python",
+            labels={"app": "test"},
+            datasets=[DatasetMount(name="sales", host_path="/var/lib/kubecoderun/datasets/sha256/abc")],
+        )
+
+        volume = next(v for v in pod.spec.volumes if v.name == "dataset-0")
+        assert volume.host_path.path == "/var/lib/kubecoderun/datasets/sha256/abc"
+        main_container = next(c for c in pod.spec.containers if c.name == "main")
+        mount = next(m for m in main_container.volume_mounts if m.name == "dataset-0")
+        assert mount.mount_path == "/mnt/datasets/sales"
+        assert mount.read_only is True
+        sidecar = next(c for c in pod.spec.containers if c.name == "sidecar")
+        assert all(m.name != "dataset-0" for m in sidecar.volume_mounts)
+
+    def test_create_pod_manifest_compile_cache(self):
+        """Test the compile cache is mounted read-write into both containers, and the sidecar gets its quota."""
+        pod = client.create_pod_manifest(
+            name="test-pod",
+            namespace="test-ns",
+            main_image="gcc:latest",
+            sidecar_image="sidecar:latest",
+            language="cpp",
+            labels={"app": "test"},
+            compile_cache=CompileCache(host_path="/var/lib/kubecoderun/compile-cache", max_size_mb=4096),
+        )
+
+        volume = next(v for v in pod.spec.volumes if v.name == "compile-cache")
+        assert (volume.host_path.path, volume.host_path.type) == ("/var/lib/kubecoderun/compile-cache", "Directory")
+        for container in pod.spec.containers:
+            mount = next(m for m in container.volume_mounts if m.name == "compile-cache")
+            assert mount.mount_path == "/mnt/cache" and not mount.read_only
+        sidecar = next(c for c in pod.spec.containers if c.name == "sidecar")
+        env = {e.name: e.value for e in sidecar.env}
+        assert (env["COMPILE_CACHE_DIR"], env["COMPILE_CACHE_MAX_MB"]) == ("/mnt/cache", "4096")
+
+        pod = client.create_pod_manifest(
+            name="test-pod",
+            namespace="test-ns",
+            main_image="gcc:latest",
+            sidecar_image="sidecar:latest",
+            language="cpp",
+            labels={"app": "test"},
+        )
+        assert all(v.name != "compile-cache" for v in pod.spec.volumes)
+
+    def test_create_pod_manifest_dns_policy(self):
+        """Test a DNS policy points the pod's resolver at the sidecar."""
+        pod = client.create_pod_manifest(
+            name="test-pod",
+            namespace="test-ns",
+            main_image="python:3.12",
+            sidecar_image="sidecar:latest",
+            language="python",
+            labels={"app": "test"},
+            dns_policy=DnsPolicy(upstream="10.96.0.10", allowlist=["pypi.org", "*.pythonhosted.org"]),
+        )
+
+        assert pod.spec.dns_policy == "None"
+        assert pod.spec.dns_config.nameservers == ["127.0.0.1", "::1"]
+        assert pod.spec.security_context.sysctls[0].name == "net.ipv4.ip_unprivileged_port_start"
+        sidecar = next(c for c in pod.spec.containers if c.name == "sidecar")
+        env_dict = {e.name: e.value for e in sidecar.env}
+        assert env_dict["DNS_UPSTREAM"] == "10.96.0.10"
+        assert env_dict["DNS_ALLOWLIST"] == "pypi.org,*.pythonhosted.org"
+
+    def test_create_pod_manifest_without_dns_policy(self):
+        """Test pods use the cluster's DNS by default."""
+        pod = client.create_pod_manifest(
+            name="test-pod",
+            namespace="test-ns",
+            main_image="python:3.12",
+            sidecar_image="sidecar:latest",
+            language="python",
+            labels={"app": "test"},
+        )
+
+        assert pod.spec.dns_policy is None
+        assert pod.spec.dns_config is None
+        sidecar = next(c for c in pod.spec.containers if c.name == "sidecar")
+        assert "DNS_UPSTREAM" not in {e.name for e in sidecar.env}
+
+    def test_create_pod_manifest_fault_injection(self):
+        """Test the fault injection spec reaches the sidecar, and is absent by default."""
+        kwargs = dict(
+            name="test-pod",
+            namespace="test-ns",
+            main_image="python:3.12",
+            sidecar_image="sidecar:latest",
+            language="python",
+            labels={"app": "test"},
+        )
+
+        pod = client.create_pod_manifest(**kwargs, fault_injection="drop=0.1")
+        default = client.create_pod_manifest(**kwargs)
+
+        sidecar = next(c for c in pod.spec.containers if c.name == "sidecar")
+        assert {e.name: e.value for e in sidecar.env}["FAULT_INJECTION"] == "drop=0.1"
+        sidecar = next(c for c in default.spec.containers if c.name == "sidecar")
+        assert "FAULT_INJECTION" not in {e.name for e in sidecar.env}
+
+    def test_create_pod_manifest_policy_bundle(self):
+        """Test the policy bundle reaches the sidecar, and is absent by default."""
+        kwargs = dict(
+            name="test-pod",
+            namespace="test-ns",
+            main_image="python:3.12",
+            sidecar_image="sidecar:latest",
+            language="python",
+            labels={"app": "test"},
+        )
+
+        pod = client.create_pod_manifest(**kwargs, policy_bundle='{"name":"p","version":"1"}')
+        default = client.create_pod_manifest(**kwargs)
+
+        sidecar = next(c for c in pod.spec.containers if c.name == "sidecar")
+        assert {e.name: e.value for e in sidecar.env}["POLICY_BUNDLE"] == '{"name":"p","version":"1"}'
+        sidecar = next(c for c in default.spec.containers if c.name == "sidecar")
+        assert "POLICY_BUNDLE" not in {e.name for e in sidecar.env}
+
+    def test_create_pod_manifest_strict_mode(self):
+        """Test strict mode reaches the sidecar, and is absent by default."""
+        kwargs = dict(
+            name="test-pod",
+            namespace="test-ns",
+            main_image="python:3.12",
+            sidecar_image="sidecar:latest",
+            language="python",
+            labels={"app": "test"},
+        )
+
+        pod = client.create_pod_manifest(**kwargs, strict_mode=True)
+        default = client.create_pod_manifest(**kwargs)
+
+        sidecar = next(c for c in pod.spec.containers if c.name == "sidecar")
+        assert {e.name: e.value for e in sidecar.env}["STRICT_MODE"] == "true"
+        sidecar = next(c for c in default.spec.containers if c.name == "sidecar")
+        assert "STRICT_MODE" not in {e.name for e in sidecar.env}
+
+    def test_create_pod_manifest_read_only_root_filesystem(self):
+        """Test read-only root filesystems come with a /tmp emptyDir in both containers."""
+        kwargs = dict(
+            name="test-pod",
+            namespace="test-ns",
+            main_image="python:3.12",
+            sidecar_image="sidecar:latest",
+            language="python",
+            labels={"app": "test"},
+        )
+
+        pod = client.create_pod_manifest(**kwargs, read_only_root_filesystem=True)
+        default = client.create_pod_manifest(**kwargs)
+
+        assert "scratch" in {v.name for v in pod.spec.volumes}
+        for container in pod.spec.containers:
+            assert container.security_context.read_only_root_filesystem is True
+            assert {m.mount_path: m.name for m in container.volume_mounts}["/tmp"] == "scratch"
+        assert "scratch" not in {v.name for v in default.spec.volumes}
+        for container in default.spec.containers:
+            assert container.security_context.read_only_root_filesystem is None
+
+    def test_create_pod_manifest_workspace_storage(self):
+        """Test the working directory volume follows the storage driver, and the sidecar is told which."""
+        kwargs = dict(
+            name="test-pod",
+            namespace="test-ns",
+            main_image="python:3.12",
+            sidecar_image="sidecar:latest",
+            language="python",
+            labels={"app": "test"},
+        )
+
+        def workspace(pod):
+            volume = next(v for v in pod.spec.volumes if v.name == "shared-data")
+            sidecar = next(c for c in pod.spec.containers if c.name == "sidecar")
+            return volume, {e.name: e.value for e in sidecar.env}["WORKSPACE_STORAGE"]
+
+        volume, driver = workspace(client.create_pod_manifest(**kwargs))
+        assert (volume.empty_dir.medium, volume.empty_dir.size_limit, driver) == ("", "1Gi", "local")
+
+        volume, driver = workspace(client.create_pod_manifest(**kwargs, workspace_storage=TmpfsStorage("256Mi")))
+        assert (volume.empty_dir.medium, volume.empty_dir.size_limit, driver) == ("Memory", "256Mi", "tmpfs")
+
+        pod = client.create_pod_manifest(**kwargs, workspace_storage=ObjectStorage("csi-s3", "100Gi"))
+        volume, driver = workspace(pod)
+        claim = volume.ephemeral.volume_claim_template.spec
+        assert volume.empty_dir is None and driver == "object"
+        assert claim.storage_class_name == "csi-s3"
+        assert claim.resources.requests == {"storage": "100Gi"}
+        for container in pod.spec.containers:
+            assert {m.mount_path: m.name for m in container.volume_mounts}["/mnt/data"] == "shared-data"
+
+    def test_build_workspace_storage(self):
+        """Test drivers are built by name, and object storage needs a storage class."""
+        assert build_workspace_storage("tmpfs", "2Gi").size_limit == "2Gi"
+        assert build_workspace_storage("object", storage_class="csi-s3").storage_class == "csi-s3"
+        with pytest.raises(ValueError, match="WORKSPACE_STORAGE_CLASS"):
+            build_workspace_storage("object")
+        with pytest.raises(ValueError, match="Unknown"):
+            build_workspace_storage("nfs")
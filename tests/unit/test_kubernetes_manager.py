@@ -5,7 +5,7 @@ from unittest.mock import AsyncMock, MagicMock, patch
 import pytest
 
 from src.services.kubernetes.manager import KubernetesManager
-from src.services.kubernetes.models import ExecutionResult, FileData, PodHandle, PoolConfig
+from src.services.kubernetes.models import Elevation, ExecutionResult, FileData, PodHandle, PoolConfig
 
 
 @pytest.fixture
@@ -321,6 +321,7 @@ class TestExecuteCode:
         assert result is sample_execution_result
         assert handle is sample_pod_handle
         assert source == "pool_hit"
+        assert result.timings["queue_wait_ms"] >= 0
 
     @pytest.mark.asyncio
     async def test_execute_code_with_job(
@@ -369,6 +370,51 @@ class TestExecuteCode:
         execute_call = mock_pool_manager.execute.call_args
         assert execute_call is not None
 
+    @pytest.mark.asyncio
+    async def test_elevated_execution_gets_own_job_pod(
+        self, kubernetes_manager, mock_pool_manager, mock_job_executor, sample_execution_result
+    ):
+        """Test elevated executions skip the pool and get the grant's limits and labels."""
+        mock_pool_manager.uses_pool.return_value = True
+        mock_job_executor.active_deadline_seconds = 300
+        mock_job_executor.execute_with_job.return_value = sample_execution_result
+
+        _, handle, source = await kubernetes_manager.execute_code(
+            session_id="session-123",
+            code="print('hello')",
+            language="python",
+            timeout=900,
+            elevation=Elevation(grant_id="grant-1", network=True, memory_mb=2048),
+        )
+
+        assert handle is None and source == "job"
+        mock_pool_manager.acquire.assert_not_called()
+        spec = mock_job_executor.execute_with_job.call_args[0][0]
+        assert spec.labels["kubecoderun.io/elevated"] == "true"
+        assert spec.labels["kubecoderun.io/elevation-grant"] == "grant-1"
+        assert spec.labels["kubecoderun.io/elevated-network"] == "true"
+        assert spec.network_isolated is False
+        assert spec.memory_limit == spec.sidecar_memory_limit == "2048Mi"
+        assert spec.max_execution_time == 900 and spec.active_deadline_seconds == 960
+
+    @pytest.mark.asyncio
+    async def test_catalog_image_gets_own_job_pod(
+        self, kubernetes_manager, mock_pool_manager, mock_job_executor, sample_execution_result
+    ):
+        """Test executions in another image than the language's skip the pool and record the image."""
+        mock_pool_manager.uses_pool.return_value = True
+        mock_pool_manager.get_config.return_value = MagicMock(image="registry/python:latest")
+        mock_job_executor.execute_with_job.return_value = sample_execution_result
+
+        result, handle, source = await kubernetes_manager.execute_code(
+            session_id="session-123", code="print('hello')", language="python", image="registry/py-datasci@sha256:abc"
+        )
+
+        assert handle is None and source == "job"
+        mock_pool_manager.acquire.assert_not_called()
+        assert mock_job_executor.execute_with_job.call_args[0][0].image == "registry/py-datasci@sha256:abc"
+        assert result.image == "registry/py-datasci@sha256:abc"
+
 
 class TestDestroyPod:
     """Tests for destroy_pod method."""
@@ -494,3 +540,86 @@ class TestGetPoolStats:
         result = kubernetes_manager.get_pool_stats()
 
         assert result == expected_stats
+
+
+class TestInterruptSession:
+    """Tests for interrupt_session method."""
+
+    @pytest.mark.asyncio
+    async def test_interrupt_pool_and_job_pods(
+        self, kubernetes_manager, mock_pool_manager, mock_job_executor, sample_pod_handle
+    ):
+        """Test every running execution of the session is signalled."""
+        job = MagicMock(sidecar_url="http://10.0.0.2:8080")
+        mock_pool_manager.get_session_pods = MagicMock(return_value=[sample_pod_handle])
+        mock_job_executor.get_session_jobs = MagicMock(return_value=[job])
+
+        with patch("httpx.AsyncClient") as mock_client_cls:
+            mock_client = AsyncMock()
+            mock_client.__aenter__.return_value = mock_client
+            mock_client.__aexit__.return_value = None
+            mock_response = MagicMock()
+            mock_response.status_code = 200
+            mock_response.json.return_value = {"interrupted": 1}
+            mock_client.post = AsyncMock(return_value=mock_response)
+            mock_client_cls.return_value = mock_client
+
+            count = await kubernetes_manager.interrupt_session("session-123")
+
+        assert count == 2
+        urls = [call.args[0] for call in mock_client.post.call_args_list]
+        assert urls == ["http://10.0.0.1:8080/interrupt", "http://10.0.0.2:8080/interrupt"]
+        mock_pool_manager.get_session_pods.assert_called_once_with("session-123")
+
+    @pytest.mark.asyncio
+    async def test_interrupt_idle_session(self, kubernetes_manager, mock_pool_manager, mock_job_executor):
+        """Test nothing is contacted when the session has no running executions."""
+        mock_pool_manager.get_session_pods = MagicMock(return_value=[])
+        mock_job_executor.get_session_jobs = MagicMock(return_value=[])
+
+        with patch("httpx.AsyncClient") as mock_client_cls:
+            count = await kubernetes_manager.interrupt_session("session-123")
+
+        assert count == 0
+        mock_client_cls.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_interrupt_sidecar_error(
+        self, kubernetes_manager, mock_pool_manager, mock_job_executor, sample_pod_handle
+    ):
+        """Test unreachable sidecars are skipped."""
+        mock_pool_manager.get_session_pods = MagicMock(return_value=[sample_pod_handle])
+        mock_job_executor.get_session_jobs = MagicMock(return_value=[])
+
+        with patch("httpx.AsyncClient") as mock_client_cls:
+            mock_client = AsyncMock()
+            mock_client.__aenter__.return_value = mock_client
+            mock_client.__aexit__.return_value = None
+            mock_client.post = AsyncMock(side_effect=Exception("Connection refused"))
+            mock_client_cls.return_value = mock_client
+
+            count = await kubernetes_manager.interrupt_session("session-123")
+
+        assert count == 0
+
+    @pytest.mark.asyncio
+    async def test_kill_posts_to_kill_endpoint(self, kubernetes_manager, mock_pool_manager, mock_job_executor):
+        """Test a kill reaches the session's job pods through the sidecar kill endpoint."""
+        job = MagicMock(sidecar_url="http://10.0.0.2:8080")
+        mock_pool_manager.get_session_pods = MagicMock(return_value=[])
+        mock_job_executor.get_session_jobs = MagicMock(return_value=[job])
+
+        with patch("httpx.AsyncClient") as mock_client_cls:
+            mock_client = AsyncMock()
+            mock_client.__aenter__.return_value = mock_client
+            mock_client.__aexit__.return_value = None
+            mock_response = MagicMock()
+            mock_response.status_code = 200
+            mock_response.json.return_value = {"killed": 1}
+            mock_client.post = AsyncMock(return_value=mock_response)
+            mock_client_cls.return_value = mock_client
+
+            count = await kubernetes_manager.kill_session("session-123")
+
+        assert count == 1
+        mock_client.post.assert_called_once_with("http://10.0.0.2:8080/kill")
@@ -0,0 +1,72 @@
+"""Tests for log file rotation and retention."""
+
+import logging
+import os
+import time
+
+import pytest
+
+from src.utils.log_rotation import (
+    SizeRotatingFileHandler,
+    TimeRotatingFileHandler,
+    rotating_file_handler,
+)
+
+
+def _record(message: str) -> logging.LogRecord:
+    return logging.LogRecord("test", logging.INFO, __file__, 1, message, None, None)
+
+
+class TestRotatingFileHandler:
+    def test_size_rotation_keeps_backup_count(self, tmp_path):
+        path = tmp_path / "logs" / "api.log"
+        handler = rotating_file_handler(path, max_bytes=100, backup_count=2)
+        try:
+            for i in range(20):
+                handler.emit(_record(f"line {i:02d} " + "x" * 40))
+        finally:
+            handler.close()
+
+        assert isinstance(handler, SizeRotatingFileHandler)
+        assert sorted(p.name for p in path.parent.iterdir()) == ["api.log", "api.log.1", "api.log.2"]
+
+    def test_time_rotation(self, tmp_path):
+        handler = rotating_file_handler(tmp_path / "api.log", rotation="time", when="H")
+        handler.close()
+
+        assert isinstance(handler, TimeRotatingFileHandler)
+        assert handler.when == "H"
+        assert handler.utc is True
+
+    def test_retention_removes_old_rotated_files(self, tmp_path):
+        path = tmp_path / "api.log"
+        expired = tmp_path / "api.log.3"
+        expired.write_text("old")
+        old = time.time() - 3 * 86400
+        os.utime(expired, (old, old))
+        recent = tmp_path / "api.log.1"
+        recent.write_text("new")
+        unrelated = tmp_path / "other.log.1"
+        unrelated.write_text("other")
+        os.utime(unrelated, (old, old))
+
+        handler = rotating_file_handler(path, retention_days=2)
+        try:
+            assert not expired.exists()
+            assert recent.exists()
+            assert unrelated.exists()
+
+            # Renaming api.log.1 to api.log.2 keeps its old mtime
+            os.utime(recent, (old, old))
+            handler.doRollover()
+        finally:
+            handler.close()
+
+        assert not (tmp_path / "api.log.2").exists()
+        assert recent.exists()
+
+    def test_unknown_rotation(self, tmp_path):
+        with pytest.raises(ValueError, match="Unknown log rotation"):
+            rotating_file_handler(tmp_path / "api.log", rotation="weekly")
+        with pytest.raises(ValueError, match="schedule"):
+            rotating_file_handler(tmp_path / "api.log", rotation="time", when="fortnight")
@@ -0,0 +1,127 @@
+"""Unit tests for language server queries."""
+
+from types import SimpleNamespace
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import httpx
+import pytest
+
+from src.models.errors import ResourceNotFoundError, ServiceUnavailableError, ValidationError
+from src.models.exec import RequestFile
+from src.models.lsp import LspQueryRequest
+from src.services.lsp import LspProxyService
+
+
+def sidecar_response(status_code=200, body=None):
+    return httpx.Response(status_code, json=body or {}, request=httpx.Request("POST", "http://pod"))
+
+
+@pytest.fixture
+def handle():
+    return SimpleNamespace(sidecar_url="http://10.0.0.1:8080")
+
+
+@pytest.fixture
+def kubernetes_manager(handle):
+    manager = MagicMock()
+    manager.acquire_pod = AsyncMock(return_value=(handle, "pool_hit"))
+    manager.destroy_pod = AsyncMock()
+    return manager
+
+
+@pytest.fixture
+def file_service():
+    service = MagicMock()
+    service.get_file_info = AsyncMock(return_value=SimpleNamespace(filename="util.py"))
+    service.get_file_content = AsyncMock(return_value=b"def helper(): pass\n")
+    return service
+
+
+@pytest.fixture
+def client():
+    client = MagicMock()
+    client.post = AsyncMock(return_value=sidecar_response(body={"server": "python", "diagnostics": []}))
+    client.patch = AsyncMock(return_value=sidecar_response())
+    client.put = AsyncMock(return_value=sidecar_response())
+    async_client = MagicMock()
+    async_client.__aenter__ = AsyncMock(return_value=client)
+    async_client.__aexit__ = AsyncMock(return_value=False)
+    with patch("src.services.lsp.httpx.AsyncClient", return_value=async_client):
+        yield client
+
+
+@pytest.fixture
+def service(kubernetes_manager, file_service):
+    return LspProxyService(kubernetes_manager=kubernetes_manager, file_service=file_service)
+
+
+class TestQuery:
+    """Tests for proxying queries to a pod's language server."""
+
+    @pytest.mark.asyncio
+    async def test_uploads_files_and_code_then_queries(self, service, client, kubernetes_manager, handle):
+        request = LspQueryRequest(
+            language="py",
+            method="diagnostics",
+            path="main.py",
+            code="import util\n",
+            files=[RequestFile(id="f1", session_id="s1", name="util.py")],
+        )
+
+        response = await service.query(request)
+
+        assert response.server == "python" and response.diagnostics == []
+        kubernetes_manager.acquire_pod.assert_awaited_once_with("s1", "py")
+        assert client.post.call_args_list[0].args[0] == "http://10.0.0.1:8080/files"
+        patch_call = client.patch.call_args
+        assert patch_call.args[0] == "http://10.0.0.1:8080/files/main.py"
+        assert patch_call.kwargs["content"] == b"import util\n"
+        assert client.post.call_args.args[0] == "http://10.0.0.1:8080/lsp/diagnostics"
+        assert client.post.call_args.kwargs["json"] == {"path": "main.py", "line": 1, "column": 1}
+        kubernetes_manager.destroy_pod.assert_awaited_once_with(handle)
+
+    @pytest.mark.asyncio
+    async def test_hover_result(self, service, client):
+        client.post.return_value = sidecar_response(body={"server": "python", "hover": {"contents": "int"}})
+
+        response = await service.query(LspQueryRequest(language="py", method="hover", path="a.py", line=3, column=5))
+
+        assert response.hover == {"contents": "int"}
+        assert client.post.call_args.kwargs["json"] == {"path": "a.py", "line": 3, "column": 5}
+
+    @pytest.mark.asyncio
+    async def test_language_without_pool(self, service, kubernetes_manager):
+        kubernetes_manager.acquire_pod.return_value = (None, "pool_miss")
+
+        with pytest.raises(ServiceUnavailableError, match="warm pod pool for go"):
+            await service.query(LspQueryRequest(language="go", method="diagnostics", path="main.go"))
+
+    @pytest.mark.asyncio
+    async def test_missing_session_file(self, service, file_service, kubernetes_manager):
+        file_service.get_file_info.return_value = None
+        request = LspQueryRequest(
+            language="py", method="hover", path="a.py", files=[RequestFile(id="gone", session_id="s1", name="x")]
+        )
+
+        with pytest.raises(ResourceNotFoundError):
+            await service.query(request)
+        kubernetes_manager.acquire_pod.assert_not_called()
+
+    @pytest.mark.asyncio
+    @pytest.mark.parametrize(
+        "status_code,error",
+        [(404, ResourceNotFoundError), (415, ValidationError), (503, ServiceUnavailableError)],
+    )
+    async def test_sidecar_errors(self, service, client, kubernetes_manager, status_code, error):
+        client.post.return_value = sidecar_response(status_code, {"detail": "nope"})
+
+        with pytest.raises(error):
+            await service.query(LspQueryRequest(language="py", method="definition", path="a.py"))
+        kubernetes_manager.destroy_pod.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_no_kubernetes(self, file_service):
+        with pytest.raises(ServiceUnavailableError):
+            await LspProxyService(None, file_service).query(
+                LspQueryRequest(language="py", method="diagnostics", path="a.py")
+            )
@@ -0,0 +1,156 @@
+"""Unit tests for session export (notebook, HTML and script)."""
+
+import base64
+import json
+from datetime import UTC, datetime
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from src.models.cell import CellInfo
+from src.models.exec import FileRef
+from src.services.notebook import (
+    MAX_EMBEDDED_IMAGE_BYTES,
+    build_notebook,
+    image_mime_type,
+    load_images,
+    render_export,
+    render_html,
+    render_script,
+)
+
+PNG = b"\x89PNG\r\n\x1a\nfake"
+
+
+def _cell(cell_id=1, **overrides):
+    fields = {
+        "code": "print('hi')",
+        "lang": "py",
+        "status": "completed",
+        "exit_code": 0,
+        "started_at": datetime(2025, 1, 1, tzinfo=UTC),
+        "duration_ms": 5,
+    }
+    fields.update(overrides)
+    return CellInfo(cell_id=cell_id, **fields)
+
+
+class TestImageMimeType:
+    """Tests for picking embeddable images."""
+
+    @pytest.mark.parametrize(
+        "name,expected",
+        [("plot.png", "image/png"), ("a/B.JPG", "image/jpeg"), ("chart.svg", "image/svg+xml"), ("data.csv", None)],
+    )
+    def test_image_mime_type(self, name, expected):
+        assert image_mime_type(name) == expected
+
+
+class TestLoadImages:
+    """Tests for fetching generated images."""
+
+    @pytest.mark.asyncio
+    async def test_loads_only_small_images(self):
+        """Non-images, missing files and oversized images are skipped."""
+        contents = {"f1": PNG, "f3": None, "f4": b"x" * (MAX_EMBEDDED_IMAGE_BYTES + 1)}
+        file_service = MagicMock()
+        file_service.get_file_content = AsyncMock(side_effect=lambda sid, fid: contents[fid])
+        cell = _cell(
+            files=[
+                FileRef(id="f1", name="plot.png"),
+                FileRef(id="f2", name="out.csv"),
+                FileRef(id="f3", name="gone.png"),
+                FileRef(id="f4", name="huge.png"),
+            ]
+        )
+
+        images = await load_images(file_service, "session-123", [cell])
+
+        assert images == {"f1": ("image/png", PNG)}
+        assert file_service.get_file_content.await_count == 3
+
+    @pytest.mark.asyncio
+    async def test_load_failure_skipped(self):
+        file_service = MagicMock()
+        file_service.get_file_content = AsyncMock(side_effect=Exception("MinIO down"))
+
+        images = await load_images(file_service, "session-123", [_cell(files=[FileRef(id="f1", name="a.png")])])
+
+        assert images == {}
+
+
+class TestBuildNotebook:
+    """Tests for the ipynb export."""
+
+    def test_cells_and_outputs(self):
+        """Cells keep their number, source and outputs; images are embedded."""
+        cells = [
+            _cell(1, code="x = 1\nprint(x)", stdout="1\n"),
+            _cell(2, code="plot()", stderr="warning\n", files=[FileRef(id="f1", name="plot.png")]),
+        ]
+
+        notebook = build_notebook(cells, {"f1": ("image/png", PNG)}, "session-123")
+
+        assert notebook["nbformat"] == 4
+        assert notebook["metadata"]["kernelspec"]["name"] == "python3"
+        first, second = notebook["cells"]
+        assert first["execution_count"] == 1
+        assert first["source"] == ["x = 1\n", "print(x)"]
+        assert first["outputs"] == [{"output_type": "stream", "name": "stdout", "text": ["1\n"]}]
+        assert second["outputs"][0]["name"] == "stderr"
+        display = second["outputs"][1]
+        assert display["output_type"] == "display_data"
+        assert base64.b64decode(display["data"]["image/png"]) == PNG
+        assert second["metadata"]["kubecoderun"]["files"] == ["plot.png"]
+
+    def test_kernel_follows_main_language(self):
+        cells = [_cell(1, lang="r"), _cell(2, lang="r"), _cell(3)]
+
+        assert build_notebook(cells, {}, "s")["metadata"]["kernelspec"]["name"] == "ir"
+
+    def test_unknown_kernel(self):
+        """Languages without a common Jupyter kernel still get a kernelspec."""
+        spec = build_notebook([_cell(lang="go")], {}, "s")["metadata"]["kernelspec"]
+
+        assert spec["name"] == "go"
+        assert spec["language"] == "go"
+
+    def test_ipynb_is_json(self):
+        notebook = json.loads(render_export("ipynb", [_cell()], {}, "session-123"))
+
+        assert notebook["cells"][0]["id"] == "cell-1"
+
+
+class TestRenderHtml:
+    """Tests for the HTML export."""
+
+    def test_escapes_and_embeds(self):
+        cells = [_cell(code="print('<b>')", stdout="<b>\n", files=[FileRef(id="f1", name="p.png")])]
+
+        page = render_html(cells, {"f1": ("image/png", PNG)}, "session-123")
+
+        assert "print(&#x27;&lt;b&gt;&#x27;)" in page
+        assert "<b>" not in page
+        assert f"data:image/png;base64,{base64.b64encode(PNG).decode()}" in page
+
+    def test_names_files_not_embedded(self):
+        page = render_html([_cell(files=[FileRef(id="f2", name="out.csv")])], {}, "session-123")
+
+        assert "Generated file: out.csv" in page
+
+
+class TestRenderScript:
+    """Tests for the py export."""
+
+    def test_python_cells_and_commented_others(self):
+        cells = [_cell(1, code="x = 1\n"), _cell(2, code="console.log(1)\n\nend", lang="js"), _cell(3, code="x")]
+
+        script = render_script(cells, "session-123")
+
+        assert "# %% In [1]\nx = 1\n" in script
+        assert "# %% In [2] (JavaScript, not Python)\n# console.log(1)\n#\n# end\n" in script
+        assert "# %% In [3]\nx\n" in script
+
+    def test_unknown_format(self):
+        with pytest.raises(ValueError):
+            render_export("pdf", [], {}, "session-123")
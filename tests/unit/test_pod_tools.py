@@ -0,0 +1,454 @@
+"""Unit tests for the file tools run in pods against session files."""
+
+import json
+from types import SimpleNamespace
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import httpx
+import pytest
+
+from src.models.errors import ResourceConflictError, ResourceNotFoundError, ServiceUnavailableError, ValidationError
+from src.models.exec import RequestFile, SecretFinding
+from src.models.pod_tools import FilePatchRequest, MediaRequest, RenderRequest, SyncDelta, SyncSignature
+from src.services.pod_tools import PodToolsService
+
+
+def sidecar_response(status_code=200, body=None, content=None):
+    request = httpx.Request("POST", "http://pod")
+    if content is not None:
+        return httpx.Response(status_code, content=content, request=request)
+    return httpx.Response(status_code, json=body or {}, request=request)
+
+
+RENDERED = {
+    "exit_code": 0,
+    "toolchain": "pandoc",
+    "output": {"name": "report.pdf", "path": "/mnt/data/report.pdf", "size": 8},
+    "log": "",
+    "execution_time_ms": 120,
+}
+
+
+def media_stream(response):
+    """A mock for ``client.stream`` answering with ``response``."""
+    context = MagicMock()
+    context.__aenter__ = AsyncMock(return_value=response)
+    context.__aexit__ = AsyncMock(return_value=False)
+    return MagicMock(return_value=context)
+
+
+def ndjson(*events):
+    return sidecar_response(content="".join(json.dumps(event) + "\n" for event in events).encode())
+
+
+@pytest.fixture
+def handle():
+    return SimpleNamespace(sidecar_url="http://10.0.0.1:8080")
+
+
+@pytest.fixture
+def kubernetes_manager(handle):
+    manager = MagicMock()
+    manager.acquire_pod = AsyncMock(return_value=(handle, "pool_hit"))
+    manager.destroy_pod = AsyncMock()
+    return manager
+
+
+@pytest.fixture
+def file_service():
+    service = MagicMock()
+    service.get_file_info = AsyncMock(return_value=SimpleNamespace(filename="report.md"))
+    service.get_file_content = AsyncMock(return_value=b"# Report\n")
+    service.store_execution_output_file = AsyncMock(return_value="out1")
+    return service
+
+
+@pytest.fixture
+def client():
+    client = MagicMock()
+    client.post = AsyncMock(return_value=sidecar_response(body=RENDERED))
+    client.get = AsyncMock(return_value=sidecar_response(content=b"%PDF-1.7"))
+    client.put = AsyncMock(return_value=sidecar_response())
+    async_client = MagicMock()
+    async_client.__aenter__ = AsyncMock(return_value=client)
+    async_client.__aexit__ = AsyncMock(return_value=False)
+    with patch("src.services.pod_tools.httpx.AsyncClient", return_value=async_client):
+        yield client
+
+
+@pytest.fixture
+def service(kubernetes_manager, file_service):
+    return PodToolsService(kubernetes_manager=kubernetes_manager, file_service=file_service)
+
+
+def render_request(**overrides):
+    fields = {
+        "format": "markdown",
+        "path": "report.md",
+        "files": [RequestFile(id="f1", session_id="s1", name="report.md")],
+    }
+    return RenderRequest(**{**fields, **overrides})
+
+
+class TestRender:
+    """Tests for rendering documents into session files."""
+
+    @pytest.mark.asyncio
+    async def test_renders_and_stores_output(self, service, client, kubernetes_manager, file_service, handle):
+        response = await service.render(render_request())
+
+        kubernetes_manager.acquire_pod.assert_awaited_once_with("s1", "py")
+        assert client.post.call_args_list[0].args[0] == "http://10.0.0.1:8080/files"
+        assert client.post.call_args.args[0] == "http://10.0.0.1:8080/render"
+        assert client.post.call_args.kwargs["json"] == {
+            "format": "markdown",
+            "output": "pdf",
+            "source": None,
+            "path": "report.md",
+            "filename": None,
+            "timeout": 60,
+        }
+        assert client.get.call_args.args[0] == "http://10.0.0.1:8080/files/report.pdf"
+        file_service.store_execution_output_file.assert_awaited_once_with("s1", "report.pdf", b"%PDF-1.7")
+        assert response.session_id == "s1" and response.toolchain == "pandoc"
+        assert response.file.id == "out1" and response.file.name == "report.pdf"
+        assert response.file.content_type == "application/pdf"
+        kubernetes_manager.destroy_pod.assert_awaited_once_with(handle)
+
+    @pytest.mark.asyncio
+    async def test_failed_build_stores_nothing(self, service, client, file_service):
+        client.post.return_value = sidecar_response(
+            body={**RENDERED, "exit_code": 1, "output": None, "log": "! Undefined control sequence"}
+        )
+
+        response = await service.render(render_request())
+
+        assert response.exit_code == 1 and response.file is None
+        assert "Undefined control sequence" in response.log
+        file_service.store_execution_output_file.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_inline_source_in_explicit_session(self, service, client, kubernetes_manager):
+        response = await service.render(
+            RenderRequest(format="latex", source="\\documentclass{article}", session_id="s2", language="r")
+        )
+
+        kubernetes_manager.acquire_pod.assert_awaited_once_with("s2", "r")
+        assert response.session_id == "s2"
+        # Nothing to upload: the only post is the render
+        assert client.post.await_count == 1
+
+    @pytest.mark.asyncio
+    async def test_blocked_output_is_not_stored(self, service, client, file_service):
+        finding = SecretFinding(source="file", file="report.pdf", kind="private_key", line=1, action="blocked")
+
+        with patch("src.services.pod_tools.scan_file", return_value=[finding]):
+            response = await service.render(render_request())
+
+        assert response.file is None and response.secret_findings == [finding]
+        file_service.store_execution_output_file.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_source_or_path(self, service, kubernetes_manager):
+        with pytest.raises(ValidationError):
+            await service.render(render_request(source="# Inline"))
+        kubernetes_manager.acquire_pod.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_missing_session_file(self, service, file_service, kubernetes_manager):
+        file_service.get_file_info.return_value = None
+
+        with pytest.raises(ResourceNotFoundError):
+            await service.render(render_request())
+        kubernetes_manager.acquire_pod.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_language_without_pool(self, service, kubernetes_manager):
+        kubernetes_manager.acquire_pod.return_value = (None, "pool_miss")
+
+        with pytest.raises(ServiceUnavailableError, match="warm pod pool for py"):
+            await service.render(render_request())
+
+    @pytest.mark.asyncio
+    @pytest.mark.parametrize(
+        "status_code,error",
+        [(404, ResourceNotFoundError), (400, ValidationError), (501, ServiceUnavailableError)],
+    )
+    async def test_sidecar_errors(self, service, client, kubernetes_manager, status_code, error):
+        client.post.side_effect = [sidecar_response(), sidecar_response(status_code, {"detail": "nope"})]
+
+        with pytest.raises(error):
+            await service.render(render_request())
+        kubernetes_manager.destroy_pod.assert_awaited_once()
+
+
+class TestMedia:
+    """Tests for ffmpeg conversions of session files."""
+
+    EVENTS = [
+        {"type": "start", "command": ["ffmpeg"]},
+        {"type": "progress", "percent": 50.0},
+        {
+            "type": "result",
+            "exit_code": 0,
+            "status": "completed",
+            "output": {"name": "clip.webm", "path": "clip.webm", "size": 4},
+            "stderr": "",
+        },
+    ]
+
+    def request(self, **overrides):
+        fields = {"input": RequestFile(id="f1", session_id="s1", name="clip.mp4"), "output": "clip.webm"}
+        return MediaRequest(**{**fields, **overrides})
+
+    @pytest.mark.asyncio
+    async def test_streams_events_and_stores_output(self, service, client, file_service, kubernetes_manager):
+        file_service.get_file_info.return_value = SimpleNamespace(filename="clip.mp4")
+        client.stream = media_stream(ndjson(*self.EVENTS))
+        client.get.return_value = sidecar_response(content=b"webm")
+
+        events = [json.loads(line) async for line in await service.media(self.request(args=["-an"]))]
+
+        assert client.stream.call_args.args == ("POST", "http://10.0.0.1:8080/media")
+        assert client.stream.call_args.kwargs["json"] == {"input": "clip.mp4", "output": "clip.webm", "args": ["-an"]}
+        assert [event["type"] for event in events] == ["start", "progress", "result"]
+        result = events[-1]
+        assert "output" not in result and result["session_id"] == "s1"
+        assert result["file"]["id"] == "out1" and result["file"]["name"] == "clip.webm"
+        file_service.store_execution_output_file.assert_awaited_once_with("s1", "clip.webm", b"webm")
+        kubernetes_manager.destroy_pod.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_failed_conversion_stores_nothing(self, service, client, file_service):
+        failed = {"type": "result", "exit_code": 1, "status": "failed", "output": None, "stderr": "Invalid data"}
+        client.stream = media_stream(ndjson(failed))
+
+        events = [json.loads(line) async for line in await service.media(self.request())]
+
+        assert events[-1]["file"] is None and events[-1]["stderr"] == "Invalid data"
+        file_service.store_execution_output_file.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_existing_output_is_a_conflict(self, service, client, kubernetes_manager):
+        client.stream = media_stream(sidecar_response(409, {"detail": "clip.webm already exists"}))
+
+        with pytest.raises(ResourceConflictError):
+            await service.media(self.request())
+        kubernetes_manager.destroy_pod.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_lost_stream_ends_with_failed_result(self, service, client, kubernetes_manager):
+        response = MagicMock(status_code=200)
+
+        async def aiter_lines():
+            yield json.dumps(self.EVENTS[0])
+            raise httpx.ReadError("connection reset")
+
+        response.aiter_lines = aiter_lines
+        client.stream = media_stream(response)
+
+        events = [json.loads(line) async for line in await service.media(self.request())]
+
+        assert events[-1]["status"] == "failed" and "connection reset" in events[-1]["stderr"]
+        kubernetes_manager.destroy_pod.assert_awaited_once()
+
+
+def stored_file(file_id, filename):
+    return SimpleNamespace(file_id=file_id, filename=filename)
+
+
+class TestSearch:
+    """Tests for searching a session's files."""
+
+    @pytest.mark.asyncio
+    async def test_searches_newest_files_and_names_their_ids(self, service, client, file_service, kubernetes_manager):
+        file_service.list_files = AsyncMock(
+            return_value=[stored_file("old", "app.py"), stored_file("new", "app.py"), stored_file("f2", "util.py")]
+        )
+        match = {"path": "app.py", "line": 3, "column": 5, "text": "def main():", "before": [], "after": []}
+        client.get.return_value = sidecar_response(body={"matches": [match], "files_scanned": 2, "truncated": False})
+
+        response = await service.search("s1", "def main", regex=False)
+
+        uploaded = [call.kwargs["files"]["files"][0] for call in client.post.call_args_list]
+        assert uploaded == ["app.py", "util.py"]
+        assert [call.args[1] for call in file_service.get_file_content.call_args_list] == ["new", "f2"]
+        assert client.get.call_args.args[0] == "http://10.0.0.1:8080/files/search"
+        assert client.get.call_args.kwargs["params"] == {
+            "q": "def main",
+            "glob": "**/*",
+            "regex": False,
+            "ignore_case": False,
+            "context": 2,
+        }
+        assert response.matches[0].file_id == "new" and response.matches[0].line == 3
+        assert response.files_scanned == 2 and not response.truncated
+        kubernetes_manager.destroy_pod.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_invalid_pattern(self, service, client, file_service):
+        file_service.list_files = AsyncMock(return_value=[])
+        client.get.return_value = sidecar_response(400, {"detail": "Invalid pattern: unbalanced parenthesis"})
+
+        with pytest.raises(ValidationError, match="Invalid pattern"):
+            await service.search("s1", "(")
+
+
+class TestApplyPatch:
+    """Tests for applying unified diffs to a session's files."""
+
+    DIFF = "--- a/app.py\n+++ b/app.py\n@@ -1 +1 @@\n-x = 1\n+x = 2\n"
+
+    @pytest.fixture(autouse=True)
+    def session_files(self, file_service):
+        file_service.list_files = AsyncMock(return_value=[stored_file("f1", "app.py"), stored_file("f2", "old.py")])
+        file_service.store_uploaded_file = AsyncMock(return_value="f3")
+        file_service.update_file_content = AsyncMock()
+        file_service.delete_file = AsyncMock(return_value=True)
+
+    @pytest.mark.asyncio
+    async def test_stores_every_change(self, service, client, file_service):
+        summary = [
+            {"path": "app.py", "status": "modified", "hunks": 1},
+            {"path": "new.py", "status": "created", "hunks": 1},
+            {"path": "old.py", "status": "deleted", "hunks": 1},
+        ]
+        uploads = [sidecar_response(), sidecar_response()]
+        client.post.side_effect = [*uploads, sidecar_response(body={"applied": True, "files": summary})]
+        client.get.side_effect = [sidecar_response(content=b"x = 2\n"), sidecar_response(content=b"y = 1\n")]
+
+        response = await service.apply_patch("s1", FilePatchRequest(diff=self.DIFF))
+
+        assert client.post.call_args.args[0] == "http://10.0.0.1:8080/files/patch"
+        assert client.post.call_args.kwargs["json"] == {"diff": self.DIFF, "strip": 1, "dry_run": False}
+        file_service.update_file_content.assert_awaited_once_with("s1", "f1", b"x = 2\n")
+        file_service.store_uploaded_file.assert_awaited_once_with("s1", "new.py", b"y = 1\n")
+        file_service.delete_file.assert_awaited_once_with("s1", "f2")
+        assert [(f.path, f.file_id) for f in response.files] == [("app.py", "f1"), ("new.py", "f3"), ("old.py", None)]
+        assert response.applied
+
+    @pytest.mark.asyncio
+    async def test_dry_run_stores_nothing(self, service, client, file_service):
+        summary = [{"path": "app.py", "status": "modified", "hunks": 1}]
+        uploads = [sidecar_response(), sidecar_response()]
+        client.post.side_effect = [*uploads, sidecar_response(body={"applied": False, "files": summary})]
+
+        response = await service.apply_patch("s1", FilePatchRequest(diff=self.DIFF, dry_run=True))
+
+        assert not response.applied and response.files[0].file_id == "f1"
+        client.get.assert_not_called()
+        file_service.update_file_content.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_rejected_hunks(self, service, client, file_service):
+        detail = {"message": "1 hunk(s) failed to apply", "rejects": [{"path": "app.py", "reason": "Context mismatch"}]}
+        client.post.side_effect = [sidecar_response(), sidecar_response(), sidecar_response(409, {"detail": detail})]
+
+        with pytest.raises(ResourceConflictError) as excinfo:
+            await service.apply_patch("s1", FilePatchRequest(diff=self.DIFF))
+
+        assert excinfo.value.details[0].field == "app.py"
+        file_service.update_file_content.assert_not_called()
+
+
+class TestSymbols:
+    """Tests for outlining a session's source files."""
+
+    @pytest.mark.asyncio
+    async def test_outlines_the_uploaded_file(self, service, client, file_service, kubernetes_manager):
+        file_service.get_file_info.return_value = SimpleNamespace(filename="app.py")
+        outline = {
+            "path": "app.py",
+            "language": "python",
+            "parser": "tree-sitter",
+            "symbols": [
+                {
+                    "name": "App",
+                    "kind": "class",
+                    "start_line": 1,
+                    "end_line": 4,
+                    "children": [{"name": "run", "kind": "method", "start_line": 2, "end_line": 4}],
+                }
+            ],
+        }
+        client.get.return_value = sidecar_response(body=outline)
+
+        response = await service.symbols("s1", "f1")
+
+        file_service.get_file_content.assert_awaited_once_with("s1", "f1")
+        assert client.post.call_args.kwargs["files"]["files"][0] == "app.py"
+        assert client.get.call_args.args[0] == "http://10.0.0.1:8080/files/app.py/symbols"
+        assert response.file_id == "f1" and response.language == "python"
+        assert response.symbols[0].children[0].name == "run"
+        kubernetes_manager.destroy_pod.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_unsupported_file_type(self, service, client):
+        client.get.return_value = sidecar_response(415, {"detail": "No grammar for .md files"})
+
+        with pytest.raises(ValidationError, match="No grammar"):
+            await service.symbols("s1", "f1")
+
+
+class TestSync:
+    """Tests for syncing stored files with signatures and deltas."""
+
+    @pytest.fixture(autouse=True)
+    def stored(self, file_service):
+        file_service.get_file_info.return_value = SimpleNamespace(filename="data.csv")
+        file_service.update_file_content = AsyncMock(return_value=SimpleNamespace(size=12))
+
+    @pytest.mark.asyncio
+    async def test_signature(self, service, client):
+        blocks = [{"weak": 1, "strong": "c"}]
+        client.get.return_value = sidecar_response(
+            body={"path": "data.csv", "size": 9, "sha256": "ab", "block_size": 512, "blocks": blocks}
+        )
+
+        response = await service.sync_signature("s1", "f1", 512)
+
+        assert client.get.call_args.args[0] == "http://10.0.0.1:8080/sync/signature/data.csv"
+        assert client.get.call_args.kwargs["params"] == {"block_size": 512}
+        assert response.file_id == "f1" and response.blocks[0].weak == 1
+
+    @pytest.mark.asyncio
+    async def test_delta(self, service, client):
+        ops = [{"copy": 0, "count": 1}, {"data": "eA=="}]
+        client.post.side_effect = [
+            sidecar_response(),
+            sidecar_response(body={"path": "data.csv", "size": 12, "sha256": "cd", "block_size": 512, "ops": ops}),
+        ]
+
+        response = await service.sync_delta("s1", "f1", SyncSignature(block_size=512, blocks=[]))
+
+        assert client.post.call_args.args[0] == "http://10.0.0.1:8080/sync/delta/data.csv"
+        assert client.post.call_args.kwargs["json"] == {"block_size": 512, "blocks": []}
+        assert response.ops == ops and response.sha256 == "cd"
+
+    @pytest.mark.asyncio
+    async def test_patch_stores_the_result(self, service, client, file_service, kubernetes_manager):
+        delta = SyncDelta(block_size=512, ops=[{"copy": 0, "count": 1}], sha256="cd")
+        client.post.side_effect = [
+            sidecar_response(),
+            sidecar_response(body={"path": "data.csv", "size": 12, "sha256": "cd"}),
+        ]
+        client.get.return_value = sidecar_response(content=b"a,b\n1,2\n3,4\n")
+
+        response = await service.sync_patch("s1", "f1", delta)
+
+        assert client.post.call_args.args[0] == "http://10.0.0.1:8080/sync/patch/data.csv"
+        file_service.update_file_content.assert_awaited_once_with("s1", "f1", b"a,b\n1,2\n3,4\n")
+        assert response.size == 12 and response.sha256 == "cd"
+        kubernetes_manager.destroy_pod.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_patch_against_a_changed_file(self, service, client, file_service):
+        delta = SyncDelta(ops=[{"copy": 0, "count": 1}], sha256="cd")
+        client.post.side_effect = [
+            sidecar_response(),
+            sidecar_response(409, {"detail": "Checksum mismatch after applying delta"}),
+        ]
+
+        with pytest.raises(ResourceConflictError, match="Checksum mismatch"):
+            await service.sync_patch("s1", "f1", delta)
+        file_service.update_file_content.assert_not_called()
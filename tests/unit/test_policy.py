@@ -0,0 +1,180 @@
+"""Unit tests for policy bundles and their dry-run evaluation."""
+
+import json
+
+import pytest
+from pydantic import ValidationError
+
+from src.config import PolicyBundleError, Settings
+from src.config.policy import parse_bundle
+from src.models.policy import PolicyEvaluationRequest
+from src.services.policy import dns_allows, evaluate
+
+BUNDLE = {
+    "name": "prod-default",
+    "version": "2026.10.1",
+    "limits": {"max_execution_time": 60, "max_memory_mb": 1024, "max_connections_per_execution": 20},
+    "languages": ["py", "JS"],
+    "network": {"isolation": False, "wan_access": True, "dns_allowlist": ["pypi.org", "*.pythonhosted.org"]},
+    "redaction": {"filters": ["secrets"], "secret_scan": "block"},
+}
+
+
+@pytest.fixture
+def bundle_path(tmp_path):
+    def write(bundle=BUNDLE):
+        path = tmp_path / f"policy-{len(list(tmp_path.iterdir()))}.json"
+        path.write_text(json.dumps(bundle))
+        return str(path)
+
+    return write
+
+
+class TestParseBundle:
+    """Tests for reading policy bundles."""
+
+    def test_settings_mapping(self):
+        bundle = parse_bundle(json.dumps(BUNDLE))
+
+        assert bundle.settings() == {
+            "max_execution_time": 60,
+            "max_memory_mb": 1024,
+            "max_connections_per_execution": 20,
+            "allowed_languages": ["py", "JS"],
+            "enable_network_isolation": False,
+            "enable_wan_access": True,
+            "dns_allowlist": ["pypi.org", "*.pythonhosted.org"],
+            "output_filters": ["secrets"],
+            "artifact_secret_scan": "block",
+        }
+
+    def test_empty_bundle_sets_nothing(self):
+        assert parse_bundle('{"name": "empty", "version": "1"}').settings() == {}
+
+    def test_pinned_version(self):
+        assert parse_bundle(json.dumps(BUNDLE), "2026.10.1").version == "2026.10.1"
+
+        with pytest.raises(PolicyBundleError, match="pins 2026.9.0"):
+            parse_bundle(json.dumps(BUNDLE), "2026.9.0")
+
+    @pytest.mark.parametrize(
+        "text",
+        [
+            "not json",
+            '{"name": "p"}',
+            '{"name": "p", "version": "1", "limits": {"max_runtime": 5}}',
+            '{"name": "p", "version": "1", "redaction": {"secret_scan": "loud"}}',
+        ],
+    )
+    def test_invalid_bundles(self, text):
+        with pytest.raises(PolicyBundleError):
+            parse_bundle(text)
+
+    def test_digest_ignores_formatting(self):
+        compact = parse_bundle(json.dumps(BUNDLE, separators=(",", ":")))
+        indented = parse_bundle(json.dumps(BUNDLE, indent=4))
+
+        assert compact.digest() == indented.digest()
+        assert compact.digest().startswith("sha256:")
+        assert compact.describe() == {"name": "prod-default", "version": "2026.10.1", "digest": compact.digest()}
+
+    def test_digest_changes_with_contents(self):
+        changed = {**BUNDLE, "languages": ["py"]}
+
+        assert parse_bundle(json.dumps(changed)).digest() != parse_bundle(json.dumps(BUNDLE)).digest()
+
+
+class TestSettingsWithBundle:
+    """Tests for settings loading a policy bundle."""
+
+    def test_bundle_replaces_settings(self, bundle_path):
+        settings = Settings(policy_bundle_path=bundle_path(), max_execution_time=300)
+
+        assert settings.max_execution_time == 60
+        assert settings.allowed_languages == ["py", "js"]
+        assert settings.enable_wan_access is True
+        assert settings.artifact_secret_scan == "block"
+        assert settings.get_policy_bundle().name == "prod-default"
+        assert json.loads(settings.get_policy_bundle_json())["version"] == "2026.10.1"
+
+    def test_no_bundle(self):
+        settings = Settings()
+
+        assert settings.get_policy_bundle() is None
+        assert settings.get_policy_bundle_json() is None
+
+    def test_bundle_values_are_validated(self, bundle_path):
+        with pytest.raises(ValidationError):
+            Settings(policy_bundle_path=bundle_path({"name": "p", "version": "1", "limits": {"max_cpus": -1}}))
+
+    def test_version_mismatch_fails(self, bundle_path):
+        with pytest.raises(ValidationError, match="POLICY_BUNDLE_VERSION"):
+            Settings(policy_bundle_path=bundle_path(), policy_bundle_version="1.0")
+
+    def test_unknown_language_fails(self, bundle_path):
+        with pytest.raises(ValidationError):
+            Settings(policy_bundle_path=bundle_path({"name": "p", "version": "1", "languages": ["cobol"]}))
+
+    def test_language_allowlist(self):
+        assert Settings().is_language_allowed("go")
+
+        settings = Settings(allowed_languages=["py"])
+        assert settings.is_language_allowed("PY")
+        assert not settings.is_language_allowed("go")
+
+
+class TestEvaluate:
+    """Tests for dry-run evaluation."""
+
+    def test_allowed_request(self, bundle_path):
+        config = Settings(policy_bundle_path=bundle_path(), dns_policy_upstream="10.0.0.10")
+
+        result = evaluate(
+            PolicyEvaluationRequest(lang="py", hosts=["pypi.org", "files.pythonhosted.org", "example.com"]), config
+        )
+
+        assert result.allowed and result.denials == []
+        assert result.policy["name"] == "prod-default"
+        assert (result.timeout_seconds, result.memory_mb, result.max_connections) == (60, 1024, 20)
+        assert result.network == "wan"
+        assert result.dns_allowlist == ["pypi.org", "*.pythonhosted.org"]
+        assert result.hosts == {"pypi.org": True, "files.pythonhosted.org": True, "example.com": False}
+        assert (result.output_filters, result.secret_scan) == (["secrets"], "block")
+
+    def test_request_narrows_access(self, bundle_path):
+        config = Settings(policy_bundle_path=bundle_path(), dns_policy_upstream="10.0.0.10")
+
+        request = PolicyEvaluationRequest(
+            lang="py", hosts=["pypi.org", "files.pythonhosted.org"], dns_allowlist=["pypi.org"], max_connections=5
+        )
+
+        result = evaluate(request, config)
+
+        assert result.hosts == {"pypi.org": True, "files.pythonhosted.org": False}
+        assert result.dns_allowlist == ["pypi.org"]
+        assert result.max_connections == 5
+
+    def test_denials(self, bundle_path):
+        config = Settings(policy_bundle_path=bundle_path())
+
+        result = evaluate(PolicyEvaluationRequest(lang="go", dns_allowlist=["pypi.org"]), config)
+
+        assert not result.allowed
+        assert [d.code for d in result.denials] == ["language_not_allowed", "dns_policy_unavailable"]
+
+    def test_unsupported_language(self):
+        result = evaluate(PolicyEvaluationRequest(lang="cobol"), Settings())
+
+        assert [d.code for d in result.denials] == ["unsupported_language"]
+
+    def test_isolated_network(self):
+        result = evaluate(PolicyEvaluationRequest(lang="py", hosts=["pypi.org"]), Settings())
+
+        assert result.policy is None
+        assert result.network == "none"
+        assert result.hosts == {"pypi.org": False}
+
+    def test_dns_allows(self):
+        assert dns_allows("Files.PythonHosted.org.", ["*.pythonhosted.org"])
+        assert not dns_allows("pythonhosted.org", ["*.pythonhosted.org"])
+        assert dns_allows("pypi.org", ["pypi.org"])
@@ -8,6 +8,7 @@ import pytest
 from kubernetes.client import ApiException
 
 from src.services.kubernetes.models import (
+    ExecutionOptions,
     ExecutionResult,
     FileData,
     PodHandle,
@@ -96,6 +97,17 @@ class TestPoolConfig:
         assert config.network_isolated is False
 
 
+class TestPodHandleSidecarUrl:
+    """Tests for the sidecar URL of a pod."""
+
+    def test_ipv4(self, pod_handle):
+        assert pod_handle.sidecar_url == "http://10.0.0.1:8080"
+
+    def test_ipv6_in_brackets(self, pod_handle):
+        pod_handle.pod_ip = "fd00:10::1a"
+        assert pod_handle.sidecar_url == "http://[fd00:10::1a]:8080"
+
+
 class TestPodPoolInit:
     """Tests for PodPool initialization."""
 
@@ -282,6 +294,74 @@ class TestPodPoolCreateWarmPod:
 
         assert result is None
 
+    @pytest.mark.asyncio
+    async def test_create_warm_pod_network_mode_changed_while_starting(self, pod_pool):
+        """A pod started with the old network mode is deleted instead of joining the pool."""
+        mock_core_api = MagicMock()
+        mock_core_api.create_namespaced_pod.return_value.metadata.uid = "new-pod-uid"
+
+        async def ready(handle):
+            pod_pool.config.network_isolated = True
+            return True
+
+        with patch("src.services.kubernetes.pool.get_core_api", return_value=mock_core_api):
+            with patch("src.services.kubernetes.pool.create_pod_manifest", return_value={}):
+                with patch.object(pod_pool, "_wait_for_pod_ready", side_effect=ready):
+                    with patch.object(pod_pool, "_delete_pod", new_callable=AsyncMock) as mock_delete:
+                        result = await pod_pool._create_warm_pod()
+
+        assert result is None
+        mock_delete.assert_awaited_once()
+        assert pod_pool._pods == {}
+
+
+class TestPodPoolNetworkMode:
+    """Tests for changing the network mode of a running pool."""
+
+    @pytest.mark.asyncio
+    async def test_drain_stale_deletes_idle_pods_of_the_old_mode(self, pod_pool, pooled_pod):
+        acquired = PooledPod(handle=MagicMock(uid="acquired-uid"), language="python", acquired=True)
+        pod_pool._pods[pooled_pod.handle.uid] = pooled_pod
+        pod_pool._pods["acquired-uid"] = acquired
+        await pod_pool._available.put(pooled_pod.handle.uid)
+        pod_pool.config.network_isolated = True
+
+        with patch.object(pod_pool, "_delete_pod", new_callable=AsyncMock) as mock_delete:
+            assert await pod_pool.drain_stale() == 1
+
+        mock_delete.assert_awaited_once_with(pooled_pod.handle)
+        assert list(pod_pool._pods) == ["acquired-uid"]
+        assert pod_pool._available.empty()
+
+    @pytest.mark.asyncio
+    async def test_drain_stale_keeps_pods_of_the_current_mode(self, pod_pool, pooled_pod):
+        pod_pool._pods[pooled_pod.handle.uid] = pooled_pod
+        await pod_pool._available.put(pooled_pod.handle.uid)
+
+        assert await pod_pool.drain_stale() == 0
+        assert pod_pool._available.qsize() == 1
+
+    @pytest.mark.asyncio
+    async def test_release_destroys_pods_of_the_old_mode(self, pod_pool, pooled_pod):
+        pooled_pod.acquired = True
+        pod_pool._pods[pooled_pod.handle.uid] = pooled_pod
+        pod_pool.config.network_isolated = True
+
+        with patch.object(pod_pool, "_delete_pod", new_callable=AsyncMock) as mock_delete:
+            await pod_pool.release(pooled_pod.handle, destroy=False)
+
+        mock_delete.assert_awaited_once()
+        assert pooled_pod.handle.uid not in pod_pool._pods
+
+    @pytest.mark.asyncio
+    async def test_manager_applies_the_mode_to_every_pool(self, pool_config):
+        with patch("src.services.kubernetes.pool.get_current_namespace", return_value="test-namespace"):
+            manager = PodPoolManager(configs=[pool_config])
+        manager._pools["python"].drain_stale = AsyncMock(return_value=2)
+
+        assert await manager.set_network_isolated(True) == 2
+        assert manager.get_config("python").network_isolated is True
+
 
 class TestPodPoolWaitForPodReady:
     """Tests for _wait_for_pod_ready method."""
@@ -449,6 +529,28 @@ class TestPodPoolExecute:
 
         assert result.exit_code == 0
         assert result.stdout == "Hello"
+        assert result.interrupted is False
+        assert result.error is None
+
+    @pytest.mark.asyncio
+    async def test_execute_passes_structured_error(self, pod_pool, pod_handle):
+        """Structured errors from the sidecar reach the result."""
+        mock_client = AsyncMock()
+        mock_response = MagicMock()
+        mock_response.status_code = 200
+        mock_response.json.return_value = {
+            "exit_code": 127,
+            "stdout": "",
+            "stderr": "RUNTIME_NOT_FOUND",
+            "execution_time_ms": 5,
+            "error": {"code": "RUNTIME_NOT_FOUND", "wanted": "python", "available": []},
+        }
+        mock_client.post = AsyncMock(return_value=mock_response)
+
+        with patch.object(pod_pool, "_get_http_client", return_value=mock_client):
+            result = await pod_pool.execute(pod_handle, "print('Hello')")
+
+        assert result.error["wanted"] == "python"
 
     @pytest.mark.asyncio
     async def test_execute_no_pod_ip(self, pod_pool, pod_handle):
@@ -459,6 +561,7 @@ class TestPodPoolExecute:
 
         assert result.exit_code == 1
         assert "Pod not ready" in result.stderr
+        assert result.error == {"code": "SPAWN_FAILED"}
 
     @pytest.mark.asyncio
     async def test_execute_with_files(self, pod_pool, pod_handle):
@@ -540,6 +643,19 @@ class TestPodPoolProperties:
 
         assert pod_pool.total_count == 1
 
+    @pytest.mark.asyncio
+    async def test_get_session_pods(self, pod_pool, pooled_pod):
+        """Test only pods acquired by the session are returned."""
+        pod_pool._pods[pooled_pod.handle.uid] = pooled_pod
+        await pod_pool._available.put(pooled_pod.handle.uid)
+        await pod_pool.acquire("session-123", timeout=5)
+
+        assert pod_pool.get_session_pods("session-123") == [pooled_pod.handle]
+        assert pod_pool.get_session_pods("other-session") == []
+
+        await pod_pool.release(pooled_pod.handle, destroy=False)
+        assert pod_pool.get_session_pods("session-123") == []
+
 
 # PodPoolManager Tests
 
@@ -932,6 +1048,63 @@ class TestPodPoolHealthCheckLoop:
         # Pod should have been removed
         assert pooled_pod.handle.uid not in pod_pool._pods
 
+    @pytest.mark.asyncio
+    async def test_health_check_loop_drains_degraded_pod(self, pod_pool, pooled_pod):
+        """Test a pod reporting itself degraded on /ready is removed on the first check."""
+        pod_pool._running = True
+        pod_pool._pods[pooled_pod.handle.uid] = pooled_pod
+        iteration = 0
+
+        async def mock_sleep(_):
+            nonlocal iteration
+            iteration += 1
+            if iteration >= 2:
+                pod_pool._running = False
+
+        mock_client = AsyncMock()
+        mock_response = MagicMock()
+        mock_response.status_code = 503
+        mock_response.json.return_value = {
+            "detail": {"status": "degraded", "degraded": ["spawn"], "checks": {"spawn": {"degraded": True}}}
+        }
+        mock_client.get = AsyncMock(return_value=mock_response)
+
+        with patch.object(pod_pool, "_get_http_client", return_value=mock_client):
+            with patch.object(pod_pool, "_delete_pod", new_callable=AsyncMock) as mock_delete:
+                with patch("asyncio.sleep", side_effect=mock_sleep):
+                    await pod_pool._health_check_loop()
+
+        assert pooled_pod.handle.uid not in pod_pool._pods
+        mock_delete.assert_awaited_once_with(pooled_pod.handle)
+        assert mock_client.get.call_args[0][0].endswith("/ready")
+
+    @pytest.mark.asyncio
+    async def test_health_check_loop_not_ready_is_tolerated(self, pod_pool, pooled_pod):
+        """Test a plain /ready failure counts towards removal instead of draining at once."""
+        pod_pool._running = True
+        pod_pool._pods[pooled_pod.handle.uid] = pooled_pod
+        iteration = 0
+
+        async def mock_sleep(_):
+            nonlocal iteration
+            iteration += 1
+            if iteration >= 2:
+                pod_pool._running = False
+
+        mock_client = AsyncMock()
+        mock_response = MagicMock()
+        mock_response.status_code = 503
+        mock_response.json.return_value = {"detail": "Main container not found"}
+        mock_client.get = AsyncMock(return_value=mock_response)
+
+        with patch.object(pod_pool, "_get_http_client", return_value=mock_client):
+            with patch("asyncio.sleep", side_effect=mock_sleep):
+                await pod_pool._health_check_loop()
+
+        # Checked twice before the loop stops; removal takes three failures
+        assert pooled_pod.handle.uid in pod_pool._pods
+        assert pooled_pod.health_check_failures == 2
+
     @pytest.mark.asyncio
     async def test_health_check_loop_exception(self, pod_pool, pooled_pod):
         """Test health check loop handles exception on health check."""
@@ -1096,6 +1269,73 @@ class TestPodPoolExecuteExtended:
 
         assert result.state_errors == ["Warning: large object skipped"]
 
+    @pytest.mark.asyncio
+    async def test_execute_with_options(self, pod_pool, pod_handle):
+        """Test execution forwards env and template context to the sidecar."""
+        mock_client = AsyncMock()
+        mock_response = MagicMock()
+        mock_response.status_code = 200
+        mock_response.json.return_value = {"exit_code": 0, "stdout": "", "stderr": "", "execution_time_ms": 5}
+        mock_client.post = AsyncMock(return_value=mock_response)
+        options = ExecutionOptions(env={"MODE": "${SESSION_ID}"}, context={"SESSION_ID": "session-123"})
+
+        with patch.object(pod_pool, "_get_http_client", return_value=mock_client):
+            await pod_pool.execute(pod_handle, "x = 1", options=options)
+
+        request_data = mock_client.post.call_args.kwargs["json"]
+        assert request_data["env"] == {"MODE": "${SESSION_ID}"}
+        assert request_data["context"] == {"SESSION_ID": "session-123"}
+        assert "template_code" not in request_data
+        assert "hooks" not in request_data
+        assert "priority" not in request_data
+
+    @pytest.mark.asyncio
+    async def test_execute_with_hooks(self, pod_pool, pod_handle):
+        """Test configured hooks (with their time budget) and priority are forwarded."""
+        mock_client = AsyncMock()
+        mock_response = MagicMock()
+        mock_response.status_code = 200
+        mock_response.json.return_value = {
+            "exit_code": 1,
+            "stdout": "",
+            "stderr": "HOOK_FAILED: pre-exec hook failed (exit code 3): snapshot failed\n",
+            "execution_time_ms": 5,
+            "error": {"code": "HOOK_FAILED", "hook": "pre", "exit_code": 3, "message": "snapshot failed"},
+        }
+        mock_client.post = AsyncMock(return_value=mock_response)
+        options = ExecutionOptions(
+            pre_hook="/opt/hooks/snapshot.sh", hook_timeout=5, priority={"oom_score_adj": 800, "cpu_weight": 20}
+        )
+
+        with patch.object(pod_pool, "_get_http_client", return_value=mock_client):
+            result = await pod_pool.execute(pod_handle, "x = 1", options=options)
+
+        request_data = mock_client.post.call_args.kwargs["json"]
+        assert request_data["hooks"] == {"pre": "/opt/hooks/snapshot.sh", "post": None, "timeout": 5}
+        assert request_data["priority"] == {"oom_score_adj": 800, "cpu_weight": 20}
+        assert result.error["hook"] == "pre"
+
+    @pytest.mark.asyncio
+    async def test_execute_with_connection_limit(self, pod_pool, pod_handle):
+        """Test the connection limit is forwarded and the sidecar's count returned."""
+        mock_client = AsyncMock()
+        mock_response = MagicMock()
+        mock_response.status_code = 200
+        mock_response.json.return_value = {
+            "exit_code": 0,
+            "stdout": "ok\n",
+            "stderr": "",
+            "execution_time_ms": 50,
+            "connections": 3,
+        }
+        mock_client.post = AsyncMock(return_value=mock_response)
+
+        with patch.object(pod_pool, "_get_http_client", return_value=mock_client):
+            result = await pod_pool.execute(pod_handle, "fetch()", options=ExecutionOptions(max_connections=10))
+
+        assert mock_client.post.call_args.kwargs["json"]["max_connections"] == 10
+        assert result.connections == 3
+
 
 class TestPoolConfigResources:
     """Tests for PoolConfig per-language resource configuration."""
@@ -0,0 +1,328 @@
+"""Unit tests for tabular file previews."""
+
+import gzip
+import struct
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+from fastapi import HTTPException
+
+from src.api.files import get_file_preview
+from src.services.parquet import ParquetError, decode_hybrid, read_parquet, snappy_decompress
+from src.services.preview import PreviewError, detect_format, preview_file
+
+# parquet.thrift values used by the writer below
+INT32, INT64, DOUBLE, BYTE_ARRAY = 1, 2, 5, 6
+REQUIRED, OPTIONAL = 0, 1
+UTF8, DATE = 0, 6
+UNCOMPRESSED, SNAPPY, GZIP = 0, 1, 2
+
+
+def _varint(n: int) -> bytes:
+    out = bytearray()
+    while True:
+        byte, n = n & 0x7F, n >> 7
+        out.append(byte | 0x80 if n else byte)
+        if not n:
+            return bytes(out)
+
+
+def _zigzag(n: int) -> bytes:
+    return _varint((n << 1) ^ (n >> 63))
+
+
+_KINDS = {"i32": 5, "i64": 6, "bin": 8, "list": 9, "struct": 12}
+
+
+def _payload(kind: str, value) -> bytes:
+    if kind in ("i32", "i64"):
+        return _zigzag(value)
+    if kind == "bin":
+        value = value.encode() if isinstance(value, str) else value
+        return _varint(len(value)) + value
+    if kind == "struct":
+        return _thrift(value)
+    element, items = value
+    header = bytes([len(items) << 4 | _KINDS[element]]) if len(items) < 15 else bytes([0xF0 | _KINDS[element]])
+    if len(items) >= 15:
+        header += _varint(len(items))
+    return header + b"".join(_payload(element, item) for item in items)
+
+
+def _thrift(fields: list[tuple]) -> bytes:
+    """Thrift compact encoding of [(field_id, kind, value)]."""
+    out = bytearray()
+    last = 0
+    for field_id, kind, value in fields:
+        if value is None:
+            continue
+        delta = field_id - last
+        out.append(delta << 4 | _KINDS[kind] if 0 < delta <= 15 else _KINDS[kind])
+        if not 0 < delta <= 15:
+            out += _zigzag(field_id)
+        out += _payload(kind, value)
+        last = field_id
+    out.append(0)
+    return bytes(out)
+
+
+def _plain(physical: int, values: list) -> bytes:
+    if physical == BYTE_ARRAY:
+        return b"".join(struct.pack("<i", len(v.encode())) + v.encode() for v in values)
+    fmt = {INT32: "i", INT64: "q", DOUBLE: "d"}[physical]
+    return struct.pack(f"<{len(values)}{fmt}", *values)
+
+
+def _bitpacked(values: list[int], width: int) -> bytes:
+    groups = (len(values) + 7) // 8
+    packed = sum(v << (i * width) for i, v in enumerate(values))
+    return _varint(groups << 1 | 1) + packed.to_bytes(groups * width, "little")
+
+
+def _snappy(data: bytes) -> bytes:
+    """Literal-only Snappy block."""
+    out = bytearray(_varint(len(data)))
+    for start in range(0, len(data), 256):
+        chunk = data[start : start + 256]
+        out += bytes([60 << 2, len(chunk) - 1]) if len(chunk) > 60 else bytes([(len(chunk) - 1) << 2])
+        out += chunk
+    return bytes(out)
+
+
+def _compress(data: bytes, codec: int) -> bytes:
+    return {UNCOMPRESSED: lambda d: d, SNAPPY: _snappy, GZIP: gzip.compress}[codec](data)
+
+
+def _page(page_type: int, body: bytes, codec: int, header_field: tuple) -> bytes:
+    compressed = _compress(body, codec)
+    header = _thrift([(1, "i32", page_type), (2, "i32", len(body)), (3, "i32", len(compressed)), header_field])
+    return header + compressed
+
+
+def _parquet(columns: list[dict], row_groups: list[dict], codec: int = UNCOMPRESSED) -> bytes:
+    """A Parquet file with v1 data pages; columns with "dictionary" are dictionary-encoded."""
+    data = bytearray(b"PAR1")
+    groups = []
+    for group in row_groups:
+        chunks = []
+        num_rows = len(group[columns[0]["name"]])
+        for column in columns:
+            values = group[column["name"]]
+            present = [v for v in values if v is not None]
+            levels = b""
+            if column["repetition"] == OPTIONAL:
+                encoded = _bitpacked([0 if v is None else 1 for v in values], 1)
+                levels = struct.pack("<i", len(encoded)) + encoded
+            start = len(data)
+            dictionary_offset = None
+            if column.get("dictionary"):
+                dictionary = sorted(set(present))
+                dictionary_offset = start
+                dictionary_header = (7, "struct", [(1, "i32", len(dictionary))])
+                data += _page(2, _plain(column["type"], dictionary), codec, dictionary_header)
+                width = max(len(dictionary) - 1, 1).bit_length()
+                body = levels + bytes([width]) + _bitpacked([dictionary.index(v) for v in present], width)
+                encoding = 8
+            else:
+                body = levels + _plain(column["type"], present)
+                encoding = 0
+            data_offset = len(data)
+            data += _page(0, body, codec, (5, "struct", [(1, "i32", len(values)), (2, "i32", encoding)]))
+            meta = [
+                (1, "i32", column["type"]),
+                (2, "list", ("i32", [encoding])),
+                (3, "list", ("bin", [column["name"]])),
+                (4, "i32", codec),
+                (5, "i64", len(values)),
+                (6, "i64", len(data) - start),
+                (7, "i64", len(data) - start),
+                (9, "i64", data_offset),
+                (11, "i64", dictionary_offset),
+            ]
+            chunks.append([(2, "i64", start), (3, "struct", meta)])
+        groups.append([(1, "list", ("struct", chunks)), (2, "i64", 0), (3, "i64", num_rows)])
+
+    schema = [[(4, "bin", "schema"), (5, "i32", len(columns))]] + [
+        [
+            (1, "i32", c["type"]),
+            (3, "i32", c["repetition"]),
+            (4, "bin", c["name"]),
+            (6, "i32", c.get("converted")),
+        ]
+        for c in columns
+    ]
+    total = sum(len(g[columns[0]["name"]]) for g in row_groups)
+    footer = _thrift(
+        [(1, "i32", 1), (2, "list", ("struct", schema)), (3, "i64", total), (4, "list", ("struct", groups))]
+    )
+    return bytes(data) + footer + struct.pack("<i", len(footer)) + b"PAR1"
+
+
+COLUMNS = [
+    {"name": "id", "type": INT64, "repetition": REQUIRED},
+    {"name": "name", "type": BYTE_ARRAY, "repetition": OPTIONAL, "converted": UTF8, "dictionary": True},
+    {"name": "score", "type": DOUBLE, "repetition": REQUIRED},
+    {"name": "day", "type": INT32, "repetition": REQUIRED, "converted": DATE},
+]
+ROW_GROUPS = [
+    {"id": [1, 2, 3], "name": ["ada", None, "ada"], "score": [1.5, 2.0, -3.25], "day": [0, 1, 19723]},
+    {"id": [4, 5], "name": ["bob", "cy"], "score": [0.0, 9.75], "day": [2, 3]},
+]
+
+
+class TestParquet:
+    @pytest.mark.parametrize("codec", [UNCOMPRESSED, SNAPPY, GZIP])
+    def test_reads_schema_and_rows(self, codec):
+        preview = read_parquet(_parquet(COLUMNS, ROW_GROUPS, codec), rows=100)
+
+        assert [(c.name, c.type, c.nullable) for c in preview.columns] == [
+            ("id", "integer", False),
+            ("name", "string", True),
+            ("score", "number", False),
+            ("day", "date", False),
+        ]
+        assert preview.total_rows == 5
+        assert preview.rows[:3] == [
+            [1, "ada", 1.5, "1970-01-01"],
+            [2, None, 2.0, "1970-01-02"],
+            [3, "ada", -3.25, "2024-01-01"],
+        ]
+        assert preview.rows[4] == [5, "cy", 9.75, "1970-01-04"]
+
+    def test_stops_after_requested_rows(self):
+        preview = read_parquet(_parquet(COLUMNS, ROW_GROUPS), rows=2)
+
+        assert [row[0] for row in preview.rows] == [1, 2]
+        assert preview.total_rows == 5
+
+    def test_rejects_non_parquet(self):
+        with pytest.raises(PreviewError):
+            preview_file("data.parquet", b"id,name\n1,a\n")
+
+    def test_snappy_copies(self):
+        assert snappy_decompress(b"\x08\x0cabcd\x01\x04") == b"abcdabcd"
+        assert snappy_decompress(b"\x08\x00a\x1a\x01\x00") == b"aaaaaaaa"
+
+    def test_hybrid_runs_and_bitpacking(self):
+        data = _varint(5 << 1) + bytes([3]) + _bitpacked([1, 0, 2, 3, 1, 0, 0, 2], 2)
+
+        assert decode_hybrid(data, 0, 2, 13) == [3, 3, 3, 3, 3, 1, 0, 2, 3, 1, 0, 0, 2]
+
+    def test_hybrid_clamps_runs_to_count(self):
+        run = _varint(1 << 41) + bytes([7])
+        packed = _varint(1 << 30 | 1) + bytes([0xFF])
+
+        assert decode_hybrid(run, 0, 3, 4) == [7, 7, 7, 7]
+        assert decode_hybrid(packed, 0, 1, 5) == [1, 1, 1, 1, 1]
+
+    def test_snappy_refuses_blocks_past_the_limit(self):
+        with pytest.raises(ParquetError, match="larger than 10 bytes"):
+            snappy_decompress(_snappy(b"x" * 100), max_size=10)
+
+    @pytest.mark.parametrize("codec", [UNCOMPRESSED, GZIP])
+    def test_refuses_pages_past_the_limit(self, codec, monkeypatch):
+        monkeypatch.setattr("src.services.parquet.MAX_PAGE_SIZE", 16)
+
+        with pytest.raises(ParquetError, match="Page larger than 16 bytes"):
+            read_parquet(_parquet(COLUMNS, ROW_GROUPS, codec), rows=100)
+
+
+class TestCsv:
+    def test_infers_types(self):
+        content = b"id,price,active,day,label\n1,2.5,true,2024-01-01,a\n2,,false,2024-01-02,\n3,4,true,2024-01-03,c\n"
+
+        preview = preview_file("sales.csv", content, rows=10)
+
+        assert [(c.name, c.type, c.nullable) for c in preview.columns] == [
+            ("id", "integer", False),
+            ("price", "number", True),
+            ("active", "boolean", False),
+            ("day", "date", False),
+            ("label", "string", True),
+        ]
+        assert preview.rows[0] == [1, 2.5, True, "2024-01-01", "a"]
+        assert preview.rows[1] == [2, None, False, "2024-01-02", None]
+        assert not preview.truncated
+
+    def test_truncates_and_sniffs_delimiter(self):
+        content = b"a;b\n" + b"".join(f"{i};x\n".encode() for i in range(50))
+
+        preview = preview_file("data.csv", content, rows=5)
+
+        assert [c.name for c in preview.columns] == ["a", "b"]
+        assert len(preview.rows) == 5
+        assert preview.truncated
+
+    def test_nan_is_not_a_number(self):
+        preview = preview_file("data.csv", b"x\nnan\n1\n")
+
+        assert preview.columns[0].type == "string"
+
+    def test_tsv(self):
+        preview = preview_file("data.tsv", b"a\tb\n1\t2\n")
+
+        assert preview.format == "tsv"
+        assert preview.rows == [[1, 2]]
+
+
+class TestJsonl:
+    def test_union_of_keys(self):
+        content = b'{"id": 1, "tags": ["a"]}\n\n{"id": 2.5, "name": "x"}\n{"id": null, "name": 3}\n'
+
+        preview = preview_file("events.jsonl", content)
+
+        assert [(c.name, c.type, c.nullable) for c in preview.columns] == [
+            ("id", "number", True),
+            ("tags", "array", True),
+            ("name", "mixed", True),
+        ]
+        assert preview.rows[0] == [1, '["a"]', None]
+
+    def test_invalid_line(self):
+        with pytest.raises(PreviewError) as exc_info:
+            preview_file("events.ndjson", b'{"a": 1}\n{oops\n')
+
+        assert "line 2" in str(exc_info.value)
+
+
+class TestDetectFormat:
+    def test_by_extension_and_magic(self):
+        assert detect_format("Sales.CSV", b"") == "csv"
+        assert detect_format("blob", _parquet(COLUMNS, ROW_GROUPS)) == "parquet"
+        assert detect_format("notes.txt", b"hello") is None
+
+
+class TestPreviewEndpoint:
+    @pytest.fixture
+    def file_service(self):
+        service = MagicMock()
+        service.get_file_info = AsyncMock(return_value=MagicMock(filename="sales.csv"))
+        service.get_file_content = AsyncMock(return_value=b"id,name\n1,a\n2,b\n")
+        return service
+
+    @pytest.mark.asyncio
+    async def test_preview(self, file_service):
+        response = await get_file_preview("s1", "f1", rows=1, format=None, file_service=file_service)
+
+        assert response.format == "csv"
+        assert response.rows == [[1, "a"]]
+        assert response.truncated is True
+
+    @pytest.mark.asyncio
+    async def test_unsupported_format(self, file_service):
+        file_service.get_file_info.return_value = MagicMock(filename="image.png")
+
+        with pytest.raises(HTTPException) as exc_info:
+            await get_file_preview("s1", "f1", rows=10, format=None, file_service=file_service)
+
+        assert exc_info.value.status_code == 415
+        assert exc_info.value.detail["error"] == "unsupported_preview_format"
+
+    @pytest.mark.asyncio
+    async def test_missing_file(self, file_service):
+        file_service.get_file_info.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await get_file_preview("s1", "f1", rows=10, format=None, file_service=file_service)
+
+        assert exc_info.value.status_code == 404
@@ -0,0 +1,85 @@
+"""Unit tests for the Prometheus exposition of runtime stats."""
+
+from src.models.scheduler import SchedulerState, SessionQueueStats
+from src.services.prometheus import render_runtime_stats, render_scheduler_state
+
+STATS = [
+    {
+        "language": "py",
+        "image": "registry/python:3.13",
+        "execution_count": 40,
+        "success_count": 30,
+        "failure_count": 8,
+        "timeout_count": 2,
+        "success_rate": 75.0,
+        "error_rate": 20.0,
+        "timeout_rate": 5.0,
+        "p50_ms": 120,
+        "p95_ms": 2400,
+    }
+]
+
+
+class TestRenderRuntimeStats:
+    """Tests for render_runtime_stats."""
+
+    def test_gauges_are_labelled_by_language_and_image(self):
+        text = render_runtime_stats(STATS, hours=24)
+
+        labels = '{language="py",image="registry/python:3.13"}'
+        assert f"kubecoderun_language_executions{labels} 40" in text
+        assert f"kubecoderun_language_success_ratio{labels} 0.75" in text
+        assert f"kubecoderun_language_timeout_ratio{labels} 0.05" in text
+        assert "# TYPE kubecoderun_language_error_ratio gauge" in text
+
+    def test_duration_quantiles(self):
+        text = render_runtime_stats(STATS, hours=24)
+
+        assert 'kubecoderun_language_duration_ms{language="py",image="registry/python:3.13",quantile="0.5"} 120' in text
+        assert 'quantile="0.95"} 2400' in text
+
+    def test_missing_image_and_escaping(self):
+        stats = [dict(STATS[0], language='we"ird', image=None)]
+
+        text = render_runtime_stats(stats, hours=1)
+
+        assert 'kubecoderun_language_executions{language="we\\"ird",image=""} 40' in text
+        assert text.endswith("\n")
+
+    def test_no_stats_still_declares_metrics(self):
+        text = render_runtime_stats([], hours=24)
+
+        assert "# HELP kubecoderun_language_executions Executions in the window (last 24h)" in text
+        assert "{" not in text
+
+
+class TestRenderSchedulerState:
+    """Tests for render_scheduler_state."""
+
+    def test_slots_waits_and_starved_sessions(self):
+        state = SchedulerState(
+            limit=10,
+            running=10,
+            waiting=3,
+            p50_wait_ms=40,
+            p95_wait_ms=12000,
+            slot_requests=500,
+            slot_timeouts=2,
+            alert_p95_ms=10000,
+            starvation_alerts=1,
+            sessions=[
+                SessionQueueStats(
+                    session_id="s1", executions=20, p50_wait_ms=9000, p95_wait_ms=15000, max_wait_ms=16000, starved=True
+                ),
+                SessionQueueStats(session_id="s2", executions=5, p50_wait_ms=0, p95_wait_ms=10, max_wait_ms=10),
+            ],
+        )
+
+        text = render_scheduler_state(state)
+
+        assert "kubecoderun_execution_slots_running 10" in text
+        assert 'kubecoderun_execution_slot_wait_ms{quantile="0.95"} 12000' in text
+        assert "# TYPE kubecoderun_execution_slot_timeouts_total counter" in text
+        assert "kubecoderun_starved_sessions 1" in text
+        assert 'kubecoderun_session_slot_wait_p95_ms{session_id="s1"} 15000' in text
+        assert 'session_id="s2"' not in text
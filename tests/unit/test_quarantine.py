@@ -0,0 +1,286 @@
+"""Unit tests for session quarantine."""
+
+import asyncio
+from datetime import UTC, datetime
+from types import SimpleNamespace
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+from fastapi import HTTPException
+
+from src.dependencies.services import reject_quarantined_session
+from src.models.session import QuarantineRecord
+from src.services.cells import CellHistoryService
+from src.services.file import FileService
+from src.services.quarantine import QuarantineService
+from src.services.session import SessionService
+from src.services.session_report import SessionReportService
+from src.services.state import StateService
+
+# Remaining TTL of each of the session's keys; session_env:s1 has no expiry
+TTLS = {
+    "sessions:s1": 3000,
+    "session_env:s1": -1,
+    "session_files:s1": 3000,
+    "files:s1:f1": 3000,
+    "session:state:s1": 1200,
+    "session:cells:s1": 3000,
+    "session:cells:s1:count": 3000,
+    "session:report:s1": 3000,
+    "session:report:s1:violations": 3000,
+    # Names the session but isn't one of its keys
+    "stats:s1": 50,
+}
+
+
+@pytest.fixture
+def mock_pipeline():
+    """Create a mock transactional pipeline."""
+    pipe = MagicMock()
+    pipe.execute = AsyncMock(return_value=[])
+    pipe.reset = AsyncMock()
+    return pipe
+
+
+@pytest.fixture
+def mock_redis(mock_pipeline):
+    """Create a mock Redis client holding one session's keys."""
+    store = {}
+    sets = {"session_files:s1": {"f1"}}
+
+    client = MagicMock()
+    client.pipeline = AsyncMock(return_value=mock_pipeline)
+    client.ttl = AsyncMock(side_effect=lambda key: TTLS.get(key, -2))
+    client.persist = AsyncMock()
+    client.expire = AsyncMock()
+    client.sadd = AsyncMock(side_effect=lambda key, *members: sets.setdefault(key, set()).update(members))
+    client.sismember = AsyncMock(return_value=False)
+    client.smembers = AsyncMock(side_effect=lambda key: set(sets.get(key, set())))
+    client.set = AsyncMock(side_effect=lambda key, value: store.__setitem__(key, value))
+    client.get = AsyncMock(side_effect=lambda key: store.get(key))
+    client.publish = AsyncMock(return_value=2)
+    return client
+
+
+@pytest.fixture
+def session_service(mock_redis):
+    service = SessionService(redis_client=mock_redis)
+    service.get_session = AsyncMock(return_value=SimpleNamespace(session_id="s1"))
+    service.update_session = AsyncMock()
+    return service
+
+
+@pytest.fixture
+def execution_service():
+    service = MagicMock()
+    service.kill_session = AsyncMock(return_value=1)
+    return service
+
+
+@pytest.fixture
+def file_service(mock_redis):
+    with (
+        patch("src.services.file.settings"),
+        patch("src.services.file.redis.from_url", return_value=mock_redis),
+    ):
+        return FileService()
+
+
+@pytest.fixture
+def service(mock_redis, session_service, execution_service, file_service):
+    return QuarantineService(
+        session_service,
+        execution_service,
+        file_service=file_service,
+        state_service=StateService(redis_client=mock_redis),
+        cell_history_service=CellHistoryService(redis_client=mock_redis),
+        report_service=SessionReportService(redis_client=mock_redis),
+        redis_client=mock_redis,
+    )
+
+
+class TestQuarantine:
+    """Tests for quarantining a session."""
+
+    @pytest.mark.asyncio
+    async def test_freezes_kills_and_preserves(self, service, mock_redis, session_service, execution_service):
+        record = await service.quarantine("s1", reason="suspicious egress", actor="10.0.0.5")
+
+        assert record.killed == 1 and record.preserved_keys == 9
+        assert record.reason == "suspicious egress" and record.actor == "10.0.0.5"
+        session_service.update_session.assert_awaited_once_with("s1", status="quarantined")
+        mock_redis.sadd.assert_any_await("quarantine:index", "s1")
+        execution_service.kill_session.assert_awaited_once_with("s1")
+        mock_redis.publish.assert_awaited_once_with("quarantine:kill", "s1")
+        persisted = {call.args[0] for call in mock_redis.persist.call_args_list}
+        assert persisted == {key for key, ttl in TTLS.items() if ttl > 0} - {"stats:s1"}
+        assert await service.get("s1") == record
+
+    @pytest.mark.asyncio
+    async def test_only_session_keys(self, mock_redis, session_service, execution_service):
+        """Without the file, state, cell and report services only the session's metadata and env are kept."""
+        service = QuarantineService(session_service, execution_service, redis_client=mock_redis)
+
+        record = await service.quarantine("s1")
+
+        assert record.preserved_keys == 2
+        mock_redis.persist.assert_awaited_once_with("sessions:s1")
+
+    @pytest.mark.asyncio
+    async def test_unknown_session(self, service, session_service, execution_service):
+        session_service.get_session.return_value = None
+
+        assert await service.quarantine("gone") is None
+        execution_service.kill_session.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_again_keeps_first_record(self, service):
+        first = await service.quarantine("s1", reason="first", actor="a")
+
+        again = await service.quarantine("s1", reason="second", actor="b")
+
+        assert again.reason == "first" and again.quarantined_at == first.quarantined_at
+
+    @pytest.mark.asyncio
+    async def test_logged_as_security_event(self, service):
+        with patch("src.services.quarantine.SecurityAudit") as audit:
+            await service.quarantine("s1", reason="r", actor="a")
+
+        audit.log_quarantine.assert_called_once_with("s1", "quarantined", "a", "r", killed=1, preserved_keys=9)
+
+
+class TestRelease:
+    """Tests for lifting a quarantine."""
+
+    @pytest.mark.asyncio
+    async def test_release_restores_expiry(self, service, mock_redis, mock_pipeline, session_service):
+        """Keys that had an expiry get a fresh session TTL; keys that had none keep none."""
+        await service.quarantine("s1")
+        mock_redis.sismember.return_value = True
+
+        with patch("src.services.quarantine.settings") as mock_settings:
+            mock_settings.get_session_ttl_minutes.return_value = 60
+            assert await service.release("s1", actor="a") is True
+
+        assert session_service.update_session.call_args.kwargs["status"] == "active"
+        expired = {call.args for call in mock_redis.expire.call_args_list}
+        assert ("sessions:s1", 3600) in expired and ("session:state:s1", 3600) in expired
+        assert {key for key, _ in expired} & {"session_env:s1", "stats:s1", "quarantine:s1"} == set()
+        mock_pipeline.srem.assert_called_once_with("quarantine:index", "s1")
+        mock_pipeline.delete.assert_called_once_with("quarantine:s1", "quarantine:s1:expiring")
+
+    @pytest.mark.asyncio
+    async def test_quarantine_again_keeps_expiring_keys(self, service, mock_redis):
+        """Quarantining again, with the expiries already removed, still restores them on release."""
+        await service.quarantine("s1")
+        ttls = {key: -1 if ttl > 0 else ttl for key, ttl in TTLS.items()}
+        mock_redis.ttl.side_effect = lambda key: ttls.get(key, -2)
+
+        await service.quarantine("s1")
+
+        assert "sessions:s1" in await mock_redis.smembers("quarantine:s1:expiring")
+
+    @pytest.mark.asyncio
+    async def test_release_not_quarantined(self, service, session_service):
+        assert await service.release("s1") is False
+        session_service.update_session.assert_not_called()
+
+
+class TestWatch:
+    """Tests for stopping executions started on this replica."""
+
+    @pytest.mark.asyncio
+    async def test_kill_quarantined(self, service, mock_redis, execution_service):
+        mock_redis.smembers.return_value = {"s1", "s2"}
+
+        assert await service.kill_quarantined() == 2
+        assert {call.args[0] for call in execution_service.kill_session.call_args_list} == {"s1", "s2"}
+
+    @pytest.mark.asyncio
+    async def test_kills_announced_sessions(self, service, mock_redis, execution_service):
+        pubsub = MagicMock()
+        pubsub.subscribe = AsyncMock()
+        pubsub.aclose = AsyncMock()
+        pubsub.get_message = AsyncMock(
+            side_effect=[{"type": "message", "data": "s2"}, None, asyncio.CancelledError()]
+        )
+        mock_redis.pubsub.return_value = pubsub
+
+        with pytest.raises(asyncio.CancelledError):
+            await service._watch_loop(0)
+
+        pubsub.subscribe.assert_awaited_once_with("quarantine:kill")
+        execution_service.kill_session.assert_awaited_once_with("s2")
+        assert pubsub.get_message.call_args.kwargs["timeout"] is None
+        pubsub.aclose.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_rechecks_the_index(self, service, mock_redis, execution_service):
+        mock_redis.smembers.return_value = {"s1"}
+        pubsub = MagicMock()
+        pubsub.subscribe = AsyncMock()
+        pubsub.aclose = AsyncMock()
+        timeouts = []
+
+        async def get_message(ignore_subscribe_messages, timeout):
+            timeouts.append(timeout)
+            if len(timeouts) > 1:
+                raise asyncio.CancelledError()
+            await asyncio.sleep(timeout + 0.01)
+
+        pubsub.get_message = get_message
+        mock_redis.pubsub.return_value = pubsub
+
+        with pytest.raises(asyncio.CancelledError):
+            await service._watch_loop(0.01)
+
+        assert 0 < timeouts[0] <= 0.01
+        execution_service.kill_session.assert_awaited_once_with("s1")
+
+    @pytest.mark.asyncio
+    async def test_resubscribes_after_errors(self, service, mock_redis, execution_service):
+        pubsub = MagicMock()
+        pubsub.subscribe = AsyncMock(side_effect=[ConnectionError("gone"), None])
+        pubsub.aclose = AsyncMock()
+        pubsub.get_message = AsyncMock(side_effect=[{"type": "message", "data": "s1"}, asyncio.CancelledError()])
+        mock_redis.pubsub.return_value = pubsub
+
+        with patch("src.services.quarantine.asyncio.sleep", new=AsyncMock()) as mock_sleep:
+            with pytest.raises(asyncio.CancelledError):
+                await service._watch_loop(0)
+
+        mock_sleep.assert_awaited_once()
+        assert pubsub.aclose.await_count == 2
+        execution_service.kill_session.assert_awaited_once_with("s1")
+
+    @pytest.mark.asyncio
+    async def test_list_newest_first(self, service, mock_redis):
+        older = QuarantineRecord(session_id="s1", quarantined_at=datetime(2026, 1, 1, tzinfo=UTC))
+        newer = QuarantineRecord(session_id="s2", quarantined_at=datetime(2026, 2, 1, tzinfo=UTC))
+        await mock_redis.set("quarantine:s1", older.model_dump_json())
+        await mock_redis.set("quarantine:s2", newer.model_dump_json())
+        mock_redis.smembers.return_value = {"s1", "s2", "stale"}
+
+        assert [r.session_id for r in await service.list_quarantined()] == ["s2", "s1"]
+
+
+class TestRejectQuarantinedSession:
+    """Tests for the route dependency guarding session changes."""
+
+    @pytest.mark.asyncio
+    async def test_rejects_quarantined(self):
+        quarantine_service = MagicMock()
+        quarantine_service.is_quarantined = AsyncMock(return_value=True)
+
+        with pytest.raises(HTTPException) as exc_info:
+            await reject_quarantined_session("s1", quarantine_service)
+
+        assert exc_info.value.status_code == 409
+        assert exc_info.value.detail["error"] == "session_quarantined"
+
+    @pytest.mark.asyncio
+    async def test_allows_others(self):
+        quarantine_service = MagicMock()
+        quarantine_service.is_quarantined = AsyncMock(return_value=False)
+
+        await reject_quarantined_session("s1", quarantine_service)
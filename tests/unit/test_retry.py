@@ -0,0 +1,78 @@
+"""Unit tests for the execution retry policy."""
+
+from unittest.mock import patch
+
+import pytest
+
+from src.models import CodeExecution, ExecutionOutput, ExecutionStatus, OutputType, RetryPolicy
+from src.services.retry import backoff_seconds, classify_failure, reduced_parallelism_env
+
+
+def _execution(status, exit_code=None, error=None, stderr=""):
+    outputs = [ExecutionOutput(type=OutputType.STDERR, content=stderr)] if stderr else []
+    return CodeExecution(
+        execution_id="exec-1",
+        session_id="session-1",
+        code="x",
+        status=status,
+        exit_code=exit_code,
+        error=error,
+        outputs=outputs,
+    )
+
+
+class TestClassifyFailure:
+    """Tests for recognizing transient failures."""
+
+    def test_spawn_failed(self):
+        assert classify_failure(_execution(ExecutionStatus.FAILED, 1, {"code": "SPAWN_FAILED"})) == "SPAWN_FAILED"
+
+    @pytest.mark.parametrize("exit_code", [137, -9])
+    def test_oom_killed(self, exit_code):
+        assert classify_failure(_execution(ExecutionStatus.FAILED, exit_code)) == "OOM"
+
+    def test_oom_from_stderr(self):
+        failed = _execution(ExecutionStatus.FAILED, 1, stderr="Traceback ...\nMemoryError\n")
+
+        assert classify_failure(failed) == "OOM"
+
+    def test_timeout(self):
+        assert classify_failure(_execution(ExecutionStatus.TIMEOUT, 124)) == "TIMEOUT"
+
+    def test_not_transient(self):
+        assert classify_failure(_execution(ExecutionStatus.COMPLETED, 0)) is None
+        assert classify_failure(_execution(ExecutionStatus.FAILED, 1, stderr="NameError: x")) is None
+        assert classify_failure(_execution(ExecutionStatus.FAILED, 127, {"code": "RUNTIME_NOT_FOUND"})) is None
+
+
+class TestBackoff:
+    def test_exponential(self):
+        policy = RetryPolicy(backoff_ms=500, backoff_multiplier=2)
+
+        assert [backoff_seconds(policy, n) for n in (1, 2, 3)] == [0.5, 1.0, 2.0]
+
+
+class TestReducedParallelism:
+    def test_halves_down_to_one(self):
+        with patch("src.services.retry.settings") as mock_settings:
+            mock_settings.max_cpus = 4.0
+
+            assert reduced_parallelism_env(1)["OMP_NUM_THREADS"] == "2"
+            assert reduced_parallelism_env(2)["MKL_NUM_THREADS"] == "1"
+            assert reduced_parallelism_env(5)["GOMAXPROCS"] == "1"
+
+
+class TestRetryPolicyModel:
+    def test_defaults(self):
+        policy = RetryPolicy()
+
+        assert policy.max_attempts == 2
+        assert policy.retry_on == ["SPAWN_FAILED"]
+
+    def test_rejects_unknown_class(self):
+        with pytest.raises(ValueError):
+            RetryPolicy(retry_on=["SEGFAULT"])
+
+    def test_limits_attempts(self):
+        with pytest.raises(ValueError):
+            RetryPolicy(max_attempts=10)
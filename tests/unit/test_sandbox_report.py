@@ -0,0 +1,85 @@
+"""Unit tests for comparing the protections an execution ran under with the configuration."""
+
+from unittest.mock import patch
+
+import pytest
+
+from src.models.exec import SandboxReport
+from src.services.sandbox_report import build_sandbox_report, find_mismatches
+
+OBSERVED = {
+    "cgroup": {"memory": "536870912", "cpu": "50000 100000", "pids": "max"},
+    "seccomp": "filter",
+    "seccomp_filters": 1,
+    "no_new_privs": False,
+    "uid": 65532,
+    "landlock": "not_applied",
+    "network": {"mode": "isolated", "interfaces": ["eth0"], "dns_policy": False},
+}
+
+
+@pytest.fixture
+def mock_settings():
+    with patch("src.services.sandbox_report.settings") as mock:
+        mock.k8s_seccomp_profile_type = "RuntimeDefault"
+        mock.k8s_sidecar_memory_limit = "512Mi"
+        mock.k8s_sidecar_cpu_limit = "500m"
+        mock.enable_network_isolation = True
+        mock.k8s_run_as_user = 65532
+        yield mock
+
+
+class TestFindMismatches:
+    """Tests for protections weaker than configured."""
+
+    def test_as_configured(self, mock_settings):
+        assert find_mismatches(SandboxReport.model_validate(OBSERVED)) == []
+
+    def test_weaker_than_configured(self, mock_settings):
+        report = SandboxReport.model_validate(
+            {
+                **OBSERVED,
+                "cgroup": {"memory": "max", "cpu": "max 100000"},
+                "seccomp": "disabled",
+                "uid": 0,
+                "network": {"mode": "open"},
+            }
+        )
+
+        assert find_mismatches(report) == [
+            "seccomp: RuntimeDefault configured, disabled applied",
+            "memory: 512Mi configured, unlimited applied",
+            "cpu: 500m configured, unlimited applied",
+            "network: isolated configured, open applied",
+            "uid: 65532 configured, 0 applied",
+        ]
+
+    def test_unconfined_and_unreadable_are_not_compared(self, mock_settings):
+        mock_settings.k8s_seccomp_profile_type = "Unconfined"
+        mock_settings.enable_network_isolation = False
+
+        report = SandboxReport.model_validate({"seccomp": "disabled", "network": {"mode": "open"}})
+
+        assert find_mismatches(report) == []
+
+
+class TestBuildSandboxReport:
+    """Tests for turning the sidecar's block into a report."""
+
+    def test_mismatches_are_audited(self, mock_settings):
+        with patch("src.services.sandbox_report.SecurityAudit") as mock_audit:
+            report = build_sandbox_report({**OBSERVED, "seccomp": "disabled"}, "session-1", "exec-1")
+
+        assert report.mismatches == ["seccomp: RuntimeDefault configured, disabled applied"]
+        event, details = mock_audit.log_security_event.call_args.args
+        assert event == "sandbox_mismatch" and details["execution_id"] == "exec-1"
+
+    def test_no_audit_without_mismatches(self, mock_settings):
+        with patch("src.services.sandbox_report.SecurityAudit") as mock_audit:
+            assert build_sandbox_report(OBSERVED).landlock == "not_applied"
+
+        mock_audit.log_security_event.assert_not_called()
+
+    def test_missing_or_malformed(self, mock_settings):
+        assert build_sandbox_report(None) is None
+        assert build_sandbox_report({"seccomp": "sometimes"}) is None
@@ -1,5 +1,6 @@
 """Unit tests for Security Middleware."""
 
+import base64
 import time
 from unittest.mock import AsyncMock, MagicMock, patch
 
@@ -112,6 +113,29 @@ class TestSecurityMiddlewareCall:
 
         mock_app.assert_called_once()
 
+    @pytest.mark.asyncio
+    async def test_dav_unauthorized_asks_for_basic_auth(self, security_middleware, mock_app, mock_receive, mock_send):
+        """Test that 401s on WebDAV paths carry a Basic auth challenge."""
+        scope = {
+            "type": "http",
+            "method": "PROPFIND",
+            "path": "/dav/session-123/",
+            "query_string": b"",
+            "headers": [],
+        }
+        with patch("src.middleware.security.get_auth_service") as mock_get_auth:
+            mock_auth = AsyncMock()
+            mock_auth.check_rate_limit.return_value = True
+            mock_auth.validate_api_key_full.return_value = MagicMock(is_valid=False, error_message=None)
+            mock_get_auth.return_value = mock_auth
+
+            await security_middleware(scope, mock_receive, mock_send)
+
+        mock_app.assert_not_called()
+        start = mock_send.call_args_list[0].args[0]
+        assert start["status"] == 401
+        assert (b"www-authenticate", b'Basic realm="workspace"') in start["headers"]
+
 
 class TestShouldSkipAuth:
     """Tests for _should_skip_auth method."""
@@ -217,6 +241,25 @@ class TestExtractApiKey:
 
         assert result == "my-key"
 
+    def test_extract_from_basic_auth_password(self, security_middleware):
+        """Test extracting API key from the password of HTTP Basic auth."""
+        request = MagicMock()
+        credentials = base64.b64encode(b"anyone:my-key").decode()
+        request.headers.get.side_effect = lambda h: f"Basic {credentials}" if h == "authorization" else None
+
+        result = security_middleware._extract_api_key(request)
+
+        assert result == "my-key"
+
+    def test_extract_from_malformed_basic_auth(self, security_middleware):
+        """Test malformed Basic credentials yield no key."""
+        request = MagicMock()
+        request.headers.get.side_effect = lambda h: "Basic not*base64" if h == "authorization" else None
+
+        result = security_middleware._extract_api_key(request)
+
+        assert result is None
+
     def test_extract_no_key(self, security_middleware):
         """Test when no API key is present."""
         request = MagicMock()
@@ -329,6 +372,17 @@ class TestValidateRequest:
         # Should not raise
         await security_middleware._validate_request(request)
 
+    @pytest.mark.asyncio
+    async def test_validate_dav_path_skips_content_type(self, security_middleware):
+        """Test WebDAV uploads skip content type validation."""
+        request = MagicMock()
+        request.method = "PUT"
+        request.url.path = "/dav/session-123/chart.png"
+        request.headers.get.return_value = "image/png"
+
+        # Should not raise
+        await security_middleware._validate_request(request)
+
     @pytest.mark.asyncio
     async def test_validate_state_path_skips_content_type(self, security_middleware):
         """Test state path skips content type validation."""
@@ -0,0 +1,255 @@
+"""Unit tests for end-of-session reports."""
+
+import hashlib
+import json
+from datetime import UTC, datetime
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from src.models.exec import SandboxReport, SecretFinding
+from src.models.execution import CodeExecution, ExecutionStatus
+from src.models.files import FileInfo
+from src.models.session import Session
+from src.models.session_report import SessionReport, SessionViolation
+from src.services.session_report import SessionReportService, execution_violations
+
+
+@pytest.fixture
+def mock_pipeline():
+    """Create a mock transactional pipeline."""
+    pipe = MagicMock()
+    pipe.execute = AsyncMock(return_value=[])
+    pipe.reset = AsyncMock()
+    return pipe
+
+
+@pytest.fixture
+def mock_redis(mock_pipeline):
+    """Create a mock Redis client."""
+    client = MagicMock()
+    client.pipeline = AsyncMock(return_value=mock_pipeline)
+    client.get = AsyncMock(return_value=None)
+    client.hget = AsyncMock(return_value=None)
+    client.hgetall = AsyncMock(return_value={})
+    client.lrange = AsyncMock(return_value=[])
+    return client
+
+
+@pytest.fixture
+def mock_file_service():
+    """Create a mock file service holding one file."""
+    service = MagicMock()
+    service.list_files = AsyncMock(
+        return_value=[
+            FileInfo(
+                file_id="f1",
+                filename="out.csv",
+                size=3,
+                content_type="text/csv",
+                created_at=datetime(2025, 1, 1, tzinfo=UTC),
+                path="/outputs/out.csv",
+            )
+        ]
+    )
+    service.get_file_content = AsyncMock(return_value=b"a,b")
+    return service
+
+
+@pytest.fixture
+def service(mock_redis, mock_file_service):
+    """Create a session report service with mocked Redis and files."""
+    return SessionReportService(redis_client=mock_redis, file_service=mock_file_service, minio_client=MagicMock())
+
+
+@pytest.fixture
+def mock_settings():
+    with patch("src.services.session_report.settings") as mock:
+        mock.session_report_enabled = True
+        mock.session_report_archive = False
+        mock.session_report_retention_hours = 24
+        mock.session_report_max_violations = 100
+        mock.minio_bucket = "files"
+        mock.get_session_ttl_minutes.return_value = 60
+        yield mock
+
+
+def _execution(**overrides):
+    values = {"execution_id": "exec-1", "session_id": "s1", "code": "print(1)", "status": ExecutionStatus.COMPLETED}
+    values.update(overrides)
+    return CodeExecution(**values)
+
+
+class TestExecutionViolations:
+    """Tests for finding the policies an execution ran into."""
+
+    def test_violations(self):
+        execution = _execution(
+            error={"code": "CONNECTION_LIMIT_EXCEEDED"}, network_connections=12, dns_denied=["a.example", "b.example"]
+        )
+        findings = [SecretFinding(source="file", file="keys.txt", kind="aws_access_key", line=3, action="blocked")]
+        sandbox = SandboxReport(mismatches=["seccomp: RuntimeDefault configured, disabled applied"])
+
+        violations = execution_violations(execution, findings, sandbox)
+
+        assert [(v.kind, v.detail) for v in violations] == [
+            ("connection_limit_exceeded", "12 connections"),
+            ("dns_denied", "a.example, b.example"),
+            ("secret_detected", "aws_access_key in keys.txt line 3 (blocked)"),
+            ("sandbox_mismatch", "seccomp: RuntimeDefault configured, disabled applied"),
+        ]
+        assert all(v.execution_id == "exec-1" for v in violations)
+
+    def test_clean_execution(self):
+        assert execution_violations(_execution()) == []
+
+
+class TestRecordExecution:
+    """Tests for tallying executions."""
+
+    @pytest.mark.asyncio
+    async def test_tallies(self, service, mock_pipeline, mock_settings):
+        """Counts, time, connections and the memory peak are added to the session's hash."""
+        execution = _execution(language="go", execution_time_ms=120, network_connections=2, memory_peak_mb=64.0)
+        violation = SessionViolation(kind="dns_denied", at=datetime.now(UTC), detail="a.example")
+
+        await service.record_execution("s1", execution, [violation])
+
+        increments = {call.args[1]: call.args[2] for call in mock_pipeline.hincrby.call_args_list}
+        assert increments == {
+            "executions": 1,
+            "status:completed": 1,
+            "lang:go": 1,
+            "execution_time_ms": 120,
+            "network_connections": 2,
+            "violations": 1,
+        }
+        mock_pipeline.hset.assert_any_call("session:report:s1", "memory_peak_mb", 64.0)
+        mock_pipeline.ltrim.assert_called_once_with("session:report:s1:violations", -100, -1)
+        mock_pipeline.expire.assert_any_call("session:report:s1", 3600)
+
+    @pytest.mark.asyncio
+    async def test_lower_memory_peak_kept(self, service, mock_redis, mock_pipeline, mock_settings):
+        mock_redis.hget.return_value = "128.0"
+
+        await service.record_execution("s1", _execution(memory_peak_mb=64.0))
+
+        assert all(call.args[1] != "memory_peak_mb" for call in mock_pipeline.hset.call_args_list)
+        mock_pipeline.rpush.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_disabled(self, service, mock_redis, mock_settings):
+        mock_settings.session_report_enabled = False
+
+        await service.record_execution("s1", _execution())
+
+        mock_redis.pipeline.assert_not_called()
+
+
+class TestBuild:
+    """Tests for assembling a report."""
+
+    @pytest.mark.asyncio
+    async def test_report(self, service, mock_redis, mock_settings):
+        """Tallies, violations and the session's files with digests make up the report."""
+        mock_redis.hgetall.return_value = {
+            "executions": "3",
+            "status:completed": "2",
+            "status:timeout": "1",
+            "lang:py": "3",
+            "execution_time_ms": "4500",
+            "network_connections": "1",
+            "memory_peak_mb": "210.5",
+            "first_at": "2025-01-01T10:00:00+00:00",
+            "last_at": "2025-01-01T11:00:00+00:00",
+            "violations": "3",
+        }
+        violation = SessionViolation(kind="dns_denied", execution_id="exec-2", at=datetime.now(UTC), detail="x")
+        mock_redis.lrange.return_value = [violation.model_dump_json()]
+        session = Session(session_id="s1", expires_at=datetime.now(UTC), metadata={"entity_id": "asst_1"})
+
+        report = await service.build("s1", session, reason="expired", ended=True)
+
+        assert (report.entity_id, report.reason, report.created_at) == ("asst_1", "expired", session.created_at)
+        assert report.ended_at is not None
+        assert report.executions.total == 3
+        assert report.executions.by_status == {"completed": 2, "timeout": 1}
+        assert report.executions.by_language == {"py": 3}
+        assert report.resources.execution_time_ms == 4500
+        assert report.resources.memory_peak_mb == 210.5
+        assert report.resources.artifact_bytes == 3
+        assert report.artifacts[0].sha256 == hashlib.sha256(b"a,b").hexdigest()
+        assert report.violations == [violation]
+        assert report.violations_dropped == 2
+
+    @pytest.mark.asyncio
+    async def test_unreadable_file(self, service, mock_file_service, mock_settings):
+        mock_file_service.get_file_content.side_effect = Exception("MinIO down")
+
+        report = await service.build("s1")
+
+        assert report.artifacts[0].sha256 is None
+        assert report.ended_at is None and report.executions.total == 0
+
+    @pytest.mark.asyncio
+    async def test_uses_recorded_digests(self, service, mock_file_service, mock_settings):
+        mock_file_service.list_files.return_value[0].sha256 = "ab" * 32
+
+        report = await service.build("s1")
+
+        assert report.artifacts[0].sha256 == "ab" * 32
+        mock_file_service.get_file_content.assert_not_called()
+
+
+class TestFinalize:
+    """Tests for the report made when a session ends."""
+
+    @pytest.mark.asyncio
+    async def test_stores_and_clears_tallies(self, service, mock_pipeline, mock_settings):
+        with patch("src.services.session_report.SecurityAudit") as mock_audit:
+            report = await service.finalize("s1", None, "deleted")
+
+        key, data = mock_pipeline.set.call_args.args
+        assert key == "session:report:s1:final"
+        assert mock_pipeline.set.call_args.kwargs["ex"] == 24 * 3600
+        assert SessionReport.model_validate_json(data) == report
+        mock_pipeline.delete.assert_called_once_with("session:report:s1", "session:report:s1:violations")
+        assert mock_audit.log_session_report.call_args.kwargs["artifacts"] == 1
+        assert report.archive_key is None
+
+    @pytest.mark.asyncio
+    async def test_archives(self, service, mock_settings):
+        mock_settings.session_report_archive = True
+
+        report = await service.finalize("s1", None, "expired")
+
+        assert report.archive_key == "reports/s1.json"
+        bucket, key, stream, length = service._minio_client.put_object.call_args.args
+        assert (bucket, key) == ("files", "reports/s1.json")
+        assert json.loads(stream.read())["reason"] == "expired"
+
+    @pytest.mark.asyncio
+    async def test_archive_failure_keeps_report(self, service, mock_settings):
+        mock_settings.session_report_archive = True
+        service._minio_client.put_object.side_effect = Exception("MinIO down")
+
+        report = await service.finalize("s1", None, "expired")
+
+        assert report is not None and report.archive_key is None
+
+    @pytest.mark.asyncio
+    async def test_disabled(self, service, mock_redis, mock_settings):
+        mock_settings.session_report_enabled = False
+
+        assert await service.finalize("s1", None, "deleted") is None
+        mock_redis.pipeline.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_get(self, service, mock_redis):
+        report = SessionReport(session_id="s1", reason="deleted")
+        mock_redis.get.return_value = report.model_dump_json()
+
+        assert await service.get("s1") == report
+
+        mock_redis.get.return_value = "not json"
+        assert await service.get("s1") is None
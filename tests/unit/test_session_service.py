@@ -219,6 +219,26 @@ async def test_cleanup_expired_sessions(session_service, mock_redis):
     assert cleaned_count == 2  # Two expired sessions cleaned
 
 
+@pytest.mark.asyncio
+async def test_cleanup_keeps_quarantined_sessions(session_service, mock_redis):
+    """Test expired sessions under quarantine are kept for investigation."""
+    mock_redis.smembers.return_value = ["quarantined1"]
+    session = Session(
+        session_id="quarantined1",
+        status=SessionStatus.QUARANTINED,
+        expires_at=datetime.now(UTC) - timedelta(hours=1),
+    )
+
+    with (
+        patch.object(session_service, "get_session", return_value=session),
+        patch.object(session_service, "delete_session") as mock_delete,
+    ):
+        cleaned_count = await session_service.cleanup_expired_sessions()
+
+    assert cleaned_count == 0
+    mock_delete.assert_not_called()
+
+
 @pytest.mark.asyncio
 async def test_session_key_generation(session_service):
     """Test session key generation."""
@@ -425,6 +445,51 @@ async def test_delete_session_with_entity_cleanup(session_service, mock_redis):
     pipeline_mock.srem.assert_called()  # Called twice - once for session index, once for entity
 
 
+@pytest.mark.asyncio
+async def test_delete_session_reports_before_file_cleanup(mock_redis):
+    """Test the end-of-session report is made before the session's files are deleted."""
+    calls = []
+    report_service = MagicMock()
+    report_service.finalize = AsyncMock(side_effect=lambda *args: calls.append(("report", *args)))
+    file_service = MagicMock()
+    file_service.cleanup_session_files = AsyncMock(side_effect=lambda session_id: calls.append(("files",)) or 1)
+    service = SessionService(redis_client=mock_redis, file_service=file_service, report_service=report_service)
+    session = Session(session_id="s1", expires_at=datetime.now(UTC))
+    mock_redis.pipeline.return_value.execute.return_value = [1, 1]
+
+    with patch.object(service, "get_session", return_value=session):
+        assert await service.delete_session("s1", reason="expired") is True
+
+    assert calls == [("report", "s1", session, "expired"), ("files",)]
+
+
+@pytest.mark.asyncio
+async def test_delete_session_survives_report_failure(mock_redis):
+    """Test a failed report doesn't keep the session from being deleted."""
+    report_service = MagicMock()
+    report_service.finalize = AsyncMock(side_effect=Exception("Redis down"))
+    service = SessionService(redis_client=mock_redis, report_service=report_service)
+    mock_redis.pipeline.return_value.execute.return_value = [1, 1]
+
+    with patch.object(service, "get_session", return_value=Session(session_id="s1", expires_at=datetime.now(UTC))):
+        assert await service.delete_session("s1") is True
+
+
+@pytest.mark.asyncio
+async def test_cleanup_reports_expiry(session_service, mock_redis):
+    """Test expired sessions are deleted with expired as the report's reason."""
+    mock_redis.smembers.return_value = ["expired1"]
+    session = Session(session_id="expired1", expires_at=datetime.now(UTC) - timedelta(hours=1))
+
+    with (
+        patch.object(session_service, "get_session", return_value=session),
+        patch.object(session_service, "delete_session", new_callable=AsyncMock) as mock_delete,
+    ):
+        await session_service.cleanup_expired_sessions()
+
+    mock_delete.assert_called_once_with("expired1", reason="expired")
+
+
 @pytest.mark.asyncio
 async def test_close(session_service, mock_redis):
     """Test service cleanup."""
@@ -1045,3 +1110,97 @@ class TestStartCleanupTask:
 
         # Task should not be started
         assert session_service._cleanup_task is None
+
+
+class TestSessionEnv:
+    """Tests for stored session environment variables."""
+
+    @pytest.mark.asyncio
+    async def test_set_session_env_expires_with_session(self, session_service, mock_redis):
+        """Stored env replaces the previous env and shares the session TTL."""
+        mock_redis.ttl = AsyncMock(return_value=3600)
+        pipeline_mock = mock_redis.pipeline.return_value
+
+        result = await session_service.set_session_env("session-123", {"API_KEY": "secret"})
+
+        assert result is True
+        pipeline_mock.delete.assert_called_once_with("session_env:session-123")
+        pipeline_mock.hset.assert_called_once_with("session_env:session-123", mapping={"API_KEY": "secret"})
+        pipeline_mock.expire.assert_called_once_with("session_env:session-123", 3600)
+
+    @pytest.mark.asyncio
+    async def test_set_session_env_missing_session(self, session_service, mock_redis):
+        """Env can't be stored for a session that doesn't exist."""
+        mock_redis.ttl = AsyncMock(return_value=-2)
+
+        result = await session_service.set_session_env("missing", {"A": "1"})
+
+        assert result is False
+        mock_redis.pipeline.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_set_empty_session_env_clears(self, session_service, mock_redis):
+        """An empty env only deletes the stored hash."""
+        mock_redis.ttl = AsyncMock(return_value=3600)
+        pipeline_mock = mock_redis.pipeline.return_value
+
+        await session_service.set_session_env("session-123", {})
+
+        pipeline_mock.delete.assert_called_once_with("session_env:session-123")
+        pipeline_mock.hset.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_get_session_env(self, session_service, mock_redis):
+        """Stored env is read back from its hash."""
+        mock_redis.hgetall = AsyncMock(return_value={"API_KEY": "secret"})
+
+        assert await session_service.get_session_env("session-123") == {"API_KEY": "secret"}
+        mock_redis.hgetall.assert_called_once_with("session_env:session-123")
+
+
+class TestSessionRestarts:
+    """Tests for kernel restart bookkeeping."""
+
+    @pytest.mark.asyncio
+    async def test_record_restart(self, session_service, mock_redis):
+        """Restarts increment the count and record the time."""
+        mock_redis.exists = AsyncMock(return_value=True)
+        pipeline_mock = mock_redis.pipeline.return_value
+        pipeline_mock.hincrby = MagicMock()
+        pipeline_mock.execute = AsyncMock(return_value=[3, 1])
+
+        assert await session_service.record_restart("session-123") == 3
+        pipeline_mock.hincrby.assert_called_once_with("sessions:session-123", "restart_count", 1)
+        assert pipeline_mock.hset.call_args.args[1] == "last_restart_at"
+
+    @pytest.mark.asyncio
+    async def test_record_restart_missing_session(self, session_service, mock_redis):
+        """A restart can't be recorded for a session that doesn't exist."""
+        mock_redis.exists = AsyncMock(return_value=False)
+
+        assert await session_service.record_restart("missing") is None
+        mock_redis.pipeline.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_get_session_parses_restarts(self, session_service, mock_redis):
+        """Restart fields round-trip through Redis strings; empty means never restarted."""
+        session_data = {
+            "session_id": "test-session",
+            "status": "active",
+            "created_at": "2023-01-01T00:00:00",
+            "last_activity": "2023-01-01T00:00:00",
+            "expires_at": "2023-01-02T00:00:00",
+            "restart_count": "2",
+            "last_restart_at": "2023-01-01T12:00:00",
+        }
+        mock_redis.hgetall.return_value = session_data
+        mock_redis.hset = AsyncMock()
+
+        session = await session_service.get_session("test-session")
+        assert session.restart_count == 2
+        assert session.last_restart_at == datetime(2023, 1, 1, 12)
+
+        mock_redis.hgetall.return_value = {**session_data, "restart_count": "0", "last_restart_at": ""}
+        session = await session_service.get_session("test-session")
+        assert session.restart_count == 0
+        assert session.last_restart_at is None
@@ -0,0 +1,220 @@
+"""Unit tests for session export and import."""
+
+import io
+import json
+import tarfile
+from datetime import UTC, datetime, timedelta
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from src.models.cell import CellInfo
+from src.models.files import FileInfo
+from src.models.session import Session
+from src.services.session_transfer import SessionTransferError, SessionTransferService, installed_packages
+
+NOW = datetime(2026, 1, 5, 12, 0, tzinfo=UTC)
+
+
+def make_cell(cell_id, code, status="completed"):
+    return CellInfo(cell_id=cell_id, code=code, lang="py", status=status, started_at=NOW)
+
+
+@pytest.fixture
+def session_service():
+    service = MagicMock()
+    service.get_session = AsyncMock(
+        return_value=Session(session_id="s1", expires_at=NOW + timedelta(hours=1), metadata={"entity_id": "e1"})
+    )
+    service.get_session_env = AsyncMock(return_value={"API_TOKEN": "secret"})
+    service.create_session = AsyncMock()
+    service.set_session_env = AsyncMock(return_value=True)
+    service.delete_session = AsyncMock(return_value=True)
+    return service
+
+
+@pytest.fixture
+def file_service():
+    service = MagicMock()
+    info = FileInfo(
+        file_id="f1", filename="data.csv", size=4, content_type="text/csv", created_at=NOW, path="/data.csv"
+    )
+    service.list_files = AsyncMock(return_value=[info])
+    service.get_file_content = AsyncMock(return_value=b"a,b\n")
+    service.restore_file = AsyncMock()
+    return service
+
+
+@pytest.fixture
+def state_service():
+    service = MagicMock()
+    service.get_state_raw = AsyncMock(return_value=b"\x02state")
+    service.save_state_raw = AsyncMock(return_value=True)
+    return service
+
+
+@pytest.fixture
+def cell_history_service():
+    service = MagicMock()
+    service.list_cells = AsyncMock(return_value=[make_cell(1, "!pip install polars==1.0"), make_cell(2, "print(1)")])
+    service.restore = AsyncMock(return_value=2)
+    return service
+
+
+@pytest.fixture
+def service(session_service, file_service, state_service, cell_history_service):
+    return SessionTransferService(session_service, file_service, state_service, MagicMock(), cell_history_service)
+
+
+def read_members(bundle):
+    with tarfile.open(fileobj=io.BytesIO(bundle), mode="r:gz") as archive:
+        return {m.name: archive.extractfile(m).read() for m in archive.getmembers()}
+
+
+def make_bundle(manifest, files=None):
+    buffer = io.BytesIO()
+    with tarfile.open(fileobj=buffer, mode="w:gz") as archive:
+        for name, content in {"manifest.json": json.dumps(manifest).encode(), **(files or {})}.items():
+            info = tarfile.TarInfo(name)
+            info.size = len(content)
+            archive.addfile(info, io.BytesIO(content))
+    return buffer.getvalue()
+
+
+class TestInstalledPackages:
+    """Tests for the packages manifest."""
+
+    def test_install_commands(self):
+        cells = [
+            make_cell(1, "%pip install -q pandas 'numpy<2' -i https://mirror/simple"),
+            make_cell(2, "cd /mnt/data && npm i lodash && python -m pip install pandas"),
+            make_cell(3, 'install.packages(c("dplyr", "ggplot2"), repos="x")'),
+        ]
+
+        assert installed_packages(cells) == [
+            {"manager": "pip", "spec": "pandas", "cell_id": 1},
+            {"manager": "pip", "spec": "numpy<2", "cell_id": 1},
+            {"manager": "npm", "spec": "lodash", "cell_id": 2},
+            {"manager": "cran", "spec": "dplyr", "cell_id": 3},
+            {"manager": "cran", "spec": "ggplot2", "cell_id": 3},
+        ]
+
+    def test_failed_cells_are_ignored(self):
+        assert installed_packages([make_cell(1, "pip install nope", status="failed")]) == []
+
+
+class TestExport:
+    """Tests for exporting a session."""
+
+    @pytest.mark.asyncio
+    async def test_bundle_contents(self, service):
+        members = read_members(await service.export("s1"))
+
+        manifest = json.loads(members["manifest.json"])
+        assert manifest["format_version"] == 1
+        assert manifest["session"]["session_id"] == "s1"
+        assert manifest["env"] == {"API_TOKEN": None}
+        assert manifest["files"][0]["file_id"] == "f1"
+        assert manifest["packages"] == [{"manager": "pip", "spec": "polars==1.0", "cell_id": 1}]
+        assert members["files/f1"] == b"a,b\n"
+        assert members["state.bin"] == b"\x02state"
+        assert len(members["cells.jsonl"].splitlines()) == 2
+
+    @pytest.mark.asyncio
+    async def test_include_env(self, service):
+        manifest = json.loads(read_members(await service.export("s1", include_env=True))["manifest.json"])
+
+        assert manifest["env"] == {"API_TOKEN": "secret"}
+
+    @pytest.mark.asyncio
+    async def test_archived_state(self, service, state_service):
+        state_service.get_state_raw = AsyncMock(side_effect=[None, b"archived"])
+        service.state_archival_service.restore_state = AsyncMock(return_value="YXJjaGl2ZWQ=")
+
+        assert read_members(await service.export("s1"))["state.bin"] == b"archived"
+
+    @pytest.mark.asyncio
+    async def test_unknown_session(self, service, session_service):
+        session_service.get_session = AsyncMock(return_value=None)
+
+        with pytest.raises(SessionTransferError) as exc_info:
+            await service.export("gone")
+
+        assert exc_info.value.code == "not_found"
+
+
+class TestImport:
+    """Tests for importing an exported session."""
+
+    @pytest.mark.asyncio
+    async def test_round_trip(self, service, session_service, file_service, state_service, cell_history_service):
+        bundle = await service.export("s1", include_env=True)
+        session_service.get_session = AsyncMock(return_value=None)
+
+        result = await service.import_bundle(bundle)
+
+        assert result.session_id == "s1"
+        assert (result.files, result.state_bytes, result.cells) == (1, 6, 2)
+        assert result.env == ["API_TOKEN"] and result.env_missing == []
+        assert session_service.create_session.call_args.kwargs == {"session_id": "s1"}
+        assert session_service.create_session.call_args.args[0].metadata == {"entity_id": "e1"}
+        file_service.restore_file.assert_called_once_with(
+            "s1", "f1", "data.csv", "/data.csv", b"a,b\n", "text/csv", NOW
+        )
+        state_service.save_state_raw.assert_called_once_with("s1", b"\x02state")
+        assert [c.cell_id for c in cell_history_service.restore.call_args.args[1]] == [1, 2]
+        session_service.set_session_env.assert_called_once_with("s1", {"API_TOKEN": "secret"})
+
+    @pytest.mark.asyncio
+    async def test_env_without_values(self, service, session_service):
+        bundle = await service.export("s1")
+        session_service.get_session = AsyncMock(return_value=None)
+
+        result = await service.import_bundle(bundle)
+
+        assert result.env == [] and result.env_missing == ["API_TOKEN"]
+        session_service.set_session_env.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_existing_session(self, service, session_service):
+        with pytest.raises(SessionTransferError) as exc_info:
+            await service.import_bundle(await service.export("s1"))
+
+        assert exc_info.value.code == "session_exists"
+        session_service.create_session.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_failed_import_is_rolled_back(self, service, session_service, file_service):
+        bundle = await service.export("s1")
+        session_service.get_session = AsyncMock(return_value=None)
+        file_service.restore_file = AsyncMock(side_effect=RuntimeError("minio down"))
+
+        with pytest.raises(RuntimeError):
+            await service.import_bundle(bundle)
+
+        session_service.delete_session.assert_called_once_with("s1")
+
+    @pytest.mark.asyncio
+    @pytest.mark.parametrize(
+        "manifest,files",
+        [
+            ({"format_version": 2, "session": {"session_id": "s1"}}, {}),
+            ({"format_version": 1, "session": {"session_id": "../s1"}}, {}),
+            ({"format_version": 1, "session": {"session_id": "s1"}, "files": []}, {"files/f1": b"x"}),
+        ],
+    )
+    async def test_invalid_bundles(self, service, session_service, manifest, files):
+        session_service.get_session = AsyncMock(return_value=None)
+
+        with pytest.raises(SessionTransferError) as exc_info:
+            await service.import_bundle(make_bundle(manifest, files))
+
+        assert exc_info.value.code == "invalid_bundle"
+        session_service.create_session.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_not_an_archive(self, service):
+        with pytest.raises(SessionTransferError) as exc_info:
+            await service.import_bundle(b"not a tarball")
+
+        assert exc_info.value.code == "invalid_archive"
@@ -42,3 +42,77 @@ class TestSeccompProfileTypeValidator:
         """Test that the default seccomp profile type is RuntimeDefault."""
         settings = Settings()
         assert settings.k8s_seccomp_profile_type == "RuntimeDefault"
+
+
+class TestOutputFilterValidators:
+    """Tests for output filter settings validation."""
+
+    def test_accepts_known_filters(self):
+        settings = Settings(output_filters=["secrets", "max_lines"])
+        assert settings.output_filters == ["secrets", "max_lines"]
+
+    def test_rejects_unknown_filter(self):
+        with pytest.raises(ValidationError) as exc_info:
+            Settings(output_filters=["secrets", "emoji"])
+
+        assert "emoji" in str(exc_info.value)
+
+    def test_rejects_invalid_pattern(self):
+        with pytest.raises(ValidationError) as exc_info:
+            Settings(output_redact_patterns=["corp-[0-9"])
+
+        assert any("output_redact_patterns" in str(e) for e in exc_info.value.errors())
+
+    def test_filters_disabled_by_default(self):
+        assert Settings().output_filters == []
+
+
+class TestContextEnvValidator:
+    """Tests for deployment context environment validation."""
+
+    def test_accepts_valid_names(self):
+        settings = Settings(context_env={"PLATFORM_DOCS_URL": "https://docs.example.com"})
+        assert settings.context_env == {"PLATFORM_DOCS_URL": "https://docs.example.com"}
+
+    def test_rejects_invalid_names(self):
+        with pytest.raises(ValidationError) as exc_info:
+            Settings(context_env={"DOCS-URL": "x", "OK": "y"})
+
+        assert "DOCS-URL" in str(exc_info.value)
+
+
+class TestFaultInjectionValidator:
+    """Tests for FAULT_INJECTION validation."""
+
+    def test_accepts_faults_and_options(self):
+        settings = Settings(fault_injection="spawn_failure=0.1, drop=0.05,delay=1,delay_ms=500,seed=7")
+        assert settings.fault_injection == "spawn_failure=0.1, drop=0.05,delay=1,delay_ms=500,seed=7"
+
+    @pytest.mark.parametrize("spec", ["explode=0.1", "drop", "drop=1.5", "seed=often"])
+    def test_rejects_invalid_faults(self, spec):
+        with pytest.raises(ValidationError) as exc_info:
+            Settings(fault_injection=spec)
+
+        assert "Invalid fault" in str(exc_info.value)
+
+    def test_disabled_by_default(self):
+        assert Settings(fault_injection="").fault_injection is None
+
+
+class TestWorkspaceStorageValidator:
+    """Tests for the working directory storage driver settings."""
+
+    def test_object_storage_needs_storage_class(self):
+        with pytest.raises(ValidationError) as exc_info:
+            Settings(workspace_storage_driver="object")
+
+        assert "WORKSPACE_STORAGE_CLASS" in str(exc_info.value)
+
+    def test_builds_configured_driver(self):
+        settings = Settings(
+            workspace_storage_driver="object", workspace_storage_class="csi-s3", workspace_size_limit="50Gi"
+        )
+
+        storage = settings.get_workspace_storage()
+        assert (storage.name, storage.storage_class, storage.size_limit) == ("object", "csi-s3", "50Gi")
+        assert Settings().get_workspace_storage().name == "local"
@@ -0,0 +1,103 @@
+"""Tests for the sidecar's synthetic benchmark."""
+
+import asyncio
+
+import pytest
+
+from executor import bench
+
+
+def stub_execute(results=None, delay=0.0):
+    """Answer executions by workload code: {code: result}, exit 0 with empty output otherwise."""
+    calls = []
+
+    async def execute(code, timeout):
+        calls.append(code)
+        await asyncio.sleep(delay)
+        default = {"exit_code": 0, "stdout": "", "stderr": "", "execution_time_ms": 5}
+        return (results or {}).get(code, default)
+
+    execute.calls = calls
+    return execute
+
+
+@pytest.fixture
+def cgroup(tmp_path):
+    (tmp_path / "memory.current").write_text("1048576\n")
+    (tmp_path / "memory.max").write_text("536870912\n")
+    (tmp_path / "cpu.stat").write_text("usage_usec 1000\nuser_usec 800\nnr_throttled 2\nthrottled_usec 50\n")
+    return tmp_path
+
+
+class TestArgs:
+    def test_defaults(self):
+        args = bench.parse_args([])
+
+        assert args.workloads == ["small", "cpu", "output"]
+        assert args.iterations is None and args.concurrency == 4
+
+    def test_unknown_workload(self):
+        with pytest.raises(SystemExit):
+            bench.parse_args(["--workload", "small,gpu"])
+
+
+class TestWorkloadCode:
+    def test_substitutes_parameters(self):
+        assert "end = time.time() + 0.5" in bench.workload_code("python", "cpu", cpu_seconds=0.5)
+        assert "i < 4;" in bench.workload_code("js", "output", output_bytes=4096)
+
+    def test_unsupported_language(self):
+        with pytest.raises(ValueError, match="No synthetic workloads for 'rs'"):
+            bench.workload_code("rs", "small")
+
+
+class TestPercentiles:
+    def test_nearest_rank(self):
+        result = bench.percentiles([float(n) for n in range(1, 101)])
+
+        assert result == {"p50": 50.0, "p95": 95.0, "p99": 99.0, "max": 100.0, "mean": 50.5}
+
+    def test_empty(self):
+        assert bench.percentiles([]) == {}
+
+
+class TestRunWorkload:
+    def test_reports_throughput_and_latency(self, cgroup):
+        execute = stub_execute(delay=0.01)
+
+        report = asyncio.run(bench.run_workload(execute, "small", "print(1)", 8, 4, 30, str(cgroup)))
+
+        assert len(execute.calls) == 8
+        assert report["failed"] == 0
+        assert report["throughput_per_second"] > 0
+        assert report["latency_ms"]["p50"] >= 10
+        assert report["memory_peak_bytes"] == 1048576
+        assert report["cpu"] == {"usage_usec": 0, "nr_throttled": 0, "throttled_usec": 0}
+
+    def test_counts_failures(self, cgroup):
+        execute = stub_execute({"boom": {"exit_code": 137, "stdout": "", "stderr": "Killed\n", "execution_time_ms": 9}})
+
+        report = asyncio.run(bench.run_workload(execute, "small", "boom", 3, 1, 30, str(cgroup)))
+
+        assert report["failed"] == 3
+        assert report["errors"] == ["exit 137: Killed"]
+
+    def test_cpu_loop_counts(self, cgroup):
+        execute = stub_execute({"spin": {"exit_code": 0, "stdout": "4000\n", "stderr": "", "execution_time_ms": 2000}})
+
+        report = asyncio.run(bench.run_workload(execute, "cpu", "spin", 2, 1, 30, str(cgroup)))
+
+        assert report["cpu_loops_per_execution"]["p50"] == 4000.0
+
+
+class TestRunBench:
+    def test_runs_selected_workloads(self, cgroup):
+        args = bench.parse_args(["--workload", "small,output", "--iterations", "2", "--output-bytes", "2048"])
+        execute = stub_execute()
+
+        report = asyncio.run(bench.run_bench(execute, "py", args, str(cgroup)))
+
+        assert report["ok"]
+        assert [w["workload"] for w in report["workloads"]] == ["small", "output"]
+        assert report["limits"]["memory"] == "536870912"
+        assert execute.calls[-1] == bench.workload_code("py", "output", output_bytes=2048)
@@ -0,0 +1,126 @@
+"""Tests for the sidecar's node-shared compiler caches."""
+
+import json
+import os
+
+import pytest
+
+from executor import compilecache
+
+
+def _write(path, size, mtime):
+    path.parent.mkdir(parents=True, exist_ok=True)
+    path.write_bytes(b"x" * size)
+    os.utime(path, (mtime, mtime))
+
+
+class TestLoad:
+    def test_off_without_dir(self):
+        assert compilecache.load("", "1024") is None
+
+    def test_quota(self, tmp_path):
+        assert compilecache.load(str(tmp_path), "").max_bytes == 2048 * 1024 * 1024
+        assert compilecache.load(str(tmp_path), "64").max_bytes == 64 * 1024 * 1024
+
+    @pytest.mark.parametrize("max_mb", ["lots", "0"])
+    def test_invalid_quota(self, tmp_path, max_mb):
+        with pytest.raises(ValueError, match="COMPILE_CACHE_MAX_MB"):
+            compilecache.load(str(tmp_path), max_mb)
+
+
+class TestPrepare:
+    def test_ccache(self, tmp_path):
+        cache = compilecache.CompileCache(str(tmp_path), 100 * 1024 * 1024)
+        env = {"PATH": "/usr/bin"}
+
+        run = cache.prepare("cpp", env)
+
+        assert run.tool == "ccache"
+        assert env["CCACHE_DIR"] == str(tmp_path / "ccache")
+        assert env["CCACHE_MAXSIZE"] == "100M"
+        assert env["CCACHE_STATSLOG"] == run.stats_path
+        assert os.path.dirname(run.stats_path) == str(tmp_path / ".stats")
+
+    def test_go_keeps_existing_goflags(self, tmp_path):
+        cache = compilecache.CompileCache(str(tmp_path), 1024)
+        env = {"GOFLAGS": "-mod=mod"}
+
+        run = cache.prepare("go", env)
+
+        assert run.tool == "go"
+        assert env["GOCACHE"] == str(tmp_path / "go-build")
+        assert env["GOFLAGS"] == f"-mod=mod -debug-actiongraph={run.stats_path}"
+
+    def test_languages_without_cache(self, tmp_path):
+        env = {}
+
+        assert compilecache.CompileCache(str(tmp_path), 1024).prepare("py", env) is None
+        assert env == {}
+
+    def test_unusable_dir(self, tmp_path):
+        blocker = tmp_path / "file"
+        blocker.write_text("")
+
+        assert compilecache.CompileCache(str(blocker), 1024).prepare("c", {}) is None
+
+
+class TestFinish:
+    def test_ccache_stats(self, tmp_path):
+        cache = compilecache.CompileCache(str(tmp_path), 1024)
+        run = cache.prepare("c", {})
+        with open(run.stats_path, "w") as f:
+            f.write("# code.c\ndirect_cache_hit\n# b.c\ncache_miss\n# c.c\npreprocessed_cache_hit\n")
+
+        assert cache.finish(run) == {"tool": "ccache", "hits": 2, "misses": 1}
+        assert not os.path.exists(run.stats_path)
+        assert cache.describe()["hit_rate"] == 0.6667
+
+    def test_go_actiongraph(self, tmp_path):
+        cache = compilecache.CompileCache(str(tmp_path), 1024)
+        run = cache.prepare("go", {})
+        actions = [
+            {"Mode": "build", "Package": "fmt"},
+            {"Mode": "build", "Package": "command-line-arguments", "Cmd": ["compile"]},
+            {"Mode": "link", "Package": "command-line-arguments", "Cmd": ["link"]},
+        ]
+        with open(run.stats_path, "w") as f:
+            json.dump(actions, f)
+
+        assert cache.finish(run) == {"tool": "go", "hits": 1, "misses": 1}
+
+    def test_nothing_reported(self, tmp_path):
+        cache = compilecache.CompileCache(str(tmp_path), 1024)
+
+        assert cache.finish(cache.prepare("go", {})) == {"tool": "go", "hits": None, "misses": None}
+        assert cache.describe()["hit_rate"] is None
+
+
+class TestSweep:
+    def test_evicts_least_recently_used_to_low_water(self, tmp_path):
+        cache = compilecache.CompileCache(str(tmp_path), 800)
+        _write(tmp_path / "ccache" / "a" / "old", 400, 100)
+        _write(tmp_path / "ccache" / "b" / "newer", 400, 200)
+        _write(tmp_path / "go-build" / "00" / "newest", 400, 300)
+
+        assert cache.sweep(now=400) == 2
+        assert not (tmp_path / "ccache" / "a" / "old").exists()
+        assert (tmp_path / "go-build" / "00" / "newest").exists()
+        assert cache.describe()["used_bytes"] == 400
+        assert cache.evictions == 2
+
+    def test_under_quota(self, tmp_path):
+        cache = compilecache.CompileCache(str(tmp_path), 1000)
+        _write(tmp_path / "ccache" / "a", 500, 100)
+
+        assert cache.sweep(now=200) == 0
+        assert cache.used_bytes == 500
+
+    def test_removes_stale_stats_files(self, tmp_path):
+        cache = compilecache.CompileCache(str(tmp_path), 1000)
+        _write(tmp_path / ".stats" / "killed", 10, 0)
+        _write(tmp_path / ".stats" / "running", 10, 5000)
+
+        cache.sweep(now=5000)
+
+        assert not (tmp_path / ".stats" / "killed").exists()
+        assert (tmp_path / ".stats" / "running").exists()
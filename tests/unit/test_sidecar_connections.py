@@ -0,0 +1,96 @@
+"""Tests for sidecar outbound connection accounting."""
+
+import os
+
+from executor import connections
+
+HEADER = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+
+
+def tcp_line(remote: str, state: str, inode: int) -> str:
+    return f"   0: 0500000A:C350 {remote} {state} 00000000:00000000 00:00000000 00000000  1000        0 {inode} 1 0\n"
+
+
+class TestParseTcpTable:
+    def test_open_connections_by_inode(self):
+        table = HEADER + tcp_line("08080808:01BB", "01", 101) + tcp_line("0500000A:0050", "02", 102)
+
+        assert connections.parse_tcp_table(table) == {101: ("08080808", 443), 102: ("0500000A", 80)}
+
+    def test_skips_listening_and_closed_sockets(self):
+        table = HEADER + tcp_line("00000000:0000", "0A", 201) + tcp_line("08080808:01BB", "06", 202)
+
+        assert connections.parse_tcp_table(table) == {}
+
+
+class TestReadConnections:
+    def test_leaves_out_loopback(self, tmp_path):
+        tcp = tmp_path / "tcp"
+        tcp.write_text(HEADER + tcp_line("0100007F:1F90", "01", 301) + tcp_line("08080808:01BB", "01", 302))
+        tcp6 = tmp_path / "tcp6"
+        tcp6.write_text(
+            HEADER
+            + tcp_line("00000000000000000000000001000000:1F90", "01", 303)
+            + tcp_line("0000000000000000FFFF00000100007F:1F90", "01", 304)
+            + tcp_line("B80D0120000000000000000001000000:01BB", "01", 305)
+        )
+
+        assert connections.read_connections((str(tcp), str(tcp6))).keys() == {302, 305}
+
+    def test_missing_tables_are_skipped(self, tmp_path):
+        assert connections.read_connections((str(tmp_path / "tcp"),)) == {}
+
+
+def fake_process(proc, pid: int, ppid: int, sockets: list[int] = ()) -> None:
+    (proc / str(pid) / "fd").mkdir(parents=True)
+    (proc / str(pid) / "stat").write_text(f"{pid} (python) x) S {ppid} {pid} {pid} 0 -1\n")
+    for fd, inode in enumerate(sockets):
+        os.symlink(f"socket:[{inode}]", proc / str(pid) / "fd" / str(fd + 3))
+    os.symlink("/dev/null", proc / str(pid) / "fd" / "0")
+
+
+class TestProcessTree:
+    def test_root_and_descendants(self, tmp_path):
+        fake_process(tmp_path, 10, 1)
+        fake_process(tmp_path, 11, 10)
+        fake_process(tmp_path, 12, 11)
+        fake_process(tmp_path, 20, 1)
+
+        assert sorted(connections.process_tree(10, str(tmp_path))) == [10, 11, 12]
+
+    def test_socket_inodes_of_the_tree(self, tmp_path):
+        fake_process(tmp_path, 10, 1, sockets=[101])
+        fake_process(tmp_path, 11, 10, sockets=[102, 103])
+
+        assert connections.socket_inodes([10, 11, 99], str(tmp_path)) == {101, 102, 103}
+
+
+class TestConnectionMonitor:
+    def test_counts_distinct_connections(self):
+        monitor = connections.ConnectionMonitor(root_pid=10)
+
+        assert monitor.record({1, 2})
+        assert monitor.record({2, 3})
+        assert monitor.record(set())
+
+        assert monitor.count == 3
+        assert monitor.peak == 2
+        assert not monitor.exceeded
+
+    def test_limit_applies_to_open_connections(self):
+        monitor = connections.ConnectionMonitor(root_pid=10, limit=2)
+
+        assert monitor.record({1, 2})
+        assert monitor.record({3, 4})
+        assert not monitor.record({5, 6, 7})
+
+        assert monitor.exceeded
+        error = monitor.error()
+        assert error["code"] == connections.ERROR_CODE
+        assert "3 connections" in error["message"]
+
+    def test_sample_of_this_process(self):
+        monitor = connections.ConnectionMonitor(root_pid=os.getpid())
+
+        assert monitor.sample()
+        assert monitor.count >= 0
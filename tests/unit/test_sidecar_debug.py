@@ -0,0 +1,90 @@
+"""Tests for the sidecar's support bundle."""
+
+import io
+import json
+import tarfile
+from types import SimpleNamespace
+
+from executor import debug
+
+
+class TestLogBuffer:
+    def test_keeps_recent_lines(self):
+        logs = debug.LogBuffer(maxlen=2)
+
+        logs.write("one\ntw")
+        logs.write("o\nthree\n")
+
+        lines = logs.text().splitlines()
+        assert [line.split(" ", 1)[1] for line in lines] == ["two", "three"]
+
+    def test_tee_writes_through(self):
+        logs = debug.LogBuffer()
+        stream = io.StringIO()
+
+        tee = logs.tee(stream)
+        print("[EXECUTE] started", file=tee, flush=True)
+
+        assert stream.getvalue() == "[EXECUTE] started\n"
+        assert logs.text().endswith(" [EXECUTE] started\n")
+
+
+class TestExecutionHistory:
+    def test_records_summaries_without_code_or_output(self):
+        history = debug.ExecutionHistory(maxlen=1)
+        response = SimpleNamespace(
+            execution_time_ms=12,
+            exit_code=1,
+            interrupted=False,
+            error={"code": "HOOK_FAILED"},
+            connections=2,
+            dns_denied=["evil.example.net"],
+            stdout="secret output",
+            stderr="",
+            provenance={"command": ["python", "/mnt/data/code.py"], "binaries": []},
+        )
+
+        history.record(0, response, code_bytes=10)
+        history.record(0, response, code_bytes=20)
+
+        (entry,) = history.entries
+        assert entry["code_bytes"] == 20
+        assert entry["error"] == "HOOK_FAILED"
+        assert entry["dns_denied"] == 1
+        assert entry["provenance"]["command"] == ["python", "/mnt/data/code.py"]
+        assert "secret output" not in json.dumps(entry)
+
+
+class TestRedactEnv:
+    def test_masks_secret_looking_names(self):
+        env = {"PATH": "/bin", "AWS_SECRET_ACCESS_KEY": "x", "GITHUB_TOKEN": "y", "DB_PASSWORD": "z"}
+
+        assert debug.redact_env(env) == {
+            "AWS_SECRET_ACCESS_KEY": debug.REDACTED,
+            "DB_PASSWORD": debug.REDACTED,
+            "GITHUB_TOKEN": debug.REDACTED,
+            "PATH": "/bin",
+        }
+
+
+class TestResourceStats:
+    def test_reads_available_cgroup_files(self, tmp_path):
+        (tmp_path / "memory.current").write_text("1048576\n")
+        (tmp_path / "pids.max").write_text("max\n")
+
+        stats = debug.resource_stats(str(tmp_path), cgroup_root=str(tmp_path))
+
+        assert stats["cgroup"] == {"memory.current": "1048576", "pids.max": "max"}
+        assert stats["working_dir"]["total"] > 0
+
+
+class TestBuildBundle:
+    def test_one_member_per_file(self):
+        bundle = debug.build_bundle({"logs.txt": "line\n", "config.json": {"language": "python"}})
+
+        with tarfile.open(fileobj=io.BytesIO(bundle), mode="r:gz") as tar:
+            members = {member.name.split("/", 1)[1]: tar.extractfile(member).read() for member in tar.getmembers()}
+
+        assert members["logs.txt"] == b"line\n"
+        assert json.loads(members["config.json"]) == {"language": "python"}
+        assert debug.platform_info(version="1.0")["version"] == "1.0"
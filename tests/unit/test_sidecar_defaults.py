@@ -0,0 +1,27 @@
+"""Tests for the sidecar's embedded default profiles."""
+
+import pytest
+
+from executor import defaults
+
+
+def test_seccomp_profile_denies_kernel_interfaces():
+    profile = defaults.seccomp_profile()
+
+    assert profile["defaultAction"] == "SCMP_ACT_ALLOW"
+    denied = {name for rule in profile["syscalls"] if rule["action"] == "SCMP_ACT_ERRNO" for name in rule["names"]}
+    assert {"init_module", "kexec_load", "bpf", "keyctl"} <= denied
+    # nsenter needs setns to enter the main container
+    assert "setns" not in denied
+
+
+def test_policy_limits():
+    limits = defaults.policy()["limits"]
+
+    assert limits["max_execution_time"] == 120
+    assert limits["max_output_size"] == 1048576
+
+
+def test_unknown_profile():
+    with pytest.raises(ValueError, match="No embedded profile"):
+        defaults.read("../main.py")
@@ -0,0 +1,82 @@
+"""Tests for the sidecar's degradation alarms."""
+
+import os
+
+from executor import degradation
+
+
+def trained(floor_ms: float = 100.0, baseline_ms: float = 20.0) -> degradation.ProbeBaseline:
+    probe = degradation.ProbeBaseline(floor_ms, factor=3.0)
+    for _ in range(degradation.BASELINE_SAMPLES):
+        probe.record(baseline_ms)
+    return probe
+
+
+class TestProbeBaseline:
+    def test_baseline_from_first_samples(self):
+        probe = degradation.ProbeBaseline(100.0)
+        for ms in (10, 50, 20, 30, 40):
+            assert probe.baseline_ms is None
+            probe.record(ms)
+
+        assert probe.baseline_ms == 30
+        assert not probe.degraded
+
+    def test_degraded_when_recent_median_exceeds_factor_and_floor(self):
+        probe = trained()
+        for ms in (500, 20, 500):
+            probe.record(ms)
+
+        assert probe.degraded
+        diagnostics = probe.diagnostics()
+        assert diagnostics["baseline_ms"] == 20
+        assert diagnostics["recent_ms"] == 500
+        assert diagnostics["threshold_ms"] == 120
+
+    def test_one_slow_sample_or_small_slowdown_is_not_degraded(self):
+        probe = trained()
+        for ms in (20, 900, 20):
+            probe.record(ms)
+        assert not probe.degraded
+
+        # 4x the baseline but only 60ms slower, under the floor
+        probe = trained()
+        for ms in (80, 80, 80):
+            probe.record(ms)
+        assert not probe.degraded
+
+    def test_failure_is_degraded_until_the_next_success(self):
+        probe = degradation.ProbeBaseline(100.0)
+        probe.record_failure("python -c pass exited 127: not found")
+
+        assert probe.degraded
+        assert probe.diagnostics()["error"].startswith("python")
+
+        probe.record(15)
+        assert not probe.degraded
+
+
+class TestHealthMonitor:
+    def test_report(self):
+        monitor = degradation.HealthMonitor(probes=("spawn", "disk"))
+        for _ in range(degradation.BASELINE_SAMPLES):
+            monitor.record("spawn", 10)
+            monitor.record("disk", 5)
+
+        assert monitor.report()["status"] == "ready"
+
+        for _ in range(degradation.RECENT_SAMPLES):
+            monitor.record("disk", 900)
+
+        report = monitor.report()
+        assert report["status"] == "degraded"
+        assert report["degraded"] == ["disk"]
+        assert set(report["checks"]) == {"spawn", "disk"}
+
+
+class TestDiskProbe:
+    def test_leaves_nothing_behind(self, tmp_path):
+        ms = degradation.disk_probe(str(tmp_path), size=4096)
+
+        assert ms >= 0
+        assert os.listdir(tmp_path) == []
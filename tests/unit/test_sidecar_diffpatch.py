@@ -0,0 +1,104 @@
+"""Tests for the sidecar's unified diff application."""
+
+import pytest
+
+from executor import diffpatch
+from executor.diffpatch import PatchError
+
+MODIFY = """\
+--- a/app.py
++++ b/app.py
+@@ -1,3 +1,3 @@
+ import os
+-print("old")
++print("new")
+ x = 1
+"""
+
+
+class TestParse:
+    def test_files_and_hunks(self):
+        [file_diff] = diffpatch.parse(MODIFY)
+        assert file_diff.path == "app.py"
+        [hunk] = file_diff.hunks
+        assert hunk.old == ["import os", 'print("old")', "x = 1"]
+
+    def test_strip_zero_keeps_prefix(self):
+        assert diffpatch.parse(MODIFY, strip=0)[0].path == "b/app.py"
+
+    def test_truncated_hunk(self):
+        with pytest.raises(PatchError, match="Truncated"):
+            diffpatch.parse(MODIFY.rsplit("\n", 2)[0])
+
+    def test_no_files(self):
+        with pytest.raises(PatchError, match="No file diffs"):
+            diffpatch.parse("just some text\n")
+
+
+class TestPlan:
+    def test_modifies_file(self, tmp_path):
+        (tmp_path / "app.py").write_text('import os\nprint("old")\nx = 1\n')
+
+        contents, files, rejects = diffpatch.plan(tmp_path, MODIFY)
+
+        assert rejects == []
+        assert files == [{"path": "app.py", "status": "modified", "hunks": 1}]
+        assert contents[(tmp_path / "app.py").resolve()] == b'import os\nprint("new")\nx = 1\n'
+        # Nothing is written by planning
+        assert 'print("old")' in (tmp_path / "app.py").read_text()
+
+    def test_finds_moved_hunk(self, tmp_path):
+        (tmp_path / "app.py").write_text('# header\n# more\nimport os\nprint("old")\nx = 1\n')
+
+        contents, _, rejects = diffpatch.plan(tmp_path, MODIFY)
+
+        assert rejects == []
+        assert contents[(tmp_path / "app.py").resolve()].startswith(b'# header\n# more\nimport os\nprint("new")')
+
+    def test_keeps_crlf_line_endings(self, tmp_path):
+        (tmp_path / "app.py").write_bytes(b'import os\r\nprint("old")\r\nx = 1\r\n')
+
+        contents, _, _ = diffpatch.plan(tmp_path, MODIFY)
+
+        assert contents[(tmp_path / "app.py").resolve()] == b'import os\r\nprint("new")\r\nx = 1\r\n'
+
+    def test_mismatch_rejects_with_context(self, tmp_path):
+        (tmp_path / "app.py").write_text('import sys\nprint("other")\nx = 2\n')
+
+        _, files, [reject] = diffpatch.plan(tmp_path, MODIFY)
+
+        assert files == []
+        assert reject["path"] == "app.py" and reject["line"] == 1
+        assert reject["found"] == ["import sys", 'print("other")', "x = 2"]
+
+    def test_create_and_delete(self, tmp_path):
+        (tmp_path / "old.txt").write_text("bye\n")
+        diff = (
+            "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,2 @@\n+hello\n+world\n"
+            "--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n"
+        )
+
+        contents, files, rejects = diffpatch.plan(tmp_path, diff)
+
+        assert rejects == []
+        assert [f["status"] for f in files] == ["created", "deleted"]
+        assert contents[(tmp_path / "new.txt").resolve()] == b"hello\nworld\n"
+        assert contents[(tmp_path / "old.txt").resolve()] is None
+
+    def test_no_newline_at_end_of_file(self, tmp_path):
+        (tmp_path / "a.txt").write_text("one\ntwo")
+        diff = "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n-two\n\\ No newline at end of file\n+three\n"
+
+        contents, _, rejects = diffpatch.plan(tmp_path, diff)
+
+        assert rejects == []
+        assert contents[(tmp_path / "a.txt").resolve()] == b"one\nthree\n"
+
+    def test_missing_file_is_rejected(self, tmp_path):
+        _, _, [reject] = diffpatch.plan(tmp_path, MODIFY)
+        assert reject["reason"] == "File not found"
+
+    def test_paths_outside_root(self, tmp_path):
+        diff = "--- a/../escape.txt\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+x\n"
+        with pytest.raises(PatchError, match="outside"):
+            diffpatch.plan(tmp_path, diff)
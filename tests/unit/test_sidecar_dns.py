@@ -0,0 +1,136 @@
+"""Tests for sidecar DNS allowlists."""
+
+import asyncio
+import struct
+
+import pytest
+
+from executor import dns
+
+
+def make_query(name: str, query_id: int = 0x1234) -> bytes:
+    question = b"".join(bytes([len(label)]) + label.encode() for label in name.split(".")) + b"\x00"
+    return struct.pack(">HHHHHH", query_id, 0x0100, 1, 0, 0, 0) + question + struct.pack(">HH", 1, 1)
+
+
+class TestParseQuery:
+    def test_name_and_question(self):
+        query = dns.parse_query(make_query("Files.PythonHosted.org"))
+
+        assert query.id == 0x1234
+        assert query.name == "files.pythonhosted.org"
+        assert query.question.endswith(struct.pack(">HH", 1, 1))
+
+    def test_rejects_responses_and_garbage(self):
+        response = bytearray(make_query("pypi.org"))
+        response[2] |= 0x80
+
+        assert dns.parse_query(bytes(response)) is None
+        assert dns.parse_query(b"\x00\x01") is None
+        assert dns.parse_query(make_query("pypi.org")[:-6]) is None
+
+    def test_nxdomain_answers_the_question(self):
+        query = dns.parse_query(make_query("evil.example.net"))
+
+        answer = dns.nxdomain(query)
+
+        query_id, flags, questions, answers = struct.unpack(">HHHH", answer[:8])
+        assert query_id == 0x1234
+        assert flags & 0x8000 and flags & 0x0100
+        assert flags & 0xF == 3
+        assert (questions, answers) == (1, 0)
+        assert answer[12:] == query.question
+
+
+class TestMatches:
+    @pytest.mark.parametrize(
+        "name,allowed",
+        [
+            ("pypi.org", True),
+            ("PyPI.org.", True),
+            ("files.pythonhosted.org", True),
+            ("pythonhosted.org", False),
+            ("evilpypi.org", False),
+            ("pypi.org.evil.net", False),
+        ],
+    )
+    def test_names_and_wildcards(self, name, allowed):
+        assert dns.matches(name, ["pypi.org", "*.pythonhosted.org"]) is allowed
+
+
+class TestUdpSocketInode:
+    def test_by_local_port(self, tmp_path):
+        table = tmp_path / "udp"
+        table.write_text(
+            "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+            "  1: 0100007F:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000 65532 0 1111 2 0\n"
+            "  2: 0100007F:D431 0100007F:0035 01 00000000:00000000 00:00000000 00000000 65532 0 2222 2 0\n"
+        )
+
+        assert dns.udp_socket_inode(0xD431, (str(table),)) == 2222
+        assert dns.udp_socket_inode(4000, (str(table),)) is None
+
+
+class TestPolicyRegistry:
+    def test_everything_allowed_without_allowlists(self):
+        policies = dns.PolicyRegistry()
+        policies.register(10, None)
+
+        assert policies.allows("example.com", 10)
+        assert policies.finish(10) == []
+
+    def test_operator_allowlist_applies_to_every_lookup(self):
+        policies = dns.PolicyRegistry(["pypi.org"])
+
+        assert policies.allows("pypi.org", None)
+        assert not policies.allows("example.com", None)
+
+    def test_execution_allowlist_narrows_the_operators(self):
+        policies = dns.PolicyRegistry(["pypi.org", "api.example.com"])
+        policies.register(10, ["api.example.com", "other.example.com"])
+
+        assert policies.allows("api.example.com", 10)
+        assert not policies.allows("pypi.org", 10)
+        assert not policies.allows("other.example.com", 10)
+        assert not policies.allows("pypi.org", 10)
+
+        assert policies.finish(10) == ["pypi.org", "other.example.com"]
+
+    def test_no_owner_without_executions(self):
+        assert dns.PolicyRegistry().owner(12345) is None
+
+
+class TestResolver:
+    @pytest.mark.asyncio
+    async def test_denied_lookup_gets_nxdomain(self):
+        loop = asyncio.get_running_loop()
+        policies = dns.PolicyRegistry(["pypi.org"])
+        server, _ = await loop.create_datagram_endpoint(
+            lambda: dns.Resolver("192.0.2.1", policies), local_addr=("127.0.0.1", 0)
+        )
+        answer = loop.create_future()
+        client, _ = await loop.create_datagram_endpoint(
+            lambda: dns._Reply(answer), remote_addr=server.get_extra_info("sockname")
+        )
+        try:
+            client.sendto(make_query("evil.example.net"))
+            response = await asyncio.wait_for(answer, 5)
+        finally:
+            client.close()
+            server.close()
+
+        assert struct.unpack(">H", response[2:4])[0] & 0xF == 3
+
+    @pytest.mark.asyncio
+    async def test_start_resolver_skips_unavailable_addresses(self):
+        transports = await dns.start_resolver("192.0.2.1", dns.PolicyRegistry(), ("127.0.0.1", "192.0.2.55"), 0)
+        try:
+            assert [t.get_extra_info("sockname")[0] for t in transports] == ["127.0.0.1"]
+        finally:
+            for transport in transports:
+                transport.close()
+
+    @pytest.mark.asyncio
+    async def test_start_resolver_fails_without_addresses(self):
+        with pytest.raises(OSError):
+            await dns.start_resolver("192.0.2.1", dns.PolicyRegistry(), ("192.0.2.55",), 0)
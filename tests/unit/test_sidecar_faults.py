@@ -0,0 +1,105 @@
+"""Tests for the sidecar's fault injection."""
+
+import asyncio
+
+import pytest
+
+from executor import faults
+
+
+def make_app(body=b"0123456789"):
+    async def app(scope, receive, send):
+        await send({"type": "http.response.start", "status": 200, "headers": []})
+        await send({"type": "http.response.body", "body": body})
+
+    return app
+
+
+def call(middleware, path="/execute"):
+    """Messages the middleware sent, and the exception it raised if any."""
+    sent = []
+
+    async def send(message):
+        sent.append(message)
+
+    async def receive():
+        return {"type": "http.request", "body": b""}
+
+    scope = {"type": "http", "method": "POST", "path": path}
+    try:
+        asyncio.run(middleware(scope, receive, send))
+    except faults.InjectedFault as e:
+        return sent, e
+    return sent, None
+
+
+class TestParse:
+    def test_parse_spec(self):
+        injector = faults.FaultInjector.parse("spawn_failure=0.1, drop=0.5,delay=1,delay_ms=50,seed=7")
+
+        assert injector.rates == {"spawn_failure": 0.1, "drop": 0.5, "delay": 1.0}
+        assert injector.delay_ms == 50
+
+    def test_empty_spec_disables(self):
+        assert faults.FaultInjector.parse("") is None
+        assert faults.FaultInjector.parse("seed=1") is None
+
+    @pytest.mark.parametrize("spec", ["explode=0.1", "drop", "drop=often", "drop=1.5", "delay_ms=soon"])
+    def test_invalid_spec(self, spec):
+        with pytest.raises(faults.FaultSpecError):
+            faults.FaultInjector.parse(spec)
+
+    def test_seed_makes_rolls_reproducible(self):
+        injectors = [faults.FaultInjector.parse("drop=0.5,seed=3") for _ in range(2)]
+
+        rolls = [[injector.roll("drop") for _ in range(50)] for injector in injectors]
+
+        assert rolls[0] == rolls[1]
+        assert 0 < sum(rolls[0]) < 50
+
+    def test_counts_injected_faults(self):
+        injector = faults.FaultInjector({"drop": 1.0, "truncate": 0.0})
+
+        assert injector.roll("drop") and not injector.roll("truncate") and not injector.roll("delay")
+        assert injector.report()["injected"] == {"drop": 1}
+
+
+class TestMiddleware:
+    def test_passes_through_without_faults(self):
+        sent, error = call(faults.FaultMiddleware(make_app(), faults.FaultInjector({"drop": 0.0})))
+
+        assert error is None
+        assert sent[-1]["body"] == b"0123456789"
+
+    def test_drop_closes_after_headers(self):
+        sent, error = call(faults.FaultMiddleware(make_app(), faults.FaultInjector({"drop": 1.0})))
+
+        assert error is not None
+        assert [m["type"] for m in sent] == ["http.response.start"]
+
+    def test_truncate_sends_half_the_body(self):
+        sent, error = call(faults.FaultMiddleware(make_app(), faults.FaultInjector({"truncate": 1.0})))
+
+        assert error is not None
+        assert sent[-1] == {"type": "http.response.body", "body": b"01234", "more_body": True}
+
+    def test_delay(self):
+        middleware = faults.FaultMiddleware(make_app(), faults.FaultInjector({"delay": 1.0}, delay_ms=100))
+        loop_time = []
+
+        async def timed():
+            start = asyncio.get_running_loop().time()
+            await middleware({"type": "http", "method": "GET", "path": "/files"}, None, lambda m: asyncio.sleep(0))
+            loop_time.append(asyncio.get_running_loop().time() - start)
+
+        asyncio.run(timed())
+
+        assert loop_time[0] >= 0.1
+
+    def test_probes_are_exempt(self):
+        injector = faults.FaultInjector({"drop": 1.0})
+
+        for path in faults.EXEMPT_PATHS:
+            sent, error = call(faults.FaultMiddleware(make_app(), injector), path)
+            assert error is None and sent[-1]["body"] == b"0123456789"
+        assert injector.injected == {}
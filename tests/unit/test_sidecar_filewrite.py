@@ -0,0 +1,74 @@
+"""Tests for the sidecar's atomic writes and appends."""
+
+import pytest
+
+from executor.filewrite import FileWriteError, atomic_write, growth, write_at
+
+
+class TestAtomicWrite:
+    def test_replaces_file_without_leaving_temporaries(self, tmp_path):
+        target = tmp_path / "data.csv"
+        target.write_bytes(b"old")
+
+        atomic_write(target, b"new contents")
+
+        assert target.read_bytes() == b"new contents"
+        assert [p.name for p in tmp_path.iterdir()] == ["data.csv"]
+
+    def test_creates_parent_directories(self, tmp_path):
+        atomic_write(tmp_path / "a" / "b.txt", b"x")
+        assert (tmp_path / "a" / "b.txt").read_bytes() == b"x"
+
+
+class TestWriteAt:
+    def test_append_creates_then_extends(self, tmp_path):
+        log = tmp_path / "run.log"
+
+        assert write_at(log, b"one\n") == 4
+        assert write_at(log, b"two\n") == 8
+        assert log.read_bytes() == b"one\ntwo\n"
+
+    def test_offset_write_overwrites_in_place(self, tmp_path):
+        target = tmp_path / "blob"
+        target.write_bytes(b"aaaaaa")
+
+        assert write_at(target, b"bb", offset=2) == 6
+        assert write_at(target, b"ccc", offset=6) == 9
+        assert target.read_bytes() == b"aabbaaccc"
+
+    def test_offset_past_end_is_refused(self, tmp_path):
+        target = tmp_path / "blob"
+        target.write_bytes(b"abc")
+
+        with pytest.raises(FileWriteError) as exc_info:
+            write_at(target, b"x", offset=4)
+        assert exc_info.value.status == 409
+
+    def test_negative_offset_is_refused(self, tmp_path):
+        with pytest.raises(FileWriteError) as exc_info:
+            write_at(tmp_path / "blob", b"x", offset=-1)
+        assert exc_info.value.status == 400
+
+    def test_expected_size_guards_retried_appends(self, tmp_path):
+        log = tmp_path / "run.log"
+        write_at(log, b"one\n", expected_size=0)
+
+        with pytest.raises(FileWriteError, match="expected 0") as exc_info:
+            write_at(log, b"one\n", expected_size=0)
+        assert exc_info.value.status == 409
+        assert log.read_bytes() == b"one\n"
+
+    def test_directory_is_refused(self, tmp_path):
+        with pytest.raises(FileWriteError, match="directory"):
+            write_at(tmp_path, b"x")
+
+
+class TestGrowth:
+    def test_counts_only_bytes_past_the_end(self, tmp_path):
+        target = tmp_path / "blob"
+        target.write_bytes(b"x" * 10)
+
+        assert growth(target, 4) == 4
+        assert growth(target, 4, offset=2) == 0
+        assert growth(target, 4, offset=8) == 2
+        assert growth(tmp_path / "missing", 3, offset=0) == 3
@@ -0,0 +1,39 @@
+"""Tests for sidecar execution hooks."""
+
+from executor import hooks
+
+
+class TestHookEnv:
+    def test_pre_hook(self):
+        assert hooks.hook_env("pre") == {"EXEC_HOOK": "pre"}
+
+    def test_post_hook_gets_exit_code(self):
+        assert hooks.hook_env("post", exit_code=2) == {"EXEC_HOOK": "post", "EXEC_EXIT_CODE": "2"}
+
+
+class TestHookFailed:
+    def test_keeps_end_of_stderr(self):
+        stderr = "x" * 5000 + "disk full\n"
+
+        error = hooks.hook_failed("post", 1, stderr, timeout=10)
+
+        assert error["code"] == "HOOK_FAILED"
+        assert error["hook"] == "post"
+        assert error["exit_code"] == 1
+        assert error["message"].endswith("disk full")
+        assert len(error["message"]) == hooks.MAX_MESSAGE_CHARS
+
+    def test_timeout(self):
+        error = hooks.hook_failed("pre", hooks.TIMEOUT_EXIT_CODE, "Command timed out after 5 seconds", timeout=5)
+
+        assert error["message"] == "timed out after 5 seconds"
+
+    def test_silent_failure(self):
+        assert hooks.hook_failed("pre", 3, "", timeout=5)["message"] == "exited with status 3"
+
+
+class TestFormatMessage:
+    def test_names_the_hook(self):
+        error = hooks.hook_failed("pre", 3, "snapshot failed", timeout=5)
+
+        assert hooks.format_message(error) == "HOOK_FAILED: pre-exec hook failed (exit code 3): snapshot failed\n"
@@ -0,0 +1,76 @@
+"""Tests for installing the static agent into a shared volume."""
+
+import json
+import os
+
+import pytest
+
+from executor import defaults, install
+
+
+def make_build(tmp_path):
+    build = tmp_path / "build"
+    build.mkdir()
+    files = []
+    for name in ("kubecoderun-agent", *install.HELPERS):
+        path = build / name
+        path.write_bytes(b"\x7fELF" + name.encode())
+        files.append(path)
+    return files
+
+
+class TestInstall:
+    def test_copies_executables_and_profiles(self, tmp_path):
+        files = make_build(tmp_path)
+        dest = tmp_path / "volume" / "kubecoderun"
+
+        report = install.install(dest, files)
+
+        assert report["ok"] is True
+        assert [f["name"] for f in report["files"]] == ["kubecoderun-agent", "nsenter"]
+        assert (dest / "nsenter").read_bytes() == b"\x7fELFnsenter"
+        assert os.access(dest / "kubecoderun-agent", os.X_OK)
+        assert json.loads((dest / "seccomp.json").read_text()) == defaults.seccomp_profile()
+        assert sorted(report["profiles"]) == sorted(defaults.PROFILES)
+        assert not list(dest.glob(".*.partial"))
+
+    def test_reinstall_replaces_files(self, tmp_path):
+        files = make_build(tmp_path)
+        install.install(tmp_path / "dest", files)
+        files[0].write_bytes(b"\x7fELFnew")
+
+        install.install(tmp_path / "dest", files)
+
+        assert (tmp_path / "dest" / "kubecoderun-agent").read_bytes() == b"\x7fELFnew"
+
+    def test_no_capabilities_to_keep(self, tmp_path):
+        files = make_build(tmp_path)
+
+        report = install.install(tmp_path / "dest", files)
+
+        assert all(f["capabilities"] is None for f in report["files"])
+
+    def test_profiles_only(self, tmp_path):
+        report = install.install(tmp_path / "seccomp", [], profiles_only=True)
+
+        assert report["files"] == []
+        assert (tmp_path / "seccomp" / "seccomp.json").is_file()
+        assert not (tmp_path / "seccomp" / "nsenter").exists()
+
+    def test_missing_helper(self, tmp_path):
+        files = make_build(tmp_path)
+        files[1].unlink()
+
+        with pytest.raises(install.InstallError, match="missing from the agent build"):
+            install.install(tmp_path / "dest", files)
+
+    def test_needs_static_build(self):
+        with pytest.raises(install.InstallError, match="static agent build"):
+            install.bundled_files()
+
+
+def test_parse_args():
+    args = install.parse_args(["/kubecoderun", "--profiles-only"])
+
+    assert args.dest == "/kubecoderun"
+    assert args.profiles_only is True
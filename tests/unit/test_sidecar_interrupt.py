@@ -0,0 +1,78 @@
+"""Tests for sidecar interrupt handling."""
+
+import signal
+
+from executor import interrupt
+
+
+class TestInterruptRegistry:
+    """Tests for tracking and signalling running executions."""
+
+    def test_interrupt_signals_process_groups(self):
+        """Every running execution's group gets SIGINT."""
+        sent = []
+        registry = interrupt.InterruptRegistry(killpg=lambda pgid, sig: sent.append((pgid, sig)))
+        registry.register(100)
+        registry.register(200)
+
+        assert registry.interrupt_all() == 2
+        assert sent == [(100, signal.SIGINT), (200, signal.SIGINT)]
+
+    def test_finish_reports_interrupted(self):
+        """finish() tells the execution whether it was interrupted, once."""
+        registry = interrupt.InterruptRegistry(killpg=lambda pgid, sig: None)
+        registry.register(100)
+        registry.register(200)
+        registry.interrupt_all()
+
+        assert registry.finish(100) is True
+        assert registry.running == 1
+
+    def test_finish_without_interrupt(self):
+        """Executions that weren't interrupted finish normally."""
+        registry = interrupt.InterruptRegistry(killpg=lambda pgid, sig: None)
+        registry.register(100)
+
+        assert registry.finish(100) is False
+        assert registry.interrupt_all() == 0
+
+    def test_exited_process_not_counted(self):
+        """A process that exited before the signal is skipped."""
+
+        def killpg(pgid, sig):
+            raise ProcessLookupError
+
+        registry = interrupt.InterruptRegistry(killpg=killpg)
+        registry.register(100)
+
+        assert registry.interrupt_all() == 0
+        assert registry.finish(100) is False
+
+
+    def test_kill_sends_sigkill_without_marking_interrupted(self):
+        """kill_all() uses SIGKILL and the execution isn't reported as interrupted."""
+        sent = []
+        registry = interrupt.InterruptRegistry(killpg=lambda pgid, sig: sent.append((pgid, sig)))
+        registry.register(100)
+
+        assert registry.kill_all() == 1
+        assert sent == [(100, signal.SIGKILL)]
+        assert registry.finish(100) is False
+
+
+class TestInterruptedResult:
+    """Tests for reporting interrupted executions."""
+
+    def test_python_killed_by_signal(self):
+        """A traceback already ending in KeyboardInterrupt is kept as is."""
+        stderr = "Traceback (most recent call last):\n  ...\nKeyboardInterrupt\n"
+        assert interrupt.interrupted_result(-2, stderr, "py") == (130, stderr)
+
+    def test_marker_added_when_missing(self):
+        """Runtimes that die silently get a marker line."""
+        assert interrupt.interrupted_result(-2, "", "python") == (130, "KeyboardInterrupt\n")
+        assert interrupt.interrupted_result(-2, "partial\n", "go") == (130, "partial\nInterrupted\n")
+
+    def test_handled_interrupt_keeps_exit_code(self):
+        """Code that caught the interrupt keeps its own exit code and output."""
+        assert interrupt.interrupted_result(0, "cleaned up\n", "py") == (0, "cleaned up\n")
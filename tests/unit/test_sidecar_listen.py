@@ -0,0 +1,85 @@
+"""Tests for the sidecar's listen addresses."""
+
+import errno
+import socket
+
+import pytest
+
+from executor import listen
+
+
+class TestParse:
+    def test_default_is_dual_stack(self):
+        assert listen.parse("") == [listen.Listener(host="::")]
+
+    def test_addresses(self):
+        listeners = listen.parse("0.0.0.0, [::1],loopback,unix:/run/sidecar/api.sock")
+
+        assert listeners == [
+            listen.Listener(host="0.0.0.0"),
+            listen.Listener(host="::1"),
+            listen.Listener(host="127.0.0.1"),
+            listen.Listener(path="/run/sidecar/api.sock"),
+        ]
+
+    @pytest.mark.parametrize("spec", ["localhost", "[::1", "unix:relative.sock", "10.0.0.300"])
+    def test_invalid(self, spec):
+        with pytest.raises(listen.ListenSpecError):
+            listen.parse(spec)
+
+    def test_describe(self):
+        assert listen.Listener(host="::1").describe(8080) == "[::1]:8080"
+        assert listen.Listener(host="127.0.0.1").describe(8080) == "127.0.0.1:8080"
+        assert listen.Listener(path="/tmp/a.sock").describe(8080) == "unix:/tmp/a.sock"
+
+
+class TestBind:
+    def test_ipv4_loopback(self):
+        sockets, addresses = listen.bind_all([listen.Listener(host="127.0.0.1")], 0)
+        try:
+            assert addresses == ["127.0.0.1:0"]
+            assert sockets[0].family == socket.AF_INET
+        finally:
+            for sock in sockets:
+                sock.close()
+
+    def test_unix_socket_replaces_stale_one(self, tmp_path):
+        path = str(tmp_path / "run" / "api.sock")
+        first, _ = listen.bind_all([listen.Listener(path=path)], 0)
+        first[0].close()
+
+        sockets, addresses = listen.bind_all([listen.Listener(path=path)], 0)
+        try:
+            assert addresses == [f"unix:{path}"]
+            client = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+            client.connect(path)
+            client.close()
+        finally:
+            for sock in sockets:
+                sock.close()
+
+    def test_wildcard_falls_back_to_ipv4(self, monkeypatch):
+        bind_tcp = listen.bind_tcp
+
+        def no_ipv6(host, port):
+            if ":" in host:
+                raise OSError(errno.EAFNOSUPPORT, "Address family not supported by protocol")
+            return bind_tcp(host, port)
+
+        monkeypatch.setattr(listen, "bind_tcp", no_ipv6)
+
+        sockets, addresses = listen.bind_all([listen.Listener(host="::")], 0)
+        try:
+            assert addresses == ["0.0.0.0:0"]
+        finally:
+            for sock in sockets:
+                sock.close()
+
+    def test_other_addresses_dont_fall_back(self, monkeypatch):
+        def no_ipv6(host, port):
+            raise OSError(errno.EADDRNOTAVAIL, "Cannot assign requested address")
+
+        monkeypatch.setattr(listen, "bind_tcp", no_ipv6)
+
+        with pytest.raises(OSError):
+            listen.bind_all([listen.Listener(host="::1")], 0)
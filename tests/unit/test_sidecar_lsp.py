@@ -0,0 +1,170 @@
+"""Tests for the sidecar's language server proxy."""
+
+import asyncio
+import sys
+
+import pytest
+
+from executor import lsp
+from executor.lsp import LspError, LspManager
+
+# A language server speaking just enough LSP: it reports a diagnostic on lines containing "oops",
+# asks the client for configuration once, and answers hover and definition.
+FAKE_SERVER = r'''
+import json, sys
+
+def read():
+    length = None
+    while True:
+        line = sys.stdin.buffer.readline()
+        if not line:
+            sys.exit(0)
+        if not line.strip():
+            break
+        if line.lower().startswith(b"content-length"):
+            length = int(line.split(b":")[1])
+    return json.loads(sys.stdin.buffer.read(length))
+
+def send(message):
+    body = json.dumps({"jsonrpc": "2.0", **message}).encode()
+    sys.stdout.buffer.write(b"Content-Length: %d\r\n\r\n" % len(body) + body)
+    sys.stdout.buffer.flush()
+
+def publish(uri, text):
+    diagnostics = [
+        {"range": {"start": {"line": n, "character": 0}, "end": {"line": n, "character": 4}},
+         "severity": 1, "message": "oops found", "source": "fake"}
+        for n, line in enumerate(text.splitlines()) if "oops" in line
+    ]
+    send({"method": "textDocument/publishDiagnostics", "params": {"uri": uri, "diagnostics": diagnostics}})
+
+while True:
+    message = read()
+    method = message.get("method")
+    if method == "initialize":
+        send({"id": message["id"], "result": {"capabilities": {}}})
+    elif method == "initialized":
+        send({"id": "cfg", "method": "workspace/configuration", "params": {"items": [{}]}})
+    elif method == "textDocument/didOpen":
+        publish(message["params"]["textDocument"]["uri"], message["params"]["textDocument"]["text"])
+    elif method == "textDocument/didChange":
+        publish(message["params"]["textDocument"]["uri"], message["params"]["contentChanges"][0]["text"])
+    elif method == "textDocument/hover":
+        position = message["params"]["position"]
+        value = "hover %d:%d" % (position["line"], position["character"])
+        send({"id": message["id"], "result": {"contents": {"kind": "markdown", "value": value}}})
+    elif method == "textDocument/definition":
+        uri = message["params"]["textDocument"]["uri"]
+        target = {"start": {"line": 0, "character": 4}, "end": {"line": 0, "character": 8}}
+        send({"id": message["id"], "result": [{"uri": uri, "range": target}]})
+    elif method == "shutdown":
+        send({"id": message["id"], "result": None})
+    elif method == "exit":
+        sys.exit(0)
+'''
+
+
+def make_manager(installed=("pyright-langserver",)):
+    spawned = []
+
+    async def spawn(command, root):
+        spawned.append(command)
+        return await asyncio.create_subprocess_exec(
+            sys.executable, "-c", FAKE_SERVER, stdin=asyncio.subprocess.PIPE, stdout=asyncio.subprocess.PIPE
+        )
+
+    async def available(names):
+        return {name for name in names if name in installed}
+
+    return LspManager(spawn, available), spawned
+
+
+class TestFraming:
+    @pytest.mark.asyncio
+    async def test_round_trip(self):
+        reader = asyncio.StreamReader()
+        reader.feed_data(lsp.encode({"id": 1, "result": "ok"}) + lsp.encode({"method": "x"}))
+        reader.feed_eof()
+
+        assert await lsp.read_message(reader) == {"id": 1, "result": "ok"}
+        assert await lsp.read_message(reader) == {"method": "x"}
+        assert await lsp.read_message(reader) is None
+
+    def test_server_for(self):
+        assert lsp.server_for("src/app.tsx") == ("typescript", "typescriptreact")
+        with pytest.raises(LspError) as exc_info:
+            lsp.server_for("notes.txt")
+        assert exc_info.value.status == 415
+
+
+class TestLanguageServer:
+    @pytest.mark.asyncio
+    async def test_diagnostics_follow_file_changes(self, tmp_path):
+        manager, _ = make_manager()
+        source = tmp_path / "app.py"
+        source.write_text("x = 1\noops\n")
+        server = await manager.get("python", tmp_path)
+        try:
+            [diagnostic] = await server.diagnostics(source, "python", wait=5)
+            assert diagnostic["line"] == 2 and diagnostic["column"] == 1
+            assert diagnostic["severity"] == "error" and diagnostic["message"] == "oops found"
+
+            source.write_text("x = 1\n")
+            assert await server.diagnostics(source, "python", wait=5) == []
+        finally:
+            await manager.stop_all()
+
+    @pytest.mark.asyncio
+    async def test_hover_and_definition_use_one_based_positions(self, tmp_path):
+        manager, _ = make_manager()
+        source = tmp_path / "app.py"
+        source.write_text("def main(): pass\nmain()\n")
+        server = await manager.get("python", tmp_path)
+        try:
+            hover = await server.hover(source, "python", line=2, column=3)
+            assert hover == {"contents": "hover 1:2"}
+
+            [location] = await server.definition(source, "python", line=2, column=1)
+            assert location == {"path": "app.py", "line": 1, "column": 5, "end_line": 1, "end_column": 9}
+        finally:
+            await manager.stop_all()
+
+    @pytest.mark.asyncio
+    async def test_missing_file(self, tmp_path):
+        manager, _ = make_manager()
+        server = await manager.get("python", tmp_path)
+        try:
+            with pytest.raises(LspError) as exc_info:
+                await server.hover(tmp_path / "missing.py", "python", 1, 1)
+            assert exc_info.value.status == 404
+        finally:
+            await manager.stop_all()
+
+
+class TestManager:
+    @pytest.mark.asyncio
+    async def test_reuses_running_server_and_picks_installed_command(self, tmp_path):
+        manager, spawned = make_manager(installed=("pylsp",))
+        try:
+            first = await manager.get("python", tmp_path)
+            second = await manager.get("python", tmp_path)
+            assert first is second
+            assert spawned == [["pylsp"]]
+        finally:
+            await manager.stop_all()
+
+    @pytest.mark.asyncio
+    async def test_no_installed_server(self, tmp_path):
+        manager, _ = make_manager(installed=())
+        with pytest.raises(LspError, match="No go language server installed") as exc_info:
+            await manager.get("go", tmp_path)
+        assert exc_info.value.status == 503
+
+    @pytest.mark.asyncio
+    async def test_stops_idle_servers(self, tmp_path):
+        manager, _ = make_manager()
+        server = await manager.get("python", tmp_path)
+
+        assert await manager.stop_idle(60, now=server.last_used + 30) == []
+        assert await manager.stop_idle(60, now=server.last_used + 61) == [server]
+        assert not server.alive and manager.status() == []
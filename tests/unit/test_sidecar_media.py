@@ -0,0 +1,137 @@
+"""Tests for the sidecar ffmpeg media profile."""
+
+import pytest
+from executor import media
+
+
+def feed_block(parser, lines):
+    """Feed lines and return the events produced."""
+    return [event for line in lines if (event := parser.feed(line)) is not None]
+
+
+class TestBuildFfmpegCommand:
+    """Tests for ffmpeg command construction."""
+
+    def test_progress_and_size_limit_options(self):
+        """Progress goes to stdout and the size limit precedes the output."""
+        cmd = media.build_ffmpeg_command("/mnt/data/in.mp4", "/mnt/data/out.webm", ["-c:v", "libvpx"], 1000)
+        assert cmd[0] == "ffmpeg"
+        assert cmd[cmd.index("-progress") + 1] == "pipe:1"
+        assert "-nostdin" in cmd
+        assert cmd[-3:] == ["-fs", "1000", "/mnt/data/out.webm"]
+
+    def test_never_overwrites(self):
+        """ffmpeg is told to fail on an existing output instead of overwriting it."""
+        cmd = media.build_ffmpeg_command("in.mp4", "out.mp4", [], 1000)
+        assert "-n" in cmd and "-y" not in cmd
+
+    def test_user_args_are_output_options(self):
+        """Caller args sit between the input and the output."""
+        cmd = media.build_ffmpeg_command("in.wav", "out.mp3", ["-b:a", "128k"], 1000)
+        assert cmd.index("in.wav") < cmd.index("-b:a") < cmd.index("out.mp3")
+
+    @pytest.mark.parametrize("option", ["-progress", "-fs", "-i", "-y"])
+    def test_reserved_options_rejected(self, option):
+        """Options the sidecar controls cannot be overridden."""
+        with pytest.raises(ValueError, match=option):
+            media.build_ffmpeg_command("in.mp4", "out.mp4", [option, "x"], 1000)
+
+    @pytest.mark.parametrize("option", ["-f", "-passlogfile", "-vstats_file", "-dump_attachment"])
+    def test_file_writing_options_rejected(self, option):
+        """Options that write files outside the size-limited output are refused."""
+        with pytest.raises(ValueError, match=option):
+            media.build_ffmpeg_command("in.mp4", "out.mp4", [option, "tee"], 1000)
+
+    @pytest.mark.parametrize(
+        "args",
+        [
+            ["big.mp4"],
+            ["-c:v", "libx264", "big.mp4"],
+            ["-an", "big.mp4"],
+            ["-map", "0", "-c", "copy", "-", "x"],
+        ],
+    )
+    def test_extra_outputs_rejected(self, args):
+        """A positional arg would be a second output that -fs doesn't cover."""
+        with pytest.raises(ValueError, match="Extra output"):
+            media.build_ffmpeg_command("in.mp4", "out.mp4", args, 1000)
+
+    def test_option_values_and_flags_accepted(self):
+        """Values that look like paths or negative numbers stay attached to their option."""
+        args = ["-vn", "-itsoffset", "-1.5", "-metadata", "title=out.mp4", "-shortest", "-b:a", "96k"]
+        cmd = media.build_ffmpeg_command("in.mp4", "out.m4a", args, 1000)
+        assert cmd[-3:] == ["-fs", "1000", "out.m4a"]
+
+
+class TestParseDuration:
+    """Tests for input duration parsing."""
+
+    def test_parses_stderr_duration(self):
+        """Duration line from the input banner is converted to seconds."""
+        line = "  Duration: 01:02:03.50, start: 0.000000, bitrate: 1411 kb/s"
+        assert media.parse_duration(line) == 3723.5
+
+    def test_ignores_other_lines(self):
+        """Lines without a duration return None."""
+        assert media.parse_duration("Stream #0:0: Audio: pcm_s16le") is None
+        assert media.parse_duration("  Duration: N/A, bitrate: N/A") is None
+
+
+class TestProgressParser:
+    """Tests for -progress stream parsing."""
+
+    def test_block_produces_one_event(self):
+        """A key=value block terminated by progress= yields one event."""
+        parser = media.ProgressParser(duration_seconds=10)
+        events = feed_block(
+            parser,
+            ["frame=120", "fps=30.0", "total_size=4096", "out_time_us=5000000", "speed=2.5x", "progress=continue"],
+        )
+        assert len(events) == 1
+        event = events[0]
+        assert event["type"] == "progress"
+        assert event["done"] is False
+        assert event["percent"] == 50.0
+        assert event["out_time_ms"] == 5000
+        assert event["duration_ms"] == 10000
+        assert event["frame"] == 120
+        assert event["fps"] == 30.0
+        assert event["speed"] == 2.5
+        assert event["total_size"] == 4096
+
+    def test_end_block_is_complete(self):
+        """progress=end marks the final event as done at 100%."""
+        parser = media.ProgressParser()
+        events = feed_block(parser, ["out_time_us=1000", "progress=end"])
+        assert events[0]["done"] is True
+        assert events[0]["percent"] == 100.0
+
+    def test_unknown_duration_has_no_percent(self):
+        """Without an input duration only absolute progress is reported."""
+        parser = media.ProgressParser()
+        events = feed_block(parser, ["out_time_us=2000000", "progress=continue"])
+        assert events[0]["percent"] is None
+        assert events[0]["out_time_ms"] == 2000
+
+    def test_not_available_values(self):
+        """N/A values (common at stream start) become None."""
+        parser = media.ProgressParser(duration_seconds=5)
+        events = feed_block(parser, ["out_time_us=N/A", "speed=N/A", "total_size=N/A", "progress=continue"])
+        assert events[0]["out_time_ms"] is None
+        assert events[0]["speed"] is None
+        assert events[0]["total_size"] is None
+        assert events[0]["percent"] is None
+
+    def test_blocks_do_not_leak(self):
+        """Each event only reflects its own block."""
+        parser = media.ProgressParser()
+        feed_block(parser, ["frame=10", "progress=continue"])
+        events = feed_block(parser, ["fps=25", "progress=continue"])
+        assert events[0]["frame"] is None
+        assert events[0]["fps"] == 25.0
+
+    def test_percent_is_capped(self):
+        """Output time past the probed duration never exceeds 100%."""
+        parser = media.ProgressParser(duration_seconds=1)
+        events = feed_block(parser, ["out_time_us=1500000", "progress=continue"])
+        assert events[0]["percent"] == 100.0
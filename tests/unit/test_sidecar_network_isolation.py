@@ -4,6 +4,10 @@ These tests verify that the network isolation overrides are correctly applied
 to environment variables for languages that require network access (e.g., Go).
 """
 
+import asyncio
+import importlib
+import sys
+
 import pytest
 
 
@@ -177,3 +181,53 @@ class TestLanguageSpecificBehavior:
             env = base_env.copy()
             result = apply_network_isolation_overrides(env, lang, network_isolated=True)
             assert "GOPROXY" not in result, f"{lang} should not have GOPROXY set"
+
+
+@pytest.fixture
+def sidecar_main():
+    """The sidecar's main module, imported through the conftest sys.path."""
+    # main tees stdout and stderr into its log buffer on import; keep pytest's streams
+    stdout, stderr = sys.stdout, sys.stderr
+    try:
+        module = importlib.import_module("main")
+    finally:
+        sys.stdout, sys.stderr = stdout, stderr
+    return module
+
+
+class TestExecuteViaNsenterIsolation:
+    """The isolation overrides applied by a real /execute, after request env."""
+
+    def test_request_env_cannot_undo_isolation(self, sidecar_main, monkeypatch, tmp_path):
+        spawned = []
+
+        async def create_subprocess_exec(*args, **kwargs):
+            spawned.append(list(args))
+            raise RuntimeError("stopped by the test")
+
+        async def no_provenance(*args):
+            return None
+
+        monkeypatch.setattr(sidecar_main, "LANGUAGE", "go")
+        monkeypatch.setattr(sidecar_main, "NETWORK_ISOLATED", True)
+        monkeypatch.setattr(sidecar_main, "find_main_container_pid", lambda: 4242)
+        monkeypatch.setattr(
+            sidecar_main,
+            "get_container_env",
+            lambda pid: {"PATH": "/usr/local/go/bin:/usr/bin", "GOPROXY": "https://proxy.golang.org,direct"},
+        )
+        monkeypatch.setattr(sidecar_main, "record_provenance", no_provenance)
+        monkeypatch.setattr(sidecar_main.asyncio, "create_subprocess_exec", create_subprocess_exec)
+        request = sidecar_main.ExecuteRequest(
+            code="package main\nfunc main() {}\n",
+            working_dir=str(tmp_path),
+            env={"GOPROXY": "https://proxy.golang.org,direct", "GOSUMDB": "sum.golang.org"},
+        )
+
+        asyncio.run(sidecar_main.execute_via_nsenter(request, sidecar_main.timing.ExecutionTimer()))
+
+        assert len(spawned) == 1
+        command = " ".join(spawned[0])
+        assert spawned[0][:3] == ["nsenter", "-t", "4242"]
+        assert "GOPROXY=off" in command and "GOSUMDB=off" in command
+        assert "proxy.golang.org" not in command and "sum.golang.org" not in command
@@ -0,0 +1,52 @@
+"""Tests for the sidecar's policy bundle."""
+
+import json
+
+import pytest
+
+from executor import policy
+
+BUNDLE = {
+    "name": "prod-default",
+    "version": "2026.10.1",
+    "limits": {"max_execution_time": 60},
+    "network": {"dns_allowlist": ["pypi.org"]},
+}
+
+
+class TestLoad:
+    def test_load(self):
+        loaded = policy.load(json.dumps(BUNDLE, separators=(",", ":"), sort_keys=True))
+
+        assert loaded.describe()["name"] == "prod-default"
+        assert loaded.describe()["version"] == "2026.10.1"
+        assert loaded.digest.startswith("sha256:")
+
+    def test_digest_ignores_formatting(self):
+        assert policy.load(json.dumps(BUNDLE, indent=2)).digest == policy.load(json.dumps(BUNDLE)).digest
+
+    def test_empty(self):
+        assert policy.load("") is None
+        assert policy.load("  ") is None
+
+    @pytest.mark.parametrize("text", ["{", "[]", '{"name": "p"}', '{"name": "p", "version": "1", "limits": 5}'])
+    def test_invalid(self, text):
+        with pytest.raises(policy.PolicyBundleError):
+            policy.load(text)
+
+
+class TestDefaults:
+    def test_max_execution_time(self):
+        loaded = policy.load(json.dumps(BUNDLE))
+
+        assert policy.max_execution_time("", loaded, 120) == 60
+        assert policy.max_execution_time("300", loaded, 120) == 300
+        assert policy.max_execution_time("", None, 120) == 120
+
+    def test_dns_allowlist(self):
+        loaded = policy.load(json.dumps(BUNDLE))
+
+        assert policy.dns_allowlist(None, loaded) == ["pypi.org"]
+        assert policy.dns_allowlist("a.com, b.com", loaded) == ["a.com", "b.com"]
+        assert policy.dns_allowlist("", loaded) == []
+        assert policy.dns_allowlist(None, None) == []
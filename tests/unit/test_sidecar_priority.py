@@ -0,0 +1,89 @@
+"""Tests for sidecar OOM/CPU priority of background executions."""
+
+import pytest
+
+from executor import priority
+
+
+class TestNiceForWeight:
+    @pytest.mark.parametrize("weight,nice", [(100, 0), (80, 1), (50, 3), (10, 10), (1, 19)])
+    def test_maps_like_cgroup_weights(self, weight, nice):
+        assert priority.nice_for_weight(weight) == nice
+
+
+class TestRaiseOomScoreAdj:
+    def test_raises(self, tmp_path):
+        path = tmp_path / "oom_score_adj"
+        path.write_text("100\n")
+
+        priority.raise_oom_score_adj(800, str(path))
+
+        assert path.read_text() == "800"
+
+    def test_never_lowers(self, tmp_path):
+        path = tmp_path / "oom_score_adj"
+        path.write_text("900\n")
+
+        priority.raise_oom_score_adj(500, str(path))
+
+        assert path.read_text() == "900\n"
+
+
+class TestMakePreexec:
+    def test_nothing_to_apply(self):
+        assert priority.make_preexec() is None
+        assert priority.make_preexec(oom_score_adj=0, cpu_weight=100) is None
+
+    def test_applies_nice_and_oom_score(self, monkeypatch):
+        calls = []
+        monkeypatch.setattr(priority.os, "nice", lambda n: calls.append(("nice", n)))
+        monkeypatch.setattr(priority, "raise_oom_score_adj", lambda v: calls.append(("oom", v)))
+
+        priority.make_preexec(oom_score_adj=500, cpu_weight=50)()
+
+        assert calls == [("oom", 500), ("nice", 3)]
+
+    def test_lower_priority_wins(self, monkeypatch):
+        calls = []
+        monkeypatch.setattr(priority.os, "nice", calls.append)
+
+        priority.make_preexec(cpu_weight=50, nice=10)()
+        priority.make_preexec(cpu_weight=10, nice=1)()
+
+        assert calls == [10, 10]
+
+    def test_applies_io_priority_and_affinity(self, monkeypatch):
+        calls = []
+        monkeypatch.setattr(priority, "set_io_priority", lambda c, level: calls.append(("io", c, level)))
+        monkeypatch.setattr(priority.os, "sched_setaffinity", lambda pid, cpus: calls.append(("cpus", pid, cpus)))
+
+        priority.make_preexec(io_priority=6, cpu_affinity=[2, 3])()
+        priority.make_preexec(io_class="idle")()
+
+        assert calls == [("io", "best-effort", 6), ("cpus", 0, [2, 3]), ("io", "idle", priority.DEFAULT_IO_PRIORITY)]
+
+    def test_failures_are_ignored(self, monkeypatch):
+        def fail(*args):
+            raise PermissionError("denied")
+
+        monkeypatch.setattr(priority.os, "nice", fail)
+        monkeypatch.setattr(priority, "raise_oom_score_adj", fail)
+
+        monkeypatch.setattr(priority, "set_io_priority", fail)
+        monkeypatch.setattr(priority.os, "sched_setaffinity", fail)
+
+        priority.make_preexec(oom_score_adj=500, cpu_weight=10, io_class="idle", cpu_affinity=[0])()
+
+
+class TestCpuAffinity:
+    def test_format_cpus(self):
+        assert priority.format_cpus([6, 0, 1, 2, 3, 9, 10]) == "0-3,6,9-10"
+
+    def test_online_cpus_accepted(self):
+        assert priority.affinity_error([0, 2], {0, 1, 2, 3}) is None
+
+    def test_offline_cpus_rejected(self):
+        error = priority.affinity_error([2, 5, 6], {0, 1, 2, 3})
+
+        assert error["code"] == "INVALID_CPU_AFFINITY"
+        assert error["message"] == "CPUs 5-6 aren't available; online: 0-3"
@@ -0,0 +1,100 @@
+"""Tests for recording which interpreter build ran an execution."""
+
+import asyncio
+import hashlib
+import os
+
+import pytest
+
+from executor import provenance
+
+
+@pytest.fixture
+def root(tmp_path):
+    """A container filesystem: /usr/bin/python -> python3.12, /bin -> usr/bin."""
+    bin_dir = tmp_path / "usr" / "bin"
+    bin_dir.mkdir(parents=True)
+    binary = bin_dir / "python3.12"
+    binary.write_bytes(b"#!fake python\n")
+    binary.chmod(0o755)
+    os.symlink("python3.12", bin_dir / "python")
+    os.symlink("/usr/bin", tmp_path / "bin")
+    (bin_dir / "notes.txt").write_text("not executable")
+    return tmp_path
+
+
+def stub_run(version="Python 3.12.3"):
+    calls = []
+
+    async def run(args, timeout):
+        calls.append(args)
+        return 0, f"{version}\n", ""
+
+    run.calls = calls
+    return run
+
+
+class TestResolve:
+    def test_follows_symlinks_inside_root(self, root):
+        assert provenance.resolve_in_root(str(root), "/bin/python") == "/usr/bin/python3.12"
+
+    def test_absolute_targets_stay_in_root(self, root, tmp_path_factory):
+        outside = tmp_path_factory.mktemp("outside")
+        os.symlink(str(outside), root / "escape")
+
+        assert provenance.resolve_in_root(str(root), "/escape/x") is None
+
+    def test_missing(self, root):
+        assert provenance.resolve_in_root(str(root), "/usr/bin/node") is None
+
+    def test_which_uses_path_order_and_needs_executables(self, root):
+        assert provenance.which(str(root), "python", "/opt/missing:/bin:/usr/bin") == "/usr/bin/python3.12"
+        assert provenance.which(str(root), "notes.txt", "/usr/bin") is None
+
+
+class TestStripEnv:
+    def test_removes_env_wrapper(self):
+        cmd = ["/usr/bin/env", "-i", "PATH=/usr/bin", "API_TOKEN=secret", "python", "/mnt/data/code.py"]
+
+        assert provenance.strip_env(cmd) == ["python", "/mnt/data/code.py"]
+
+    def test_other_commands_unchanged(self):
+        assert provenance.strip_env(["sh", "-c", "A=1 true"]) == ["sh", "-c", "A=1 true"]
+
+
+class TestRecord:
+    def test_records_path_hash_and_version(self, root):
+        cache = provenance.ProvenanceCache(stub_run())
+        cmd = ["/usr/bin/env", "-i", "PATH=/bin", "python", "/mnt/data/code.py"]
+
+        record = asyncio.run(cache.record(str(root), "/bin", cmd, "python"))
+
+        assert record["command"] == ["python", "/mnt/data/code.py"]
+        assert record["binaries"] == [
+            {
+                "name": "python",
+                "path": "/usr/bin/python3.12",
+                "sha256": hashlib.sha256(b"#!fake python\n").hexdigest(),
+                "version": "Python 3.12.3",
+            }
+        ]
+
+    def test_cached_until_the_binary_changes(self, root):
+        run = stub_run()
+        cache = provenance.ProvenanceCache(run)
+
+        asyncio.run(cache.record(str(root), "/bin", [], "py"))
+        asyncio.run(cache.record(str(root), "/bin", [], "py"))
+        assert run.calls == [["/usr/bin/python3.12", "--version"]]
+
+        (root / "usr" / "bin" / "python3.12").write_bytes(b"#!upgraded python\n")
+        record = asyncio.run(cache.record(str(root), "/bin", [], "py"))
+
+        assert len(run.calls) == 2
+        assert record["binaries"][0]["sha256"] == hashlib.sha256(b"#!upgraded python\n").hexdigest()
+
+    def test_missing_binary(self, root):
+        record = asyncio.run(provenance.ProvenanceCache(stub_run()).record(str(root), "/bin", [], "java"))
+
+        assert [b["name"] for b in record["binaries"]] == ["javac", "java"]
+        assert all(b["path"] is None for b in record["binaries"])
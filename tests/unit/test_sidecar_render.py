@@ -0,0 +1,95 @@
+"""Tests for the sidecar document rendering toolchains."""
+
+import pytest
+from executor import render
+
+
+class TestSelectToolchain:
+    """Tests for toolchain selection."""
+
+    def test_prefers_latexmk_when_available(self):
+        """latexmk is preferred over raw pdflatex when both exist."""
+        toolchain = render.select_toolchain("latex", "pdf", {"latexmk", "pdflatex"})
+        assert toolchain.name == "latexmk"
+
+    def test_latexmk_requires_pdflatex(self):
+        """latexmk alone is not enough, it drives pdflatex."""
+        toolchain = render.select_toolchain("latex", "pdf", {"latexmk", "tectonic"})
+        assert toolchain.name == "tectonic"
+
+    def test_markdown_pdf_needs_an_engine(self):
+        """pandoc without any PDF engine cannot produce PDF."""
+        assert render.select_toolchain("markdown", "pdf", {"pandoc"}) is None
+        toolchain = render.select_toolchain("markdown", "pdf", {"pandoc", "weasyprint"})
+        assert toolchain.name == "pandoc+weasyprint"
+
+    def test_nothing_installed(self):
+        """No toolchain is selected when nothing is installed."""
+        assert render.select_toolchain("markdown", "html", set()) is None
+
+    def test_unsupported_conversion(self):
+        """Unknown conversions have no toolchain."""
+        assert render.select_toolchain("latex", "html", {"pandoc", "pdflatex"}) is None
+        assert ("latex", "html") not in render.supported_conversions()
+
+    def test_required_binaries_deduplicated(self):
+        """Probing list contains each binary once, in preference order."""
+        binaries = render.required_binaries("markdown", "pdf")
+        assert binaries[0] == "pandoc"
+        assert binaries.count("pandoc") == 1
+        assert "weasyprint" in binaries
+
+
+class TestToolchainCommand:
+    """Tests for command construction."""
+
+    def test_pdflatex_uses_output_directory(self):
+        """LaTeX engines are pointed at the build directory."""
+        toolchain = render.select_toolchain("latex", "pdf", {"pdflatex"})
+        cmd = toolchain.command(source="/mnt/data/report.tex", output="/mnt/data/.render-1/report.pdf")
+        assert cmd[0] == "pdflatex"
+        assert "-output-directory=/mnt/data/.render-1" in cmd
+        assert "-interaction=nonstopmode" in cmd
+        assert cmd[-1] == "/mnt/data/report.tex"
+
+    def test_pandoc_writes_requested_output(self):
+        """pandoc is told the exact output path."""
+        toolchain = render.select_toolchain("markdown", "html", {"pandoc"})
+        cmd = toolchain.command(source="/mnt/data/a.md", output="/mnt/data/.render-1/output.html")
+        assert "--output=/mnt/data/.render-1/output.html" in cmd
+        assert "--standalone" in cmd
+
+    def test_built_artifact_name(self):
+        """LaTeX output follows the source stem; pandoc output is fixed."""
+        latex = render.select_toolchain("latex", "pdf", {"pdflatex"})
+        pandoc = render.select_toolchain("markdown", "html", {"pandoc"})
+        assert render.built_artifact_name(latex, "report.tex", "pdf") == "report.pdf"
+        assert render.built_artifact_name(pandoc, "notes.md", "html") == "output.html"
+
+
+class TestOutputFilename:
+    """Tests for artifact naming."""
+
+    @pytest.mark.parametrize(
+        "source,output,requested,expected",
+        [
+            ("report.tex", "pdf", None, "report.pdf"),
+            ("docs/notes.md", "html", None, "notes.html"),
+            (None, "pdf", None, "document.pdf"),
+            (None, "pdf", "summary", "summary.pdf"),
+            (None, "html", "summary.html", "summary.html"),
+            (None, "pdf", "../../etc/passwd", "passwd.pdf"),
+            (None, "pdf", "my report!.pdf", "my_report_.pdf"),
+            (None, "pdf", ".hidden", "hidden.pdf"),
+        ],
+    )
+    def test_output_filename(self, source, output, requested, expected):
+        """Artifact names are derived safely and carry the right extension."""
+        assert render.output_filename(source, output, requested) == expected
+
+    def test_unused_filename(self):
+        """A render never takes a name a workspace file already has."""
+        taken = {"report.pdf", "report.rendered.pdf"}
+        assert render.unused_filename("notes.pdf", taken.__contains__) == "notes.pdf"
+        assert render.unused_filename("report.pdf", taken.__contains__) == "report.rendered-2.pdf"
+        assert render.unused_filename("report.pdf", {"report.pdf"}.__contains__) == "report.rendered.pdf"
@@ -0,0 +1,81 @@
+"""Tests for the sidecar's read-only root filesystem check."""
+
+import asyncio
+import subprocess
+
+from executor import rootfs
+
+
+async def run_locally(args, timeout):
+    """Run the main container's commands here, as if both containers shared a filesystem."""
+    proc = subprocess.run(args, capture_output=True, text=True, timeout=timeout)
+    return proc.returncode, proc.stdout, proc.stderr
+
+
+def unwritable(tmp_path):
+    """A directory that can't be created: its parent is a file."""
+    (tmp_path / "file").write_text("")
+    return str(tmp_path / "file" / "dir")
+
+
+class TestProbes:
+    def test_writable_directory(self, tmp_path):
+        assert rootfs.probe_writable(str(tmp_path / "new")) is None
+        assert (tmp_path / "new").is_dir()
+        assert list((tmp_path / "new").iterdir()) == []
+
+    def test_unwritable_directory(self, tmp_path):
+        assert rootfs.probe_writable(unwritable(tmp_path)) == "Not a directory"
+
+    def test_probe_main(self, tmp_path):
+        results = asyncio.run(rootfs.probe_main(run_locally, [str(tmp_path), unwritable(tmp_path)]))
+
+        assert results == [None, "not writable"]
+
+    def test_probe_main_failure(self):
+        async def run(args, timeout):
+            return 127, "", "nsenter: not found\n"
+
+        assert asyncio.run(rootfs.probe_main(run, ["/a", "/b"])) == ["Probe failed: nsenter: not found"] * 2
+
+    def test_read_only(self, tmp_path):
+        assert rootfs.is_read_only(str(tmp_path)) is False
+        assert rootfs.is_read_only(str(tmp_path / "missing")) is None
+
+
+class TestCheck:
+    def test_all_writable(self, tmp_path):
+        directories = {"working_dir": str(tmp_path / "data"), "scratch_dir": str(tmp_path / "tmp")}
+
+        report = asyncio.run(rootfs.check(run_locally, directories, "py", main_root=str(tmp_path)))
+
+        assert report["ok"] and report["degraded"] == []
+        assert report["read_only_root"]["main"] is False
+        assert rootfs.summary(report) == "all directories writable"
+
+    def test_unwritable_scratch_degrades_compiled_languages(self, tmp_path):
+        directories = {"working_dir": str(tmp_path), "scratch_dir": unwritable(tmp_path)}
+
+        report = asyncio.run(rootfs.check(run_locally, directories, "rs"))
+
+        assert report["degraded"] == ["uploads over 1 MiB", "compiled languages"]
+        assert report["directories"]["scratch_dir"]["main_error"] == "not writable"
+        assert rootfs.summary(report).startswith("degraded: uploads over 1 MiB, compiled languages (scratch_dir")
+
+    def test_interpreted_languages_dont_need_scratch_in_main(self, tmp_path):
+        async def run(args, timeout):
+            return 0, "ok\nnot writable\n", ""
+
+        directories = {"working_dir": str(tmp_path), "scratch_dir": str(tmp_path / "tmp")}
+
+        report = asyncio.run(rootfs.check(run, directories, "py"))
+
+        assert report["ok"]
+        assert report["directories"]["scratch_dir"]["main_error"] == "not writable"
+
+    def test_unwritable_working_dir(self, tmp_path):
+        directories = {"working_dir": unwritable(tmp_path), "scratch_dir": str(tmp_path)}
+
+        report = asyncio.run(rootfs.check(run_locally, directories, "py"))
+
+        assert report["degraded"] == ["executions", "file uploads", "workspaces", "renders"]
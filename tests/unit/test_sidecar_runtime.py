@@ -0,0 +1,67 @@
+"""Tests for sidecar missing-interpreter detection."""
+
+import pytest
+
+from executor import runtime
+
+
+class TestFindMissingBinary:
+    """Tests for recognizing spawn failures."""
+
+    @pytest.mark.parametrize(
+        "stderr,language,expected",
+        [
+            ("/usr/bin/env: ‘python’: No such file or directory\n", "py", "python"),
+            ("/usr/bin/env: 'python': No such file or directory\n", "python", "python"),
+            ("env: can't execute 'node': No such file or directory\n", "js", "node"),
+            ("sh: 1: rustc: not found\n", "rs", "rustc"),
+            ("sh: javac: command not found\n", "java", "javac"),
+            ("nsenter: failed to execute /usr/bin/env: No such file or directory\n", "go", "env"),
+        ],
+    )
+    def test_detects(self, stderr, language, expected):
+        assert runtime.find_missing_binary(127, stderr, language) == expected
+
+    def test_ignores_other_exit_codes(self):
+        assert runtime.find_missing_binary(1, "sh: 1: rustc: not found", "rs") is None
+
+    def test_ignores_binaries_the_code_ran(self):
+        """A script calling a missing tool itself isn't a missing runtime."""
+        assert runtime.find_missing_binary(127, "sh: 1: curl: not found", "py") is None
+        assert runtime.find_missing_binary(127, "sh: 1: rustc: not found", "py") is None
+
+
+class TestFindCandidates:
+    def test_versioned_newest_first(self):
+        available = ["python3", "python3.9", "python3.11", "python3-config", "pip"]
+
+        assert runtime.find_candidates("python", available) == ["python3.11", "python3.9", "python3"]
+
+    def test_alternatives(self):
+        assert runtime.find_candidates("node", ["nodejs", "npm"]) == ["nodejs"]
+        assert runtime.find_candidates("gcc", ["clang", "gcc-12"]) == ["gcc-12", "clang"]
+
+    def test_nothing_similar(self):
+        assert runtime.find_candidates("ldc2", ["ls", "cat"]) == []
+
+
+class TestRuntimeNotFound:
+    def test_error_and_message(self):
+        error = runtime.runtime_not_found("python3", ["python3.11"])
+
+        assert error == {
+            "code": "RUNTIME_NOT_FOUND",
+            "wanted": "python3",
+            "available": ["python3.11"],
+            "suggestion": "python3.11",
+        }
+        message = runtime.format_message(error, "env: 'python3': No such file or directory\n")
+        assert message.startswith("RUNTIME_NOT_FOUND: 'python3' is not installed")
+        assert "Use 'python3.11' instead." in message
+        assert message.endswith("No such file or directory\n")
+
+    def test_no_candidates(self):
+        error = runtime.runtime_not_found("Rscript", [])
+
+        assert "suggestion" not in error
+        assert runtime.format_message(error) == "RUNTIME_NOT_FOUND: 'Rscript' is not installed in this image.\n"
@@ -0,0 +1,67 @@
+"""Tests for the sidecar's report of the protections an execution ran under."""
+
+from executor import sandbox
+
+STATUS = """Name:\tpython
+Uid:\t65532\t65532\t65532\t65532
+NoNewPrivs:\t0
+Seccomp:\t2
+Seccomp_filters:\t1
+"""
+
+
+def write_pod(tmp_path, status=STATUS, lsm="lockdown,capability,landlock,yama,apparmor", interfaces=("lo", "eth0")):
+    """A /proc/self/status, cgroup, LSM list and /sys/class/net under tmp_path."""
+    (tmp_path / "status").write_text(status)
+    cgroup = tmp_path / "cgroup"
+    cgroup.mkdir()
+    (cgroup / "memory.max").write_text("536870912\n")
+    (cgroup / "cpu.max").write_text("50000 100000\n")
+    (cgroup / "pids.max").write_text("max\n")
+    if lsm is not None:
+        (tmp_path / "lsm").write_text(lsm)
+    net = tmp_path / "net"
+    net.mkdir()
+    for name in interfaces:
+        (net / name).mkdir()
+    return {
+        "status_path": str(tmp_path / "status"),
+        "cgroup_root": str(cgroup),
+        "lsm_path": str(tmp_path / "lsm"),
+        "net_dir": str(net),
+    }
+
+
+class TestReport:
+    def test_reads_kernel_state(self, tmp_path):
+        report = sandbox.report(network_isolated=True, dns_policy=False, **write_pod(tmp_path))
+
+        assert report["seccomp"] == "filter" and report["seccomp_filters"] == 1
+        assert report["no_new_privs"] is False
+        assert report["uid"] == 65532
+        assert report["cgroup"] == {"memory": "536870912", "cpu": "50000 100000", "pids": "max"}
+        assert report["landlock"] == "not_applied"
+        assert report["network"] == {"mode": "isolated", "interfaces": ["eth0"], "dns_policy": False}
+
+    def test_without_seccomp_or_landlock(self, tmp_path):
+        paths = write_pod(tmp_path, status="Uid:\t0\t0\t0\t0\nNoNewPrivs:\t1\nSeccomp:\t0\n", lsm="capability,yama")
+
+        report = sandbox.report(network_isolated=False, dns_policy=True, **paths)
+
+        assert (report["seccomp"], report["no_new_privs"], report["uid"]) == ("disabled", True, 0)
+        assert report["landlock"] == "unavailable"
+        assert report["network"]["mode"] == "open" and report["network"]["dns_policy"] is True
+
+    def test_unreadable_fields_are_none(self, tmp_path):
+        report = sandbox.report(
+            network_isolated=True,
+            dns_policy=False,
+            status_path=str(tmp_path / "missing"),
+            cgroup_root=str(tmp_path / "missing"),
+            lsm_path=str(tmp_path / "missing"),
+            net_dir=str(tmp_path / "missing"),
+        )
+
+        assert report["seccomp"] is None and report["no_new_privs"] is None and report["uid"] is None
+        assert report["landlock"] is None and report["network"]["interfaces"] is None
+        assert report["cgroup"] == {"memory": None, "cpu": None, "pids": None}
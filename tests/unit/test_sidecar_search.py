@@ -0,0 +1,115 @@
+"""Tests for the sidecar's workspace file search."""
+
+import time
+
+import pytest
+
+from executor import search
+from executor.search import SearchError, glob_to_regex
+
+
+@pytest.fixture
+def project(tmp_path):
+    (tmp_path / "src").mkdir()
+    (tmp_path / "src" / "app.py").write_text("import os\n\ndef main():\n    return os.getcwd()\n")
+    (tmp_path / "src" / "notes.md").write_text("main entry point\n")
+    (tmp_path / "top.py").write_text("def main_helper():\n    pass\n")
+    return tmp_path
+
+
+class TestGlob:
+    @pytest.mark.parametrize(
+        "pattern,path,matches",
+        [
+            ("**/*.py", "top.py", True),
+            ("**/*.py", "src/app.py", True),
+            ("*.py", "src/app.py", False),
+            ("src/*", "src/app.py", True),
+            ("src/?pp.py", "src/app.py", True),
+            ("**/*.py", "src/notes.md", False),
+        ],
+    )
+    def test_patterns(self, pattern, path, matches):
+        assert bool(glob_to_regex(pattern).match(path)) is matches
+
+
+class TestSearch:
+    def test_matches_with_context(self, project):
+        result = search.search(project, r"def main\(", glob="**/*.py", context=1)
+
+        assert result["truncated"] is False
+        [match] = result["matches"]
+        assert match["path"] == "src/app.py"
+        assert (match["line"], match["column"]) == (3, 1)
+        assert match["before"] == [""]
+        assert match["after"] == ["    return os.getcwd()"]
+
+    def test_glob_limits_files(self, project):
+        result = search.search(project, "main", glob="**/*.md")
+        assert [m["path"] for m in result["matches"]] == ["src/notes.md"]
+        assert result["files_scanned"] == 1
+
+    def test_literal_and_case_insensitive(self, project):
+        assert search.search(project, "os.getcwd()", regex=False)["matches"]
+        assert not search.search(project, "MAIN", glob="**/*.md")["matches"]
+        assert search.search(project, "MAIN", glob="**/*.md", ignore_case=True)["matches"]
+
+    def test_match_cap_truncates(self, project):
+        result = search.search(project, "main", max_matches=2)
+        assert len(result["matches"]) == 2
+        assert result["truncated"] is True
+
+    def test_skips_binary_hidden_and_symlinked_files(self, project, tmp_path_factory):
+        outside = tmp_path_factory.mktemp("outside")
+        (outside / "secret.py").write_text("def main(): pass\n")
+        (project / "link.py").symlink_to(outside / "secret.py")
+        (project / "data.bin").write_bytes(b"main\0\x01")
+        (project / ".git").mkdir()
+        (project / ".git" / "config.py").write_text("main\n")
+
+        paths = {m["path"] for m in search.search(project, "main")["matches"]}
+
+        assert paths == {"src/app.py", "src/notes.md", "top.py"}
+
+    def test_large_files_are_skipped_and_reported(self, project, monkeypatch):
+        monkeypatch.setattr(search, "MAX_FILE_SIZE", 10)
+        result = search.search(project, "main", glob="**/*.py")
+        assert result["matches"] == []
+        assert result["truncated"] is True
+
+    def test_invalid_pattern(self, project):
+        with pytest.raises(SearchError, match="Invalid pattern"):
+            search.search(project, "(")
+
+
+class TestRun:
+    @pytest.mark.asyncio
+    async def test_searches_in_child_process(self, project):
+        result = await search.run(project, r"def main\(", glob="**/*.py")
+
+        assert [m["path"] for m in result["matches"]] == ["src/app.py"]
+        assert result["truncated"] is False
+
+    @pytest.mark.asyncio
+    async def test_errors_are_raised(self, project):
+        with pytest.raises(SearchError, match="Invalid pattern"):
+            await search.run(project, "(")
+
+    @pytest.mark.asyncio
+    async def test_catastrophic_backtracking_is_killed(self, project, monkeypatch):
+        """A pattern stuck inside one regex call is stopped by killing its process."""
+        monkeypatch.setattr(search, "KILL_GRACE", 0.5)
+        (project / "slow.txt").write_text("a" * 40 + "!\n")
+
+        started = time.monotonic()
+        with pytest.raises(SearchError, match="timed out"):
+            await search.run(project, r"(a+)+$", glob="*.txt", timeout=0.5)
+
+        assert time.monotonic() - started < 5
+
+
+def test_worker_command(monkeypatch):
+    assert search.worker_command()[1:] == ["-m", "executor.search"]
+
+    monkeypatch.setattr(search.sys, "frozen", True, raising=False)
+    assert search.worker_command()[1:] == ["search-worker"]
@@ -0,0 +1,171 @@
+"""Tests for the sidecar's conformance self-test."""
+
+import asyncio
+import struct
+import subprocess
+
+import pytest
+
+from executor import selftest
+
+
+def local_runner(working_dir):
+    """Run commands on this machine, in place of the main container."""
+
+    async def run(args, timeout):
+        result = await asyncio.to_thread(
+            subprocess.run, args, cwd=working_dir, capture_output=True, text=True, timeout=timeout
+        )
+        return result.returncode, result.stdout, result.stderr
+
+    return run
+
+
+def stub_runner(working_dir, results):
+    """Run shell commands locally and answer the rest by binary name: {binary: (exit_code, stdout, stderr)}."""
+    local = local_runner(working_dir)
+
+    async def run(args, timeout):
+        if args[0] == "sh":
+            return await local(args, timeout)
+        return results.get(args[0], (127, "", f"{args[0]}: not found"))
+
+    return run
+
+
+def stub_binaries(present):
+    async def probe(names):
+        return set(present) & set(names)
+
+    return probe
+
+
+def write_cgroup(root, **files):
+    for name, value in files.items():
+        path = root / name
+        path.parent.mkdir(parents=True, exist_ok=True)
+        path.write_text(value + "\n")
+
+
+class TestLimits:
+    def test_reads_cgroup_v2(self, tmp_path):
+        write_cgroup(tmp_path, **{"memory.max": "536870912", "cpu.max": "50000 100000", "pids.max": "512"})
+
+        result = selftest.check_limits(str(tmp_path))
+
+        assert result["ok"]
+        assert result["limits"] == {"memory": "536870912", "cpu": "50000 100000", "pids": "512"}
+        assert result["unlimited"] == []
+
+    def test_unlimited_is_a_warning(self, tmp_path):
+        write_cgroup(tmp_path, **{"memory.max": "max", "cpu.max": "max 100000"})
+
+        result = selftest.check_limits(str(tmp_path))
+
+        assert result["ok"]
+        assert result["unlimited"] == ["memory", "cpu", "pids"]
+
+    def test_cgroup_v1_memory(self, tmp_path):
+        write_cgroup(tmp_path, **{"memory/memory.limit_in_bytes": str(2**63 - 4096)})
+
+        assert selftest.read_cgroup_limits(str(tmp_path))["memory"] == "max"
+
+    def test_no_cgroup(self, tmp_path):
+        assert not selftest.check_limits(str(tmp_path / "missing"))["ok"]
+
+
+class TestWorkspaceIo:
+    def test_round_trip(self, tmp_path):
+        result = asyncio.run(selftest.check_workspace_io(local_runner(tmp_path), str(tmp_path)))
+
+        assert result["ok"], result["detail"]
+        assert list(tmp_path.iterdir()) == []
+
+    def test_main_container_sees_other_directory(self, tmp_path):
+        other = tmp_path / "other"
+        other.mkdir()
+
+        result = asyncio.run(selftest.check_workspace_io(local_runner(other), str(tmp_path)))
+
+        assert not result["ok"]
+
+
+class TestCheckNsenter:
+    def test_capabilities(self, tmp_path):
+        nsenter = tmp_path / "nsenter"
+        nsenter.write_bytes(b"\x7fELF")
+
+        assert selftest.check_nsenter(str(nsenter), uid=0)["ok"]
+        result = selftest.check_nsenter(str(nsenter), uid=65532)
+        assert not result["ok"]
+        assert result["detail"] == "Missing file capabilities: cap_sys_admin, cap_sys_chroot, cap_sys_ptrace"
+
+    def test_staticx_bundle_fails(self, tmp_path):
+        nsenter = tmp_path / "nsenter"
+        nsenter.write_bytes(b"\x7fELF" + selftest.STATICX_MARKER + b"\0")
+
+        result = selftest.check_nsenter(str(nsenter), uid=0)
+
+        assert not result["ok"]
+        assert "staticx bundle" in result["detail"]
+
+    def test_not_found(self, tmp_path):
+        assert not selftest.check_nsenter(str(tmp_path / "missing"))["ok"]
+
+    def test_parse_capabilities(self):
+        permitted = (1 << 18) | (1 << 19) | (1 << 21)
+        # VFS_CAP_REVISION_2 with the effective flag, permitted and inheritable, low then high words
+        value = struct.pack("<IIIII", 0x02000001, permitted, 0, 0, 0)
+
+        assert selftest.parse_capabilities(value) == set(selftest.NSENTER_CAPABILITIES)
+        assert selftest.parse_capabilities(struct.pack("<IIIII", 0x02000000, permitted, 0, 0, 0)) == set()
+
+
+@pytest.fixture
+def cgroup(tmp_path, monkeypatch):
+    root = tmp_path / "cgroup"
+    write_cgroup(root, **{"memory.max": "536870912"})
+    monkeypatch.setattr(selftest, "CGROUP_ROOT", str(root))
+    monkeypatch.setattr(selftest, "check_nsenter", lambda: {"name": "nsenter", "ok": True, "ms": 0, "detail": ""})
+    return root
+
+
+class TestRunSelftest:
+    def test_passing_image(self, tmp_path, cgroup):
+        run = stub_runner(
+            tmp_path, {"python": (0, "selftest-ok\n", ""), "gcc": (0, "gcc (Debian 12.2.0) 12.2.0\n", "")}
+        )
+
+        report = asyncio.run(
+            selftest.run_selftest(run, stub_binaries({"python", "gcc"}), str(tmp_path), "py", version="1.2.3")
+        )
+
+        assert report["ok"] and report["failed"] == []
+        assert report["version"] == "1.2.3" and report["language"] == "py"
+        assert [c["name"] for c in report["checks"]] == [
+            "nsenter",
+            "spawn",
+            "limits",
+            "workspace_io",
+            "language_runtime",
+        ]
+        runtimes = {r["name"]: r for r in report["runtimes"]}
+        assert runtimes["python"]["required"] and not runtimes["gcc"]["required"]
+        assert runtimes["gcc"]["detail"] == "gcc (Debian 12.2.0) 12.2.0"
+
+    def test_missing_language_runtime_fails(self, tmp_path, cgroup):
+        run = stub_runner(tmp_path, {})
+
+        report = asyncio.run(selftest.run_selftest(run, stub_binaries(set()), str(tmp_path), "rs"))
+
+        assert not report["ok"]
+        assert report["failed"] == ["language_runtime"]
+
+    def test_broken_optional_runtime_is_informational(self, tmp_path, cgroup):
+        run = stub_runner(tmp_path, {"node": (0, "selftest-ok\n", ""), "php": (139, "", "Segmentation fault")})
+
+        report = asyncio.run(selftest.run_selftest(run, stub_binaries({"node", "php"}), str(tmp_path), "js"))
+
+        assert report["ok"]
+        php = next(r for r in report["runtimes"] if r["name"] == "php")
+        assert not php["ok"] and php["detail"] == "exit 139: Segmentation fault"
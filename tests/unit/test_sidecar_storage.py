@@ -0,0 +1,73 @@
+"""Tests for the sidecar's storage drivers of the working directory."""
+
+import pytest
+
+from executor import filewrite, storage
+
+
+class TestLoad:
+    def test_drivers_by_name(self):
+        assert storage.load("").name == "local"
+        assert storage.load("TMPFS").describe() == {
+            "driver": "tmpfs",
+            "atomic_rename": True,
+            "in_place_writes": True,
+            "memory_backed": True,
+        }
+        assert storage.load("object").atomic_rename is False
+
+    def test_unknown_driver(self):
+        with pytest.raises(ValueError, match="Unknown WORKSPACE_STORAGE"):
+            storage.load("nfs")
+
+
+class TestLocalDisk:
+    def test_writes_through_filewrite(self, tmp_path):
+        driver = storage.load("local")
+        path = tmp_path / "dir" / "a.txt"
+
+        driver.write_file(path, b"hello")
+        assert driver.write_at(path, b" world") == 11
+        assert path.read_bytes() == b"hello world"
+        assert [p.name for p in path.parent.iterdir()] == ["a.txt"]
+
+
+class TestObjectStorage:
+    """Files on object storage are written whole, without renames."""
+
+    def test_write_file_without_rename(self, tmp_path, monkeypatch):
+        def no_rename(*args):
+            raise OSError("rename not supported")
+
+        monkeypatch.setattr(storage.os, "replace", no_rename)
+        path = tmp_path / "dir" / "a.txt"
+
+        storage.load("object").write_file(path, b"hello")
+
+        assert path.read_bytes() == b"hello"
+
+    def test_append_and_offset_writes_rewrite_the_file(self, tmp_path):
+        driver = storage.load("object")
+        path = tmp_path / "log.txt"
+
+        assert driver.write_at(path, b"abc") == 3
+        assert driver.write_at(path, b"def", expected_size=3) == 6
+        assert driver.write_at(path, b"XY", offset=1) == 6
+        assert driver.write_at(path, b"1234567", offset=4) == 11
+        assert path.read_bytes() == b"aXYd1234567"
+
+    def test_conditions_match_local_disk(self, tmp_path):
+        driver = storage.load("object")
+        path = tmp_path / "log.txt"
+        path.write_bytes(b"abc")
+
+        with pytest.raises(filewrite.FileWriteError) as exc_info:
+            driver.write_at(path, b"x", expected_size=2)
+        assert exc_info.value.status == 409
+        with pytest.raises(filewrite.FileWriteError) as exc_info:
+            driver.write_at(path, b"x", offset=4)
+        assert exc_info.value.status == 409
+        with pytest.raises(filewrite.FileWriteError) as exc_info:
+            driver.write_at(path, b"x", offset=-1)
+        assert exc_info.value.status == 400
+        assert path.read_bytes() == b"abc"
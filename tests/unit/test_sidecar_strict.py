@@ -0,0 +1,76 @@
+"""Tests for the sidecar's strict validation mode."""
+
+import json
+
+import pytest
+
+from executor import strict
+
+
+class TestNormalizePath:
+    @pytest.mark.parametrize(
+        "path, expected",
+        [
+            ("a/./b//c", "a/b/c"),
+            ("/mnt/data/x/../y", "/mnt/data/y"),
+            ("./out.txt", "out.txt"),
+            ("café.txt", "café.txt"),
+        ],
+    )
+    def test_normalizes(self, path, expected):
+        assert strict.normalize_path(path) == expected
+
+    @pytest.mark.parametrize(
+        "path, reason",
+        [
+            ("a/../../etc/passwd", "leaves its base"),
+            ("../x", "leaves its base"),
+            ("a\x00b", "control characters"),
+            ("a\nb", "control characters"),
+            ("a\\b", "backslash"),
+            ("x" * 256, "name longer"),
+            ("a/" * 2100, "longer than 4096"),
+        ],
+    )
+    def test_rejects(self, path, reason):
+        with pytest.raises(strict.PathRejected, match=reason):
+            strict.normalize_path(path)
+
+
+class TestSummarizeDetail:
+    def test_validation_errors_without_values(self):
+        detail = [{"loc": ["body", "timeout"], "type": "int_type", "msg": "...", "input": "secret"}]
+
+        assert strict.summarize_detail(detail) == [{"loc": ["body", "timeout"], "type": "int_type"}]
+
+    def test_messages_are_truncated(self):
+        assert len(strict.summarize_detail("x" * 1000)) == strict.MAX_LOGGED_DETAIL
+        assert strict.summarize_detail(None) is None
+
+
+class TestAnomalyMonitor:
+    def test_logs_security_events(self, capsys):
+        monitor = strict.AnomalyMonitor()
+
+        monitor.record("validation_failed", "10.0.0.1", "POST", "/execute", [{"loc": ["body"], "type": "missing"}])
+
+        event = json.loads(capsys.readouterr().out.removeprefix("[SECURITY] "))
+        assert event["event"] == "anomalous_request"
+        assert (event["reason"], event["client"], event["path"]) == ("validation_failed", "10.0.0.1", "/execute")
+        assert monitor.report() == {"anomalies": {"validation_failed": 1}, "bursting_clients": []}
+
+    def test_burst_is_logged_once_per_window(self):
+        monitor = strict.AnomalyMonitor(threshold=3, window=60)
+
+        for now in (0, 1, 2, 3):
+            monitor.record("unknown_route", "10.0.0.2", "GET", "/admin", now=now)
+        assert monitor.counts["anomaly_burst"] == 1
+        assert monitor.report()["bursting_clients"] == ["10.0.0.2"]
+
+        monitor.record("unknown_route", "10.0.0.2", "GET", "/admin", now=200)
+        assert monitor.report()["bursting_clients"] == []
+
+
+def test_model_config():
+    assert strict.model_config(True) == {"extra": "forbid", "strict": True}
+    assert strict.model_config(False) == {}
@@ -0,0 +1,112 @@
+"""Tests for the sidecar's code outlines."""
+
+import pytest
+
+from executor import symbols
+from executor.symbols import SymbolsError
+
+PYTHON_SOURCE = b'''\
+import os
+
+
+class Loader:
+    def __init__(self):
+        pass
+
+    async def load(self, path):
+        def inner():
+            return path
+        return inner()
+
+
+if os.name == "nt":
+    def helper():
+        pass
+'''
+
+
+class FakeNode:
+    """Just enough of a tree-sitter node for the outline walker."""
+
+    def __init__(self, type, start=0, end=0, fields=None, children=None, span=(0, 0)):
+        self.type = type
+        self.start_point = (start, 0)
+        self.end_point = (end, 0)
+        self.start_byte, self.end_byte = span
+        self._fields = fields or {}
+        self.children = children or list(self._fields.values())
+
+    @property
+    def child_count(self):
+        return len(self.children)
+
+    def child_by_field_name(self, name):
+        return self._fields.get(name)
+
+
+def summary(found):
+    return [(s.name, s.kind, s.start_line, s.end_line, summary(s.children)) for s in found]
+
+
+class TestLanguages:
+    @pytest.mark.parametrize("path,language", [("a.py", "python"), ("src/app.TSX", "tsx"), ("lib.rs", "rust")])
+    def test_by_extension(self, path, language):
+        assert symbols.language_for(path) == language
+
+    def test_unsupported(self):
+        with pytest.raises(SymbolsError) as exc_info:
+            symbols.language_for("notes.txt")
+        assert exc_info.value.status == 415
+
+
+class TestPythonAst:
+    def test_nested_definitions(self):
+        assert summary(symbols.outline_python_ast(PYTHON_SOURCE)) == [
+            ("Loader", "class", 4, 11, [
+                ("__init__", "method", 5, 6, []),
+                ("load", "method", 8, 11, [("inner", "function", 9, 10, [])]),
+            ]),
+            ("helper", "function", 15, 16, []),
+        ]
+
+    def test_syntax_error(self):
+        with pytest.raises(SymbolsError, match="line 1") as exc_info:
+            symbols.outline_python_ast(b"def broken(:\n")
+        assert exc_info.value.status == 422
+
+
+class TestTreeWalker:
+    def test_c_function_names_come_from_nested_declarators(self):
+        source = b"int *make(void) {}"
+        name = FakeNode("identifier", span=(5, 9))
+        declarator = FakeNode("pointer_declarator", fields={
+            "declarator": FakeNode("function_declarator", fields={"declarator": name}),
+        })
+        root = FakeNode("translation_unit", children=[
+            FakeNode("function_definition", 0, 2, fields={"declarator": declarator}),
+            # A struct used without a body isn't a definition
+            FakeNode("struct_specifier", 3, 3, fields={"name": FakeNode("type_identifier", span=(0, 3))}),
+        ])
+
+        assert summary(symbols.outline_tree(root, source, symbols.DEFINITIONS["c"])) == [
+            ("make", "function", 1, 3, []),
+        ]
+
+    def test_functions_inside_classes_are_methods(self):
+        source = b"class A: def run"
+        method = FakeNode("function_definition", 1, 2, fields={"name": FakeNode("identifier", span=(13, 16))})
+        body = FakeNode("block", children=[method])
+        cls = FakeNode("class_definition", 0, 2, fields={"name": FakeNode("identifier", span=(6, 7)), "body": body})
+        root = FakeNode("module", children=[cls])
+
+        assert summary(symbols.outline_tree(root, source, symbols.DEFINITIONS["python"])) == [
+            ("A", "class", 1, 3, [("run", "method", 2, 3, [])]),
+        ]
+
+
+class TestOutline:
+    def test_oversized_files_are_refused(self, monkeypatch):
+        monkeypatch.setattr(symbols, "MAX_SOURCE_SIZE", 4)
+        with pytest.raises(SymbolsError) as exc_info:
+            symbols.outline("a.py", b"x = 12345")
+        assert exc_info.value.status == 413
@@ -0,0 +1,110 @@
+"""Tests for sidecar rsync-style incremental sync."""
+
+import base64
+import os
+
+import pytest
+
+from executor import sync
+
+
+def roundtrip(old: bytes, new: bytes, block_size: int) -> list[dict]:
+    ops = sync.compute_delta(new, sync.signature(old, block_size), block_size)
+    assert sync.apply_delta(old, ops, block_size) == new
+    return ops
+
+
+def literal_bytes(ops: list[dict]) -> int:
+    return sum(len(base64.b64decode(op["data"])) for op in ops if "data" in op)
+
+
+class TestWeakChecksum:
+    """Tests for the rolling checksum."""
+
+    def test_rolling_matches_direct(self):
+        """Rolling the window gives the same checksum as computing it from scratch."""
+        data = os.urandom(300)
+        block_size = 64
+        ops = sync.compute_delta(data, sync.signature(data[100:164], block_size), block_size)
+        assert {"copy": 0, "count": 1} in ops
+
+
+class TestDelta:
+    """Tests for computing and applying deltas."""
+
+    def test_unchanged_file_is_all_copies(self):
+        data = os.urandom(10000)
+        assert roundtrip(data, data, 1024) == [{"copy": 0, "count": 10}]
+
+    def test_insertion_sends_only_the_change(self):
+        old = os.urandom(100_000)
+        new = old[:50_000] + b"inserted" + old[50_000:]
+        ops = roundtrip(old, new, 4096)
+        assert literal_bytes(ops) <= 4096 + len(b"inserted")
+
+    @pytest.mark.parametrize(
+        "old,new",
+        [
+            (b"", b"new file"),
+            (b"old contents", b""),
+            (b"short", b"shorter or longer"),
+            (b"x" * 5000, b"x" * 5001),
+        ],
+    )
+    def test_edge_cases(self, old, new):
+        roundtrip(old, new, 512)
+
+    def test_short_last_block_matches(self):
+        old = os.urandom(1000)
+        new = os.urandom(20) + old
+        ops = roundtrip(old, new, 512)
+        assert literal_bytes(ops) == 20
+
+    def test_copy_beyond_file_rejected(self):
+        with pytest.raises(sync.DeltaError, match="beyond"):
+            sync.apply_delta(b"abc", [{"copy": 0, "count": 2}], 512)
+
+    def test_repeated_copies_past_limit_rejected(self):
+        """A few bytes of delta repeating a block can't build a file over the limit."""
+        base = os.urandom(1024 * 1024)
+        ops = [{"copy": 0, "count": 2}] * 1000
+
+        with pytest.raises(sync.DeltaTooLarge, match="limit"):
+            sync.apply_delta(base, ops, 512 * 1024, max_size=10 * 1024 * 1024)
+        assert len(sync.apply_delta(base, ops[:10], 512 * 1024, max_size=10 * 1024 * 1024)) == 10 * 1024 * 1024
+
+    def test_data_past_limit_rejected(self):
+        data = base64.b64encode(b"x" * 100).decode()
+        with pytest.raises(sync.DeltaTooLarge):
+            sync.apply_delta(b"", [{"data": data}], 512, max_size=99)
+
+    @pytest.mark.parametrize("op", [{"copy": -1}, {"copy": "0"}, {"data": "not base64!"}, {"move": 1}])
+    def test_malformed_ops_rejected(self, op):
+        with pytest.raises(sync.DeltaError):
+            sync.apply_delta(b"abc", [op], 512)
+
+
+class TestManifest:
+    """Tests for the workspace manifest."""
+
+    def test_lists_files_recursively(self, tmp_path):
+        (tmp_path / "sub").mkdir()
+        (tmp_path / "a.txt").write_bytes(b"hello")
+        (tmp_path / "sub" / "b.bin").write_bytes(b"\x00" * 10)
+
+        entries = sync.manifest(tmp_path, checksums=True)
+
+        assert [(e["path"], e["size"]) for e in entries] == [("a.txt", 5), ("sub/b.bin", 10)]
+        assert entries[0]["sha256"] == sync.file_sha256(b"hello")
+        assert "mtime" in entries[1]
+
+    def test_skips_symlinks(self, tmp_path):
+        outside = tmp_path / "outside"
+        outside.mkdir()
+        (outside / "secret").write_text("x")
+        root = tmp_path / "root"
+        root.mkdir()
+        (root / "link").symlink_to(outside)
+        (root / "file-link").symlink_to(outside / "secret")
+
+        assert sync.manifest(root) == []
@@ -0,0 +1,65 @@
+"""Tests for sidecar session context templating."""
+
+import pytest
+from executor import templating
+
+
+class TestExpand:
+    """Tests for ${NAME} substitution."""
+
+    def test_known_variables_substituted(self):
+        """Known names are replaced with their values."""
+        variables = {"SESSION_ID": "abc123", "WORKSPACE": "/mnt/data"}
+        assert templating.expand("${WORKSPACE}/runs/${SESSION_ID}", variables) == "/mnt/data/runs/abc123"
+
+    def test_unknown_variables_untouched(self):
+        """Unknown names are left as written so shell and JS syntax survive."""
+        text = "console.log(`${count} items`); echo ${HOME}"
+        assert templating.expand(text, {"SESSION_ID": "abc"}) == text
+
+    def test_escaped_reference_is_literal(self):
+        """$${NAME} produces a literal ${NAME}."""
+        assert templating.expand("$${SESSION_ID}", {"SESSION_ID": "abc"}) == "${SESSION_ID}"
+
+    def test_bare_dollar_names_not_expanded(self):
+        """Only the braced form is a template."""
+        assert templating.expand("$SESSION_ID", {"SESSION_ID": "abc"}) == "$SESSION_ID"
+
+
+class TestResolveEnv:
+    """Tests for request env resolution."""
+
+    def test_values_expanded_against_context(self):
+        """Env values may reference context variables."""
+        env = templating.resolve_env({"OUT_DIR": "${WORKSPACE}/out"}, {"WORKSPACE": "/mnt/data"})
+        assert env == {"OUT_DIR": "/mnt/data/out"}
+
+    def test_env_cannot_reference_env(self):
+        """Env entries don't expand each other (no recursion)."""
+        env = templating.resolve_env({"A": "x", "B": "${A}"}, {})
+        assert env["B"] == "${A}"
+
+    @pytest.mark.parametrize("name", ["BAD-NAME", "1X", "", "A B"])
+    def test_invalid_names_rejected(self, name):
+        """Names must be shell identifiers."""
+        with pytest.raises(ValueError, match="Invalid environment variable names"):
+            templating.resolve_env({name: "1"}, {})
+
+
+class TestBuildVariables:
+    """Tests for code template variables."""
+
+    def test_context_overrides_env(self):
+        """Platform context can't be spoofed through request env."""
+        variables = templating.build_variables({"SESSION_ID": "real"}, {"SESSION_ID": "fake", "TOKEN": "t"})
+        assert variables == {"SESSION_ID": "real", "TOKEN": "t"}
+
+
+class TestRedactCommand:
+    """Tests for log redaction of env arguments."""
+
+    def test_only_named_values_masked(self):
+        """Request env values are masked, other args are kept."""
+        cmd = ["/usr/bin/env", "-i", "PATH=/usr/bin", "API_KEY=sk-secret", "python", "code.py"]
+        redacted = templating.redact_command(cmd, {"API_KEY"})
+        assert redacted == ["/usr/bin/env", "-i", "PATH=/usr/bin", "API_KEY=***", "python", "code.py"]
@@ -0,0 +1,65 @@
+"""Tests for the sidecar's execution latency breakdown."""
+
+import asyncio
+import sys
+
+import pytest
+
+from executor import timing
+
+
+class TestExecutionTimer:
+    def test_unreached_phases_are_none(self):
+        timer = timing.ExecutionTimer()
+
+        timings = timer.timings()
+
+        assert timings["spawn_ms"] is None
+        assert timings["first_output_ms"] is None
+        assert timings["total_ms"] >= 0
+
+    def test_phases_are_relative(self):
+        timer = timing.ExecutionTimer()
+        timer.started -= 0.5
+        timer.mark_spawned()
+        timer.spawned -= 0.2
+        timer.mark_output()
+        first_output = timer.first_output
+        timer.mark_output()
+
+        timings = timer.timings()
+
+        assert timer.first_output == first_output
+        assert 300 <= timings["spawn_ms"] < 400
+        assert 200 <= timings["first_output_ms"] < 300
+        assert timings["total_ms"] >= 500
+
+
+class TestCommunicate:
+    @pytest.mark.asyncio
+    async def test_marks_first_output(self):
+        proc = await asyncio.create_subprocess_exec(
+            sys.executable,
+            "-c",
+            "import sys, time; time.sleep(0.2); print('out'); sys.stderr.write('err')",
+            stdout=asyncio.subprocess.PIPE,
+            stderr=asyncio.subprocess.PIPE,
+        )
+        timer = timing.ExecutionTimer()
+        timer.mark_spawned()
+
+        stdout, stderr = await timing.communicate(proc, timer)
+
+        assert (stdout, stderr) == (b"out\n", b"err")
+        assert proc.returncode == 0
+        assert timer.timings()["first_output_ms"] >= 200
+
+    @pytest.mark.asyncio
+    async def test_no_output(self):
+        proc = await asyncio.create_subprocess_exec(
+            sys.executable, "-c", "pass", stdout=asyncio.subprocess.PIPE, stderr=asyncio.subprocess.PIPE
+        )
+        timer = timing.ExecutionTimer()
+
+        assert await timing.communicate(proc, timer) == (b"", b"")
+        assert timer.first_output is None
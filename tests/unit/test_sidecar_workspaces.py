@@ -0,0 +1,145 @@
+"""Tests for the sidecar's named workspaces."""
+
+import pytest
+
+from executor import workspaces
+from executor.workspaces import WorkspaceError, WorkspacePolicy, WorkspaceRegistry
+
+
+class TestWorkspaceIds:
+    @pytest.mark.parametrize("workspace_id", ["a", "session-1", "A_b-2"])
+    def test_valid_ids(self, workspace_id):
+        WorkspaceRegistry.validate_id(workspace_id)
+
+    @pytest.mark.parametrize("workspace_id", ["", "..", ".workspaces", "a/b", "-a", "x" * 65])
+    def test_invalid_ids(self, workspace_id):
+        with pytest.raises(WorkspaceError):
+            WorkspaceRegistry.validate_id(workspace_id)
+
+
+class TestRegistry:
+    def test_put_creates_then_updates(self, tmp_path):
+        registry = WorkspaceRegistry(str(tmp_path))
+
+        workspace, created = registry.put("one", WorkspacePolicy(quota_bytes=10))
+        assert created and (tmp_path / "one").is_dir()
+
+        workspace, created = registry.put("one", WorkspacePolicy(read_only=True))
+        assert not created
+        assert workspace.policy == WorkspacePolicy(read_only=True)
+
+    def test_policies_survive_a_restart(self, tmp_path):
+        WorkspaceRegistry(str(tmp_path)).put("one", WorkspacePolicy(quota_bytes=10, retention_seconds=600))
+
+        registry = WorkspaceRegistry(str(tmp_path))
+        registry.load()
+
+        assert registry.get("one").policy == WorkspacePolicy(quota_bytes=10, retention_seconds=600)
+
+    def test_policies_are_written_through_the_storage_driver(self, tmp_path):
+        written = []
+
+        def write_file(path, data):
+            written.append(path.name)
+            path.write_bytes(data)
+
+        WorkspaceRegistry(str(tmp_path), write_file=write_file).put("one", WorkspacePolicy(quota_bytes=10))
+
+        registry = WorkspaceRegistry(str(tmp_path))
+        registry.load()
+        assert written == ["one.json"]
+        assert registry.get("one").policy == WorkspacePolicy(quota_bytes=10)
+
+    def test_unknown_workspace_is_404(self, tmp_path):
+        with pytest.raises(WorkspaceError) as exc_info:
+            WorkspaceRegistry(str(tmp_path)).get("missing")
+        assert exc_info.value.status == 404
+        assert exc_info.value.code == "WORKSPACE_NOT_FOUND"
+
+    def test_delete_removes_files_and_policy(self, tmp_path):
+        registry = WorkspaceRegistry(str(tmp_path))
+        registry.put("one", WorkspacePolicy())
+        (tmp_path / "one" / "data.csv").write_text("a,b\n")
+
+        registry.delete("one")
+
+        assert not (tmp_path / "one").exists()
+        assert not (tmp_path / workspaces.METADATA_DIR / "one.json").exists()
+        assert registry.all() == []
+
+    def test_owner_of_paths_in_the_working_directory(self, tmp_path):
+        registry = WorkspaceRegistry(str(tmp_path))
+        registry.put("one", WorkspacePolicy(read_only=True))
+        (tmp_path / "link").symlink_to(tmp_path / "one")
+
+        assert registry.owner(tmp_path / "one") == "one"
+        assert registry.owner(tmp_path / "one" / "new.txt") == "one"
+        assert registry.owner(tmp_path / "link" / "data.csv") == "one"
+        assert registry.owner(tmp_path / workspaces.METADATA_DIR / "one.json") == workspaces.METADATA_DIR
+        assert registry.owner(tmp_path / "two" / "a.txt") is None
+        assert registry.owner(tmp_path / "a.txt") is None
+        assert registry.owner(tmp_path) is None
+
+
+class TestPolicies:
+    def test_read_only_refuses_writes(self, tmp_path):
+        registry = WorkspaceRegistry(str(tmp_path))
+        registry.put("ro", WorkspacePolicy(read_only=True))
+
+        with pytest.raises(WorkspaceError) as exc_info:
+            registry.check_writable("ro", 1)
+        assert exc_info.value.status == 403
+
+    def test_quota_refuses_uploads_past_it_but_allows_deletes(self, tmp_path):
+        registry = WorkspaceRegistry(str(tmp_path))
+        registry.put("q", WorkspacePolicy(quota_bytes=10))
+        (tmp_path / "q" / "a.txt").write_bytes(b"x" * 8)
+
+        registry.check_writable("q", 2)
+        with pytest.raises(WorkspaceError, match="quota exceeded") as exc_info:
+            registry.check_writable("q", 3)
+        assert exc_info.value.status == 413
+
+        (tmp_path / "q" / "b.txt").write_bytes(b"x" * 8)
+        registry.check_writable("q")
+
+    def test_quota_error_after_execution(self, tmp_path):
+        registry = WorkspaceRegistry(str(tmp_path))
+        registry.put("q", WorkspacePolicy(quota_bytes=10))
+        assert registry.quota_error("q") is None
+
+        (tmp_path / "q" / "out.bin").write_bytes(b"x" * 11)
+
+        error = registry.quota_error("q")
+        assert error["code"] == workspaces.QUOTA_EXCEEDED
+        assert error["usage_bytes"] == 11 and error["quota_bytes"] == 10
+
+    def test_usage_ignores_symlinks(self, tmp_path):
+        registry = WorkspaceRegistry(str(tmp_path))
+        registry.put("one", WorkspacePolicy())
+        (tmp_path / "big").write_bytes(b"x" * 100)
+        (tmp_path / "one" / "link").symlink_to(tmp_path / "big")
+        (tmp_path / "one" / "a").write_bytes(b"x" * 3)
+
+        assert registry.usage("one") == 3
+
+    def test_scratch_copy_leaves_workspace_untouched(self, tmp_path):
+        registry = WorkspaceRegistry(str(tmp_path))
+        registry.put("ro", WorkspacePolicy(read_only=True))
+        (tmp_path / "ro" / "input.txt").write_text("data")
+
+        scratch = registry.scratch_copy("ro")
+        (scratch / "input.txt").write_text("changed")
+
+        assert (tmp_path / "ro" / "input.txt").read_text() == "data"
+        assert scratch.parent == tmp_path / workspaces.METADATA_DIR
+
+    def test_sweep_deletes_idle_workspaces(self, tmp_path):
+        registry = WorkspaceRegistry(str(tmp_path))
+        idle, _ = registry.put("idle", WorkspacePolicy(retention_seconds=60))
+        busy, _ = registry.put("busy", WorkspacePolicy(retention_seconds=60))
+        registry.put("kept", WorkspacePolicy())
+        busy.last_used = idle.last_used + 30
+
+        assert registry.sweep(now=idle.last_used + 61) == ["idle"]
+        assert [w.id for w in registry.all()] == ["busy", "kept"]
@@ -230,6 +230,20 @@ class TestQueryMethods:
 
         assert result == []
 
+    @pytest.mark.asyncio
+    async def test_get_runtime_stats_no_db(self):
+        """Test get_runtime_stats when no database."""
+        service = SQLiteMetricsService(db_path="/tmp/test.db")
+        service._db = None
+        service._running = True
+
+        start = datetime.now(UTC) - timedelta(days=1)
+        end = datetime.now(UTC)
+
+        result = await service.get_runtime_stats(start=start, end=end)
+
+        assert result == []
+
 
 class TestRunAggregation:
     """Tests for run_aggregation method."""
@@ -0,0 +1,234 @@
+"""Unit tests for execution templates."""
+
+import json
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+from fastapi import HTTPException, Request
+
+from src.api.templates import get_template, list_templates, run_template
+from src.models import ExecResponse
+from src.models.template import ExecutionTemplate, TemplateRunRequest
+from src.services import templates as templates_module
+from src.services.templates import (
+    TemplateError,
+    coerce_argument,
+    literal,
+    load_templates,
+    render_template,
+)
+
+DEFINITIONS = {
+    "convert_csv": {
+        "description": "Convert a CSV file",
+        "lang": "py",
+        "code": "import pandas as pd\ndf = pd.read_csv({{file}})\nprint(df.to_{{format}}())",
+        "params": {
+            "file": {"type": "path"},
+            "format": {"choices": ["json", "html"]},
+        },
+    },
+    "head": {
+        "lang": "js",
+        "code": "console.log({{text}}.slice(0, {{count}}), {{verbose}})",
+        "params": {
+            "text": {},
+            "count": {"type": "integer", "minimum": 1, "maximum": 100, "default": 10},
+            "verbose": {"type": "boolean", "required": False},
+        },
+    },
+}
+
+
+@pytest.fixture
+def templates():
+    return load_templates(DEFINITIONS)
+
+
+class TestLiteral:
+    def test_python_string_is_quoted(self):
+        assert literal("a'); import os; ('", "py") == repr("a'); import os; ('")
+
+    def test_json_string_for_c_family(self):
+        assert literal('say "hi" \\', "js") == '"say \\"hi\\" \\\\"'
+
+    def test_php_single_quotes(self):
+        assert literal("$HOME'", "php") == "'$HOME\\''"
+
+    def test_fortran_doubles_quotes(self):
+        assert literal("it's", "f90") == "'it''s'"
+
+    def test_booleans_and_numbers(self):
+        assert literal(True, "py") == "True"
+        assert literal(False, "r") == "FALSE"
+        assert literal(True, "js") == "true"
+        assert literal(3, "go") == "3"
+        assert literal(2.5, "py") == "2.5"
+
+
+class TestLoadTemplates:
+    def test_loads_valid(self, templates):
+        assert set(templates) == {"convert_csv", "head"}
+        assert templates["convert_csv"].params["file"].type == "path"
+
+    def test_skips_invalid(self):
+        loaded = load_templates(
+            {
+                "bad-name": {"lang": "py", "code": "pass"},
+                "bad_lang": {"lang": "cobol", "code": "pass"},
+                "unknown_placeholder": {"lang": "py", "code": "print({{x}})"},
+                "bad_default": {"lang": "py", "code": "{{n}}", "params": {"n": {"type": "integer", "default": "x"}}},
+                "not_an_object": "print(1)",
+                "ok": {"lang": "py", "code": "print(1)"},
+            }
+        )
+
+        assert set(loaded) == {"ok"}
+
+    def test_reads_file(self, tmp_path):
+        path = tmp_path / "templates.json"
+        path.write_text(json.dumps(DEFINITIONS))
+        templates_module.get_templates.cache_clear()
+        try:
+            with patch("src.services.templates.settings") as mock_settings:
+                mock_settings.execution_templates_path = str(path)
+                assert set(templates_module.get_templates()) == {"convert_csv", "head"}
+        finally:
+            templates_module.get_templates.cache_clear()
+
+    def test_unreadable_file(self, tmp_path):
+        templates_module.get_templates.cache_clear()
+        try:
+            with patch("src.services.templates.settings") as mock_settings:
+                mock_settings.execution_templates_path = str(tmp_path / "missing.json")
+                assert templates_module.get_templates() == {}
+        finally:
+            templates_module.get_templates.cache_clear()
+
+
+class TestCoerceArgument:
+    def test_rejects_wrong_type(self, templates):
+        param = templates["head"].params["count"]
+        with pytest.raises(TemplateError, match="integer"):
+            coerce_argument("count", param, "5")
+        with pytest.raises(TemplateError, match="integer"):
+            coerce_argument("count", param, True)
+
+    def test_range(self, templates):
+        param = templates["head"].params["count"]
+        with pytest.raises(TemplateError, match="at most 100"):
+            coerce_argument("count", param, 101)
+
+    def test_control_characters(self, templates):
+        with pytest.raises(TemplateError, match="control characters"):
+            coerce_argument("text", templates["head"].params["text"], "a\nimport os")
+
+    def test_path_escape(self, templates):
+        param = templates["convert_csv"].params["file"]
+        with pytest.raises(TemplateError, match="inside the workspace"):
+            coerce_argument("file", param, "../etc/passwd")
+
+    def test_path_normalized(self, templates):
+        assert coerce_argument("file", templates["convert_csv"].params["file"], "./data//in.csv") == "data/in.csv"
+
+    def test_choices(self, templates):
+        with pytest.raises(TemplateError, match="one of: json, html"):
+            coerce_argument("format", templates["convert_csv"].params["format"], "parquet")
+
+
+class TestRenderTemplate:
+    def test_expands_literals_and_env(self, templates):
+        code, env = render_template(templates["convert_csv"], {"file": "in.csv", "format": "json"})
+
+        assert "pd.read_csv('in.csv')" in code
+        assert "df.to_'json'()" in code
+        assert env == {"PARAM_FILE": "in.csv", "PARAM_FORMAT": "json"}
+
+    def test_defaults_and_optional(self, templates):
+        with pytest.raises(TemplateError, match="verbose"):
+            render_template(templates["head"], {"text": "abc"})
+
+        code, env = render_template(templates["head"], {"text": "abc", "verbose": True})
+
+        assert code == 'console.log("abc".slice(0, 10), true)'
+        assert env["PARAM_COUNT"] == "10"
+        assert env["PARAM_VERBOSE"] == "true"
+
+    def test_missing_required(self, templates):
+        with pytest.raises(TemplateError, match="Missing required parameter: text"):
+            render_template(templates["head"], {})
+
+    def test_unknown_argument(self, templates):
+        with pytest.raises(TemplateError, match="Unknown parameters: extra"):
+            render_template(templates["head"], {"text": "a", "extra": 1})
+
+
+class TestTemplateEndpoints:
+    @pytest.fixture
+    def patched_templates(self, templates):
+        with patch("src.api.templates.get_templates", return_value=templates):
+            yield templates
+
+    @pytest.mark.asyncio
+    async def test_list(self, patched_templates):
+        response = await list_templates()
+
+        assert [t.name for t in response.templates] == ["convert_csv", "head"]
+
+    @pytest.mark.asyncio
+    async def test_get_not_found(self, patched_templates):
+        with pytest.raises(HTTPException) as exc_info:
+            await get_template("missing")
+
+        assert exc_info.value.status_code == 404
+        assert exc_info.value.detail["error"] == "template_not_found"
+
+    @pytest.mark.asyncio
+    async def test_get(self, patched_templates):
+        assert isinstance(await get_template("head"), ExecutionTemplate)
+
+    @pytest.mark.asyncio
+    async def test_run(self, patched_templates):
+        http_request = MagicMock(spec=Request)
+        http_request.state = MagicMock()
+        http_request.state.api_key_hash = "abc123hash"
+        http_request.state.is_env_key = False
+        expected = ExecResponse(session_id="session-123", stdout="[]", stderr="")
+
+        with patch("src.api.templates.ExecutionOrchestrator") as MockOrchestrator:
+            MockOrchestrator.return_value.execute = AsyncMock(return_value=expected)
+
+            response = await run_template(
+                name="convert_csv",
+                body=TemplateRunRequest(params={"file": "in.csv", "format": "html"}, session_id="session-123"),
+                http_request=http_request,
+                session_service=MagicMock(),
+                file_service=MagicMock(),
+                execution_service=MagicMock(),
+                state_service=MagicMock(),
+                state_archival_service=MagicMock(),
+            )
+
+        assert response is expected
+        request = MockOrchestrator.return_value.execute.call_args.args[0]
+        assert request.lang == "py"
+        assert request.session_id == "session-123"
+        assert "pd.read_csv('in.csv')" in request.code
+        assert request.env == {"PARAM_FILE": "in.csv", "PARAM_FORMAT": "html"}
+
+    @pytest.mark.asyncio
+    async def test_run_invalid_params(self, patched_templates):
+        with pytest.raises(HTTPException) as exc_info:
+            await run_template(
+                name="convert_csv",
+                body=TemplateRunRequest(params={"file": "../x", "format": "json"}),
+                http_request=MagicMock(spec=Request),
+                session_service=MagicMock(),
+                file_service=MagicMock(),
+                execution_service=MagicMock(),
+                state_service=MagicMock(),
+                state_archival_service=MagicMock(),
+            )
+
+        assert exc_info.value.status_code == 400
+        assert exc_info.value.detail["error"] == "invalid_template_params"
@@ -0,0 +1,181 @@
+"""Unit tests for image thumbnails."""
+
+import asyncio
+import struct
+import threading
+import time
+import zlib
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+from fastapi import HTTPException
+
+from src.api.files import get_file_thumbnail
+from src.services.thumbnail import (
+    MAX_CONCURRENT_THUMBNAILS,
+    MAX_SOURCE_PIXELS,
+    PNG_SIGNATURE,
+    ThumbnailError,
+    _decode_png,
+    encode_png,
+    generate_thumbnail,
+    make_thumbnail,
+)
+
+
+def _chunk(kind: bytes, data: bytes) -> bytes:
+    return struct.pack(">I", len(data)) + kind + data + struct.pack(">I", zlib.crc32(kind + data))
+
+
+def _filtered_png(width: int, height: int, pixels, filter_type: int) -> bytes:
+    """RGB PNG whose rows all use one filter type."""
+    bpp = 3
+    raw = bytearray()
+    prev = bytes(width * bpp)
+    for y in range(height):
+        line = bytes(c for x in range(width) for c in pixels(x, y))
+        out = bytearray()
+        for i, value in enumerate(line):
+            a = line[i - bpp] if i >= bpp else 0
+            b = prev[i]
+            c = prev[i - bpp] if i >= bpp else 0
+            if filter_type == 1:
+                predictor = a
+            elif filter_type == 2:
+                predictor = b
+            elif filter_type == 3:
+                predictor = (a + b) >> 1
+            elif filter_type == 4:
+                p = a + b - c
+                pa, pb, pc = abs(p - a), abs(p - b), abs(p - c)
+                predictor = a if pa <= pb and pa <= pc else b if pb <= pc else c
+            else:
+                predictor = 0
+            out.append((value - predictor) & 0xFF)
+        raw += bytes([filter_type]) + out
+        prev = line
+    header = struct.pack(">IIBBBBB", width, height, 8, 2, 0, 0, 0)
+    return PNG_SIGNATURE + _chunk(b"IHDR", header) + _chunk(b"IDAT", zlib.compress(bytes(raw))) + _chunk(b"IEND", b"")
+
+
+def _gradient(x: int, y: int) -> tuple[int, int, int]:
+    return (x * 7) % 256, (y * 13) % 256, (x * y) % 256
+
+
+class TestDecode:
+    @pytest.mark.parametrize("filter_type", [0, 1, 2, 3, 4])
+    def test_unfilters(self, filter_type):
+        png = _decode_png(_filtered_png(20, 6, _gradient, filter_type))
+
+        expected = [bytes(c for x in range(20) for c in _gradient(x, y)) for y in range(6)]
+        assert png.rows == expected
+
+    def test_palette_with_transparency(self):
+        header = struct.pack(">IIBBBBB", 4, 1, 2, 3, 0, 0, 0)
+        palette = bytes([255, 0, 0, 0, 0, 255])
+        raw = b"\x00" + bytes([0b00010000])
+        png = _decode_png(
+            PNG_SIGNATURE
+            + _chunk(b"IHDR", header)
+            + _chunk(b"PLTE", palette)
+            + _chunk(b"tRNS", b"\x00")
+            + _chunk(b"IDAT", zlib.compress(raw))
+            + _chunk(b"IEND", b"")
+        )
+
+        assert png.palette == [(255, 0, 0, 0), (0, 0, 255, 255)]
+        assert png.has_alpha
+
+    def test_rejects_other_formats(self):
+        with pytest.raises(ThumbnailError) as exc_info:
+            make_thumbnail(b"\xff\xd8\xff\xe0 jpeg")
+
+        assert exc_info.value.code == "unsupported_thumbnail_format"
+
+    def test_rejects_truncated(self):
+        content = _filtered_png(20, 6, _gradient, 0)
+
+        with pytest.raises(ThumbnailError):
+            make_thumbnail(content[:40], 8)
+
+    def test_rejects_images_past_the_pixel_limit(self):
+        header = struct.pack(">IIBBBBB", 2001, MAX_SOURCE_PIXELS // 2000, 8, 2, 0, 0, 0)
+        content = PNG_SIGNATURE + _chunk(b"IHDR", header) + _chunk(b"IDAT", zlib.compress(b"")) + _chunk(b"IEND", b"")
+
+        with pytest.raises(ThumbnailError, match="larger than"):
+            make_thumbnail(content, 16)
+
+
+class TestMakeThumbnail:
+    def test_downscales_keeping_aspect_ratio(self):
+        content = _filtered_png(100, 50, lambda x, y: (200, 100, 50) if x < 50 else (0, 0, 0), 1)
+
+        png = _decode_png(make_thumbnail(content, 20))
+
+        assert (png.width, png.height, png.color_type) == (20, 10, 2)
+        assert png.rows[0][:3] == bytes([200, 100, 50])
+        assert png.rows[0][-3:] == bytes([0, 0, 0])
+
+    def test_small_image_unchanged(self):
+        content = encode_png(2, 1, [bytes([1, 2, 3, 4, 5, 6])], alpha=False)
+
+        assert make_thumbnail(content, 256) is content
+
+    @pytest.mark.asyncio
+    async def test_bounds_concurrent_jobs(self):
+        running = peak = 0
+        lock = threading.Lock()
+
+        def slow(content, width):
+            nonlocal running, peak
+            with lock:
+                running += 1
+                peak = max(peak, running)
+            time.sleep(0.05)
+            with lock:
+                running -= 1
+            return b"png"
+
+        with patch("src.services.thumbnail.make_thumbnail", side_effect=slow):
+            results = await asyncio.gather(*(generate_thumbnail(b"", 16) for _ in range(6)))
+
+        assert results == [b"png"] * 6
+        assert peak == MAX_CONCURRENT_THUMBNAILS
+
+
+class TestThumbnailEndpoint:
+    @pytest.fixture
+    def file_service(self):
+        service = MagicMock()
+        service.get_file_info = AsyncMock(return_value=MagicMock(filename="plot.png"))
+        service.get_file_content = AsyncMock(return_value=_filtered_png(64, 32, _gradient, 4))
+        service.get_thumbnail = AsyncMock(return_value=None)
+        service.store_thumbnail = AsyncMock()
+        return service
+
+    @pytest.mark.asyncio
+    async def test_generates_and_caches(self, file_service):
+        response = await get_file_thumbnail("s1", "f1", w=16, file_service=file_service)
+
+        assert response.media_type == "image/png"
+        assert _decode_png(response.body).width == 16
+        file_service.store_thumbnail.assert_awaited_once_with("s1", "f1", 16, response.body)
+
+    @pytest.mark.asyncio
+    async def test_serves_cached(self, file_service):
+        file_service.get_thumbnail.return_value = b"cached"
+
+        response = await get_file_thumbnail("s1", "f1", w=16, file_service=file_service)
+
+        assert response.body == b"cached"
+        file_service.get_file_content.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_not_an_image(self, file_service):
+        file_service.get_file_content.return_value = b"a,b\n1,2\n"
+
+        with pytest.raises(HTTPException) as exc_info:
+            await get_file_thumbnail("s1", "f1", w=16, file_service=file_service)
+
+        assert exc_info.value.status_code == 415
+        file_service.store_thumbnail.assert_not_called()
@@ -0,0 +1,133 @@
+"""Unit tests for timeout suggestions."""
+
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from src.services.timeout_advisor import TimeoutAdvisor, code_signature, percentile, suggest
+
+
+@pytest.fixture
+def mock_pipeline():
+    """Create a mock transactional pipeline."""
+    pipe = MagicMock()
+    pipe.execute = AsyncMock(return_value=[])
+    pipe.reset = AsyncMock()
+    return pipe
+
+
+@pytest.fixture
+def mock_redis(mock_pipeline):
+    """Create a mock Redis client."""
+    client = MagicMock()
+    client.pipeline = AsyncMock(return_value=mock_pipeline)
+    client.lrange = AsyncMock(return_value=[])
+    return client
+
+
+@pytest.fixture
+def advisor(mock_redis):
+    """Create a timeout advisor with mocked Redis."""
+    return TimeoutAdvisor(redis_client=mock_redis)
+
+
+@pytest.fixture
+def mock_settings():
+    with patch("src.services.timeout_advisor.settings") as mock:
+        mock.timeout_history_size = 100
+        mock.timeout_history_ttl_hours = 24
+        mock.timeout_suggestion_min_samples = 3
+        mock.timeout_suggestion_percentile = 95.0
+        mock.timeout_suggestion_multiplier = 1.5
+        mock.max_execution_time = 300
+        mock.get_session_ttl_minutes.return_value = 60
+        yield mock
+
+
+class TestCodeSignature:
+    """Tests for code signatures."""
+
+    def test_ignores_trailing_whitespace_and_blank_lines(self):
+        assert code_signature("py", "x = 1\n\nprint(x)  \n") == code_signature("py", "x = 1\nprint(x)")
+
+    def test_indentation_and_language_matter(self):
+        assert code_signature("py", "if x:\n    y()") != code_signature("py", "if x:\ny()")
+        assert code_signature("py", "print(1)") != code_signature("js", "print(1)")
+
+
+class TestSuggest:
+    """Tests for computing a suggestion from durations."""
+
+    def test_percentile_nearest_rank(self):
+        values = list(range(1, 101))
+
+        assert percentile(values, 95) == 95
+        assert percentile(values, 100) == 100
+        assert percentile([7], 95) == 7
+
+    def test_percentile_times_multiplier(self, mock_settings):
+        suggestion = suggest([1000] * 19 + [10000], "code")
+
+        assert suggestion.percentile_ms == 1000
+        assert suggestion.seconds == 2
+        assert suggestion.samples == 20
+
+    def test_capped_at_max_execution_time(self, mock_settings):
+        mock_settings.max_execution_time = 30
+
+        assert suggest([30000, 30000, 30000], "code").seconds == 30
+
+    def test_at_least_one_second(self, mock_settings):
+        assert suggest([5, 5, 5], "code").seconds == 1
+
+
+class TestTimeoutAdvisor:
+    """Tests for recording durations and suggesting timeouts."""
+
+    @pytest.mark.asyncio
+    async def test_record_keeps_code_and_session_history(self, advisor, mock_pipeline, mock_settings):
+        await advisor.record("session-123", "py", "print(1)", 250)
+
+        code_key = f"timeouts:code:{code_signature('py', 'print(1)')}"
+        session_key = "timeouts:session:session-123:py"
+        assert [call.args for call in mock_pipeline.rpush.call_args_list] == [(code_key, 250), (session_key, 250)]
+        mock_pipeline.ltrim.assert_any_call(code_key, -100, -1)
+        mock_pipeline.expire.assert_any_call(code_key, 24 * 3600)
+        mock_pipeline.expire.assert_any_call(session_key, 3600)
+        mock_pipeline.reset.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_record_disabled(self, advisor, mock_redis, mock_settings):
+        mock_settings.timeout_history_size = 0
+
+        await advisor.record("session-123", "py", "print(1)", 250)
+
+        mock_redis.pipeline.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_prefers_code_history(self, advisor, mock_redis, mock_settings):
+        mock_redis.lrange.return_value = [b"2000", b"4000", b"not-a-number", b"3000"]
+
+        suggestion = await advisor.suggest("py", "train()", "session-123")
+
+        assert suggestion.basis == "code"
+        assert suggestion.samples == 3
+        assert suggestion.seconds == 6
+        mock_redis.lrange.assert_awaited_once()
+
+    @pytest.mark.asyncio
+    async def test_falls_back_to_session_then_default(self, advisor, mock_redis, mock_settings):
+        mock_redis.lrange.side_effect = [[b"1000"], [b"1000", b"1000", b"20000"]]
+
+        suggestion = await advisor.suggest("py", "train()", "session-123")
+
+        assert suggestion.basis == "session"
+        assert suggestion.seconds == 30
+
+        mock_redis.lrange.side_effect = None
+        mock_redis.lrange.return_value = [b"1000"]
+        default = await advisor.suggest("py", "train()", "session-123")
+
+        assert default.basis == "default"
+        assert default.seconds == 300
+        assert default.samples == 0
@@ -0,0 +1,396 @@
+"""Unit tests for session variable inspection."""
+
+import contextlib
+import io
+import json
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from src.models import ExecutionError, ResourceNotFoundError, ValidationError
+from src.models.execution import CodeExecution, ExecutionOutput, ExecutionStatus, OutputType
+from src.services.variables import (
+    VARIABLES_MARKER,
+    VariableInspector,
+    build_inspection_code,
+    build_sandbox_code,
+    complete_code,
+    export_dataframe,
+    parse_inspection_output,
+    summarize_namespace,
+)
+
+
+class FakeArray:
+    """Looks like a numpy array."""
+
+    shape = (3, 4)
+    dtype = "float64"
+    nbytes = 96
+
+    def __repr__(self):
+        return "array([[0., 0., 0., 0.], ...])"
+
+
+class FakeFrame:
+    """Looks like a pandas DataFrame."""
+
+    shape = (100, 2)
+    columns = ["price", "qty"]
+    dtypes = {"price": "float64", "qty": "int64"}
+
+    def memory_usage(self, deep=False):
+        return MagicMock(sum=lambda: 1600)
+
+    def __repr__(self):
+        return "   price  qty\n0    1.0    1"
+
+
+class BrokenRepr:
+    def __repr__(self):
+        raise RuntimeError("nope")
+
+
+class TestSummarizeNamespace:
+    """Tests for the in-sandbox summarizer."""
+
+    def test_basic_values(self):
+        """Names are sorted; private names and modules are left out."""
+        summary = summarize_namespace({"b": [1, 2, 3], "a": 42, "_hidden": 1, "json": json}, 100, 50)
+
+        assert summary["total"] == 2
+        a, b = summary["variables"]
+        assert a["name"] == "a"
+        assert a["type"] == "int"
+        assert a["preview"] == "42"
+        assert b["length"] == 3
+        assert b["size_bytes"] > 0
+
+    def test_array_like(self):
+        """Arrays report shape, dtype and nbytes."""
+        (info,) = summarize_namespace({"arr": FakeArray()}, 100, 50)["variables"]
+
+        assert info["shape"] == [3, 4]
+        assert info["dtype"] == "float64"
+        assert info["size_bytes"] == 96
+        assert info["type"].endswith("FakeArray")
+
+    def test_dataframe_like(self):
+        """Dataframes report columns and memory usage."""
+        (info,) = summarize_namespace({"df": FakeFrame()}, 100, 50)["variables"]
+
+        assert info["shape"] == [100, 2]
+        assert info["columns"] == ["price", "qty"]
+        assert info["size_bytes"] == 1600
+
+    def test_previews_truncated(self):
+        """Long values are cut to the preview limit."""
+        (info,) = summarize_namespace({"s": "x" * 1000}, 100, 20)["variables"]
+
+        assert len(info["preview"]) <= 20
+        assert info["length"] == 1000
+
+    def test_broken_repr(self):
+        """A failing __repr__ doesn't break the listing."""
+        (info,) = summarize_namespace({"bad": BrokenRepr()}, 100, 50)["variables"]
+
+        assert info["name"] == "bad"
+        assert info["preview"].startswith("<BrokenRepr instance")
+
+    def test_variable_limit(self):
+        """Only the first max_variables are described, but all are counted."""
+        summary = summarize_namespace({f"v{i}": i for i in range(10)}, 3, 50)
+
+        assert [v["name"] for v in summary["variables"]] == ["v0", "v1", "v2"]
+        assert summary["total"] == 10
+
+
+class TestInspectionCode:
+    """Tests for the generated code and its output."""
+
+    def test_round_trip(self):
+        """The generated code describes the namespace it runs in, not its own helpers."""
+        namespace = {"x": 1, "name": "abc", "_private": 2}
+        stdout = io.StringIO()
+
+        with contextlib.redirect_stdout(stdout):
+            exec(build_inspection_code(100, 50), namespace)
+
+        summary = parse_inspection_output("noise\n" + stdout.getvalue())
+        assert [v["name"] for v in summary["variables"]] == ["name", "x"]
+
+    def test_missing_summary(self):
+        """Output without the marker is an error."""
+        with pytest.raises(ValueError):
+            parse_inspection_output("Traceback ...\nModuleNotFoundError: No module named 'pandas'\n")
+
+
+class TestExportDataframe:
+    """Tests for the in-sandbox dataframe exporter."""
+
+    @pytest.fixture(autouse=True)
+    def pandas(self):
+        return pytest.importorskip("pandas")
+
+    def test_head_as_records(self, pandas):
+        """JSON exports rows as records with dtypes."""
+        namespace = {"df": pandas.DataFrame({"a": [1, 2, 3], "b": ["x", "y", "z"]})}
+
+        result = export_dataframe(namespace, "df", 2, "json", "head", 10_000)
+
+        assert result["total_rows"] == 3
+        assert result["rows"] == 2
+        assert result["data"] == [{"a": 1, "b": "x"}, {"a": 2, "b": "y"}]
+        assert result["dtypes"]["a"] == "int64"
+
+    def test_csv_tail_of_records_list(self, pandas):
+        """Lists of records are converted; tail picks the last rows."""
+        namespace = {"rows": [{"a": 1}, {"a": 2}, {"a": 3}]}
+
+        result = export_dataframe(namespace, "rows", 1, "csv", "tail", 10_000)
+
+        assert result["data"] == "a\n3\n"
+
+    def test_errors(self, pandas):
+        """Missing, private and non-tabular variables are reported, as are oversized exports."""
+        namespace = {"n": 42, "_p": [1], "df": pandas.DataFrame({"a": range(100)})}
+
+        assert export_dataframe(namespace, "missing", 5, "json", "head", 10_000) == {"error": "not_found"}
+        assert export_dataframe(namespace, "_p", 5, "json", "head", 10_000) == {"error": "not_found"}
+        assert export_dataframe(namespace, "n", 5, "json", "head", 10_000)["error"] == "unsupported_type"
+        assert export_dataframe(namespace, "df", 100, "json", "head", 100)["error"] == "too_large"
+
+    def test_sandbox_code_arguments(self):
+        """Helper arguments are passed as literals."""
+        code = build_sandbox_code(export_dataframe, "df", 5, "csv", "head", 100)
+        assert "export_dataframe(_ci_namespace, 'df', 5, 'csv', 'head', 100)" in code
+
+
+def load_data(path, limit=10):
+    """Load rows from a file."""
+
+
+class TestCompleteCode:
+    """Tests for the in-sandbox completer."""
+
+    def test_completes_session_names(self):
+        """Variables, builtins and keywords complete without rlcompleter decorations."""
+        namespace = {"load_data": load_data, "data": {"a": 1}}
+
+        result = complete_code(namespace, "x = lo", 6, 10, 100)
+
+        assert {"text": "load_data", "type": "function"} in result["matches"]
+        assert {"text": "locals", "type": "function"} in result["matches"]
+        assert result["cursor_start"] == 4
+        assert result["cursor_end"] == 6
+
+    def test_completes_attributes(self):
+        """Dotted names complete attributes of the session's objects."""
+        result = complete_code({"data": {"a": 1}}, "data.ke", 7, 10, 100)
+
+        assert result["matches"] == [{"text": "data.keys", "type": "function"}]
+
+    def test_signature_help(self):
+        """Inside a call, the callee's signature and docstring are returned."""
+        result = complete_code({"load_data": load_data}, "load_data('a.csv', ", 19, 10, 100)
+
+        assert result["matches"] == []
+        assert result["signature"]["signature"] == "load_data(path, limit=10)"
+        assert result["signature"]["doc"] == "Load rows from a file."
+
+    def test_nested_call(self):
+        """Closed inner calls are skipped when looking for the enclosing call."""
+        result = complete_code({"load_data": load_data}, "load_data(str(1), ", 18, 10, 100)
+
+        assert result["signature"]["name"] == "load_data"
+
+    def test_inspect_name_under_cursor(self):
+        """The full name around the cursor is described, even mid-word."""
+        result = complete_code({"load_data": load_data}, "load_data", 3, 10, 100)
+
+        assert result["inspect"]["name"] == "load_data"
+        assert result["inspect"]["type"] == "function"
+
+    def test_limits(self):
+        """Matches and docstrings are capped."""
+        namespace = {f"v{i}": i for i in range(20)}
+
+        assert len(complete_code(namespace, "v", 1, 5, 100)["matches"]) == 5
+        assert len(complete_code({}, "len(", 4, 5, 10)["signature"]["doc"]) == 10
+
+
+def _execution(stdout, status=ExecutionStatus.COMPLETED):
+    return CodeExecution(
+        execution_id="exec-1",
+        session_id="session-123",
+        code="...",
+        status=status,
+        outputs=[ExecutionOutput(type=OutputType.STDOUT, content=stdout)] if stdout else [],
+    )
+
+
+@pytest.fixture
+def services():
+    """Create mocked execution and state services."""
+    execution_service = MagicMock()
+    execution_service.execute_code = AsyncMock()
+    execution_service.kubernetes_manager.destroy_pod = AsyncMock()
+    state_service = MagicMock()
+    state_service.get_state = AsyncMock(return_value="c3RhdGU=")
+    archival_service = MagicMock()
+    archival_service.restore_state = AsyncMock(return_value=None)
+    return execution_service, state_service, archival_service
+
+
+class TestVariableInspector:
+    """Tests for VariableInspector.inspect."""
+
+    @pytest.mark.asyncio
+    async def test_no_state(self, services):
+        """Sessions without state report no variables and run nothing."""
+        execution_service, state_service, archival_service = services
+        state_service.get_state.return_value = None
+
+        with patch("src.services.variables.settings") as mock_settings:
+            mock_settings.state_archive_enabled = True
+            response = await VariableInspector(*services).inspect("session-123")
+
+        assert response.has_state is False
+        assert response.variables == []
+        archival_service.restore_state.assert_called_once_with("session-123")
+        execution_service.execute_code.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_inspect_state(self, services):
+        """State is restored into a sandbox without capturing it back."""
+        execution_service, _, _ = services
+        summary = {"variables": [{"name": "x", "type": "int", "size_bytes": 28, "preview": "42"}], "total": 1}
+        handle = MagicMock()
+        execution_service.execute_code.return_value = (
+            _execution(VARIABLES_MARKER + json.dumps(summary)),
+            handle,
+            None,
+            [],
+            "pool_hit",
+        )
+
+        response = await VariableInspector(*services).inspect("session-123")
+
+        assert response.has_state is True
+        assert response.variables[0].name == "x"
+        assert response.total == 1
+        kwargs = execution_service.execute_code.call_args.kwargs
+        assert kwargs == {"initial_state": "c3RhdGU=", "capture_state": False}
+        execution_service.kubernetes_manager.destroy_pod.assert_called_once_with(handle)
+
+    @pytest.mark.asyncio
+    async def test_inspection_failure(self, services):
+        """A failed summarizer raises and still releases the pod."""
+        execution_service, _, _ = services
+        execution_service.execute_code.return_value = (
+            _execution("", status=ExecutionStatus.FAILED),
+            MagicMock(),
+            None,
+            [],
+            "pool_hit",
+        )
+
+        with pytest.raises(ExecutionError):
+            await VariableInspector(*services).inspect("session-123")
+
+        execution_service.kubernetes_manager.destroy_pod.assert_called_once()
+
+
+class TestVariableInspectorExport:
+    """Tests for VariableInspector.export_dataframe."""
+
+    def _returns(self, execution_service, result):
+        execution_service.execute_code.return_value = (
+            _execution(VARIABLES_MARKER + json.dumps(result)),
+            MagicMock(),
+            None,
+            [],
+            "pool_hit",
+        )
+
+    @pytest.mark.asyncio
+    async def test_export_records(self, services):
+        """The exporter's result is returned as is for JSON."""
+        execution_service, _, _ = services
+        result = {"total_rows": 1, "rows": 1, "columns": ["a"], "dtypes": {"a": "int64"}, "data": [{"a": 1}]}
+        self._returns(execution_service, result)
+
+        assert await VariableInspector(*services).export_dataframe("session-123", "df", 5) == result
+        code = execution_service.execute_code.call_args.args[1].code
+        assert "export_dataframe(_ci_namespace, 'df', 5, 'json', 'head'," in code
+
+    @pytest.mark.asyncio
+    async def test_export_parquet_decoded(self, services):
+        """Parquet comes back from the sandbox as base64 and is decoded to bytes."""
+        execution_service, _, _ = services
+        self._returns(execution_service, {"total_rows": 1, "rows": 1, "columns": [], "dtypes": {}, "data": "UEFSMQ=="})
+
+        result = await VariableInspector(*services).export_dataframe("session-123", "df", 5, "parquet")
+
+        assert result["data"] == b"PAR1"
+
+    @pytest.mark.asyncio
+    async def test_export_not_found(self, services):
+        """Unknown variables and sessions without state are 404s."""
+        execution_service, state_service, _ = services
+        self._returns(execution_service, {"error": "not_found"})
+
+        with pytest.raises(ResourceNotFoundError):
+            await VariableInspector(*services).export_dataframe("session-123", "df", 5)
+
+        state_service.get_state.return_value = None
+        execution_service.execute_code.reset_mock()
+        with patch("src.services.variables.settings") as mock_settings:
+            mock_settings.state_archive_enabled = False
+            with pytest.raises(ResourceNotFoundError):
+                await VariableInspector(*services).export_dataframe("session-123", "df", 5)
+        execution_service.execute_code.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_export_rejections(self, services):
+        """Non-tabular values and oversized exports are validation errors."""
+        execution_service, _, _ = services
+
+        self._returns(execution_service, {"error": "unsupported_type", "type": "int"})
+        with pytest.raises(ValidationError, match="can't be converted"):
+            await VariableInspector(*services).export_dataframe("session-123", "n", 5)
+
+        self._returns(execution_service, {"error": "too_large", "size": 5_000_000})
+        with pytest.raises(ValidationError, match="request fewer rows"):
+            await VariableInspector(*services).export_dataframe("session-123", "df", 5)
+
+        self._returns(execution_service, {"error": "export_failed", "message": "ArrowTypeError: mixed"})
+        with pytest.raises(ExecutionError, match="ArrowTypeError"):
+            await VariableInspector(*services).export_dataframe("session-123", "df", 5, "parquet")
+
+
+class TestVariableInspectorComplete:
+    """Tests for VariableInspector.complete."""
+
+    @pytest.mark.asyncio
+    async def test_complete_without_state(self, services):
+        """Completion still runs (builtins only) when the session has no state."""
+        execution_service, state_service, _ = services
+        state_service.get_state.return_value = None
+        result = {"matches": [{"text": "print", "type": "function"}], "cursor_start": 0, "cursor_end": 3}
+        execution_service.execute_code.return_value = (
+            _execution(VARIABLES_MARKER + json.dumps(result)),
+            MagicMock(),
+            None,
+            [],
+            "pool_hit",
+        )
+
+        with patch("src.services.variables.settings") as mock_settings:
+            mock_settings.state_archive_enabled = False
+            mock_settings.max_execution_time = 30
+            response = await VariableInspector(*services).complete("session-123", "pri", 3)
+
+        assert response.matches[0].text == "print"
+        assert execution_service.execute_code.call_args.kwargs["initial_state"] is None
+        assert "complete_code(_ci_namespace, 'pri', 3," in execution_service.execute_code.call_args.args[1].code
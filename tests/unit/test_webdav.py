@@ -0,0 +1,221 @@
+"""Unit tests for WebDAV workspace access."""
+
+from datetime import UTC, datetime
+from unittest.mock import AsyncMock, MagicMock, patch
+from xml.etree import ElementTree
+
+import pytest
+from fastapi import HTTPException
+
+from src.api.webdav import copy_or_move_file, delete_file, get_file, propfind_collection, put_file, router
+from src.dependencies import reject_quarantined_session
+from src.models import FileInfo
+from src.services.webdav import DAV_NS, find_file, multistatus, parse_destination
+
+
+def _file(file_id: str, filename: str, day: int = 1) -> FileInfo:
+    return FileInfo(
+        file_id=file_id,
+        filename=filename,
+        size=12,
+        content_type="text/csv",
+        created_at=datetime(2026, 1, day, tzinfo=UTC),
+        path=f"/{filename}",
+    )
+
+
+def _request(method: str = "GET", headers: dict | None = None, body: bytes = b"") -> MagicMock:
+    request = MagicMock()
+    request.method = method
+    request.headers = headers or {}
+    request.body = AsyncMock(return_value=body)
+    return request
+
+
+@pytest.fixture
+def file_service():
+    service = MagicMock()
+    service.list_files = AsyncMock(return_value=[_file("f1", "data.csv"), _file("f2", "my report.txt")])
+    service.get_file_content = AsyncMock(return_value=b"a,b\n1,2\n")
+    service.store_uploaded_file = AsyncMock(return_value="f3")
+    service.delete_file = AsyncMock(return_value=True)
+    return service
+
+
+@pytest.fixture
+def session_service():
+    service = MagicMock()
+    service.get_session = AsyncMock(return_value=MagicMock())
+    return service
+
+
+@pytest.fixture
+def mock_settings():
+    with patch("src.api.webdav.settings") as mock:
+        mock.max_file_size_mb = 1
+        mock.max_files_per_session = 3
+        yield mock
+
+
+class TestHelpers:
+    def test_find_file_by_workspace_name(self):
+        files = [_file("old", "my report.txt", day=1), _file("new", "my report.txt", day=2)]
+
+        assert find_file(files, "my_report.txt").file_id == "new"
+        assert find_file(files, "my report.txt") is None
+
+    def test_parse_destination(self):
+        assert parse_destination("https://host/dav/s1/new%20name.csv", "s1") == "new name.csv"
+        assert parse_destination("/dav/s1/out.csv", "s1") == "out.csv"
+        assert parse_destination("/dav/s2/out.csv", "s1") is None
+        assert parse_destination("/dav/s1/sub/out.csv", "s1") is None
+        assert parse_destination(None, "s1") is None
+
+    def test_multistatus(self):
+        root = ElementTree.fromstring(multistatus("s1", [_file("f1", "data.csv")]))
+        responses = root.findall(f"{{{DAV_NS}}}response")
+
+        assert [r.findtext(f"{{{DAV_NS}}}href") for r in responses] == ["/dav/s1/", "/dav/s1/data.csv"]
+        assert responses[0].find(f".//{{{DAV_NS}}}collection") is not None
+        prop = responses[1].find(f".//{{{DAV_NS}}}prop")
+        assert prop.findtext(f"{{{DAV_NS}}}getcontentlength") == "12"
+        assert prop.findtext(f"{{{DAV_NS}}}getetag") == '"f1"'
+        assert prop.findtext(f"{{{DAV_NS}}}getlastmodified") == "Thu, 01 Jan 2026 00:00:00 GMT"
+
+
+class TestPropfind:
+    @pytest.mark.asyncio
+    async def test_lists_files(self, file_service, session_service):
+        response = await propfind_collection("s1", _request("PROPFIND", {"depth": "1"}), file_service, session_service)
+
+        assert response.status_code == 207
+        assert b"/dav/s1/my_report.txt" in response.body
+
+    @pytest.mark.asyncio
+    async def test_depth_zero(self, file_service, session_service):
+        response = await propfind_collection("s1", _request("PROPFIND", {"depth": "0"}), file_service, session_service)
+
+        assert b"data.csv" not in response.body
+        file_service.list_files.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_unknown_session(self, file_service, session_service):
+        session_service.get_session.return_value = None
+
+        with pytest.raises(HTTPException) as exc_info:
+            await propfind_collection("missing", _request("PROPFIND"), file_service, session_service)
+
+        assert exc_info.value.status_code == 404
+
+
+class TestGetAndDelete:
+    @pytest.mark.asyncio
+    async def test_get(self, file_service):
+        response = await get_file("s1", "data.csv", _request(), file_service)
+
+        assert response.body == b"a,b\n1,2\n"
+        assert response.headers["etag"] == '"f1"'
+
+    @pytest.mark.asyncio
+    async def test_head(self, file_service):
+        response = await get_file("s1", "data.csv", _request("HEAD"), file_service)
+
+        assert response.headers["content-length"] == "12"
+        file_service.get_file_content.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_get_missing(self, file_service):
+        with pytest.raises(HTTPException) as exc_info:
+            await get_file("s1", "nope.csv", _request(), file_service)
+
+        assert exc_info.value.status_code == 404
+
+    @pytest.mark.asyncio
+    async def test_delete(self, file_service):
+        response = await delete_file("s1", "data.csv", file_service)
+
+        assert response.status_code == 204
+        file_service.delete_file.assert_awaited_once_with("s1", "f1")
+
+
+class TestPut:
+    @pytest.mark.asyncio
+    async def test_creates(self, file_service, session_service, mock_settings):
+        request = _request("PUT", {"content-type": "image/png"}, b"png")
+
+        response = await put_file("s1", "chart 1.png", request, file_service, session_service)
+
+        assert response.status_code == 201
+        assert response.headers["location"] == "/dav/s1/chart_1.png"
+        file_service.store_uploaded_file.assert_awaited_once_with("s1", "chart_1.png", b"png", "image/png")
+        file_service.delete_file.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_replaces(self, file_service, session_service, mock_settings):
+        response = await put_file("s1", "data.csv", _request("PUT", body=b"x"), file_service, session_service)
+
+        assert response.status_code == 204
+        file_service.delete_file.assert_awaited_once_with("s1", "f1")
+
+    @pytest.mark.asyncio
+    async def test_too_large(self, file_service, session_service, mock_settings):
+        request = _request("PUT", body=b"x" * (1024 * 1024 + 1))
+
+        with pytest.raises(HTTPException) as exc_info:
+            await put_file("s1", "big.bin", request, file_service, session_service)
+
+        assert exc_info.value.status_code == 413
+
+    @pytest.mark.asyncio
+    async def test_file_limit(self, file_service, session_service, mock_settings):
+        mock_settings.max_files_per_session = 2
+
+        with pytest.raises(HTTPException) as exc_info:
+            await put_file("s1", "new.csv", _request("PUT", body=b"x"), file_service, session_service)
+
+        assert exc_info.value.status_code == 507
+
+
+class TestCopyMove:
+    @pytest.mark.asyncio
+    async def test_move(self, file_service):
+        request = _request("MOVE", {"destination": "/dav/s1/renamed.csv"})
+
+        response = await copy_or_move_file("s1", "data.csv", request, file_service)
+
+        assert response.status_code == 201
+        file_service.store_uploaded_file.assert_awaited_once_with("s1", "renamed.csv", b"a,b\n1,2\n", "text/csv")
+        file_service.delete_file.assert_awaited_once_with("s1", "f1")
+
+    @pytest.mark.asyncio
+    async def test_copy_keeps_source(self, file_service):
+        request = _request("COPY", {"destination": "/dav/s1/copy.csv"})
+
+        await copy_or_move_file("s1", "data.csv", request, file_service)
+
+        file_service.delete_file.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_no_overwrite(self, file_service):
+        request = _request("COPY", {"destination": "/dav/s1/my_report.txt", "overwrite": "F"})
+
+        with pytest.raises(HTTPException) as exc_info:
+            await copy_or_move_file("s1", "data.csv", request, file_service)
+
+        assert exc_info.value.status_code == 412
+
+    @pytest.mark.asyncio
+    async def test_other_session(self, file_service):
+        request = _request("MOVE", {"destination": "/dav/s2/data.csv"})
+
+        with pytest.raises(HTTPException) as exc_info:
+            await copy_or_move_file("s1", "data.csv", request, file_service)
+
+        assert exc_info.value.status_code == 403
+
+    def test_rejects_quarantined_session(self):
+        """COPY and MOVE change the session, so they are refused like PUT and DELETE while it is quarantined."""
+        route = next(r for r in router.routes if "MOVE" in getattr(r, "methods", ()))
+
+        assert {"COPY", "MOVE"} <= route.methods
+        assert reject_quarantined_session in [dependency.call for dependency in route.dependant.dependencies]
@@ -0,0 +1,217 @@
+"""Unit tests for workspace locks."""
+
+import json
+import time
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+from redis.exceptions import WatchError
+
+from src.services.workspace_lock import (
+    WorkspaceLockService,
+    find_conflicts,
+    normalize_scope_path,
+    paths_overlap,
+)
+
+
+@pytest.fixture
+def mock_pipeline():
+    """Create a mock transactional pipeline with no locks held."""
+    pipe = MagicMock()
+    pipe.watch = AsyncMock()
+    pipe.unwatch = AsyncMock()
+    pipe.hgetall = AsyncMock(return_value={})
+    pipe.execute = AsyncMock(return_value=[])
+    pipe.reset = AsyncMock()
+    return pipe
+
+
+@pytest.fixture
+def mock_redis(mock_pipeline):
+    """Create a mock Redis client."""
+    client = MagicMock()
+    client.pipeline = AsyncMock(return_value=mock_pipeline)
+    client.hdel = AsyncMock(return_value=1)
+    client.hgetall = AsyncMock(return_value={})
+    return client
+
+
+@pytest.fixture
+def lock_service(mock_redis):
+    """Create a lock service with mocked Redis."""
+    return WorkspaceLockService(redis_client=mock_redis)
+
+
+def _lock(paths, expires_in=60, owner="execution"):
+    return {"paths": paths, "owner": owner, "expires_at": time.time() + expires_in}
+
+
+class TestNormalizeScopePath:
+    """Tests for scope path normalization."""
+
+    @pytest.mark.parametrize("path", ["", ".", "/", "/mnt/data", "/mnt/data/"])
+    def test_workspace_root(self, path):
+        """All spellings of the workspace root normalize to the root scope."""
+        assert normalize_scope_path(path) == ""
+
+    @pytest.mark.parametrize(
+        "path,expected",
+        [
+            ("out", "out"),
+            ("/out/", "out"),
+            ("./out//a", "out/a"),
+            ("/mnt/data/out/a.csv", "out/a.csv"),
+            ("out\\a", "out/a"),
+        ],
+    )
+    def test_relative_paths(self, path, expected):
+        """Paths are made workspace-relative."""
+        assert normalize_scope_path(path) == expected
+
+    @pytest.mark.parametrize("path", ["..", "../etc", "out/../../x", "/mnt/data/../x"])
+    def test_escaping_paths_rejected(self, path):
+        """Parent references are rejected."""
+        with pytest.raises(ValueError, match="escapes the workspace"):
+            normalize_scope_path(path)
+
+
+class TestPathsOverlap:
+    """Tests for path overlap."""
+
+    def test_root_overlaps_everything(self):
+        """The whole-workspace scope overlaps any path."""
+        assert paths_overlap("", "out")
+        assert paths_overlap("data/a.csv", "")
+
+    def test_parent_and_child_overlap(self):
+        """A directory overlaps the paths under it."""
+        assert paths_overlap("out", "out/a.csv")
+        assert paths_overlap("out/a.csv", "out")
+
+    def test_siblings_disjoint(self):
+        """Sibling paths and shared prefixes don't overlap."""
+        assert not paths_overlap("out", "data")
+        assert not paths_overlap("out", "output")
+
+
+class TestFindConflicts:
+    """Tests for conflict detection."""
+
+    def test_overlapping_lock_conflicts(self):
+        """An unexpired overlapping lock is a conflict."""
+        held = {"t1": _lock(["out"])}
+        assert find_conflicts(held, ["out/a"], time.time()) == [held["t1"]]
+
+    def test_expired_lock_ignored(self):
+        """Expired locks never conflict."""
+        held = {"t1": _lock([""], expires_in=-1)}
+        assert find_conflicts(held, ["out"], time.time()) == []
+
+    def test_holder_token_ignored(self):
+        """The caller's own lock doesn't conflict."""
+        held = {"t1": _lock(["out"], owner="client")}
+        assert find_conflicts(held, ["out"], time.time(), holder_token="t1") == []
+
+
+class TestTryAcquire:
+    """Tests for WorkspaceLockService.try_acquire."""
+
+    @pytest.mark.asyncio
+    async def test_acquire_free_workspace(self, lock_service, mock_pipeline):
+        """A lock is written when nothing overlaps."""
+        token, conflicts = await lock_service.try_acquire("session-123", ["out"], "execution", 60)
+
+        assert token
+        assert conflicts == []
+        key, stored_token, value = mock_pipeline.hset.call_args[0]
+        assert key == "session:locks:session-123"
+        assert stored_token == token
+        assert json.loads(value)["paths"] == ["out"]
+        mock_pipeline.expire.assert_called_once_with("session:locks:session-123", 60)
+        mock_pipeline.reset.assert_called_once()
+
+    @pytest.mark.asyncio
+    async def test_acquire_conflict(self, lock_service, mock_pipeline):
+        """Overlapping locks block acquisition and are returned."""
+        mock_pipeline.hgetall.return_value = {"t1": json.dumps(_lock([""]))}
+
+        token, conflicts = await lock_service.try_acquire("session-123", ["out"], "execution", 60)
+
+        assert token is None
+        assert conflicts[0]["paths"] == [""]
+        mock_pipeline.hset.assert_not_called()
+        mock_pipeline.unwatch.assert_called_once()
+
+    @pytest.mark.asyncio
+    async def test_acquire_prunes_expired(self, lock_service, mock_pipeline):
+        """Expired entries are removed when a new lock is written."""
+        mock_pipeline.hgetall.return_value = {"old": json.dumps(_lock([""], expires_in=-5))}
+
+        token, _ = await lock_service.try_acquire("session-123", ["out"], "execution", 60)
+
+        assert token
+        mock_pipeline.hdel.assert_called_once_with("session:locks:session-123", "old")
+
+    @pytest.mark.asyncio
+    async def test_acquire_retries_on_watch_error(self, lock_service, mock_pipeline):
+        """A concurrent change re-runs the check instead of failing."""
+        mock_pipeline.execute.side_effect = [WatchError(), []]
+
+        token, _ = await lock_service.try_acquire("session-123", ["out"], "execution", 60)
+
+        assert token
+        assert mock_pipeline.watch.call_count == 2
+
+
+class TestAcquireAndRelease:
+    """Tests for waiting, release and listing."""
+
+    @pytest.mark.asyncio
+    async def test_acquire_waits_until_free(self, lock_service, mock_pipeline):
+        """acquire polls until the overlapping lock clears."""
+        lock_service.POLL_INTERVAL_SECONDS = 0
+        mock_pipeline.hgetall.side_effect = [{"t1": json.dumps(_lock(["out"]))}, {}]
+
+        token, conflicts = await lock_service.acquire("session-123", ["out"], "execution", 60, wait_seconds=5)
+
+        assert token
+        assert conflicts == []
+
+    @pytest.mark.asyncio
+    async def test_acquire_without_wait_returns_conflicts(self, lock_service, mock_pipeline):
+        """wait_seconds=0 makes a single attempt."""
+        mock_pipeline.hgetall.return_value = {"t1": json.dumps(_lock(["out"]))}
+
+        token, conflicts = await lock_service.acquire("session-123", ["out"], "client", 60)
+
+        assert token is None
+        assert len(conflicts) == 1
+        assert mock_pipeline.watch.call_count == 1
+
+    @pytest.mark.asyncio
+    async def test_release(self, lock_service, mock_redis):
+        """Release deletes the token's entry."""
+        assert await lock_service.release("session-123", "t1") is True
+        mock_redis.hdel.assert_called_once_with("session:locks:session-123", "t1")
+
+    @pytest.mark.asyncio
+    async def test_release_unknown_token(self, lock_service, mock_redis):
+        """Releasing a lock that isn't held reports False."""
+        mock_redis.hdel.return_value = 0
+        assert await lock_service.release("session-123", "missing") is False
+
+    @pytest.mark.asyncio
+    async def test_list_locks_hides_expired(self, lock_service, mock_redis):
+        """Only live locks are listed, soonest expiry first, without tokens."""
+        later, sooner = _lock(["a"], expires_in=120), _lock(["b"], expires_in=30)
+        mock_redis.hgetall.return_value = {
+            "t1": json.dumps(later),
+            "t2": json.dumps(sooner),
+            "t3": json.dumps(_lock(["c"], expires_in=-1)),
+            "t4": "not json",
+        }
+
+        locks = await lock_service.list_locks("session-123")
+
+        assert locks == [sooner, later]